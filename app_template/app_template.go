@@ -0,0 +1,116 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+// Package app_template defines provisioning templates:  named sets of the
+// volumes an application needs, e.g., a database's data and log volumes,
+// which are provisioned together as a volume group.
+package app_template
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/storage"
+)
+
+// VolumeTemplate describes one volume of a template.  Its name is appended
+// to the instance name to name the volume, e.g., the "data" volume of
+// instance "db1" is named "db1-data".
+type VolumeTemplate struct {
+	Name             string            `json:"name"`
+	Size             string            `json:"size"`
+	Protocol         config.Protocol   `json:"protocol,omitempty"`
+	StorageClass     string            `json:"storageClass,omitempty"`
+	AccessMode       config.AccessMode `json:"accessMode,omitempty"`
+	MinIOPS          int               `json:"minIOPS,omitempty"`
+	MaxIOPS          int               `json:"maxIOPS,omitempty"`
+	BurstIOPS        int               `json:"burstIOPS,omitempty"`
+	FSType           string            `json:"fsType,omitempty"`
+	MkfsOptions      string            `json:"mkfsOptions,omitempty"`
+	MountOptions     string            `json:"mountOptions,omitempty"`
+	SnapshotPolicy   string            `json:"snapshotPolicy,omitempty"`
+	SnapshotSchedule string            `json:"snapshotSchedule,omitempty"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+}
+
+type Config struct {
+	Version string            `json:"version"`
+	Name    string            `json:"name"`
+	Volumes []*VolumeTemplate `json:"volumes"`
+}
+
+func (c *Config) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("Template name must be specified.")
+	}
+	if len(c.Volumes) == 0 {
+		return fmt.Errorf("Template %s has no volumes.", c.Name)
+	}
+	names := make(map[string]bool, len(c.Volumes))
+	for _, v := range c.Volumes {
+		if v == nil || v.Name == "" {
+			return fmt.Errorf("Each volume of template %s must have a name.",
+				c.Name)
+		}
+		if strings.ContainsAny(v.Name, "/ ") {
+			return fmt.Errorf("Volume name %q of template %s may not contain "+
+				"slashes or spaces.", v.Name, c.Name)
+		}
+		if names[v.Name] {
+			return fmt.Errorf("Volume %s is listed more than once in "+
+				"template %s.", v.Name, c.Name)
+		}
+		names[v.Name] = true
+		// Check the fields as they'll be requested.
+		if err := v.volumeConfig(c.Name).Validate(); err != nil {
+			return fmt.Errorf("Invalid volume %s of template %s:  %v",
+				v.Name, c.Name, err)
+		}
+	}
+	c.Version = config.OrchestratorMajorVersion
+	return nil
+}
+
+// VolumeName returns the name of a template volume for an instance.
+func VolumeName(instanceName, volumeName string) string {
+	return instanceName + "-" + volumeName
+}
+
+// VolumeConfigs returns the configs of the volumes of an instance of the
+// template, in the template's order.
+func (c *Config) VolumeConfigs(instanceName string) []*storage.VolumeConfig {
+	ret := make([]*storage.VolumeConfig, 0, len(c.Volumes))
+	for _, v := range c.Volumes {
+		ret = append(ret, v.volumeConfig(instanceName))
+	}
+	return ret
+}
+
+func (v *VolumeTemplate) volumeConfig(
+	instanceName string,
+) *storage.VolumeConfig {
+	var metadata map[string]string
+	if len(v.Metadata) > 0 {
+		metadata = make(map[string]string, len(v.Metadata))
+		for k, value := range v.Metadata {
+			metadata[k] = value
+		}
+	}
+	return &storage.VolumeConfig{
+		Version:          config.OrchestratorMajorVersion,
+		Name:             VolumeName(instanceName, v.Name),
+		Size:             v.Size,
+		Protocol:         v.Protocol,
+		StorageClass:     v.StorageClass,
+		AccessMode:       v.AccessMode,
+		MinIOPS:          v.MinIOPS,
+		MaxIOPS:          v.MaxIOPS,
+		BurstIOPS:        v.BurstIOPS,
+		FSType:           v.FSType,
+		MkfsOptions:      v.MkfsOptions,
+		MountOptions:     v.MountOptions,
+		SnapshotPolicy:   v.SnapshotPolicy,
+		SnapshotSchedule: v.SnapshotSchedule,
+		Metadata:         metadata,
+	}
+}
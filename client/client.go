@@ -0,0 +1,345 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+// Package client is a Go client for Trident's REST API, for controllers,
+// tools, and tests that manage Trident's backends, storage classes, and
+// volumes.  It uses the v2 API, whose failures it reports as *Error.
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/storage"
+	"github.com/netapp/trident/storage_class"
+)
+
+const (
+	DefaultTimeout       = 30 * time.Second
+	DefaultMaxRetries    = 3
+	DefaultRetryInterval = time.Second
+	// maxRetryWait bounds how long a Retry-After header makes the client
+	// wait.
+	maxRetryWait = time.Minute
+)
+
+// Config configures a Client.
+type Config struct {
+	// Address is Trident's address, e.g., "10.0.0.1:8000" or
+	// "https://trident.example.com:8443"; it defaults to http.
+	Address string
+	// Timeout bounds each attempt at a request; it defaults to
+	// DefaultTimeout.
+	Timeout time.Duration
+	// MaxRetries is how many times a request is retried when Trident is
+	// bootstrapping or rate limits it, and, for reads, when Trident can't be
+	// reached.  Zero uses DefaultMaxRetries; a negative value disables
+	// retries.
+	MaxRetries int
+	// RetryInterval is how long to wait before retrying, unless Trident
+	// says how long to wait; it defaults to DefaultRetryInterval.
+	RetryInterval time.Duration
+	// HTTPClient, if set, sends the requests instead of a client with
+	// Timeout, e.g., to use a client certificate.
+	HTTPClient *http.Client
+}
+
+// Client calls Trident's REST API.  It is safe for concurrent use.
+type Client struct {
+	baseURL       string
+	httpClient    *http.Client
+	maxRetries    int
+	retryInterval time.Duration
+	sleep         func(time.Duration)
+}
+
+// NewClient returns a client for the Trident at c.Address.
+func NewClient(c *Config) (*Client, error) {
+	if c.Address == "" {
+		return nil, fmt.Errorf("Trident's address is required.")
+	}
+	baseURL := strings.TrimSuffix(c.Address, "/")
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "http://" + baseURL
+	}
+	if _, err := url.Parse(baseURL); err != nil {
+		return nil, fmt.Errorf("Invalid address %s:  %v", c.Address, err)
+	}
+	client := &Client{
+		baseURL:       baseURL,
+		httpClient:    c.HTTPClient,
+		maxRetries:    c.MaxRetries,
+		retryInterval: c.RetryInterval,
+		sleep:         time.Sleep,
+	}
+	if client.httpClient == nil {
+		timeout := c.Timeout
+		if timeout == 0 {
+			timeout = DefaultTimeout
+		}
+		client.httpClient = &http.Client{Timeout: timeout}
+	}
+	if client.maxRetries == 0 {
+		client.maxRetries = DefaultMaxRetries
+	} else if client.maxRetries < 0 {
+		client.maxRetries = 0
+	}
+	if client.retryInterval == 0 {
+		client.retryInterval = DefaultRetryInterval
+	}
+	return client, nil
+}
+
+// do sends a request, retrying it as Config.MaxRetries allows, and decodes
+// a successful response's body into response, if it is non-nil.  The
+// request is sent as JSON, unless it is already encoded as a []byte.
+func (c *Client) do(
+	method, path string, request, response interface{},
+) error {
+	var body []byte
+	switch r := request.(type) {
+	case nil:
+	case []byte:
+		body = r
+	default:
+		var err error
+		if body, err = json.Marshal(request); err != nil {
+			return err
+		}
+	}
+	for attempt := 0; ; attempt++ {
+		respBody, wait, err := c.send(method, path, body)
+		if err == nil {
+			if response == nil {
+				return nil
+			}
+			return json.Unmarshal(respBody, response)
+		}
+		if wait < 0 || attempt >= c.maxRetries {
+			return err
+		}
+		c.sleep(wait)
+	}
+}
+
+// send makes one attempt at a request.  If it fails, send returns how long
+// to wait before retrying, or a negative duration if the request mustn't
+// be retried.
+func (c *Client) send(
+	method, path string, body []byte,
+) ([]byte, time.Duration, error) {
+	req, err := http.NewRequest(method, c.baseURL+path,
+		bytes.NewReader(body))
+	if err != nil {
+		return nil, -1, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// A write may have been carried out even though its response was
+		// lost, so only reads are retried.
+		if method == "GET" {
+			return nil, c.retryInterval, err
+		}
+		return nil, -1, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, -1, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return respBody, 0, nil
+	}
+	err = newError(method, path, resp.Status, resp.StatusCode, respBody)
+	// Trident rejects requests with a Retry-After header, without carrying
+	// them out, while it bootstraps or when they are rate limited.
+	retryAfter := resp.Header.Get("Retry-After")
+	if retryAfter == "" || (resp.StatusCode !=
+		http.StatusServiceUnavailable &&
+		resp.StatusCode != http.StatusTooManyRequests) {
+		return nil, -1, err
+	}
+	wait := c.retryInterval
+	if seconds, parseErr := strconv.Atoi(retryAfter); parseErr == nil &&
+		seconds >= 0 {
+		wait = time.Duration(seconds) * time.Second
+		if wait > maxRetryWait {
+			wait = maxRetryWait
+		}
+	}
+	return nil, wait, err
+}
+
+// list returns every name from a v2 list call, following its pages.
+func (c *Client) list(path string, query url.Values) ([]string, error) {
+	names := make([]string, 0)
+	for {
+		response := &struct {
+			Items      []string `json:"items"`
+			Pagination *struct {
+				Continue string `json:"continue"`
+			} `json:"pagination"`
+		}{}
+		listPath := path
+		if encoded := query.Encode(); encoded != "" {
+			listPath += "?" + encoded
+		}
+		if err := c.do("GET", listPath, nil, response); err != nil {
+			return nil, err
+		}
+		names = append(names, response.Items...)
+		if response.Pagination == nil ||
+			response.Pagination.Continue == "" {
+			return names, nil
+		}
+		query.Set("continue", response.Pagination.Continue)
+	}
+}
+
+// GetVersion returns Trident's version.
+func (c *Client) GetVersion() (string, error) {
+	response := &struct {
+		Version string `json:"version"`
+	}{}
+	if err := c.do("GET", config.VersionURLV2, nil, response); err != nil {
+		return "", err
+	}
+	return response.Version, nil
+}
+
+// ListBackends returns the names of Trident's backends.
+func (c *Client) ListBackends() ([]string, error) {
+	return c.list(config.BackendURLV2, url.Values{})
+}
+
+// GetBackend returns the named backend.
+func (c *Client) GetBackend(
+	name string,
+) (*storage.StorageBackendExternal, error) {
+	response := &struct {
+		Backend *storage.StorageBackendExternal `json:"backend"`
+	}{}
+	if err := c.do("GET", config.BackendURLV2+"/"+name, nil,
+		response); err != nil {
+		return nil, err
+	}
+	return response.Backend, nil
+}
+
+// AddBackend adds a backend with the given JSON configuration, or updates
+// the backend it names, and returns the backend's name.
+func (c *Client) AddBackend(configJSON []byte) (string, error) {
+	response := &struct {
+		Backend string `json:"backend"`
+	}{}
+	if err := c.do("POST", config.BackendURLV2, configJSON,
+		response); err != nil {
+		return "", err
+	}
+	return response.Backend, nil
+}
+
+// DeleteBackend takes the named backend offline.  A backend whose volumes
+// would be stranded is only taken offline if confirm is set.
+func (c *Client) DeleteBackend(name string, confirm bool) error {
+	path := config.BackendURLV2 + "/" + name
+	if confirm {
+		path += "?confirm=true"
+	}
+	return c.do("DELETE", path, nil, nil)
+}
+
+// ListVolumes returns the names of Trident's volumes.  If metadata isn't
+// empty, it is a selector, e.g., "team=web", and only the volumes whose
+// metadata matches are listed.
+func (c *Client) ListVolumes(metadata string) ([]string, error) {
+	query := url.Values{}
+	if metadata != "" {
+		query.Set("metadata", metadata)
+	}
+	return c.list(config.VolumeURLV2, query)
+}
+
+// GetVolume returns the named volume.
+func (c *Client) GetVolume(name string) (*storage.VolumeExternal, error) {
+	response := &struct {
+		Volume *storage.VolumeExternal `json:"volume"`
+	}{}
+	if err := c.do("GET", config.VolumeURLV2+"/"+name, nil,
+		response); err != nil {
+		return nil, err
+	}
+	return response.Volume, nil
+}
+
+// CreateVolume creates a volume and returns its name.
+func (c *Client) CreateVolume(
+	volumeConfig *storage.VolumeConfig,
+) (string, error) {
+	response := &struct {
+		Volume string `json:"volume"`
+	}{}
+	if err := c.do("POST", config.VolumeURLV2, volumeConfig,
+		response); err != nil {
+		return "", err
+	}
+	return response.Volume, nil
+}
+
+// DeleteVolume deletes the named volume.  If force is true, the volume is
+// deleted even if it is attached or has snapshots.
+func (c *Client) DeleteVolume(name string, force bool) error {
+	path := config.VolumeURLV2 + "/" + name
+	if force {
+		path += "?force=true"
+	}
+	return c.do("DELETE", path, nil, nil)
+}
+
+// ListStorageClasses returns the names of Trident's storage classes.
+func (c *Client) ListStorageClasses() ([]string, error) {
+	return c.list(config.StorageClassURLV2, url.Values{})
+}
+
+// GetStorageClass returns the named storage class.
+func (c *Client) GetStorageClass(
+	name string,
+) (*storage_class.StorageClassExternal, error) {
+	response := &struct {
+		StorageClass *storage_class.StorageClassExternal `json:"storageClass"`
+	}{}
+	if err := c.do("GET", config.StorageClassURLV2+"/"+name, nil,
+		response); err != nil {
+		return nil, err
+	}
+	return response.StorageClass, nil
+}
+
+// AddStorageClass adds a storage class and returns its name.
+func (c *Client) AddStorageClass(
+	scConfig *storage_class.Config,
+) (string, error) {
+	response := &struct {
+		StorageClass string `json:"storageClass"`
+	}{}
+	if err := c.do("POST", config.StorageClassURLV2, scConfig,
+		response); err != nil {
+		return "", err
+	}
+	return response.StorageClass, nil
+}
+
+// DeleteStorageClass deletes the named storage class.
+func (c *Client) DeleteStorageClass(name string) error {
+	return c.do("DELETE", config.StorageClassURLV2+"/"+name, nil, nil)
+}
@@ -0,0 +1,242 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package client
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/storage"
+)
+
+func newTestClient(t *testing.T, url string) (*Client, *[]time.Duration) {
+	c, err := NewClient(&Config{Address: url})
+	if err != nil {
+		t.Fatal("Unable to create client:  ", err)
+	}
+	waits := make([]time.Duration, 0)
+	c.sleep = func(d time.Duration) {
+		waits = append(waits, d)
+	}
+	return c, &waits
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func TestNewClient(t *testing.T) {
+	if _, err := NewClient(&Config{}); err == nil {
+		t.Error("Created a client without an address.")
+	}
+	c, err := NewClient(&Config{Address: "10.0.0.1:8000/", MaxRetries: -1})
+	if err != nil {
+		t.Fatal("Unable to create client:  ", err)
+	}
+	if c.baseURL != "http://10.0.0.1:8000" || c.maxRetries != 0 ||
+		c.retryInterval != DefaultRetryInterval ||
+		c.httpClient.Timeout != DefaultTimeout {
+		t.Errorf("Wrong client settings:  %v", c)
+	}
+}
+
+func TestListPages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != config.VolumeURLV2 ||
+				r.URL.Query().Get("metadata") != "team=web" {
+				writeJSON(w, http.StatusBadRequest, nil)
+				return
+			}
+			if r.URL.Query().Get("continue") == "" {
+				writeJSON(w, http.StatusOK, map[string]interface{}{
+					"items": []string{"vol1", "vol2"},
+					"pagination": map[string]interface{}{
+						"total":    3,
+						"continue": "2",
+					},
+				})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"items":      []string{"vol3"},
+				"pagination": map[string]interface{}{"total": 3},
+			})
+		}))
+	defer server.Close()
+	c, _ := newTestClient(t, server.URL)
+	names, err := c.ListVolumes("team=web")
+	if err != nil {
+		t.Fatal("Unable to list volumes:  ", err)
+	}
+	if !reflect.DeepEqual(names, []string{"vol1", "vol2", "vol3"}) {
+		t.Errorf("Expected all three pages' volumes; got %v.", names)
+	}
+}
+
+func TestVolumes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == "GET" &&
+				r.URL.Path == config.VolumeURLV2+"/vol1":
+				writeJSON(w, http.StatusOK, map[string]interface{}{
+					"volume": &storage.VolumeExternal{
+						Config: &storage.VolumeConfig{
+							Name: "vol1",
+							Size: "1073741824",
+						},
+						Backend: "ontap",
+					},
+				})
+			case r.Method == "GET":
+				writeJSON(w, http.StatusNotFound, map[string]interface{}{
+					"error": map[string]string{
+						"code":    CodeNotFound,
+						"message": "Volume vol2 was not found.",
+					},
+				})
+			case r.Method == "POST":
+				body, _ := ioutil.ReadAll(r.Body)
+				volumeConfig := &storage.VolumeConfig{}
+				if err := json.Unmarshal(body, volumeConfig); err != nil ||
+					volumeConfig.Size == "" {
+					writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+						"error": map[string]interface{}{
+							"code":    CodeInvalidInput,
+							"message": "Invalid volume configuration.",
+							"fields": []FieldError{
+								{Field: "size", Message: "is required"},
+							},
+						},
+					})
+					return
+				}
+				writeJSON(w, http.StatusCreated, map[string]string{
+					"volume": volumeConfig.Name,
+				})
+			case r.Method == "DELETE" && r.URL.Query().Get("force") == "true":
+				writeJSON(w, http.StatusOK, map[string]string{})
+			default:
+				writeJSON(w, http.StatusConflict, map[string]interface{}{
+					"error": map[string]string{
+						"code":    CodeConflict,
+						"message": "Volume vol1 is attached.",
+					},
+				})
+			}
+		}))
+	defer server.Close()
+	c, waits := newTestClient(t, server.URL)
+
+	volume, err := c.GetVolume("vol1")
+	if err != nil {
+		t.Fatal("Unable to get volume:  ", err)
+	}
+	if volume.Config.Name != "vol1" || volume.Backend != "ontap" {
+		t.Errorf("Wrong volume:  %v", volume)
+	}
+	if _, err = c.GetVolume("vol2"); !IsNotFound(err) ||
+		err.Error() != "Volume vol2 was not found." {
+		t.Errorf("Expected a NotFound error; got %v.", err)
+	}
+
+	name, err := c.CreateVolume(&storage.VolumeConfig{
+		Name: "vol3",
+		Size: "1GB",
+	})
+	if err != nil || name != "vol3" {
+		t.Errorf("Unable to create volume:  %s, %v", name, err)
+	}
+	_, err = c.CreateVolume(&storage.VolumeConfig{Name: "vol4"})
+	apiErr, ok := err.(*Error)
+	if !ok || apiErr.StatusCode != http.StatusBadRequest ||
+		apiErr.Code != CodeInvalidInput || len(apiErr.Fields) != 1 ||
+		apiErr.Fields[0].Field != "size" {
+		t.Errorf("Expected an invalid input error; got %v.", err)
+	}
+
+	if err = c.DeleteVolume("vol1", false); !IsConflict(err) {
+		t.Errorf("Expected a conflict; got %v.", err)
+	}
+	if err = c.DeleteVolume("vol1", true); err != nil {
+		t.Error("Unable to force volume deletion:  ", err)
+	}
+	if len(*waits) != 0 {
+		t.Errorf("Requests that failed for good were retried:  %v", *waits)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			switch {
+			case r.Method == "POST":
+				// Unavailable, but not rejected for a retry.
+				writeJSON(w, http.StatusServiceUnavailable,
+					map[string]interface{}{
+						"error": map[string]string{
+							"code":    CodeUnavailable,
+							"message": "No backend can hold the volume.",
+						},
+					})
+			case requests <= 2:
+				w.Header().Set("Retry-After", "5")
+				writeJSON(w, http.StatusServiceUnavailable,
+					map[string]interface{}{
+						"error": map[string]string{
+							"code":    CodeUnavailable,
+							"message": "Trident is bootstrapping.",
+						},
+					})
+			default:
+				writeJSON(w, http.StatusOK, map[string]string{
+					"version": config.OrchestratorVersion,
+				})
+			}
+		}))
+	defer server.Close()
+	c, waits := newTestClient(t, server.URL)
+
+	version, err := c.GetVersion()
+	if err != nil || version != config.OrchestratorVersion {
+		t.Errorf("Expected version %s; got %s, %v.",
+			config.OrchestratorVersion, version, err)
+	}
+	if !reflect.DeepEqual(*waits, []time.Duration{5 * time.Second,
+		5 * time.Second}) {
+		t.Errorf("Expected two retries after 5s; got %v.", *waits)
+	}
+
+	requests = 0
+	if _, err = c.CreateVolume(&storage.VolumeConfig{
+		Name: "vol1",
+	}); GetType(err) == "" || requests != 1 {
+		t.Errorf("Expected one unavailable request; got %d, %v.",
+			requests, err)
+	}
+
+	server.Close()
+	*waits = (*waits)[:0]
+	if _, err = c.ListBackends(); err == nil {
+		t.Error("Listed backends from a closed server.")
+	}
+	if len(*waits) != DefaultMaxRetries {
+		t.Errorf("Expected %d retries; got %v.", DefaultMaxRetries, *waits)
+	}
+	*waits = (*waits)[:0]
+	if err = c.DeleteBackend("ontap", false); err == nil || len(*waits) != 0 {
+		t.Errorf("Expected a failed delete without retries; got %v, %v.",
+			err, *waits)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/netapp/trident/errors"
+)
+
+// Error codes reported by Trident's v2 API.
+const (
+	CodeInvalidJSON     = "InvalidJSON"
+	CodeInvalidInput    = "InvalidInput"
+	CodeNotFound        = "NotFound"
+	CodeAlreadyExists   = "AlreadyExists"
+	CodeConflict        = "Conflict"
+	CodeOperationFailed = "OperationFailed"
+	CodeInternal        = "InternalError"
+	CodeRateLimited     = "RateLimited"
+	CodeUnavailable     = "Unavailable"
+)
+
+// FieldError explains what is wrong with a field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is a failed request, as reported by Trident.
+type Error struct {
+	// StatusCode is the request's HTTP status.
+	StatusCode int
+	// Code is the v2 API's error code, or "" if Trident didn't report one,
+	// e.g., because a proxy failed the request.
+	Code    string
+	Message string
+	Fields  []FieldError
+	// Details explain the error further, e.g., why no storage pool could
+	// hold a volume.
+	Details json.RawMessage
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Type returns the orchestrator error type that the error's code reports,
+// or "" if there is none.
+func (e *Error) Type() errors.Type {
+	switch e.Code {
+	case CodeNotFound:
+		return errors.NotFound
+	case CodeAlreadyExists:
+		return errors.AlreadyExists
+	case CodeConflict:
+		return errors.Conflict
+	case CodeInvalidInput, CodeInvalidJSON:
+		return errors.InvalidInput
+	case CodeUnavailable:
+		return errors.BackendUnavailable
+	}
+	return ""
+}
+
+// GetType returns the orchestrator error type of err, or "" if it has none
+// or wasn't reported by Trident.
+func GetType(err error) errors.Type {
+	if apiErr, ok := err.(*Error); ok {
+		return apiErr.Type()
+	}
+	return errors.GetType(err)
+}
+
+// IsNotFound returns true if err reports that a named object doesn't exist.
+func IsNotFound(err error) bool {
+	return GetType(err) == errors.NotFound
+}
+
+// IsAlreadyExists returns true if err reports that an object with the same
+// name already exists.
+func IsAlreadyExists(err error) bool {
+	return GetType(err) == errors.AlreadyExists
+}
+
+// IsConflict returns true if err reports that an object's state prevents
+// the request, e.g., deleting an attached volume.
+func IsConflict(err error) bool {
+	return GetType(err) == errors.Conflict
+}
+
+// errorBody is the body of a failed v2 request.
+type errorBody struct {
+	Error *struct {
+		Code    string          `json:"code"`
+		Message string          `json:"message"`
+		Fields  []FieldError    `json:"fields"`
+		Details json.RawMessage `json:"details"`
+	} `json:"error"`
+}
+
+// newError returns the error reported by a failed request's body, or a
+// generic one if the body doesn't hold a v2 error.
+func newError(method, url, status string, statusCode int, body []byte) error {
+	apiErr := &Error{StatusCode: statusCode}
+	response := &errorBody{}
+	if err := json.Unmarshal(body, response); err == nil &&
+		response.Error != nil && response.Error.Message != "" {
+		apiErr.Code = response.Error.Code
+		apiErr.Message = response.Error.Message
+		apiErr.Fields = response.Error.Fields
+		apiErr.Details = response.Error.Details
+		return apiErr
+	}
+	apiErr.Message = fmt.Sprintf("%s %s returned %s.", method, url, status)
+	return apiErr
+}
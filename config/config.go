@@ -3,6 +3,7 @@
 package config
 
 import (
+	"sort"
 	"strings"
 	"time"
 )
@@ -10,14 +11,70 @@ import (
 type Protocol string
 type AccessMode string
 type VolumeType string
+type ReclaimPolicy string
 
 const (
 	/* Misc. orchestrator constants */
-	OrchestratorName       = "trident"
-	OrchestratorVersion    = "17.04.0"
-	OrchestratorAPIVersion = "1"
-	PersistentStoreTimeout = 60 * time.Second
-	MaxBootstrapAttempts   = 10
+	OrchestratorName         = "trident"
+	OrchestratorVersion      = "17.04.0"
+	OrchestratorAPIVersion   = "1"
+	OrchestratorAPIVersionV2 = "2"
+	PersistentStoreTimeout   = 60 * time.Second
+
+	/* Bootstrap defaults; see BootstrapConfig */
+	MaxBootstrapAttempts     = 10
+	BootstrapWorkers         = 16
+	BackendInitRetryInterval = 30 * time.Second
+
+	/* Operation timeout defaults; see TimeoutConfig */
+	ProvisionTimeout  = 5 * time.Minute
+	DeleteTimeout     = 5 * time.Minute
+	BackendAddTimeout = 2 * time.Minute
+
+	/* Transaction janitor defaults; see JanitorConfig */
+	JanitorInterval    = 5 * time.Minute
+	JanitorMinAge      = 10 * time.Minute
+	JanitorMaxAttempts = 3
+
+	// BackendWatchInterval is the default interval between checks of the
+	// backend configs watched; see BackendWatcherConfig.
+	BackendWatchInterval = 30 * time.Second
+
+	/* Usage reporting defaults; see UsageReportConfig */
+	UsageReportInterval  = time.Hour
+	UsageReportTimeout   = 30 * time.Second
+	UsageReportTenantKey = "tenant"
+
+	/* Tracing defaults; see TracingConfig */
+	TracingFlushInterval = time.Second
+	TracingTimeout       = 10 * time.Second
+
+	// VolumeExpiryGracePeriod is the default time between a volume's
+	// expiry and its deletion; see VolumeExpiryConfig.
+	VolumeExpiryGracePeriod = time.Hour
+
+	// HealthCheckTimeout bounds the persistent store request made by each
+	// health check.
+	HealthCheckTimeout = 5 * time.Second
+
+	// ReaperInterval is the interval between checks for terminating
+	// volumes whose deletion grace period has ended, and for expired
+	// volumes.
+	ReaperInterval = time.Minute
+
+	// RebalanceInterval is the interval between analyses of how each
+	// storage class's volumes are spread across its backends.
+	RebalanceInterval = 15 * time.Minute
+
+	// CredentialRotationCheckInterval is the interval between checks for
+	// backends whose passwords are due to be rotated.
+	CredentialRotationCheckInterval = 5 * time.Minute
+
+	// ProvisioningRetryBackoff is the wait after a volume's first failed
+	// provisioning attempt; it doubles with each further failure, up to
+	// MaxProvisioningRetryBackoff.
+	ProvisioningRetryBackoff    = 30 * time.Second
+	MaxProvisioningRetryBackoff = 15 * time.Minute
 
 	/* Protocol constants */
 	File                Protocol = "file"
@@ -31,6 +88,14 @@ const (
 	ReadWriteMany AccessMode = "ReadWriteMany"
 	ModeAny       AccessMode = ""
 
+	/* Reclaim policy constants */
+	ReclaimDelete ReclaimPolicy = "Delete"
+	ReclaimRetain ReclaimPolicy = "Retain"
+	ReclaimAny    ReclaimPolicy = ""
+
+	/* Filesystem constants */
+	DefaultFSType = "ext4"
+
 	/* Volume type constants */
 	ONTAP_NFS         VolumeType = "ONTAP_NFS"
 	ONTAP_iSCSI       VolumeType = "ONTAP_iSCSI"
@@ -46,6 +111,9 @@ const (
 
 	/* REST frontend constants */
 	MaxRESTRequestSize = 10240
+
+	/* Kubernetes frontend constants */
+	KubernetesFrontendName = "kubernetes"
 )
 
 var (
@@ -54,13 +122,52 @@ var (
 		Block:       true,
 		ProtocolAny: true,
 	}
+	validFSTypes = map[string]bool{
+		"ext3": true,
+		"ext4": true,
+		"xfs":  true,
+	}
+	/* Build variables, set by the Makefile with the linker's -X flag */
+	GitSHA    = "unknown"
+	BuildDate = "unknown"
+
 	/* API Server and persistent store variables */
 	OrchestratorMajorVersion = getMajorVersion(OrchestratorVersion)
+	StoreSchemaVersion       = OrchestratorMajorVersion
 	VersionURL               = "/" + OrchestratorName + "/v" + OrchestratorAPIVersion + "/version"
 	BackendURL               = "/" + OrchestratorName + "/v" + OrchestratorAPIVersion + "/backend"
 	VolumeURL                = "/" + OrchestratorName + "/v" + OrchestratorAPIVersion + "/volume"
 	TransactionURL           = "/" + OrchestratorName + "/v" + OrchestratorAPIVersion + "/txn"
+	BackendTransactionURL    = "/" + OrchestratorName + "/v" + OrchestratorAPIVersion + "/backendtxn"
+	RetainedVolumeURL        = "/" + OrchestratorName + "/v" + OrchestratorAPIVersion + "/retained"
+	ProvisioningRetryURL     = "/" + OrchestratorName + "/v" + OrchestratorAPIVersion + "/retry"
+	ZombieVolumeURL          = "/" + OrchestratorName + "/v" + OrchestratorAPIVersion + "/zombie"
+	RebalanceURL             = "/" + OrchestratorName + "/v" + OrchestratorAPIVersion + "/rebalance"
 	StorageClassURL          = "/" + OrchestratorName + "/v" + OrchestratorAPIVersion + "/storageclass"
+	EventsURL                = "/" + OrchestratorName + "/v" + OrchestratorAPIVersion + "/events"
+	HookURL                  = "/" + OrchestratorName + "/v" + OrchestratorAPIVersion + "/hook"
+	SnapshotPolicyURL        = "/" + OrchestratorName + "/v" + OrchestratorAPIVersion + "/snapshotpolicy"
+	FailedURL                = "/" + OrchestratorName + "/v" + OrchestratorAPIVersion + "/failed"
+	LoggingURL               = "/" + OrchestratorName + "/v" + OrchestratorAPIVersion + "/logging"
+	DebugURL                 = "/" + OrchestratorName + "/v" + OrchestratorAPIVersion + "/debug"
+	StateURL                 = "/" + OrchestratorName + "/v" + OrchestratorAPIVersion + "/state"
+	VolumeGroupURL           = "/" + OrchestratorName + "/v" + OrchestratorAPIVersion + "/volumegroup"
+	TemplateURL              = "/" + OrchestratorName + "/v" + OrchestratorAPIVersion + "/template"
+	NamespacePolicyURL       = "/" + OrchestratorName + "/v" + OrchestratorAPIVersion + "/namespacepolicy"
+	SupportBundleURL         = "/" + OrchestratorName + "/v" + OrchestratorAPIVersion + "/supportbundle"
+	LogsURL                  = "/" + OrchestratorName + "/v" + OrchestratorAPIVersion + "/logs"
+	// HealthURL and ReadyURL are unversioned, for probes.
+	HealthURL = "/healthz"
+	ReadyURL  = "/readyz"
+	// APIDocumentURL serves the OpenAPI document describing the REST API.
+	APIDocumentURL = "/swagger.json"
+
+	/* API Server v2 variables */
+	VersionURLV2      = "/" + OrchestratorName + "/v" + OrchestratorAPIVersionV2 + "/version"
+	BackendURLV2      = "/" + OrchestratorName + "/v" + OrchestratorAPIVersionV2 + "/backend"
+	VolumeURLV2       = "/" + OrchestratorName + "/v" + OrchestratorAPIVersionV2 + "/volume"
+	StorageClassURLV2 = "/" + OrchestratorName + "/v" + OrchestratorAPIVersionV2 + "/storageclass"
+	EventsURLV2       = "/" + OrchestratorName + "/v" + OrchestratorAPIVersionV2 + "/events"
 )
 
 func IsValidProtocol(p Protocol) bool {
@@ -76,6 +183,24 @@ func GetValidProtocolNames() []string {
 	return ret
 }
 
+// IsValidReclaimPolicy returns true if p is Delete, Retain, or unset.
+func IsValidReclaimPolicy(p ReclaimPolicy) bool {
+	return p == ReclaimDelete || p == ReclaimRetain || p == ReclaimAny
+}
+
+func IsValidFSType(fsType string) bool {
+	return validFSTypes[fsType]
+}
+
+func GetValidFSTypes() []string {
+	ret := make([]string, 0, len(validFSTypes))
+	for key := range validFSTypes {
+		ret = append(ret, key)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
 func getMajorVersion(version string) string {
 	tmp := strings.Split(version, ".")
 	if len(tmp) == 1 {
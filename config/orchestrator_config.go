@@ -0,0 +1,634 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/netapp/trident/faults"
+)
+
+const (
+	/* Volume placement policies */
+	// PlacementRandom tries a storage class's pools in random order, for
+	// better distribution of load across all backends.
+	PlacementRandom = "random"
+	// PlacementOrdered tries a storage class's pools in order of backend
+	// and pool name, so that placement is predictable.
+	PlacementOrdered = "ordered"
+
+	/* Volume expiry actions */
+	// ExpiryDelete deletes expired volumes once the expiry grace period
+	// ends.
+	ExpiryDelete = "delete"
+	// ExpiryFlag only flags expired volumes, leaving their deletion to an
+	// administrator or an expiry hook.
+	ExpiryFlag = "flag"
+
+	DefaultPort      = "8000"
+	DefaultLogLevel  = "info"
+	DefaultLogFormat = "text"
+)
+
+var (
+	validPlacementPolicies = map[string]bool{
+		PlacementRandom:  true,
+		PlacementOrdered: true,
+	}
+	validLogLevels = map[string]bool{
+		"debug":   true,
+		"info":    true,
+		"warn":    true,
+		"warning": true,
+		"error":   true,
+		"fatal":   true,
+		"panic":   true,
+	}
+)
+
+// OrchestratorConfig holds the settings for a Trident process.  It is read
+// from a YAML or JSON file, and any command-line flags override it.
+type OrchestratorConfig struct {
+	// EtcdV2 is the etcd server (v2 API) used to persist state.
+	EtcdV2 string `json:"etcdV2,omitempty"`
+	// NoPersistence keeps state in memory only.
+	NoPersistence bool `json:"noPersistence,omitempty"`
+	// K8sAPIServer enables the Kubernetes frontend for the given API server.
+	K8sAPIServer string `json:"k8sAPIServer,omitempty"`
+	// K8sPod enables the Kubernetes frontend when running in a pod.
+	K8sPod bool `json:"k8sPod,omitempty"`
+	// Address is the address the REST API listens on; empty for all.
+	Address string    `json:"address,omitempty"`
+	Port    string    `json:"port,omitempty"`
+	TLS     TLSConfig `json:"tls,omitempty"`
+	// LogLevel is one of debug, info, warn, error, fatal, or panic.
+	LogLevel string `json:"logLevel,omitempty"`
+	// SubsystemLogLevels overrides LogLevel for the core, persistent_store,
+	// drivers, and frontends subsystems.
+	SubsystemLogLevels map[string]string `json:"subsystemLogLevels,omitempty"`
+	// LogFormat is text or json.
+	LogFormat string `json:"logFormat,omitempty"`
+	// LogBufferSize is the number of recent log entries kept in memory
+	// for the logs API; 0 keeps the default.
+	LogBufferSize int             `json:"logBufferSize,omitempty"`
+	RateLimit     RateLimitConfig `json:"rateLimit,omitempty"`
+	Bootstrap     BootstrapConfig `json:"bootstrap,omitempty"`
+	Timeouts      TimeoutConfig   `json:"timeouts,omitempty"`
+	Janitor       JanitorConfig   `json:"janitor,omitempty"`
+	// SecretKeyFile, if set, holds a base64-encoded AES key with which
+	// backend secrets are encrypted in the persistent store.
+	SecretKeyFile string `json:"secretKeyFile,omitempty"`
+	// DeletionGracePeriod is a duration (e.g., 24h) for which deleted
+	// volumes are kept on their backends, renamed, and can be undeleted.
+	// Volumes are deleted immediately if it is unset.
+	DeletionGracePeriod string `json:"deletionGracePeriod,omitempty"`
+	// PlacementPolicy is the order in which a storage class's pools are
+	// tried for a new volume; see PlacementRandom and PlacementOrdered.
+	PlacementPolicy string `json:"placementPolicy,omitempty"`
+	// PlacementSeed, if set, seeds the random placement policy, so that
+	// placement can be reproduced, e.g., when debugging.
+	PlacementSeed int64 `json:"placementSeed,omitempty"`
+	// StrictStorageClasses rejects storage classes requesting attribute
+	// values their schemas don't allow, rather than adding them with
+	// warnings.
+	StrictStorageClasses bool `json:"strictStorageClasses,omitempty"`
+	// ProtectStorageClasses refuses to delete storage classes that volumes
+	// still use unless forced, marking them pending deletion instead.
+	ProtectStorageClasses bool `json:"protectStorageClasses,omitempty"`
+	// FaultPoints arms failure points, keyed by point, for testing recovery;
+	// see the faults package.  Never set it in production.
+	FaultPoints map[string]string `json:"faultPoints,omitempty"`
+	// BackendWatcher, if set, keeps the backends in line with a directory
+	// or ConfigMap of backend configs.
+	BackendWatcher BackendWatcherConfig `json:"backendWatcher,omitempty"`
+	// UsageReport, if set, periodically reports the space volumes use.
+	UsageReport UsageReportConfig `json:"usageReport,omitempty"`
+	// VolumeExpiry controls what happens to volumes whose TTL has passed.
+	VolumeExpiry VolumeExpiryConfig `json:"volumeExpiry,omitempty"`
+	// Tracing, if set, records spans for orchestrator operations, store
+	// requests, and driver calls.
+	Tracing TracingConfig `json:"tracing,omitempty"`
+}
+
+// GracePeriod returns the deletion grace period, or zero if deleted
+// volumes are deleted immediately.
+func (c *OrchestratorConfig) GracePeriod() time.Duration {
+	return parseDuration(c.DeletionGracePeriod, 0)
+}
+
+// TLSConfig enables HTTPS for the REST API.
+type TLSConfig struct {
+	CertFile string `json:"certFile,omitempty"`
+	KeyFile  string `json:"keyFile,omitempty"`
+	// ClientCAFile, if set, requires REST clients to present certificates
+	// signed by one of its CAs.
+	ClientCAFile string `json:"clientCAFile,omitempty"`
+}
+
+// IsSet returns true if HTTPS is enabled.
+func (c *TLSConfig) IsSet() bool {
+	return c.CertFile != ""
+}
+
+// RateLimitConfig throttles REST requests.  Rates are in requests per
+// second; zero means no limit.
+type RateLimitConfig struct {
+	Rate  float64 `json:"rate,omitempty"`
+	Burst int     `json:"burst,omitempty"`
+	// RouteRates overrides Rate for individual routes, as a list of
+	// route=rate pairs (e.g., AddVolume=2,AddBackend=0.5).
+	RouteRates              string `json:"routeRates,omitempty"`
+	MaxInFlightProvisioning int    `json:"maxInFlightProvisioning,omitempty"`
+}
+
+// IsSet returns true if any limit is set.
+func (c *RateLimitConfig) IsSet() bool {
+	return c.Rate > 0 || c.RouteRates != "" || c.MaxInFlightProvisioning > 0
+}
+
+// BootstrapConfig controls how stored state is loaded at startup.
+type BootstrapConfig struct {
+	// MaxAttempts is how many times, a second apart, to try reaching the
+	// persistent store.
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+	// Workers bounds how many backends are initialized, how many stored
+	// records are read, and how many volumes are rebuilt, concurrently.
+	Workers int `json:"workers,omitempty"`
+	// Resilient quarantines corrupt stored records instead of failing.
+	Resilient bool `json:"resilient,omitempty"`
+	// DeferBackendInit retries backends whose storage systems are
+	// unreachable in the background instead of failing.
+	DeferBackendInit bool `json:"deferBackendInit,omitempty"`
+	// BackendInitRetryInterval is a duration (e.g., 30s) between retries of
+	// deferred backends.
+	BackendInitRetryInterval string `json:"backendInitRetryInterval,omitempty"`
+}
+
+// RetryInterval returns the interval between retries of deferred backends.
+func (c *BootstrapConfig) RetryInterval() time.Duration {
+	interval, err := time.ParseDuration(c.BackendInitRetryInterval)
+	if err != nil || interval <= 0 {
+		return BackendInitRetryInterval
+	}
+	return interval
+}
+
+func (c *BootstrapConfig) Validate() error {
+	if c.MaxAttempts < 0 {
+		return fmt.Errorf("bootstrap.maxAttempts must not be negative.")
+	}
+	if c.Workers < 1 {
+		return fmt.Errorf("bootstrap.workers must be at least 1.")
+	}
+	if c.BackendInitRetryInterval != "" {
+		interval, err := time.ParseDuration(c.BackendInitRetryInterval)
+		if err != nil {
+			return fmt.Errorf("Invalid bootstrap.backendInitRetryInterval:  "+
+				"%v", err)
+		}
+		if interval <= 0 {
+			return fmt.Errorf("bootstrap.backendInitRetryInterval must be " +
+				"positive.")
+		}
+	}
+	return nil
+}
+
+// TimeoutConfig bounds how long each kind of operation may take, as
+// durations (e.g., 90s).  An operation that times out fails, releasing the
+// orchestrator, even if the storage system never responds.
+type TimeoutConfig struct {
+	// Provision bounds volume creation, including any hooks.
+	Provision string `json:"provision,omitempty"`
+	// Delete bounds volume deletion.
+	Delete string `json:"delete,omitempty"`
+	// BackendAdd bounds adding or updating a backend, including connecting
+	// to its storage system.
+	BackendAdd string `json:"backendAdd,omitempty"`
+	// Store bounds each persistent store request.
+	Store string `json:"store,omitempty"`
+}
+
+func parseDuration(
+	duration string, defaultDuration time.Duration,
+) time.Duration {
+	d, err := time.ParseDuration(duration)
+	if err != nil || d <= 0 {
+		return defaultDuration
+	}
+	return d
+}
+
+func (c *TimeoutConfig) ProvisionTimeout() time.Duration {
+	return parseDuration(c.Provision, ProvisionTimeout)
+}
+
+func (c *TimeoutConfig) DeleteTimeout() time.Duration {
+	return parseDuration(c.Delete, DeleteTimeout)
+}
+
+func (c *TimeoutConfig) BackendAddTimeout() time.Duration {
+	return parseDuration(c.BackendAdd, BackendAddTimeout)
+}
+
+func (c *TimeoutConfig) StoreTimeout() time.Duration {
+	return parseDuration(c.Store, PersistentStoreTimeout)
+}
+
+func (c *TimeoutConfig) Validate() error {
+	for _, t := range []struct {
+		name, value string
+	}{
+		{"provision", c.Provision},
+		{"delete", c.Delete},
+		{"backendAdd", c.BackendAdd},
+		{"store", c.Store},
+	} {
+		if t.value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(t.value)
+		if err != nil {
+			return fmt.Errorf("Invalid timeouts.%s:  %v", t.name, err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("timeouts.%s must be positive.", t.name)
+		}
+	}
+	return nil
+}
+
+// JanitorConfig controls the background resolution of volume transactions
+// left behind by failed operations, which are otherwise only resolved at
+// bootstrap.
+type JanitorConfig struct {
+	// Interval is a duration (e.g., 5m) between checks for transactions.
+	Interval string `json:"interval,omitempty"`
+	// MinAge is how old a transaction must be before it is resolved.
+	MinAge string `json:"minAge,omitempty"`
+	// MaxAttempts is how many times resolving a transaction may fail before
+	// it is left for an administrator.
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+}
+
+func (c *JanitorConfig) IntervalDuration() time.Duration {
+	return parseDuration(c.Interval, JanitorInterval)
+}
+
+func (c *JanitorConfig) MinAgeDuration() time.Duration {
+	return parseDuration(c.MinAge, JanitorMinAge)
+}
+
+func (c *JanitorConfig) Validate() error {
+	for _, d := range []struct {
+		name, value string
+	}{
+		{"interval", c.Interval},
+		{"minAge", c.MinAge},
+	} {
+		if d.value == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(d.value)
+		if err != nil {
+			return fmt.Errorf("Invalid janitor.%s:  %v", d.name, err)
+		}
+		if duration <= 0 {
+			return fmt.Errorf("janitor.%s must be positive.", d.name)
+		}
+	}
+	if c.MaxAttempts < 1 {
+		return fmt.Errorf("janitor.maxAttempts must be at least 1.")
+	}
+	return nil
+}
+
+// BackendWatcherConfig makes Trident add, update, and offline backends to
+// match a set of backend configs, e.g., kept in version control.  At most
+// one of Directory and ConfigMap may be set.
+type BackendWatcherConfig struct {
+	// Directory holds one backend config per *.json file.  A mounted
+	// ConfigMap may be used.
+	Directory string `json:"directory,omitempty"`
+	// ConfigMap is a Kubernetes ConfigMap, named as <namespace>/<name>,
+	// each of whose keys holds a backend config.  It requires the
+	// Kubernetes frontend.
+	ConfigMap string `json:"configMap,omitempty"`
+	// Interval is a duration (e.g., 30s) between checks of the configs.
+	Interval string `json:"interval,omitempty"`
+}
+
+// IsSet returns true if backend configs are watched.
+func (c *BackendWatcherConfig) IsSet() bool {
+	return c.Directory != "" || c.ConfigMap != ""
+}
+
+func (c *BackendWatcherConfig) IntervalDuration() time.Duration {
+	return parseDuration(c.Interval, BackendWatchInterval)
+}
+
+func (c *BackendWatcherConfig) Validate() error {
+	if c.Directory != "" && c.ConfigMap != "" {
+		return fmt.Errorf("Cannot watch both a directory and a ConfigMap " +
+			"of backend configs.")
+	}
+	if c.ConfigMap != "" {
+		parts := strings.SplitN(c.ConfigMap, "/", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("backendWatcher.configMap %q must be named "+
+				"as <namespace>/<name>.", c.ConfigMap)
+		}
+	}
+	if c.Interval != "" {
+		interval, err := time.ParseDuration(c.Interval)
+		if err != nil {
+			return fmt.Errorf("Invalid backendWatcher.interval:  %v", err)
+		}
+		if interval <= 0 {
+			return fmt.Errorf("backendWatcher.interval must be positive.")
+		}
+	}
+	return nil
+}
+
+// UsageReportConfig makes Trident report the space its volumes use, by
+// volume, storage class, and tenant, once per interval, for chargeback.
+// Records are appended to File, posted to URL, or both.
+type UsageReportConfig struct {
+	File string `json:"file,omitempty"`
+	// Format is csv or json, for File.
+	Format string `json:"format,omitempty"`
+	URL    string `json:"url,omitempty"`
+	// Interval is a duration (e.g., 1h) between reports.
+	Interval string `json:"interval,omitempty"`
+	// TenantKey is the volume metadata key whose value names a volume's
+	// tenant.
+	TenantKey string `json:"tenantKey,omitempty"`
+}
+
+// IsSet returns true if usage is reported.
+func (c *UsageReportConfig) IsSet() bool {
+	return c.File != "" || c.URL != ""
+}
+
+func (c *UsageReportConfig) IntervalDuration() time.Duration {
+	return parseDuration(c.Interval, UsageReportInterval)
+}
+
+func (c *UsageReportConfig) Validate() error {
+	if c.File != "" && c.Format != "csv" && c.Format != "json" {
+		return fmt.Errorf("Invalid usageReport.format %q; must be csv or "+
+			"json.", c.Format)
+	}
+	if c.URL != "" && !strings.HasPrefix(c.URL, "http://") &&
+		!strings.HasPrefix(c.URL, "https://") {
+		return fmt.Errorf("usageReport.url %q must be an HTTP or HTTPS URL.",
+			c.URL)
+	}
+	if c.Interval != "" {
+		interval, err := time.ParseDuration(c.Interval)
+		if err != nil {
+			return fmt.Errorf("Invalid usageReport.interval:  %v", err)
+		}
+		if interval <= 0 {
+			return fmt.Errorf("usageReport.interval must be positive.")
+		}
+	}
+	if c.IsSet() && c.TenantKey == "" {
+		return fmt.Errorf("usageReport.tenantKey must not be empty.")
+	}
+	return nil
+}
+
+// VolumeExpiryConfig controls the handling of volumes that expire, i.e.,
+// whose TTL or expiry time has passed.  Expired volumes are flagged, and
+// expiry hooks notified, when they are found; they are deleted when
+// GracePeriod ends if Action is ExpiryDelete.
+type VolumeExpiryConfig struct {
+	// Action is ExpiryDelete or ExpiryFlag.
+	Action string `json:"action,omitempty"`
+	// GracePeriod is a duration (e.g., 1h) between a volume's expiry and
+	// its deletion, during which its expiry may be extended.
+	GracePeriod string `json:"gracePeriod,omitempty"`
+}
+
+func (c *VolumeExpiryConfig) GracePeriodDuration() time.Duration {
+	return parseDuration(c.GracePeriod, VolumeExpiryGracePeriod)
+}
+
+func (c *VolumeExpiryConfig) Validate() error {
+	if c.Action != ExpiryDelete && c.Action != ExpiryFlag {
+		return fmt.Errorf("Invalid volumeExpiry.action %q; must be %s or "+
+			"%s.", c.Action, ExpiryDelete, ExpiryFlag)
+	}
+	if c.GracePeriod != "" {
+		gracePeriod, err := time.ParseDuration(c.GracePeriod)
+		if err != nil {
+			return fmt.Errorf("Invalid volumeExpiry.gracePeriod:  %v", err)
+		}
+		if gracePeriod < 0 {
+			return fmt.Errorf("volumeExpiry.gracePeriod must not be " +
+				"negative.")
+		}
+	}
+	return nil
+}
+
+// TracingConfig sets the exporter spans are sent to.  Spans are logged at
+// debug level by the log exporter, or posted in Zipkin's JSON format to URL
+// by the zipkin exporter.
+type TracingConfig struct {
+	// Exporter is log or zipkin; tracing is disabled if it is empty.
+	Exporter string `json:"exporter,omitempty"`
+	// URL is a Zipkin-compatible collector's spans endpoint, for zipkin.
+	URL string `json:"url,omitempty"`
+	// ServiceName is the service spans are recorded for; it defaults to
+	// trident.
+	ServiceName string `json:"serviceName,omitempty"`
+}
+
+// IsSet returns true if tracing is enabled.
+func (c *TracingConfig) IsSet() bool {
+	return c.Exporter != ""
+}
+
+func (c *TracingConfig) Validate() error {
+	switch c.Exporter {
+	case "", "log":
+		if c.URL != "" {
+			return fmt.Errorf("tracing.url only applies to the zipkin " +
+				"exporter.")
+		}
+	case "zipkin":
+		if !strings.HasPrefix(c.URL, "http://") &&
+			!strings.HasPrefix(c.URL, "https://") {
+			return fmt.Errorf("tracing.url %q must be an HTTP or HTTPS URL.",
+				c.URL)
+		}
+	default:
+		return fmt.Errorf("Invalid tracing.exporter %q; must be log or "+
+			"zipkin.", c.Exporter)
+	}
+	return nil
+}
+
+// NewVolumeExpiryConfig returns the default volume expiry settings.
+func NewVolumeExpiryConfig() *VolumeExpiryConfig {
+	return &VolumeExpiryConfig{
+		Action:      ExpiryDelete,
+		GracePeriod: VolumeExpiryGracePeriod.String(),
+	}
+}
+
+// NewJanitorConfig returns the default transaction janitor settings.
+func NewJanitorConfig() *JanitorConfig {
+	return &JanitorConfig{
+		Interval:    JanitorInterval.String(),
+		MinAge:      JanitorMinAge.String(),
+		MaxAttempts: JanitorMaxAttempts,
+	}
+}
+
+// NewTimeoutConfig returns the default timeouts.
+func NewTimeoutConfig() *TimeoutConfig {
+	return &TimeoutConfig{
+		Provision:  ProvisionTimeout.String(),
+		Delete:     DeleteTimeout.String(),
+		BackendAdd: BackendAddTimeout.String(),
+		Store:      PersistentStoreTimeout.String(),
+	}
+}
+
+// NewBootstrapConfig returns the default bootstrap settings.
+func NewBootstrapConfig() *BootstrapConfig {
+	return &BootstrapConfig{
+		MaxAttempts:              MaxBootstrapAttempts,
+		Workers:                  BootstrapWorkers,
+		BackendInitRetryInterval: BackendInitRetryInterval.String(),
+	}
+}
+
+// NewOrchestratorConfig returns the default settings.
+func NewOrchestratorConfig() *OrchestratorConfig {
+	return &OrchestratorConfig{
+		Port:            DefaultPort,
+		LogLevel:        DefaultLogLevel,
+		LogFormat:       DefaultLogFormat,
+		RateLimit:       RateLimitConfig{Burst: 10},
+		Bootstrap:       *NewBootstrapConfig(),
+		Timeouts:        *NewTimeoutConfig(),
+		Janitor:         *NewJanitorConfig(),
+		PlacementPolicy: PlacementRandom,
+		UsageReport: UsageReportConfig{
+			Format:    "csv",
+			Interval:  UsageReportInterval.String(),
+			TenantKey: UsageReportTenantKey,
+		},
+		VolumeExpiry: *NewVolumeExpiryConfig(),
+	}
+}
+
+// LoadOrchestratorConfig reads settings from a YAML or JSON file.  Settings
+// absent from the file keep their defaults.
+func LoadOrchestratorConfig(path string) (*OrchestratorConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read config file:  %v", err)
+	}
+	c := NewOrchestratorConfig()
+	if err = yaml.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("Unable to parse config file %s:  %v", path,
+			err)
+	}
+	return c, nil
+}
+
+// Validate returns an error describing the first invalid setting.
+func (c *OrchestratorConfig) Validate() error {
+	if c.EtcdV2 != "" && c.NoPersistence {
+		return fmt.Errorf("Cannot skip persistence and use etcdV2.")
+	}
+	if c.EtcdV2 == "" && !c.NoPersistence {
+		return fmt.Errorf("Must specify a valid persistent store (currently " +
+			"supporting etcdV2) or no persistence.")
+	}
+	if port, err := strconv.ParseUint(c.Port, 10, 16); err != nil || port == 0 {
+		return fmt.Errorf("Invalid port %q.", c.Port)
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		return fmt.Errorf("Must specify both a TLS certificate and key to " +
+			"enable HTTPS.")
+	}
+	if c.TLS.ClientCAFile != "" && !c.TLS.IsSet() {
+		return fmt.Errorf("Client certificate verification requires a TLS " +
+			"certificate and key.")
+	}
+	if !validLogLevels[strings.ToLower(c.LogLevel)] {
+		return fmt.Errorf("Invalid log level %q.", c.LogLevel)
+	}
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		return fmt.Errorf("Invalid log format %q; must be text or json.",
+			c.LogFormat)
+	}
+	if c.LogBufferSize < 0 {
+		return fmt.Errorf("Log buffer size must not be negative.")
+	}
+	if c.RateLimit.Rate < 0 || c.RateLimit.Burst < 0 ||
+		c.RateLimit.MaxInFlightProvisioning < 0 {
+		return fmt.Errorf("Rate limits must not be negative.")
+	}
+	if c.RateLimit.Rate > 0 && c.RateLimit.Burst == 0 {
+		return fmt.Errorf("A rate limit requires a burst of at least 1.")
+	}
+	if err := c.Bootstrap.Validate(); err != nil {
+		return err
+	}
+	if err := c.Timeouts.Validate(); err != nil {
+		return err
+	}
+	if err := c.Janitor.Validate(); err != nil {
+		return err
+	}
+	if err := c.BackendWatcher.Validate(); err != nil {
+		return err
+	}
+	if err := c.UsageReport.Validate(); err != nil {
+		return err
+	}
+	if err := c.VolumeExpiry.Validate(); err != nil {
+		return err
+	}
+	if err := c.Tracing.Validate(); err != nil {
+		return err
+	}
+	if c.BackendWatcher.ConfigMap != "" && c.K8sAPIServer == "" &&
+		!c.K8sPod {
+		return fmt.Errorf("Watching a ConfigMap of backend configs " +
+			"requires the Kubernetes frontend.")
+	}
+	if c.DeletionGracePeriod != "" {
+		gracePeriod, err := time.ParseDuration(c.DeletionGracePeriod)
+		if err != nil {
+			return fmt.Errorf("Invalid deletionGracePeriod:  %v", err)
+		}
+		if gracePeriod < 0 {
+			return fmt.Errorf("deletionGracePeriod must not be negative.")
+		}
+	}
+	if !validPlacementPolicies[c.PlacementPolicy] {
+		return fmt.Errorf("Invalid placement policy %q; must be %s or %s.",
+			c.PlacementPolicy, PlacementRandom, PlacementOrdered)
+	}
+	for point, mode := range c.FaultPoints {
+		if err := faults.Validate(point, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
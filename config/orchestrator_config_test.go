@@ -0,0 +1,179 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, dir, name, contents string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal("Unable to write config file:  ", err)
+	}
+	return path
+}
+
+func TestLoadOrchestratorConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trident-config")
+	if err != nil {
+		t.Fatal("Unable to create temporary directory:  ", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, path := range []string{
+		writeConfigFile(t, dir, "config.yaml", `
+etcdV2: http://127.0.0.1:8001
+address: 127.0.0.1
+tls:
+  certFile: /etc/trident/cert.pem
+  keyFile: /etc/trident/key.pem
+logLevel: debug
+bootstrap:
+  workers: 4
+  backendInitRetryInterval: 1m
+timeouts:
+  provision: 90s
+placementPolicy: ordered
+`),
+		writeConfigFile(t, dir, "config.json", `{
+	"etcdV2": "http://127.0.0.1:8001",
+	"address": "127.0.0.1",
+	"tls": {
+		"certFile": "/etc/trident/cert.pem",
+		"keyFile": "/etc/trident/key.pem"
+	},
+	"logLevel": "debug",
+	"bootstrap": {"workers": 4, "backendInitRetryInterval": "1m"},
+	"timeouts": {"provision": "90s"},
+	"placementPolicy": "ordered"
+}`),
+	} {
+		c, err := LoadOrchestratorConfig(path)
+		if err != nil {
+			t.Fatalf("Unable to load %s:  %v", path, err)
+		}
+		if err = c.Validate(); err != nil {
+			t.Errorf("%s:  unexpected validation error:  %v", path, err)
+		}
+		if c.EtcdV2 != "http://127.0.0.1:8001" || c.Address != "127.0.0.1" ||
+			!c.TLS.IsSet() || c.LogLevel != "debug" ||
+			c.PlacementPolicy != PlacementOrdered {
+			t.Errorf("%s:  unexpected settings:  %+v", path, c)
+		}
+		if c.Bootstrap.Workers != 4 ||
+			c.Bootstrap.RetryInterval() != time.Minute {
+			t.Errorf("%s:  unexpected bootstrap settings:  %+v", path,
+				c.Bootstrap)
+		}
+		if c.Timeouts.ProvisionTimeout() != 90*time.Second {
+			t.Errorf("%s:  unexpected provision timeout %v.", path,
+				c.Timeouts.ProvisionTimeout())
+		}
+		// Settings absent from the file keep their defaults.
+		if c.Port != DefaultPort || c.RateLimit.Burst != 10 ||
+			c.Bootstrap.MaxAttempts != MaxBootstrapAttempts ||
+			c.Timeouts.DeleteTimeout() != DeleteTimeout {
+			t.Errorf("%s:  defaults not kept:  %+v", path, c)
+		}
+	}
+
+	if _, err = LoadOrchestratorConfig(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Loaded a nonexistent config file.")
+	}
+	bad := writeConfigFile(t, dir, "bad.yaml", "port: [8000")
+	if _, err = LoadOrchestratorConfig(bad); err == nil {
+		t.Error("Loaded an unparsable config file.")
+	}
+}
+
+func TestOrchestratorConfigValidate(t *testing.T) {
+	valid := func() *OrchestratorConfig {
+		c := NewOrchestratorConfig()
+		c.NoPersistence = true
+		return c
+	}
+	if err := valid().Validate(); err != nil {
+		t.Fatal("Default config with no persistence is invalid:  ", err)
+	}
+	for name, modify := range map[string]func(c *OrchestratorConfig){
+		"no store":         func(c *OrchestratorConfig) { c.NoPersistence = false },
+		"two stores":       func(c *OrchestratorConfig) { c.EtcdV2 = "http://etcd:8001" },
+		"bad port":         func(c *OrchestratorConfig) { c.Port = "http" },
+		"zero port":        func(c *OrchestratorConfig) { c.Port = "0" },
+		"cert without key": func(c *OrchestratorConfig) { c.TLS.CertFile = "cert.pem" },
+		"CA without cert":  func(c *OrchestratorConfig) { c.TLS.ClientCAFile = "ca.pem" },
+		"bad log level":    func(c *OrchestratorConfig) { c.LogLevel = "verbose" },
+		"negative rate":    func(c *OrchestratorConfig) { c.RateLimit.Rate = -1 },
+		"rate without burst": func(c *OrchestratorConfig) {
+			c.RateLimit.Rate = 1
+			c.RateLimit.Burst = 0
+		},
+		"negative attempts": func(c *OrchestratorConfig) { c.Bootstrap.MaxAttempts = -1 },
+		"no workers":        func(c *OrchestratorConfig) { c.Bootstrap.Workers = 0 },
+		"bad interval": func(c *OrchestratorConfig) {
+			c.Bootstrap.BackendInitRetryInterval = "soon"
+		},
+		"negative interval": func(c *OrchestratorConfig) {
+			c.Bootstrap.BackendInitRetryInterval = "-1s"
+		},
+		"bad placement": func(c *OrchestratorConfig) { c.PlacementPolicy = "fastest" },
+		"bad timeout":   func(c *OrchestratorConfig) { c.Timeouts.Provision = "forever" },
+		"zero timeout":  func(c *OrchestratorConfig) { c.Timeouts.Store = "0s" },
+		"bad janitor interval": func(c *OrchestratorConfig) {
+			c.Janitor.Interval = "hourly"
+		},
+		"no janitor attempts": func(c *OrchestratorConfig) { c.Janitor.MaxAttempts = 0 },
+		"two backend sources": func(c *OrchestratorConfig) {
+			c.BackendWatcher.Directory = "/etc/trident/backends"
+			c.BackendWatcher.ConfigMap = "trident/backends"
+		},
+		"ConfigMap without Kubernetes": func(c *OrchestratorConfig) {
+			c.BackendWatcher.ConfigMap = "trident/backends"
+		},
+		"bad ConfigMap name": func(c *OrchestratorConfig) {
+			c.K8sPod = true
+			c.BackendWatcher.ConfigMap = "backends"
+		},
+		"bad watch interval": func(c *OrchestratorConfig) {
+			c.BackendWatcher.Directory = "/etc/trident/backends"
+			c.BackendWatcher.Interval = "0s"
+		},
+		"bad usage report format": func(c *OrchestratorConfig) {
+			c.UsageReport.File = "/var/log/trident/usage.csv"
+			c.UsageReport.Format = "xml"
+		},
+		"bad usage report URL": func(c *OrchestratorConfig) {
+			c.UsageReport.URL = "billing.example.com"
+		},
+		"no tenant key": func(c *OrchestratorConfig) {
+			c.UsageReport.URL = "http://billing.example.com/usage"
+			c.UsageReport.TenantKey = ""
+		},
+		"bad expiry action": func(c *OrchestratorConfig) {
+			c.VolumeExpiry.Action = "archive"
+		},
+		"negative expiry grace period": func(c *OrchestratorConfig) {
+			c.VolumeExpiry.GracePeriod = "-1h"
+		},
+		"bad tracing exporter": func(c *OrchestratorConfig) {
+			c.Tracing.Exporter = "jaeger"
+		},
+		"zipkin without URL": func(c *OrchestratorConfig) {
+			c.Tracing.Exporter = "zipkin"
+		},
+		"bad fault point": func(c *OrchestratorConfig) {
+			c.FaultPoints = map[string]string{"nowhere": "error"}
+		},
+	} {
+		c := valid()
+		modify(c)
+		if err := c.Validate(); err == nil {
+			t.Errorf("%s:  expected a validation error.", name)
+		}
+	}
+}
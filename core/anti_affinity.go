@@ -0,0 +1,47 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/netapp/trident/errors"
+	"github.com/netapp/trident/storage"
+)
+
+// antiAffinityBackends returns the backends that a volume's anti-affinity
+// keeps it off, mapped to the reason for each:  those of the volumes it
+// names, and those of the other volumes in its spread group.  It returns an
+// error, along with the backends of the volumes it found, if it names a
+// volume that doesn't exist.  The caller must hold the orchestrator lock.
+func (o *tridentOrchestrator) antiAffinityBackends(
+	volConfig *storage.VolumeConfig,
+) (map[string]string, error) {
+	var err error
+	avoid := make(map[string]string)
+	for _, name := range volConfig.AntiAffinity {
+		vol, ok := o.volumes[name]
+		if !ok {
+			err = errors.Errorf(errors.InvalidInput, "Unknown volume %s in "+
+				"the anti-affinity of volume %s.", name, volConfig.Name)
+			continue
+		}
+		avoid[vol.Backend.Name] = fmt.Sprintf("Backend holds volume %s.",
+			name)
+	}
+	if volConfig.SpreadGroup == "" {
+		return avoid, err
+	}
+	for name, vol := range o.volumes {
+		if name == volConfig.Name ||
+			vol.Config.SpreadGroup != volConfig.SpreadGroup ||
+			vol.Config.Namespace != volConfig.Namespace {
+			continue
+		}
+		if _, ok := avoid[vol.Backend.Name]; !ok {
+			avoid[vol.Backend.Name] = fmt.Sprintf("Backend holds a volume "+
+				"of spread group %s.", volConfig.SpreadGroup)
+		}
+	}
+	return avoid, err
+}
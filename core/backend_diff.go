@@ -0,0 +1,196 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"reflect"
+	"sort"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+
+	"github.com/netapp/trident/errors"
+	"github.com/netapp/trident/storage"
+	"github.com/netapp/trident/storage/factory"
+	sa "github.com/netapp/trident/storage_attribute"
+)
+
+// BackendDiff reports how adding a config would change an existing backend.
+type BackendDiff struct {
+	Backend string `json:"backend"`
+	// PoolsAdded and PoolsRemoved are the storage pools the config adds and
+	// removes, sorted.
+	PoolsAdded   []string `json:"poolsAdded"`
+	PoolsRemoved []string `json:"poolsRemoved"`
+	// PoolsChanged are the pools kept whose attributes change.
+	PoolsChanged []*PoolDiff `json:"poolsChanged"`
+	// StorageClasses are the storage classes that would gain or lose any of
+	// the backend's pools.
+	StorageClasses []*StorageClassDiff `json:"storageClasses"`
+	// Rejected, if set, explains why the update would be rejected, e.g.,
+	// because it removes a pool that has volumes.
+	Rejected string `json:"rejected,omitempty"`
+}
+
+// PoolDiff reports the attributes of a storage pool that a config changes.
+type PoolDiff struct {
+	Pool       string           `json:"pool"`
+	Attributes []*AttributeDiff `json:"attributes"`
+}
+
+// AttributeDiff reports the change to a storage pool's offer of an
+// attribute.  Old is nil if the attribute is added, and New is nil if it's
+// removed.
+type AttributeDiff struct {
+	Attribute string   `json:"attribute"`
+	Old       sa.Offer `json:"old,omitempty"`
+	New       sa.Offer `json:"new,omitempty"`
+}
+
+// StorageClassDiff reports the backend's storage pools that a storage class
+// would gain and lose.
+type StorageClassDiff struct {
+	StorageClass string   `json:"storageClass"`
+	PoolsAdded   []string `json:"poolsAdded"`
+	PoolsRemoved []string `json:"poolsRemoved"`
+}
+
+// DiffStorageBackend reports how adding a config would change an existing
+// backend, without changing it.  The config is initialized as
+// AddStorageBackend would, and checked with the same rules as an update,
+// but an update that would be rejected is reported rather than returned as
+// an error.
+func (o *tridentOrchestrator) DiffStorageBackend(
+	ctx context.Context, backendName, configJSON string,
+) (*BackendDiff, error) {
+	ctx, cancel := context.WithTimeout(ctx, o.timeouts.BackendAddTimeout())
+	defer cancel()
+	var candidate *storage.StorageBackend
+	err := callWithContext(ctx, "Backend initialization", nil, func() error {
+		var initErr error
+		candidate, initErr = factory.NewStorageBackendForConfig(configJSON)
+		return initErr
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// As in ValidateStorageBackend, the candidate is only initialized, so
+	// the orchestrator needn't be locked until it's compared.
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	original, ok := o.backends[backendName]
+	if !ok {
+		if _, failed := o.failedBackends[backendName]; failed {
+			return nil, errors.Errorf(errors.Conflict, "Backend %s failed "+
+				"to initialize; its storage pools are unknown.", backendName)
+		}
+		return nil, errors.Errorf(errors.NotFound,
+			"Backend %s not found.", backendName)
+	}
+	if candidate.Name != backendName {
+		return nil, errors.Errorf(errors.InvalidInput, "The config is for "+
+			"backend %s, not %s.", candidate.Name, backendName)
+	}
+
+	diff := &BackendDiff{
+		Backend:        backendName,
+		PoolsAdded:     make([]string, 0),
+		PoolsRemoved:   make([]string, 0),
+		PoolsChanged:   make([]*PoolDiff, 0),
+		StorageClasses: make([]*StorageClassDiff, 0),
+	}
+	if err = o.validateBackendUpdate(original, candidate); err != nil {
+		diff.Rejected = err.Error()
+	}
+	for name, pool := range candidate.Storage {
+		originalPool, ok := original.Storage[name]
+		if !ok {
+			diff.PoolsAdded = append(diff.PoolsAdded, name)
+			continue
+		}
+		attributes := diffOffers(originalPool.Attributes, pool.Attributes)
+		if len(attributes) > 0 {
+			diff.PoolsChanged = append(diff.PoolsChanged,
+				&PoolDiff{Pool: name, Attributes: attributes})
+		}
+	}
+	for name := range original.Storage {
+		if _, ok := candidate.Storage[name]; !ok {
+			diff.PoolsRemoved = append(diff.PoolsRemoved, name)
+		}
+	}
+	sort.Strings(diff.PoolsAdded)
+	sort.Strings(diff.PoolsRemoved)
+	sort.Sort(byPool(diff.PoolsChanged))
+
+	classNames := make([]string, 0, len(o.storageClasses))
+	for name := range o.storageClasses {
+		classNames = append(classNames, name)
+	}
+	sort.Strings(classNames)
+	for _, name := range classNames {
+		sc := o.storageClasses[name]
+		scDiff := &StorageClassDiff{
+			StorageClass: name,
+			PoolsAdded:   make([]string, 0),
+			PoolsRemoved: make([]string, 0),
+		}
+		for poolName, pool := range candidate.Storage {
+			originalPool, ok := original.Storage[poolName]
+			if sc.Matches(pool) && (!ok || !sc.ContainsPool(originalPool)) {
+				scDiff.PoolsAdded = append(scDiff.PoolsAdded, poolName)
+			}
+		}
+		for poolName, originalPool := range original.Storage {
+			pool, ok := candidate.Storage[poolName]
+			if sc.ContainsPool(originalPool) && (!ok || !sc.Matches(pool)) {
+				scDiff.PoolsRemoved = append(scDiff.PoolsRemoved, poolName)
+			}
+		}
+		if len(scDiff.PoolsAdded) > 0 || len(scDiff.PoolsRemoved) > 0 {
+			sort.Strings(scDiff.PoolsAdded)
+			sort.Strings(scDiff.PoolsRemoved)
+			diff.StorageClasses = append(diff.StorageClasses, scDiff)
+		}
+	}
+	log.WithFields(log.Fields{
+		"backendName":    backendName,
+		"poolsAdded":     diff.PoolsAdded,
+		"poolsRemoved":   diff.PoolsRemoved,
+		"storageClasses": len(diff.StorageClasses),
+	}).Debug("Compared backend config.")
+	return diff, nil
+}
+
+// diffOffers returns the attributes whose offers differ, sorted.
+func diffOffers(old, new map[string]sa.Offer) []*AttributeDiff {
+	names := make([]string, 0, len(old)+len(new))
+	for name := range old {
+		names = append(names, name)
+	}
+	for name := range new {
+		if _, ok := old[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	diffs := make([]*AttributeDiff, 0)
+	for _, name := range names {
+		if !reflect.DeepEqual(old[name], new[name]) {
+			diffs = append(diffs, &AttributeDiff{
+				Attribute: name,
+				Old:       old[name],
+				New:       new[name],
+			})
+		}
+	}
+	return diffs
+}
+
+type byPool []*PoolDiff
+
+func (p byPool) Len() int           { return len(p) }
+func (p byPool) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p byPool) Less(i, j int) bool { return p[i].Pool < p[j].Pool }
@@ -0,0 +1,248 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/netapp/trident/errors"
+	"github.com/netapp/trident/persistent_store"
+	"github.com/netapp/trident/storage"
+)
+
+// RenameBackend changes a backend's name in Trident and in the stored
+// records of its volumes; nothing changes on the storage system.  The new
+// name is kept in place of the one the driver derives from the backend
+// config, so a config added again later should set backendName to it.  A
+// transaction ensures that an interrupted rename is completed when Trident
+// restarts.
+func (o *tridentOrchestrator) RenameBackend(
+	backendName, newName string,
+) (*storage.StorageBackendExternal, error) {
+	if newName == "" {
+		return nil, errors.Errorf(errors.InvalidInput,
+			"The new backend name must not be empty.")
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	backend, ok := o.backends[backendName]
+	if !ok {
+		if _, failed := o.failedBackends[backendName]; failed {
+			return nil, errors.Errorf(errors.Conflict, "Backend %s has not "+
+				"initialized and can't be renamed.", backendName)
+		}
+		return nil, errors.Errorf(errors.NotFound,
+			"Backend %s not found.", backendName)
+	}
+	if backendName == newName {
+		return backend.ConstructExternal(), nil
+	}
+	_, exists := o.backends[newName]
+	if _, failed := o.failedBackends[newName]; exists || failed {
+		return nil, errors.Errorf(errors.AlreadyExists,
+			"Backend %s already exists.", newName)
+	}
+	if volumeName := o.mirroredVolumeOnBackend(backendName); volumeName != "" {
+		return nil, errors.Errorf(errors.Conflict, "Backend %s holds the "+
+			"mirror of volume %s and can't be renamed.", backendName,
+			volumeName)
+	}
+
+	// Volume records are written from volumes that point to the backend
+	// itself, so they follow its name as it changes and is restored.
+	volumes := make([]*storage.Volume, 0)
+	for _, pool := range backend.Storage {
+		for _, vol := range pool.Volumes {
+			volumes = append(volumes, vol)
+		}
+	}
+	orphans := make([]*storage.VolumeExternal, 0)
+	for _, orphan := range o.orphanedVolumes {
+		if orphan.Backend == backendName {
+			orphans = append(orphans, orphan)
+			volumes = append(volumes, volumeRecord(orphan, backend))
+		}
+	}
+
+	backendTxn := &persistent_store.BackendTransaction{
+		Op:      persistent_store.RenameBackend,
+		Name:    newName,
+		OldName: backendName,
+	}
+	if err := o.storeClient.AddBackendTransaction(backendTxn); err != nil {
+		return nil, err
+	}
+	backend.Name = newName
+	if err := o.storeClient.AddBackend(backend); err != nil {
+		backend.Name = backendName
+		if txErr := o.storeClient.DeleteBackendTransaction(
+			backendTxn); txErr != nil {
+			log.WithFields(log.Fields{
+				"backend": backendName,
+			}).Warn("Unable to delete backend rename transaction.")
+		}
+		return nil, err
+	}
+	for i, vol := range volumes {
+		if err := o.storeClient.UpdateVolume(vol); err != nil {
+			backend.Name = backendName
+			o.undoBackendRename(backendTxn, volumes[:i])
+			return nil, err
+		}
+	}
+	// Every record now names the new backend, so the rename is complete on
+	// restart even if the old backend record can't be removed now.
+	if err := o.storeClient.DeleteBackend(
+		&storage.StorageBackend{Name: backendName}); err != nil {
+		log.WithFields(log.Fields{
+			"backend": newName,
+			"oldName": backendName,
+		}).Warnf("Unable to delete the old backend record; it will be "+
+			"removed on restart:  %v", err)
+	} else if err = o.storeClient.DeleteBackendTransaction(
+		backendTxn); err != nil {
+		log.WithFields(log.Fields{
+			"backend": newName,
+		}).Warn("Unable to delete backend rename transaction; it will be " +
+			"resolved on restart.")
+	}
+
+	delete(o.backends, backendName)
+	o.backends[newName] = backend
+	for _, orphan := range orphans {
+		orphan.Backend = newName
+	}
+	// Storage classes may select pools by backend name.
+	for _, pool := range backend.Storage {
+		pool.StorageClasses = []string{}
+	}
+	for _, sc := range o.storageClasses {
+		sc.RemovePoolsForBackend(backend)
+		sc.CheckAndAddBackend(backend)
+	}
+	log.WithFields(log.Fields{
+		"oldName": backendName,
+		"name":    newName,
+		"volumes": len(volumes),
+	}).Info("Renamed backend.")
+	external := backend.ConstructExternal()
+	oldExternal := *external
+	oldExternal.Name = backendName
+	o.markDirty()
+	o.events.publish(EventDelete, EventObjectBackend, backendName,
+		&oldExternal)
+	o.events.publish(EventCreate, EventObjectBackend, newName, external)
+	return external, nil
+}
+
+// volumeRecord returns a volume from which the stored record of v can be
+// written, naming backend in place of the backend v names.
+func volumeRecord(
+	v *storage.VolumeExternal, backend *storage.StorageBackend,
+) *storage.Volume {
+	vol := storage.NewVolume(v.Config, backend,
+		&storage.StoragePool{Name: v.Pool})
+	vol.Attachments = v.Attachments
+	vol.Deletion = v.Deletion
+	vol.Replication = v.Replication
+	return vol
+}
+
+// undoBackendRename restores the stored records of a backend rename that
+// failed partway.  If they can't all be restored, the transaction is kept,
+// so that the rename is completed instead on restart.
+func (o *tridentOrchestrator) undoBackendRename(
+	backendTxn *persistent_store.BackendTransaction, volumes []*storage.Volume,
+) {
+	logFields := log.Fields{
+		"backend": backendTxn.OldName,
+		"newName": backendTxn.Name,
+	}
+	for _, vol := range volumes {
+		if err := o.storeClient.UpdateVolume(vol); err != nil {
+			log.WithFields(logFields).Warnf("Unable to restore volume %s; "+
+				"the rename will be completed on restart:  %v",
+				vol.Config.Name, err)
+			return
+		}
+	}
+	if err := o.storeClient.DeleteBackend(
+		&storage.StorageBackend{Name: backendTxn.Name}); err != nil {
+		log.WithFields(logFields).Warnf("Unable to delete the new backend "+
+			"record; the rename will be completed on restart:  %v", err)
+		return
+	}
+	if err := o.storeClient.DeleteBackendTransaction(backendTxn); err != nil {
+		log.WithFields(logFields).Warn("Unable to delete backend rename " +
+			"transaction.")
+	}
+}
+
+// bootstrapBackendTxns completes any backend renames that were interrupted.
+// It runs before the backends are loaded.  A rename is complete once the
+// backend is stored under its new name, since volume records are only
+// rewritten after that and only restored before it's removed again.
+func (o *tridentOrchestrator) bootstrapBackendTxns() error {
+	backendTxns, err := o.storeClient.GetBackendTransactions()
+	if err != nil {
+		if err.Error() == persistent_store.KeyErrorMsg {
+			return nil
+		}
+		return err
+	}
+	for _, bt := range backendTxns {
+		if bt.Op == persistent_store.RenameBackend {
+			if err = o.finishBackendRename(bt); err != nil {
+				return fmt.Errorf("Unable to finish renaming backend %s to "+
+					"%s:  %v", bt.OldName, bt.Name, err)
+			}
+		}
+		if err = o.storeClient.DeleteBackendTransaction(bt); err != nil {
+			return fmt.Errorf("Failed to clean up backend rename "+
+				"transaction:  %v", err)
+		}
+	}
+	return nil
+}
+
+func (o *tridentOrchestrator) finishBackendRename(
+	bt *persistent_store.BackendTransaction,
+) error {
+	if _, err := o.storeClient.GetBackend(bt.Name); err != nil {
+		if err.Error() == persistent_store.KeyErrorMsg {
+			// The rename didn't get far enough to change anything.
+			return nil
+		}
+		return err
+	}
+	volumes, err := o.getVolumesForBootstrap()
+	if err != nil && err.Error() != persistent_store.KeyErrorMsg {
+		return err
+	}
+	backend := &storage.StorageBackend{Name: bt.Name}
+	for _, v := range volumes {
+		if v.Backend != bt.OldName {
+			continue
+		}
+		err = o.storeClient.UpdateVolume(volumeRecord(v, backend))
+		if err != nil {
+			return err
+		}
+	}
+	err = o.storeClient.DeleteBackend(&storage.StorageBackend{Name: bt.OldName})
+	if err != nil {
+		// The old record may already be gone.
+		if _, getErr := o.storeClient.GetBackend(bt.OldName); getErr == nil {
+			return err
+		}
+	}
+	log.WithFields(log.Fields{
+		"oldName": bt.OldName,
+		"name":    bt.Name,
+		"handler": "Bootstrap",
+	}).Info("Finished renaming backend.")
+	return nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/netapp/trident/errors"
+	"github.com/netapp/trident/storage"
+	"github.com/netapp/trident/storage/factory"
+	"github.com/netapp/trident/tracing"
+)
+
+// UpdateStorageBackend changes some of a backend's config.  Unlike adding
+// the backend's config again, which replaces it, the update is merged into
+// the stored config, so fields it leaves out, e.g., the password, keep their
+// values; see factory.MergeBackendConfig.  The backend stays offline if it
+// was.  The updated backend is validated like any other update and stored
+// once, and is returned with any warnings about it.  The backend's name and
+// driver can't be changed.
+func (o *tridentOrchestrator) UpdateStorageBackend(
+	ctx context.Context, backendName, configJSON string,
+) (result *BackendResult, err error) {
+	ctx, span := tracing.StartSpan(ctx, tracing.Core,
+		"UpdateStorageBackend", map[string]string{"backend": backendName})
+	defer func() {
+		span.Finish(err)
+	}()
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	original, ok := o.backends[backendName]
+	if !ok {
+		if _, failed := o.failedBackends[backendName]; failed {
+			return nil, errors.Errorf(errors.Conflict, "Backend %s has not "+
+				"initialized; add its config again to retry.", backendName)
+		}
+		return nil, errors.Errorf(errors.NotFound,
+			"Backend %s not found.", backendName)
+	}
+	storedJSON, err := original.ConstructPersistent().MarshalConfig()
+	if err != nil {
+		return nil, err
+	}
+	mergedJSON, err := factory.MergeBackendConfig(storedJSON, configJSON)
+	if err != nil {
+		return nil, errors.Errorf(errors.InvalidInput, "%v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, o.timeouts.BackendAddTimeout())
+	defer cancel()
+	var storageBackend *storage.StorageBackend
+	err = callWithContext(ctx, "Backend initialization", nil, func() error {
+		var initErr error
+		storageBackend, initErr = factory.NewStorageBackendForStoredConfig(
+			mergedJSON)
+		return initErr
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkContext(ctx, "Backend update"); err != nil {
+		return nil, err
+	}
+	if storageBackend.Name != backendName {
+		return nil, errors.Errorf(errors.InvalidInput, "The update would "+
+			"rename backend %s to %s; rename it instead.", backendName,
+			storageBackend.Name)
+	}
+	if storageBackend.GetDriverName() != original.GetDriverName() {
+		return nil, errors.Errorf(errors.InvalidInput, "The update would "+
+			"change backend %s's driver from %s to %s.", backendName,
+			original.GetDriverName(), storageBackend.GetDriverName())
+	}
+	storageBackend.Online = original.Online
+	return o.addStorageBackend(ctx, storageBackend)
+}
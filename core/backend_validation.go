@@ -0,0 +1,100 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"sort"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+
+	"github.com/netapp/trident/storage"
+	"github.com/netapp/trident/storage/factory"
+)
+
+// BackendValidation reports what adding a backend config would do.
+type BackendValidation struct {
+	// Backend is the backend the config describes.  Its storage pools list
+	// the storage classes they would satisfy.
+	Backend *storage.StorageBackendExternal `json:"backend"`
+	// Update is true if the config would update an existing backend, or
+	// one that failed to initialize.
+	Update bool `json:"update"`
+	// StorageClasses are the storage classes that any of the backend's
+	// pools would satisfy, sorted.
+	StorageClasses []string `json:"storageClasses"`
+}
+
+// ValidateStorageBackend checks a backend config as AddStorageBackend
+// would, connecting to the storage system and discovering its pools, but
+// doesn't add the backend.  CHAP credentials aren't applied.
+func (o *tridentOrchestrator) ValidateStorageBackend(
+	ctx context.Context, configJSON string,
+) (*BackendValidation, error) {
+	ctx, cancel := context.WithTimeout(ctx, o.timeouts.BackendAddTimeout())
+	defer cancel()
+	var storageBackend *storage.StorageBackend
+	err := callWithContext(ctx, "Backend initialization", nil, func() error {
+		var initErr error
+		storageBackend, initErr = factory.NewStorageBackendForConfig(
+			configJSON)
+		return initErr
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// The backend is only initialized, not added, so the orchestrator needn't
+	// be locked until it's compared with the existing backends.
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	validation := &BackendValidation{StorageClasses: make([]string, 0)}
+	if originalBackend, ok := o.backends[storageBackend.Name]; ok {
+		validation.Update = true
+		if err = o.validateBackendUpdate(originalBackend,
+			storageBackend); err != nil {
+			return nil, err
+		}
+	} else if _, ok = o.failedBackends[storageBackend.Name]; ok {
+		// Adding the config would finish adding the failed backend.
+		validation.Update = true
+	}
+	if storageBackend.Chap.IsSet() {
+		if err = storageBackend.CheckChap(); err != nil {
+			return nil, err
+		}
+	}
+	if storageBackend.Rotation.IsSet() {
+		if err = storageBackend.CheckRotation(); err != nil {
+			return nil, err
+		}
+	}
+	// The backend is discarded, so its pools can record the classes they
+	// would satisfy.
+	classNames := make([]string, 0, len(o.storageClasses))
+	for name := range o.storageClasses {
+		classNames = append(classNames, name)
+	}
+	sort.Strings(classNames)
+	for _, name := range classNames {
+		matched := false
+		for _, pool := range storageBackend.Storage {
+			if o.storageClasses[name].Matches(pool) {
+				pool.AddStorageClass(name)
+				matched = true
+			}
+		}
+		if matched {
+			validation.StorageClasses = append(validation.StorageClasses,
+				name)
+		}
+	}
+	validation.Backend = storageBackend.ConstructExternal()
+	log.WithFields(log.Fields{
+		"backendName":    storageBackend.Name,
+		"update":         validation.Update,
+		"storageClasses": validation.StorageClasses,
+	}).Debug("Validated backend.")
+	return validation, nil
+}
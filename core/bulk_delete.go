@@ -0,0 +1,263 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+
+	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/errors"
+	"github.com/netapp/trident/persistent_store"
+	"github.com/netapp/trident/storage"
+	"github.com/netapp/trident/tracing"
+)
+
+// BulkDeleteConfig selects the volumes DeleteVolumes deletes:  either those
+// named or those whose metadata matches a selector.
+type BulkDeleteConfig struct {
+	Volumes  []string          `json:"volumes,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Force deletes volumes even if they are attached to nodes.
+	Force bool `json:"force,omitempty"`
+}
+
+// Validate checks that c selects volumes in exactly one way, and names each
+// volume once.
+func (c *BulkDeleteConfig) Validate() error {
+	if len(c.Volumes) > 0 && len(c.Metadata) > 0 {
+		return errors.Errorf(errors.InvalidInput, "Select volumes either by "+
+			"name or by metadata, not both.")
+	}
+	if len(c.Volumes) == 0 && len(c.Metadata) == 0 {
+		return errors.Errorf(errors.InvalidInput, "Select volumes by name "+
+			"or by metadata.")
+	}
+	if err := storage.ValidateMetadata(c.Metadata); err != nil {
+		return errors.Errorf(errors.InvalidInput, "%v", err)
+	}
+	named := make(map[string]bool, len(c.Volumes))
+	for _, name := range c.Volumes {
+		if name == "" {
+			return errors.Errorf(errors.InvalidInput,
+				"Volume names must not be empty.")
+		}
+		if named[name] {
+			return errors.Errorf(errors.InvalidInput,
+				"Volume %s is named more than once.", name)
+		}
+		named[name] = true
+	}
+	return nil
+}
+
+// VolumeDeleteResult is the outcome of deleting one of a bulk delete's
+// volumes.
+type VolumeDeleteResult struct {
+	Volume string `json:"volume"`
+	// Found is false if the volume doesn't exist.
+	Found bool   `json:"found"`
+	Error string `json:"error,omitempty"`
+	// Err is the error deleting the volume, for frontends that report errors
+	// by kind.
+	Err error `json:"-"`
+}
+
+// BulkDeleteResult reports the outcome of deleting each of a bulk delete's
+// volumes, in the order they were named, or by name if they were selected
+// by metadata.
+type BulkDeleteResult struct {
+	Volumes []*VolumeDeleteResult `json:"volumes"`
+	// Failed is the number of volumes that weren't deleted.
+	Failed int `json:"failed"`
+}
+
+// newBulkDeleteResult returns the result of deleting volumes, whose
+// deletions returned found and errs.
+func newBulkDeleteResult(
+	volumes []string, found []bool, errs []error,
+) *BulkDeleteResult {
+	result := &BulkDeleteResult{
+		Volumes: make([]*VolumeDeleteResult, 0, len(volumes)),
+	}
+	for i, name := range volumes {
+		item := &VolumeDeleteResult{Volume: name, Found: found[i]}
+		if errs[i] != nil {
+			item.Error = errs[i].Error()
+			item.Err = errs[i]
+			result.Failed++
+		}
+		result.Volumes = append(result.Volumes, item)
+	}
+	return result
+}
+
+// AllFailed returns an error if volumes were selected but none of them
+// were deleted.
+func (r *BulkDeleteResult) AllFailed() error {
+	if len(r.Volumes) == 0 || r.Failed < len(r.Volumes) {
+		return nil
+	}
+	return fmt.Errorf("None of the %d volumes were deleted.", len(r.Volumes))
+}
+
+// bulkDeleteWorkers bounds how many volumes DeleteVolumes deletes from
+// their backends at once.  Each backend still runs one operation at a time.
+const bulkDeleteWorkers = 16
+
+// pendingVolumeDeletion is a volume DeleteVolumes is deleting from its
+// backend, with the transaction logged for it.
+type pendingVolumeDeletion struct {
+	index  int
+	volume *storage.Volume
+	txn    *persistent_store.VolumeTransaction
+	err    error
+}
+
+// DeleteVolumes deletes several volumes, as DeleteVolume or, if forced,
+// ForceDeleteVolume would.  The volumes are checked and their transactions
+// logged in one pass under the orchestrator lock; they are then deleted
+// from their backends concurrently, without the lock, and removed from
+// Trident once the lock is taken again.  Volumes that are retained or left
+// terminating aren't deleted from their backends, so they are handled in
+// the first pass.  Each volume is deleted in its own transaction, so one
+// that fails doesn't stop the rest from being deleted, nor undo them; the
+// outcome of each is reported.
+func (o *tridentOrchestrator) DeleteVolumes(
+	ctx context.Context, deleteConfig *BulkDeleteConfig,
+) (*BulkDeleteResult, error) {
+	if err := deleteConfig.Validate(); err != nil {
+		return nil, err
+	}
+	volumes := deleteConfig.selectedVolumes(o.ListVolumesByMetadata)
+	ctx, span := tracing.StartSpan(ctx, tracing.Core, "DeleteVolumes",
+		map[string]string{"volumes": strconv.Itoa(len(volumes))})
+	defer span.Finish(nil)
+
+	found := make([]bool, len(volumes))
+	errs := make([]error, len(volumes))
+	pending, timeout := o.startVolumeDeletions(ctx, volumes,
+		deleteConfig.Force, found, errs)
+
+	// As with a single deletion, once the transactions are logged the
+	// deletions are no longer canceled with ctx, but are still bounded by
+	// the delete timeout.
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, bulkDeleteWorkers)
+	for _, p := range pending {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(p *pendingVolumeDeletion) {
+			defer wg.Done()
+			volCtx, volSpan := tracing.StartChildSpan(ctx, tracing.Core,
+				"DestroyVolume", map[string]string{
+					"volume": p.volume.Config.Name,
+				})
+			deleteCtx, cancel := context.WithTimeout(
+				tracing.Detach(volCtx), timeout)
+			p.err = destroyVolume(deleteCtx, p.volume)
+			cancel()
+			volSpan.Finish(p.err)
+			<-sem
+		}(p)
+	}
+	wg.Wait()
+
+	o.finishVolumeDeletions(pending, errs)
+
+	result := newBulkDeleteResult(volumes, found, errs)
+	log.WithFields(log.Fields{
+		"volumes": len(volumes),
+		"failed":  result.Failed,
+		"force":   deleteConfig.Force,
+	}).Info("Deleted volumes.")
+	return result, nil
+}
+
+// startVolumeDeletions checks each of the volumes named, recording in
+// found and errs whether it was found and why it can't be deleted, and
+// logs the transactions of those to be deleted from their backends.
+// Volumes that are retained or left terminating are deleted here.  The
+// volumes returned are reserved until finishVolumeDeletions, along with
+// the delete timeout.
+func (o *tridentOrchestrator) startVolumeDeletions(
+	ctx context.Context, volumes []string, force bool, found []bool,
+	errs []error,
+) ([]*pendingVolumeDeletion, time.Duration) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	timeout := o.timeouts.DeleteTimeout()
+	pending := make([]*pendingVolumeDeletion, 0, len(volumes))
+	for i, name := range volumes {
+		var volume *storage.Volume
+		volume, found[i], errs[i] = o.volumeToDelete(name, force)
+		if volume == nil {
+			continue
+		}
+		if volume.Config.ReclaimPolicy == config.ReclaimRetain ||
+			o.terminatesVolume(volume) {
+			found[i], errs[i] = o.deleteVolumeLocked(ctx, name, force)
+			continue
+		}
+		txnCtx, cancel := context.WithTimeout(ctx, timeout)
+		txn, err := o.addDeleteTransaction(txnCtx, volume)
+		cancel()
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		o.deletingVolumes[name] = true
+		pending = append(pending, &pendingVolumeDeletion{
+			index:  i,
+			volume: volume,
+			txn:    txn,
+		})
+	}
+	return pending, timeout
+}
+
+// finishVolumeDeletions removes the volumes deleted from their backends
+// from Trident and resolves their transactions.  The transactions of those
+// that weren't deleted are left, so that the deletions are attempted again.
+func (o *tridentOrchestrator) finishVolumeDeletions(
+	pending []*pendingVolumeDeletion, errs []error,
+) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	for _, p := range pending {
+		delete(o.deletingVolumes, p.volume.Config.Name)
+		if p.err == nil {
+			p.err = o.removeDestroyedVolume(p.volume)
+		}
+		if p.err != nil {
+			errs[p.index] = p.err
+			continue
+		}
+		o.resolveDeleteTransaction(p.txn, p.volume)
+	}
+}
+
+// selectedVolumes returns the names of the volumes c selects, listing
+// those whose metadata matches with list.  Volumes selected by metadata are
+// sorted by name.
+func (c *BulkDeleteConfig) selectedVolumes(
+	list func(selector map[string]string) []*storage.VolumeExternal,
+) []string {
+	if len(c.Metadata) == 0 {
+		return c.Volumes
+	}
+	names := make([]string, 0)
+	for _, volume := range list(c.Metadata) {
+		names = append(names, volume.Config.Name)
+	}
+	sort.Strings(names)
+	return names
+}
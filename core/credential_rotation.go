@@ -0,0 +1,155 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/logging"
+	"github.com/netapp/trident/storage"
+)
+
+// initializeRotation starts the rotation schedule of a backend being added,
+// keeping that of the backend it updates, if any.  The caller must hold the
+// orchestrator lock.
+func (o *tridentOrchestrator) initializeRotation(
+	backend, originalBackend *storage.StorageBackend,
+) {
+	rotation := &backend.Rotation
+	if originalBackend != nil {
+		rotation.Inherit(&originalBackend.Rotation)
+	}
+	if rotation.LastRotated.IsZero() {
+		rotation.LastRotated = time.Now()
+	}
+	if rotation.PendingPassword != "" {
+		logging.AddSecret(rotation.PendingPassword)
+		log.WithFields(log.Fields{
+			"backend": backend.Name,
+		}).Warn("The backend's last credential rotation was not finished; " +
+			"its new password is kept with the backend until the backend " +
+			"is updated.")
+	}
+}
+
+// startCredentialRotation rotates the passwords of the backends that are
+// due once per interval for the life of the process.
+func (o *tridentOrchestrator) startCredentialRotation() {
+	go func() {
+		for now := range time.Tick(config.CredentialRotationCheckInterval) {
+			o.rotateDueCredentials(now)
+		}
+	}()
+}
+
+// rotateDueCredentials rotates the password of each backend due to be
+// rotated at t, returning the number rotated.  The orchestrator is locked
+// throughout, so that no driver call races a password change.
+func (o *tridentOrchestrator) rotateDueCredentials(t time.Time) int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	names := make([]string, 0)
+	for name, backend := range o.backends {
+		if backend.Rotation.IsSet() && backend.Rotation.Due(t) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	rotated := 0
+	for _, name := range names {
+		if err := o.rotateCredentials(o.backends[name]); err != nil {
+			log.WithFields(log.Fields{
+				"backend": name,
+			}).Warnf("Unable to rotate the backend's password:  %v", err)
+			continue
+		}
+		rotated++
+	}
+	return rotated
+}
+
+// rotateCredentials changes a backend's password to one generated or read
+// from Vault.  The new password is recorded with the backend before it's
+// applied, and the stored credentials are swapped only once the driver has
+// logged in with it, so a failed rotation leaves the previous password in
+// place.  The caller must hold the orchestrator lock.
+func (o *tridentOrchestrator) rotateCredentials(
+	backend *storage.StorageBackend,
+) error {
+	defer o.markDirty()
+	rotation := &backend.Rotation
+	if rotation.PendingPassword != "" {
+		return fmt.Errorf("The last rotation was not finished; update the " +
+			"backend with its current password to resume rotation.")
+	}
+	driver, ok := backend.Driver.(storage.CredentialDriver)
+	if !ok {
+		return fmt.Errorf("The %s driver doesn't support credential "+
+			"rotation.", backend.GetDriverName())
+	}
+	password, err := rotation.NewPassword()
+	if err != nil {
+		return err
+	}
+	logging.AddSecret(password)
+	previous := driver.GetPassword()
+	if password == previous {
+		return fmt.Errorf("The new password is the same as the current one.")
+	}
+
+	// Record the new password first, so that it isn't lost if Trident
+	// stops before the rotation is finished.
+	rotation.PendingPassword = password
+	if err = o.storeClient.UpdateBackend(backend); err != nil {
+		rotation.PendingPassword = ""
+		return fmt.Errorf("Unable to record the new password:  %v", err)
+	}
+	if err = driver.ChangePassword(password); err != nil {
+		return o.abandonRotation(backend, fmt.Errorf("Unable to change the "+
+			"password:  %v", err))
+	}
+	if err = driver.CheckCredentials(); err != nil {
+		if restoreErr := driver.ChangePassword(previous); restoreErr != nil {
+			return fmt.Errorf("The new password failed verification (%v), "+
+				"and the previous password couldn't be restored:  %v",
+				err, restoreErr)
+		}
+		return o.abandonRotation(backend, fmt.Errorf("The new password "+
+			"failed verification:  %v", err))
+	}
+
+	// Swap the stored credentials in a single update.
+	lastRotated := rotation.LastRotated
+	rotation.PendingPassword = ""
+	rotation.LastRotated = time.Now()
+	if err = o.storeClient.UpdateBackend(backend); err != nil {
+		rotation.PendingPassword = password
+		rotation.LastRotated = lastRotated
+		return fmt.Errorf("Unable to store the new password:  %v", err)
+	}
+	log.WithFields(log.Fields{
+		"backend": backend.Name,
+		"source":  rotation.Source,
+	}).Info("Rotated the backend's password.")
+	return nil
+}
+
+// abandonRotation clears the pending password of a rotation that left the
+// previous password in place, and returns err.
+func (o *tridentOrchestrator) abandonRotation(
+	backend *storage.StorageBackend, err error,
+) error {
+	backend.Rotation.PendingPassword = ""
+	if storeErr := o.storeClient.UpdateBackend(backend); storeErr != nil {
+		log.WithFields(log.Fields{
+			"backend": backend.Name,
+		}).Warnf("Unable to clear the pending password:  %v", storeErr)
+	}
+	return err
+}
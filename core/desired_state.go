@@ -0,0 +1,306 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+
+	"github.com/netapp/trident/errors"
+	"github.com/netapp/trident/storage"
+	"github.com/netapp/trident/storage/factory"
+	"github.com/netapp/trident/storage_class"
+	"github.com/netapp/trident/tracing"
+)
+
+// DesiredState is the complete set of backends and storage classes that
+// Trident should have.  Each backend is a config as accepted by
+// AddStorageBackend.
+type DesiredState struct {
+	Backends       []json.RawMessage       `json:"backends"`
+	StorageClasses []*storage_class.Config `json:"storageClasses"`
+}
+
+// StateChanges lists, by name, how the objects of one type were reconciled.
+// Removed backends are offlined, and deleted if they have no volumes.
+type StateChanges struct {
+	Added     []string `json:"added"`
+	Updated   []string `json:"updated"`
+	Unchanged []string `json:"unchanged"`
+	Removed   []string `json:"removed"`
+}
+
+func newStateChanges() StateChanges {
+	return StateChanges{
+		Added:     make([]string, 0),
+		Updated:   make([]string, 0),
+		Unchanged: make([]string, 0),
+		Removed:   make([]string, 0),
+	}
+}
+
+// StateReport reports the changes ApplyState made.  Errors lists the
+// changes that failed; the objects they concern are left as they were.
+type StateReport struct {
+	Backends       StateChanges `json:"backends"`
+	StorageClasses StateChanges `json:"storageClasses"`
+	Errors         []string     `json:"errors,omitempty"`
+}
+
+func (r *StateReport) addError(err error) {
+	r.Errors = append(r.Errors, err.Error())
+}
+
+// ApplyState reconciles Trident with state:  backends and storage classes
+// that are missing are added, those whose configs differ are updated, and
+// those not in state are removed.  Applying the same state again changes
+// nothing.
+//
+// Every backend config is initialized before anything changes; if any is
+// invalid or its storage system can't be reached, ApplyState fails without
+// changing anything, since the backend it describes can't be identified and
+// mustn't be removed.  Failures while applying the changes are collected in
+// the report, and the remaining changes are still made.
+func (o *tridentOrchestrator) ApplyState(
+	ctx context.Context, state *DesiredState,
+) (report *StateReport, err error) {
+	ctx, span := tracing.StartSpan(ctx, tracing.Core, "ApplyState", nil)
+	defer func() {
+		span.Finish(err)
+	}()
+	classConfigs := make(map[string]*storage_class.Config,
+		len(state.StorageClasses))
+	for _, scConfig := range state.StorageClasses {
+		if _, err := validateStorageClassConfig(scConfig,
+			o.strictStorageClasses); err != nil {
+			return nil, err
+		}
+		if _, ok := classConfigs[scConfig.Name]; ok {
+			return nil, errors.Errorf(errors.InvalidInput,
+				"Storage class %s is listed more than once.", scConfig.Name)
+		}
+		classConfigs[scConfig.Name] = scConfig
+	}
+	backends := make(map[string]*storage.StorageBackend,
+		len(state.Backends))
+	for i, configJSON := range state.Backends {
+		backend, err := o.initializeDesiredBackend(ctx, string(configJSON))
+		if err != nil {
+			return nil, errors.Errorf(errors.InvalidInput,
+				"Unable to initialize backend %d:  %v", i, err)
+		}
+		if _, ok := backends[backend.Name]; ok {
+			return nil, errors.Errorf(errors.InvalidInput,
+				"Backend %s is listed more than once.", backend.Name)
+		}
+		backends[backend.Name] = backend
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	report = &StateReport{
+		Backends:       newStateChanges(),
+		StorageClasses: newStateChanges(),
+	}
+	// Classes are added before backends, so that new backends' pools are
+	// matched to them, and deleted after backends are offlined.
+	o.applyStorageClasses(classConfigs, report)
+	o.applyBackends(ctx, backends, report)
+	o.removeBackends(backends, report)
+	o.removeStorageClasses(classConfigs, report)
+
+	log.WithFields(log.Fields{
+		"backends":       report.Backends,
+		"storageClasses": report.StorageClasses,
+		"errors":         len(report.Errors),
+	}).Info("Applied desired state.")
+	return report, nil
+}
+
+// initializeDesiredBackend initializes a backend from its config, as
+// AddStorageBackend does, without adding it.
+func (o *tridentOrchestrator) initializeDesiredBackend(
+	ctx context.Context, configJSON string,
+) (*storage.StorageBackend, error) {
+	ctx, cancel := context.WithTimeout(ctx, o.timeouts.BackendAddTimeout())
+	defer cancel()
+	var storageBackend *storage.StorageBackend
+	err := callWithContext(ctx, "Backend initialization", nil, func() error {
+		var initErr error
+		storageBackend, initErr = factory.NewStorageBackendForConfig(
+			configJSON)
+		return initErr
+	}, nil)
+	return storageBackend, err
+}
+
+// sortedBackendNames returns the names of backends, sorted, so that changes
+// are applied and reported in a stable order.
+func sortedBackendNames(
+	backends map[string]*storage.StorageBackend,
+) []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedClassNames(configs map[string]*storage_class.Config) []string {
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sameStorageClassConfig returns whether two storage class configs are
+// equivalent.
+func sameStorageClassConfig(a, b *storage_class.Config) bool {
+	aJSON, aErr := json.Marshal(a)
+	bJSON, bErr := json.Marshal(b)
+	return aErr == nil && bErr == nil && bytes.Equal(aJSON, bJSON)
+}
+
+// sameBackendConfig returns whether an initialized backend has the same
+// config as an online backend.
+func sameBackendConfig(oldBackend, newBackend *storage.StorageBackend) bool {
+	if !oldBackend.Online {
+		return false
+	}
+	oldConfig, oldErr := oldBackend.ConstructPersistent().MarshalConfig()
+	newConfig, newErr := newBackend.ConstructPersistent().MarshalConfig()
+	return oldErr == nil && newErr == nil && oldConfig == newConfig
+}
+
+// applyStorageClasses adds and updates storage classes.  The caller must
+// hold the orchestrator lock.
+func (o *tridentOrchestrator) applyStorageClasses(
+	configs map[string]*storage_class.Config, report *StateReport,
+) {
+	for _, name := range sortedClassNames(configs) {
+		scConfig := configs[name]
+		// Default the version, as the stored config's was.
+		storage_class.New(scConfig)
+		sc, ok := o.storageClasses[name]
+		if !ok {
+			if _, err := o.addStorageClass(scConfig); err != nil {
+				report.addError(err)
+				continue
+			}
+			report.StorageClasses.Added = append(
+				report.StorageClasses.Added, name)
+			continue
+		}
+		if sameStorageClassConfig(sc.ConstructExternal().Config, scConfig) {
+			report.StorageClasses.Unchanged = append(
+				report.StorageClasses.Unchanged, name)
+			continue
+		}
+		if _, err := o.updateStorageClass(scConfig); err != nil {
+			report.addError(err)
+			continue
+		}
+		report.StorageClasses.Updated = append(
+			report.StorageClasses.Updated, name)
+	}
+}
+
+// applyBackends adds and updates backends.  The caller must hold the
+// orchestrator lock.
+func (o *tridentOrchestrator) applyBackends(
+	ctx context.Context, backends map[string]*storage.StorageBackend,
+	report *StateReport,
+) {
+	for _, name := range sortedBackendNames(backends) {
+		backend := backends[name]
+		original, exists := o.backends[name]
+		_, failed := o.failedBackends[name]
+		if exists {
+			// Credentials hosts already use are kept, so they mustn't
+			// count as a change.
+			if backend.Chap.IsSet() {
+				backend.Chap.Inherit(&original.Chap)
+			}
+			if backend.Rotation.IsSet() {
+				backend.Rotation.Inherit(&original.Rotation)
+			}
+			if sameBackendConfig(original, backend) {
+				report.Backends.Unchanged = append(report.Backends.Unchanged,
+					name)
+				continue
+			}
+		}
+		addCtx, cancel := context.WithTimeout(ctx,
+			o.timeouts.BackendAddTimeout())
+		_, err := o.addStorageBackend(addCtx, backend)
+		cancel()
+		if err != nil {
+			report.addError(fmt.Errorf("Unable to apply backend %s:  %v",
+				name, err))
+			continue
+		}
+		if exists || failed {
+			report.Backends.Updated = append(report.Backends.Updated, name)
+		} else {
+			report.Backends.Added = append(report.Backends.Added, name)
+		}
+	}
+}
+
+// removeBackends offlines the online backends that aren't in backends.  The
+// caller must hold the orchestrator lock.
+func (o *tridentOrchestrator) removeBackends(
+	backends map[string]*storage.StorageBackend, report *StateReport,
+) {
+	removed := make([]string, 0)
+	for name, backend := range o.backends {
+		if _, ok := backends[name]; !ok && backend.Online {
+			removed = append(removed, name)
+		}
+	}
+	for name, backend := range o.failedBackends {
+		if _, ok := backends[name]; !ok && backend.persistent.Online {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		if _, err := o.offlineBackend(name); err != nil {
+			report.addError(fmt.Errorf("Unable to remove backend %s:  %v",
+				name, err))
+			continue
+		}
+		report.Backends.Removed = append(report.Backends.Removed, name)
+	}
+}
+
+// removeStorageClasses deletes the storage classes that aren't in configs.
+// The caller must hold the orchestrator lock.
+func (o *tridentOrchestrator) removeStorageClasses(
+	configs map[string]*storage_class.Config, report *StateReport,
+) {
+	removed := make([]string, 0)
+	for name := range o.storageClasses {
+		if _, ok := configs[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		if _, err := o.deleteStorageClass(name, false); err != nil {
+			report.addError(fmt.Errorf("Unable to remove storage class "+
+				"%s:  %v", name, err))
+			continue
+		}
+		report.StorageClasses.Removed = append(
+			report.StorageClasses.Removed, name)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+type EventType string
+type EventObjectType string
+
+const (
+	EventCreate EventType = "create"
+	EventUpdate EventType = "update"
+	EventDelete EventType = "delete"
+
+	EventObjectBackend      EventObjectType = "backend"
+	EventObjectVolume       EventObjectType = "volume"
+	EventObjectStorageClass EventObjectType = "storageClass"
+
+	// eventBufferSize is the number of events buffered per subscriber before
+	// further events are dropped for that subscriber.
+	eventBufferSize = 100
+)
+
+// Event describes a change to an object managed by the orchestrator.  Object
+// holds the external representation of the object after the change, or
+// before it for deletions.
+type Event struct {
+	Type       EventType       `json:"type"`
+	ObjectType EventObjectType `json:"objectType"`
+	Name       string          `json:"name"`
+	Timestamp  time.Time       `json:"timestamp"`
+	Object     interface{}     `json:"object,omitempty"`
+}
+
+// eventBus fans out orchestrator events to subscribers.  Publishing never
+// blocks; subscribers that fall behind miss events rather than stalling the
+// orchestrator.
+type eventBus struct {
+	mutex       *sync.Mutex
+	subscribers map[<-chan Event]chan Event
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{
+		mutex:       &sync.Mutex{},
+		subscribers: make(map[<-chan Event]chan Event),
+	}
+}
+
+func (b *eventBus) subscribe() <-chan Event {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	ch := make(chan Event, eventBufferSize)
+	b.subscribers[ch] = ch
+	return ch
+}
+
+func (b *eventBus) unsubscribe(ch <-chan Event) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if sendCh, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(sendCh)
+	}
+}
+
+func (b *eventBus) publish(
+	eventType EventType, objectType EventObjectType, name string,
+	object interface{},
+) {
+	event := Event{
+		Type:       eventType,
+		ObjectType: objectType,
+		Name:       name,
+		Timestamp:  time.Now(),
+		Object:     object,
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for _, ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			log.WithFields(log.Fields{
+				"type":       eventType,
+				"objectType": objectType,
+				"name":       name,
+			}).Warn("Event subscriber is not keeping up; dropping event.")
+		}
+	}
+}
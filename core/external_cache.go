@@ -0,0 +1,109 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"sync"
+
+	"github.com/netapp/trident/storage"
+)
+
+// orchestratorLock is the orchestrator's global lock.  It also holds the
+// cached external representations, which are built and discarded under it.
+type orchestratorLock struct {
+	sync.Mutex
+	externals externalCache
+}
+
+func newOrchestratorLock() *orchestratorLock {
+	return &orchestratorLock{}
+}
+
+// externalCache holds the external representations of the backends and
+// volumes, built when they are first requested after a change.  The cached
+// objects are copy-on-write:  they are never modified, only replaced, so
+// they are shared by every caller until the next change.  Callers must not
+// modify them either.
+type externalCache struct {
+	// backends holds every backend, including failed ones, by name;
+	// onlineBackends lists the online ones.
+	backends       map[string]*storage.StorageBackendExternal
+	onlineBackends []*storage.StorageBackendExternal
+	// volumes holds every volume, including orphaned ones, by name;
+	// volumeList lists them.
+	volumes    map[string]*storage.VolumeExternal
+	volumeList []*storage.VolumeExternal
+}
+
+func (c *externalCache) invalidate() {
+	c.backends = nil
+	c.onlineBackends = nil
+	c.volumes = nil
+	c.volumeList = nil
+}
+
+// markDirty discards the cached external representations after a change to
+// the orchestrator's backends, volumes, or storage pools.  Every change that
+// publishes an event marks the cache dirty before publishing it.  The caller
+// must hold the lock.
+func (o *tridentOrchestrator) markDirty() {
+	o.mutex.externals.invalidate()
+}
+
+// externalBackends returns the cached backends, building them if needed.
+// The caller must hold the lock.
+func (o *tridentOrchestrator) externalBackends() *externalCache {
+	c := &o.mutex.externals
+	if c.backends != nil {
+		return c
+	}
+	c.backends = make(map[string]*storage.StorageBackendExternal,
+		len(o.backends)+len(o.failedBackends))
+	c.onlineBackends = make([]*storage.StorageBackendExternal, 0,
+		len(o.backends)+len(o.failedBackends))
+	for name, b := range o.backends {
+		external := b.ConstructExternal()
+		c.backends[name] = external
+		if b.Online {
+			c.onlineBackends = append(c.onlineBackends, external)
+		}
+	}
+	for name, b := range o.failedBackends {
+		if _, ok := c.backends[name]; ok {
+			continue
+		}
+		external := b.constructExternal()
+		c.backends[name] = external
+		if b.persistent.Online {
+			c.onlineBackends = append(c.onlineBackends, external)
+		}
+	}
+	return c
+}
+
+// externalVolumes returns the cached volumes, building them if needed.  The
+// caller must hold the lock.
+func (o *tridentOrchestrator) externalVolumes() *externalCache {
+	c := &o.mutex.externals
+	if c.volumes != nil {
+		return c
+	}
+	c.volumes = make(map[string]*storage.VolumeExternal,
+		len(o.volumes)+len(o.orphanedVolumes))
+	c.volumeList = make([]*storage.VolumeExternal, 0,
+		len(o.volumes)+len(o.orphanedVolumes))
+	for name, v := range o.volumes {
+		external := v.ConstructExternal()
+		c.volumes[name] = external
+		c.volumeList = append(c.volumeList, external)
+	}
+	for name, v := range o.orphanedVolumes {
+		if _, ok := c.volumes[name]; ok {
+			continue
+		}
+		orphan := *v
+		c.volumes[name] = &orphan
+		c.volumeList = append(c.volumeList, &orphan)
+	}
+	return c
+}
@@ -0,0 +1,167 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+
+	"github.com/netapp/trident/errors"
+	"github.com/netapp/trident/storage"
+	"github.com/netapp/trident/storage/factory"
+)
+
+// failedBackend is a stored backend whose driver failed to initialize at
+// bootstrap, e.g., because its storage system was unreachable.
+type failedBackend struct {
+	persistent  *storage.StorageBackendPersistent
+	configJSON  string
+	err         error
+	attempts    int
+	lastAttempt time.Time
+}
+
+func (b *failedBackend) constructExternal() *storage.StorageBackendExternal {
+	return &storage.StorageBackendExternal{
+		Name:      b.persistent.Name,
+		Storage:   make(map[string]*storage.StoragePoolExternal),
+		Online:    b.persistent.Online,
+		Volumes:   make([]string, 0),
+		Limits:    b.persistent.Limits,
+		InitError: b.err.Error(),
+	}
+}
+
+func (o *tridentOrchestrator) addFailedBackend(
+	b *storage.StorageBackendPersistent, configJSON string, err error,
+) {
+	o.failedBackends[b.Name] = &failedBackend{
+		persistent:  b,
+		configJSON:  configJSON,
+		err:         err,
+		attempts:    1,
+		lastAttempt: time.Now(),
+	}
+	log.WithFields(log.Fields{
+		"backend": b.Name,
+		"handler": "Bootstrap",
+	}).Warnf("Unable to initialize backend; will retry:  %v", err)
+}
+
+// startBackendInitRetries retries the failed backends once per interval
+// until all of them have initialized.
+func (o *tridentOrchestrator) startBackendInitRetries() {
+	go func() {
+		ticker := time.NewTicker(o.bootstrapConfig.RetryInterval())
+		defer ticker.Stop()
+		for range ticker.C {
+			if remaining := o.retryFailedBackends(); remaining == 0 {
+				return
+			}
+		}
+	}()
+}
+
+// retryFailedBackends attempts to initialize each failed backend and
+// returns the number still failing.  Drivers are initialized without
+// holding the orchestrator lock.
+func (o *tridentOrchestrator) retryFailedBackends() int {
+	o.mutex.Lock()
+	pending := make([]*failedBackend, 0, len(o.failedBackends))
+	for _, b := range o.failedBackends {
+		pending = append(pending, b)
+	}
+	o.mutex.Unlock()
+
+	for _, b := range pending {
+		storageBackend, err := factory.NewStorageBackendForStoredConfig(
+			b.configJSON)
+
+		o.mutex.Lock()
+		// The backend may have been replaced or deleted meanwhile.
+		if o.failedBackends[b.persistent.Name] != b {
+			o.mutex.Unlock()
+			continue
+		}
+		b.attempts++
+		b.lastAttempt = time.Now()
+		if err == nil {
+			storageBackend.Online = b.persistent.Online
+			_, err = o.addStorageBackend(context.Background(),
+				storageBackend)
+		}
+		if err != nil {
+			b.err = err
+			o.markDirty()
+		}
+		o.mutex.Unlock()
+
+		logFields := log.Fields{
+			"backend":  b.persistent.Name,
+			"attempts": b.attempts,
+		}
+		if err != nil {
+			log.WithFields(logFields).Warnf("Unable to initialize backend; "+
+				"will retry:  %v", err)
+			continue
+		}
+		log.WithFields(logFields).Info("Initialized backend.")
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return len(o.failedBackends)
+}
+
+// adoptOrphanedVolumes binds the volumes orphaned by a backend that failed
+// to initialize once it has.  The caller must hold the orchestrator lock.
+func (o *tridentOrchestrator) adoptOrphanedVolumes(
+	backend *storage.StorageBackend,
+) {
+	for name, orphan := range o.orphanedVolumes {
+		if orphan.Backend != backend.Name {
+			continue
+		}
+		pool, ok := backend.Storage[orphan.Pool]
+		if !ok {
+			continue
+		}
+		vol := storage.NewVolume(orphan.Config, backend, pool)
+		vol.Attachments = orphan.Attachments
+		vol.Deletion = orphan.Deletion
+		vol.Replication = orphan.Replication
+		pool.AddVolume(vol, true)
+		o.volumes[name] = vol
+		delete(o.orphanedVolumes, name)
+		o.markDirty()
+		log.WithFields(log.Fields{
+			"volume":  name,
+			"backend": backend.Name,
+		}).Info("Added a volume of an initialized backend.")
+	}
+}
+
+// deleteFailedBackend removes a backend that never initialized.  Since its
+// volumes can't be deleted without the backend, it must have none.  The
+// caller must hold the orchestrator lock.
+func (o *tridentOrchestrator) deleteFailedBackend(backendName string) error {
+	for _, orphan := range o.orphanedVolumes {
+		if orphan.Backend == backendName {
+			return errors.Errorf(errors.Conflict, "Backend %s has volumes and "+
+				"has not initialized; it can't be deleted.", backendName)
+		}
+	}
+	b := o.failedBackends[backendName]
+	if err := o.storeClient.DeleteBackend(
+		&storage.StorageBackend{Name: backendName}); err != nil {
+		return err
+	}
+	delete(o.failedBackends, backendName)
+	o.health.setFailedBackends(len(o.failedBackends))
+	o.markDirty()
+	o.events.publish(EventDelete, EventObjectBackend, backendName,
+		b.constructExternal())
+	return nil
+}
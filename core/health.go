@@ -0,0 +1,90 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/persistent_store"
+)
+
+// HealthStatus reports whether the orchestrator can serve requests, for
+// liveness and readiness probes and monitoring.
+type HealthStatus struct {
+	// Ready is true once the orchestrator has bootstrapped, as long as its
+	// persistent store is reachable.
+	Ready          bool `json:"ready"`
+	Bootstrapped   bool `json:"bootstrapped"`
+	StoreReachable bool `json:"storeReachable"`
+	// StoreError explains why the persistent store is unreachable.
+	StoreError string `json:"storeError,omitempty"`
+	// FailedBackends counts the backends that failed to initialize at
+	// bootstrap and are being retried.
+	FailedBackends int `json:"failedBackends"`
+	// PendingTransactions counts the unresolved volume transactions.  It is
+	// zero if the persistent store is unreachable.
+	PendingTransactions int       `json:"pendingTransactions"`
+	CheckedAt           time.Time `json:"checkedAt"`
+}
+
+// healthState holds the state reported by GetHealth.  It has its own lock,
+// so that probes aren't held up by operations holding the orchestrator
+// lock, such as volume creation.
+type healthState struct {
+	mutex          *sync.Mutex
+	bootstrapped   bool
+	failedBackends int
+}
+
+func newHealthState() *healthState {
+	return &healthState{mutex: &sync.Mutex{}}
+}
+
+func (h *healthState) setBootstrapped() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.bootstrapped = true
+}
+
+func (h *healthState) setFailedBackends(count int) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.failedBackends = count
+}
+
+// IsBootstrapped returns true once Bootstrap has completed.
+func (o *tridentOrchestrator) IsBootstrapped() bool {
+	o.health.mutex.Lock()
+	defer o.health.mutex.Unlock()
+	return o.health.bootstrapped
+}
+
+// GetHealth checks that the persistent store is reachable, by counting the
+// pending volume transactions, and reports it with the bootstrap state and
+// the number of failed backends.  It doesn't take the orchestrator lock.
+func (o *tridentOrchestrator) GetHealth() *HealthStatus {
+	o.health.mutex.Lock()
+	status := &HealthStatus{
+		Bootstrapped:   o.health.bootstrapped,
+		FailedBackends: o.health.failedBackends,
+		CheckedAt:      time.Now().UTC(),
+	}
+	o.health.mutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		config.HealthCheckTimeout)
+	defer cancel()
+	txns, err := o.storeClient.WithContext(ctx).GetVolumeTransactions()
+	if err != nil && err.Error() != persistent_store.KeyErrorMsg {
+		status.StoreError = err.Error()
+	} else {
+		status.StoreReachable = true
+		status.PendingTransactions = len(txns)
+	}
+	status.Ready = status.Bootstrapped && status.StoreReachable
+	return status
+}
@@ -0,0 +1,192 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"fmt"
+	"sort"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+
+	"github.com/netapp/trident/errors"
+	"github.com/netapp/trident/hooks"
+	"github.com/netapp/trident/storage"
+)
+
+func (o *tridentOrchestrator) bootstrapHooks() error {
+	persistentHooks, err := o.storeClient.GetHooks()
+	if err != nil {
+		return err
+	}
+	for _, h := range persistentHooks {
+		o.hooks[h.Name] = h
+		log.WithFields(log.Fields{
+			"hook":    h.Name,
+			"handler": "Bootstrap",
+		}).Info("Added an existing hook.")
+	}
+	return nil
+}
+
+func (o *tridentOrchestrator) AddHook(hookConfig *hooks.Config) (*hooks.Config, error) {
+	if err := hookConfig.Validate(); err != nil {
+		return nil, err
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if _, ok := o.hooks[hookConfig.Name]; ok {
+		return nil, errors.Errorf(errors.AlreadyExists,
+			"Hook %s already exists.", hookConfig.Name)
+	}
+	hook := *hookConfig
+	if err := o.storeClient.AddHook(&hook); err != nil {
+		return nil, err
+	}
+	o.hooks[hook.Name] = &hook
+	log.WithFields(log.Fields{
+		"hook":  hook.Name,
+		"phase": hook.Phase,
+		"url":   hook.URL,
+	}).Info("Added a new hook.")
+	ret := hook
+	return &ret, nil
+}
+
+func (o *tridentOrchestrator) GetHook(hookName string) *hooks.Config {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	hook, ok := o.hooks[hookName]
+	if !ok {
+		return nil
+	}
+	ret := *hook
+	return &ret
+}
+
+func (o *tridentOrchestrator) ListHooks() []*hooks.Config {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	ret := make([]*hooks.Config, 0, len(o.hooks))
+	for _, hook := range o.hooks {
+		hookCopy := *hook
+		ret = append(ret, &hookCopy)
+	}
+	return ret
+}
+
+func (o *tridentOrchestrator) DeleteHook(hookName string) (bool, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	hook, ok := o.hooks[hookName]
+	if !ok {
+		return false, errors.Errorf(errors.NotFound,
+			"Hook %s not found.", hookName)
+	}
+	if err := o.storeClient.DeleteHook(hook); err != nil {
+		return true, err
+	}
+	delete(o.hooks, hookName)
+	return true, nil
+}
+
+// getHooksForPhase returns copies of the hooks for phase, sorted by name so
+// that they run in a predictable order.
+func (o *tridentOrchestrator) getHooksForPhase(phase hooks.Phase) []*hooks.Config {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	names := make([]string, 0)
+	for name, hook := range o.hooks {
+		if hook.Phase == phase {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	ret := make([]*hooks.Config, 0, len(names))
+	for _, name := range names {
+		hook := *o.hooks[name]
+		ret = append(ret, &hook)
+	}
+	return ret
+}
+
+// runPreProvisionHooks passes volumeConfig through each pre-provision hook
+// in turn and returns the resulting configuration.  Hooks are called
+// without holding the orchestrator lock, so a slow hook only delays the
+// volume it is consulted about.
+func (o *tridentOrchestrator) runPreProvisionHooks(
+	ctx context.Context, volumeConfig *storage.VolumeConfig,
+) (*storage.VolumeConfig, error) {
+	for _, hook := range o.getHooksForPhase(hooks.PreProvision) {
+		response, err := hook.Call(ctx, &hooks.Request{
+			Phase:  hooks.PreProvision,
+			Volume: volumeConfig,
+		})
+		if err != nil {
+			if hook.FailurePolicy == hooks.FailurePolicyIgnore {
+				log.WithFields(log.Fields{
+					"hook":   hook.Name,
+					"volume": volumeConfig.Name,
+				}).Warnf("Ignoring failed hook:  %v", err)
+				continue
+			}
+			return nil, err
+		}
+		if !response.Allowed {
+			return nil, fmt.Errorf("Volume %s rejected by hook %s:  %s",
+				volumeConfig.Name, hook.Name, response.Reason)
+		}
+		if response.Volume != nil {
+			if response.Volume.Name != volumeConfig.Name {
+				return nil, errors.Errorf(errors.InvalidInput, "Hook %s may "+
+					"not rename volume %s.", hook.Name, volumeConfig.Name)
+			}
+			if err = response.Volume.Validate(); err != nil {
+				return nil, fmt.Errorf("Hook %s returned an invalid volume "+
+					"configuration:  %v", hook.Name, err)
+			}
+			volumeConfig = response.Volume
+		}
+	}
+	return volumeConfig, nil
+}
+
+// runPostProvisionHooks notifies each post-provision hook about a newly
+// created volume.  Failures are logged, since the volume already exists.
+func (o *tridentOrchestrator) runPostProvisionHooks(vol *storage.VolumeExternal) {
+	for _, hook := range o.getHooksForPhase(hooks.PostProvision) {
+		// The volume exists regardless of the caller, so notify the hooks
+		// even if the request is canceled.
+		_, err := hook.Call(context.Background(), &hooks.Request{
+			Phase:   hooks.PostProvision,
+			Volume:  vol.Config,
+			Backend: vol.Backend,
+			Pool:    vol.Pool,
+		})
+		if err != nil {
+			log.WithFields(log.Fields{
+				"hook":   hook.Name,
+				"volume": vol.Config.Name,
+			}).Warnf("Post-provision hook failed:  %v", err)
+		}
+	}
+}
+
+// runExpiryHooks notifies each expiry hook that a volume has expired.
+// Failures are logged; the volume is handled as configured regardless.
+func (o *tridentOrchestrator) runExpiryHooks(vol *storage.VolumeExternal) {
+	for _, hook := range o.getHooksForPhase(hooks.Expiry) {
+		_, err := hook.Call(context.Background(), &hooks.Request{
+			Phase:   hooks.Expiry,
+			Volume:  vol.Config,
+			Backend: vol.Backend,
+			Pool:    vol.Pool,
+		})
+		if err != nil {
+			log.WithFields(log.Fields{
+				"hook":   hook.Name,
+				"volume": vol.Config.Name,
+			}).Warnf("Expiry hook failed:  %v", err)
+		}
+	}
+}
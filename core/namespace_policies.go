@@ -0,0 +1,120 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/netapp/trident/errors"
+	"github.com/netapp/trident/namespace_policy"
+	"github.com/netapp/trident/storage"
+)
+
+func copyNamespacePolicy(
+	p *namespace_policy.Config,
+) *namespace_policy.Config {
+	ret := *p
+	if p.AllowedStorageClasses != nil {
+		ret.AllowedStorageClasses = append([]string{},
+			p.AllowedStorageClasses...)
+	}
+	return &ret
+}
+
+func (o *tridentOrchestrator) bootstrapNamespacePolicies() error {
+	policies, err := o.storeClient.GetNamespacePolicies()
+	if err != nil {
+		return err
+	}
+	for _, p := range policies {
+		o.namespacePolicies[p.Namespace] = p
+		log.WithFields(log.Fields{
+			"namespace": p.Namespace,
+			"handler":   "Bootstrap",
+		}).Info("Added an existing namespace policy.")
+	}
+	return nil
+}
+
+// SetNamespacePolicy sets the policy of a namespace, replacing any it had.
+// It applies to volumes created afterwards; existing volumes are left as
+// they are.
+func (o *tridentOrchestrator) SetNamespacePolicy(
+	policyConfig *namespace_policy.Config,
+) (*namespace_policy.Config, error) {
+	if err := policyConfig.Validate(); err != nil {
+		return nil, errors.Errorf(errors.InvalidInput, "%v", err)
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	policy := copyNamespacePolicy(policyConfig)
+	if err := o.storeClient.AddNamespacePolicy(policy); err != nil {
+		return nil, err
+	}
+	o.namespacePolicies[policy.Namespace] = policy
+	log.WithFields(log.Fields{
+		"namespace":             policy.Namespace,
+		"allowedStorageClasses": policy.AllowedStorageClasses,
+		"defaultStorageClass":   policy.DefaultStorageClass,
+		"maxVolumeSize":         policy.MaxVolumeSize,
+	}).Info("Set the namespace's policy.")
+	return copyNamespacePolicy(policy), nil
+}
+
+func (o *tridentOrchestrator) GetNamespacePolicy(
+	namespace string,
+) *namespace_policy.Config {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	policy, ok := o.namespacePolicies[namespace]
+	if !ok {
+		return nil
+	}
+	return copyNamespacePolicy(policy)
+}
+
+func (o *tridentOrchestrator) ListNamespacePolicies() []*namespace_policy.Config {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	ret := make([]*namespace_policy.Config, 0, len(o.namespacePolicies))
+	for _, policy := range o.namespacePolicies {
+		ret = append(ret, copyNamespacePolicy(policy))
+	}
+	return ret
+}
+
+// DeleteNamespacePolicy removes a namespace's policy, leaving its volumes
+// unrestricted.
+func (o *tridentOrchestrator) DeleteNamespacePolicy(
+	namespace string,
+) (bool, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	policy, ok := o.namespacePolicies[namespace]
+	if !ok {
+		return false, errors.Errorf(errors.NotFound,
+			"Namespace policy %s not found.", namespace)
+	}
+	if err := o.storeClient.DeleteNamespacePolicy(policy); err != nil {
+		return true, err
+	}
+	delete(o.namespacePolicies, namespace)
+	return true, nil
+}
+
+// applyNamespacePolicy applies the policy of a volume's namespace, if it has
+// one, to the volume:  it gets the namespace's default storage class if it
+// names none, and an InvalidInput error is returned if its class or size
+// isn't allowed.  The caller must hold the orchestrator lock.
+func (o *tridentOrchestrator) applyNamespacePolicy(
+	volConfig *storage.VolumeConfig,
+) error {
+	policy, ok := o.namespacePolicies[volConfig.Namespace]
+	if volConfig.Namespace == "" || !ok {
+		return nil
+	}
+	if err := policy.Apply(volConfig); err != nil {
+		return errors.Errorf(errors.InvalidInput, "%v", err)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/errors"
+)
+
+// OfflineBackendReport describes what offlining a backend affects.
+type OfflineBackendReport struct {
+	Backend string `json:"backend"`
+	// StorageClasses are the storage classes the backend's pools satisfy;
+	// they lose those pools.
+	StorageClasses []string `json:"storageClasses"`
+	// EmptyStorageClasses are the storage classes left without any pools,
+	// in which no volumes can be created until a backend's pools match them.
+	EmptyStorageClasses []string `json:"emptyStorageClasses"`
+	// Volumes are the backend's volumes, which are stranded on it:  the
+	// backend is kept offline, creating no new volumes, until they're all
+	// deleted.
+	Volumes []string `json:"volumes"`
+	// Deleted is set if the backend has no volumes, in which case it's
+	// deleted rather than kept offline.
+	Deleted bool `json:"deleted"`
+}
+
+// PreviewOfflineBackend reports what offlining a backend would affect,
+// without offlining it.
+func (o *tridentOrchestrator) PreviewOfflineBackend(
+	backendName string,
+) (*OfflineBackendReport, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.offlineBackendReport(backendName)
+}
+
+// OfflineBackendWithReport offlines a backend as OfflineBackend does, and
+// reports what it affected.  If the backend has volumes that would be
+// stranded, it's only offlined if confirm is set; otherwise a Conflict
+// error, whose details are the report, is returned.
+func (o *tridentOrchestrator) OfflineBackendWithReport(
+	backendName string, confirm bool,
+) (*OfflineBackendReport, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	report, err := o.offlineBackendReport(backendName)
+	if err != nil {
+		return nil, err
+	}
+	if len(report.Volumes) > 0 && !confirm {
+		return nil, errors.ErrorfWithDetails(errors.Conflict, report,
+			"Offlining backend %s would strand its volumes:  %s.  Confirm "+
+				"to offline it anyway.", backendName,
+			strings.Join(report.Volumes, ", "))
+	}
+	if _, err = o.offlineBackend(backendName); err != nil {
+		return nil, err
+	}
+	log.WithFields(log.Fields{
+		"backend":        backendName,
+		"storageClasses": report.StorageClasses,
+		"volumes":        report.Volumes,
+		"deleted":        report.Deleted,
+	}).Info("Offlined backend.")
+	return report, nil
+}
+
+// offlineBackendReport reports what offlining a backend would affect.  The
+// pools of a backend that failed to initialize are unknown, so only its
+// volumes are reported.  The caller must hold the orchestrator lock.
+func (o *tridentOrchestrator) offlineBackendReport(
+	backendName string,
+) (*OfflineBackendReport, error) {
+	report := &OfflineBackendReport{
+		Backend:             backendName,
+		StorageClasses:      make([]string, 0),
+		EmptyStorageClasses: make([]string, 0),
+		Volumes:             make([]string, 0),
+	}
+	backend, ok := o.backends[backendName]
+	if !ok {
+		if _, failed := o.failedBackends[backendName]; !failed {
+			return nil, errors.Errorf(errors.NotFound,
+				"Backend %s not found.", backendName)
+		}
+		for name, orphan := range o.orphanedVolumes {
+			if orphan.Backend == backendName {
+				report.Volumes = append(report.Volumes, name)
+			}
+		}
+		sort.Strings(report.Volumes)
+		report.Deleted = len(report.Volumes) == 0
+		return report, nil
+	}
+
+	classes := make(map[string]bool)
+	for _, pool := range backend.Storage {
+		for _, scName := range pool.StorageClasses {
+			classes[scName] = true
+		}
+		for name := range pool.Volumes {
+			report.Volumes = append(report.Volumes, name)
+		}
+	}
+	for scName := range classes {
+		report.StorageClasses = append(report.StorageClasses, scName)
+		sc, ok := o.storageClasses[scName]
+		if !ok {
+			continue
+		}
+		remaining := 0
+		for _, pool := range sc.GetStoragePoolsForProtocol(
+			config.ProtocolAny) {
+			if pool.Backend != backend {
+				remaining++
+			}
+		}
+		if remaining == 0 {
+			report.EmptyStorageClasses = append(report.EmptyStorageClasses,
+				scName)
+		}
+	}
+	sort.Strings(report.StorageClasses)
+	sort.Strings(report.EmptyStorageClasses)
+	sort.Strings(report.Volumes)
+	report.Deleted = len(report.Volumes) == 0
+	return report, nil
+}
@@ -5,6 +5,7 @@ package core
 import (
 	"fmt"
 	"math/rand"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -13,38 +14,204 @@ import (
 	dvp "github.com/netapp/netappdvp/storage_drivers"
 	"golang.org/x/net/context"
 
+	"github.com/netapp/trident/app_template"
 	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/errors"
+	"github.com/netapp/trident/faults"
 	"github.com/netapp/trident/frontend"
+	"github.com/netapp/trident/hooks"
+	"github.com/netapp/trident/logging"
+	"github.com/netapp/trident/namespace_policy"
 	"github.com/netapp/trident/persistent_store"
+	"github.com/netapp/trident/snapshot_policy"
 	"github.com/netapp/trident/storage"
 	"github.com/netapp/trident/storage/factory"
+	sa "github.com/netapp/trident/storage_attribute"
 	"github.com/netapp/trident/storage_class"
+	"github.com/netapp/trident/tracing"
 )
 
 type tridentOrchestrator struct {
-	backends       map[string]*storage.StorageBackend
-	volumes        map[string]*storage.Volume
-	frontends      map[string]frontend.FrontendPlugin
-	mutex          *sync.Mutex
-	storageClasses map[string]*storage_class.StorageClass
-	storeClient    persistent_store.Client
-	bootstrapped   bool
+	backends         map[string]*storage.StorageBackend
+	volumes          map[string]*storage.Volume
+	orphanedVolumes  map[string]*storage.VolumeExternal
+	retainedVolumes  map[string]*persistent_store.RetainedVolume
+	frontends        map[string]frontend.FrontendPlugin
+	mutex            *orchestratorLock
+	storageClasses   map[string]*storage_class.StorageClass
+	storeClient      persistent_store.Client
+	bootstrapped     bool
+	events           *eventBus
+	provisioning     *provisioningNotifier
+	hooks            map[string]*hooks.Config
+	snapshotPolicies map[string]*snapshot_policy.Config
+	bootstrapConfig  config.BootstrapConfig
+	// failedBackends holds backends that failed to initialize at bootstrap
+	// while initialization is deferred; see SetDeferredBackendInit.
+	failedBackends  map[string]*failedBackend
+	placementPolicy string
+	timeouts        config.TimeoutConfig
+	janitorConfig   config.JanitorConfig
+	// placementRand orders pools under the random placement policy; see
+	// SetPlacementSeed.
+	placementRand *rand.Rand
+	// txnAttempts tracks transactions the janitor failed to resolve, keyed
+	// by volume name.
+	txnAttempts map[string]*txnAttempts
+	// deletionGracePeriod is how long deleted volumes are kept; see
+	// SetDeletionGracePeriod.
+	deletionGracePeriod time.Duration
+	// provisioningRetries holds the failed provisioning attempts recorded
+	// by frontends, by volume name.
+	provisioningRetries map[string]*persistent_store.ProvisioningRetry
+	// volumeGroups holds the volume groups, by name.  pendingVolumeGroups
+	// holds the groups being created, with the backend chosen for them.
+	volumeGroups        map[string]*persistent_store.VolumeGroup
+	pendingVolumeGroups map[string]string
+	// deletingVolumes holds the volumes DeleteVolumes is destroying on
+	// their backends without the orchestrator lock.
+	deletingVolumes map[string]bool
+	// templates holds the provisioning templates, by name.
+	templates map[string]*app_template.Config
+	// namespacePolicies holds the namespace policies, by namespace.
+	namespacePolicies map[string]*namespace_policy.Config
+	// volumeExpiry controls the handling of expired volumes; see
+	// SetVolumeExpiryConfig.
+	volumeExpiry config.VolumeExpiryConfig
+	// rebalanceReport is the latest rebalancing analysis; see
+	// analyzeRebalance.
+	rebalanceReport *RebalanceReport
+	// health holds the state reported by GetHealth; see healthState.
+	health *healthState
+	// strictStorageClasses rejects storage classes whose attribute
+	// requests don't match their schemas; see SetStrictStorageClasses.
+	strictStorageClasses bool
+	// protectStorageClasses refuses to delete storage classes that volumes
+	// still use; see SetProtectStorageClasses.
+	protectStorageClasses bool
 }
 
 // returns a storage orchestrator instance
 func NewTridentOrchestrator(client persistent_store.Client) *tridentOrchestrator {
 	orchestrator := tridentOrchestrator{
-		backends:       make(map[string]*storage.StorageBackend),
-		volumes:        make(map[string]*storage.Volume),
-		frontends:      make(map[string]frontend.FrontendPlugin),
-		storageClasses: make(map[string]*storage_class.StorageClass),
-		mutex:          &sync.Mutex{},
-		storeClient:    client,
-		bootstrapped:   false,
-	}
+		backends:         make(map[string]*storage.StorageBackend),
+		volumes:          make(map[string]*storage.Volume),
+		orphanedVolumes:  make(map[string]*storage.VolumeExternal),
+		retainedVolumes:  make(map[string]*persistent_store.RetainedVolume),
+		frontends:        make(map[string]frontend.FrontendPlugin),
+		storageClasses:   make(map[string]*storage_class.StorageClass),
+		mutex:            newOrchestratorLock(),
+		storeClient:      client,
+		bootstrapped:     false,
+		events:           newEventBus(),
+		provisioning:     newProvisioningNotifier(),
+		hooks:            make(map[string]*hooks.Config),
+		snapshotPolicies: make(map[string]*snapshot_policy.Config),
+		bootstrapConfig:  *config.NewBootstrapConfig(),
+		failedBackends:   make(map[string]*failedBackend),
+		placementPolicy:  config.PlacementRandom,
+		timeouts:         *config.NewTimeoutConfig(),
+		janitorConfig:    *config.NewJanitorConfig(),
+		txnAttempts:      make(map[string]*txnAttempts),
+	}
+	orchestrator.provisioningRetries = make(
+		map[string]*persistent_store.ProvisioningRetry)
+	orchestrator.volumeGroups = make(
+		map[string]*persistent_store.VolumeGroup)
+	orchestrator.pendingVolumeGroups = make(map[string]string)
+	orchestrator.deletingVolumes = make(map[string]bool)
+	orchestrator.templates = make(map[string]*app_template.Config)
+	orchestrator.namespacePolicies = make(
+		map[string]*namespace_policy.Config)
+	orchestrator.volumeExpiry = *config.NewVolumeExpiryConfig()
+	orchestrator.health = newHealthState()
+	orchestrator.placementRand = rand.New(
+		rand.NewSource(time.Now().UnixNano()))
 	return &orchestrator
 }
 
+// SetDeferredBackendInit controls whether Bootstrap tolerates backends
+// whose storage systems are unreachable.  Such backends are retried in the
+// background; their volumes are orphaned until the backend initializes.
+func (o *tridentOrchestrator) SetDeferredBackendInit(enabled bool) {
+	o.bootstrapConfig.DeferBackendInit = enabled
+}
+
+// SetResilientBootstrap controls whether Bootstrap quarantines corrupt
+// backend and volume records, logging them and continuing, rather than
+// failing.  Quarantined records are listed by ListQuarantinedRecords.
+func (o *tridentOrchestrator) SetResilientBootstrap(enabled bool) {
+	o.bootstrapConfig.Resilient = enabled
+}
+
+// SetBootstrapConfig replaces all of the bootstrap settings.  It must be
+// called before Bootstrap.
+func (o *tridentOrchestrator) SetBootstrapConfig(c *config.BootstrapConfig) {
+	o.bootstrapConfig = *c
+}
+
+// SetPlacementPolicy sets the order in which a storage class's pools are
+// tried for new volumes; see config.PlacementRandom and
+// config.PlacementOrdered.
+func (o *tridentOrchestrator) SetPlacementPolicy(policy string) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.placementPolicy = policy
+}
+
+// SetPlacementSeed seeds the random placement policy, so that the pools
+// tried for a sequence of volumes can be reproduced, e.g., when debugging.
+// A seed of zero seeds it from the clock, as NewTridentOrchestrator does.
+func (o *tridentOrchestrator) SetPlacementSeed(seed int64) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	o.placementRand = rand.New(rand.NewSource(seed))
+}
+
+// SetStrictStorageClasses controls whether storage classes requesting
+// attribute values their schemas don't allow, e.g., a misspelled media
+// type, are rejected rather than added with warnings.  It must be called
+// before any storage classes are added.
+func (o *tridentOrchestrator) SetStrictStorageClasses(strict bool) {
+	o.strictStorageClasses = strict
+}
+
+// SetProtectStorageClasses controls whether storage classes that volumes
+// still use are protected from deletion.  A protected class isn't deleted
+// unless forced; it's marked pending deletion instead, and is deleted once
+// its last volume is.
+func (o *tridentOrchestrator) SetProtectStorageClasses(protect bool) {
+	o.protectStorageClasses = protect
+}
+
+// SetTimeouts sets how long volume creation and deletion and backend
+// addition may take.  The store timeout is set on the store client itself.
+func (o *tridentOrchestrator) SetTimeouts(c *config.TimeoutConfig) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.timeouts = *c
+}
+
+// SetDeletionGracePeriod sets how long deleted volumes are kept on their
+// backends, during which they are terminating and can be undeleted.  With
+// a zero grace period, volumes are deleted immediately.
+func (o *tridentOrchestrator) SetDeletionGracePeriod(
+	gracePeriod time.Duration,
+) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.deletionGracePeriod = gracePeriod
+}
+
+// SetJanitorConfig sets how stale volume transactions are resolved.  It
+// must be called before Bootstrap.
+func (o *tridentOrchestrator) SetJanitorConfig(c *config.JanitorConfig) {
+	o.janitorConfig = *c
+}
+
 func (o *tridentOrchestrator) Bootstrap() error {
 	var err error = nil
 	dvp.ExtendedDriverVersion = config.OrchestratorName + "-" +
@@ -56,7 +223,22 @@ func (o *tridentOrchestrator) Bootstrap() error {
 			"volume, or storage class state: %s", err.Error())
 		return fmt.Errorf(errMsg)
 	}
+	// Discard anything listed while the state was being loaded.
+	o.mutex.Lock()
+	o.markDirty()
+	o.mutex.Unlock()
 	o.bootstrapped = true
+	o.health.setFailedBackends(len(o.failedBackends))
+	o.health.setBootstrapped()
+	o.startSnapshotScheduler()
+	o.startReplicationMonitor()
+	o.startTransactionJanitor()
+	o.startVolumeReaper()
+	o.startRebalanceAnalyzer()
+	o.startCredentialRotation()
+	if len(o.failedBackends) > 0 {
+		o.startBackendInitRetries()
+	}
 	log.Infof("%s bootstrapped successfully.", config.OrchestratorName)
 	return err
 }
@@ -64,15 +246,15 @@ func (o *tridentOrchestrator) Bootstrap() error {
 func (o *tridentOrchestrator) bootstrapBackends() error {
 	var tries int
 
-	persistentBackends, err := o.storeClient.GetBackends()
-	for tries = 0; err == context.DeadlineExceeded && tries < config.MaxBootstrapAttempts; tries++ {
+	persistentBackends, err := o.getBackendsForBootstrap()
+	for tries = 0; err == context.DeadlineExceeded && tries < o.bootstrapConfig.MaxAttempts; tries++ {
 		// Wait up to ten seconds for etcd to come online if unavailable.
 		time.Sleep(time.Second)
-		persistentBackends, err = o.storeClient.GetBackends()
+		persistentBackends, err = o.getBackendsForBootstrap()
 	}
 
 	if err != nil {
-		if tries == config.MaxBootstrapAttempts {
+		if tries == o.bootstrapConfig.MaxAttempts {
 			log.Warnf("Persistent store failed to come online after %d seconds.", tries)
 		}
 		return err
@@ -80,21 +262,62 @@ func (o *tridentOrchestrator) bootstrapBackends() error {
 		log.Infof("Persistent store is up after %d second(s).", tries)
 	}
 
+	// Initialize the drivers concurrently, since each may wait on its
+	// storage system, then add the backends in order.
+	configs := make([]string, 0, len(persistentBackends))
+	backends := make([]*storage.StorageBackendPersistent, 0,
+		len(persistentBackends))
 	for _, b := range persistentBackends {
 		// TODO:  If the API evolves, check the Version field here.
 		serializedConfig, err := b.MarshalConfig()
 		if err != nil {
-			return err
+			if !o.bootstrapConfig.Resilient {
+				return err
+			}
+			if err = o.quarantineRecord(persistent_store.BackendRecord,
+				b.Name, err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+		configs = append(configs, serializedConfig)
+		backends = append(backends, b)
+	}
+	storageBackends := make([]*storage.StorageBackend, len(configs))
+	errs := make([]error, len(configs))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, o.bootstrapConfig.Workers)
+	for i := range configs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			storageBackends[i], errs[i] =
+				factory.NewStorageBackendForStoredConfig(configs[i])
+			<-sem
+		}(i)
+	}
+	wg.Wait()
+
+	for i, b := range backends {
+		if errs[i] != nil {
+			if !o.bootstrapConfig.DeferBackendInit {
+				return errs[i]
+			}
+			o.mutex.Lock()
+			o.addFailedBackend(b, configs[i], errs[i])
+			o.mutex.Unlock()
+			continue
+		}
+		o.mutex.Lock()
+		_, err = o.addStorageBackend(context.Background(), storageBackends[i])
+		if err == nil {
+			o.backends[b.Name].Online = b.Online
 		}
-		_, err = o.AddStorageBackend(serializedConfig)
+		o.mutex.Unlock()
 		if err != nil {
 			return err
 		}
-
-		// Note that AddStorageBackend returns an external copy of the newly
-		// added backend, so we have to go fetch it manually.
-		newBackend := o.backends[b.Name]
-		newBackend.Online = b.Online
 		log.WithFields(log.Fields{
 			"backend": b.Name,
 			"handler": "Bootstrap",
@@ -103,6 +326,87 @@ func (o *tridentOrchestrator) bootstrapBackends() error {
 	return nil
 }
 
+// getBackendsForBootstrap reads the stored backends.  In resilient mode,
+// corrupt records are quarantined until the rest can be read.
+func (o *tridentOrchestrator) getBackendsForBootstrap() (
+	[]*storage.StorageBackendPersistent, error,
+) {
+	for {
+		backends, err := o.storeClient.GetBackends()
+		corrupt, ok := err.(*persistent_store.CorruptRecordError)
+		if !ok || !o.bootstrapConfig.Resilient {
+			return backends, err
+		}
+		if err = o.quarantineRecord(corrupt.Type, corrupt.Name,
+			corrupt.Error()); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// getVolumesForBootstrap is the volume counterpart of
+// getBackendsForBootstrap.
+func (o *tridentOrchestrator) getVolumesForBootstrap() (
+	[]*storage.VolumeExternal, error,
+) {
+	for {
+		volumes, err := o.storeClient.GetVolumes()
+		corrupt, ok := err.(*persistent_store.CorruptRecordError)
+		if !ok || !o.bootstrapConfig.Resilient {
+			return volumes, err
+		}
+		if err = o.quarantineRecord(corrupt.Type, corrupt.Name,
+			corrupt.Error()); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (o *tridentOrchestrator) quarantineRecord(
+	recordType persistent_store.RecordType, name, reason string,
+) error {
+	if err := o.storeClient.QuarantineRecord(recordType, name,
+		reason); err != nil {
+		return fmt.Errorf("Unable to quarantine %s record %s:  %v",
+			recordType, name, err)
+	}
+	log.WithFields(log.Fields{
+		"type":    recordType,
+		"name":    name,
+		"reason":  reason,
+		"handler": "Bootstrap",
+	}).Error("Quarantined a corrupt record; repair it and move it back to " +
+		"restore it.")
+	return nil
+}
+
+// ListQuarantinedRecords returns the records set aside during bootstrap.
+func (o *tridentOrchestrator) ListQuarantinedRecords() (
+	[]*persistent_store.QuarantinedRecord, error,
+) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	records, err := o.storeClient.GetQuarantinedRecords()
+	if err != nil {
+		if err.Error() == persistent_store.KeyErrorMsg {
+			return make([]*persistent_store.QuarantinedRecord, 0), nil
+		}
+		return nil, err
+	}
+	return records, nil
+}
+
+// GetLoggingConfig returns the process's logging configuration.
+func (o *tridentOrchestrator) GetLoggingConfig() *logging.Config {
+	return logging.GetConfig()
+}
+
+// SetLoggingConfig changes the process's log levels and format.
+func (o *tridentOrchestrator) SetLoggingConfig(c *logging.Config) error {
+	return logging.SetConfig(c)
+}
+
 func (o *tridentOrchestrator) bootstrapStorageClasses() error {
 	persistentStorageClasses, err := o.storeClient.GetStorageClasses()
 	if err != nil {
@@ -123,26 +427,44 @@ func (o *tridentOrchestrator) bootstrapStorageClasses() error {
 	return nil
 }
 
+// bootstrapPendingStorageClassDeletes deletes the storage classes pending
+// deletion whose last volumes were deleted while Trident wasn't running.
+func (o *tridentOrchestrator) bootstrapPendingStorageClassDeletes() error {
+	for name := range o.storageClasses {
+		o.finishStorageClassDelete(name)
+	}
+	return nil
+}
+
 func (o *tridentOrchestrator) bootstrapVolumes() error {
-	volumes, err := o.storeClient.GetVolumes()
+	volumes, err := o.getVolumesForBootstrap()
 	if err != nil {
 		return err
 	}
-	for _, v := range volumes {
-		// TODO:  If the API evolves, check the Version field here.
-		var backend *storage.StorageBackend
-		var ok bool
-		backend, ok = o.backends[v.Backend]
-		if !ok {
-			return fmt.Errorf("Couldn't find backend %s for volume %s!",
-				v.Backend, v.Config.Name)
-		}
-		vc, ok := backend.Storage[v.Pool]
-		if !ok {
-			return fmt.Errorf("Couldn't find storage pool %s on backend %s!",
-				v.Pool, v.Backend)
+
+	// Rebuild the volumes concurrently, as with the backends, then add them
+	// to their pools in order.
+	vols := make([]*storage.Volume, len(volumes))
+	reasons := make([]string, len(volumes))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, o.bootstrapConfig.Workers)
+	for i := range volumes {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			vols[i], reasons[i] = o.rebuildVolume(volumes[i])
+			<-sem
+		}(i)
+	}
+	wg.Wait()
+
+	for i, v := range volumes {
+		if vols[i] == nil {
+			o.addOrphanedVolume(v, reasons[i])
+			continue
 		}
-		vol := storage.NewVolume(v.Config, backend, vc)
+		vol := vols[i]
 		vol.Pool.AddVolume(vol, true)
 		o.volumes[vol.Config.Name] = vol
 		log.WithFields(log.Fields{
@@ -153,6 +475,51 @@ func (o *tridentOrchestrator) bootstrapVolumes() error {
 	return nil
 }
 
+// rebuildVolume reconstructs a stored volume on its backend and pool, or
+// returns why it can't be.  It only reads the orchestrator's backends, so
+// bootstrapVolumes may run it concurrently.
+func (o *tridentOrchestrator) rebuildVolume(v *storage.VolumeExternal) (
+	*storage.Volume, string,
+) {
+	// TODO:  If the API evolves, check the Version field here.
+	backend, ok := o.backends[v.Backend]
+	if !ok {
+		if _, failed := o.failedBackends[v.Backend]; failed {
+			return nil, "The volume's backend has not initialized."
+		}
+		return nil, "Couldn't find the volume's backend."
+	}
+	vc, ok := backend.Storage[v.Pool]
+	if !ok {
+		return nil, "Couldn't find the volume's storage pool."
+	}
+	vol := storage.NewVolume(v.Config, backend, vc)
+	vol.Attachments = v.Attachments
+	vol.Deletion = v.Deletion
+	vol.Replication = v.Replication
+	return vol, ""
+}
+
+// addOrphanedVolume keeps a volume whose backend or pool is missing, so
+// that it can still be read and later reattached with ReattachVolume.
+func (o *tridentOrchestrator) addOrphanedVolume(
+	v *storage.VolumeExternal, reason string,
+) {
+	orphan := *v
+	orphan.Orphaned = true
+	// Volumes stored by earlier versions lack the access details.
+	if orphan.AccessInfo == nil {
+		orphan.AccessInfo = orphan.Config.AccessInfo.Details()
+	}
+	o.orphanedVolumes[orphan.Config.Name] = &orphan
+	log.WithFields(log.Fields{
+		"volume":  orphan.Config.Name,
+		"backend": orphan.Backend,
+		"pool":    orphan.Pool,
+		"handler": "Bootstrap",
+	}).Warnf("%s  Added the volume as orphaned.", reason)
+}
+
 func (o *tridentOrchestrator) bootstrapVolTxns() error {
 	volTxns, err := o.storeClient.GetVolumeTransactions()
 	if err != nil {
@@ -173,8 +540,14 @@ func (o *tridentOrchestrator) bootstrap() error {
 	// Fetching backend information
 
 	type bootstrapFunc func() error
-	for _, f := range []bootstrapFunc{o.bootstrapBackends,
-		o.bootstrapStorageClasses, o.bootstrapVolumes, o.bootstrapVolTxns} {
+	for _, f := range []bootstrapFunc{o.bootstrapBackendTxns,
+		o.bootstrapBackends,
+		o.bootstrapStorageClasses, o.bootstrapVolumes,
+		o.bootstrapRetainedVolumes, o.bootstrapProvisioningRetries,
+		o.bootstrapVolumeGroups, o.bootstrapTemplates,
+		o.bootstrapNamespacePolicies,
+		o.bootstrapVolTxns, o.bootstrapPendingStorageClassDeletes,
+		o.bootstrapHooks, o.bootstrapSnapshotPolicies} {
 		err := f()
 		if err != nil {
 			if err.Error() == persistent_store.KeyErrorMsg {
@@ -222,7 +595,7 @@ func (o *tridentOrchestrator) rollBackTransaction(v *persistent_store.VolumeTran
 			// If the volume was added to etcd, we will have loaded the
 			// volume into memory, and we can just delete it normally.
 			// Handles case 3)
-			err := o.deleteVolume(v.Config.Name)
+			err := o.deleteVolume(context.Background(), v.Config.Name)
 			if err != nil {
 				return fmt.Errorf("Unable to clean up volume %s:  %v",
 					v.Config.Name, err)
@@ -240,13 +613,16 @@ func (o *tridentOrchestrator) rollBackTransaction(v *persistent_store.VolumeTran
 					// so we can safely skip offline backends.
 					continue
 				}
-				// TODO:  Change this to check the error type when backends
-				// return a standardized error when a volume is not found.
-				// For now, though, fail on an error, since backends currently
-				// do not report errors for volumes not present.
-				if err := backend.Driver.Destroy(
-					backend.Driver.GetInternalVolumeName(v.Config.Name),
-				); err != nil {
+				// The volume is usually absent from all but one backend,
+				// or from all of them if it was never created.
+				internalName, err := backend.InternalVolumeName(v.Config)
+				if err != nil {
+					// The name is too long for the backend, so the volume
+					// can't have been created there.
+					continue
+				}
+				err = backend.Driver.Destroy(internalName)
+				if err != nil && !errors.IsNotFound(err) {
 					return fmt.Errorf("Error attempting to clean up volume %s "+
 						"from backend %s:  %v", v.Config.Name, backend.Name,
 						err)
@@ -270,7 +646,7 @@ func (o *tridentOrchestrator) rollBackTransaction(v *persistent_store.VolumeTran
 			log.WithFields(log.Fields{
 				"name": v.Config.Name,
 			}).Info("Volume for delete transaction found.")
-			err := o.deleteVolume(v.Config.Name)
+			err := o.deleteVolume(context.Background(), v.Config.Name)
 			if err != nil {
 				return fmt.Errorf("Unable to clean up deleted volume %s:  %v",
 					v.Config.Name, err)
@@ -284,6 +660,34 @@ func (o *tridentOrchestrator) rollBackTransaction(v *persistent_store.VolumeTran
 			return fmt.Errorf("Failed to clean up volume deletion transaction:"+
 				"  %v", err)
 		}
+	case persistent_store.RenameVolume:
+		// The volume is stored under its new name before its old record is
+		// removed, so if the new record exists, finish the rename;
+		// otherwise, the volume still has its old name and nothing changed.
+		if _, ok := o.volumes[v.Config.Name]; ok {
+			if oldVolume, ok := o.volumes[v.OldName]; ok {
+				if err := o.storeClient.DeleteVolumeIgnoreNotFound(
+					oldVolume); err != nil {
+					return fmt.Errorf("Unable to finish renaming volume %s "+
+						"to %s:  %v", v.OldName, v.Config.Name, err)
+				}
+				oldVolume.Pool.DeleteVolume(oldVolume)
+				delete(o.volumes, v.OldName)
+				o.markDirty()
+			}
+			log.WithFields(log.Fields{
+				"oldName": v.OldName,
+				"name":    v.Config.Name,
+			}).Info("Finished renaming volume.")
+		}
+		if err := o.storeClient.DeleteVolumeTransaction(v); err != nil {
+			return fmt.Errorf("Failed to clean up volume rename transaction:"+
+				"  %v", err)
+		}
+	case persistent_store.TerminateVolume, persistent_store.UndeleteVolume:
+		if err := o.rollBackVolumeDeletionChange(v); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -302,16 +706,26 @@ func (o *tridentOrchestrator) AddFrontend(f frontend.FrontendPlugin) {
 	o.frontends[name] = f
 }
 
+// GetFrontend returns the frontend with the given name, e.g., so that other
+// frontends can use features specific to it.  Like AddFrontend, it expects
+// frontends to be added before Trident starts serving requests.
+func (o *tridentOrchestrator) GetFrontend(
+	name string,
+) (frontend.FrontendPlugin, error) {
+	f, ok := o.frontends[name]
+	if !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Frontend %s not found.", name)
+	}
+	return f, nil
+}
+
 func (o *tridentOrchestrator) validateBackendUpdate(
 	oldBackend *storage.StorageBackend, newBackend *storage.StorageBackend,
 ) error {
 	var err error
 	err = nil
 	errorList := make([]string, 0)
-	// Validate that protocols haven't changed
-	if oldBackend.GetProtocol() != newBackend.GetProtocol() {
-		errorList = append(errorList, "Cannot change backend protocol")
-	}
 
 	// Validate that the storage pools of the updated backend contain enough
 	// capacity to accommodate all of the original backend's volumes.
@@ -322,12 +736,23 @@ func (o *tridentOrchestrator) validateBackendUpdate(
 		}
 	}
 	for _, vcName := range usedPools {
-		if _, ok := newBackend.Storage[vcName]; !ok {
+		newVC, ok := newBackend.Storage[vcName]
+		if !ok {
 			// If a storage pool that contained volumes isn't present in
 			// the new config, we can't use the new config.
 			errorList = append(errorList,
 				fmt.Sprintf("In-use storage pool %s not present in "+
 					"updated backend", vcName))
+			continue
+		}
+		// Validate that the protocols of in-use pools haven't changed;
+		// other pools may change protocol, or be added with another one.
+		oldProtocol := oldBackend.Storage[vcName].GetProtocol()
+		if newVC.GetProtocol() != oldProtocol {
+			errorList = append(errorList,
+				fmt.Sprintf("Cannot change protocol of in-use storage pool "+
+					"%s from %s to %s", vcName, oldProtocol,
+					newVC.GetProtocol()))
 		}
 	}
 
@@ -372,21 +797,69 @@ func (o *tridentOrchestrator) GetVersion() string {
 	return config.OrchestratorVersion
 }
 
-func (o *tridentOrchestrator) AddStorageBackend(configJSON string) (
-	*storage.StorageBackendExternal, error) {
-	var (
-		protocol config.Protocol
-	)
-
+// AddStorageBackend adds or updates a backend, returning it with any
+// warnings about it.  If ctx is done or the backend add timeout expires
+// before the backend is stored, it is not added.
+func (o *tridentOrchestrator) AddStorageBackend(
+	ctx context.Context, configJSON string,
+) (result *BackendResult, err error) {
+	ctx, span := tracing.StartSpan(ctx, tracing.Core, "AddStorageBackend",
+		nil)
+	defer func() {
+		if result != nil {
+			span.SetTag("backend", result.Name)
+		}
+		span.Finish(err)
+	}()
 	o.mutex.Lock()
 	defer o.mutex.Unlock()
 
-	storageBackend, err := factory.NewStorageBackendForConfig(configJSON)
+	ctx, cancel := context.WithTimeout(ctx, o.timeouts.BackendAddTimeout())
+	defer cancel()
+	var storageBackend *storage.StorageBackend
+	err = callWithContext(ctx, "Backend initialization", nil, func() error {
+		var initErr error
+		storageBackend, initErr = factory.NewStorageBackendForConfig(
+			configJSON)
+		return initErr
+	}, nil)
 	if err != nil {
 		return nil, err
 	}
+	if err = checkContext(ctx, "Backend addition"); err != nil {
+		return nil, err
+	}
+	return o.addStorageBackend(ctx, storageBackend)
+}
+
+// AddStorageBackends adds or updates each of several backends, as
+// AddStorageBackend does, reporting the result of each.  A backend that
+// fails doesn't stop the rest from being added.
+func (o *tridentOrchestrator) AddStorageBackends(
+	ctx context.Context, configs []string,
+) *BackendsResult {
+	result := &BackendsResult{
+		Backends: make([]*BackendItemResult, 0, len(configs)),
+	}
+	for _, configJSON := range configs {
+		result.add(o.AddStorageBackend(ctx, configJSON))
+	}
+	log.WithFields(log.Fields{
+		"backends": len(configs),
+		"failed":   result.Failed,
+	}).Info("Added backends.")
+	return result
+}
+
+// addStorageBackend adds or updates an initialized backend.  The caller must
+// hold the orchestrator lock.
+func (o *tridentOrchestrator) addStorageBackend(
+	ctx context.Context, storageBackend *storage.StorageBackend,
+) (*BackendResult, error) {
+	var err error
+
 	newBackend := true
-	protocol = storageBackend.GetProtocol()
+	protocols := storageBackend.Protocols()
 	originalBackend, ok := o.backends[storageBackend.Name]
 	if ok {
 		newBackend = false
@@ -394,16 +867,42 @@ func (o *tridentOrchestrator) AddStorageBackend(configJSON string) (
 			return nil, err
 		}
 	}
+	// A backend awaiting initialization is already stored.
+	_, wasFailed := o.failedBackends[storageBackend.Name]
+
+	if storageBackend.Chap.IsSet() {
+		// Keep the credentials hosts already use unless new ones are set.
+		if !newBackend {
+			storageBackend.Chap.Inherit(&originalBackend.Chap)
+		}
+		if err = storageBackend.ApplyChap(); err != nil {
+			return nil, err
+		}
+		logging.AddSecret(storageBackend.Chap.ChapInitiatorSecret)
+		logging.AddSecret(storageBackend.Chap.ChapTargetInitiatorSecret)
+	}
+	if storageBackend.Rotation.IsSet() {
+		if err = storageBackend.CheckRotation(); err != nil {
+			return nil, err
+		}
+		o.initializeRotation(storageBackend, originalBackend)
+	}
 
 	log.WithFields(log.Fields{
 		"backendName": storageBackend.Name,
-		"protocol":    protocol,
+		"protocols":   protocols,
 		"newBackend":  newBackend,
 	}).Debug("Adding backend.")
-	if err = o.updateBackendOnPersistentStore(storageBackend, newBackend); err != nil {
+	if err = o.updateBackendOnPersistentStore(ctx, storageBackend,
+		newBackend && !wasFailed); err != nil {
 		return nil, err
 	}
 	o.backends[storageBackend.Name] = storageBackend
+	if wasFailed {
+		delete(o.failedBackends, storageBackend.Name)
+		o.health.setFailedBackends(len(o.failedBackends))
+		o.adoptOrphanedVolumes(storageBackend)
+	}
 
 	classes := make([]string, 0, len(o.storageClasses))
 	for _, storageClass := range o.storageClasses {
@@ -417,12 +916,12 @@ func (o *tridentOrchestrator) AddStorageBackend(configJSON string) (
 	if len(classes) == 0 {
 		log.WithFields(log.Fields{
 			"backendName": storageBackend.Name,
-			"protocol":    protocol,
+			"protocols":   protocols,
 		}).Info("Newly added backend satisfies no storage classes.")
 	} else {
 		log.WithFields(log.Fields{
 			"backendName": storageBackend.Name,
-			"protocol":    protocol,
+			"protocols":   protocols,
 		}).Infof("Newly added backend satisfies storage classes %s.",
 			strings.Join(classes, ", "))
 	}
@@ -437,38 +936,75 @@ func (o *tridentOrchestrator) AddStorageBackend(configJSON string) (
 			}
 		}
 	}
-	return storageBackend.ConstructExternal(), nil
+	o.markDirty()
+	external := storageBackend.ConstructExternal()
+	if newBackend {
+		o.events.publish(EventCreate, EventObjectBackend, external.Name, external)
+	} else {
+		o.events.publish(EventUpdate, EventObjectBackend, external.Name, external)
+	}
+	return &BackendResult{
+		StorageBackendExternal: external,
+		Warnings:               backendWarnings(storageBackend, classes),
+	}, nil
 }
 
+// GetBackend returns the named backend, or nil if there is none.  The
+// backend is shared with other callers and must not be modified.
 func (o *tridentOrchestrator) GetBackend(backend string) *storage.StorageBackendExternal {
 	o.mutex.Lock()
 	defer o.mutex.Unlock()
-	var storageBackend *storage.StorageBackend
-	var found bool
-	if storageBackend, found = o.backends[backend]; !found {
-		return nil
-	}
-	return storageBackend.ConstructExternal()
+	return o.externalBackends().backends[backend]
 }
 
+// ListBackends returns the online backends.  The backends are shared with
+// other callers and must not be modified, though the slice may be.
 func (o *tridentOrchestrator) ListBackends() []*storage.StorageBackendExternal {
 	o.mutex.Lock()
 	defer o.mutex.Unlock()
-	backends := make([]*storage.StorageBackendExternal, 0)
-	for _, b := range o.backends {
-		if b.Online {
-			backends = append(backends, b.ConstructExternal())
+	online := o.externalBackends().onlineBackends
+	backends := make([]*storage.StorageBackendExternal, len(online))
+	copy(backends, online)
+	return backends
+}
+
+// GenerateStorageClasses suggests storage classes for a backend's pools,
+// one for each distinct set of capabilities.  Nothing is added; the
+// administrator reviews the suggestions and adds those that are wanted.
+func (o *tridentOrchestrator) GenerateStorageClasses(
+	backendName string,
+) ([]*storage_class.Recommendation, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	backend, ok := o.backends[backendName]
+	if !ok {
+		if _, failed := o.failedBackends[backendName]; failed {
+			return nil, errors.Errorf(errors.Conflict, "Backend %s failed "+
+				"to initialize; its storage pools are unknown.", backendName)
 		}
+		return nil, errors.Errorf(errors.NotFound,
+			"Backend %s not found.", backendName)
 	}
-	return backends
+	return storage_class.Recommend(backend), nil
 }
 
+// OfflineBackend offlines a backend even if its volumes would be stranded;
+// see OfflineBackendWithReport.
 func (o *tridentOrchestrator) OfflineBackend(backendName string) (bool, error) {
 	o.mutex.Lock()
 	defer o.mutex.Unlock()
+	return o.offlineBackend(backendName)
+}
 
+// offlineBackend offlines a backend, deleting it if it has no volumes.  The
+// caller must hold the orchestrator lock.
+func (o *tridentOrchestrator) offlineBackend(backendName string) (bool, error) {
 	backend, found := o.backends[backendName]
 	if !found {
+		if _, failed := o.failedBackends[backendName]; failed {
+			return true, o.deleteFailedBackend(backendName)
+		}
 		return false, nil
 	}
 	backend.Online = false
@@ -482,15 +1018,161 @@ func (o *tridentOrchestrator) OfflineBackend(backendName string) (bool, error) {
 	for _, sc := range storageClasses {
 		sc.RemovePoolsForBackend(backend)
 	}
+	o.markDirty()
 	if !backend.HasVolumes() {
 		delete(o.backends, backendName)
-		return true, o.storeClient.DeleteBackend(backend)
+		if err := o.storeClient.DeleteBackend(backend); err != nil {
+			return true, err
+		}
+		o.events.publish(EventDelete, EventObjectBackend, backendName,
+			backend.ConstructExternal())
+		return true, nil
+	}
+	if err := o.storeClient.UpdateBackend(backend); err != nil {
+		return true, err
+	}
+	o.events.publish(EventUpdate, EventObjectBackend, backendName,
+		backend.ConstructExternal())
+	return true, nil
+}
+
+// OnlineBackend reverses OfflineBackend:  it marks an offline backend online
+// and adds its storage pools back to the storage classes they satisfy,
+// without its config being added again.  The backend is returned with any
+// warnings about it, e.g., that it satisfies no storage classes.  A backend
+// that is already online is returned as it is.
+func (o *tridentOrchestrator) OnlineBackend(
+	backendName string,
+) (*BackendResult, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	backend, ok := o.backends[backendName]
+	if !ok {
+		if _, failed := o.failedBackends[backendName]; failed {
+			return nil, errors.Errorf(errors.Conflict, "Backend %s has not "+
+				"initialized; add its config again to retry.", backendName)
+		}
+		return nil, errors.Errorf(errors.NotFound,
+			"Backend %s not found.", backendName)
+	}
+	if backend.Online {
+		return &BackendResult{
+			StorageBackendExternal: backend.ConstructExternal(),
+		}, nil
+	}
+	backend.Online = true
+	if err := o.storeClient.UpdateBackend(backend); err != nil {
+		backend.Online = false
+		return nil, err
+	}
+	classes := make([]string, 0, len(o.storageClasses))
+	for _, sc := range o.storageClasses {
+		if added := sc.CheckAndAddBackend(backend); added > 0 {
+			classes = append(classes, sc.GetName())
+		}
+	}
+	sort.Strings(classes)
+	log.WithFields(log.Fields{
+		"backend":        backendName,
+		"storageClasses": classes,
+	}).Info("Brought backend back online.")
+	external := backend.ConstructExternal()
+	o.markDirty()
+	o.events.publish(EventUpdate, EventObjectBackend, backendName, external)
+	return &BackendResult{
+		StorageBackendExternal: external,
+		Warnings:               backendWarnings(backend, classes),
+	}, nil
+}
+
+// AddVolume runs any pre-provision hooks against volumeConfig, creates the
+// volume, and then notifies any post-provision hooks.  The volume is
+// returned with any warnings about it.  If ctx is done or the provision
+// timeout expires, creation stops, and any volume already created on a
+// backend is removed.  If volumeConfig has a RequestID and the volume was
+// already created by a request with that ID, that volume is returned.
+func (o *tridentOrchestrator) AddVolume(
+	ctx context.Context, volumeConfig *storage.VolumeConfig,
+) (result *VolumeResult, err error) {
+	ctx, span := tracing.StartSpan(ctx, tracing.Core, "AddVolume",
+		map[string]string{
+			"volume":       volumeConfig.Name,
+			"storageClass": volumeConfig.StorageClass,
+		})
+	defer func() {
+		if result != nil {
+			span.SetTag("backend", result.Backend)
+			span.SetTag("pool", result.Pool)
+		}
+		span.Finish(err)
+	}()
+	o.mutex.Lock()
+	timeout := o.timeouts.ProvisionTimeout()
+	existing := o.getVolumeForRequest(volumeConfig)
+	_, groupPending := o.pendingVolumeGroups[volumeConfig.Group]
+	o.mutex.Unlock()
+	if volumeConfig.Group != "" && !groupPending && existing == nil {
+		return nil, errors.Errorf(errors.InvalidInput, "Volume %s names "+
+			"volume group %s; volumes are only added to a group when it "+
+			"is created.", volumeConfig.Name, volumeConfig.Group)
+	}
+	if existing != nil {
+		log.WithFields(log.Fields{
+			"volume":    volumeConfig.Name,
+			"requestID": volumeConfig.RequestID,
+		}).Info("Volume already created for this request.")
+		return &VolumeResult{VolumeExternal: existing}, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	volumeConfig, err = o.runPreProvisionHooks(ctx, volumeConfig)
+	if err != nil {
+		return nil, err
+	}
+	if volumeConfig.IsClone() {
+		result, err = o.createVolumeFromSnapshot(ctx, volumeConfig)
+	} else {
+		result, err = o.addVolume(ctx, volumeConfig)
 	}
-	return true, o.storeClient.UpdateBackend(backend)
+	if err == nil && result != nil {
+		o.runPostProvisionHooks(result.VolumeExternal)
+	}
+	return result, err
+}
+
+// CreateVolumeFromSnapshot creates a volume from the snapshot named by
+// volumeConfig's SourceVolume and SourceSnapshot.  AddVolume does the same
+// for any volume config that names a snapshot.
+func (o *tridentOrchestrator) CreateVolumeFromSnapshot(
+	ctx context.Context, volumeConfig *storage.VolumeConfig,
+) (*VolumeResult, error) {
+	if volumeConfig.SourceVolume == "" || volumeConfig.SourceSnapshot == "" {
+		return nil, errors.Errorf(errors.InvalidInput, "Volume %s must "+
+			"specify a source volume and snapshot.", volumeConfig.Name)
+	}
+	return o.AddVolume(ctx, volumeConfig)
+}
+
+// getVolumeForRequest returns the volume named in volumeConfig if it was
+// created by a request with the same RequestID, or nil.  The caller must
+// hold the orchestrator lock.
+func (o *tridentOrchestrator) getVolumeForRequest(
+	volumeConfig *storage.VolumeConfig,
+) *storage.VolumeExternal {
+	if volumeConfig.RequestID == "" {
+		return nil
+	}
+	vol, ok := o.volumes[volumeConfig.Name]
+	if !ok || vol.Config.RequestID != volumeConfig.RequestID {
+		return nil
+	}
+	return vol.ConstructExternal()
 }
 
-func (o *tridentOrchestrator) AddVolume(volumeConfig *storage.VolumeConfig) (
-	externalVol *storage.VolumeExternal, err error) {
+func (o *tridentOrchestrator) addVolume(
+	ctx context.Context, volumeConfig *storage.VolumeConfig,
+) (result *VolumeResult, err error) {
 	var (
 		backend *storage.StorageBackend
 		vol     *storage.Volume
@@ -498,35 +1180,205 @@ func (o *tridentOrchestrator) AddVolume(volumeConfig *storage.VolumeConfig) (
 	o.mutex.Lock()
 	defer o.mutex.Unlock()
 
-	if _, ok := o.volumes[volumeConfig.Name]; ok {
-		return nil, fmt.Errorf("Volume %s already exists.", volumeConfig.Name)
+	// A retry of a request that is still running when this one starts
+	// finds the volume only once the lock is acquired.
+	if existing := o.getVolumeForRequest(volumeConfig); existing != nil {
+		return &VolumeResult{VolumeExternal: existing}, nil
+	}
+	if o.volumeExists(volumeConfig.Name) {
+		return nil, errors.Errorf(errors.AlreadyExists,
+			"Volume %s already exists.", volumeConfig.Name)
 	}
 	volumeConfig.Version = config.OrchestratorMajorVersion
+	volumeConfig.SetCreationTime(time.Now().UTC())
+
+	o.provisioning.notify(ProvisioningStarted, volumeConfig.Name,
+		volumeConfig.StorageClass, "", "", fmt.Sprintf("Provisioning "+
+			"volume %s with storage class %s.", volumeConfig.Name,
+			volumeConfig.StorageClass))
+	defer func() {
+		if err != nil {
+			o.provisioning.notify(ProvisioningFailed, volumeConfig.Name,
+				volumeConfig.StorageClass, "", "", err.Error())
+		} else if vol != nil {
+			o.provisioning.notify(ProvisioningSucceeded, volumeConfig.Name,
+				volumeConfig.StorageClass, vol.Backend.Name, vol.Pool.Name,
+				fmt.Sprintf("Created volume %s in storage pool %s of "+
+					"backend %s.", volumeConfig.Name, vol.Pool.Name,
+					vol.Backend.Name))
+		}
+	}()
 
+	if err = o.applyNamespacePolicy(volumeConfig); err != nil {
+		return nil, err
+	}
 	storageClass, ok := o.storageClasses[volumeConfig.StorageClass]
 	if !ok {
-		return nil, fmt.Errorf("Unknown storage class:  %s",
+		return nil, errors.Errorf(errors.InvalidInput,
+			"Unknown storage class:  %s", volumeConfig.StorageClass)
+	}
+	if storageClass.IsPendingDelete() {
+		return nil, errors.Errorf(errors.Conflict, "Storage class %s is "+
+			"pending deletion; no volumes can be created in it.",
 			volumeConfig.StorageClass)
 	}
+	if volumeConfig.SnapshotSchedule != "" {
+		if _, ok = o.snapshotPolicies[volumeConfig.SnapshotSchedule]; !ok {
+			return nil, errors.Errorf(errors.InvalidInput,
+				"Unknown snapshot policy:  %s", volumeConfig.SnapshotSchedule)
+		}
+	}
+	// Only block volumes have a filesystem to choose.
+	if volumeConfig.Protocol == config.ProtocolAny &&
+		(volumeConfig.FSType != "" || volumeConfig.MkfsOptions != "") {
+		volumeConfig.Protocol = config.Block
+	}
 	protocol := volumeConfig.Protocol
 	if protocol == config.ProtocolAny {
 		protocol = o.getProtocol(volumeConfig.AccessMode)
 	}
 	pools := storageClass.GetStoragePoolsForProtocol(volumeConfig.Protocol)
 	if len(pools) == 0 {
-		return nil, fmt.Errorf("No available backends for storage class %s!",
-			volumeConfig.StorageClass)
+		return nil, o.placementError(volumeConfig, storageClass,
+			fmt.Sprintf("No available backends for storage class %s!",
+				volumeConfig.StorageClass))
+	}
+	// The volumes of a group are all created on the backend of the first.
+	groupBackend := o.pendingVolumeGroups[volumeConfig.Group]
+	if groupBackend != "" {
+		groupPools := make([]*storage.StoragePool, 0, len(pools))
+		for _, pool := range pools {
+			if pool.Backend.Name == groupBackend {
+				groupPools = append(groupPools, pool)
+			}
+		}
+		if len(groupPools) == 0 {
+			return nil, o.placementError(volumeConfig, storageClass,
+				fmt.Sprintf("No storage pools of backend %s, which holds "+
+					"volume group %s, satisfy storage class %s!",
+					groupBackend, volumeConfig.Group,
+					volumeConfig.StorageClass))
+		}
+		pools = groupPools
+	}
+	storageClass.ApplyQoSDefaults(volumeConfig)
+	storageClass.ApplyReclaimPolicy(volumeConfig)
+	storageClass.ApplyMountOptions(volumeConfig)
+	storageClass.ApplySnapshotDefaults(volumeConfig)
+	if volumeConfig.HasQoS() {
+		if err = storage.ValidateQoS(volumeConfig.MinIOPS,
+			volumeConfig.MaxIOPS, volumeConfig.BurstIOPS); err != nil {
+			return nil, err
+		}
+		qosPools := make([]*storage.StoragePool, 0, len(pools))
+		for _, pool := range pools {
+			if pool.CanHonorQoS(volumeConfig.MinIOPS, volumeConfig.MaxIOPS) {
+				qosPools = append(qosPools, pool)
+			}
+		}
+		if len(qosPools) == 0 {
+			return nil, o.placementError(volumeConfig, storageClass,
+				fmt.Sprintf("No backends for storage class %s can provide "+
+					"between %d and %d IOPS!", volumeConfig.StorageClass,
+					volumeConfig.MinIOPS, volumeConfig.MaxIOPS))
+		}
+		pools = qosPools
+	}
+	if storageClass.RequiresEncryption() {
+		volumeConfig.Encryption = true
+	}
+	if volumeConfig.Encryption {
+		encryptedPools := make([]*storage.StoragePool, 0, len(pools))
+		for _, pool := range pools {
+			if pool.SupportsEncryption() {
+				encryptedPools = append(encryptedPools, pool)
+			}
+		}
+		if len(encryptedPools) == 0 {
+			return nil, o.placementError(volumeConfig, storageClass,
+				fmt.Sprintf("No backends for storage class %s support "+
+					"encryption!", volumeConfig.StorageClass))
+		}
+		pools = encryptedPools
+	}
+	if err = volumeConfig.ValidateReadOnly(); err != nil {
+		return nil, errors.Errorf(errors.InvalidInput, "%v", err)
+	}
+	// Volumes that hosts may only read are read-only on their backends too.
+	volumeConfig.ReadOnly = volumeConfig.IsReadOnly()
+	if volumeConfig.ReadOnly {
+		readOnlyPools := make([]*storage.StoragePool, 0, len(pools))
+		for _, pool := range pools {
+			if pool.Backend.SupportsReadOnly() {
+				readOnlyPools = append(readOnlyPools, pool)
+			}
+		}
+		if len(readOnlyPools) == 0 {
+			return nil, o.placementError(volumeConfig, storageClass,
+				fmt.Sprintf("No backends for storage class %s support "+
+					"read-only volumes!", volumeConfig.StorageClass))
+		}
+		pools = readOnlyPools
+	}
+	storageClass.ApplySpaceEfficiency(volumeConfig)
+	efficientPools := make([]*storage.StoragePool, 0, len(pools))
+	for _, pool := range pools {
+		if pool.SupportsSpaceEfficiency(volumeConfig) {
+			efficientPools = append(efficientPools, pool)
+		}
+	}
+	if len(efficientPools) == 0 {
+		return nil, o.placementError(volumeConfig, storageClass,
+			fmt.Sprintf("No backends for storage class %s support the "+
+				"requested provisioning type, deduplication, and "+
+				"compression!", volumeConfig.StorageClass))
+	}
+	pools = efficientPools
+	if volumeConfig.Access != nil && !volumeConfig.Access.IsEmpty() {
+		accessPools := make([]*storage.StoragePool, 0, len(pools))
+		for _, pool := range pools {
+			if pool.Backend.SupportsVolumeAccess() {
+				accessPools = append(accessPools, pool)
+			}
+		}
+		if len(accessPools) == 0 {
+			return nil, o.placementError(volumeConfig, storageClass,
+				fmt.Sprintf("No backends for storage class %s support "+
+					"volume access control!", volumeConfig.StorageClass))
+		}
+		pools = accessPools
+	}
+	avoid, err := o.antiAffinityBackends(volumeConfig)
+	if err != nil {
+		return nil, err
+	}
+	if len(avoid) > 0 {
+		spreadPools := make([]*storage.StoragePool, 0, len(pools))
+		for _, pool := range pools {
+			if _, ok := avoid[pool.Backend.Name]; !ok {
+				spreadPools = append(spreadPools, pool)
+			}
+		}
+		if len(spreadPools) == 0 {
+			return nil, o.placementError(volumeConfig, storageClass,
+				fmt.Sprintf("No backends for storage class %s satisfy the "+
+					"volume's anti-affinity!", volumeConfig.StorageClass))
+		}
+		pools = spreadPools
 	}
 
 	// Check if an addVolume transaction already exists for this name.
 	// If so, we failed earlier and we need to call the bootstrap cleanup code.
 	// If this fails, return an error.  If it succeeds or no transaction
 	// existed, log a new transaction in the persistent store and proceed.
+	// Requests made on behalf of the caller are abandoned if ctx is done;
+	// cleanup is not, so that the transaction is resolved.
+	store := o.storeClient.WithContext(ctx)
 	volTxn := &persistent_store.VolumeTransaction{
 		Config: volumeConfig,
 		Op:     persistent_store.AddVolume,
 	}
-	oldTxn, err := o.storeClient.GetExistingVolumeTransaction(volTxn)
+	oldTxn, err := store.GetExistingVolumeTransaction(volTxn)
 	if err != nil {
 		log.Warning("Unable to check for existing volume transactions:  %v",
 			err)
@@ -540,7 +1392,7 @@ func (o *tridentOrchestrator) AddVolume(volumeConfig *storage.VolumeConfig) (
 		}
 	}
 
-	err = o.storeClient.AddVolumeTransaction(volTxn)
+	err = store.AddVolumeTransaction(volTxn)
 	if err != nil {
 		return nil, err
 	}
@@ -550,6 +1402,10 @@ func (o *tridentOrchestrator) AddVolume(volumeConfig *storage.VolumeConfig) (
 		var (
 			cleanupErr, txErr error
 		)
+		if faults.IsCrash(err) {
+			// Leave everything for the next bootstrap to roll back.
+			return
+		}
 		if err != nil {
 			// We failed somewhere.  There are two possible cases:
 			// 1.  We failed to allocate on a backend and fell through to the
@@ -563,11 +1419,7 @@ func (o *tridentOrchestrator) AddVolume(volumeConfig *storage.VolumeConfig) (
 			if backend != nil && vol != nil {
 				// We succeeded in adding the volume to the backend; now
 				// delete it
-				cleanupErr = backend.RemoveVolume(vol)
-				if cleanupErr != nil {
-					cleanupErr = fmt.Errorf("Unable to delete volume "+
-						"from backend during cleanup:  %v", cleanupErr)
-				}
+				cleanupErr = o.removeFailedVolume(backend, vol)
 			}
 		}
 		if cleanupErr == nil {
@@ -583,7 +1435,7 @@ func (o *tridentOrchestrator) AddVolume(volumeConfig *storage.VolumeConfig) (
 			// Remove the volume from memory, if it's there, so that the user
 			// can try to re-add.  This will trigger recovery code.
 			delete(o.volumes, volumeConfig.Name)
-			externalVol = nil
+			result = nil
 			// Report on all errors we encountered.
 			errList := make([]string, 0, 3)
 			for _, e := range []error{err, cleanupErr, txErr} {
@@ -596,27 +1448,89 @@ func (o *tridentOrchestrator) AddVolume(volumeConfig *storage.VolumeConfig) (
 		return
 	}()
 
-	// randomize the backend list for better distribution of load across all backends
-	rand.Seed(time.Now().UnixNano())
+	if err = faults.Check(faults.VolumeTxnLogged); err != nil {
+		return nil, err
+	}
+
 	log.WithFields(log.Fields{
 		"volume": volumeConfig.Name,
 	}).Debugf("Looking through %d backends", len(pools))
 	errorMessages := make([]string, 0)
-	for _, num := range rand.Perm(len(pools)) {
+	failures := make(map[*storage.StoragePool]error)
+	order := o.placementOrder(pools)
+	sortByBackendTier(pools, order, storageClass)
+	for _, num := range order {
+		if err = checkContext(ctx, "Volume creation"); err != nil {
+			return nil, err
+		}
 		backend = pools[num].Backend
-		if vol, err = backend.AddVolume(
-			volumeConfig, pools[num], storageClass.GetAttributes(),
-		); vol != nil && err == nil {
-			if vol.Config.Protocol == config.ProtocolAny {
-				vol.Config.Protocol = backend.GetProtocol()
-			}
-			err = o.storeClient.AddVolume(vol)
-			if err != nil {
-				return nil, err
-			}
+		if limitErr := backend.CheckLimits(volumeConfig); limitErr != nil {
+			log.WithFields(log.Fields{
+				"backend": backend.Name,
+				"volume":  volumeConfig.Name,
+			}).Debugf("Skipping backend:  %v", limitErr)
+			errorMessages = append(errorMessages,
+				fmt.Sprintf("[%s]", limitErr.Error()))
+			continue
+		}
+		var (
+			poolBackend = backend
+			pool        = pools[num]
+			// Each attempt fills in its own copy of the config, e.g., with
+			// the internal name, since an abandoned attempt may go on
+			// writing it after the attempt returns.
+			poolConfig = *volumeConfig
+			newVol     *storage.Volume
+		)
+		o.provisioning.notify(ProvisioningPoolSelected, volumeConfig.Name,
+			volumeConfig.StorageClass, backend.Name, pool.Name,
+			fmt.Sprintf("Creating volume %s in storage pool %s of backend "+
+				"%s.", volumeConfig.Name, pool.Name, backend.Name))
+		err = callWithContext(ctx, "Volume creation", map[string]string{
+			"volume":  volumeConfig.Name,
+			"backend": poolBackend.Name,
+			"pool":    pool.Name,
+		}, func() error {
+			var addErr error
+			newVol, addErr = poolBackend.CreateVolume(ctx, &poolConfig,
+				pool, storageClass.GetAttributes())
+			return addErr
+		}, func() {
+			o.removeAbandonedVolume(poolBackend, newVol)
+		})
+		if err == nil && newVol != nil {
+			// newVol may only be read if the call wasn't abandoned, and is
+			// only added to its pool here, under the lock.
+			vol = newVol
+			pool.AddVolume(vol, false)
+		}
+		if vol != nil && err == nil {
+			if vol.Config.Protocol == config.ProtocolAny {
+				vol.Config.Protocol = pool.GetProtocol()
+			}
+			if vol.Config.Protocol == config.Block && vol.Config.FSType == "" {
+				vol.Config.FSType = config.DefaultFSType
+			}
+			vol.Config.BackendTier = storageClass.BackendTier(backend.Name)
+			if err = faults.Check(faults.VolumeCreatedOnBackend); err != nil {
+				return nil, err
+			}
+			err = store.AddVolume(vol)
+			if err != nil {
+				return nil, err
+			}
+			if err = faults.Check(faults.VolumeStored); err != nil {
+				return nil, err
+			}
 			o.volumes[volumeConfig.Name] = vol
-			externalVol = vol.ConstructExternal()
-			return externalVol, nil
+			externalVol := vol.ConstructExternal()
+			o.markDirty()
+			o.events.publish(EventCreate, EventObjectVolume,
+				volumeConfig.Name, externalVol)
+			return &VolumeResult{
+				VolumeExternal: externalVol,
+				Warnings:       volumeWarnings(vol, failures),
+			}, nil
 		} else if err != nil {
 			log.WithFields(log.Fields{
 				"backend": backend.Name,
@@ -624,6 +1538,12 @@ func (o *tridentOrchestrator) AddVolume(volumeConfig *storage.VolumeConfig) (
 				"volume":  volumeConfig.Name,
 				"error":   err,
 			}).Warn("Failed to create the volume on this backend!")
+			failures[pools[num]] = err
+			o.provisioning.notify(ProvisioningPoolFailed, volumeConfig.Name,
+				volumeConfig.StorageClass, backend.Name, pools[num].Name,
+				fmt.Sprintf("Failed to create volume %s in storage pool %s "+
+					"of backend %s:  %v", volumeConfig.Name, pools[num].Name,
+					backend.Name, err))
 			errorMessages = append(errorMessages,
 				fmt.Sprintf("[Failed to create volume %s "+
 					"on storage pool %s from backend %s: %s]",
@@ -632,28 +1552,295 @@ func (o *tridentOrchestrator) AddVolume(volumeConfig *storage.VolumeConfig) (
 		}
 	}
 
-	externalVol = nil
 	if len(errorMessages) == 0 {
-		err = fmt.Errorf("No suitable %s backend with \"%s\" "+
-			"storage class and %s of free space was found! Find available backends"+
-			" under %s.", volumeConfig.Protocol,
-			volumeConfig.StorageClass, volumeConfig.Size, config.BackendURL)
+		err = o.placementError(volumeConfig, storageClass, fmt.Sprintf(
+			"No suitable %s backend with \"%s\" storage class and %s of "+
+				"free space was found! Find available backends under %s.",
+			volumeConfig.Protocol, volumeConfig.StorageClass,
+			volumeConfig.Size, config.BackendURL))
 	} else {
-		err = fmt.Errorf("Encountered error(s) in creating the volume: %s",
-			strings.Join(errorMessages, ", "))
+		// Creation failures are unexpected, so the error has no type.
+		err = errors.ErrorfWithDetails("", o.explainPlacement(volumeConfig,
+			storageClass, failures), "Encountered error(s) in creating the "+
+			"volume: %s", strings.Join(errorMessages, ", "))
 	}
 	return nil, err
 }
 
+func (o *tridentOrchestrator) createVolumeFromSnapshot(
+	ctx context.Context, volumeConfig *storage.VolumeConfig,
+) (result *VolumeResult, err error) {
+	var vol *storage.Volume
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if existing := o.getVolumeForRequest(volumeConfig); existing != nil {
+		return &VolumeResult{VolumeExternal: existing}, nil
+	}
+	if o.volumeExists(volumeConfig.Name) {
+		return nil, errors.Errorf(errors.AlreadyExists,
+			"Volume %s already exists.", volumeConfig.Name)
+	}
+	sourceVol, ok := o.volumes[volumeConfig.SourceVolume]
+	if !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Source volume %s not found.", volumeConfig.SourceVolume)
+	}
+	if err := o.checkNotDeleting(sourceVol); err != nil {
+		return nil, err
+	}
+	backend := sourceVol.Backend
+	if !backend.Online {
+		return nil, errors.Errorf(errors.BackendUnavailable, "Backend %s for "+
+			"source volume %s is offline.", backend.Name, sourceVol.Config.Name)
+	}
+	if !backend.Capabilities().SupportsClones {
+		return nil, errors.Errorf(errors.InvalidInput, "Backend %s of "+
+			"source volume %s can't clone volumes.", backend.Name,
+			sourceVol.Config.Name)
+	}
+	// Clones are created on their source's backend, so they can't be
+	// placed elsewhere to satisfy their anti-affinity.
+	avoid, err := o.antiAffinityBackends(volumeConfig)
+	if err != nil {
+		return nil, err
+	}
+	if reason, ok := avoid[backend.Name]; ok {
+		return nil, errors.Errorf(errors.InvalidInput, "Clone %s must be "+
+			"created on backend %s of its source volume, but violates its "+
+			"anti-affinity:  %s", volumeConfig.Name, backend.Name, reason)
+	}
+	if err = volumeConfig.ValidateReadOnly(); err != nil {
+		return nil, errors.Errorf(errors.InvalidInput, "%v", err)
+	}
+	volumeConfig.ReadOnly = volumeConfig.IsReadOnly()
+	if volumeConfig.ReadOnly && !backend.SupportsReadOnly() {
+		return nil, errors.Errorf(errors.InvalidInput, "Clone %s must be "+
+			"created on backend %s of its source volume, which doesn't "+
+			"support read-only volumes.", volumeConfig.Name, backend.Name)
+	}
+	snapshots, err := backend.ListSnapshots(ctx, sourceVol)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list snapshots of volume %s:  %v",
+			sourceVol.Config.Name, err)
+	}
+	found := false
+	for _, snapshot := range snapshots {
+		if snapshot == volumeConfig.SourceSnapshot {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, errors.Errorf(errors.NotFound, "Snapshot %s of volume %s "+
+			"not found.", volumeConfig.SourceSnapshot, sourceVol.Config.Name)
+	}
+
+	// Clones live in the source volume's pool, so they inherit its size
+	// and protocol, and must use a storage class that the pool satisfies.
+	volumeConfig.Version = config.OrchestratorMajorVersion
+	volumeConfig.SetCreationTime(time.Now().UTC())
+	volumeConfig.Size = sourceVol.Config.Size
+	volumeConfig.Protocol = sourceVol.Config.Protocol
+	// Clones contain the source's filesystem.
+	if sourceVol.Config.Formatted && volumeConfig.FSType != "" &&
+		volumeConfig.FSType != sourceVol.Config.FSType {
+		return nil, errors.Errorf(errors.InvalidInput, "Source volume %s is "+
+			"formatted as %s; its clones can't use fsType %s.",
+			sourceVol.Config.Name, sourceVol.Config.FSType, volumeConfig.FSType)
+	}
+	if volumeConfig.FSType == "" {
+		volumeConfig.FSType = sourceVol.Config.FSType
+	}
+	volumeConfig.Formatted = sourceVol.Config.Formatted
+	if volumeConfig.StorageClass == "" {
+		volumeConfig.StorageClass = sourceVol.Config.StorageClass
+	}
+	if err = o.applyNamespacePolicy(volumeConfig); err != nil {
+		return nil, err
+	}
+	storageClass, ok := o.storageClasses[volumeConfig.StorageClass]
+	if !ok {
+		return nil, errors.Errorf(errors.InvalidInput,
+			"Unknown storage class:  %s", volumeConfig.StorageClass)
+	}
+	if storageClass.IsPendingDelete() {
+		return nil, errors.Errorf(errors.Conflict, "Storage class %s is "+
+			"pending deletion; no volumes can be created in it.",
+			volumeConfig.StorageClass)
+	}
+	storageClass.ApplyReclaimPolicy(volumeConfig)
+	storageClass.ApplyMountOptions(volumeConfig)
+	poolMatches := false
+	for _, scName := range sourceVol.Pool.StorageClasses {
+		if scName == volumeConfig.StorageClass {
+			poolMatches = true
+			break
+		}
+	}
+	if !poolMatches {
+		return nil, errors.Errorf(errors.InvalidInput, "Storage pool %s of "+
+			"source volume %s does not satisfy storage class %s.",
+			sourceVol.Pool.Name, sourceVol.Config.Name,
+			volumeConfig.StorageClass)
+	}
+	if volumeConfig.SnapshotSchedule != "" {
+		if _, ok = o.snapshotPolicies[volumeConfig.SnapshotSchedule]; !ok {
+			return nil, errors.Errorf(errors.InvalidInput,
+				"Unknown snapshot policy:  %s", volumeConfig.SnapshotSchedule)
+		}
+	}
+	if volumeConfig.Access != nil && !volumeConfig.Access.IsEmpty() &&
+		!backend.SupportsVolumeAccess() {
+		return nil, errors.Errorf(errors.InvalidInput,
+			"Backend %s does not support volume access control.", backend.Name)
+	}
+
+	store := o.storeClient.WithContext(ctx)
+	volTxn := &persistent_store.VolumeTransaction{
+		Config: volumeConfig,
+		Op:     persistent_store.AddVolume,
+	}
+	oldTxn, err := store.GetExistingVolumeTransaction(volTxn)
+	if err != nil {
+		return nil, err
+	}
+	if oldTxn != nil {
+		if err = o.rollBackTransaction(oldTxn); err != nil {
+			return nil, fmt.Errorf("Unable to roll back existing transaction "+
+				"for volume %s:  %v", volumeConfig.Name, err)
+		}
+	}
+	if err = store.AddVolumeTransaction(volTxn); err != nil {
+		return nil, err
+	}
+
+	// Recovery function in case of error; see addVolume.
+	defer func() {
+		var cleanupErr, txErr error
+		if faults.IsCrash(err) {
+			return
+		}
+		if err != nil && vol != nil {
+			cleanupErr = o.removeFailedVolume(backend, vol)
+		}
+		if cleanupErr == nil {
+			txErr = o.storeClient.DeleteVolumeTransaction(volTxn)
+		}
+		if cleanupErr != nil || txErr != nil {
+			delete(o.volumes, volumeConfig.Name)
+			result = nil
+			errList := make([]string, 0, 3)
+			for _, e := range []error{err, cleanupErr, txErr} {
+				if e != nil {
+					errList = append(errList, e.Error())
+				}
+			}
+			err = fmt.Errorf("%s", strings.Join(errList, "\n\t"))
+		}
+	}()
+
+	if err = backend.CheckLimits(volumeConfig); err != nil {
+		return nil, err
+	}
+	if err = faults.Check(faults.VolumeTxnLogged); err != nil {
+		return nil, err
+	}
+	// As in addVolume, an abandoned clone may go on writing its config.
+	var (
+		cloneConfig = *volumeConfig
+		clone       *storage.Volume
+	)
+	err = callWithContext(ctx, "Volume creation", map[string]string{
+		"volume":         volumeConfig.Name,
+		"backend":        backend.Name,
+		"sourceVolume":   volumeConfig.SourceVolume,
+		"sourceSnapshot": volumeConfig.SourceSnapshot,
+	}, func() error {
+		var cloneErr error
+		clone, cloneErr = backend.CreateClone(ctx, &cloneConfig,
+			sourceVol, volumeConfig.SourceSnapshot)
+		return cloneErr
+	}, func() {
+		o.removeAbandonedVolume(backend, clone)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create volume %s from snapshot %s "+
+			"of volume %s:  %v", volumeConfig.Name,
+			volumeConfig.SourceSnapshot, sourceVol.Config.Name, err)
+	}
+	vol = clone
+	vol.Pool.AddVolume(vol, false)
+	if err = faults.Check(faults.VolumeCreatedOnBackend); err != nil {
+		return nil, err
+	}
+	if err = store.AddVolume(vol); err != nil {
+		return nil, err
+	}
+	if err = faults.Check(faults.VolumeStored); err != nil {
+		return nil, err
+	}
+	o.volumes[volumeConfig.Name] = vol
+	externalVol := vol.ConstructExternal()
+	o.markDirty()
+	o.events.publish(EventCreate, EventObjectVolume, volumeConfig.Name,
+		externalVol)
+	return &VolumeResult{
+		VolumeExternal: externalVol,
+		Warnings:       volumeWarnings(vol, nil),
+	}, nil
+}
+
+// GetVolume returns the named volume, or nil if there is none.  The volume
+// is shared with other callers and must not be modified.
 func (o *tridentOrchestrator) GetVolume(volume string) *storage.VolumeExternal {
 	o.mutex.Lock()
 	defer o.mutex.Unlock()
 
+	return o.externalVolumes().volumes[volume]
+}
+
+// GetVolumeChap returns the CHAP credentials, including the secrets, that
+// hosts need to attach a volume.  It fails with a NotFound error if the
+// volume doesn't exist or its backend doesn't use CHAP.
+func (o *tridentOrchestrator) GetVolumeChap(
+	volume string,
+) (*storage.ChapConfig, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
 	vol, found := o.volumes[volume]
 	if !found {
-		return nil
+		return nil, errors.Errorf(errors.NotFound,
+			"Volume %s not found.", volume)
 	}
-	return vol.ConstructExternal()
+	if !vol.Backend.Chap.IsSet() {
+		return nil, errors.Errorf(errors.NotFound,
+			"Volume %s's backend doesn't use CHAP.", volume)
+	}
+	chap := vol.Backend.Chap
+	return &chap, nil
+}
+
+// volumeExists returns true if a volume, including an orphaned one, has the
+// given name.
+func (o *tridentOrchestrator) volumeExists(volumeName string) bool {
+	if _, ok := o.volumes[volumeName]; ok {
+		return true
+	}
+	_, ok := o.orphanedVolumes[volumeName]
+	return ok
+}
+
+// volumeNotFoundError explains why a volume can't be changed:  either it
+// doesn't exist or it is orphaned.
+func (o *tridentOrchestrator) volumeNotFoundError(volumeName string) error {
+	if _, ok := o.orphanedVolumes[volumeName]; ok {
+		return errors.Errorf(errors.Conflict, "Volume %s is orphaned; "+
+			"reattach it to a backend first.", volumeName)
+	}
+	return errors.Errorf(errors.NotFound, "Volume %s not found.", volumeName)
 }
 
 func (o *tridentOrchestrator) GetDriverTypeForVolume(
@@ -672,9 +1859,12 @@ func (o *tridentOrchestrator) GetVolumeType(vol *storage.VolumeExternal) config.
 	o.mutex.Lock()
 	defer o.mutex.Unlock()
 
-	// Since the caller has a valid VolumeExternal and we're disallowing
-	// backend deletion, we can assume that this will not hit a nil pointer.
-	driver := o.backends[vol.Backend].GetDriverName()
+	// Orphaned volumes have no backend.
+	backend, ok := o.backends[vol.Backend]
+	if !ok {
+		return config.UnknownVolumeType
+	}
+	driver := backend.GetDriverName()
 	switch {
 	case driver == dvp.OntapNASStorageDriverName:
 		return config.ONTAP_NFS
@@ -689,13 +1879,98 @@ func (o *tridentOrchestrator) GetVolumeType(vol *storage.VolumeExternal) config.
 	}
 }
 
+// ListVolumes returns every volume, including orphaned ones.  The volumes
+// are shared with other callers and must not be modified, though the slice
+// may be.
 func (o *tridentOrchestrator) ListVolumes() []*storage.VolumeExternal {
 	o.mutex.Lock()
 	defer o.mutex.Unlock()
 
-	volumes := make([]*storage.VolumeExternal, 0, len(o.volumes))
-	for _, v := range o.volumes {
-		volumes = append(volumes, v.ConstructExternal())
+	cached := o.externalVolumes().volumeList
+	volumes := make([]*storage.VolumeExternal, len(cached))
+	copy(volumes, cached)
+	return volumes
+}
+
+// ListVolumesByMetadata returns the volumes that have every key and value
+// in selector.
+func (o *tridentOrchestrator) ListVolumesByMetadata(
+	selector map[string]string,
+) []*storage.VolumeExternal {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	volumes := make([]*storage.VolumeExternal, 0)
+	for _, v := range o.externalVolumes().volumeList {
+		if v.Config.MatchesMetadata(selector) {
+			volumes = append(volumes, v)
+		}
+	}
+	return volumes
+}
+
+// walkVolumesChunk is the number of volumes WalkVolumes reads each time it
+// takes the lock.  It's a variable so that tests can shrink it.
+var walkVolumesChunk = 100
+
+// WalkVolumes calls walk for every volume, including orphaned ones, until
+// walk returns false.  walk runs without the orchestrator's lock, so it may
+// be slow, e.g., write each volume to a client, without blocking other
+// operations.  Rather than building the whole list under the lock,
+// WalkVolumes lists the volumes' names and reads them walkVolumesChunk at a
+// time, so volumes deleted before they're reached are skipped, and volumes
+// added during the walk aren't walked.  As with ListVolumes, the volumes
+// must not be modified.
+func (o *tridentOrchestrator) WalkVolumes(
+	walk func(*storage.VolumeExternal) bool,
+) {
+	o.mutex.Lock()
+	names := make([]string, 0, len(o.volumes)+len(o.orphanedVolumes))
+	for name := range o.volumes {
+		names = append(names, name)
+	}
+	for name := range o.orphanedVolumes {
+		if _, ok := o.volumes[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	o.mutex.Unlock()
+
+	chunk := make([]*storage.VolumeExternal, 0, walkVolumesChunk)
+	for start := 0; start < len(names); start += walkVolumesChunk {
+		end := start + walkVolumesChunk
+		if end > len(names) {
+			end = len(names)
+		}
+		chunk = o.readVolumes(names[start:end], chunk[:0])
+		for _, v := range chunk {
+			if !walk(v) {
+				return
+			}
+		}
+	}
+}
+
+// readVolumes appends the named volumes that still exist to volumes, using
+// the cached volumes if they're current.
+func (o *tridentOrchestrator) readVolumes(
+	names []string, volumes []*storage.VolumeExternal,
+) []*storage.VolumeExternal {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	cached := o.mutex.externals.volumes
+	for _, name := range names {
+		if cached != nil {
+			if v, ok := cached[name]; ok {
+				volumes = append(volumes, v)
+			}
+		} else if v, ok := o.volumes[name]; ok {
+			volumes = append(volumes, v.ConstructExternal())
+		} else if orphan, ok := o.orphanedVolumes[name]; ok {
+			orphanCopy := *orphan
+			volumes = append(volumes, &orphanCopy)
+		}
 	}
 	return volumes
 }
@@ -704,20 +1979,70 @@ func (o *tridentOrchestrator) ListVolumes() []*storage.VolumeExternal {
 // not construct a transaction, nor does it take locks; it assumes that the
 // caller will take care of both of these.  It also assumes that the volume
 // exists in memory.
-func (o *tridentOrchestrator) deleteVolume(volumeName string) error {
+func (o *tridentOrchestrator) deleteVolume(
+	ctx context.Context, volumeName string,
+) error {
 
 	volume := o.volumes[volumeName]
 
-	// Note that this call will only return an error if the backend actually
-	// fails to delete the volume.  If the volume does not exist on the backend,
-	// the nDVP will not return an error.  Thus, we're fine.
-	if err := volume.Backend.RemoveVolume(volume); err != nil {
+	if volume.Config.ReclaimPolicy == config.ReclaimRetain {
+		if err := o.retainVolume(volume); err != nil {
+			return err
+		}
+		return o.removeDeletedVolume(volume)
+	}
+	if err := destroyVolume(ctx, volume); err != nil {
+		return err
+	}
+	return o.removeDestroyedVolume(volume)
+}
+
+// destroyVolume deletes a volume from its backend.  It doesn't change the
+// orchestrator's state, so it may be called without the orchestrator lock.
+func destroyVolume(ctx context.Context, volume *storage.Volume) error {
+	// Note that this call will only return an error if the backend
+	// actually fails to delete the volume.  If the volume does not exist
+	// on the backend, the nDVP will not return an error.  Thus, we're
+	// fine.
+	if err := callWithContext(ctx, "Volume deletion", map[string]string{
+		"volume":  volume.Config.Name,
+		"backend": volume.Backend.Name,
+	}, func() error {
+		return volume.Backend.DestroyVolume(ctx, volume)
+	}, nil); err != nil {
 		log.WithFields(log.Fields{
-			"volume":  volumeName,
+			"volume":  volume.Config.Name,
 			"backend": volume.Backend.Name,
 		}).Error("Unable to delete volume from backend.")
 		return err
 	}
+	return nil
+}
+
+// removeDestroyedVolume removes a volume deleted from its backend from its
+// pool and from Trident.  The caller must hold the orchestrator lock.
+func (o *tridentOrchestrator) removeDestroyedVolume(
+	volume *storage.Volume,
+) error {
+	// The volume is only removed from its pool once the deletion is known
+	// to have finished, so an abandoned deletion leaves it in place, to be
+	// deleted again.
+	volume.Pool.DeleteVolume(volume)
+	o.markDirty()
+	if err := faults.Check(faults.VolumeDeletedFromBackend); err != nil {
+		return err
+	}
+	return o.removeDeletedVolume(volume)
+}
+
+// removeDeletedVolume removes a volume that was deleted or retained on its
+// backend from the store and from Trident, along with its backend if the
+// backend is offline and now empty.  The caller must hold the orchestrator
+// lock.
+func (o *tridentOrchestrator) removeDeletedVolume(
+	volume *storage.Volume,
+) error {
+	volumeName := volume.Config.Name
 	// Ignore failures to find the volume being deleted, as this may be called
 	// during recovery of a volume that has already been deleted from etcd.
 	// During normal operation, checks on whether the volume is present in the
@@ -739,8 +2064,14 @@ func (o *tridentOrchestrator) deleteVolume(volumeName string) error {
 			return err
 		}
 		delete(o.backends, volume.Backend.Name)
+		o.events.publish(EventDelete, EventObjectBackend, volume.Backend.Name,
+			volume.Backend.ConstructExternal())
 	}
 	delete(o.volumes, volumeName)
+	o.markDirty()
+	o.events.publish(EventDelete, EventObjectVolume, volumeName,
+		volume.ConstructExternal())
+	o.finishStorageClassDelete(volume.Config.StorageClass)
 	return nil
 }
 
@@ -751,38 +2082,591 @@ func (o *tridentOrchestrator) deleteVolume(volumeName string) error {
 // successfully, ensuring that the deletion will complete either upon retrying
 // the delete or upon reboot of Trident.
 // Returns true if the volume is found and false otherwise.
-func (o *tridentOrchestrator) DeleteVolume(volumeName string) (found bool, err error) {
+// Attached volumes are not deleted; see ForceDeleteVolume.  If ctx is done
+// before deletion from the backend begins, the volume is left in place.
+func (o *tridentOrchestrator) DeleteVolume(
+	ctx context.Context, volumeName string,
+) (found bool, err error) {
+	return o.deleteVolumeWithTxn(ctx, volumeName, false)
+}
+
+// ForceDeleteVolume deletes a volume even if it is attached to nodes.
+func (o *tridentOrchestrator) ForceDeleteVolume(
+	ctx context.Context, volumeName string,
+) (found bool, err error) {
+	return o.deleteVolumeWithTxn(ctx, volumeName, true)
+}
+
+func (o *tridentOrchestrator) deleteVolumeWithTxn(
+	ctx context.Context, volumeName string, force bool,
+) (found bool, err error) {
+	ctx, span := tracing.StartSpan(ctx, tracing.Core, "DeleteVolume",
+		map[string]string{"volume": volumeName})
+	defer func() {
+		span.Finish(err)
+	}()
 	o.mutex.Lock()
 	defer o.mutex.Unlock()
+	return o.deleteVolumeLocked(ctx, volumeName, force)
+}
 
+// deleteVolumeLocked deletes a volume as DeleteVolume does.  The caller
+// must hold the orchestrator lock.
+func (o *tridentOrchestrator) deleteVolumeLocked(
+	ctx context.Context, volumeName string, force bool,
+) (found bool, err error) {
+	// Once the transaction is logged, the deletion is no longer canceled with
+	// ctx, since it would be completed at the next bootstrap anyway, but it
+	// is still bounded by the delete timeout.
+	deadline := time.Now().Add(o.timeouts.DeleteTimeout())
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+	deleteCtx, deleteCancel := context.WithDeadline(tracing.Detach(ctx),
+		deadline)
+	defer deleteCancel()
+
+	volume, found, err := o.volumeToDelete(volumeName, force)
+	if volume == nil {
+		return found, err
+	}
+	if o.terminatesVolume(volume) {
+		return true, o.terminateVolume(ctx, deleteCtx, volume)
+	}
+	return true, o.deleteVolumeInTxn(ctx, deleteCtx, volume)
+}
+
+// volumeToDelete returns the volume named, if it may be deleted.
+// Otherwise it returns nil, whether the volume was found, and why it can't
+// be deleted; orphaned volumes are removed from Trident here, if forced.
+// The caller must hold the orchestrator lock.
+func (o *tridentOrchestrator) volumeToDelete(
+	volumeName string, force bool,
+) (*storage.Volume, bool, error) {
 	volume, ok := o.volumes[volumeName]
 	if !ok {
-		return false, fmt.Errorf("Volume %s not found.", volumeName)
+		if orphan, isOrphan := o.orphanedVolumes[volumeName]; isOrphan && force {
+			return nil, true, o.deleteOrphanedVolume(orphan)
+		}
+		return nil, false, o.volumeNotFoundError(volumeName)
+	}
+	if o.deletingVolumes[volumeName] {
+		return nil, true, errors.Errorf(errors.Conflict,
+			"Volume %s is being deleted.", volumeName)
+	}
+	if _, ok = o.volumeGroups[volume.Config.Group]; ok {
+		return nil, true, errors.Errorf(errors.Conflict, "Volume %s "+
+			"belongs to volume group %s; delete the group instead.",
+			volumeName, volume.Config.Group)
+	}
+	if err := checkNotReplicated(volume); err != nil {
+		return nil, true, err
+	}
+	if len(volume.Attachments) > 0 {
+		if !force {
+			return nil, true, errors.Errorf(errors.Conflict, "Volume %s is "+
+				"attached to node(s) %s; detach it first or force the "+
+				"deletion.", volumeName,
+				strings.Join(volume.AttachedNodes(), ", "))
+		}
+		log.WithFields(log.Fields{
+			"volume": volumeName,
+			"nodes":  volume.AttachedNodes(),
+		}).Warn("Deleting an attached volume.")
+	}
+	if volume.Config.ReclaimPolicy == config.ReclaimRetain {
+		retained, isRetained := o.retainedVolumes[volumeName]
+		if isRetained && !retained.Matches(volume) {
+			return nil, true, errors.Errorf(errors.Conflict, "A retained "+
+				"volume named %s already exists; import or delete it "+
+				"before deleting this volume.", volumeName)
+		}
 	}
+	return volume, true, nil
+}
 
-	volTxn := &persistent_store.VolumeTransaction{
-		Config: volume.Config,
-		Op:     persistent_store.DeleteVolume,
-	}
-	if err = o.storeClient.AddVolumeTransaction(volTxn); err != nil {
-		return true, err
+// terminatesVolume returns true if deleting volume leaves it terminating
+// for the deletion grace period.  Deleting a terminating volume again
+// deletes it immediately.
+func (o *tridentOrchestrator) terminatesVolume(volume *storage.Volume) bool {
+	return volume.Config.ReclaimPolicy != config.ReclaimRetain &&
+		o.deletionGracePeriod > 0 && !volume.IsTerminating()
+}
+
+// deleteVolumeInTxn deletes a volume within a transaction, which is only
+// resolved if all stages of deletion complete.  The transaction is logged
+// with ctx; the deletion itself uses deleteCtx.  The caller must hold the
+// orchestrator lock.
+func (o *tridentOrchestrator) deleteVolumeInTxn(
+	ctx, deleteCtx context.Context, volume *storage.Volume,
+) error {
+	volTxn, err := o.addDeleteTransaction(ctx, volume)
+	if err != nil {
+		return err
 	}
-	if err = o.deleteVolume(volumeName); err != nil {
+	if err = o.deleteVolume(deleteCtx, volume.Config.Name); err != nil {
 		// Do not try to delete the volume transaction here; instead, if we
 		// fail, leave the transaction around and let the deletion be attempted
 		// again.
-		return true, err
+		return err
 	}
-	err = o.storeClient.DeleteVolumeTransaction(volTxn)
-	if err != nil {
+	o.resolveDeleteTransaction(volTxn, volume)
+	return nil
+}
+
+// addDeleteTransaction logs the transaction deleting a volume with ctx.
+func (o *tridentOrchestrator) addDeleteTransaction(
+	ctx context.Context, volume *storage.Volume,
+) (*persistent_store.VolumeTransaction, error) {
+	volTxn := &persistent_store.VolumeTransaction{
+		Config: volume.Config,
+		Op:     persistent_store.DeleteVolume,
+	}
+	if err := o.storeClient.WithContext(ctx).AddVolumeTransaction(
+		volTxn); err != nil {
+		return nil, err
+	}
+	return volTxn, nil
+}
+
+// resolveDeleteTransaction deletes the transaction of a volume that was
+// deleted.  The caller must hold the orchestrator lock.
+func (o *tridentOrchestrator) resolveDeleteTransaction(
+	volTxn *persistent_store.VolumeTransaction, volume *storage.Volume,
+) {
+	if err := o.storeClient.DeleteVolumeTransaction(volTxn); err != nil {
 		log.WithFields(log.Fields{
 			"volume": volume,
 		}).Warn("Unable to delete volume transaction.  Repeat deletion to " +
 			"finalize.")
 		// Reinsert the volume so that it can be deleted again
-		o.volumes[volumeName] = volume
+		o.volumes[volume.Config.Name] = volume
 	}
-	return true, nil
+}
+
+// UpdateVolumeMetadata replaces a volume's metadata.
+func (o *tridentOrchestrator) UpdateVolumeMetadata(
+	volumeName string, metadata map[string]string,
+) (*storage.VolumeExternal, error) {
+	if err := storage.ValidateMetadata(metadata); err != nil {
+		return nil, err
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	volume, ok := o.volumes[volumeName]
+	if !ok {
+		return nil, o.volumeNotFoundError(volumeName)
+	}
+	newMetadata := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		newMetadata[k] = v
+	}
+	oldMetadata := volume.Config.Metadata
+	volume.Config.Metadata = newMetadata
+	if err := o.storeClient.UpdateVolume(volume); err != nil {
+		volume.Config.Metadata = oldMetadata
+		return nil, err
+	}
+	externalVol := volume.ConstructExternal()
+	o.markDirty()
+	o.events.publish(EventUpdate, EventObjectVolume, volumeName, externalVol)
+	return externalVol, nil
+}
+
+// RenameVolume changes a volume's name in Trident.  The volume keeps its
+// internal name, so nothing changes on the backend.  A transaction ensures
+// that an interrupted rename is completed when Trident restarts.
+func (o *tridentOrchestrator) RenameVolume(
+	volumeName, newName string,
+) (*storage.VolumeExternal, error) {
+	if newName == "" {
+		return nil, errors.Errorf(errors.InvalidInput,
+			"The new volume name must not be empty.")
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	volume, ok := o.volumes[volumeName]
+	if !ok {
+		return nil, o.volumeNotFoundError(volumeName)
+	}
+	if err := o.checkNotDeleting(volume); err != nil {
+		return nil, err
+	}
+	if volumeName == newName {
+		return volume.ConstructExternal(), nil
+	}
+	if _, ok = o.volumeGroups[volume.Config.Group]; ok {
+		return nil, errors.Errorf(errors.Conflict, "Volume %s belongs to "+
+			"volume group %s and can't be renamed.", volumeName,
+			volume.Config.Group)
+	}
+	if o.volumeExists(newName) {
+		return nil, errors.Errorf(errors.AlreadyExists,
+			"Volume %s already exists.", newName)
+	}
+
+	newConfig := *volume.Config
+	newConfig.Name = newName
+	renamed := storage.NewVolume(&newConfig, volume.Backend, volume.Pool)
+	renamed.Attachments = volume.Attachments
+	renamed.Deletion = volume.Deletion
+	renamed.Replication = volume.Replication
+
+	volTxn := &persistent_store.VolumeTransaction{
+		Config:  &newConfig,
+		Op:      persistent_store.RenameVolume,
+		OldName: volumeName,
+	}
+	if err := o.storeClient.AddVolumeTransaction(volTxn); err != nil {
+		return nil, err
+	}
+	if err := o.storeClient.AddVolume(renamed); err != nil {
+		if txErr := o.storeClient.DeleteVolumeTransaction(volTxn); txErr != nil {
+			log.WithFields(log.Fields{
+				"volume": volumeName,
+			}).Warn("Unable to delete volume rename transaction.")
+		}
+		return nil, err
+	}
+	if err := o.storeClient.DeleteVolume(volume); err != nil {
+		// Leave the transaction in place if the new record can't be
+		// removed either, so that the rename is completed on restart.
+		if delErr := o.storeClient.DeleteVolume(renamed); delErr == nil {
+			if txErr := o.storeClient.DeleteVolumeTransaction(volTxn); txErr != nil {
+				log.WithFields(log.Fields{
+					"volume": volumeName,
+				}).Warn("Unable to delete volume rename transaction.")
+			}
+		}
+		return nil, err
+	}
+
+	volume.Pool.DeleteVolume(volume)
+	delete(o.volumes, volumeName)
+	renamed.Pool.AddVolume(renamed, false)
+	o.volumes[newName] = renamed
+	if err := o.storeClient.DeleteVolumeTransaction(volTxn); err != nil {
+		log.WithFields(log.Fields{
+			"volume": newName,
+		}).Warn("Unable to delete volume rename transaction; it will be " +
+			"resolved on restart.")
+	}
+	log.WithFields(log.Fields{
+		"oldName": volumeName,
+		"name":    newName,
+	}).Info("Renamed volume.")
+	o.markDirty()
+	o.events.publish(EventDelete, EventObjectVolume, volumeName,
+		volume.ConstructExternal())
+	externalVol := renamed.ConstructExternal()
+	o.events.publish(EventCreate, EventObjectVolume, newName, externalVol)
+	return externalVol, nil
+}
+
+// deleteOrphanedVolume forgets an orphaned volume.  Since its backend is
+// unknown, the volume itself is left in place.
+func (o *tridentOrchestrator) deleteOrphanedVolume(
+	orphan *storage.VolumeExternal,
+) error {
+	if err := o.storeClient.DeleteVolumeIgnoreNotFound(
+		storage.NewVolume(orphan.Config, nil, nil)); err != nil {
+		return err
+	}
+	delete(o.orphanedVolumes, orphan.Config.Name)
+	log.WithFields(log.Fields{
+		"volume":  orphan.Config.Name,
+		"backend": orphan.Backend,
+	}).Warn("Removed orphaned volume from Trident; it was not deleted " +
+		"from its backend.")
+	o.markDirty()
+	o.events.publish(EventDelete, EventObjectVolume, orphan.Config.Name,
+		orphan)
+	o.finishStorageClassDelete(orphan.Config.StorageClass)
+	return nil
+}
+
+// ReattachVolume binds an orphaned volume to a backend, e.g., after its
+// backend has been re-added.  If poolName is empty, the volume's previous
+// pool is used.  The volume must already exist on the backend with the
+// same internal name.
+func (o *tridentOrchestrator) ReattachVolume(
+	volumeName, backendName, poolName string,
+) (*storage.VolumeExternal, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	orphan, ok := o.orphanedVolumes[volumeName]
+	if !ok {
+		if _, ok = o.volumes[volumeName]; ok {
+			return nil, errors.Errorf(errors.Conflict,
+				"Volume %s is not orphaned.", volumeName)
+		}
+		return nil, errors.Errorf(errors.NotFound,
+			"Volume %s not found.", volumeName)
+	}
+	if poolName == "" {
+		poolName = orphan.Pool
+	}
+	pool, err := o.getPoolForExistingVolume(orphan.Config, backendName,
+		poolName)
+	if err != nil {
+		return nil, err
+	}
+
+	vol := storage.NewVolume(orphan.Config, pool.Backend, pool)
+	vol.Attachments = orphan.Attachments
+	vol.Deletion = orphan.Deletion
+	vol.Replication = orphan.Replication
+	if err = o.storeClient.UpdateVolume(vol); err != nil {
+		return nil, err
+	}
+	pool.AddVolume(vol, true)
+	o.volumes[volumeName] = vol
+	delete(o.orphanedVolumes, volumeName)
+	log.WithFields(log.Fields{
+		"volume":  volumeName,
+		"backend": backendName,
+		"pool":    poolName,
+	}).Info("Reattached orphaned volume.")
+	externalVol := vol.ConstructExternal()
+	o.markDirty()
+	o.events.publish(EventUpdate, EventObjectVolume, volumeName, externalVol)
+	return externalVol, nil
+}
+
+// getPoolForExistingVolume returns the named pool, checking that it can
+// hold a volume that already exists on its backend.
+func (o *tridentOrchestrator) getPoolForExistingVolume(
+	volConfig *storage.VolumeConfig, backendName, poolName string,
+) (*storage.StoragePool, error) {
+	backend, ok := o.backends[backendName]
+	if !ok || !backend.Online {
+		return nil, errors.Errorf(errors.NotFound,
+			"Backend %s not found.", backendName)
+	}
+	pool, ok := backend.Storage[poolName]
+	if !ok {
+		return nil, errors.Errorf(errors.InvalidInput,
+			"Backend %s has no storage pool named %s.", backendName, poolName)
+	}
+	if volConfig.Protocol != config.ProtocolAny &&
+		pool.GetProtocol() != config.ProtocolAny &&
+		volConfig.Protocol != pool.GetProtocol() {
+		return nil, errors.Errorf(errors.InvalidInput, "Storage pool %s of "+
+			"backend %s does not support protocol %s.", poolName,
+			backendName, volConfig.Protocol)
+	}
+	return pool, nil
+}
+
+// SetVolumeAccess replaces the list of hosts allowed to access a volume.
+func (o *tridentOrchestrator) SetVolumeAccess(
+	volumeName string, access *storage.VolumeAccess,
+) (*storage.VolumeExternal, error) {
+	if err := access.Validate(); err != nil {
+		return nil, err
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	volume, ok := o.volumes[volumeName]
+	if !ok {
+		return nil, o.volumeNotFoundError(volumeName)
+	}
+	if err := o.checkNotDeleting(volume); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(),
+		o.timeouts.ProvisionTimeout())
+	defer cancel()
+	if err := volume.Backend.SetVolumeAccess(ctx, volume,
+		access); err != nil {
+		return nil, err
+	}
+	oldAccess := volume.Config.Access
+	accessCopy := *access
+	volume.Config.Access = &accessCopy
+	if err := o.storeClient.UpdateVolume(volume); err != nil {
+		// Try to restore the backend to match the persisted volume.
+		volume.Config.Access = oldAccess
+		restore := oldAccess
+		if restore == nil {
+			restore = &storage.VolumeAccess{}
+		}
+		if restoreErr := volume.Backend.SetVolumeAccess(ctx, volume,
+			restore); restoreErr != nil {
+			log.WithFields(log.Fields{
+				"volume": volumeName,
+				"error":  restoreErr,
+			}).Error("Unable to restore volume access after failing to " +
+				"persist it.")
+		}
+		return nil, err
+	}
+	log.WithFields(log.Fields{
+		"volume":          volumeName,
+		"nfsClients":      access.NfsClients,
+		"iscsiInitiators": access.IscsiInitiators,
+	}).Info("Updated volume access.")
+	externalVol := volume.ConstructExternal()
+	o.markDirty()
+	o.events.publish(EventUpdate, EventObjectVolume, volumeName, externalVol)
+	return externalVol, nil
+}
+
+// SetVolumeFormatted records that a block volume has been formatted as
+// fsType.  A volume's filesystem type can't be changed once it is
+// formatted.
+func (o *tridentOrchestrator) SetVolumeFormatted(
+	volumeName, fsType string,
+) (*storage.VolumeExternal, error) {
+	if !config.IsValidFSType(fsType) {
+		return nil, errors.Errorf(errors.InvalidInput, "%v is an unsupported "+
+			"filesystem type! Acceptable values:  %s", fsType,
+			strings.Join(config.GetValidFSTypes(), ", "))
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	volume, ok := o.volumes[volumeName]
+	if !ok {
+		return nil, o.volumeNotFoundError(volumeName)
+	}
+	if volume.Config.Protocol != config.Block {
+		return nil, errors.Errorf(errors.InvalidInput,
+			"Volume %s is not a block volume.", volumeName)
+	}
+	if volume.Config.FSType != "" && volume.Config.FSType != fsType {
+		return nil, errors.Errorf(errors.Conflict, "Volume %s uses fsType %s; "+
+			"it can't be changed to %s.", volumeName, volume.Config.FSType,
+			fsType)
+	}
+	if volume.Config.Formatted {
+		return volume.ConstructExternal(), nil
+	}
+
+	oldFSType := volume.Config.FSType
+	volume.Config.FSType = fsType
+	volume.Config.Formatted = true
+	if err := o.storeClient.UpdateVolume(volume); err != nil {
+		volume.Config.FSType = oldFSType
+		volume.Config.Formatted = false
+		return nil, err
+	}
+	log.WithFields(log.Fields{
+		"volume": volumeName,
+		"fsType": fsType,
+	}).Info("Recorded volume as formatted.")
+	externalVol := volume.ConstructExternal()
+	o.markDirty()
+	o.events.publish(EventUpdate, EventObjectVolume, volumeName, externalVol)
+	return externalVol, nil
+}
+
+// AttachVolume records that a volume is published to a node.  Attaching a
+// volume to a node it is already attached to is not an error.
+func (o *tridentOrchestrator) AttachVolume(
+	volumeName, node string, readOnly bool,
+) (*storage.VolumeExternal, error) {
+	if node == "" {
+		return nil, errors.Errorf(errors.InvalidInput,
+			"A node name is required.")
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	volume, ok := o.volumes[volumeName]
+	if !ok {
+		return nil, o.volumeNotFoundError(volumeName)
+	}
+	if err := o.checkNotDeleting(volume); err != nil {
+		return nil, err
+	}
+	if attachment := volume.GetAttachment(node); attachment != nil {
+		if attachment.ReadOnly != readOnly {
+			return nil, errors.Errorf(errors.Conflict, "Volume %s is already "+
+				"attached to node %s with readOnly=%t.", volumeName, node,
+				attachment.ReadOnly)
+		}
+		return volume.ConstructExternal(), nil
+	}
+	switch volume.Config.AccessMode {
+	case config.ReadWriteOnce:
+		if len(volume.Attachments) > 0 {
+			return nil, errors.Errorf(errors.Conflict, "Volume %s is "+
+				"ReadWriteOnce and already attached to node %s.", volumeName,
+				volume.Attachments[0].Node)
+		}
+	case config.ReadOnlyMany:
+		if !readOnly {
+			return nil, errors.Errorf(errors.InvalidInput, "Volume %s is "+
+				"ReadOnlyMany and can only be attached read-only.", volumeName)
+		}
+	}
+
+	oldAttachments := volume.Attachments
+	volume.Attachments = append(append([]storage.VolumeAttachment{},
+		oldAttachments...), storage.VolumeAttachment{
+		Node:       node,
+		ReadOnly:   readOnly,
+		AttachedAt: time.Now().UTC(),
+	})
+	if err := o.storeClient.UpdateVolume(volume); err != nil {
+		volume.Attachments = oldAttachments
+		return nil, err
+	}
+	log.WithFields(log.Fields{
+		"volume":   volumeName,
+		"node":     node,
+		"readOnly": readOnly,
+	}).Info("Attached volume.")
+	externalVol := volume.ConstructExternal()
+	o.markDirty()
+	o.events.publish(EventUpdate, EventObjectVolume, volumeName, externalVol)
+	return externalVol, nil
+}
+
+// DetachVolume removes the record of a volume being published to a node.
+// Detaching a volume from a node it isn't attached to is not an error.
+func (o *tridentOrchestrator) DetachVolume(
+	volumeName, node string,
+) (*storage.VolumeExternal, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	volume, ok := o.volumes[volumeName]
+	if !ok {
+		return nil, o.volumeNotFoundError(volumeName)
+	}
+	if volume.GetAttachment(node) == nil {
+		log.WithFields(log.Fields{
+			"volume": volumeName,
+			"node":   node,
+		}).Debug("Volume is not attached to node.")
+		return volume.ConstructExternal(), nil
+	}
+
+	oldAttachments := volume.Attachments
+	attachments := make([]storage.VolumeAttachment, 0, len(oldAttachments))
+	for _, a := range oldAttachments {
+		if a.Node != node {
+			attachments = append(attachments, a)
+		}
+	}
+	volume.Attachments = attachments
+	if err := o.storeClient.UpdateVolume(volume); err != nil {
+		volume.Attachments = oldAttachments
+		return nil, err
+	}
+	log.WithFields(log.Fields{
+		"volume": volumeName,
+		"node":   node,
+	}).Info("Detached volume.")
+	externalVol := volume.ConstructExternal()
+	o.markDirty()
+	o.events.publish(EventUpdate, EventObjectVolume, volumeName, externalVol)
+	return externalVol, nil
 }
 
 func (o *tridentOrchestrator) ListVolumesByPlugin(pluginName string) []*storage.VolumeExternal {
@@ -804,7 +2688,7 @@ func (o *tridentOrchestrator) ListVolumesByPlugin(pluginName string) []*storage.
 }
 
 // getProtocol returns the appropriate protocol name based on volume access mode
-//or an empty string if all protocols are applicable.
+// or an empty string if all protocols are applicable.
 // ReadWriteOnce -> Any (File + Block)
 // ReadOnlyMany -> File
 // ReadWriteMany -> File
@@ -821,12 +2705,81 @@ func (o *tridentOrchestrator) getProtocol(mode config.AccessMode) config.Protoco
 	}
 }
 
-func (o *tridentOrchestrator) AddStorageClass(scConfig *storage_class.Config) (*storage_class.StorageClassExternal, error) {
+// validateStorageClassConfig checks a storage class's config, returning
+// the problems with its attribute requests as warnings, or, if strict is
+// set, as an error.
+func validateStorageClassConfig(
+	scConfig *storage_class.Config, strict bool,
+) ([]string, error) {
+	if err := storage.ValidateQoS(scConfig.MinIOPS, scConfig.MaxIOPS,
+		scConfig.BurstIOPS); err != nil {
+		return nil, err
+	}
+	if err := storage.ValidateMountOptions(scConfig.MountOptions); err != nil {
+		return nil, err
+	}
+	if err := storage.ValidateSnapshotReserve(
+		scConfig.SnapshotReserve); err != nil {
+		return nil, err
+	}
+	preferred := make(map[string]bool, len(scConfig.BackendPreference))
+	for _, backend := range scConfig.BackendPreference {
+		if backend == "" || preferred[backend] {
+			return nil, errors.Errorf(errors.InvalidInput, "Backend "+
+				"preference must list distinct backend names.")
+		}
+		preferred[backend] = true
+	}
+	if req, ok := scConfig.Attributes[sa.ProvisioningType]; ok {
+		if value := req.Value(); value != sa.Thin && value != sa.Thick {
+			return nil, errors.Errorf(errors.InvalidInput, "Invalid %s %v; "+
+				"must be %s or %s.", sa.ProvisioningType, value, sa.Thin,
+				sa.Thick)
+		}
+	}
+	if !config.IsValidReclaimPolicy(scConfig.ReclaimPolicy) {
+		return nil, errors.Errorf(errors.InvalidInput, "%v is an "+
+			"unsupported reclaim policy!  Acceptable values:  %s, %s",
+			scConfig.ReclaimPolicy, config.ReclaimDelete, config.ReclaimRetain)
+	}
+	problems := sa.ValidateRequests(scConfig.Attributes)
+	if strict && len(problems) > 0 {
+		return nil, errors.Errorf(errors.InvalidInput, "Invalid storage "+
+			"class %s:  %s", scConfig.Name, strings.Join(problems, "  "))
+	}
+	if len(problems) == 0 {
+		return nil, nil
+	}
+	return problems, nil
+}
+
+func (o *tridentOrchestrator) AddStorageClass(
+	scConfig *storage_class.Config,
+) (*StorageClassResult, error) {
+	warnings, err := validateStorageClassConfig(scConfig,
+		o.strictStorageClasses)
+	if err != nil {
+		return nil, err
+	}
 	o.mutex.Lock()
 	defer o.mutex.Unlock()
+	result, err := o.addStorageClass(scConfig)
+	if err != nil {
+		return nil, err
+	}
+	result.Warnings = append(warnings, result.Warnings...)
+	return result, nil
+}
+
+// addStorageClass adds a validated storage class.  The caller must hold the
+// orchestrator lock.
+func (o *tridentOrchestrator) addStorageClass(
+	scConfig *storage_class.Config,
+) (*StorageClassResult, error) {
 	sc := storage_class.New(scConfig)
 	if _, ok := o.storageClasses[sc.GetName()]; ok {
-		return nil, fmt.Errorf("Storage class %s already exists.", sc.GetName())
+		return nil, errors.Errorf(errors.AlreadyExists,
+			"Storage class %s already exists.", sc.GetName())
 	}
 	err := o.storeClient.AddStorageClass(sc)
 	if err != nil {
@@ -846,7 +2799,98 @@ func (o *tridentOrchestrator) AddStorageClass(scConfig *storage_class.Config) (*
 			"storageClass": sc.GetName(),
 		}).Infof("Storage class satisfied by %d storage pools.", added)
 	}
-	return sc.ConstructExternal(), nil
+	external := o.constructStorageClassExternal(sc)
+	o.markDirty()
+	o.events.publish(EventCreate, EventObjectStorageClass, sc.GetName(),
+		external)
+	return &StorageClassResult{
+		StorageClassExternal: external,
+		Warnings:             o.storageClassWarnings(sc, added),
+	}, nil
+}
+
+// UpdateStorageClass replaces a storage class's config and re-evaluates
+// which storage pools satisfy it.  Existing volumes of the class stay where
+// they are; those whose pools no longer satisfy it are reported as
+// nonconforming.
+func (o *tridentOrchestrator) UpdateStorageClass(
+	scConfig *storage_class.Config,
+) (*StorageClassResult, error) {
+	warnings, err := validateStorageClassConfig(scConfig,
+		o.strictStorageClasses)
+	if err != nil {
+		return nil, err
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	result, err := o.updateStorageClass(scConfig)
+	if err != nil {
+		return nil, err
+	}
+	result.Warnings = append(warnings, result.Warnings...)
+	return result, nil
+}
+
+// updateStorageClass updates a storage class with a validated config.  The
+// caller must hold the orchestrator lock.
+func (o *tridentOrchestrator) updateStorageClass(
+	scConfig *storage_class.Config,
+) (*StorageClassResult, error) {
+	oldSC, ok := o.storageClasses[scConfig.Name]
+	if !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Storage class %s not found.", scConfig.Name)
+	}
+	sc := storage_class.New(scConfig)
+	sc.SetPendingDelete(oldSC.IsPendingDelete())
+	if err := o.storeClient.UpdateStorageClass(sc); err != nil {
+		return nil, err
+	}
+	for _, vc := range oldSC.GetStoragePoolsForProtocol(config.ProtocolAny) {
+		vc.RemoveStorageClass(sc.GetName())
+	}
+	o.storageClasses[sc.GetName()] = sc
+	added := 0
+	for _, backend := range o.backends {
+		added += sc.CheckAndAddBackend(backend)
+	}
+	external := o.constructStorageClassExternal(sc)
+	if len(external.NonconformingVolumes) > 0 {
+		log.WithFields(log.Fields{
+			"storageClass": sc.GetName(),
+			"volumes":      external.NonconformingVolumes,
+		}).Warn("Some of the storage class's volumes are in storage pools " +
+			"that no longer satisfy it.")
+	}
+	log.WithFields(log.Fields{
+		"storageClass": sc.GetName(),
+	}).Infof("Updated storage class; satisfied by %d storage pools.", added)
+	o.markDirty()
+	o.events.publish(EventUpdate, EventObjectStorageClass, sc.GetName(),
+		external)
+	return &StorageClassResult{
+		StorageClassExternal: external,
+		Warnings:             o.storageClassWarnings(sc, added),
+	}, nil
+}
+
+// constructStorageClassExternal returns the external form of sc, listing
+// the volumes of the class in pools that don't satisfy it.  Volumes on
+// offline backends, whose pools satisfy no class, aren't listed.  The caller
+// must hold the orchestrator lock.
+func (o *tridentOrchestrator) constructStorageClassExternal(
+	sc *storage_class.StorageClass,
+) *storage_class.StorageClassExternal {
+	external := sc.ConstructExternal()
+	for name, vol := range o.volumes {
+		if vol.Config.StorageClass == sc.GetName() && vol.Backend.Online &&
+			!sc.ContainsPool(vol.Pool) {
+			external.NonconformingVolumes = append(
+				external.NonconformingVolumes, name)
+		}
+	}
+	sort.Strings(external.NonconformingVolumes)
+	return external
 }
 
 func (o *tridentOrchestrator) GetStorageClass(scName string) *storage_class.StorageClassExternal {
@@ -858,7 +2902,7 @@ func (o *tridentOrchestrator) GetStorageClass(scName string) *storage_class.Stor
 	}
 	// Storage classes aren't threadsafe (we modify them during runtime),
 	// so return a copy, rather than the original
-	return sc.ConstructExternal()
+	return o.constructStorageClassExternal(sc)
 }
 
 func (o *tridentOrchestrator) ListStorageClasses() []*storage_class.StorageClassExternal {
@@ -866,23 +2910,65 @@ func (o *tridentOrchestrator) ListStorageClasses() []*storage_class.StorageClass
 	defer o.mutex.Unlock()
 	ret := make([]*storage_class.StorageClassExternal, 0, len(o.storageClasses))
 	for _, sc := range o.storageClasses {
-		ret = append(ret, sc.ConstructExternal())
+		ret = append(ret, o.constructStorageClassExternal(sc))
 	}
 	return ret
 }
 
-// Delete storage class deletes a storage class from the orchestrator iff
-// no volumes exist that use that storage class.
+// DeleteStorageClass deletes a storage class from the orchestrator.  If
+// storage classes are protected and volumes still use the class, it's
+// marked pending deletion instead, and a Conflict error is returned; see
+// SetProtectStorageClasses.
 func (o *tridentOrchestrator) DeleteStorageClass(scName string) (bool, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.deleteStorageClass(scName, false)
+}
+
+// ForceDeleteStorageClass deletes a storage class even if volumes still use
+// it.
+func (o *tridentOrchestrator) ForceDeleteStorageClass(
+	scName string,
+) (bool, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.deleteStorageClass(scName, true)
+}
+
+// storageClassVolumes returns the names of the volumes, including orphaned
+// ones, that use a storage class.  The caller must hold the orchestrator
+// lock.
+func (o *tridentOrchestrator) storageClassVolumes(scName string) []string {
+	names := make([]string, 0)
+	for name, vol := range o.volumes {
+		if vol.Config.StorageClass == scName {
+			names = append(names, name)
+		}
+	}
+	for name, orphan := range o.orphanedVolumes {
+		if orphan.Config.StorageClass == scName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// deleteStorageClass deletes a storage class, unless storage classes are
+// protected, volumes still use it, and force isn't set, in which case it's
+// marked pending deletion.  The caller must hold the orchestrator lock.
+func (o *tridentOrchestrator) deleteStorageClass(
+	scName string, force bool,
+) (bool, error) {
 	sc, found := o.storageClasses[scName]
 	if !found {
-		return found, fmt.Errorf("Storage class %s not found.", scName)
+		return found, errors.Errorf(errors.NotFound,
+			"Storage class %s not found.", scName)
 	}
-	volumes := sc.GetVolumes()
-	if len(volumes) > 0 {
-		volNames := make([]string, len(volumes))
-		for i, vol := range volumes {
-			volNames[i] = vol.Config.Name
+	volNames := o.storageClassVolumes(scName)
+	if len(volNames) > 0 {
+		if o.protectStorageClasses && !force {
+			return found, o.markStorageClassPendingDelete(sc, volNames)
 		}
 		log.WithFields(log.Fields{
 			"storageClass": scName,
@@ -902,22 +2988,95 @@ func (o *tridentOrchestrator) DeleteStorageClass(scName string) (bool, error) {
 	for _, vc := range sc.GetStoragePoolsForProtocol(config.ProtocolAny) {
 		vc.RemoveStorageClass(scName)
 	}
+	o.markDirty()
+	o.events.publish(EventDelete, EventObjectStorageClass, scName,
+		sc.ConstructExternal())
 	return found, nil
 }
 
+// markStorageClassPendingDelete marks a storage class that volumes still
+// use to be deleted once the last of them is, and returns the Conflict
+// error reporting that it wasn't deleted.  The caller must hold the
+// orchestrator lock.
+func (o *tridentOrchestrator) markStorageClassPendingDelete(
+	sc *storage_class.StorageClass, volNames []string,
+) error {
+	if !sc.IsPendingDelete() {
+		sc.SetPendingDelete(true)
+		if err := o.storeClient.UpdateStorageClass(sc); err != nil {
+			sc.SetPendingDelete(false)
+			return err
+		}
+		log.WithFields(log.Fields{
+			"storageClass": sc.GetName(),
+			"volumes":      volNames,
+		}).Info("Storage class is still in use; it will be deleted once " +
+			"its last volume is.")
+		o.markDirty()
+		o.events.publish(EventUpdate, EventObjectStorageClass, sc.GetName(),
+			o.constructStorageClassExternal(sc))
+	}
+	return errors.Errorf(errors.Conflict, "Storage class %s is still used "+
+		"by volumes %s; it will be deleted once they are, or it can be "+
+		"deleted now with force.", sc.GetName(), strings.Join(volNames, ", "))
+}
+
+// finishStorageClassDelete deletes a storage class pending deletion if no
+// volumes use it any longer.  The caller must hold the orchestrator lock.
+func (o *tridentOrchestrator) finishStorageClassDelete(scName string) {
+	sc, ok := o.storageClasses[scName]
+	if !ok || !sc.IsPendingDelete() ||
+		len(o.storageClassVolumes(scName)) > 0 {
+		return
+	}
+	if _, err := o.deleteStorageClass(scName, true); err != nil {
+		log.WithFields(log.Fields{
+			"storageClass": scName,
+		}).Warnf("Unable to delete storage class pending deletion after "+
+			"its last volume was deleted; it will be retried when Trident "+
+			"restarts:  %v", err)
+		return
+	}
+	log.WithFields(log.Fields{
+		"storageClass": scName,
+	}).Info("Deleted storage class pending deletion after its last volume " +
+		"was deleted.")
+}
+
+func (o *tridentOrchestrator) SubscribeEvents() <-chan Event {
+	return o.events.subscribe()
+}
+
+func (o *tridentOrchestrator) UnsubscribeEvents(ch <-chan Event) {
+	o.events.unsubscribe(ch)
+}
+
+func (o *tridentOrchestrator) AddProvisioningListener(
+	l ProvisioningListener,
+) {
+	o.provisioning.add(l)
+}
+
+func (o *tridentOrchestrator) RemoveProvisioningListener(
+	l ProvisioningListener,
+) {
+	o.provisioning.remove(l)
+}
+
 func (o *tridentOrchestrator) updateBackendOnPersistentStore(
-	backend *storage.StorageBackend, newBackend bool,
+	ctx context.Context, backend *storage.StorageBackend, newBackend bool,
 ) error {
 	// Update the persistent store with the backend information
 	if o.bootstrapped {
 		var err error
+		store := o.storeClient.WithContext(ctx)
 		if newBackend {
-			err = o.storeClient.AddBackend(backend)
+			err = store.AddBackend(backend)
 		} else {
 			log.WithFields(log.Fields{
 				"backend": backend.Name,
 			}).Info("Updating an existing backend.")
-			err = o.storeClient.UpdateBackend(backend)
+			err = store.UpdateBackend(backend)
 		}
 		if err != nil {
 			return err
@@ -925,3 +3084,155 @@ func (o *tridentOrchestrator) updateBackendOnPersistentStore(
 	}
 	return nil
 }
+
+type poolsByName struct {
+	pools []*storage.StoragePool
+	order []int
+}
+
+func (p poolsByName) Len() int      { return len(p.order) }
+func (p poolsByName) Swap(i, j int) { p.order[i], p.order[j] = p.order[j], p.order[i] }
+func (p poolsByName) Less(i, j int) bool {
+	a, b := p.pools[p.order[i]], p.pools[p.order[j]]
+	if a.Backend.Name != b.Backend.Name {
+		return a.Backend.Name < b.Backend.Name
+	}
+	return a.Name < b.Name
+}
+
+// placementOrder returns the order, as indices into pools, in which to try
+// pools for a new volume according to the placement policy.  The caller
+// must hold the orchestrator lock.
+func (o *tridentOrchestrator) placementOrder(pools []*storage.StoragePool) []int {
+	if o.placementPolicy != config.PlacementOrdered {
+		// Randomize the pools for better distribution of load across all
+		// backends.
+		return o.placementRand.Perm(len(pools))
+	}
+	order := make([]int, len(pools))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Sort(poolsByName{pools, order})
+	return order
+}
+
+// backendTiers sorts an order of pools by the tiers of their backends in a
+// storage class's backend preference, keeping the order within each tier.
+type backendTiers struct {
+	pools []*storage.StoragePool
+	order []int
+	sc    *storage_class.StorageClass
+}
+
+func (t backendTiers) Len() int      { return len(t.order) }
+func (t backendTiers) Swap(i, j int) { t.order[i], t.order[j] = t.order[j], t.order[i] }
+func (t backendTiers) Less(i, j int) bool {
+	return t.sc.BackendTier(t.pools[t.order[i]].Backend.Name) <
+		t.sc.BackendTier(t.pools[t.order[j]].Backend.Name)
+}
+
+// sortByBackendTier reorders order, indices into pools, so that the pools
+// of the storage class's preferred backends are tried first.
+func sortByBackendTier(
+	pools []*storage.StoragePool, order []int,
+	sc *storage_class.StorageClass,
+) {
+	sort.Stable(backendTiers{pools, order, sc})
+}
+
+// checkContext returns an error if ctx is done, so that a canceled or
+// expired request stops before starting its next backend operation.
+func checkContext(ctx context.Context, operation string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s canceled:  %v", operation, err)
+	}
+	return nil
+}
+
+// callWithContext runs f, a backend operation, but returns early with an
+// error if ctx is done first, so that an unresponsive storage system can't
+// hold the orchestrator lock indefinitely.  An abandoned f keeps running; if
+// it later succeeds, undo is called to reverse its effect.  Since an
+// abandoned f runs without the lock, it must only call the storage system,
+// leaving the orchestrator's state, including its pools, to the caller; undo
+// must take the lock itself.  The call is traced as a driver span with tags,
+// e.g., the volume and backend.
+func callWithContext(
+	ctx context.Context, operation string, tags map[string]string,
+	f func() error, undo func(),
+) (err error) {
+	if err = checkContext(ctx, operation); err != nil {
+		return err
+	}
+	_, span := tracing.StartChildSpan(ctx, tracing.Driver, operation, tags)
+	defer func() {
+		span.Finish(err)
+	}()
+	var (
+		mutex     sync.Mutex
+		abandoned bool
+	)
+	done := make(chan error, 1)
+	go func() {
+		err := f()
+		mutex.Lock()
+		wasAbandoned := abandoned
+		mutex.Unlock()
+		if !wasAbandoned {
+			done <- err
+		} else if err == nil && undo != nil {
+			undo()
+		}
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	select {
+	case err := <-done:
+		// f finished while ctx was expiring.
+		return err
+	default:
+	}
+	abandoned = true
+	log.WithFields(log.Fields{
+		"operation": operation,
+		"error":     ctx.Err(),
+	}).Warn("Abandoned a backend operation.")
+	return fmt.Errorf("%s abandoned:  %v", operation, ctx.Err())
+}
+
+// removeAbandonedVolume removes a volume whose creation finished after the
+// request for it was abandoned.
+func (o *tridentOrchestrator) removeAbandonedVolume(
+	backend *storage.StorageBackend, vol *storage.Volume,
+) {
+	if vol == nil {
+		return
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	logFields := log.Fields{
+		"backend": backend.Name,
+		"volume":  vol.Config.Name,
+	}
+	// The volume was never added to its pool.  The request's context is
+	// done, so the deletion is given its own.
+	ctx, cancel := context.WithTimeout(context.Background(),
+		o.timeouts.DeleteTimeout())
+	defer cancel()
+	if err := backend.DestroyVolume(ctx, vol); err != nil {
+		if zombieErr := o.recordZombieVolume(vol, err); zombieErr != nil {
+			log.WithFields(logFields).Errorf("Unable to remove a volume "+
+				"created after its request was abandoned; remove it "+
+				"manually:  %v; %v", err, zombieErr)
+		}
+		return
+	}
+	log.WithFields(logFields).Info("Removed a volume created after its " +
+		"request was abandoned.")
+}
@@ -6,20 +6,34 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
 
+	"github.com/netapp/trident/app_template"
 	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/credentials"
 	"github.com/netapp/trident/drivers/fake"
+	"github.com/netapp/trident/errors"
+	"github.com/netapp/trident/faults"
+	"github.com/netapp/trident/hooks"
+	"github.com/netapp/trident/namespace_policy"
 	"github.com/netapp/trident/persistent_store"
+	"github.com/netapp/trident/snapshot_policy"
 	"github.com/netapp/trident/storage"
 	backend_fake "github.com/netapp/trident/storage/fake"
 	sa "github.com/netapp/trident/storage_attribute"
 	"github.com/netapp/trident/storage_class"
 	tu "github.com/netapp/trident/storage_class/test_utils"
+	"github.com/netapp/trident/tracing"
 )
 
 var (
@@ -27,6 +41,8 @@ var (
 	debug  = flag.Bool("debug", false, "Enable debugging output")
 
 	inMemoryClient *persistent_store.InMemoryClient
+
+	testCtx = context.Background()
 )
 
 func init() {
@@ -73,6 +89,74 @@ func cleanup(t *testing.T, o *tridentOrchestrator) {
 	if err != nil && err.Error() != persistent_store.KeyErrorMsg {
 		t.Fatal("Unable to clean up volumes:  ", err)
 	}
+	hookList, err := o.storeClient.GetHooks()
+	if err != nil && err.Error() != persistent_store.KeyErrorMsg {
+		t.Fatal("Unable to retrieve hooks:  ", err)
+	}
+	for _, h := range hookList {
+		if err = o.storeClient.DeleteHook(h); err != nil {
+			t.Fatalf("Unable to clean up hook %s:  %v", h.Name, err)
+		}
+	}
+	policies, err := o.storeClient.GetSnapshotPolicies()
+	if err != nil && err.Error() != persistent_store.KeyErrorMsg {
+		t.Fatal("Unable to retrieve snapshot policies:  ", err)
+	}
+	for _, p := range policies {
+		if err = o.storeClient.DeleteSnapshotPolicy(p); err != nil {
+			t.Fatalf("Unable to clean up snapshot policy %s:  %v", p.Name,
+				err)
+		}
+	}
+	retainedVols, err := o.storeClient.GetRetainedVolumes()
+	if err != nil && err.Error() != persistent_store.KeyErrorMsg {
+		t.Fatal("Unable to retrieve retained volumes:  ", err)
+	}
+	for _, r := range retainedVols {
+		if err = o.storeClient.DeleteRetainedVolume(r); err != nil {
+			t.Fatalf("Unable to clean up retained volume %s:  %v",
+				r.Config.Name, err)
+		}
+	}
+	retries, err := o.storeClient.GetProvisioningRetries()
+	if err != nil && err.Error() != persistent_store.KeyErrorMsg {
+		t.Fatal("Unable to retrieve provisioning retries:  ", err)
+	}
+	for _, r := range retries {
+		if err = o.storeClient.DeleteProvisioningRetry(r); err != nil {
+			t.Fatalf("Unable to clean up provisioning retry %s:  %v",
+				r.Volume, err)
+		}
+	}
+	groups, err := o.storeClient.GetVolumeGroups()
+	if err != nil && err.Error() != persistent_store.KeyErrorMsg {
+		t.Fatal("Unable to retrieve volume groups:  ", err)
+	}
+	for _, g := range groups {
+		if err = o.storeClient.DeleteVolumeGroup(g); err != nil {
+			t.Fatalf("Unable to clean up volume group %s:  %v", g.Name,
+				err)
+		}
+	}
+	templates, err := o.storeClient.GetTemplates()
+	if err != nil && err.Error() != persistent_store.KeyErrorMsg {
+		t.Fatal("Unable to retrieve templates:  ", err)
+	}
+	for _, tmpl := range templates {
+		if err = o.storeClient.DeleteTemplate(tmpl); err != nil {
+			t.Fatalf("Unable to clean up template %s:  %v", tmpl.Name, err)
+		}
+	}
+	nsPolicies, err := o.storeClient.GetNamespacePolicies()
+	if err != nil && err.Error() != persistent_store.KeyErrorMsg {
+		t.Fatal("Unable to retrieve namespace policies:  ", err)
+	}
+	for _, p := range nsPolicies {
+		if err = o.storeClient.DeleteNamespacePolicy(p); err != nil {
+			t.Fatalf("Unable to clean up namespace policy %s:  %v",
+				p.Namespace, err)
+		}
+	}
 	if *etcdV2 == "" {
 		// Clear the InMemoryClient state so that it looks like we're
 		// bootstrapping afresh next time.
@@ -173,7 +257,7 @@ func diffExternalBackends(
 func runDeleteTest(
 	t *testing.T, d *deleteTest, orchestrator *tridentOrchestrator,
 ) {
-	found, err := orchestrator.DeleteVolume(d.name)
+	found, err := orchestrator.DeleteVolume(testCtx, d.name)
 	if err == nil && !d.expectedSuccess {
 		t.Errorf("%s:  volume delete succeeded when it should not have.",
 			d.name)
@@ -378,7 +462,7 @@ func TestAddStorageClassVolumes(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Unable to generate config JSON for %s:  %v", c.name, err)
 		}
-		_, err = orchestrator.AddStorageBackend(config)
+		_, err = orchestrator.AddStorageBackend(testCtx, config)
 		if err != nil {
 			t.Errorf("Unable to add backend %s:  %v", c.name, err)
 			errored = true
@@ -571,7 +655,7 @@ func TestAddStorageClassVolumes(t *testing.T) {
 			deleteAfterSC: false,
 		},
 	} {
-		vol, err := orchestrator.AddVolume(s.config)
+		vol, err := orchestrator.AddVolume(testCtx, s.config)
 		if err != nil && s.expectedSuccess {
 			t.Errorf("%s:  got unexpected error %v", s.name, err)
 			continue
@@ -633,7 +717,7 @@ func TestAddStorageClassVolumes(t *testing.T) {
 			t.Errorf("%s:  unable to communicate with backing store:  %v",
 				s.name, err)
 		}
-		if !reflect.DeepEqual(externalVolume, vol) {
+		if !reflect.DeepEqual(externalVolume, vol.VolumeExternal) {
 			t.Errorf("%s:  external volume %s stored in backend does not match"+
 				" created volume.", s.name, externalVolume.Config.Name)
 			externalVolJSON, err := json.Marshal(externalVolume)
@@ -733,7 +817,7 @@ func addBackend(
 	if err != nil {
 		t.Fatal("Unable to create mock driver config JSON: ", err)
 	}
-	_, err = orchestrator.AddStorageBackend(configJSON)
+	_, err = orchestrator.AddStorageBackend(testCtx, configJSON)
 	if err != nil {
 		t.Fatal("Unable to add initial backend:  ", err)
 	}
@@ -782,8 +866,8 @@ func TestBackendUpdateAndDelete(t *testing.T) {
 	}
 	orchestrator.mutex.Unlock()
 
-	_, err := orchestrator.AddVolume(generateVolumeConfig(volumeName, 50, scName,
-		config.File))
+	_, err := orchestrator.AddVolume(testCtx,
+		generateVolumeConfig(volumeName, 50, scName, config.File))
 	if err != nil {
 		t.Fatal("Unable to create volume: ", err)
 	}
@@ -856,7 +940,7 @@ func TestBackendUpdateAndDelete(t *testing.T) {
 				err)
 			continue
 		}
-		_, err = orchestrator.AddStorageBackend(newConfigJSON)
+		_, err = orchestrator.AddStorageBackend(testCtx, newConfigJSON)
 		if err != nil {
 			t.Errorf("%s:  unable to update backend with a nonconflicting "+
 				"change:  %v", c.name, err)
@@ -977,7 +1061,7 @@ func TestBackendUpdateAndDelete(t *testing.T) {
 				err)
 			continue
 		}
-		_, err = orchestrator.AddStorageBackend(newConfigJSON)
+		_, err = orchestrator.AddStorageBackend(testCtx, newConfigJSON)
 		if err == nil {
 			t.Errorf("%s:  invalid backend update completed successfully.",
 				c.name)
@@ -1016,8 +1100,8 @@ func TestBackendUpdateAndDelete(t *testing.T) {
 	if err != nil {
 		t.Fatal("Unable to offline backend:  ", err)
 	}
-	_, err = orchestrator.AddVolume(generateVolumeConfig(offlineVolumeName, 50,
-		scName, config.File))
+	_, err = orchestrator.AddVolume(testCtx,
+		generateVolumeConfig(offlineVolumeName, 50, scName, config.File))
 	if err == nil {
 		t.Error("Created volume volume on offline backend.")
 	}
@@ -1090,7 +1174,7 @@ func TestBackendUpdateAndDelete(t *testing.T) {
 	newOrchestrator.mutex.Unlock()
 
 	// Test that deleting the volume causes the backend to be deleted.
-	_, err = orchestrator.DeleteVolume(volumeName)
+	_, err = orchestrator.DeleteVolume(testCtx, volumeName)
 	if err != nil {
 		t.Fatal("Unable to delete volume for offline backend:  ", err)
 	}
@@ -1146,7 +1230,7 @@ func TestBackendCleanup(t *testing.T) {
 
 	orchestrator := getOrchestrator()
 	addBackendStorageClass(t, orchestrator, offlineBackendName, scName)
-	_, err := orchestrator.AddVolume(generateVolumeConfig(volumeName, 50,
+	_, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(volumeName, 50,
 		scName, config.File))
 	if err != nil {
 		t.Fatal("Unable to create volume: ", err)
@@ -1204,7 +1288,7 @@ func TestLoadBackend(t *testing.T) {
 			},
 		},
 	)
-	originalBackend, err := orchestrator.AddStorageBackend(configJSON)
+	originalBackend, err := orchestrator.AddStorageBackend(testCtx, configJSON)
 	if err != nil {
 		t.Fatal("Unable to initially add backend:  ", err)
 	}
@@ -1217,7 +1301,7 @@ func TestLoadBackend(t *testing.T) {
 	if err != nil {
 		t.Fatal("Unable to marshal config from stored backend:  ", err)
 	}
-	newBackend, err := orchestrator.AddStorageBackend(newConfig)
+	newBackend, err := orchestrator.AddStorageBackend(testCtx, newConfig)
 	if err != nil {
 		t.Error("Unable to add backend from config:  ", err)
 	} else if !reflect.DeepEqual(newBackend, originalBackend) {
@@ -1227,9 +1311,11 @@ func TestLoadBackend(t *testing.T) {
 	newOrchestrator := getOrchestrator()
 	if bootstrappedBackend := newOrchestrator.GetBackend(backendName); bootstrappedBackend == nil {
 		t.Error("Unable to find backend after bootstrapping.")
-	} else if !reflect.DeepEqual(bootstrappedBackend, originalBackend) {
+	} else if !reflect.DeepEqual(bootstrappedBackend,
+		originalBackend.StorageBackendExternal) {
 		t.Errorf("External backends differ.")
-		diffExternalBackends(t, originalBackend, bootstrappedBackend)
+		diffExternalBackends(t, originalBackend.StorageBackendExternal,
+			bootstrappedBackend)
 	}
 	cleanup(t, orchestrator)
 }
@@ -1251,7 +1337,7 @@ func prepRecoveryTest(
 			},
 		},
 	)
-	_, err = orchestrator.AddStorageBackend(configJSON)
+	_, err = orchestrator.AddStorageBackend(testCtx, configJSON)
 	if err != nil {
 		t.Fatal("Unable to initialize backend: ", err)
 	}
@@ -1341,7 +1427,7 @@ func TestAddVolumeRecovery(t *testing.T) {
 	// afterwards
 	fullVolumeConfig := generateVolumeConfig(fullVolumeName, 50, scName,
 		config.File)
-	_, err := orchestrator.AddVolume(fullVolumeConfig)
+	_, err := orchestrator.AddVolume(testCtx, fullVolumeConfig)
 	if err != nil {
 		t.Fatal("Unable to add volume: ", err)
 	}
@@ -1368,17 +1454,17 @@ func TestDeleteVolumeRecovery(t *testing.T) {
 	// For the full test, we delete everything but the ending transaction.
 	fullVolumeConfig := generateVolumeConfig(fullVolumeName, 50, scName,
 		config.File)
-	_, err := orchestrator.AddVolume(fullVolumeConfig)
+	_, err := orchestrator.AddVolume(testCtx, fullVolumeConfig)
 	if err != nil {
 		t.Fatal("Unable to add volume: ", err)
 	}
-	_, err = orchestrator.DeleteVolume(fullVolumeName)
+	_, err = orchestrator.DeleteVolume(testCtx, fullVolumeName)
 	if err != nil {
 		t.Fatal("Unable to remove full volume:  ", err)
 	}
 	txOnlyVolumeConfig := generateVolumeConfig(txOnlyVolumeName, 50, scName,
 		config.File)
-	_, err = orchestrator.AddVolume(txOnlyVolumeConfig)
+	_, err = orchestrator.AddVolume(testCtx, txOnlyVolumeConfig)
 	if err != nil {
 		t.Fatal("Unable to add tx only volume: ", err)
 	}
@@ -1427,9 +1513,11 @@ func TestStorageClassOnlyBootstrap(t *testing.T) {
 	newOrchestrator := getOrchestrator()
 	if bootstrappedSC := newOrchestrator.GetStorageClass(scName); bootstrappedSC == nil {
 		t.Error("Unable to find storage class after bootstrapping.")
-	} else if !reflect.DeepEqual(bootstrappedSC, originalSC) {
+	} else if !reflect.DeepEqual(bootstrappedSC,
+		originalSC.StorageClassExternal) {
 		t.Errorf("External storage classs differ:\n\tOriginal:  %v\n\t."+
-			"Bootstrapped:  %v", originalSC, bootstrappedSC)
+			"Bootstrapped:  %v", originalSC.StorageClassExternal,
+			bootstrappedSC)
 	}
 	cleanup(t, orchestrator)
 }
@@ -1453,3 +1541,4974 @@ func TestFirstVolumeRecovery(t *testing.T) {
 		})
 	cleanup(t, orchestrator)
 }
+
+func TestEvents(t *testing.T) {
+	const (
+		backendName = "eventBackend"
+		scName      = "eventSC"
+		volumeName  = "eventVolume"
+	)
+
+	orchestrator := getOrchestrator()
+	events := orchestrator.SubscribeEvents()
+	defer orchestrator.UnsubscribeEvents(events)
+
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	if _, err := orchestrator.AddVolume(testCtx,
+		generateVolumeConfig(volumeName, 1, scName, config.File),
+	); err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+	if _, err := orchestrator.DeleteVolume(testCtx, volumeName); err != nil {
+		t.Fatal("Unable to delete volume:  ", err)
+	}
+	if _, err := orchestrator.DeleteStorageClass(scName); err != nil {
+		t.Fatal("Unable to delete storage class:  ", err)
+	}
+	if _, err := orchestrator.OfflineBackend(backendName); err != nil {
+		t.Fatal("Unable to offline backend:  ", err)
+	}
+
+	expected := []struct {
+		eventType  EventType
+		objectType EventObjectType
+		name       string
+	}{
+		{EventCreate, EventObjectBackend, backendName},
+		{EventCreate, EventObjectStorageClass, scName},
+		{EventCreate, EventObjectVolume, volumeName},
+		{EventDelete, EventObjectVolume, volumeName},
+		{EventDelete, EventObjectStorageClass, scName},
+		{EventDelete, EventObjectBackend, backendName},
+	}
+	for i, e := range expected {
+		select {
+		case event := <-events:
+			if event.Type != e.eventType || event.ObjectType != e.objectType ||
+				event.Name != e.name {
+				t.Errorf("Event %d:  expected %s %s %s, got %s %s %s", i,
+					e.eventType, e.objectType, e.name,
+					event.Type, event.ObjectType, event.Name)
+			}
+			if event.Object == nil {
+				t.Errorf("Event %d has no object.", i)
+			}
+		default:
+			t.Fatalf("Missing event %d (%s %s %s).", i, e.eventType,
+				e.objectType, e.name)
+		}
+	}
+	select {
+	case event := <-events:
+		t.Errorf("Unexpected event:  %s %s %s", event.Type, event.ObjectType,
+			event.Name)
+	default:
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestProvisioningHooks(t *testing.T) {
+	const (
+		backendName = "hookBackend"
+		scName      = "hookSC"
+	)
+	postCalls := make(chan string, 10)
+	hookServer := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			var req hooks.Request
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Error("Unable to decode hook request:  ", err)
+				return
+			}
+			response := &hooks.Response{Allowed: true}
+			switch {
+			case req.Phase == hooks.PostProvision:
+				postCalls <- req.Volume.Name
+			case strings.HasPrefix(req.Volume.Name, "reject"):
+				response.Allowed = false
+				response.Reason = "rejected for testing"
+			default:
+				req.Volume.SnapshotPolicy = "hooked"
+				response.Volume = req.Volume
+			}
+			json.NewEncoder(w).Encode(response)
+		},
+	))
+	defer hookServer.Close()
+
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	for _, phase := range []hooks.Phase{hooks.PreProvision, hooks.PostProvision} {
+		if _, err := orchestrator.AddHook(&hooks.Config{
+			Name:  string(phase),
+			Phase: phase,
+			URL:   hookServer.URL,
+		}); err != nil {
+			t.Fatalf("Unable to add %s hook:  %v", phase, err)
+		}
+	}
+
+	vol, err := orchestrator.AddVolume(testCtx,
+		generateVolumeConfig("hooked", 1, scName, config.File))
+	if err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+	if vol.Config.SnapshotPolicy != "hooked" {
+		t.Errorf("Pre-provision hook change not applied; got snapshot "+
+			"policy %s.", vol.Config.SnapshotPolicy)
+	}
+	select {
+	case name := <-postCalls:
+		if name != "hooked" {
+			t.Errorf("Post-provision hook called for %s, not hooked.", name)
+		}
+	default:
+		t.Error("Post-provision hook not called.")
+	}
+
+	if _, err = orchestrator.AddVolume(testCtx,
+		generateVolumeConfig("rejected", 1, scName, config.File),
+	); err == nil {
+		t.Error("Volume rejected by pre-provision hook was created.")
+	}
+	if orchestrator.GetVolume("rejected") != nil {
+		t.Error("Rejected volume present in orchestrator.")
+	}
+	if len(postCalls) != 0 {
+		t.Error("Post-provision hook called for rejected volume.")
+	}
+	if _, err = orchestrator.DeleteVolume(testCtx, "hooked"); err != nil {
+		t.Error("Unable to delete volume:  ", err)
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestSnapshotPolicies(t *testing.T) {
+	const (
+		backendName = "snapshotBackend"
+		scName      = "snapshotSC"
+		volName     = "scheduled"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	for _, policy := range []*snapshot_policy.Config{
+		{Name: "bad-schedule", Schedule: "* * *", Retention: 1},
+		{Name: "bad-retention", Schedule: "@hourly", Retention: 0},
+	} {
+		if _, err := orchestrator.AddSnapshotPolicy(policy); err == nil {
+			t.Errorf("Invalid snapshot policy %s was added.", policy.Name)
+		}
+	}
+	policy, err := orchestrator.AddSnapshotPolicy(&snapshot_policy.Config{
+		Name:      "hourly",
+		Schedule:  "0 * * * *",
+		Retention: 2,
+	})
+	if err != nil {
+		t.Fatal("Unable to add snapshot policy:  ", err)
+	}
+
+	volConfig := generateVolumeConfig("unscheduled", 1, scName, config.File)
+	volConfig.SnapshotSchedule = "nonexistent"
+	if _, err = orchestrator.AddVolume(testCtx, volConfig); err == nil {
+		t.Error("Volume with an unknown snapshot policy was created.")
+	}
+	volConfig = generateVolumeConfig(volName, 1, scName, config.File)
+	volConfig.SnapshotSchedule = policy.Name
+	if _, err = orchestrator.AddVolume(testCtx, volConfig); err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+
+	// Policies must survive bootstrapping.
+	newOrchestrator := getOrchestrator()
+	if newOrchestrator.GetSnapshotPolicy(policy.Name) == nil {
+		t.Error("Snapshot policy not bootstrapped.")
+	}
+
+	start := time.Date(2017, time.March, 1, 9, 0, 0, 0, time.UTC)
+	orchestrator.runScheduledSnapshots(start.Add(30 * time.Minute))
+	for i := 0; i < 3; i++ {
+		orchestrator.runScheduledSnapshots(
+			start.Add(time.Duration(i) * time.Hour))
+	}
+	vol := orchestrator.volumes[volName]
+	driver := vol.Backend.Driver.(*backend_fake.FakeStorageDriver)
+	snapshots := make([]string, 0)
+	for _, snapshot := range driver.Snapshots[vol.Config.InternalName] {
+		snapshots = append(snapshots, snapshot.Name)
+	}
+	expected := []string{
+		policy.SnapshotName(start.Add(time.Hour)),
+		policy.SnapshotName(start.Add(2 * time.Hour)),
+	}
+	if !reflect.DeepEqual(snapshots, expected) {
+		t.Errorf("Expected snapshots %v; got %v.", expected, snapshots)
+	}
+
+	if _, err = orchestrator.DeleteVolume(testCtx, volName); err != nil {
+		t.Error("Unable to delete volume:  ", err)
+	}
+	if _, err = orchestrator.DeleteSnapshotPolicy(policy.Name); err != nil {
+		t.Error("Unable to delete snapshot policy:  ", err)
+	}
+	if orchestrator.GetSnapshotPolicy(policy.Name) != nil {
+		t.Error("Deleted snapshot policy still present.")
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestCreateVolumeFromSnapshot(t *testing.T) {
+	const (
+		backendName  = "cloneBackend"
+		scName       = "cloneSC"
+		sourceName   = "source"
+		snapshotName = "snap"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	if _, err := orchestrator.AddVolume(testCtx,
+		generateVolumeConfig(sourceName, 1, scName, config.File),
+	); err != nil {
+		t.Fatal("Unable to add source volume:  ", err)
+	}
+	source := orchestrator.volumes[sourceName]
+	if err := source.Backend.CreateSnapshot(testCtx, source,
+		snapshotName); err != nil {
+		t.Fatal("Unable to create snapshot:  ", err)
+	}
+
+	for _, test := range []struct {
+		name   string
+		config *storage.VolumeConfig
+	}{
+		{"No snapshot", &storage.VolumeConfig{Name: "clone",
+			SourceVolume: sourceName}},
+		{"Unknown source", &storage.VolumeConfig{Name: "clone",
+			SourceVolume: "nonexistent", SourceSnapshot: snapshotName}},
+		{"Unknown snapshot", &storage.VolumeConfig{Name: "clone",
+			SourceVolume: sourceName, SourceSnapshot: "nonexistent"}},
+		{"Existing volume", &storage.VolumeConfig{Name: sourceName,
+			SourceVolume: sourceName, SourceSnapshot: snapshotName}},
+	} {
+		_, err := orchestrator.CreateVolumeFromSnapshot(testCtx, test.config)
+		if err == nil {
+			t.Errorf("%s:  volume was created.", test.name)
+		}
+	}
+	if orchestrator.GetVolume("clone") != nil {
+		t.Fatal("Failed clone present in orchestrator.")
+	}
+
+	clone, err := orchestrator.CreateVolumeFromSnapshot(testCtx,
+		&storage.VolumeConfig{
+			Name:           "clone",
+			SourceVolume:   sourceName,
+			SourceSnapshot: snapshotName,
+		})
+	if err != nil {
+		t.Fatal("Unable to create volume from snapshot:  ", err)
+	}
+	if clone.Backend != source.Backend.Name || clone.Pool != source.Pool.Name {
+		t.Errorf("Clone placed in %s/%s rather than %s/%s.", clone.Backend,
+			clone.Pool, source.Backend.Name, source.Pool.Name)
+	}
+	if clone.Config.StorageClass != scName ||
+		clone.Config.Size != source.Config.Size {
+		t.Errorf("Clone did not inherit the source's storage class and "+
+			"size; got %s and %s.", clone.Config.StorageClass,
+			clone.Config.Size)
+	}
+	found := false
+	for _, vol := range orchestrator.storageClasses[scName].GetVolumes() {
+		if vol.Config.Name == "clone" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Clone not registered with its storage class.")
+	}
+	if _, err = orchestrator.storeClient.GetVolume("clone"); err != nil {
+		t.Error("Clone not persisted:  ", err)
+	}
+	txns, err := orchestrator.storeClient.GetVolumeTransactions()
+	if err != nil && err.Error() != persistent_store.KeyErrorMsg {
+		t.Error("Unable to list volume transactions:  ", err)
+	} else if len(txns) != 0 {
+		t.Errorf("Found %d volume transactions after cloning.", len(txns))
+	}
+
+	for _, name := range []string{"clone", sourceName} {
+		if _, err = orchestrator.DeleteVolume(testCtx, name); err != nil {
+			t.Errorf("Unable to delete volume %s:  %v", name, err)
+		}
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestVolumeGroups(t *testing.T) {
+	const (
+		scName       = "groupSC"
+		groupName    = "group"
+		snapshotName = "groupSnap"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, "groupBackend1", scName)
+	addBackend(t, orchestrator, "groupBackend2")
+
+	volNames := []string{"groupData", "groupLog", "groupIndex"}
+	groupConfig := &VolumeGroupConfig{Name: groupName}
+	for _, name := range volNames {
+		groupConfig.Volumes = append(groupConfig.Volumes,
+			generateVolumeConfig(name, 1, scName, config.File))
+	}
+	group, err := orchestrator.AddVolumeGroup(testCtx, groupConfig)
+	if err != nil {
+		t.Fatal("Unable to add volume group:  ", err)
+	}
+	if !reflect.DeepEqual(group.Volumes, volNames) {
+		t.Errorf("Expected volumes %v; got %v.", volNames, group.Volumes)
+	}
+	for _, name := range volNames {
+		vol := orchestrator.GetVolume(name)
+		if vol == nil {
+			t.Fatalf("Volume %s not created.", name)
+		}
+		if vol.Backend != group.Backend || vol.Config.Group != groupName {
+			t.Errorf("Volume %s is on backend %s in group %s; expected "+
+				"backend %s in group %s.", name, vol.Backend,
+				vol.Config.Group, group.Backend, groupName)
+		}
+	}
+	if _, err = orchestrator.AddVolumeGroup(testCtx,
+		groupConfig); errors.GetType(err) != errors.AlreadyExists {
+		t.Error("Expected AlreadyExists error for existing group, got ", err)
+	}
+
+	if err = orchestrator.SnapshotVolumeGroup(groupName,
+		snapshotName); err != nil {
+		t.Fatal("Unable to snapshot volume group:  ", err)
+	}
+	for _, name := range volNames {
+		vol := orchestrator.volumes[name]
+		snapshots, err := vol.Backend.ListSnapshots(testCtx, vol)
+		if err != nil {
+			t.Fatal("Unable to list snapshots:  ", err)
+		}
+		if !reflect.DeepEqual(snapshots, []string{snapshotName}) {
+			t.Errorf("Volume %s has snapshots %v.", name, snapshots)
+		}
+	}
+
+	if _, err = orchestrator.DeleteVolume(testCtx,
+		volNames[0]); errors.GetType(err) != errors.Conflict {
+		t.Error("Expected Conflict error deleting a member, got ", err)
+	}
+	if _, err = orchestrator.AddVolume(testCtx, &storage.VolumeConfig{
+		Name:         "groupExtra",
+		Size:         "1073741824",
+		StorageClass: scName,
+		Group:        groupName,
+	}); errors.GetType(err) != errors.InvalidInput {
+		t.Error("Expected InvalidInput error adding a volume to a group, "+
+			"got ", err)
+	}
+
+	// A group whose volumes can't all be created isn't added, and the
+	// volumes that were created are removed.
+	failedConfig := &VolumeGroupConfig{
+		Name: "failedGroup",
+		Volumes: []*storage.VolumeConfig{
+			generateVolumeConfig("failedSmall", 1, scName, config.File),
+			generateVolumeConfig("failedLarge", 1000, scName, config.File),
+		},
+	}
+	if _, err = orchestrator.AddVolumeGroup(testCtx,
+		failedConfig); err == nil {
+		t.Error("Group with an oversized volume added.")
+	}
+	if orchestrator.GetVolumeGroup("failedGroup") != nil ||
+		orchestrator.GetVolume("failedSmall") != nil {
+		t.Error("Failed volume group not rolled back.")
+	}
+
+	found, err := orchestrator.DeleteVolumeGroup(testCtx, groupName)
+	if !found || err != nil {
+		t.Fatal("Unable to delete volume group:  ", err)
+	}
+	for _, name := range volNames {
+		if orchestrator.GetVolume(name) != nil {
+			t.Errorf("Volume %s not deleted with its group.", name)
+		}
+	}
+	if orchestrator.GetVolumeGroup(groupName) != nil {
+		t.Error("Volume group not deleted.")
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestTemplates(t *testing.T) {
+	const (
+		scName       = "templateSC"
+		templateName = "mongodb"
+		instanceName = "mongo1"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, "templateBackend", scName)
+
+	for _, invalid := range []*app_template.Config{
+		{Name: "empty"},
+		{Name: "unnamed", Volumes: []*app_template.VolumeTemplate{
+			{Size: "1G", StorageClass: scName},
+		}},
+		{Name: "badQoS", Volumes: []*app_template.VolumeTemplate{
+			{Name: "data", Size: "1G", MinIOPS: 500, MaxIOPS: 100},
+		}},
+	} {
+		if _, err := orchestrator.AddTemplate(invalid); err == nil {
+			t.Errorf("Invalid template %s added.", invalid.Name)
+		}
+	}
+	template, err := orchestrator.AddTemplate(&app_template.Config{
+		Name: templateName,
+		Volumes: []*app_template.VolumeTemplate{
+			{
+				Name:         "data",
+				Size:         "1073741824",
+				StorageClass: scName,
+				MountOptions: "noatime",
+			},
+			{Name: "journal", Size: "1073741824", StorageClass: scName},
+		},
+	})
+	if err != nil {
+		t.Fatal("Unable to add template:  ", err)
+	}
+	if _, err = orchestrator.AddTemplate(
+		template); errors.GetType(err) != errors.AlreadyExists {
+		t.Error("Expected AlreadyExists error for existing template, got ",
+			err)
+	}
+
+	// Templates must survive bootstrapping.
+	newOrchestrator := getOrchestrator()
+	if !reflect.DeepEqual(newOrchestrator.GetTemplate(templateName),
+		template) {
+		t.Error("Template not bootstrapped.")
+	}
+
+	group, err := orchestrator.ProvisionTemplate(testCtx, templateName,
+		instanceName)
+	if err != nil {
+		t.Fatal("Unable to provision template:  ", err)
+	}
+	volNames := []string{"mongo1-data", "mongo1-journal"}
+	if group.Name != instanceName || group.Template != templateName ||
+		!reflect.DeepEqual(group.Volumes, volNames) {
+		t.Errorf("Wrong volume group %v.", group)
+	}
+	vol := orchestrator.GetVolume("mongo1-data")
+	if vol == nil {
+		t.Fatal("Template volume not created.")
+	}
+	if vol.Config.MountOptions != "noatime" ||
+		vol.Config.Group != instanceName {
+		t.Errorf("Volume has mount options %q in group %s.",
+			vol.Config.MountOptions, vol.Config.Group)
+	}
+	if _, err = orchestrator.ProvisionTemplate(testCtx, templateName,
+		instanceName); errors.GetType(err) != errors.AlreadyExists {
+		t.Error("Expected AlreadyExists error for existing instance, got ",
+			err)
+	}
+	if _, err = orchestrator.ProvisionTemplate(testCtx, "missing",
+		"mongo2"); !errors.IsNotFound(err) {
+		t.Error("Expected NotFound error for missing template, got ", err)
+	}
+
+	// Deleting a template leaves its instances in place.
+	if found, err := orchestrator.DeleteTemplate(
+		templateName); !found || err != nil {
+		t.Fatal("Unable to delete template:  ", err)
+	}
+	if orchestrator.GetTemplate(templateName) != nil {
+		t.Error("Template not deleted.")
+	}
+	if orchestrator.GetVolumeGroup(instanceName) == nil {
+		t.Error("Instance deleted with its template.")
+	}
+	if _, err = orchestrator.DeleteVolumeGroup(testCtx,
+		instanceName); err != nil {
+		t.Error("Unable to delete instance:  ", err)
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestVolumeReplication(t *testing.T) {
+	const (
+		scName      = "replicationSC"
+		source      = "replicationSource"
+		peer        = "replicationPeer"
+		volName     = "replicatedVolume"
+		otherVolume = "replicatedVolume2"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, source, scName)
+	for _, name := range []string{volName, otherVolume} {
+		if _, err := orchestrator.AddVolume(testCtx,
+			generateVolumeConfig(name, 1, scName, config.File)); err != nil {
+			t.Fatal("Unable to add volume:  ", err)
+		}
+	}
+	addBackend(t, orchestrator, peer)
+
+	if _, err := orchestrator.EnableReplication(volName,
+		&ReplicationConfig{PeerBackend: source}); err == nil {
+		t.Error("Volume replicated to its own backend.")
+	}
+	vol, err := orchestrator.EnableReplication(volName,
+		&ReplicationConfig{PeerBackend: peer})
+	if err != nil {
+		t.Fatal("Unable to enable replication:  ", err)
+	}
+	if vol.Replication == nil || vol.Replication.PeerBackend != peer ||
+		vol.Replication.PeerPool != "primary" {
+		t.Fatalf("Wrong replication %v.", vol.Replication)
+	}
+	peerBackend := orchestrator.backends[peer]
+	peerDriver := peerBackend.Driver.(*backend_fake.FakeStorageDriver)
+	mirror, ok := peerDriver.Mirrors[vol.Replication.PeerInternalName]
+	if !ok {
+		t.Fatal("Mirror not created on the peer backend.")
+	}
+	if _, err = orchestrator.EnableReplication(volName, &ReplicationConfig{
+		PeerBackend: peer,
+	}); errors.GetType(err) != errors.AlreadyExists {
+		t.Error("Expected AlreadyExists error for replicated volume, got ",
+			err)
+	}
+	if _, err = orchestrator.DeleteVolume(testCtx,
+		volName); errors.GetType(err) != errors.Conflict {
+		t.Error("Expected Conflict error deleting a replicated volume, "+
+			"got ", err)
+	}
+	if _, err = orchestrator.RenameBackend(peer,
+		"renamedPeer"); errors.GetType(err) != errors.Conflict {
+		t.Error("Expected Conflict error renaming a peer backend, got ", err)
+	}
+
+	// Health and lag are reported with the volume.
+	mirror.Error = "Link down."
+	mirror.LastTransfer = time.Now().UTC().Add(-time.Hour)
+	orchestrator.checkReplication()
+	vol = orchestrator.GetVolume(volName)
+	if vol.Replication.Status.Healthy ||
+		vol.Replication.Status.Message != "Link down." ||
+		vol.Replication.LagSeconds < 3600 {
+		t.Errorf("Wrong replication status %v with lag %d.",
+			vol.Replication.Status, vol.Replication.LagSeconds)
+	}
+	if _, err = orchestrator.FailoverVolume(
+		volName); errors.GetType(err) != errors.Conflict {
+		t.Error("Expected Conflict error failing over an unhealthy mirror, "+
+			"got ", err)
+	}
+	mirror.Error = ""
+	orchestrator.checkReplication()
+	if !orchestrator.GetVolume(volName).Replication.Status.Healthy {
+		t.Error("Mirror not reported healthy.")
+	}
+
+	// Replication must survive bootstrapping.
+	newOrchestrator := getOrchestrator()
+	if bootstrapped := newOrchestrator.GetVolume(
+		volName); bootstrapped == nil || bootstrapped.Replication == nil {
+		t.Error("Volume replication not bootstrapped.")
+	}
+
+	sourceBackend := orchestrator.backends[source]
+	sourceDriver := sourceBackend.Driver.(*backend_fake.FakeStorageDriver)
+	sourceInternalName := orchestrator.volumes[volName].Config.InternalName
+	vol, err = orchestrator.FailoverVolume(volName)
+	if err != nil {
+		t.Fatal("Unable to fail volume over:  ", err)
+	}
+	if vol.Backend != peer || vol.Pool != "primary" ||
+		vol.Replication != nil {
+		t.Errorf("Volume on backend %s, pool %s, with replication %v "+
+			"after failover.", vol.Backend, vol.Pool, vol.Replication)
+	}
+	if !mirror.Broken {
+		t.Error("Mirror not broken by failover.")
+	}
+	if sourceDriver.Get(sourceInternalName) != nil {
+		t.Error("Former source removed by failover.")
+	}
+
+	// Disabling replication removes the mirror.
+	vol, err = orchestrator.EnableReplication(otherVolume,
+		&ReplicationConfig{PeerBackend: peer, PeerPool: "primary"})
+	if err != nil {
+		t.Fatal("Unable to enable replication:  ", err)
+	}
+	mirrorName := vol.Replication.PeerInternalName
+	if vol, err = orchestrator.DisableReplication(otherVolume); err != nil {
+		t.Fatal("Unable to disable replication:  ", err)
+	}
+	if vol.Replication != nil {
+		t.Error("Replication not disabled.")
+	}
+	if peerDriver.Get(mirrorName) == nil {
+		t.Error("Mirror not destroyed.")
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestBackendFailover(t *testing.T) {
+	const (
+		scName       = "failoverSC"
+		orphanSCName = "failoverOrphanSC"
+		source       = "failoverSource"
+		orphanSource = "failoverOrphanSource"
+		peer         = "failoverPeer"
+		replicated   = "failoverReplicated"
+		broken       = "failoverBroken"
+		unreplicated = "failoverUnreplicated"
+		orphaned     = "failoverOrphaned"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, source, scName)
+	for _, name := range []string{replicated, broken, unreplicated} {
+		if _, err := orchestrator.AddVolume(testCtx,
+			generateVolumeConfig(name, 1, scName, config.File)); err != nil {
+			t.Fatal("Unable to add volume:  ", err)
+		}
+	}
+	addBackend(t, orchestrator, peer)
+	peerBackend := orchestrator.backends[peer]
+	peerDriver := peerBackend.Driver.(*backend_fake.FakeStorageDriver)
+	for _, name := range []string{replicated, broken} {
+		if _, err := orchestrator.EnableReplication(name,
+			&ReplicationConfig{PeerBackend: peer}); err != nil {
+			t.Fatal("Unable to enable replication:  ", err)
+		}
+	}
+	// A relationship lost along with the source can't be broken.
+	delete(peerDriver.Mirrors,
+		orchestrator.volumes[broken].Replication.PeerInternalName)
+	if _, err := orchestrator.AttachVolume(replicated, "node1",
+		false); err != nil {
+		t.Fatal("Unable to attach volume:  ", err)
+	}
+
+	if _, err := orchestrator.FailoverBackend(
+		"failoverMissing"); errors.GetType(err) != errors.NotFound {
+		t.Error("Expected NotFound error for a missing backend, got ", err)
+	}
+	report, err := orchestrator.FailoverBackend(source)
+	if err != nil {
+		t.Fatal("Unable to fail backend over:  ", err)
+	}
+	if len(report.FailedOver) != 1 ||
+		report.FailedOver[0].Volume != replicated ||
+		report.FailedOver[0].PeerBackend != peer {
+		t.Errorf("Wrong volumes failed over:  %v", report.FailedOver)
+	}
+	if len(report.Failed) != 2 || report.Failed[0].Volume != broken ||
+		report.Failed[0].Error == "" ||
+		report.Failed[1].Volume != unreplicated {
+		t.Errorf("Wrong volumes reported as failed:  %v", report.Failed)
+	}
+	if !report.Offline || orchestrator.backends[source].Online {
+		t.Error("Failed backend not offlined.")
+	}
+	vol := orchestrator.GetVolume(replicated)
+	if vol.Backend != peer || vol.Replication != nil ||
+		len(vol.Attachments) != 1 {
+		t.Errorf("Volume on backend %s with replication %v and "+
+			"attachments %v after failover.", vol.Backend, vol.Replication,
+			vol.Attachments)
+	}
+	if vol = orchestrator.GetVolume(broken); vol.Backend != source ||
+		vol.Replication == nil {
+		t.Error("Volume that could not be failed over was changed.")
+	}
+
+	// Volumes of a backend that doesn't initialize are failed over too.
+	addBackend(t, orchestrator, orphanSource)
+	if _, err = orchestrator.AddStorageClass(&storage_class.Config{
+		Name: orphanSCName,
+		BackendStoragePools: map[string][]string{
+			orphanSource: {"primary"},
+		},
+	}); err != nil {
+		t.Fatal("Unable to add storage class:  ", err)
+	}
+	if _, err = orchestrator.AddVolume(testCtx, generateVolumeConfig(orphaned,
+		1, orphanSCName, config.File)); err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+	vol, err = orchestrator.EnableReplication(orphaned,
+		&ReplicationConfig{PeerBackend: peer, PeerPool: "primary"})
+	if err != nil {
+		t.Fatal("Unable to enable replication:  ", err)
+	}
+	mirrorName := vol.Replication.PeerInternalName
+	fake.SetUnreachable(orphanSource, true)
+	defer fake.SetUnreachable(orphanSource, false)
+	deferred := NewTridentOrchestrator(orchestrator.storeClient)
+	deferred.SetDeferredBackendInit(true)
+	if err = deferred.Bootstrap(); err != nil {
+		t.Fatal("Deferred bootstrap failed:  ", err)
+	}
+	// The fake driver's mirrors don't survive bootstrapping.
+	peerBackend = deferred.backends[peer]
+	peerDriver = peerBackend.Driver.(*backend_fake.FakeStorageDriver)
+	peerDriver.Mirrors[mirrorName] = &fake.FakeMirror{}
+	report, err = deferred.FailoverBackend(orphanSource)
+	if err != nil {
+		t.Fatal("Unable to fail backend over:  ", err)
+	}
+	if len(report.FailedOver) != 1 || len(report.Failed) != 0 ||
+		report.Offline {
+		t.Errorf("Wrong report for failed backend:  %v", report)
+	}
+	vol = deferred.GetVolume(orphaned)
+	if vol == nil || vol.Orphaned || vol.Backend != peer ||
+		vol.Config.InternalName != mirrorName {
+		t.Errorf("Orphaned volume not failed over:  %v", vol)
+	}
+	cleanup(t, deferred)
+}
+
+func TestVolumeUsage(t *testing.T) {
+	const (
+		backendName = "usageBackend"
+		scName      = "usageSC"
+		volName     = "usageVolume"
+		deletedName = "usageDeletedVolume"
+	)
+	orchestrator := getOrchestrator()
+	orchestrator.SetDeletionGracePeriod(time.Hour)
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	before := time.Now().UTC()
+	for _, name := range []string{volName, deletedName} {
+		volConfig := generateVolumeConfig(name, 1, scName, config.File)
+		volConfig.Metadata = map[string]string{"tenant": "web"}
+		if _, err := orchestrator.AddVolume(testCtx, volConfig); err != nil {
+			t.Fatal("Unable to add volume:  ", err)
+		}
+	}
+	if _, err := orchestrator.DeleteVolume(testCtx, deletedName); err != nil {
+		t.Fatal("Unable to delete volume:  ", err)
+	}
+	vol := orchestrator.volumes[volName]
+	driver := vol.Backend.Driver.(*backend_fake.FakeStorageDriver)
+	driver.UsedBytes[vol.Config.InternalName] = 1024
+
+	usage := orchestrator.ListVolumeUsage()
+	if len(usage) != 1 {
+		t.Fatalf("Expected usage of 1 volume; got %d.", len(usage))
+	}
+	u := usage[0]
+	if u.Volume != volName || u.Backend != backendName ||
+		u.StorageClass != scName || u.Metadata["tenant"] != "web" ||
+		u.ProvisionedBytes != 1024*1024*1024 {
+		t.Errorf("Wrong volume usage %v.", u)
+	}
+	if u.ConsumedBytes == nil || *u.ConsumedBytes != 1024 {
+		t.Errorf("Wrong consumed bytes %v.", u.ConsumedBytes)
+	}
+	if u.CreatedAt.Before(before) {
+		t.Errorf("Creation time %v not recorded.", u.CreatedAt)
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestBackendLimits(t *testing.T) {
+	const (
+		backendName = "limitBackend"
+		scName      = "limitSC"
+	)
+	configJSON, err := fake.NewFakeStorageDriverConfigJSON(
+		backendName,
+		config.File,
+		map[string]*fake.FakeStoragePool{
+			"primary": &fake.FakeStoragePool{
+				Attrs: map[string]sa.Offer{
+					sa.Media:            sa.NewStringOffer("hdd"),
+					sa.ProvisioningType: sa.NewStringOffer("thick", "thin"),
+					sa.TestingAttribute: sa.NewBoolOffer(true),
+				},
+				Bytes: 100 * 1024 * 1024 * 1024,
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal("Unable to create mock driver config JSON: ", err)
+	}
+	var configMap map[string]interface{}
+	if err = json.Unmarshal([]byte(configJSON), &configMap); err != nil {
+		t.Fatal("Unable to unmarshal mock driver config JSON:  ", err)
+	}
+	configMap["limitVolumeSize"] = fmt.Sprintf("%d", 2*1024*1024*1024)
+	configMap["limitAggregateUsage"] = fmt.Sprintf("%d", 3*1024*1024*1024)
+	configMap["maxVolumes"] = 2
+	limitedJSON, err := json.Marshal(configMap)
+	if err != nil {
+		t.Fatal("Unable to marshal limited config JSON:  ", err)
+	}
+
+	orchestrator := getOrchestrator()
+	configMap["maxVolumes"] = -1
+	invalidJSON, _ := json.Marshal(configMap)
+	_, err = orchestrator.AddStorageBackend(testCtx, string(invalidJSON))
+	if err == nil {
+		t.Error("Backend with negative maxVolumes was added.")
+	}
+	backend, err := orchestrator.AddStorageBackend(testCtx, string(limitedJSON))
+	if err != nil {
+		t.Fatal("Unable to add backend:  ", err)
+	}
+	if backend.Limits == nil || backend.Limits.MaxVolumes != 2 {
+		t.Errorf("Backend limits not reported; got %v.", backend.Limits)
+	}
+	if _, err = orchestrator.AddStorageClass(&storage_class.Config{
+		Name: scName,
+		Attributes: map[string]sa.Request{
+			sa.TestingAttribute: sa.NewBoolRequest(true),
+		},
+	}); err != nil {
+		t.Fatal("Unable to add storage class:  ", err)
+	}
+
+	for _, test := range []struct {
+		name     string
+		gb       int
+		expected bool
+	}{
+		{"too-large", 3, false},
+		{"first", 2, true},
+		{"over-usage", 2, false},
+		{"second", 1, true},
+		{"over-count", 1, false},
+	} {
+		_, err = orchestrator.AddVolume(testCtx,
+			generateVolumeConfig(test.name, test.gb, scName, config.File))
+		if test.expected && err != nil {
+			t.Errorf("%s:  unable to add volume:  %v", test.name, err)
+		} else if !test.expected && err == nil {
+			t.Errorf("%s:  volume exceeding backend limits was added.",
+				test.name)
+		}
+	}
+
+	// Limits must survive bootstrapping.
+	newOrchestrator := getOrchestrator()
+	if b := newOrchestrator.GetBackend(backendName); b == nil ||
+		b.Limits == nil || b.Limits.MaxVolumes != 2 {
+		t.Error("Backend limits not bootstrapped.")
+	}
+
+	for _, name := range []string{"first", "second"} {
+		if _, err = orchestrator.DeleteVolume(testCtx, name); err != nil {
+			t.Errorf("Unable to delete volume %s:  %v", name, err)
+		}
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestVirtualPools(t *testing.T) {
+	const backendName = "virtualBackend"
+	configJSON, err := fake.NewFakeStorageDriverConfigJSON(
+		backendName,
+		config.File,
+		map[string]*fake.FakeStoragePool{
+			"primary": &fake.FakeStoragePool{
+				Attrs: map[string]sa.Offer{
+					sa.Media:            sa.NewStringOffer("hdd"),
+					sa.ProvisioningType: sa.NewStringOffer("thick", "thin"),
+				},
+				Bytes: 100 * 1024 * 1024 * 1024,
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal("Unable to create mock driver config JSON: ", err)
+	}
+	var configMap map[string]interface{}
+	if err = json.Unmarshal([]byte(configJSON), &configMap); err != nil {
+		t.Fatal("Unable to unmarshal mock driver config JSON:  ", err)
+	}
+	configMap["virtualPools"] = map[string]*storage.VirtualPool{
+		"gold": &storage.VirtualPool{
+			Attributes: map[string]string{sa.ProvisioningType: "thick"},
+			Labels:     map[string]string{"tier": "gold"},
+			Defaults:   storage.VirtualPoolDefaults{SnapshotDir: "true"},
+		},
+		"bronze": &storage.VirtualPool{
+			Pool:       "primary",
+			Attributes: map[string]string{sa.ProvisioningType: "thin"},
+			Labels:     map[string]string{"tier": "bronze"},
+		},
+	}
+	virtualJSON, err := json.Marshal(configMap)
+	if err != nil {
+		t.Fatal("Unable to marshal virtual pool config JSON:  ", err)
+	}
+
+	orchestrator := getOrchestrator()
+	backend, err := orchestrator.AddStorageBackend(testCtx, string(virtualJSON))
+	if err != nil {
+		t.Fatal("Unable to add backend:  ", err)
+	}
+	if len(backend.Storage) != 2 || backend.Storage["gold"] == nil ||
+		backend.Storage["gold"].PhysicalPool != "primary" {
+		t.Errorf("Virtual pools not reported; got %v.", backend.Storage)
+	}
+	for _, scConfig := range []*storage_class.Config{
+		{
+			Name: "thick",
+			Attributes: map[string]sa.Request{
+				sa.ProvisioningType: sa.NewStringRequest("thick"),
+			},
+		},
+		{Name: "bronze", Selector: map[string]string{"tier": "bronze"}},
+	} {
+		if _, err = orchestrator.AddStorageClass(scConfig); err != nil {
+			t.Fatalf("Unable to add storage class %s:  %v", scConfig.Name,
+				err)
+		}
+	}
+
+	for _, test := range []struct {
+		volume, storageClass, pool, snapshotDir string
+	}{
+		{"goldVol", "thick", "gold", "true"},
+		{"bronzeVol", "bronze", "bronze", ""},
+	} {
+		if _, err = orchestrator.AddVolume(testCtx, generateVolumeConfig(test.volume,
+			1, test.storageClass, config.File)); err != nil {
+			t.Errorf("Unable to add volume %s:  %v", test.volume, err)
+			continue
+		}
+		vol := orchestrator.volumes[test.volume]
+		if vol.Pool.Name != test.pool {
+			t.Errorf("%s:  expected pool %s; got %s.", test.volume, test.pool,
+				vol.Pool.Name)
+		}
+		if vol.Config.SnapshotDir != test.snapshotDir {
+			t.Errorf("%s:  expected snapshot directory %q; got %q.",
+				test.volume, test.snapshotDir, vol.Config.SnapshotDir)
+		}
+		driver := vol.Backend.Driver.(*backend_fake.FakeStorageDriver)
+		if p := driver.Volumes[vol.Config.InternalName]; p != "primary" {
+			t.Errorf("%s:  expected physical pool primary; got %s.",
+				test.volume, p)
+		}
+	}
+
+	// Virtual pools must survive bootstrapping.
+	newOrchestrator := getOrchestrator()
+	if vol, ok := newOrchestrator.volumes["goldVol"]; !ok ||
+		vol.Pool.Name != "gold" {
+		t.Error("Volume in virtual pool not bootstrapped.")
+	}
+
+	for _, name := range []string{"goldVol", "bronzeVol"} {
+		if _, err = orchestrator.DeleteVolume(testCtx, name); err != nil {
+			t.Errorf("Unable to delete volume %s:  %v", name, err)
+		}
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestVolumeAccess(t *testing.T) {
+	const (
+		backendName = "accessBackend"
+		scName      = "accessSC"
+		volName     = "accessVol"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+
+	volConfig := generateVolumeConfig(volName, 1, scName, config.File)
+	volConfig.Access = &storage.VolumeAccess{
+		NfsClients: []string{"10.0.0.0/24"},
+	}
+	if _, err := orchestrator.AddVolume(testCtx, volConfig); err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+	vol := orchestrator.volumes[volName]
+	driver := vol.Backend.Driver.(*backend_fake.FakeStorageDriver)
+	if access := driver.VolumeAccess[vol.Config.InternalName]; !reflect.DeepEqual(
+		access.NfsClients, []string{"10.0.0.0/24"}) {
+		t.Errorf("Access not applied at creation; got %v.", access)
+	}
+
+	if _, err := orchestrator.SetVolumeAccess(volName, &storage.VolumeAccess{
+		IscsiInitiators: []string{"not-an-iqn"},
+	}); err == nil {
+		t.Error("Invalid initiator name was accepted.")
+	}
+	if _, err := orchestrator.SetVolumeAccess("nonexistent",
+		&storage.VolumeAccess{}); err == nil {
+		t.Error("Access was set for a nonexistent volume.")
+	}
+	newAccess := &storage.VolumeAccess{
+		NfsClients: []string{"10.0.1.5", "host.example.com"},
+	}
+	external, err := orchestrator.SetVolumeAccess(volName, newAccess)
+	if err != nil {
+		t.Fatal("Unable to set volume access:  ", err)
+	}
+	if !reflect.DeepEqual(external.Config.Access, newAccess) {
+		t.Errorf("Expected access %v; got %v.", newAccess,
+			external.Config.Access)
+	}
+	if access := driver.VolumeAccess[vol.Config.InternalName]; !reflect.DeepEqual(
+		&access, newAccess) {
+		t.Errorf("Access not pushed to the backend; got %v.", access)
+	}
+	persistentVol, err := orchestrator.storeClient.GetVolume(volName)
+	if err != nil {
+		t.Fatal("Unable to get volume from the store:  ", err)
+	}
+	if !reflect.DeepEqual(persistentVol.Config.Access, newAccess) {
+		t.Errorf("Access not persisted; got %v.", persistentVol.Config.Access)
+	}
+
+	if _, err = orchestrator.DeleteVolume(testCtx, volName); err != nil {
+		t.Error("Unable to delete volume:  ", err)
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestVolumeAttachments(t *testing.T) {
+	const (
+		backendName = "attachBackend"
+		scName      = "attachSC"
+		rwoName     = "rwoVol"
+		rwxName     = "rwxVol"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	for name, mode := range map[string]config.AccessMode{
+		rwoName: config.ReadWriteOnce,
+		rwxName: config.ReadWriteMany,
+	} {
+		volConfig := generateVolumeConfig(name, 1, scName, config.File)
+		volConfig.AccessMode = mode
+		if _, err := orchestrator.AddVolume(testCtx, volConfig); err != nil {
+			t.Fatalf("Unable to add volume %s:  %v", name, err)
+		}
+	}
+
+	for _, test := range []struct {
+		volume, node string
+		expected     bool
+	}{
+		{rwoName, "node1", true},
+		{rwoName, "node1", true},
+		{rwoName, "node2", false},
+		{rwxName, "node1", true},
+		{rwxName, "node2", true},
+		{rwxName, "", false},
+		{"nonexistent", "node1", false},
+	} {
+		_, err := orchestrator.AttachVolume(test.volume, test.node, false)
+		if test.expected && err != nil {
+			t.Errorf("Unable to attach %s to %q:  %v", test.volume,
+				test.node, err)
+		} else if !test.expected && err == nil {
+			t.Errorf("Attached %s to %q.", test.volume, test.node)
+		}
+	}
+	if external := orchestrator.GetVolume(rwxName); len(
+		external.Attachments) != 2 {
+		t.Errorf("Expected 2 attachments; got %v.", external.Attachments)
+	}
+
+	// Attachments must survive bootstrapping.
+	newOrchestrator := getOrchestrator()
+	if vol, ok := newOrchestrator.volumes[rwoName]; !ok ||
+		vol.GetAttachment("node1") == nil {
+		t.Error("Volume attachment not bootstrapped.")
+	}
+
+	if _, err := orchestrator.DeleteVolume(testCtx, rwoName); err == nil {
+		t.Error("Attached volume was deleted.")
+	}
+	if _, err := orchestrator.DetachVolume(rwoName, "node1"); err != nil {
+		t.Error("Unable to detach volume:  ", err)
+	}
+	if _, err := orchestrator.DeleteVolume(testCtx, rwoName); err != nil {
+		t.Error("Unable to delete detached volume:  ", err)
+	}
+	if _, err := orchestrator.ForceDeleteVolume(testCtx, rwxName); err != nil {
+		t.Error("Unable to force deletion of attached volume:  ", err)
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestVolumeFSType(t *testing.T) {
+	const (
+		backendName = "fsTypeBackend"
+		scName      = "fsTypeSC"
+		volName     = "fsTypeVol"
+	)
+	orchestrator := getOrchestrator()
+	configJSON, err := fake.NewFakeStorageDriverConfigJSON(
+		backendName,
+		config.Block,
+		map[string]*fake.FakeStoragePool{
+			"primary": &fake.FakeStoragePool{
+				Attrs: map[string]sa.Offer{
+					sa.Media:            sa.NewStringOffer("hdd"),
+					sa.ProvisioningType: sa.NewStringOffer("thick"),
+					sa.TestingAttribute: sa.NewBoolOffer(true),
+				},
+				Bytes: 100 * 1024 * 1024 * 1024,
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal("Unable to create mock driver config JSON: ", err)
+	}
+	if _, err = orchestrator.AddStorageBackend(testCtx, configJSON); err != nil {
+		t.Fatal("Unable to add backend:  ", err)
+	}
+	if _, err = orchestrator.AddStorageClass(&storage_class.Config{
+		Name: scName,
+		Attributes: map[string]sa.Request{
+			sa.Media:            sa.NewStringRequest("hdd"),
+			sa.TestingAttribute: sa.NewBoolRequest(true),
+		},
+	}); err != nil {
+		t.Fatal("Unable to add storage class:  ", err)
+	}
+
+	volConfig := generateVolumeConfig(volName, 1, scName, config.Block)
+	volConfig.FSType = "btrfs"
+	if _, err = orchestrator.AddVolume(testCtx, volConfig); err == nil {
+		t.Error("Volume with an unsupported fsType was created.")
+	}
+	volConfig.FSType = ""
+	external, err := orchestrator.AddVolume(testCtx, volConfig)
+	if err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+	if external.Config.FSType != config.DefaultFSType {
+		t.Errorf("Expected fsType %s; got %s.", config.DefaultFSType,
+			external.Config.FSType)
+	}
+
+	if _, err = orchestrator.SetVolumeFormatted(volName, "xfs"); err == nil {
+		t.Error("Volume fsType was changed.")
+	}
+	if _, err = orchestrator.SetVolumeFormatted(volName,
+		config.DefaultFSType); err != nil {
+		t.Fatal("Unable to record volume as formatted:  ", err)
+	}
+	persistentVol, err := orchestrator.storeClient.GetVolume(volName)
+	if err != nil {
+		t.Fatal("Unable to get volume from the store:  ", err)
+	}
+	if !persistentVol.Config.Formatted {
+		t.Error("Formatted state was not persisted.")
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestRenameVolume(t *testing.T) {
+	const (
+		backendName = "renameBackend"
+		scName      = "renameSC"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	for _, name := range []string{"oldVol", "otherVol", "txnVol"} {
+		_, err := orchestrator.AddVolume(testCtx,
+			generateVolumeConfig(name, 1, scName, config.File))
+		if err != nil {
+			t.Fatalf("Unable to add volume %s:  %v", name, err)
+		}
+	}
+	internalName := orchestrator.volumes["oldVol"].Config.InternalName
+
+	if _, err := orchestrator.RenameVolume("oldVol", "otherVol"); err == nil {
+		t.Error("Volume was renamed to an existing volume's name.")
+	}
+	if _, err := orchestrator.RenameVolume("nonexistent", "newVol"); err == nil {
+		t.Error("Nonexistent volume was renamed.")
+	}
+	external, err := orchestrator.RenameVolume("oldVol", "newVol")
+	if err != nil {
+		t.Fatal("Unable to rename volume:  ", err)
+	}
+	if external.Config.Name != "newVol" ||
+		external.Config.InternalName != internalName {
+		t.Errorf("Unexpected config for renamed volume:  %v", external.Config)
+	}
+	if orchestrator.GetVolume("oldVol") != nil {
+		t.Error("Volume is still present under its old name.")
+	}
+	vol := orchestrator.volumes["newVol"]
+	if _, ok := vol.Pool.Volumes["newVol"]; !ok {
+		t.Error("Renamed volume not found in its pool.")
+	}
+	if _, err = orchestrator.storeClient.GetVolume("oldVol"); err == nil {
+		t.Error("Old volume record is still in the store.")
+	}
+	if _, err = orchestrator.storeClient.GetVolume("newVol"); err != nil {
+		t.Error("Unable to get renamed volume from the store:  ", err)
+	}
+
+	// Simulate a rename interrupted after the new record was stored; it
+	// should be finished on bootstrap.
+	txnVol := orchestrator.volumes["txnVol"]
+	newConfig := *txnVol.Config
+	newConfig.Name = "txnVolRenamed"
+	if err = orchestrator.storeClient.AddVolumeTransaction(
+		&persistent_store.VolumeTransaction{
+			Config:  &newConfig,
+			Op:      persistent_store.RenameVolume,
+			OldName: "txnVol",
+		}); err != nil {
+		t.Fatal("Unable to add volume transaction:  ", err)
+	}
+	if err = orchestrator.storeClient.AddVolume(storage.NewVolume(&newConfig,
+		txnVol.Backend, txnVol.Pool)); err != nil {
+		t.Fatal("Unable to add renamed volume to the store:  ", err)
+	}
+	newOrchestrator := getOrchestrator()
+	if _, ok := newOrchestrator.volumes["txnVol"]; ok {
+		t.Error("Interrupted rename left the old volume in place.")
+	}
+	if _, ok := newOrchestrator.volumes["txnVolRenamed"]; !ok {
+		t.Error("Interrupted rename was not finished.")
+	}
+	if txns, err := newOrchestrator.storeClient.GetVolumeTransactions(); err == nil &&
+		len(txns) != 0 {
+		t.Errorf("Expected no volume transactions; got %d.", len(txns))
+	}
+	cleanup(t, newOrchestrator)
+}
+
+func TestRenameBackend(t *testing.T) {
+	const (
+		backendName = "renameFromBackend"
+		otherName   = "renameOtherBackend"
+		scName      = "renameBackendSC"
+		volName     = "renameBackendVol"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	addBackend(t, orchestrator, otherName)
+	if _, err := orchestrator.AddVolume(testCtx,
+		generateVolumeConfig(volName, 1, scName, config.File)); err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+
+	for _, test := range []struct {
+		oldName, newName string
+		errType          errors.Type
+	}{
+		{backendName, "", errors.InvalidInput},
+		{"nonexistent", "renameToBackend", errors.NotFound},
+		{backendName, otherName, errors.AlreadyExists},
+	} {
+		_, err := orchestrator.RenameBackend(test.oldName, test.newName)
+		if errors.GetType(err) != test.errType {
+			t.Errorf("Renaming %s to %q:  expected a %s error; got %v.",
+				test.oldName, test.newName, test.errType, err)
+		}
+	}
+
+	external, err := orchestrator.RenameBackend(backendName, "renameToBackend")
+	if err != nil {
+		t.Fatal("Unable to rename backend:  ", err)
+	}
+	if external.Name != "renameToBackend" {
+		t.Errorf("Expected backend renameToBackend; got %s.", external.Name)
+	}
+	if orchestrator.GetBackend(backendName) != nil {
+		t.Error("Backend is still present under its old name.")
+	}
+	if vol := orchestrator.GetVolume(volName); vol == nil ||
+		vol.Backend != "renameToBackend" {
+		t.Errorf("Volume doesn't name the renamed backend:  %v", vol)
+	}
+	if sc := orchestrator.GetStorageClass(scName); sc == nil ||
+		len(sc.StoragePools["renameToBackend"]) == 0 {
+		t.Errorf("Storage class doesn't include the renamed backend:  %v", sc)
+	}
+	if _, err = orchestrator.storeClient.GetBackend(backendName); err == nil {
+		t.Error("Old backend record is still in the store.")
+	}
+	if vol, err := orchestrator.storeClient.GetVolume(volName); err != nil ||
+		vol.Backend != "renameToBackend" {
+		t.Errorf("Stored volume doesn't name the renamed backend:  %v, %v",
+			vol, err)
+	}
+
+	// The new name should survive bootstrapping, even though the driver
+	// derives the old one from the config.
+	newOrchestrator := getOrchestrator()
+	if newOrchestrator.GetBackend("renameToBackend") == nil {
+		t.Error("Renamed backend not found after bootstrapping.")
+	}
+	if _, ok := newOrchestrator.volumes[volName]; !ok {
+		t.Error("Volume not loaded with the renamed backend.")
+	}
+
+	// Simulate a rename interrupted after the backend was stored under its
+	// new name; it should be finished on bootstrap.
+	backend := newOrchestrator.backends[otherName]
+	if err = newOrchestrator.storeClient.AddBackendTransaction(
+		&persistent_store.BackendTransaction{
+			Op:      persistent_store.RenameBackend,
+			Name:    "renamedOtherBackend",
+			OldName: otherName,
+		}); err != nil {
+		t.Fatal("Unable to add backend transaction:  ", err)
+	}
+	backend.Name = "renamedOtherBackend"
+	if err = newOrchestrator.storeClient.AddBackend(backend); err != nil {
+		t.Fatal("Unable to add renamed backend to the store:  ", err)
+	}
+	newOrchestrator = getOrchestrator()
+	if newOrchestrator.GetBackend(otherName) != nil {
+		t.Error("Interrupted rename left the old backend in place.")
+	}
+	if newOrchestrator.GetBackend("renamedOtherBackend") == nil {
+		t.Error("Interrupted rename was not finished.")
+	}
+	if txns, err := newOrchestrator.storeClient.GetBackendTransactions(); err == nil &&
+		len(txns) != 0 {
+		t.Errorf("Expected no backend transactions; got %d.", len(txns))
+	}
+	cleanup(t, newOrchestrator)
+}
+
+type testResolver map[string]string
+
+func (r testResolver) Resolve(name string) (map[string]string, error) {
+	return r, nil
+}
+
+func TestBackendCredentials(t *testing.T) {
+	const backendName = "credentialsBackend"
+	credentials.Register("test", testResolver{"InstanceName": backendName})
+	orchestrator := getOrchestrator()
+	configJSON, err := fake.NewFakeStorageDriverConfigJSON("", config.File,
+		map[string]*fake.FakeStoragePool{
+			"primary": &fake.FakeStoragePool{
+				Attrs: map[string]sa.Offer{
+					sa.Media: sa.NewStringOffer("hdd"),
+				},
+				Bytes: 100 * 1024 * 1024 * 1024,
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal("Unable to generate config JSON:  ", err)
+	}
+	var configMap map[string]interface{}
+	if err = json.Unmarshal([]byte(configJSON), &configMap); err != nil {
+		t.Fatal("Unable to parse config JSON:  ", err)
+	}
+	configMap["credentials"] = map[string]string{"type": "test", "name": "x"}
+	configBytes, _ := json.Marshal(configMap)
+
+	external, err := orchestrator.AddStorageBackend(testCtx,
+		string(configBytes))
+	if err != nil {
+		t.Fatal("Unable to add backend:  ", err)
+	}
+	if external.Name != backendName {
+		t.Errorf("Expected backend %s; got %s.", backendName, external.Name)
+	}
+	externalConfig := external.Config.(*fake.FakeStorageDriverConfig)
+	if externalConfig.InstanceName != credentials.Redacted {
+		t.Errorf("Resolved credentials not redacted:  %s",
+			externalConfig.InstanceName)
+	}
+	if external.Credentials == nil || external.Credentials.Type != "test" {
+		t.Errorf("Unexpected credentials reference:  %v",
+			external.Credentials)
+	}
+	persistent, err := orchestrator.storeClient.GetBackend(backendName)
+	if err != nil {
+		t.Fatal("Unable to get backend from the store:  ", err)
+	}
+	if persistent.Config.FakeStorageDriverConfig.InstanceName != "" {
+		t.Error("Resolved credentials were stored.")
+	}
+	driver := orchestrator.backends[backendName].Driver
+	instanceName := driver.(*backend_fake.FakeStorageDriver).Config.InstanceName
+	if instanceName != backendName {
+		t.Errorf("Driver has unexpected instance name %s.", instanceName)
+	}
+
+	// The credentials should be resolved again on bootstrap.
+	newOrchestrator := getOrchestrator()
+	if newOrchestrator.GetBackend(backendName) == nil {
+		t.Error("Backend with credentials not found after bootstrapping.")
+	}
+	cleanup(t, newOrchestrator)
+}
+
+func TestBackendChap(t *testing.T) {
+	const (
+		backendName = "chapBackend"
+		scName      = "chapSC"
+		volumeName  = "chapVolume"
+	)
+	orchestrator := getOrchestrator()
+	chapConfigJSON := func(protocol config.Protocol) string {
+		configJSON, err := fake.NewFakeStorageDriverConfigJSON(backendName,
+			protocol, map[string]*fake.FakeStoragePool{
+				"primary": &fake.FakeStoragePool{
+					Attrs: map[string]sa.Offer{
+						sa.Media: sa.NewStringOffer("hdd"),
+					},
+					Bytes: 100 * 1024 * 1024 * 1024,
+				},
+			},
+		)
+		if err != nil {
+			t.Fatal("Unable to generate config JSON:  ", err)
+		}
+		var configMap map[string]interface{}
+		if err = json.Unmarshal([]byte(configJSON), &configMap); err != nil {
+			t.Fatal("Unable to parse config JSON:  ", err)
+		}
+		configMap["useCHAP"] = true
+		configMap["chapUsername"] = "host"
+		configBytes, _ := json.Marshal(configMap)
+		return string(configBytes)
+	}
+
+	if _, err := orchestrator.AddStorageBackend(testCtx,
+		chapConfigJSON(config.File)); err == nil {
+		t.Error("File backend using CHAP was added.")
+	}
+	external, err := orchestrator.AddStorageBackend(testCtx,
+		chapConfigJSON(config.Block))
+	if err != nil {
+		t.Fatal("Unable to add backend:  ", err)
+	}
+	if external.Chap == nil || external.Chap.ChapUsername != "host" ||
+		external.Chap.ChapInitiatorSecret != credentials.Redacted {
+		t.Errorf("Unexpected CHAP settings reported:  %v", external.Chap)
+	}
+	driver := orchestrator.backends[backendName].Driver
+	fakeDriver := driver.(*backend_fake.FakeStorageDriver)
+	if fakeDriver.Chap == nil {
+		t.Fatal("CHAP credentials not set on the driver.")
+	}
+	chap := *fakeDriver.Chap
+	if len(chap.ChapInitiatorSecret) != 16 ||
+		len(chap.ChapTargetInitiatorSecret) != 16 {
+		t.Errorf("Unexpected generated secrets:  %s, %s",
+			chap.ChapInitiatorSecret, chap.ChapTargetInitiatorSecret)
+	}
+
+	// Updating the backend should keep the generated secrets.
+	if _, err = orchestrator.AddStorageBackend(testCtx,
+		chapConfigJSON(config.Block)); err != nil {
+		t.Fatal("Unable to update backend:  ", err)
+	}
+	if orchestrator.backends[backendName].Chap != chap {
+		t.Error("Updating the backend changed its CHAP credentials.")
+	}
+
+	if _, err = orchestrator.AddStorageClass(&storage_class.Config{
+		Name:        scName,
+		RequireCHAP: true,
+	}); err != nil {
+		t.Fatal("Unable to add storage class:  ", err)
+	}
+	vol, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(
+		volumeName, 1, scName, config.Block))
+	if err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+	if vol.Chap == nil ||
+		vol.Chap.ChapTargetInitiatorSecret != credentials.Redacted {
+		t.Errorf("Unexpected volume CHAP settings:  %v", vol.Chap)
+	}
+	volChap, err := orchestrator.GetVolumeChap(volumeName)
+	if err != nil {
+		t.Fatal("Unable to get volume CHAP credentials:  ", err)
+	}
+	if *volChap != chap {
+		t.Errorf("Expected CHAP credentials %v; got %v.", chap, *volChap)
+	}
+
+	// The stored credentials should be reused on bootstrap.
+	newOrchestrator := getOrchestrator()
+	backend, ok := newOrchestrator.backends[backendName]
+	if !ok {
+		t.Fatal("Backend using CHAP not found after bootstrapping.")
+	}
+	if backend.Chap != chap {
+		t.Error("Bootstrapping changed the backend's CHAP credentials.")
+	}
+	cleanup(t, newOrchestrator)
+}
+
+func TestCredentialRotation(t *testing.T) {
+	const backendName = "rotationBackend"
+	orchestrator := getOrchestrator()
+	rotationConfigJSON := func(interval string) string {
+		configJSON, err := fake.NewFakeStorageDriverConfigJSON(backendName,
+			config.File, map[string]*fake.FakeStoragePool{
+				"primary": &fake.FakeStoragePool{
+					Attrs: map[string]sa.Offer{
+						sa.Media: sa.NewStringOffer("hdd"),
+					},
+					Bytes: 100 * 1024 * 1024 * 1024,
+				},
+			},
+		)
+		if err != nil {
+			t.Fatal("Unable to generate config JSON:  ", err)
+		}
+		var configMap map[string]interface{}
+		if err = json.Unmarshal([]byte(configJSON), &configMap); err != nil {
+			t.Fatal("Unable to parse config JSON:  ", err)
+		}
+		configMap["Password"] = "initial"
+		configMap["credentialRotation"] = map[string]string{
+			"interval": interval,
+		}
+		configBytes, _ := json.Marshal(configMap)
+		return string(configBytes)
+	}
+
+	if _, err := orchestrator.AddStorageBackend(testCtx,
+		rotationConfigJSON("1m")); err == nil {
+		t.Error("Backend rotating more often than hourly was added.")
+	}
+	external, err := orchestrator.AddStorageBackend(testCtx,
+		rotationConfigJSON("1h"))
+	if err != nil {
+		t.Fatal("Unable to add backend:  ", err)
+	}
+	if external.CredentialRotation == nil ||
+		external.CredentialRotation.LastRotated.IsZero() {
+		t.Errorf("Unexpected rotation settings reported:  %v",
+			external.CredentialRotation)
+	}
+	backend := orchestrator.backends[backendName]
+	fakeDriver := backend.Driver.(*backend_fake.FakeStorageDriver)
+
+	now := time.Now()
+	if rotated := orchestrator.rotateDueCredentials(now); rotated != 0 {
+		t.Errorf("Rotated %d passwords before they were due.", rotated)
+	}
+	if fakeDriver.GetPassword() != "initial" {
+		t.Error("Password changed before it was due.")
+	}
+
+	// A password that fails verification should be rolled back.
+	later := now.Add(2 * time.Hour)
+	fakeDriver.CredentialCheckError = fmt.Errorf("Login failed.")
+	if rotated := orchestrator.rotateDueCredentials(later); rotated != 0 {
+		t.Errorf("Rotated %d passwords that failed verification.", rotated)
+	}
+	if fakeDriver.GetPassword() != "initial" {
+		t.Error("Password that failed verification was kept.")
+	}
+	if backend.Rotation.PendingPassword != "" {
+		t.Error("Failed rotation left a pending password.")
+	}
+
+	fakeDriver.CredentialCheckError = nil
+	if rotated := orchestrator.rotateDueCredentials(later); rotated != 1 {
+		t.Fatalf("Expected 1 password rotated; got %d.", rotated)
+	}
+	password := fakeDriver.GetPassword()
+	if password == "initial" || len(password) != 24 {
+		t.Errorf("Unexpected rotated password %q.", password)
+	}
+	if !backend.Rotation.LastRotated.After(now) {
+		t.Error("Rotation time not updated.")
+	}
+	if orchestrator.rotateDueCredentials(later) != 0 {
+		t.Error("Password rotated again before the next interval.")
+	}
+
+	// The new password should be stored with the backend.
+	newOrchestrator := getOrchestrator()
+	newBackend, ok := newOrchestrator.backends[backendName]
+	if !ok {
+		t.Fatal("Rotated backend not found after bootstrapping.")
+	}
+	newDriver := newBackend.Driver.(*backend_fake.FakeStorageDriver)
+	if newDriver.GetPassword() != password {
+		t.Error("Bootstrapped backend doesn't use the rotated password.")
+	}
+	if !newBackend.Rotation.LastRotated.Equal(backend.Rotation.LastRotated) {
+		t.Errorf("Expected last rotation %v after bootstrapping; got %v.",
+			backend.Rotation.LastRotated, newBackend.Rotation.LastRotated)
+	}
+	cleanup(t, newOrchestrator)
+}
+
+func TestIscsiPortals(t *testing.T) {
+	orchestrator := getOrchestrator()
+	for _, test := range []struct {
+		name     string
+		reported []string
+		expected []string
+	}{
+		{"All", nil, []string{"10.0.0.1", "10.0.0.2:3260", "10.0.0.3"}},
+		{"Restricted", []string{"10.0.0.3", "10.0.0.2"},
+			[]string{"10.0.0.2:3260", "10.0.0.3"}},
+		{"None", []string{"10.0.0.9"}, nil},
+	} {
+		backendName := "portals" + test.name
+		configJSON, err := fake.NewFakeStorageDriverConfigJSON(backendName,
+			config.Block, map[string]*fake.FakeStoragePool{
+				"primary": &fake.FakeStoragePool{
+					Attrs: map[string]sa.Offer{
+						sa.Media: sa.NewStringOffer("hdd"),
+					},
+					Bytes: 100 * 1024 * 1024 * 1024,
+				},
+			},
+		)
+		if err != nil {
+			t.Fatal("Unable to generate config JSON:  ", err)
+		}
+		var configMap map[string]interface{}
+		if err = json.Unmarshal([]byte(configJSON), &configMap); err != nil {
+			t.Fatal("Unable to parse config JSON:  ", err)
+		}
+		configMap["Portals"] = []string{"10.0.0.1", "10.0.0.2:3260",
+			"10.0.0.3"}
+		if test.reported != nil {
+			configMap["reportedPortals"] = test.reported
+		}
+		configBytes, _ := json.Marshal(configMap)
+		if _, err = orchestrator.AddStorageBackend(testCtx,
+			string(configBytes)); err != nil {
+			t.Fatalf("%s:  unable to add backend:  %v", test.name, err)
+		}
+		// Each backend gets its own storage class, so that the volume is
+		// placed on it.
+		if _, err = orchestrator.AddStorageClass(&storage_class.Config{
+			Name: backendName,
+			BackendStoragePools: map[string][]string{
+				backendName: []string{"primary"},
+			},
+		}); err != nil {
+			t.Fatalf("%s:  unable to add storage class:  %v", test.name, err)
+		}
+		vol, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(
+			"portalVolume"+test.name, 1, backendName, config.Block))
+		if err != nil {
+			t.Fatalf("%s:  unable to add volume:  %v", test.name, err)
+		}
+		accessInfo := vol.Config.AccessInfo
+		if !reflect.DeepEqual(accessInfo.IscsiPortals, test.expected) {
+			t.Errorf("%s:  expected portals %v; got %v.", test.name,
+				test.expected, accessInfo.IscsiPortals)
+		}
+		if len(test.expected) > 0 &&
+			accessInfo.IscsiTargetPortal != test.expected[0] {
+			t.Errorf("%s:  expected target portal %s; got %s.", test.name,
+				test.expected[0], accessInfo.IscsiTargetPortal)
+		}
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestDualProtocolBackend(t *testing.T) {
+	const (
+		backendName = "dualBackend"
+		scName      = "dualSC"
+	)
+	orchestrator := getOrchestrator()
+	dualConfigJSON := func(sanProtocol config.Protocol) string {
+		configJSON, err := fake.NewFakeStorageDriverConfigJSON(backendName,
+			config.ProtocolAny, map[string]*fake.FakeStoragePool{
+				"nas": &fake.FakeStoragePool{
+					Attrs: map[string]sa.Offer{
+						sa.Media: sa.NewStringOffer("hdd"),
+					},
+					Bytes:    100 * 1024 * 1024 * 1024,
+					Protocol: config.File,
+				},
+				"san": &fake.FakeStoragePool{
+					Attrs: map[string]sa.Offer{
+						sa.Media: sa.NewStringOffer("hdd"),
+					},
+					Bytes:    100 * 1024 * 1024 * 1024,
+					Protocol: sanProtocol,
+				},
+			},
+		)
+		if err != nil {
+			t.Fatal("Unable to generate config JSON:  ", err)
+		}
+		return configJSON
+	}
+
+	external, err := orchestrator.AddStorageBackend(testCtx,
+		dualConfigJSON(config.Block))
+	if err != nil {
+		t.Fatal("Unable to add backend:  ", err)
+	}
+	if !reflect.DeepEqual(external.Protocols,
+		[]config.Protocol{config.Block, config.File}) {
+		t.Errorf("Unexpected backend protocols:  %v", external.Protocols)
+	}
+	if _, err = orchestrator.AddStorageClass(&storage_class.Config{
+		Name: scName,
+		BackendStoragePools: map[string][]string{
+			backendName: []string{"nas", "san"},
+		},
+	}); err != nil {
+		t.Fatal("Unable to add storage class:  ", err)
+	}
+	for _, test := range []struct {
+		protocol config.Protocol
+		pool     string
+	}{
+		{config.File, "nas"},
+		{config.Block, "san"},
+	} {
+		volumeName := "dualVolume-" + string(test.protocol)
+		vol, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(
+			volumeName, 1, scName, test.protocol))
+		if err != nil {
+			t.Fatalf("Unable to add %s volume:  %v", test.protocol, err)
+		}
+		if vol.Pool != test.pool || vol.Config.Protocol != test.protocol {
+			t.Errorf("Expected a %s volume in pool %s; got a %s volume in "+
+				"pool %s.", test.protocol, test.pool, vol.Config.Protocol,
+				vol.Pool)
+		}
+	}
+
+	// The protocol of an in-use pool can't change.
+	if _, err = orchestrator.AddStorageBackend(testCtx,
+		dualConfigJSON(config.File)); err == nil {
+		t.Error("Backend update changed the protocol of an in-use pool.")
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestVolumeMetadata(t *testing.T) {
+	const (
+		backendName = "metadataBackend"
+		scName      = "metadataSC"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	for name, owner := range map[string]string{
+		"aliceVol": "alice",
+		"bobVol":   "bob",
+	} {
+		volConfig := generateVolumeConfig(name, 1, scName, config.File)
+		volConfig.Metadata = map[string]string{"owner": owner, "team": "db"}
+		if _, err := orchestrator.AddVolume(testCtx, volConfig); err != nil {
+			t.Fatalf("Unable to add volume %s:  %v", name, err)
+		}
+	}
+
+	for _, test := range []struct {
+		selector map[string]string
+		expected []string
+	}{
+		{map[string]string{"team": "db"}, []string{"aliceVol", "bobVol"}},
+		{map[string]string{"owner": "alice"}, []string{"aliceVol"}},
+		{map[string]string{"owner": "alice", "team": "web"}, []string{}},
+	} {
+		names := make([]string, 0)
+		for _, v := range orchestrator.ListVolumesByMetadata(test.selector) {
+			names = append(names, v.Config.Name)
+		}
+		sort.Strings(names)
+		if !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("%v:  expected volumes %v; got %v.", test.selector,
+				test.expected, names)
+		}
+	}
+
+	if _, err := orchestrator.UpdateVolumeMetadata("aliceVol",
+		map[string]string{"": "x"}); err == nil {
+		t.Error("Metadata with an empty key was accepted.")
+	}
+	newMetadata := map[string]string{"owner": "carol"}
+	external, err := orchestrator.UpdateVolumeMetadata("aliceVol", newMetadata)
+	if err != nil {
+		t.Fatal("Unable to update volume metadata:  ", err)
+	}
+	if !reflect.DeepEqual(external.Config.Metadata, newMetadata) {
+		t.Errorf("Expected metadata %v; got %v.", newMetadata,
+			external.Config.Metadata)
+	}
+	persistentVol, err := orchestrator.storeClient.GetVolume("aliceVol")
+	if err != nil {
+		t.Fatal("Unable to get volume from the store:  ", err)
+	}
+	if !reflect.DeepEqual(persistentVol.Config.Metadata, newMetadata) {
+		t.Errorf("Metadata was not persisted; got %v.",
+			persistentVol.Config.Metadata)
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestOrphanedVolumes(t *testing.T) {
+	const (
+		backendName = "orphanBackend"
+		scName      = "orphanSC"
+		volName     = "orphanVol"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	if _, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(volName, 1,
+		scName, config.File)); err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+	// Remove the backend behind Trident's back.
+	if err := orchestrator.storeClient.DeleteBackend(
+		orchestrator.backends[backendName]); err != nil {
+		t.Fatal("Unable to delete backend from the store:  ", err)
+	}
+
+	newOrchestrator := getOrchestrator()
+	if !newOrchestrator.bootstrapped {
+		t.Fatal("Orchestrator failed to bootstrap with an orphaned volume.")
+	}
+	external := newOrchestrator.GetVolume(volName)
+	if external == nil || !external.Orphaned {
+		t.Fatalf("Expected %s to be orphaned; got %v.", volName, external)
+	}
+	if len(newOrchestrator.ListVolumes()) != 1 {
+		t.Error("Orphaned volume was not listed.")
+	}
+	if newOrchestrator.GetVolumeType(external) != config.UnknownVolumeType {
+		t.Error("Expected an unknown volume type for an orphaned volume.")
+	}
+	if _, err := newOrchestrator.AddVolume(testCtx,
+		generateVolumeConfig(volName, 1, scName, config.File)); err == nil {
+		t.Error("Volume with an orphaned volume's name was created.")
+	}
+	if _, err := newOrchestrator.DeleteVolume(testCtx, volName); err == nil {
+		t.Error("Orphaned volume was deleted without force.")
+	}
+	if _, err := newOrchestrator.ReattachVolume(volName, backendName,
+		""); err == nil {
+		t.Error("Volume was reattached to a nonexistent backend.")
+	}
+
+	addBackend(t, newOrchestrator, backendName)
+	if _, err := newOrchestrator.ReattachVolume(volName, backendName,
+		"nonexistent"); err == nil {
+		t.Error("Volume was reattached to a nonexistent pool.")
+	}
+	external, err := newOrchestrator.ReattachVolume(volName, backendName, "")
+	if err != nil {
+		t.Fatal("Unable to reattach volume:  ", err)
+	}
+	if external.Orphaned || external.Backend != backendName {
+		t.Errorf("Unexpected reattached volume:  %v", external)
+	}
+	vol := newOrchestrator.volumes[volName]
+	if _, ok := vol.Pool.Volumes[volName]; !ok {
+		t.Error("Reattached volume not found in its pool.")
+	}
+	if _, err = newOrchestrator.ReattachVolume(volName, backendName,
+		""); err == nil {
+		t.Error("Volume that isn't orphaned was reattached.")
+	}
+	cleanup(t, newOrchestrator)
+}
+
+// corruptStoreClient reports the named volume records as corrupt until they
+// are quarantined.
+type corruptStoreClient struct {
+	*persistent_store.InMemoryClient
+	corruptVolumes map[string]bool
+}
+
+func (c *corruptStoreClient) GetVolumes() ([]*storage.VolumeExternal, error) {
+	for name := range c.corruptVolumes {
+		return nil, &persistent_store.CorruptRecordError{
+			Type: persistent_store.VolumeRecord,
+			Name: name,
+			Err:  fmt.Errorf("unexpected end of JSON input"),
+		}
+	}
+	return c.InMemoryClient.GetVolumes()
+}
+
+func (c *corruptStoreClient) QuarantineRecord(
+	recordType persistent_store.RecordType, name, reason string,
+) error {
+	delete(c.corruptVolumes, name)
+	return c.InMemoryClient.QuarantineRecord(recordType, name, reason)
+}
+
+func TestResilientBootstrap(t *testing.T) {
+	const (
+		backendName = "resilientBackend"
+		scName      = "resilientSC"
+	)
+	if *etcdV2 != "" {
+		t.Skip("Corrupt records are only simulated with the in-memory store.")
+	}
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	for _, name := range []string{"goodVol", "badVol"} {
+		if _, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(name, 1,
+			scName, config.File)); err != nil {
+			t.Fatalf("Unable to add volume %s:  %v", name, err)
+		}
+	}
+	store := &corruptStoreClient{
+		InMemoryClient: inMemoryClient,
+		corruptVolumes: map[string]bool{"badVol": true},
+	}
+
+	strict := NewTridentOrchestrator(store)
+	if err := strict.Bootstrap(); err == nil {
+		t.Error("Bootstrap succeeded with a corrupt volume record.")
+	}
+
+	resilient := NewTridentOrchestrator(store)
+	resilient.SetResilientBootstrap(true)
+	if err := resilient.Bootstrap(); err != nil {
+		t.Fatal("Resilient bootstrap failed:  ", err)
+	}
+	if resilient.GetVolume("goodVol") == nil {
+		t.Error("Intact volume was not bootstrapped.")
+	}
+	if resilient.GetVolume("badVol") != nil {
+		t.Error("Corrupt volume was bootstrapped.")
+	}
+	records, err := resilient.ListQuarantinedRecords()
+	if err != nil {
+		t.Fatal("Unable to list quarantined records:  ", err)
+	}
+	if len(records) != 1 || records[0].Type != persistent_store.VolumeRecord ||
+		records[0].Name != "badVol" {
+		t.Errorf("Unexpected quarantined records:  %v", records)
+	}
+	cleanup(t, resilient)
+}
+
+func TestBootstrapManyBackends(t *testing.T) {
+	const (
+		numBackends = 2*config.BootstrapWorkers + 1
+		scName      = "manyBackendsSC"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, "manyBackends0", scName)
+	for i := 1; i < numBackends; i++ {
+		addBackend(t, orchestrator, fmt.Sprintf("manyBackends%d", i))
+	}
+	for i := 0; i < numBackends; i++ {
+		if _, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(
+			fmt.Sprintf("manyBackendsVol%d", i), 1, scName,
+			config.File)); err != nil {
+			t.Fatal("Unable to add volume:  ", err)
+		}
+	}
+
+	newOrchestrator := getOrchestrator()
+	if !newOrchestrator.bootstrapped {
+		t.Fatal("Orchestrator failed to bootstrap.")
+	}
+	if len(newOrchestrator.backends) != numBackends {
+		t.Errorf("Expected %d backends; got %d.", numBackends,
+			len(newOrchestrator.backends))
+	}
+	if len(newOrchestrator.volumes) != numBackends {
+		t.Errorf("Expected %d volumes; got %d.", numBackends,
+			len(newOrchestrator.volumes))
+	}
+	if sc := newOrchestrator.storageClasses[scName]; sc == nil ||
+		len(sc.GetStoragePoolsForProtocol(config.File)) != numBackends {
+		t.Error("Storage class does not include every backend's pool.")
+	}
+	cleanup(t, newOrchestrator)
+}
+
+func TestBootstrapManyVolumes(t *testing.T) {
+	const (
+		numVolumes    = 9
+		scName        = "manyVolumesSC"
+		orphanBackend = "manyVolumesOrphan"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, "manyVolumes", scName)
+	addBackend(t, orchestrator, orphanBackend)
+	for i := 0; i < numVolumes; i++ {
+		if _, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(
+			fmt.Sprintf("manyVolumesVol%d", i), 1, scName,
+			config.File)); err != nil {
+			t.Fatal("Unable to add volume:  ", err)
+		}
+	}
+	orphans := make(map[string]bool)
+	for name, vol := range orchestrator.volumes {
+		if vol.Backend.Name == orphanBackend {
+			orphans[name] = true
+		}
+	}
+	// Remove a backend behind Trident's back, orphaning its volumes.
+	if err := orchestrator.storeClient.DeleteBackend(
+		orchestrator.backends[orphanBackend]); err != nil {
+		t.Fatal("Unable to delete backend from the store:  ", err)
+	}
+
+	// Use fewer workers than volumes, so that some wait for others.
+	newOrchestrator := NewTridentOrchestrator(orchestrator.storeClient)
+	bootstrapConfig := config.NewBootstrapConfig()
+	bootstrapConfig.Workers = 2
+	newOrchestrator.SetBootstrapConfig(bootstrapConfig)
+	if err := newOrchestrator.Bootstrap(); err != nil {
+		t.Fatal("Orchestrator failed to bootstrap:  ", err)
+	}
+	for i := 0; i < numVolumes; i++ {
+		name := fmt.Sprintf("manyVolumesVol%d", i)
+		if orphans[name] {
+			if _, ok := newOrchestrator.orphanedVolumes[name]; !ok {
+				t.Errorf("Volume %s was not orphaned.", name)
+			}
+			continue
+		}
+		vol, ok := newOrchestrator.volumes[name]
+		if !ok {
+			t.Errorf("Volume %s was not bootstrapped.", name)
+		} else if vol.Pool.Volumes[name] != vol {
+			t.Errorf("Volume %s was not added to its pool.", name)
+		}
+	}
+	cleanup(t, newOrchestrator)
+}
+
+func TestDeferredBackendInit(t *testing.T) {
+	const (
+		backendName     = "deferredBackend"
+		downBackendName = "deferredDownBackend"
+		scName          = "deferredSC"
+		volName         = "deferredVol"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, downBackendName, scName)
+	if _, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(volName, 1,
+		scName, config.File)); err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+	addBackend(t, orchestrator, backendName)
+	fake.SetUnreachable(downBackendName, true)
+	defer fake.SetUnreachable(downBackendName, false)
+
+	strict := NewTridentOrchestrator(orchestrator.storeClient)
+	if err := strict.Bootstrap(); err == nil {
+		t.Error("Bootstrap succeeded with an unreachable backend.")
+	}
+
+	deferred := NewTridentOrchestrator(orchestrator.storeClient)
+	deferred.SetDeferredBackendInit(true)
+	if err := deferred.Bootstrap(); err != nil {
+		t.Fatal("Deferred bootstrap failed:  ", err)
+	}
+	if _, ok := deferred.backends[backendName]; !ok {
+		t.Error("Reachable backend was not bootstrapped.")
+	}
+	external := deferred.GetBackend(downBackendName)
+	if external == nil || external.InitError == "" || external.Online {
+		t.Errorf("Expected %s to be listed as failed; got %v.",
+			downBackendName, external)
+	}
+	if len(deferred.ListBackends()) != 2 {
+		t.Error("Failed backend was not listed.")
+	}
+	if vol := deferred.GetVolume(volName); vol == nil || !vol.Orphaned {
+		t.Errorf("Expected %s to be orphaned; got %v.", volName, vol)
+	}
+	if health := deferred.GetHealth(); !health.Ready ||
+		health.FailedBackends != 1 {
+		t.Errorf("Expected a ready orchestrator with 1 failed backend; "+
+			"got %v.", health)
+	}
+	if remaining := deferred.retryFailedBackends(); remaining != 1 {
+		t.Errorf("Expected 1 failed backend; got %d.", remaining)
+	}
+	if deferred.failedBackends[downBackendName].attempts != 2 {
+		t.Error("Failed attempt was not recorded.")
+	}
+	if _, err := deferred.OfflineBackend(downBackendName); err == nil {
+		t.Error("Failed backend with volumes was deleted.")
+	}
+
+	fake.SetUnreachable(downBackendName, false)
+	if remaining := deferred.retryFailedBackends(); remaining != 0 {
+		t.Fatalf("Expected no failed backends; got %d.", remaining)
+	}
+	if health := deferred.GetHealth(); health.FailedBackends != 0 {
+		t.Errorf("Expected no failed backends in health; got %d.",
+			health.FailedBackends)
+	}
+	external = deferred.GetBackend(downBackendName)
+	if external == nil || external.InitError != "" || !external.Online {
+		t.Errorf("Expected %s to be initialized; got %v.", downBackendName,
+			external)
+	}
+	vol, ok := deferred.volumes[volName]
+	if !ok {
+		t.Fatalf("Volume %s was not added to its initialized backend.",
+			volName)
+	}
+	if _, ok = vol.Pool.Volumes[volName]; !ok {
+		t.Error("Volume not found in its pool.")
+	}
+	if _, ok = deferred.orphanedVolumes[volName]; ok {
+		t.Error("Volume is still orphaned.")
+	}
+	if sc := deferred.storageClasses[scName]; sc == nil ||
+		len(sc.GetStoragePoolsForProtocol(config.File)) != 1 {
+		t.Error("Storage class does not include the initialized backend.")
+	}
+	cleanup(t, deferred)
+}
+
+func TestHealth(t *testing.T) {
+	orchestrator := getOrchestrator()
+	unbootstrapped := NewTridentOrchestrator(orchestrator.storeClient)
+	if unbootstrapped.IsBootstrapped() {
+		t.Error("Orchestrator is bootstrapped before Bootstrap.")
+	}
+	if health := unbootstrapped.GetHealth(); health.Ready ||
+		health.Bootstrapped || !health.StoreReachable {
+		t.Errorf("Expected an unready orchestrator with a reachable "+
+			"store; got %v.", health)
+	}
+
+	health := orchestrator.GetHealth()
+	if !orchestrator.IsBootstrapped() || !health.Ready ||
+		!health.Bootstrapped || !health.StoreReachable ||
+		health.StoreError != "" {
+		t.Errorf("Expected a ready orchestrator; got %v.", health)
+	}
+	pending := health.PendingTransactions
+	volTxn := &persistent_store.VolumeTransaction{
+		Config: generateVolumeConfig("healthVol", 1, "healthSC",
+			config.File),
+		Op: persistent_store.AddVolume,
+	}
+	if err := orchestrator.storeClient.AddVolumeTransaction(
+		volTxn); err != nil {
+		t.Fatal("Unable to add volume transaction:  ", err)
+	}
+	if health = orchestrator.GetHealth(); health.PendingTransactions !=
+		pending+1 {
+		t.Errorf("Expected %d pending transactions; got %d.", pending+1,
+			health.PendingTransactions)
+	}
+	if err := orchestrator.storeClient.DeleteVolumeTransaction(
+		volTxn); err != nil {
+		t.Error("Unable to delete volume transaction:  ", err)
+	}
+}
+
+func TestGetVersionInfo(t *testing.T) {
+	orchestrator := getOrchestrator()
+	addBackend(t, orchestrator, "versionBackend")
+	info := orchestrator.GetVersionInfo()
+	if info.Version != config.OrchestratorVersion ||
+		info.APIVersion != config.OrchestratorAPIVersion ||
+		info.StoreSchemaVersion != config.StoreSchemaVersion ||
+		info.GoVersion == "" || info.GitSHA == "" {
+		t.Errorf("Wrong build information %v.", info)
+	}
+	found := false
+	for _, driver := range info.Drivers {
+		for _, backend := range driver.Backends {
+			if backend != "versionBackend" {
+				continue
+			}
+			found = true
+			if driver.Name != fake.FakeStorageDriverName ||
+				driver.Version != config.OrchestratorVersion {
+				t.Errorf("Wrong version for the fake driver:  %v", driver)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Backend's driver not reported in %v.", info.Drivers)
+	}
+
+	unbootstrapped := NewTridentOrchestrator(orchestrator.storeClient)
+	if info = unbootstrapped.GetVersionInfo(); len(info.Drivers) != 0 {
+		t.Errorf("Drivers reported before Bootstrap:  %v", info.Drivers)
+	}
+}
+
+func TestOrderedPlacement(t *testing.T) {
+	const scName = "orderedSC"
+	orchestrator := getOrchestrator()
+	orchestrator.SetPlacementPolicy(config.PlacementOrdered)
+	// Add the backends out of order.
+	addBackendStorageClass(t, orchestrator, "orderedBackendB", scName)
+	addBackend(t, orchestrator, "orderedBackendA")
+	if sc := orchestrator.storageClasses[scName]; sc == nil ||
+		len(sc.GetStoragePoolsForProtocol(config.File)) != 2 {
+		t.Fatal("Storage class does not include both backends.")
+	}
+	for i := 0; i < 3; i++ {
+		volName := fmt.Sprintf("orderedVol%d", i)
+		vol, err := orchestrator.AddVolume(testCtx,
+			generateVolumeConfig(volName, 1, scName, config.File))
+		if err != nil {
+			t.Fatal("Unable to add volume:  ", err)
+		}
+		if vol.Backend != "orderedBackendA" {
+			t.Errorf("Expected %s on orderedBackendA; got %s.", volName,
+				vol.Backend)
+		}
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestPlacementSeed(t *testing.T) {
+	pools := make([]*storage.StoragePool, 0)
+	for i := 0; i < 10; i++ {
+		pools = append(pools, &storage.StoragePool{
+			Name:    fmt.Sprintf("pool%d", i),
+			Backend: &storage.StorageBackend{Name: "seededBackend"},
+		})
+	}
+	first := NewTridentOrchestrator(nil)
+	second := NewTridentOrchestrator(nil)
+	first.SetPlacementSeed(42)
+	second.SetPlacementSeed(42)
+	for i := 0; i < 5; i++ {
+		a, b := first.placementOrder(pools), second.placementOrder(pools)
+		if !reflect.DeepEqual(a, b) {
+			t.Fatalf("Orchestrators with the same seed placed volume %d "+
+				"differently:  %v, %v", i, a, b)
+		}
+	}
+}
+
+func TestCanceledAddVolume(t *testing.T) {
+	const (
+		backendName = "canceledBackend"
+		scName      = "canceledSC"
+		volName     = "canceledVolume"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := orchestrator.AddVolume(ctx,
+		generateVolumeConfig(volName, 1, scName, config.File)); err == nil {
+		t.Error("Volume was created with a canceled context.")
+	}
+	if orchestrator.GetVolume(volName) != nil {
+		t.Error("Canceled volume present in orchestrator.")
+	}
+	txns, err := orchestrator.storeClient.GetVolumeTransactions()
+	if err != nil && err.Error() != persistent_store.KeyErrorMsg {
+		t.Error("Unable to list volume transactions:  ", err)
+	} else if len(txns) != 0 {
+		t.Errorf("Found %d volume transactions after canceling.", len(txns))
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestProvisionTimeout(t *testing.T) {
+	const (
+		backendName = "slowBackend"
+		scName      = "slowSC"
+		volName     = "slowVolume"
+		delay       = 200 * time.Millisecond
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	timeouts := config.NewTimeoutConfig()
+	timeouts.Provision = "20ms"
+	orchestrator.SetTimeouts(timeouts)
+	fake.SetDelay(backendName, delay)
+	defer fake.SetDelay(backendName, 0)
+
+	start := time.Now()
+	if _, err := orchestrator.AddVolume(testCtx,
+		generateVolumeConfig(volName, 1, scName, config.File)); err == nil {
+		t.Error("Volume was created despite timing out.")
+	}
+	if elapsed := time.Since(start); elapsed >= delay {
+		t.Errorf("AddVolume waited %v for an unresponsive backend.", elapsed)
+	}
+	if orchestrator.GetVolume(volName) != nil {
+		t.Error("Timed out volume present in orchestrator.")
+	}
+
+	// The volume created after the timeout should be removed from the
+	// backend.
+	time.Sleep(2 * delay)
+	orchestrator.mutex.Lock()
+	backend := orchestrator.backends[backendName]
+	driver := backend.Driver.(*backend_fake.FakeStorageDriver)
+	if len(driver.Volumes) != 0 {
+		t.Errorf("Abandoned volume left on backend:  %v", driver.Volumes)
+	}
+	orchestrator.mutex.Unlock()
+	cleanup(t, orchestrator)
+}
+
+// TestAbandonedProvisionRace adds volumes to a pool while a creation in the
+// same pool that timed out is still running, which must not touch the pool
+// without the orchestrator's lock.  Run it with -race.
+func TestAbandonedProvisionRace(t *testing.T) {
+	const (
+		backendName = "abandonedBackend"
+		scName      = "abandonedSC"
+		volName     = "abandonedVolume"
+		volumes     = 6
+		delay       = 200 * time.Millisecond
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	timeouts := config.NewTimeoutConfig()
+	timeouts.Provision = "20ms"
+	orchestrator.SetTimeouts(timeouts)
+	fake.SetDelay(backendName, delay)
+	if _, err := orchestrator.AddVolume(testCtx,
+		generateVolumeConfig(volName, 1, scName, config.File)); err == nil {
+		fake.SetDelay(backendName, 0)
+		t.Fatal("Volume was created despite timing out.")
+	}
+	fake.SetDelay(backendName, 0)
+	orchestrator.SetTimeouts(config.NewTimeoutConfig())
+
+	// Keep adding volumes to the pool until after the abandoned creation
+	// finishes.
+	for i := 0; i < volumes; i++ {
+		name := fmt.Sprintf("concurrentVolume%d", i)
+		if _, err := orchestrator.AddVolume(testCtx,
+			generateVolumeConfig(name, 1, scName, config.File)); err != nil {
+			t.Errorf("Unable to add volume %s:  %v", name, err)
+		}
+		time.Sleep(delay / (volumes / 2))
+	}
+	time.Sleep(delay)
+
+	orchestrator.mutex.Lock()
+	backend := orchestrator.backends[backendName]
+	pool := backend.Storage["primary"]
+	if _, ok := pool.Volumes[volName]; ok {
+		t.Error("Abandoned volume was added to its pool.")
+	}
+	if len(pool.Volumes) != volumes {
+		t.Errorf("Expected %d volumes in the pool; got %d.", volumes,
+			len(pool.Volumes))
+	}
+	driver := backend.Driver.(*backend_fake.FakeStorageDriver)
+	if len(driver.Volumes) != volumes {
+		t.Errorf("Expected %d volumes on the backend; got %v.", volumes,
+			driver.Volumes)
+	}
+	orchestrator.mutex.Unlock()
+	cleanup(t, orchestrator)
+}
+
+// TestAbandonedCallSerialization checks that a backend's driver sees one
+// operation at a time:  an abandoned creation that succeeds is undone while
+// a second, also abandoned, creation is still in progress, and the deletion
+// has to wait for it.
+func TestAbandonedCallSerialization(t *testing.T) {
+	const (
+		backendName = "serialBackend"
+		scName      = "serialSC"
+		delay       = 200 * time.Millisecond
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	timeouts := config.NewTimeoutConfig()
+	timeouts.Provision = "20ms"
+	orchestrator.SetTimeouts(timeouts)
+	fake.SetDelay(backendName, delay)
+	for _, name := range []string{"serialVolume1", "serialVolume2"} {
+		if _, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(
+			name, 1, scName, config.File)); err == nil {
+			t.Errorf("Volume %s was created despite timing out.", name)
+		}
+	}
+	// The first creation finishes and is undone while the second waits for,
+	// and then runs, its own creation.
+	time.Sleep(4 * delay)
+	fake.SetDelay(backendName, 0)
+	orchestrator.SetTimeouts(config.NewTimeoutConfig())
+	if _, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(
+		"serialVolume3", 1, scName, config.File)); err != nil {
+		t.Error("Unable to add volume:  ", err)
+	}
+
+	orchestrator.mutex.Lock()
+	backend := orchestrator.backends[backendName]
+	driver := backend.Driver.(*backend_fake.FakeStorageDriver)
+	if driver.Overlapped() {
+		t.Error("Driver operations overlapped.")
+	}
+	if len(driver.DestroyedVolumes) != 2 {
+		t.Errorf("Expected both abandoned volumes to be destroyed; got %v.",
+			driver.DestroyedVolumes)
+	}
+	if len(driver.Volumes) != 1 {
+		t.Errorf("Expected 1 volume on the backend; got %v.", driver.Volumes)
+	}
+	if pool := backend.Storage["primary"]; len(pool.Volumes) != 1 ||
+		pool.Volumes["serialVolume3"] == nil {
+		t.Errorf("Unexpected volumes in the pool:  %v", pool.Volumes)
+	}
+	orchestrator.mutex.Unlock()
+	cleanup(t, orchestrator)
+}
+
+// countVolumeTransactions returns the number of stored volume transactions.
+func countVolumeTransactions(t *testing.T, o *tridentOrchestrator) int {
+	txns, err := o.storeClient.GetVolumeTransactions()
+	if err != nil && err.Error() != persistent_store.KeyErrorMsg {
+		t.Fatal("Unable to list volume transactions:  ", err)
+	}
+	return len(txns)
+}
+
+func TestInjectedFailures(t *testing.T) {
+	const (
+		backendName = "faultyBackend"
+		scName      = "faultySC"
+		volName     = "faultyVolume"
+	)
+	defer faults.DisarmAll()
+	for _, point := range []string{faults.VolumeTxnLogged,
+		faults.VolumeCreatedOnBackend, faults.VolumeStored} {
+		for _, mode := range []string{faults.ModeError, faults.ModeCrash} {
+			orchestrator := getOrchestrator()
+			addBackendStorageClass(t, orchestrator, backendName, scName)
+			if err := faults.Arm(point, mode); err != nil {
+				t.Fatal("Unable to arm failure point:  ", err)
+			}
+			_, err := orchestrator.AddVolume(testCtx,
+				generateVolumeConfig(volName, 1, scName, config.File))
+			faults.DisarmAll()
+			if err == nil {
+				t.Errorf("%s/%s:  volume was created.", point, mode)
+			}
+			if orchestrator.GetVolume(volName) != nil {
+				t.Errorf("%s/%s:  failed volume present in orchestrator.",
+					point, mode)
+			}
+			if mode == faults.ModeCrash {
+				if countVolumeTransactions(t, orchestrator) != 1 {
+					t.Errorf("%s/%s:  transaction not left for bootstrap.",
+						point, mode)
+				}
+				// Restart, rolling back the transaction.
+				orchestrator = NewTridentOrchestrator(orchestrator.storeClient)
+				if err = orchestrator.Bootstrap(); err != nil {
+					t.Fatalf("%s/%s:  unable to bootstrap:  %v", point, mode,
+						err)
+				}
+				if orchestrator.GetVolume(volName) != nil {
+					t.Errorf("%s/%s:  volume not rolled back.", point, mode)
+				}
+			}
+			if n := countVolumeTransactions(t, orchestrator); n != 0 {
+				t.Errorf("%s/%s:  found %d volume transactions.", point, mode,
+					n)
+			}
+			if _, err = orchestrator.storeClient.GetVolume(volName); err == nil {
+				t.Errorf("%s/%s:  failed volume left in the store.", point,
+					mode)
+			}
+			cleanup(t, orchestrator)
+		}
+	}
+
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	if _, err := orchestrator.AddVolume(testCtx,
+		generateVolumeConfig(volName, 1, scName, config.File)); err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+	if err := faults.Arm(faults.VolumeDeletedFromBackend,
+		faults.ModeError); err != nil {
+		t.Fatal("Unable to arm failure point:  ", err)
+	}
+	if _, err := orchestrator.DeleteVolume(testCtx, volName); err == nil {
+		t.Error("Volume deletion succeeded despite a failure.")
+	}
+	faults.DisarmAll()
+	if countVolumeTransactions(t, orchestrator) != 1 {
+		t.Error("Deletion transaction not left for retry.")
+	}
+	if _, err := orchestrator.DeleteVolume(testCtx, volName); err != nil {
+		t.Error("Unable to retry volume deletion:  ", err)
+	}
+	if n := countVolumeTransactions(t, orchestrator); n != 0 {
+		t.Errorf("Found %d volume transactions after deletion.", n)
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestTransactionJanitor(t *testing.T) {
+	const (
+		backendName = "janitorBackend"
+		scName      = "janitorSC"
+		addName     = "janitorAddVolume"
+		deleteName  = "janitorDeleteVolume"
+	)
+	defer faults.DisarmAll()
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	janitorConfig := config.NewJanitorConfig()
+	janitorConfig.MinAge = "1h"
+	janitorConfig.MaxAttempts = 2
+	orchestrator.SetJanitorConfig(janitorConfig)
+
+	if _, err := orchestrator.AddVolume(testCtx,
+		generateVolumeConfig(deleteName, 1, scName, config.File)); err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+	if err := faults.Arm(faults.VolumeCreatedOnBackend,
+		faults.ModeCrash); err != nil {
+		t.Fatal("Unable to arm failure point:  ", err)
+	}
+	if _, err := orchestrator.AddVolume(testCtx,
+		generateVolumeConfig(addName, 1, scName, config.File)); err == nil {
+		t.Fatal("Volume was created despite a failure.")
+	}
+	if err := faults.Arm(faults.VolumeDeletedFromBackend,
+		faults.ModeError); err != nil {
+		t.Fatal("Unable to arm failure point:  ", err)
+	}
+	if _, err := orchestrator.DeleteVolume(testCtx, deleteName); err == nil {
+		t.Fatal("Volume was deleted despite a failure.")
+	}
+
+	txns, err := orchestrator.ListPendingTransactions()
+	if err != nil {
+		t.Fatal("Unable to list pending transactions:  ", err)
+	}
+	if len(txns) != 2 || txns[0].Volume != addName ||
+		txns[0].Op != persistent_store.AddVolume ||
+		txns[1].Volume != deleteName ||
+		txns[1].Op != persistent_store.DeleteVolume {
+		t.Fatalf("Unexpected pending transactions:  %v", txns)
+	}
+	if txns[0].Created.IsZero() || txns[0].Age == "" {
+		t.Error("Transaction age not reported.")
+	}
+
+	if resolved := orchestrator.resolveStaleTransactions(); resolved != 0 {
+		t.Errorf("Resolved %d transactions younger than the minimum age.",
+			resolved)
+	}
+	janitorConfig.MinAge = "1ns"
+	orchestrator.SetJanitorConfig(janitorConfig)
+	// The deletion keeps failing until it is escalated.
+	for i := 0; i < 2; i++ {
+		expected := 1 - i
+		if resolved := orchestrator.resolveStaleTransactions(); resolved !=
+			expected {
+			t.Errorf("Expected %d resolved transactions; got %d.", expected,
+				resolved)
+		}
+	}
+	txns, err = orchestrator.ListPendingTransactions()
+	if err != nil {
+		t.Fatal("Unable to list pending transactions:  ", err)
+	}
+	if len(txns) != 1 || txns[0].Volume != deleteName ||
+		!txns[0].Escalated || txns[0].Attempts != 2 ||
+		txns[0].LastError == "" {
+		t.Fatalf("Expected an escalated deletion; got %v.", txns)
+	}
+	faults.DisarmAll()
+	if resolved := orchestrator.resolveStaleTransactions(); resolved != 0 {
+		t.Error("Escalated transaction was retried.")
+	}
+	if _, err = orchestrator.DeleteVolume(testCtx, deleteName); err != nil {
+		t.Error("Unable to delete volume:  ", err)
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestAddVolumeRequestID(t *testing.T) {
+	const (
+		backendName = "idempotentBackend"
+		scName      = "idempotentSC"
+		volName     = "idempotentVolume"
+		requestID   = "request-1"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+
+	volConfig := generateVolumeConfig(volName, 1, scName, config.File)
+	volConfig.RequestID = requestID
+	first, err := orchestrator.AddVolume(testCtx, volConfig)
+	if err != nil {
+		t.Fatal("Unable to create volume:  ", err)
+	}
+
+	retryConfig := generateVolumeConfig(volName, 1, scName, config.File)
+	retryConfig.RequestID = requestID
+	retried, err := orchestrator.AddVolume(testCtx, retryConfig)
+	if err != nil {
+		t.Fatal("Retried request failed:  ", err)
+	}
+	if !reflect.DeepEqual(first, retried) {
+		t.Errorf("Retried request returned a different volume.\n"+
+			"\tExpected: %v\n\tGot: %v", first, retried)
+	}
+
+	for _, id := range []string{"", "request-2"} {
+		otherConfig := generateVolumeConfig(volName, 1, scName, config.File)
+		otherConfig.RequestID = id
+		if _, err = orchestrator.AddVolume(testCtx, otherConfig); err == nil {
+			t.Errorf("Request with ID %q created a duplicate volume.", id)
+		}
+	}
+
+	orchestrator.mutex.Lock()
+	backend := orchestrator.backends[backendName]
+	driver := backend.Driver.(*backend_fake.FakeStorageDriver)
+	if len(driver.Volumes) != 1 {
+		t.Errorf("Expected one volume on the backend; found %d.",
+			len(driver.Volumes))
+	}
+	orchestrator.mutex.Unlock()
+	cleanup(t, orchestrator)
+}
+
+func TestErrorTypes(t *testing.T) {
+	const (
+		backendName = "errorBackend"
+		scName      = "errorSC"
+		volName     = "errorVolume"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	if _, err := orchestrator.AddVolume(testCtx,
+		generateVolumeConfig(volName, 1, scName, config.File)); err != nil {
+		t.Fatal("Unable to create volume:  ", err)
+	}
+	if _, err := orchestrator.AttachVolume(volName, "node1", false); err != nil {
+		t.Fatal("Unable to attach volume:  ", err)
+	}
+
+	for _, test := range []struct {
+		name     string
+		call     func() error
+		expected errors.Type
+	}{
+		{"duplicate volume", func() error {
+			_, err := orchestrator.AddVolume(testCtx,
+				generateVolumeConfig(volName, 1, scName, config.File))
+			return err
+		}, errors.AlreadyExists},
+		{"unknown storage class", func() error {
+			_, err := orchestrator.AddVolume(testCtx,
+				generateVolumeConfig("other", 1, "nonexistent", config.File))
+			return err
+		}, errors.InvalidInput},
+		{"missing volume", func() error {
+			_, err := orchestrator.DeleteVolume(testCtx, "nonexistent")
+			return err
+		}, errors.NotFound},
+		{"attached volume", func() error {
+			_, err := orchestrator.DeleteVolume(testCtx, volName)
+			return err
+		}, errors.Conflict},
+		{"unsupported protocol", func() error {
+			_, err := orchestrator.AddVolume(testCtx,
+				generateVolumeConfig("other", 1, scName, config.Block))
+			return err
+		}, errors.BackendUnavailable},
+	} {
+		err := test.call()
+		if errorType := errors.GetType(err); errorType != test.expected {
+			t.Errorf("%s:  expected error type %q, got %q (%v).", test.name,
+				test.expected, errorType, err)
+		}
+	}
+	if _, err := orchestrator.DetachVolume(volName, "node1"); err != nil {
+		t.Error("Unable to detach volume:  ", err)
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestDeleteVolumeMissingFromBackend(t *testing.T) {
+	const (
+		backendName = "missingVolumeBackend"
+		scName      = "missingVolumeSC"
+		volName     = "missingVolume"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	if _, err := orchestrator.AddVolume(testCtx,
+		generateVolumeConfig(volName, 1, scName, config.File)); err != nil {
+		t.Fatal("Unable to create volume:  ", err)
+	}
+
+	// Remove the volume behind Trident's back.
+	orchestrator.mutex.Lock()
+	vol := orchestrator.volumes[volName]
+	driver := vol.Backend.Driver.(*backend_fake.FakeStorageDriver)
+	delete(driver.Volumes, vol.Config.InternalName)
+	err := vol.Backend.Driver.Destroy(vol.Config.InternalName)
+	orchestrator.mutex.Unlock()
+	if !errors.IsNotFound(err) {
+		t.Errorf("Expected a NotFound error from the driver; got %v.", err)
+	}
+
+	if _, err = orchestrator.DeleteVolume(testCtx, volName); err != nil {
+		t.Error("Unable to delete volume missing from its backend:  ", err)
+	}
+	if orchestrator.GetVolume(volName) != nil {
+		t.Error("Deleted volume still present in orchestrator.")
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestRetainedVolumes(t *testing.T) {
+	const (
+		backendName = "retainBackend"
+		scName      = "retainSC"
+		volName     = "retainVol"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	volConfig := generateVolumeConfig(volName, 1, scName, config.File)
+	volConfig.ReclaimPolicy = config.ReclaimRetain
+	if _, err := orchestrator.AddVolume(testCtx, volConfig); err != nil {
+		t.Fatal("Unable to create volume:  ", err)
+	}
+	vol := orchestrator.volumes[volName]
+	internalName := vol.Config.InternalName
+	driver := vol.Backend.Driver.(*backend_fake.FakeStorageDriver)
+
+	if _, err := orchestrator.DeleteVolume(testCtx, volName); err != nil {
+		t.Fatal("Unable to delete retained volume:  ", err)
+	}
+	if orchestrator.GetVolume(volName) != nil {
+		t.Error("Retained volume still present in orchestrator.")
+	}
+	if driver.DestroyedVolumes[internalName] {
+		t.Error("Retained volume was deleted from its backend.")
+	}
+	if _, err := orchestrator.ImportRetainedVolume("nonexistent", "",
+		""); !errors.IsNotFound(err) {
+		t.Errorf("Expected NotFound importing a nonexistent volume; got %v.",
+			err)
+	}
+
+	// The retained volume should survive a restart.
+	newOrchestrator := getOrchestrator()
+	retainedVols := newOrchestrator.ListRetainedVolumes()
+	if len(retainedVols) != 1 || retainedVols[0].Config.Name != volName ||
+		retainedVols[0].Backend != backendName {
+		t.Fatalf("Unexpected retained volumes:  %v", retainedVols)
+	}
+	external, err := newOrchestrator.ImportRetainedVolume(volName, "", "")
+	if err != nil {
+		t.Fatal("Unable to import retained volume:  ", err)
+	}
+	if external.Backend != backendName ||
+		external.Config.InternalName != internalName {
+		t.Errorf("Unexpected imported volume:  %v", external)
+	}
+	if len(newOrchestrator.ListRetainedVolumes()) != 0 {
+		t.Error("Imported volume is still listed as retained.")
+	}
+
+	// A volume deleted with the Delete policy is removed from its backend.
+	vol = newOrchestrator.volumes[volName]
+	vol.Config.ReclaimPolicy = config.ReclaimDelete
+	driver = vol.Backend.Driver.(*backend_fake.FakeStorageDriver)
+	if _, err = newOrchestrator.DeleteVolume(testCtx, volName); err != nil {
+		t.Fatal("Unable to delete volume:  ", err)
+	}
+	if !driver.DestroyedVolumes[internalName] {
+		t.Error("Volume with the Delete policy was left on its backend.")
+	}
+	if len(newOrchestrator.ListRetainedVolumes()) != 0 {
+		t.Error("Volume with the Delete policy was retained.")
+	}
+	cleanup(t, newOrchestrator)
+}
+
+func TestProvisioningRetries(t *testing.T) {
+	const volName = "retryVol"
+	orchestrator := getOrchestrator()
+	if orchestrator.GetProvisioningRetry(volName) != nil {
+		t.Fatal("Found a provisioning retry before any failures.")
+	}
+	var (
+		retry *persistent_store.ProvisioningRetry
+		err   error
+	)
+	for i := 1; i <= 3; i++ {
+		before := time.Now().UTC()
+		retry, err = orchestrator.RecordProvisioningFailure(volName,
+			fmt.Errorf("Failure %d.", i))
+		if err != nil {
+			t.Fatal("Unable to record provisioning failure:  ", err)
+		}
+		if retry.Attempts != i {
+			t.Errorf("Expected %d attempts; got %d.", i, retry.Attempts)
+		}
+		backoff := provisioningBackoff(i)
+		if retry.NextAttempt.Before(before.Add(backoff)) {
+			t.Errorf("Next attempt %v is less than %v after failure %d.",
+				retry.NextAttempt, backoff, i)
+		}
+	}
+	if retry.LastError != "Failure 3." {
+		t.Errorf("Unexpected last error:  %s", retry.LastError)
+	}
+	if provisioningBackoff(2) != 2*config.ProvisioningRetryBackoff {
+		t.Errorf("Backoff did not double:  %v", provisioningBackoff(2))
+	}
+	if provisioningBackoff(100) != config.MaxProvisioningRetryBackoff {
+		t.Errorf("Backoff exceeded the maximum:  %v",
+			provisioningBackoff(100))
+	}
+
+	// The record, and so the backoff, should survive a restart.
+	newOrchestrator := getOrchestrator()
+	retries := newOrchestrator.ListProvisioningRetries()
+	if len(retries) != 1 || retries[0].Volume != volName ||
+		retries[0].Attempts != 3 {
+		t.Fatalf("Unexpected provisioning retries:  %v", retries)
+	}
+
+	// Abandoning and resuming keep the attempts, and persist.
+	if retry, err = newOrchestrator.AbandonProvisioning(
+		volName); err != nil || !retry.Abandoned {
+		t.Fatalf("Unable to abandon provisioning:  %v, %v", retry, err)
+	}
+	if !getOrchestrator().GetProvisioningRetry(volName).Abandoned {
+		t.Error("Abandoned provisioning retry not persisted.")
+	}
+	before := time.Now().UTC()
+	if retry, err = newOrchestrator.RetryProvisioningNow(
+		volName); err != nil {
+		t.Fatal("Unable to retry provisioning:  ", err)
+	}
+	if retry.Abandoned || retry.Attempts != 3 ||
+		retry.NextAttempt.Before(before) ||
+		retry.NextAttempt.After(time.Now().UTC()) {
+		t.Errorf("Unexpected provisioning retry:  %v", retry)
+	}
+	if _, err = newOrchestrator.AbandonProvisioning(
+		"missing"); !errors.IsNotFound(err) {
+		t.Errorf("Expected NotFound abandoning a missing retry; got %v.",
+			err)
+	}
+
+	if found, err := newOrchestrator.DeleteProvisioningRetry(
+		volName); !found || err != nil {
+		t.Errorf("Unable to delete provisioning retry:  %v", err)
+	}
+	if newOrchestrator.GetProvisioningRetry(volName) != nil {
+		t.Error("Provisioning retry still present after deletion.")
+	}
+	if _, err := newOrchestrator.DeleteProvisioningRetry(
+		volName); !errors.IsNotFound(err) {
+		t.Errorf("Expected NotFound deleting a missing retry; got %v.", err)
+	}
+	cleanup(t, newOrchestrator)
+}
+
+func TestDeletionGracePeriod(t *testing.T) {
+	const (
+		backendName = "gracePeriodBackend"
+		scName      = "gracePeriodSC"
+		volName     = "gracePeriodVol"
+		otherName   = "gracePeriodOtherVol"
+	)
+	orchestrator := getOrchestrator()
+	orchestrator.SetDeletionGracePeriod(time.Hour)
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	for _, name := range []string{volName, otherName} {
+		if _, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(
+			name, 1, scName, config.File)); err != nil {
+			t.Fatal("Unable to create volume:  ", err)
+		}
+	}
+	vol := orchestrator.volumes[volName]
+	internalName := vol.Config.InternalName
+	driver := vol.Backend.Driver.(*backend_fake.FakeStorageDriver)
+
+	if _, err := orchestrator.DeleteVolume(testCtx, volName); err != nil {
+		t.Fatal("Unable to delete volume:  ", err)
+	}
+	external := orchestrator.GetVolume(volName)
+	if external == nil || external.Deletion == nil {
+		t.Fatalf("Expected %s to be terminating; got %v.", volName, external)
+	}
+	deletedName := external.Config.InternalName
+	if _, ok := driver.Volumes[deletedName]; !ok ||
+		deletedName == internalName {
+		t.Errorf("Terminating volume was not renamed on its backend; "+
+			"internal name is %s.", deletedName)
+	}
+	if _, err := orchestrator.AttachVolume(volName, "node1",
+		false); errors.GetType(err) != errors.Conflict {
+		t.Errorf("Expected a Conflict attaching a terminating volume; got %v.",
+			err)
+	}
+	if reaped := orchestrator.reapTerminatingVolumes(time.Now()); reaped != 0 {
+		t.Errorf("Reaped %d volumes within their grace period.", reaped)
+	}
+
+	external, err := orchestrator.UndeleteVolume(volName)
+	if err != nil {
+		t.Fatal("Unable to undelete volume:  ", err)
+	}
+	if external.Deletion != nil ||
+		external.Config.InternalName != internalName {
+		t.Errorf("Unexpected undeleted volume:  %v", external)
+	}
+	if _, ok := driver.Volumes[internalName]; !ok {
+		t.Error("Undeleted volume did not get its name back on its backend.")
+	}
+	if _, err = orchestrator.UndeleteVolume(volName); errors.GetType(
+		err) != errors.Conflict {
+		t.Errorf("Expected a Conflict undeleting a volume that isn't "+
+			"terminating; got %v.", err)
+	}
+
+	// The reaper deletes volumes once their grace period ends.
+	if _, err = orchestrator.DeleteVolume(testCtx, volName); err != nil {
+		t.Fatal("Unable to delete volume:  ", err)
+	}
+	deletedName = orchestrator.volumes[volName].Config.InternalName
+	reaped := orchestrator.reapTerminatingVolumes(time.Now().Add(2 *
+		time.Hour))
+	if reaped != 1 || orchestrator.GetVolume(volName) != nil {
+		t.Errorf("Expected the terminating volume to be reaped; reaped %d.",
+			reaped)
+	}
+	if !driver.DestroyedVolumes[deletedName] {
+		t.Error("Reaped volume was not deleted from its backend.")
+	}
+
+	// A rename interrupted before the record was updated is undone.
+	other := orchestrator.volumes[otherName]
+	volTxn := &persistent_store.VolumeTransaction{
+		Config:          other.Config,
+		Op:              persistent_store.TerminateVolume,
+		NewInternalName: other.Config.InternalName + "_deleted",
+	}
+	if err = orchestrator.storeClient.AddVolumeTransaction(volTxn); err != nil {
+		t.Fatal("Unable to add volume transaction:  ", err)
+	}
+	if err = driver.Rename(other.Config.InternalName,
+		volTxn.NewInternalName); err != nil {
+		t.Fatal("Unable to rename volume:  ", err)
+	}
+	if err = orchestrator.rollBackTransaction(volTxn); err != nil {
+		t.Fatal("Unable to roll back terminate transaction:  ", err)
+	}
+	if _, ok := driver.Volumes[other.Config.InternalName]; !ok {
+		t.Error("Interrupted rename was not undone.")
+	}
+
+	// Terminating volumes are still terminating after a restart, and
+	// deleting one again deletes it immediately.
+	if _, err = orchestrator.DeleteVolume(testCtx, otherName); err != nil {
+		t.Fatal("Unable to delete volume:  ", err)
+	}
+	newOrchestrator := getOrchestrator()
+	newOrchestrator.SetDeletionGracePeriod(time.Hour)
+	external = newOrchestrator.GetVolume(otherName)
+	if external == nil || external.Deletion == nil {
+		t.Fatalf("Expected %s to be terminating after bootstrap; got %v.",
+			otherName, external)
+	}
+	if _, err = newOrchestrator.DeleteVolume(testCtx, otherName); err != nil {
+		t.Fatal("Unable to delete terminating volume:  ", err)
+	}
+	if newOrchestrator.GetVolume(otherName) != nil {
+		t.Error("Terminating volume was not deleted immediately.")
+	}
+	cleanup(t, newOrchestrator)
+}
+
+func TestVolumeExpiry(t *testing.T) {
+	const (
+		backendName  = "expiryBackend"
+		scName       = "expirySC"
+		volName      = "expiryVol"
+		extendedName = "expiryExtendedVol"
+	)
+	expiryCalls := make(chan string, 10)
+	hookServer := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			var req hooks.Request
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Error("Unable to decode hook request:  ", err)
+				return
+			}
+			if req.Phase == hooks.Expiry {
+				expiryCalls <- req.Volume.Name
+			}
+			json.NewEncoder(w).Encode(&hooks.Response{Allowed: true})
+		},
+	))
+	defer hookServer.Close()
+
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	if _, err := orchestrator.AddHook(&hooks.Config{
+		Name:  "expiry",
+		Phase: hooks.Expiry,
+		URL:   hookServer.URL,
+	}); err != nil {
+		t.Fatal("Unable to add expiry hook:  ", err)
+	}
+	invalid := generateVolumeConfig(volName, 1, scName, config.File)
+	invalid.TTL = "-1h"
+	if err := invalid.Validate(); err == nil {
+		t.Error("Validated a volume with a negative TTL.")
+	}
+	for _, name := range []string{volName, extendedName} {
+		volConfig := generateVolumeConfig(name, 1, scName, config.File)
+		volConfig.TTL = "1h"
+		vol, err := orchestrator.AddVolume(testCtx, volConfig)
+		if err != nil {
+			t.Fatal("Unable to add volume:  ", err)
+		}
+		if vol.Config.ExpiresAt == nil || !vol.Config.ExpiresAt.Equal(
+			vol.Config.CreatedAt.Add(time.Hour)) {
+			t.Errorf("Wrong expiry %v for volume created at %v.",
+				vol.Config.ExpiresAt, vol.Config.CreatedAt)
+		}
+	}
+
+	now := time.Now()
+	if deleted := orchestrator.expireVolumes(now); deleted != 0 ||
+		len(expiryCalls) != 0 {
+		t.Errorf("Expired volumes before their TTL; deleted %d.", deleted)
+	}
+	// Expired volumes are flagged, and the hooks notified, but they are
+	// kept until the grace period ends.
+	expiredAt := now.Add(2 * time.Hour)
+	if deleted := orchestrator.expireVolumes(expiredAt); deleted != 0 {
+		t.Errorf("Deleted %d volumes within their grace period.", deleted)
+	}
+	if len(expiryCalls) != 2 {
+		t.Errorf("Expected 2 expiry hook calls; got %d.", len(expiryCalls))
+	}
+	vol := orchestrator.GetVolume(volName)
+	if vol == nil || vol.Config.ExpiredAt == nil ||
+		!vol.Config.ExpiredAt.Equal(expiredAt) {
+		t.Fatalf("Expected %s to be flagged as expired; got %v.", volName,
+			vol)
+	}
+	extendedAt := expiredAt.Add(24 * time.Hour)
+	vol, err := orchestrator.SetVolumeExpiry(extendedName, &extendedAt)
+	if err != nil {
+		t.Fatal("Unable to extend volume expiry:  ", err)
+	}
+	if vol.Config.ExpiredAt != nil {
+		t.Error("Extended volume is still flagged as expired.")
+	}
+	if _, err = orchestrator.SetVolumeExpiry("missing",
+		nil); errors.GetType(err) != errors.NotFound {
+		t.Errorf("Expected NotFound for a missing volume; got %v.", err)
+	}
+
+	// Flagged volumes are only deleted when expired volumes are deleted.
+	afterGracePeriod := expiredAt.Add(2 * time.Hour)
+	orchestrator.SetVolumeExpiryConfig(&config.VolumeExpiryConfig{
+		Action: config.ExpiryFlag,
+	})
+	if deleted := orchestrator.expireVolumes(afterGracePeriod); deleted != 0 {
+		t.Errorf("Deleted %d volumes that should only be flagged.", deleted)
+	}
+	orchestrator.SetVolumeExpiryConfig(config.NewVolumeExpiryConfig())
+	if deleted := orchestrator.expireVolumes(afterGracePeriod); deleted != 1 {
+		t.Errorf("Expected 1 expired volume to be deleted; got %d.",
+			deleted)
+	}
+	if orchestrator.GetVolume(volName) != nil {
+		t.Error("Expired volume was not deleted.")
+	}
+	if orchestrator.GetVolume(extendedName) == nil {
+		t.Error("Volume with an extended expiry was deleted.")
+	}
+
+	// Expiry state is persisted.
+	newOrchestrator := getOrchestrator()
+	vol = newOrchestrator.GetVolume(extendedName)
+	if vol == nil || vol.Config.ExpiresAt == nil ||
+		!vol.Config.ExpiresAt.Equal(extendedAt) {
+		t.Errorf("Volume expiry not persisted; got %v.", vol)
+	}
+	if _, err = newOrchestrator.DeleteVolume(testCtx,
+		extendedName); err != nil {
+		t.Error("Unable to delete volume:  ", err)
+	}
+	cleanup(t, newOrchestrator)
+}
+
+func TestUpdateStorageClass(t *testing.T) {
+	const (
+		backendName = "updateSCBackend"
+		scName      = "updateSC"
+		volName     = "updateSCVol"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	if _, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(volName,
+		1, scName, config.File)); err != nil {
+		t.Fatal("Unable to create volume:  ", err)
+	}
+	pool := orchestrator.volumes[volName].Pool
+
+	// Require SSDs, which the backend's only pool doesn't offer.
+	ssdConfig := &storage_class.Config{
+		Name: scName,
+		Attributes: map[string]sa.Request{
+			sa.Media:            sa.NewStringRequest("ssd"),
+			sa.TestingAttribute: sa.NewBoolRequest(true),
+		},
+	}
+	external, err := orchestrator.UpdateStorageClass(ssdConfig)
+	if err != nil {
+		t.Fatal("Unable to update storage class:  ", err)
+	}
+	if len(external.StoragePools) != 0 {
+		t.Errorf("Expected no storage pools; got %v.", external.StoragePools)
+	}
+	if !reflect.DeepEqual(external.NonconformingVolumes,
+		[]string{volName}) {
+		t.Errorf("Expected %s to be nonconforming; got %v.", volName,
+			external.NonconformingVolumes)
+	}
+	for _, sc := range pool.StorageClasses {
+		if sc == scName {
+			t.Error("Storage pool still lists the updated storage class.")
+		}
+	}
+	if _, err = orchestrator.AddVolume(testCtx, generateVolumeConfig(
+		"updateSCVol2", 1, scName, config.File)); err == nil {
+		t.Error("Volume was created in a pool that doesn't satisfy its " +
+			"storage class.")
+	}
+
+	// The update survives a restart.
+	newOrchestrator := getOrchestrator()
+	sc := newOrchestrator.GetStorageClass(scName)
+	if sc == nil || len(sc.NonconformingVolumes) != 1 {
+		t.Fatalf("Unexpected storage class after bootstrap:  %v", sc)
+	}
+
+	ssdConfig.Attributes[sa.Media] = sa.NewStringRequest("hdd")
+	if external, err = newOrchestrator.UpdateStorageClass(
+		ssdConfig); err != nil {
+		t.Fatal("Unable to update storage class:  ", err)
+	}
+	if len(external.StoragePools[backendName]) != 1 ||
+		len(external.NonconformingVolumes) != 0 {
+		t.Errorf("Unexpected storage class after update:  %v", external)
+	}
+	if _, err = newOrchestrator.UpdateStorageClass(&storage_class.Config{
+		Name: "nonexistentSC",
+	}); !errors.IsNotFound(err) {
+		t.Errorf("Expected NotFound updating a nonexistent storage class; "+
+			"got %v.", err)
+	}
+	cleanup(t, newOrchestrator)
+}
+
+func TestGenerateStorageClasses(t *testing.T) {
+	const backendName = "generateSCBackend"
+	orchestrator := getOrchestrator()
+	addBackend(t, orchestrator, backendName)
+
+	recommendations, err := orchestrator.GenerateStorageClasses(backendName)
+	if err != nil {
+		t.Fatal("Unable to generate storage classes:  ", err)
+	}
+	if len(recommendations) != 1 {
+		t.Fatalf("Expected one storage class; got %d.",
+			len(recommendations))
+	}
+	scName := backendName + "-hdd-testingattribute"
+	if name := recommendations[0].Config.Name; name != scName {
+		t.Errorf("Expected storage class %s; got %s.", scName, name)
+	}
+	// Nothing is added until the administrator applies the suggestion.
+	if orchestrator.GetStorageClass(scName) != nil {
+		t.Error("Generating storage classes added one.")
+	}
+	external, err := orchestrator.AddStorageClass(recommendations[0].Config)
+	if err != nil {
+		t.Fatal("Unable to add generated storage class:  ", err)
+	}
+	if !reflect.DeepEqual(external.StoragePools[backendName],
+		recommendations[0].StoragePools) {
+		t.Errorf("Expected the class to match %v; got %v.",
+			recommendations[0].StoragePools,
+			external.StoragePools[backendName])
+	}
+	if _, err = orchestrator.GenerateStorageClasses(
+		"nonexistentBackend"); !errors.IsNotFound(err) {
+		t.Errorf("Expected NotFound for a nonexistent backend; got %v.",
+			err)
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestValidateStorageBackend(t *testing.T) {
+	const (
+		backendName = "validateBackend"
+		scName      = "validateSC"
+	)
+	orchestrator := getOrchestrator()
+	if _, err := orchestrator.AddStorageClass(&storage_class.Config{
+		Name: scName,
+		Attributes: map[string]sa.Request{
+			sa.Media: sa.NewStringRequest("hdd"),
+		},
+	}); err != nil {
+		t.Fatal("Unable to add storage class:  ", err)
+	}
+	configJSON, err := fake.NewFakeStorageDriverConfigJSON(backendName,
+		config.File, map[string]*fake.FakeStoragePool{
+			"primary": &fake.FakeStoragePool{
+				Attrs: map[string]sa.Offer{
+					sa.Media: sa.NewStringOffer("hdd"),
+				},
+				Bytes: 100 * 1024 * 1024 * 1024,
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal("Unable to generate config JSON:  ", err)
+	}
+
+	validation, err := orchestrator.ValidateStorageBackend(testCtx,
+		configJSON)
+	if err != nil {
+		t.Fatal("Unable to validate backend:  ", err)
+	}
+	if validation.Update {
+		t.Error("New backend reported as an update.")
+	}
+	if validation.Backend.Name != backendName {
+		t.Errorf("Expected backend %s; got %s.", backendName,
+			validation.Backend.Name)
+	}
+	pool, ok := validation.Backend.Storage["primary"]
+	if !ok {
+		t.Fatal("Discovered pool not reported.")
+	}
+	if !reflect.DeepEqual(pool.StorageClasses, []string{scName}) ||
+		!reflect.DeepEqual(validation.StorageClasses, []string{scName}) {
+		t.Errorf("Expected pool to satisfy %s; got %v and %v.", scName,
+			pool.StorageClasses, validation.StorageClasses)
+	}
+	if orchestrator.GetBackend(backendName) != nil {
+		t.Error("Validating the backend added it.")
+	}
+	if sc := orchestrator.GetStorageClass(scName); len(sc.StoragePools) != 0 {
+		t.Errorf("Validating the backend added pools to the storage "+
+			"class:  %v", sc.StoragePools)
+	}
+
+	if _, err = orchestrator.AddStorageBackend(testCtx,
+		configJSON); err != nil {
+		t.Fatal("Unable to add backend:  ", err)
+	}
+	validation, err = orchestrator.ValidateStorageBackend(testCtx,
+		configJSON)
+	if err != nil {
+		t.Fatal("Unable to validate backend update:  ", err)
+	}
+	if !validation.Update {
+		t.Error("Backend update not reported as an update.")
+	}
+
+	if _, err = orchestrator.ValidateStorageBackend(testCtx,
+		`{"version": 1, "storageDriverName": "nonexistent"}`); err == nil {
+		t.Error("Config for an unknown driver validated.")
+	}
+	cleanup(t, orchestrator)
+}
+
+func desiredBackendConfig(
+	t *testing.T, name string, pools ...string,
+) json.RawMessage {
+	fakePools := make(map[string]*fake.FakeStoragePool, len(pools))
+	for _, pool := range pools {
+		fakePools[pool] = &fake.FakeStoragePool{
+			Attrs: map[string]sa.Offer{
+				sa.Media: sa.NewStringOffer("hdd"),
+			},
+			Bytes: 100 * 1024 * 1024 * 1024,
+		}
+	}
+	configJSON, err := fake.NewFakeStorageDriverConfigJSON(name, config.File,
+		fakePools)
+	if err != nil {
+		t.Fatal("Unable to generate config JSON:  ", err)
+	}
+	return json.RawMessage(configJSON)
+}
+
+func desiredStorageClass(name string) *storage_class.Config {
+	return &storage_class.Config{
+		Name: name,
+		Attributes: map[string]sa.Request{
+			sa.Media: sa.NewStringRequest("hdd"),
+		},
+	}
+}
+
+func checkStateChanges(
+	t *testing.T, kind string, expected, got StateChanges,
+) {
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("Wrong %s changes; expected %+v, got %+v.", kind, expected,
+			got)
+	}
+}
+
+func TestApplyState(t *testing.T) {
+	orchestrator := getOrchestrator()
+	state := &DesiredState{
+		Backends: []json.RawMessage{
+			desiredBackendConfig(t, "stateA", "primary"),
+			desiredBackendConfig(t, "stateB", "primary"),
+		},
+		StorageClasses: []*storage_class.Config{
+			desiredStorageClass("stateGold"),
+			desiredStorageClass("stateSilver"),
+		},
+	}
+	report, err := orchestrator.ApplyState(testCtx, state)
+	if err != nil {
+		t.Fatal("Unable to apply state:  ", err)
+	}
+	expected := newStateChanges()
+	expected.Added = []string{"stateA", "stateB"}
+	checkStateChanges(t, "backend", expected, report.Backends)
+	expected.Added = []string{"stateGold", "stateSilver"}
+	checkStateChanges(t, "storage class", expected, report.StorageClasses)
+	if sc := orchestrator.GetStorageClass("stateGold"); len(
+		sc.StoragePools) != 2 {
+		t.Errorf("Storage class not matched to new backends:  %v",
+			sc.StoragePools)
+	}
+
+	// Applying the same state again changes nothing.
+	if report, err = orchestrator.ApplyState(testCtx, state); err != nil {
+		t.Fatal("Unable to reapply state:  ", err)
+	}
+	expected = newStateChanges()
+	expected.Unchanged = []string{"stateA", "stateB"}
+	checkStateChanges(t, "backend", expected, report.Backends)
+	expected.Unchanged = []string{"stateGold", "stateSilver"}
+	checkStateChanges(t, "storage class", expected, report.StorageClasses)
+
+	silver := desiredStorageClass("stateSilver")
+	silver.Attributes[sa.Media] = sa.NewStringRequest("ssd")
+	state = &DesiredState{
+		Backends: []json.RawMessage{
+			desiredBackendConfig(t, "stateA", "primary", "secondary"),
+			desiredBackendConfig(t, "stateC", "primary"),
+		},
+		StorageClasses: []*storage_class.Config{
+			silver,
+			desiredStorageClass("stateBronze"),
+		},
+	}
+	if report, err = orchestrator.ApplyState(testCtx, state); err != nil {
+		t.Fatal("Unable to apply changed state:  ", err)
+	}
+	expected = newStateChanges()
+	expected.Added = []string{"stateC"}
+	expected.Updated = []string{"stateA"}
+	expected.Removed = []string{"stateB"}
+	checkStateChanges(t, "backend", expected, report.Backends)
+	expected = newStateChanges()
+	expected.Added = []string{"stateBronze"}
+	expected.Updated = []string{"stateSilver"}
+	expected.Removed = []string{"stateGold"}
+	checkStateChanges(t, "storage class", expected, report.StorageClasses)
+	if len(report.Errors) > 0 {
+		t.Error("Unexpected errors:  ", report.Errors)
+	}
+	backend := orchestrator.GetBackend("stateA")
+	if _, ok := backend.Storage["secondary"]; !ok {
+		t.Error("Backend stateA not updated.")
+	}
+	if orchestrator.GetBackend("stateB") != nil {
+		t.Error("Backend stateB not removed.")
+	}
+	if orchestrator.GetStorageClass("stateGold") != nil {
+		t.Error("Storage class stateGold not removed.")
+	}
+
+	// A config that can't be initialized fails the whole request.
+	state.Backends = append(state.Backends, json.RawMessage(
+		`{"version": 1, "storageDriverName": "nonexistent"}`))
+	state.StorageClasses = nil
+	if _, err = orchestrator.ApplyState(testCtx, state); err == nil {
+		t.Error("State with an invalid backend config applied.")
+	}
+	if orchestrator.GetStorageClass("stateSilver") == nil {
+		t.Error("Failed request removed storage classes.")
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestExplainPlacement(t *testing.T) {
+	const (
+		backendName = "placementBackend"
+		scName      = "placementSC"
+		ssdSCName   = "placementSSD"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	if _, err := orchestrator.AddStorageClass(&storage_class.Config{
+		Name: ssdSCName,
+		Attributes: map[string]sa.Request{
+			sa.Media: sa.NewStringRequest("ssd"),
+		},
+	}); err != nil {
+		t.Fatal("Unable to add storage class:  ", err)
+	}
+
+	report, err := orchestrator.ExplainPlacement(generateVolumeConfig(
+		"placementVol", 1, scName, config.File))
+	if err != nil {
+		t.Fatal("Unable to explain placement:  ", err)
+	}
+	eligible := report.Eligible()
+	if len(eligible) != 1 || eligible[0].Backend != backendName ||
+		eligible[0].Pool != "primary" {
+		t.Errorf("Expected only %s/primary to be eligible; got %v.",
+			backendName, report.Pools)
+	}
+	report, err = orchestrator.ExplainPlacement(generateVolumeConfig(
+		"placementVol", 1, scName, config.Block))
+	if err != nil {
+		t.Fatal("Unable to explain placement:  ", err)
+	}
+	if len(report.Eligible()) != 0 {
+		t.Error("Block volume eligible for a file backend.")
+	}
+	if _, err = orchestrator.ExplainPlacement(generateVolumeConfig(
+		"placementVol", 1, "nonexistentSC",
+		config.File)); errors.GetType(err) != errors.InvalidInput {
+		t.Errorf("Expected InvalidInput for an unknown storage class; got "+
+			"%v.", err)
+	}
+
+	// A failed placement carries the report.
+	_, err = orchestrator.AddVolume(testCtx, generateVolumeConfig(
+		"placementVol", 1, ssdSCName, config.File))
+	if errors.GetType(err) != errors.BackendUnavailable {
+		t.Fatalf("Expected BackendUnavailable; got %v.", err)
+	}
+	report, ok := errors.GetDetails(err).(*PlacementReport)
+	if !ok {
+		t.Fatalf("Expected a placement report; got %v.",
+			errors.GetDetails(err))
+	}
+	var primary *PoolPlacement
+	for _, p := range report.Pools {
+		if p.Backend == backendName && p.Pool == "primary" {
+			primary = p
+		}
+	}
+	if primary == nil || len(primary.Reasons) != 1 ||
+		!strings.Contains(primary.Reasons[0], "media=ssd requested, but "+
+			"hdd offered") {
+		t.Errorf("Unexpected placement for %s/primary:  %v", backendName,
+			primary)
+	}
+	if !strings.Contains(err.Error(), backendName+"/primary") {
+		t.Errorf("Error doesn't name the storage pool:  %v", err)
+	}
+	cleanup(t, orchestrator)
+}
+
+type recordingListener struct {
+	events []ProvisioningEvent
+}
+
+func (l *recordingListener) OnProvisioningEvent(event ProvisioningEvent) {
+	l.events = append(l.events, event)
+}
+
+func (l *recordingListener) types() []ProvisioningEventType {
+	ret := make([]ProvisioningEventType, 0, len(l.events))
+	for _, e := range l.events {
+		ret = append(ret, e.Type)
+	}
+	return ret
+}
+
+func TestProvisioningListener(t *testing.T) {
+	const (
+		backendName = "listenerBackend"
+		scName      = "listenerSC"
+		volName     = "listenerVol"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	listener := &recordingListener{}
+	orchestrator.AddProvisioningListener(listener)
+
+	if _, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(
+		volName, 1, scName, config.File)); err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+	expected := []ProvisioningEventType{ProvisioningStarted,
+		ProvisioningPoolSelected, ProvisioningSucceeded}
+	if !reflect.DeepEqual(listener.types(), expected) {
+		t.Errorf("Expected events %v; got %v.", expected, listener.types())
+	}
+	if last := listener.events[len(listener.events)-1]; last.Backend !=
+		backendName || last.Pool != "primary" {
+		t.Errorf("Unexpected placement in event:  %v", last)
+	}
+
+	listener.events = nil
+	if _, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(
+		"listenerVol2", 1, scName, config.Block)); err == nil {
+		t.Fatal("Block volume created on a file backend.")
+	}
+	expected = []ProvisioningEventType{ProvisioningStarted,
+		ProvisioningFailed}
+	if !reflect.DeepEqual(listener.types(), expected) {
+		t.Errorf("Expected events %v; got %v.", expected, listener.types())
+	}
+
+	orchestrator.RemoveProvisioningListener(listener)
+	listener.events = nil
+	orchestrator.DeleteVolume(testCtx, volName)
+	if _, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(
+		volName, 1, scName, config.File)); err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+	if len(listener.events) != 0 {
+		t.Errorf("Removed listener was called:  %v", listener.types())
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestExternalCache(t *testing.T) {
+	const (
+		backendName = "cachedBackend"
+		scName      = "cachedSC"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	if _, err := orchestrator.AddVolume(testCtx,
+		generateVolumeConfig("vol1", 1, scName, config.File)); err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+
+	// Reads share the external representations built by the first one.
+	volume := orchestrator.GetVolume("vol1")
+	volumes := orchestrator.ListVolumes()
+	if volume == nil || len(volumes) != 1 || volumes[0] != volume ||
+		orchestrator.GetVolume("vol1") != volume {
+		t.Fatalf("Expected the cached volume; got %v, %v.", volume, volumes)
+	}
+	backend := orchestrator.GetBackend(backendName)
+	if backend == nil || orchestrator.ListBackends()[0] != backend {
+		t.Fatalf("Expected the cached backend; got %v.", backend)
+	}
+
+	// Changes discard them.
+	if _, err := orchestrator.AddVolume(testCtx,
+		generateVolumeConfig("vol2", 1, scName, config.File)); err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+	if len(orchestrator.ListVolumes()) != 2 ||
+		orchestrator.GetVolume("vol1") == volume {
+		t.Error("Volumes weren't rebuilt after a volume was added.")
+	}
+	if b := orchestrator.GetBackend(backendName); b == backend ||
+		len(b.Volumes) != 2 {
+		t.Errorf("Backend wasn't rebuilt after a volume was added:  %v", b)
+	}
+	if _, err := orchestrator.DeleteVolume(testCtx, "vol1"); err != nil {
+		t.Fatal("Unable to delete volume:  ", err)
+	}
+	if orchestrator.GetVolume("vol1") != nil ||
+		len(orchestrator.ListVolumes()) != 1 {
+		t.Error("Deleted volume is still listed.")
+	}
+	if _, err := orchestrator.OfflineBackend(backendName); err != nil {
+		t.Fatal("Unable to offline backend:  ", err)
+	}
+	if len(orchestrator.ListBackends()) != 0 ||
+		orchestrator.GetBackend(backendName).Online {
+		t.Error("Offline backend is still listed as online.")
+	}
+	cleanup(t, orchestrator)
+}
+
+// TestExternalCacheChanges checks that each change is visible to the next
+// read, and that operations that only read keep the cached volumes.
+func TestExternalCacheChanges(t *testing.T) {
+	const (
+		backendName = "changedBackend"
+		scName      = "changedSC"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	if _, err := orchestrator.AddVolume(testCtx,
+		generateVolumeConfig("vol1", 1, scName, config.File)); err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+
+	volume := orchestrator.GetVolume("vol1")
+	orchestrator.GetVolumeChap("vol1")
+	orchestrator.ListVolumeUsage()
+	orchestrator.GetBackend(backendName)
+	if orchestrator.GetVolume("vol1") != volume {
+		t.Error("Volumes were rebuilt after operations that only read.")
+	}
+
+	if _, err := orchestrator.UpdateVolumeMetadata("vol1",
+		map[string]string{"team": "storage"}); err != nil {
+		t.Fatal("Unable to update volume metadata:  ", err)
+	}
+	if v := orchestrator.ListVolumes()[0]; v.Config.Metadata["team"] !=
+		"storage" {
+		t.Errorf("Listed volume lacks its new metadata:  %v",
+			v.Config.Metadata)
+	}
+	if _, err := orchestrator.AttachVolume("vol1", "node1",
+		false); err != nil {
+		t.Fatal("Unable to attach volume:  ", err)
+	}
+	if v := orchestrator.ListVolumes()[0]; len(v.Attachments) != 1 {
+		t.Errorf("Listed volume lacks its attachment:  %v", v.Attachments)
+	}
+	if _, err := orchestrator.DetachVolume("vol1", "node1"); err != nil {
+		t.Fatal("Unable to detach volume:  ", err)
+	}
+	if v := orchestrator.ListVolumes()[0]; len(v.Attachments) != 0 {
+		t.Errorf("Listed volume is still attached:  %v", v.Attachments)
+	}
+	if _, err := orchestrator.RenameVolume("vol1", "vol2"); err != nil {
+		t.Fatal("Unable to rename volume:  ", err)
+	}
+	volumes := orchestrator.ListVolumes()
+	if len(volumes) != 1 || volumes[0].Config.Name != "vol2" {
+		t.Errorf("Expected only the renamed volume; got %v.", volumes)
+	}
+	if b := orchestrator.ListBackends()[0]; len(b.Volumes) != 1 ||
+		b.Volumes[0] != "vol2" {
+		t.Errorf("Listed backend lacks the renamed volume:  %v", b.Volumes)
+	}
+	if _, err := orchestrator.OfflineBackend(backendName); err != nil {
+		t.Fatal("Unable to offline backend:  ", err)
+	}
+	if len(orchestrator.ListBackends()) != 0 {
+		t.Error("Offline backend is still listed.")
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestWalkVolumes(t *testing.T) {
+	const (
+		backendName = "walkBackend"
+		scName      = "walkSC"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	for _, name := range []string{"vol1", "vol2", "vol3"} {
+		if _, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(
+			name, 1, scName, config.File)); err != nil {
+			t.Fatal("Unable to add volume:  ", err)
+		}
+	}
+
+	walked := make(map[string]bool)
+	orchestrator.WalkVolumes(func(v *storage.VolumeExternal) bool {
+		walked[v.Config.Name] = true
+		// Volumes can be changed while they are walked.
+		if _, err := orchestrator.DeleteVolume(testCtx,
+			v.Config.Name); err != nil {
+			t.Error("Unable to delete volume:  ", err)
+		}
+		return true
+	})
+	if len(walked) != 3 || len(orchestrator.ListVolumes()) != 0 {
+		t.Errorf("Expected to walk and delete three volumes; got %v.",
+			walked)
+	}
+
+	if _, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(
+		"vol4", 1, scName, config.File)); err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+	calls := 0
+	orchestrator.WalkVolumes(func(v *storage.VolumeExternal) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Errorf("Walk continued after it was stopped:  %d calls", calls)
+	}
+
+	// Volumes are read as they're reached, so those deleted first are
+	// skipped, and those added during the walk aren't walked.
+	defer func(chunk int) {
+		walkVolumesChunk = chunk
+	}(walkVolumesChunk)
+	walkVolumesChunk = 1
+	for _, name := range []string{"vol5", "vol6"} {
+		if _, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(
+			name, 1, scName, config.File)); err != nil {
+			t.Fatal("Unable to add volume:  ", err)
+		}
+	}
+	walked = make(map[string]bool)
+	orchestrator.WalkVolumes(func(v *storage.VolumeExternal) bool {
+		walked[v.Config.Name] = true
+		if len(walked) > 1 {
+			return true
+		}
+		for _, other := range orchestrator.ListVolumes() {
+			if other.Config.Name == v.Config.Name {
+				continue
+			}
+			if _, err := orchestrator.DeleteVolume(testCtx,
+				other.Config.Name); err != nil {
+				t.Error("Unable to delete volume:  ", err)
+			}
+		}
+		if _, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(
+			"vol7", 1, scName, config.File)); err != nil {
+			t.Error("Unable to add volume:  ", err)
+		}
+		return true
+	})
+	if len(walked) != 1 {
+		t.Errorf("Expected to walk only the first volume; walked %v.",
+			walked)
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestZombieVolumes(t *testing.T) {
+	const (
+		backendName = "zombieBackend"
+		scName      = "zombieSC"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	for _, name := range []string{"zombie", "reused"} {
+		if _, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(
+			name, 1, scName, config.File)); err != nil {
+			t.Fatal("Unable to add volume:  ", err)
+		}
+	}
+
+	// Leave "zombie" on the backend, as a failed rollback would.
+	orchestrator.mutex.Lock()
+	vol := orchestrator.volumes["zombie"]
+	delete(orchestrator.volumes, "zombie")
+	if err := orchestrator.storeClient.DeleteVolume(vol); err != nil {
+		t.Fatal("Unable to delete volume from the store:  ", err)
+	}
+	if err := orchestrator.recordZombieVolume(vol,
+		fmt.Errorf("injected")); err != nil {
+		t.Fatal("Unable to record zombie volume:  ", err)
+	}
+	reused := persistent_store.NewZombieVolume(orchestrator.volumes["reused"],
+		fmt.Errorf("injected"))
+	if err := orchestrator.storeClient.AddZombieVolume(reused); err != nil {
+		t.Fatal("Unable to record zombie volume:  ", err)
+	}
+	vol.Backend.Online = false
+	orchestrator.mutex.Unlock()
+
+	// An offline backend's zombies are retried later; a zombie whose
+	// internal name is in use again is forgotten, not deleted.
+	if deleted := orchestrator.deleteZombieVolumes(); deleted != 0 {
+		t.Errorf("Deleted %d zombies from an offline backend.", deleted)
+	}
+	zombies, err := orchestrator.ListZombieVolumes()
+	if err != nil || len(zombies) != 1 ||
+		zombies[0].Config.Name != "zombie" || zombies[0].Attempts != 2 {
+		t.Fatalf("Expected one zombie with two attempts; got %v, %v.",
+			zombies, err)
+	}
+	if orchestrator.GetVolume("reused") == nil {
+		t.Error("Volume reusing a zombie's name was removed.")
+	}
+
+	orchestrator.mutex.Lock()
+	vol.Backend.Online = true
+	orchestrator.mutex.Unlock()
+	if deleted := orchestrator.deleteZombieVolumes(); deleted != 1 {
+		t.Errorf("Expected to delete one zombie; deleted %d.", deleted)
+	}
+	driver := vol.Backend.Driver.(*backend_fake.FakeStorageDriver)
+	if _, ok := driver.Volumes[vol.Config.InternalName]; ok {
+		t.Error("Zombie volume is still on the backend.")
+	}
+	if zombies, err = orchestrator.ListZombieVolumes(); err != nil ||
+		len(zombies) != 0 {
+		t.Errorf("Expected no zombies; got %v, %v.", zombies, err)
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestBackendPreference(t *testing.T) {
+	const scName = "preferenceSC"
+	orchestrator := getOrchestrator()
+	addBackend(t, orchestrator, "fallback")
+	addBackend(t, orchestrator, "preferred")
+	if _, err := orchestrator.AddStorageClass(
+		&storage_class.Config{
+			Name: scName,
+			Attributes: map[string]sa.Request{
+				sa.TestingAttribute: sa.NewBoolRequest(true),
+			},
+			BackendPreference: []string{"preferred", "fallback"},
+		},
+	); err != nil {
+		t.Fatal("Unable to add storage class: ", err)
+	}
+
+	// The preferred backend's pool has room for the first volume only, so
+	// the second fails over to the next backend.
+	for _, expected := range []struct {
+		name    string
+		backend string
+		tier    int
+	}{
+		{"first", "preferred", 1},
+		{"second", "fallback", 2},
+	} {
+		vol, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(
+			expected.name, 60, scName, config.File))
+		if err != nil {
+			t.Fatal("Unable to add volume:  ", err)
+		}
+		if vol.Backend != expected.backend ||
+			vol.BackendTier != expected.tier {
+			t.Errorf("Expected volume %s on backend %s, tier %d; got %s, "+
+				"tier %d.", expected.name, expected.backend, expected.tier,
+				vol.Backend, vol.BackendTier)
+		}
+	}
+
+	if _, err := orchestrator.AddStorageClass(&storage_class.Config{
+		Name:              "duplicateSC",
+		BackendPreference: []string{"preferred", "preferred"},
+	}); err == nil {
+		t.Error("Added a storage class with a duplicate backend preference.")
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestAntiAffinity(t *testing.T) {
+	const scName = "antiAffinitySC"
+	orchestrator := getOrchestrator()
+	addBackend(t, orchestrator, "arrayA")
+	addBackend(t, orchestrator, "arrayB")
+	if _, err := orchestrator.AddStorageClass(
+		&storage_class.Config{
+			Name: scName,
+			Attributes: map[string]sa.Request{
+				sa.TestingAttribute: sa.NewBoolRequest(true),
+			},
+		},
+	); err != nil {
+		t.Fatal("Unable to add storage class: ", err)
+	}
+	addReplica := func(
+		name, namespace string,
+	) (*VolumeResult, error) {
+		volConfig := generateVolumeConfig(name, 1, scName, config.File)
+		volConfig.Namespace = namespace
+		volConfig.SpreadGroup = "db"
+		return orchestrator.AddVolume(testCtx, volConfig)
+	}
+
+	first, err := addReplica("replica1", "prod")
+	if err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+	second, err := addReplica("replica2", "prod")
+	if err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+	if first.Backend == second.Backend {
+		t.Errorf("Volumes of a spread group share backend %s.",
+			first.Backend)
+	}
+	if _, err = addReplica("replica3", "prod"); errors.GetType(err) !=
+		errors.BackendUnavailable {
+		t.Errorf("Expected no backend for a third replica; got %v.", err)
+	}
+	// Spread groups are per namespace.
+	if _, err = addReplica("replica3", "test"); err != nil {
+		t.Error("Unable to add volume in another namespace:  ", err)
+	}
+
+	volConfig := generateVolumeConfig("apart", 1, scName, config.File)
+	volConfig.AntiAffinity = []string{"replica2"}
+	vol, err := orchestrator.AddVolume(testCtx, volConfig)
+	if err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+	if vol.Backend != first.Backend {
+		t.Errorf("Expected volume on backend %s; got %s.", first.Backend,
+			vol.Backend)
+	}
+	volConfig = generateVolumeConfig("unknown", 1, scName, config.File)
+	volConfig.AntiAffinity = []string{"missing"}
+	if _, err = orchestrator.AddVolume(testCtx, volConfig); errors.GetType(
+		err) != errors.InvalidInput {
+		t.Errorf("Expected an unknown volume to be rejected; got %v.", err)
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestRebalanceReport(t *testing.T) {
+	const scName = "rebalanceSC"
+	orchestrator := getOrchestrator()
+	addBackend(t, orchestrator, "busy")
+	addBackend(t, orchestrator, "idle")
+	// The backend preference piles every volume onto one backend.
+	if _, err := orchestrator.AddStorageClass(
+		&storage_class.Config{
+			Name: scName,
+			Attributes: map[string]sa.Request{
+				sa.TestingAttribute: sa.NewBoolRequest(true),
+			},
+			BackendPreference: []string{"busy"},
+		},
+	); err != nil {
+		t.Fatal("Unable to add storage class: ", err)
+	}
+	for i := 0; i < 4; i++ {
+		if _, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(
+			fmt.Sprintf("rebalance%d", i), 10, scName,
+			config.File)); err != nil {
+			t.Fatal("Unable to add volume:  ", err)
+		}
+	}
+
+	report := orchestrator.GetRebalanceReport()
+	if len(report.StorageClasses) != 1 {
+		t.Fatalf("Expected one storage class; got %d.",
+			len(report.StorageClasses))
+	}
+	balance := report.StorageClasses[0]
+	if len(balance.Backends) != 2 || balance.Backends[0].Volumes != 4 ||
+		balance.Backends[1].Volumes != 0 {
+		t.Errorf("Unexpected backend loads:  %v, %v", balance.Backends[0],
+			balance.Backends[1])
+	}
+	if balance.Imbalance != 2 {
+		t.Errorf("Expected imbalance 2; got %v.", balance.Imbalance)
+	}
+	// Two moves even out the backends.
+	if len(balance.Moves) != 2 {
+		t.Fatalf("Expected two moves; got %d.", len(balance.Moves))
+	}
+	for _, move := range balance.Moves {
+		if move.FromBackend != "busy" || move.ToBackend != "idle" ||
+			move.ToPool != "primary" {
+			t.Errorf("Unexpected move:  %v", move)
+		}
+	}
+	if orchestrator.GetRebalanceReport() != report {
+		t.Error("Expected the report to be kept until the next analysis.")
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestNVMeVolumes(t *testing.T) {
+	const backendName = "nvmeBackend"
+	orchestrator := getOrchestrator()
+	configJSON, err := fake.NewFakeStorageDriverConfigJSON(backendName,
+		config.Block, map[string]*fake.FakeStoragePool{
+			"nvme": &fake.FakeStoragePool{
+				Attrs: map[string]sa.Offer{
+					sa.NVMe:             sa.NewBoolOffer(true),
+					sa.TestingAttribute: sa.NewBoolOffer(true),
+				},
+				Bytes: 100 * 1024 * 1024 * 1024,
+			},
+			"iscsi": &fake.FakeStoragePool{
+				Attrs: map[string]sa.Offer{
+					sa.TestingAttribute: sa.NewBoolOffer(true),
+				},
+				Bytes: 100 * 1024 * 1024 * 1024,
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal("Unable to generate config JSON:  ", err)
+	}
+	var configMap map[string]interface{}
+	if err = json.Unmarshal([]byte(configJSON), &configMap); err != nil {
+		t.Fatal("Unable to parse config JSON:  ", err)
+	}
+	configMap["Portals"] = []string{"10.0.0.1"}
+	configBytes, _ := json.Marshal(configMap)
+	if _, err = orchestrator.AddStorageBackend(testCtx,
+		string(configBytes)); err != nil {
+		t.Fatal("Unable to add backend:  ", err)
+	}
+	// Only classes that request NVMe get the NVMe pool.
+	for name, attrs := range map[string]map[string]sa.Request{
+		"nvmeSC":  {sa.NVMe: sa.NewBoolRequest(true)},
+		"iscsiSC": {sa.TestingAttribute: sa.NewBoolRequest(true)},
+	} {
+		if _, err = orchestrator.AddStorageClass(&storage_class.Config{
+			Name:       name,
+			Attributes: attrs,
+		}); err != nil {
+			t.Fatal("Unable to add storage class:  ", err)
+		}
+	}
+
+	vol, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(
+		"nvmeVolume", 1, "nvmeSC", config.Block))
+	if err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+	if vol.Pool != "nvme" || vol.AccessInfo == nil ||
+		vol.AccessInfo.Protocol != storage.AccessNVMe {
+		t.Fatalf("Expected an NVMe volume; got pool %s, access %v.",
+			vol.Pool, vol.AccessInfo)
+	}
+	if vol.AccessInfo.NVMe.SubsystemNQN == "" ||
+		len(vol.AccessInfo.NVMe.Namespaces) != 1 ||
+		len(vol.Config.AccessInfo.IscsiPortals) != 0 {
+		t.Errorf("Unexpected NVMe access info:  %v, %v", vol.AccessInfo.NVMe,
+			vol.Config.AccessInfo)
+	}
+
+	vol, err = orchestrator.AddVolume(testCtx, generateVolumeConfig(
+		"iscsiVolume", 1, "iscsiSC", config.Block))
+	if err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+	if vol.Pool != "iscsi" || vol.AccessInfo == nil ||
+		vol.AccessInfo.Protocol != storage.AccessISCSI {
+		t.Errorf("Expected an iSCSI volume; got pool %s, access %v.",
+			vol.Pool, vol.AccessInfo)
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestReadOnlyVolumes(t *testing.T) {
+	const (
+		backendName = "readOnlyBackend"
+		scName      = "readOnlySC"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+
+	// ReadOnlyMany volumes are read-only whether or not they ask to be.
+	volConfig := generateVolumeConfig("roxVol", 1, scName, config.File)
+	volConfig.AccessMode = config.ReadOnlyMany
+	vol, err := orchestrator.AddVolume(testCtx, volConfig)
+	if err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+	if !vol.ReadOnly || !vol.Config.ReadOnly {
+		t.Error("ReadOnlyMany volume not read-only.")
+	}
+	backend := orchestrator.volumes["roxVol"].Backend
+	driver := backend.Driver.(*backend_fake.FakeStorageDriver)
+	if !driver.ReadOnly[vol.InternalName] {
+		t.Error("ReadOnlyMany volume not read-only on its backend.")
+	}
+
+	volConfig = generateVolumeConfig("rwoVol", 1, scName, config.File)
+	volConfig.AccessMode = config.ReadWriteOnce
+	if vol, err = orchestrator.AddVolume(testCtx, volConfig); err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+	if vol.ReadOnly || driver.ReadOnly[vol.InternalName] {
+		t.Error("ReadWriteOnce volume is read-only.")
+	}
+
+	volConfig = generateVolumeConfig("badVol", 1, scName, config.File)
+	volConfig.AccessMode = config.ReadWriteMany
+	volConfig.ReadOnly = true
+	if _, err = orchestrator.AddVolume(testCtx, volConfig); errors.GetType(
+		err) != errors.InvalidInput {
+		t.Errorf("Expected an invalid input error for a read-only "+
+			"ReadWriteMany volume; got %v.", err)
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestNamespacePolicies(t *testing.T) {
+	const (
+		backendName = "nsPolicyBackend"
+		scName      = "nsPolicySC"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	if _, err := orchestrator.AddStorageClass(&storage_class.Config{
+		Name: "otherSC",
+		Attributes: map[string]sa.Request{
+			sa.TestingAttribute: sa.NewBoolRequest(true),
+		},
+	}); err != nil {
+		t.Fatal("Unable to add storage class:  ", err)
+	}
+	if _, err := orchestrator.SetNamespacePolicy(&namespace_policy.Config{
+		Namespace:             "team-a",
+		DefaultStorageClass:   "missing",
+		AllowedStorageClasses: []string{scName},
+	}); errors.GetType(err) != errors.InvalidInput {
+		t.Errorf("Expected an invalid policy to be rejected; got %v.", err)
+	}
+	if _, err := orchestrator.SetNamespacePolicy(&namespace_policy.Config{
+		Namespace:             "team-a",
+		AllowedStorageClasses: []string{scName},
+		DefaultStorageClass:   scName,
+		MaxVolumeSize:         fmt.Sprintf("%d", 2*1024*1024*1024),
+	}); err != nil {
+		t.Fatal("Unable to set namespace policy:  ", err)
+	}
+
+	// Volumes that name no class get the namespace's default.
+	volConfig := generateVolumeConfig("defaultVol", 1, "", config.File)
+	volConfig.Namespace = "team-a"
+	vol, err := orchestrator.AddVolume(testCtx, volConfig)
+	if err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+	if vol.Config.StorageClass != scName {
+		t.Errorf("Expected storage class %s; got %s.", scName,
+			vol.Config.StorageClass)
+	}
+	for name, volConfig := range map[string]*storage.VolumeConfig{
+		"class": generateVolumeConfig("otherVol", 1, "otherSC", config.File),
+		"size":  generateVolumeConfig("bigVol", 3, scName, config.File),
+	} {
+		volConfig.Namespace = "team-a"
+		if _, err = orchestrator.AddVolume(testCtx,
+			volConfig); errors.GetType(err) != errors.InvalidInput {
+			t.Errorf("Expected a volume violating the %s policy to be "+
+				"rejected; got %v.", name, err)
+		}
+	}
+	// Other namespaces are unrestricted.
+	volConfig = generateVolumeConfig("otherVol", 3, "otherSC", config.File)
+	volConfig.Namespace = "team-b"
+	if _, err = orchestrator.AddVolume(testCtx, volConfig); err != nil {
+		t.Error("Unable to add volume in unrestricted namespace:  ", err)
+	}
+
+	// Policies survive bootstrapping.
+	newOrchestrator := getOrchestrator()
+	if policy := newOrchestrator.GetNamespacePolicy(
+		"team-a"); policy == nil || policy.DefaultStorageClass != scName {
+		t.Errorf("Namespace policy not bootstrapped; got %v.", policy)
+	}
+	if _, err = orchestrator.DeleteNamespacePolicy("team-a"); err != nil {
+		t.Error("Unable to delete namespace policy:  ", err)
+	}
+	if len(orchestrator.ListNamespacePolicies()) != 0 {
+		t.Error("Namespace policy not deleted.")
+	}
+	cleanup(t, orchestrator)
+}
+
+// spanRecorder is a tracing.Exporter that keeps the spans exported to it.
+type spanRecorder struct {
+	mutex sync.Mutex
+	spans []*tracing.Span
+}
+
+func (r *spanRecorder) Export(span *tracing.Span) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+func (r *spanRecorder) find(name string) *tracing.Span {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, span := range r.spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func TestTracing(t *testing.T) {
+	const (
+		backendName = "tracingBackend"
+		scName      = "tracingSC"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	recorder := &spanRecorder{}
+	tracing.SetExporter(recorder)
+	defer tracing.SetExporter(nil)
+
+	volConfig := generateVolumeConfig("tracedVol", 1, scName, config.File)
+	if _, err := orchestrator.AddVolume(testCtx, volConfig); err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+	root := recorder.find("AddVolume")
+	if root == nil {
+		t.Fatal("No span recorded for AddVolume.")
+	}
+	if root.ParentID != "" || root.Tags["volume"] != "tracedVol" ||
+		root.Tags["backend"] != backendName ||
+		root.Tags[tracing.ComponentTag] != tracing.Core {
+		t.Errorf("Unexpected AddVolume span:  %+v", root)
+	}
+	driverSpan := recorder.find("Volume creation")
+	if driverSpan == nil {
+		t.Fatal("No span recorded for the driver call.")
+	}
+	if driverSpan.TraceID != root.TraceID || driverSpan.ParentID != root.ID ||
+		driverSpan.Tags["backend"] != backendName ||
+		driverSpan.Tags[tracing.ComponentTag] != tracing.Driver {
+		t.Errorf("Unexpected driver span:  %+v", driverSpan)
+	}
+
+	if _, err := orchestrator.DeleteVolume(testCtx, "missingVol"); err == nil {
+		t.Error("Deleted a missing volume.")
+	}
+	if span := recorder.find("DeleteVolume"); span == nil ||
+		span.Tags[tracing.ErrorTag] == "" {
+		t.Errorf("Expected a failed DeleteVolume span; got %+v.", span)
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestResultWarnings(t *testing.T) {
+	const (
+		backendName = "warningsBackend"
+		scName      = "warningsSC"
+	)
+	orchestrator := getOrchestrator()
+	configJSON, err := fake.NewFakeStorageDriverConfigJSON(backendName,
+		config.File, map[string]*fake.FakeStoragePool{
+			"primary": &fake.FakeStoragePool{
+				Attrs: map[string]sa.Offer{
+					sa.Media: sa.NewStringOffer("hdd"),
+				},
+				Bytes: 100 * 1024 * 1024 * 1024,
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal("Unable to generate config JSON:  ", err)
+	}
+	backend, err := orchestrator.AddStorageBackend(testCtx, configJSON)
+	if err != nil {
+		t.Fatal("Unable to add backend:  ", err)
+	}
+	if len(backend.Warnings) != 1 || !strings.Contains(backend.Warnings[0],
+		"satisfies no storage classes") {
+		t.Errorf("Expected a warning that the backend satisfies no storage "+
+			"classes; got %v.", backend.Warnings)
+	}
+
+	if _, err = orchestrator.AddStorageClass(&storage_class.Config{
+		Name: scName,
+		Attributes: map[string]sa.Request{
+			sa.Media: sa.NewStringRequest("hdd"),
+		},
+	}); err != nil {
+		t.Fatal("Unable to add storage class:  ", err)
+	}
+	if backend, err = orchestrator.AddStorageBackend(testCtx,
+		configJSON); err != nil {
+		t.Fatal("Unable to update backend:  ", err)
+	}
+	if len(backend.Warnings) != 0 {
+		t.Errorf("Unexpected backend warnings:  %v", backend.Warnings)
+	}
+	vol, err := orchestrator.AddVolume(testCtx,
+		generateVolumeConfig("warningsVol", 1, scName, config.File))
+	if err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+	if len(vol.Warnings) != 0 {
+		t.Errorf("Unexpected volume warnings:  %v", vol.Warnings)
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestStorageClassAttributeValidation(t *testing.T) {
+	const backendName = "schemaBackend"
+	orchestrator := getOrchestrator()
+	addBackend(t, orchestrator, backendName)
+
+	typoConfig := &storage_class.Config{
+		Name: "typo",
+		Attributes: map[string]sa.Request{
+			sa.Media: sa.NewStringRequest("hybird"),
+		},
+	}
+	result, err := orchestrator.AddStorageClass(typoConfig)
+	if err != nil {
+		t.Fatal("Unable to add storage class:  ", err)
+	}
+	if len(result.Warnings) != 3 ||
+		!strings.Contains(result.Warnings[0], "Did you mean hybrid?") ||
+		!strings.Contains(result.Warnings[1], "No storage pools satisfy") ||
+		!strings.Contains(result.Warnings[2], "media=hybird") {
+		t.Errorf("Unexpected warnings:  %v", result.Warnings)
+	}
+
+	result, err = orchestrator.AddStorageClass(&storage_class.Config{
+		Name: "encrypted",
+		Attributes: map[string]sa.Request{
+			sa.Media:      sa.NewStringRequest(sa.HDD),
+			sa.Encryption: sa.NewBoolRequest(true),
+		},
+	})
+	if err != nil {
+		t.Fatal("Unable to add storage class:  ", err)
+	}
+	expected := []string{
+		"No storage pools satisfy storage class encrypted; its volumes " +
+			"can't be created until a backend's pools match it.",
+		"No storage pool offers encryption=true.  Only ontap-nas and " +
+			"ontap-san pools can.",
+	}
+	if !reflect.DeepEqual(result.Warnings, expected) {
+		t.Errorf("Expected warnings %v; got %v.", expected, result.Warnings)
+	}
+
+	orchestrator.SetStrictStorageClasses(true)
+	typoConfig.Name = "strictTypo"
+	if _, err = orchestrator.AddStorageClass(typoConfig); errors.GetType(
+		err) != errors.InvalidInput {
+		t.Errorf("Expected strict mode to reject the storage class; got %v.",
+			err)
+	}
+	if orchestrator.GetStorageClass("strictTypo") != nil {
+		t.Error("Rejected storage class was added.")
+	}
+	if _, err = orchestrator.UpdateStorageClass(&storage_class.Config{
+		Name: "encrypted",
+		Attributes: map[string]sa.Request{
+			sa.ProvisioningType: sa.NewStringRequest(sa.Thin),
+			sa.Media:            sa.NewStringRequest("flash"),
+		},
+	}); errors.GetType(err) != errors.InvalidInput {
+		t.Errorf("Expected strict mode to reject the update; got %v.", err)
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestAddStorageBackends(t *testing.T) {
+	orchestrator := getOrchestrator()
+	configs := make([]string, 0, 3)
+	for _, name := range []string{"batch1", "batch2"} {
+		configJSON, err := fake.NewFakeStorageDriverConfigJSON(name,
+			config.File, map[string]*fake.FakeStoragePool{
+				"primary": &fake.FakeStoragePool{
+					Attrs: map[string]sa.Offer{
+						sa.Media: sa.NewStringOffer("hdd"),
+					},
+					Bytes: 100 * 1024 * 1024 * 1024,
+				},
+			},
+		)
+		if err != nil {
+			t.Fatal("Unable to generate config JSON:  ", err)
+		}
+		configs = append(configs, configJSON)
+	}
+	configs = append(configs[:1], `{"storageDriverName": "missing"}`,
+		configs[1])
+
+	result := orchestrator.AddStorageBackends(testCtx, configs)
+	if len(result.Backends) != 3 || result.Failed != 1 {
+		t.Fatalf("Expected one of three backends to fail; got %d of %d.",
+			result.Failed, len(result.Backends))
+	}
+	for i, name := range []string{"batch1", "", "batch2"} {
+		item := result.Backends[i]
+		if item.Index != i {
+			t.Errorf("Backend %d reported at index %d.", i, item.Index)
+		}
+		if name == "" {
+			if item.Err == nil || item.Error == "" || item.Backend != nil {
+				t.Errorf("Expected backend %d to fail.", i)
+			}
+			continue
+		}
+		if item.Err != nil || item.Backend == nil ||
+			item.Backend.Name != name {
+			t.Errorf("Expected backend %d to be %s; got %+v.", i, name,
+				item)
+		}
+		if orchestrator.GetBackend(name) == nil {
+			t.Errorf("Backend %s wasn't added.", name)
+		}
+	}
+	if err := result.AllFailed(); err != nil {
+		t.Error("Unexpected error:  ", err)
+	}
+
+	failed := orchestrator.AddStorageBackends(testCtx, configs[1:2])
+	if failed.AllFailed() == nil {
+		t.Error("Expected an error when no backends were added.")
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestDeleteVolumes(t *testing.T) {
+	const (
+		backendName = "bulkDeleteBackend"
+		scName      = "bulkDeleteSC"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	for i, app := range []string{"web", "web", "db", "web"} {
+		volConfig := generateVolumeConfig(fmt.Sprintf("bulk%d", i), 1,
+			scName, config.File)
+		volConfig.Metadata = map[string]string{"app": app}
+		if _, err := orchestrator.AddVolume(testCtx, volConfig); err != nil {
+			t.Fatal("Unable to create volume:  ", err)
+		}
+	}
+
+	for _, deleteConfig := range []*BulkDeleteConfig{
+		{},
+		{Volumes: []string{"bulk0"}, Metadata: map[string]string{"a": "b"}},
+		{Volumes: []string{"bulk0", "bulk0"}},
+	} {
+		if _, err := orchestrator.DeleteVolumes(testCtx,
+			deleteConfig); errors.GetType(err) != errors.InvalidInput {
+			t.Errorf("Expected %+v to be rejected; got %v.", deleteConfig,
+				err)
+		}
+	}
+
+	result, err := orchestrator.DeleteVolumes(testCtx, &BulkDeleteConfig{
+		Metadata: map[string]string{"app": "web"},
+	})
+	if err != nil {
+		t.Fatal("Unable to delete volumes:  ", err)
+	}
+	if result.Failed != 0 || len(result.Volumes) != 3 ||
+		result.Volumes[0].Volume != "bulk0" ||
+		result.Volumes[2].Volume != "bulk3" {
+		t.Errorf("Unexpected result %+v.", result)
+	}
+	for _, name := range []string{"bulk0", "bulk1", "bulk3"} {
+		if orchestrator.GetVolume(name) != nil {
+			t.Errorf("Volume %s wasn't deleted.", name)
+		}
+	}
+
+	result, err = orchestrator.DeleteVolumes(testCtx, &BulkDeleteConfig{
+		Volumes: []string{"missing", "bulk2"},
+	})
+	if err != nil {
+		t.Fatal("Unable to delete volumes:  ", err)
+	}
+	if result.Failed != 1 || result.Volumes[0].Found ||
+		!errors.IsNotFound(result.Volumes[0].Err) ||
+		!result.Volumes[1].Found || result.Volumes[1].Err != nil {
+		t.Errorf("Unexpected result %+v.", result)
+	}
+	if orchestrator.GetVolume("bulk2") != nil {
+		t.Error("Volume bulk2 wasn't deleted.")
+	}
+	if err = result.AllFailed(); err != nil {
+		t.Error("Unexpected error:  ", err)
+	}
+	cleanup(t, orchestrator)
+}
+
+// TestDeleteVolumesConcurrently deletes more volumes than there are bulk
+// delete workers, and checks that volumes being deleted are reserved and
+// that failed deletions leave their transactions.
+func TestDeleteVolumesConcurrently(t *testing.T) {
+	const (
+		backendName = "bulkConcurrentBackend"
+		scName      = "bulkConcurrentSC"
+	)
+	defer faults.DisarmAll()
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	names := make([]string, 0, bulkDeleteWorkers+4)
+	for i := 0; i < bulkDeleteWorkers+4; i++ {
+		name := fmt.Sprintf("bulkConcurrent%d", i)
+		if _, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(
+			name, 1, scName, config.File)); err != nil {
+			t.Fatal("Unable to create volume:  ", err)
+		}
+		names = append(names, name)
+	}
+
+	orchestrator.mutex.Lock()
+	orchestrator.deletingVolumes[names[0]] = true
+	orchestrator.mutex.Unlock()
+	if _, err := orchestrator.RenameVolume(names[0],
+		"bulkRenamed"); errors.GetType(err) != errors.Conflict {
+		t.Error("Expected renaming a volume being deleted to conflict; "+
+			"got ", err)
+	}
+	result, err := orchestrator.DeleteVolumes(testCtx, &BulkDeleteConfig{
+		Volumes: names[:1],
+	})
+	if err != nil {
+		t.Fatal("Unable to delete volumes:  ", err)
+	}
+	if !result.Volumes[0].Found ||
+		errors.GetType(result.Volumes[0].Err) != errors.Conflict {
+		t.Errorf("Expected a volume being deleted to conflict; got %+v.",
+			result.Volumes[0])
+	}
+	orchestrator.mutex.Lock()
+	delete(orchestrator.deletingVolumes, names[0])
+	orchestrator.mutex.Unlock()
+
+	if err = faults.Arm(faults.VolumeDeletedFromBackend,
+		faults.ModeError); err != nil {
+		t.Fatal("Unable to arm failure point:  ", err)
+	}
+	result, err = orchestrator.DeleteVolumes(testCtx, &BulkDeleteConfig{
+		Volumes: names[:2],
+	})
+	faults.DisarmAll()
+	if err != nil {
+		t.Fatal("Unable to delete volumes:  ", err)
+	}
+	if result.Failed != 2 || result.AllFailed() == nil {
+		t.Errorf("Expected both deletions to fail; got %+v.", result)
+	}
+	if n := countVolumeTransactions(t, orchestrator); n != 2 {
+		t.Errorf("Expected 2 deletion transactions left for retry; got %d.",
+			n)
+	}
+
+	result, err = orchestrator.DeleteVolumes(testCtx, &BulkDeleteConfig{
+		Volumes: names,
+	})
+	if err != nil {
+		t.Fatal("Unable to delete volumes:  ", err)
+	}
+	if result.Failed != 0 || len(result.Volumes) != len(names) {
+		t.Errorf("Unexpected result %+v.", result)
+	}
+	for i, name := range names {
+		if result.Volumes[i].Volume != name {
+			t.Errorf("Expected result %d to be for %s; got %s.", i, name,
+				result.Volumes[i].Volume)
+		}
+		if orchestrator.GetVolume(name) != nil {
+			t.Errorf("Volume %s wasn't deleted.", name)
+		}
+	}
+	if n := countVolumeTransactions(t, orchestrator); n != 0 {
+		t.Errorf("Found %d volume transactions after deletion.", n)
+	}
+	if len(orchestrator.deletingVolumes) != 0 {
+		t.Errorf("Volumes still reserved after deletion:  %v",
+			orchestrator.deletingVolumes)
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestProtectStorageClasses(t *testing.T) {
+	const (
+		backendName = "protectBackend"
+		scName      = "protectSC"
+		forcedSC    = "protectForcedSC"
+		bootstrapSC = "protectBootstrapSC"
+	)
+	orchestrator := getOrchestrator()
+	orchestrator.SetProtectStorageClasses(true)
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	for _, name := range []string{"protect0", "protect1"} {
+		if _, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(
+			name, 1, scName, config.File)); err != nil {
+			t.Fatal("Unable to create volume:  ", err)
+		}
+	}
+
+	found, err := orchestrator.DeleteStorageClass(scName)
+	if !found || errors.GetType(err) != errors.Conflict {
+		t.Fatalf("Expected a conflict deleting a class in use; got %v.", err)
+	}
+	if sc := orchestrator.GetStorageClass(scName); sc == nil ||
+		!sc.PendingDelete {
+		t.Fatal("Storage class wasn't marked pending deletion.")
+	}
+	if _, err = orchestrator.AddVolume(testCtx, generateVolumeConfig(
+		"protect2", 1, scName, config.File)); errors.GetType(err) !=
+		errors.Conflict {
+		t.Error("Expected a conflict creating a volume in a class pending "+
+			"deletion; got ", err)
+	}
+	if _, err = orchestrator.DeleteVolume(testCtx, "protect0"); err != nil {
+		t.Fatal("Unable to delete volume:  ", err)
+	}
+	if orchestrator.GetStorageClass(scName) == nil {
+		t.Fatal("Storage class was deleted while a volume still used it.")
+	}
+
+	restarted := NewTridentOrchestrator(orchestrator.storeClient)
+	if err = restarted.Bootstrap(); err != nil {
+		t.Fatal("Unable to bootstrap:  ", err)
+	}
+	if sc := restarted.GetStorageClass(scName); sc == nil ||
+		!sc.PendingDelete {
+		t.Error("Pending deletion wasn't persisted.")
+	}
+
+	if _, err = orchestrator.DeleteVolume(testCtx, "protect1"); err != nil {
+		t.Fatal("Unable to delete volume:  ", err)
+	}
+	if orchestrator.GetStorageClass(scName) != nil {
+		t.Error("Storage class wasn't deleted with its last volume.")
+	}
+
+	// A forced delete ignores volumes.
+	addBackendStorageClass(t, orchestrator, backendName+"2", forcedSC)
+	if _, err = orchestrator.AddVolume(testCtx, generateVolumeConfig(
+		"protect2", 1, forcedSC, config.File)); err != nil {
+		t.Fatal("Unable to create volume:  ", err)
+	}
+	if found, err = orchestrator.ForceDeleteStorageClass(
+		forcedSC); !found || err != nil {
+		t.Error("Unable to force deletion of a class in use:  ", err)
+	}
+
+	// A class whose last volume went away while Trident wasn't running is
+	// deleted at bootstrap.
+	addBackendStorageClass(t, orchestrator, backendName+"3", bootstrapSC)
+	if _, err = orchestrator.AddVolume(testCtx, generateVolumeConfig(
+		"protect3", 1, bootstrapSC, config.File)); err != nil {
+		t.Fatal("Unable to create volume:  ", err)
+	}
+	if _, err = orchestrator.DeleteStorageClass(bootstrapSC); errors.GetType(
+		err) != errors.Conflict {
+		t.Fatal("Expected a conflict deleting a class in use; got ", err)
+	}
+	if err = orchestrator.storeClient.DeleteVolumeIgnoreNotFound(
+		orchestrator.volumes["protect3"]); err != nil {
+		t.Fatal("Unable to delete volume from the store:  ", err)
+	}
+	restarted = NewTridentOrchestrator(orchestrator.storeClient)
+	if err = restarted.Bootstrap(); err != nil {
+		t.Fatal("Unable to bootstrap:  ", err)
+	}
+	if restarted.GetStorageClass(bootstrapSC) != nil {
+		t.Error("Storage class pending deletion without volumes wasn't " +
+			"deleted at bootstrap.")
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestOfflineBackendWithReport(t *testing.T) {
+	const (
+		backendName = "offlineReportBackend"
+		scName      = "offlineReportSC"
+		volumeName  = "offlineReportVolume"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	if _, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(
+		volumeName, 1, scName, config.File)); err != nil {
+		t.Fatal("Unable to create volume:  ", err)
+	}
+
+	if _, err := orchestrator.PreviewOfflineBackend(
+		"missing"); !errors.IsNotFound(err) {
+		t.Error("Expected a missing backend not to be found; got ", err)
+	}
+	expected := &OfflineBackendReport{
+		Backend:             backendName,
+		StorageClasses:      []string{scName},
+		EmptyStorageClasses: []string{scName},
+		Volumes:             []string{volumeName},
+	}
+	report, err := orchestrator.PreviewOfflineBackend(backendName)
+	if err != nil {
+		t.Fatal("Unable to preview offlining the backend:  ", err)
+	}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("Expected report %+v; got %+v.", expected, report)
+	}
+	if !orchestrator.GetBackend(backendName).Online {
+		t.Error("Previewing offlined the backend.")
+	}
+
+	_, err = orchestrator.OfflineBackendWithReport(backendName, false)
+	if errors.GetType(err) != errors.Conflict {
+		t.Fatal("Expected a conflict stranding volumes; got ", err)
+	}
+	if !reflect.DeepEqual(errors.GetDetails(err), expected) {
+		t.Errorf("Expected the conflict to report %+v; got %+v.", expected,
+			errors.GetDetails(err))
+	}
+	if !orchestrator.GetBackend(backendName).Online {
+		t.Error("Backend was offlined without confirmation.")
+	}
+
+	report, err = orchestrator.OfflineBackendWithReport(backendName, true)
+	if err != nil {
+		t.Fatal("Unable to offline the backend:  ", err)
+	}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("Expected report %+v; got %+v.", expected, report)
+	}
+	if b := orchestrator.GetBackend(backendName); b == nil || b.Online {
+		t.Error("Backend wasn't offlined with confirmation.")
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestOnlineBackend(t *testing.T) {
+	const (
+		backendName = "onlineBackend"
+		scName      = "onlineSC"
+		volumeName  = "onlineVolume"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	if _, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(
+		volumeName, 1, scName, config.File)); err != nil {
+		t.Fatal("Unable to create volume:  ", err)
+	}
+	if _, err := orchestrator.OnlineBackend(
+		"missing"); !errors.IsNotFound(err) {
+		t.Error("Expected a missing backend not to be found; got ", err)
+	}
+	if _, err := orchestrator.OfflineBackend(backendName); err != nil {
+		t.Fatal("Unable to offline backend:  ", err)
+	}
+	if pools := orchestrator.GetStorageClass(scName).StoragePools; len(
+		pools) != 0 {
+		t.Fatalf("Offline backend's pools still satisfy the class:  %v",
+			pools)
+	}
+
+	result, err := orchestrator.OnlineBackend(backendName)
+	if err != nil {
+		t.Fatal("Unable to bring backend online:  ", err)
+	}
+	if !result.Online {
+		t.Error("Backend wasn't reported online.")
+	}
+	matched := len(
+		orchestrator.GetStorageClass(scName).StoragePools[backendName])
+	if matched == 0 {
+		t.Error("Backend's pools weren't added back to the storage class.")
+	}
+	if _, err = orchestrator.AddVolume(testCtx, generateVolumeConfig(
+		volumeName+"2", 1, scName, config.File)); err != nil {
+		t.Error("Unable to create a volume on the online backend:  ", err)
+	}
+	persistent, err := orchestrator.storeClient.GetBackend(backendName)
+	if err != nil {
+		t.Fatal("Unable to retrieve backend from the store:  ", err)
+	}
+	if !persistent.Online {
+		t.Error("Backend wasn't stored online.")
+	}
+
+	// Bringing an online backend online changes nothing.
+	if _, err = orchestrator.OnlineBackend(backendName); err != nil {
+		t.Error("Unable to bring an online backend online:  ", err)
+	}
+	if pools := orchestrator.GetStorageClass(scName).StoragePools; len(
+		pools[backendName]) != matched {
+		t.Errorf("Storage class pools were duplicated:  %v", pools)
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestUpdateStorageBackend(t *testing.T) {
+	const (
+		backendName = "updateConfigBackend"
+		scName      = "updateConfigSC"
+		volumeName  = "updateConfigVolume"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	if _, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(
+		volumeName, 1, scName, config.File)); err != nil {
+		t.Fatal("Unable to create volume:  ", err)
+	}
+	if _, err := orchestrator.UpdateStorageBackend(testCtx, "missing",
+		`{}`); !errors.IsNotFound(err) {
+		t.Error("Expected a missing backend not to be found; got ", err)
+	}
+	for _, update := range []string{
+		`[]`,
+		`{"backendName": "renamed"}`,
+	} {
+		if _, err := orchestrator.UpdateStorageBackend(testCtx, backendName,
+			update); errors.GetType(err) != errors.InvalidInput {
+			t.Errorf("Expected update %s to be rejected; got %v.", update,
+				err)
+		}
+	}
+
+	if _, err := orchestrator.OfflineBackend(backendName); err != nil {
+		t.Fatal("Unable to offline backend:  ", err)
+	}
+	if _, err := orchestrator.UpdateStorageBackend(testCtx, backendName,
+		`{"maxVolumes": 5}`); err != nil {
+		t.Fatal("Unable to update backend:  ", err)
+	}
+	orchestrator.mutex.Lock()
+	backend := orchestrator.backends[backendName]
+	if backend.Online {
+		t.Error("Offline backend was brought online by an update.")
+	}
+	if backend.Limits.MaxVolumes != 5 {
+		t.Errorf("Expected maxVolumes 5; got %d.", backend.Limits.MaxVolumes)
+	}
+	if backend.Storage["primary"].Volumes[volumeName] == nil {
+		t.Error("Volume wasn't moved to the updated backend.")
+	}
+	orchestrator.mutex.Unlock()
+	persistent, err := orchestrator.storeClient.GetBackend(backendName)
+	if err != nil {
+		t.Fatal("Unable to retrieve backend from the store:  ", err)
+	}
+	if persistent.Online || persistent.Limits == nil ||
+		persistent.Limits.MaxVolumes != 5 {
+		t.Errorf("Backend wasn't stored as updated:  %+v", persistent)
+	}
+
+	// Fields left out of an update keep their values.
+	if _, err = orchestrator.UpdateStorageBackend(testCtx, backendName,
+		`{"maxSessions": 2}`); err != nil {
+		t.Fatal("Unable to update backend:  ", err)
+	}
+	orchestrator.mutex.Lock()
+	limits := orchestrator.backends[backendName].Limits
+	orchestrator.mutex.Unlock()
+	if limits.MaxVolumes != 5 || limits.MaxSessions != 2 {
+		t.Errorf("Expected both limits to be kept; got %+v.", limits)
+	}
+	cleanup(t, orchestrator)
+}
+
+func TestDiffStorageBackend(t *testing.T) {
+	const (
+		backendName = "diffBackend"
+		scName      = "diffSC"
+		volumeName  = "diffVolume"
+	)
+	orchestrator := getOrchestrator()
+	addBackendStorageClass(t, orchestrator, backendName, scName)
+	if _, err := orchestrator.AddVolume(testCtx, generateVolumeConfig(
+		volumeName, 1, scName, config.File)); err != nil {
+		t.Fatal("Unable to create volume:  ", err)
+	}
+	candidate := func(name string) string {
+		configJSON, err := fake.NewFakeStorageDriverConfigJSON(name,
+			config.File, map[string]*fake.FakeStoragePool{
+				"primary": &fake.FakeStoragePool{
+					Attrs: map[string]sa.Offer{
+						sa.Media: sa.NewStringOffer("ssd"),
+						sa.ProvisioningType: sa.NewStringOffer("thick",
+							"thin"),
+						sa.TestingAttribute: sa.NewBoolOffer(true),
+					},
+					Bytes: 100 * 1024 * 1024 * 1024,
+				},
+				"secondary": &fake.FakeStoragePool{
+					Attrs: map[string]sa.Offer{
+						sa.Media:            sa.NewStringOffer("hdd"),
+						sa.ProvisioningType: sa.NewStringOffer("thick"),
+						sa.TestingAttribute: sa.NewBoolOffer(true),
+					},
+					Bytes: 100 * 1024 * 1024 * 1024,
+				},
+			})
+		if err != nil {
+			t.Fatal("Unable to create mock driver config JSON:  ", err)
+		}
+		return configJSON
+	}
+
+	if _, err := orchestrator.DiffStorageBackend(testCtx, "missing",
+		candidate("missing")); !errors.IsNotFound(err) {
+		t.Error("Expected a missing backend not to be found; got ", err)
+	}
+	if _, err := orchestrator.DiffStorageBackend(testCtx, backendName,
+		candidate("other")); errors.GetType(err) != errors.InvalidInput {
+		t.Error("Expected a config for another backend to be rejected; "+
+			"got ", err)
+	}
+
+	diff, err := orchestrator.DiffStorageBackend(testCtx, backendName,
+		candidate(backendName))
+	if err != nil {
+		t.Fatal("Unable to diff backend:  ", err)
+	}
+	if !reflect.DeepEqual(diff.PoolsAdded, []string{"secondary"}) ||
+		len(diff.PoolsRemoved) != 0 {
+		t.Errorf("Expected only secondary to be added; got %v and %v.",
+			diff.PoolsAdded, diff.PoolsRemoved)
+	}
+	if len(diff.PoolsChanged) != 1 || diff.PoolsChanged[0].Pool != "primary" ||
+		len(diff.PoolsChanged[0].Attributes) != 1 ||
+		diff.PoolsChanged[0].Attributes[0].Attribute != sa.Media {
+		t.Errorf("Expected only primary's media to change; got %+v.",
+			diff.PoolsChanged)
+	}
+	expected := []*StorageClassDiff{{
+		StorageClass: scName,
+		PoolsAdded:   []string{"secondary"},
+		PoolsRemoved: []string{"primary"},
+	}}
+	if !reflect.DeepEqual(diff.StorageClasses, expected) {
+		t.Errorf("Expected %s to move to secondary.", scName)
+		for _, scDiff := range diff.StorageClasses {
+			t.Logf("Got %+v.", *scDiff)
+		}
+	}
+	// The volume's storage class would no longer match its pool.
+	if diff.Rejected == "" {
+		t.Error("Expected the update to be reported as rejected.")
+	}
+
+	orchestrator.mutex.Lock()
+	if _, ok := orchestrator.backends[backendName].Storage["secondary"]; ok {
+		t.Error("Backend was changed by a diff.")
+	}
+	orchestrator.mutex.Unlock()
+	cleanup(t, orchestrator)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math/rand"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"testing"
@@ -13,9 +14,17 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 	dvp "github.com/netapp/netappdvp/storage_drivers"
+	"golang.org/x/net/context"
 
+	"github.com/netapp/trident/app_template"
 	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/errors"
 	"github.com/netapp/trident/frontend"
+	"github.com/netapp/trident/hooks"
+	"github.com/netapp/trident/logging"
+	"github.com/netapp/trident/namespace_policy"
+	"github.com/netapp/trident/persistent_store"
+	"github.com/netapp/trident/snapshot_policy"
 	"github.com/netapp/trident/storage"
 	"github.com/netapp/trident/storage/ontap"
 	"github.com/netapp/trident/storage_class"
@@ -51,23 +60,109 @@ type MockOrchestrator struct {
 	storageClasses map[string]*storage_class.StorageClass
 	volumes        map[string]*storage.Volume
 	mutex          *sync.Mutex
+	events         *eventBus
+	provisioning   *provisioningNotifier
+	hooks          map[string]*hooks.Config
+	policies       map[string]*snapshot_policy.Config
+	loggingConfig  *logging.Config
+	retries        map[string]*persistent_store.ProvisioningRetry
+	frontends      map[string]frontend.FrontendPlugin
+	groups         map[string]*persistent_store.VolumeGroup
+	templates      map[string]*app_template.Config
+	placementRand  *rand.Rand
+	nsPolicies     map[string]*namespace_policy.Config
 }
 
 func (m *MockOrchestrator) Bootstrap() error {
 	return nil
 }
 
+// IsBootstrapped returns true, since the mock orchestrator has no state to
+// bootstrap.
+func (m *MockOrchestrator) IsBootstrapped() bool {
+	return true
+}
+
+func (m *MockOrchestrator) GetHealth() *HealthStatus {
+	return &HealthStatus{
+		Ready:          true,
+		Bootstrapped:   true,
+		StoreReachable: true,
+		CheckedAt:      time.Now().UTC(),
+	}
+}
+
+func (m *MockOrchestrator) ListQuarantinedRecords() (
+	[]*persistent_store.QuarantinedRecord, error,
+) {
+	return make([]*persistent_store.QuarantinedRecord, 0), nil
+}
+
+func (m *MockOrchestrator) ListPendingTransactions() (
+	[]*PendingTransaction, error,
+) {
+	return make([]*PendingTransaction, 0), nil
+}
+
+func (m *MockOrchestrator) ListZombieVolumes() (
+	[]*persistent_store.ZombieVolume, error,
+) {
+	return make([]*persistent_store.ZombieVolume, 0), nil
+}
+
+// GetLoggingConfig and SetLoggingConfig leave the process's logging alone.
+func (m *MockOrchestrator) GetLoggingConfig() *logging.Config {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	c := *m.loggingConfig
+	return &c
+}
+
+func (m *MockOrchestrator) SetLoggingConfig(c *logging.Config) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	loggingConfig := *c
+	m.loggingConfig = &loggingConfig
+	return nil
+}
+
 func (m *MockOrchestrator) AddFrontend(f frontend.FrontendPlugin) {
-	// NOP for the time being, since users of MockOrchestrator don't need this
+	m.frontends[f.GetName()] = f
+}
+
+func (m *MockOrchestrator) GetFrontend(
+	name string,
+) (frontend.FrontendPlugin, error) {
+	f, ok := m.frontends[name]
+	if !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Frontend %s not found.", name)
+	}
+	return f, nil
 }
 
 func (o *MockOrchestrator) GetVersion() string {
 	return config.OrchestratorVersion
 }
 
+// GetVersionInfo reports the mock orchestrator's frontends, but none of its
+// mock backends' drivers.
+func (m *MockOrchestrator) GetVersionInfo() *VersionInfo {
+	info := newVersionInfo()
+	for name := range m.frontends {
+		info.Frontends[name] = config.OrchestratorVersion
+	}
+	return info
+}
+
 // TODO:  Add extra methods to add backends without needing to provide a valid,
 // stringified JSON config.
-func (m *MockOrchestrator) AddStorageBackend(configJSON string) (*storage.StorageBackendExternal, error) {
+func (m *MockOrchestrator) AddStorageBackend(
+	ctx context.Context, configJSON string,
+) (*BackendResult, error) {
 	// We need to do this to determine if the backend is NFS or not.
 	backend := &storage.StorageBackend{
 		Name:    fmt.Sprintf("mock-%d", len(m.backends)),
@@ -80,7 +175,72 @@ func (m *MockOrchestrator) AddStorageBackend(configJSON string) (*storage.Storag
 	defer m.mutex.Unlock()
 	m.backends[backend.Name] = backend
 	m.mockBackends[backend.Name] = mock
-	return backend.ConstructExternal(), nil
+	return &BackendResult{StorageBackendExternal: backend.ConstructExternal()},
+		nil
+}
+
+func (m *MockOrchestrator) AddStorageBackends(
+	ctx context.Context, configs []string,
+) *BackendsResult {
+	result := &BackendsResult{
+		Backends: make([]*BackendItemResult, 0, len(configs)),
+	}
+	for _, configJSON := range configs {
+		result.add(m.AddStorageBackend(ctx, configJSON))
+	}
+	return result
+}
+
+func (m *MockOrchestrator) UpdateStorageBackend(
+	ctx context.Context, backendName, configJSON string,
+) (*BackendResult, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	backend, ok := m.backends[backendName]
+	if !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Backend %s not found.", backendName)
+	}
+	return &BackendResult{StorageBackendExternal: backend.ConstructExternal()},
+		nil
+}
+
+// ValidateStorageBackend reports the backend AddStorageBackend would add,
+// without adding it.
+func (m *MockOrchestrator) ValidateStorageBackend(
+	ctx context.Context, configJSON string,
+) (*BackendValidation, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	backend := &storage.StorageBackend{
+		Name:    fmt.Sprintf("mock-%d", len(m.backends)),
+		Driver:  nil,
+		Online:  true,
+		Storage: make(map[string]*storage.StoragePool),
+	}
+	return &BackendValidation{
+		Backend:        backend.ConstructExternal(),
+		StorageClasses: make([]string, 0),
+	}, nil
+}
+
+// DiffStorageBackend reports no changes to an existing backend.
+func (m *MockOrchestrator) DiffStorageBackend(
+	ctx context.Context, backendName, configJSON string,
+) (*BackendDiff, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, ok := m.backends[backendName]; !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Backend %s not found.", backendName)
+	}
+	return &BackendDiff{
+		Backend:        backendName,
+		PoolsAdded:     make([]string, 0),
+		PoolsRemoved:   make([]string, 0),
+		PoolsChanged:   make([]*PoolDiff, 0),
+		StorageClasses: make([]*StorageClassDiff, 0),
+	}, nil
 }
 
 // Convenience method for test harnesses to avoid having to create a
@@ -145,19 +305,161 @@ func (m *MockOrchestrator) OfflineBackend(backend string) (bool, error) {
 	return false, nil
 }
 
-func (m *MockOrchestrator) AddVolume(volumeConfig *storage.VolumeConfig) (*storage.VolumeExternal, error) {
+func (m *MockOrchestrator) PreviewOfflineBackend(
+	backend string,
+) (*OfflineBackendReport, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, ok := m.backends[backend]; !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Backend %s not found.", backend)
+	}
+	return &OfflineBackendReport{
+		Backend:             backend,
+		StorageClasses:      make([]string, 0),
+		EmptyStorageClasses: make([]string, 0),
+		Volumes:             make([]string, 0),
+		Deleted:             true,
+	}, nil
+}
+
+func (m *MockOrchestrator) OnlineBackend(
+	backend string,
+) (*BackendResult, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	b, ok := m.backends[backend]
+	if !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Backend %s not found.", backend)
+	}
+	b.Online = true
+	return &BackendResult{StorageBackendExternal: b.ConstructExternal()}, nil
+}
+
+func (m *MockOrchestrator) OfflineBackendWithReport(
+	backend string, confirm bool,
+) (*OfflineBackendReport, error) {
+	return m.PreviewOfflineBackend(backend)
+}
+
+func (m *MockOrchestrator) GenerateStorageClasses(
+	backendName string,
+) ([]*storage_class.Recommendation, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	backend, ok := m.backends[backendName]
+	if !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Backend %s not found.", backendName)
+	}
+	return storage_class.Recommend(backend), nil
+}
+
+// ApplyState adds every storage class and backend in state, reporting them
+// as added; nothing is updated or removed.
+func (m *MockOrchestrator) ApplyState(
+	ctx context.Context, state *DesiredState,
+) (*StateReport, error) {
+	report := &StateReport{
+		Backends:       newStateChanges(),
+		StorageClasses: newStateChanges(),
+	}
+	for _, scConfig := range state.StorageClasses {
+		m.AddStorageClass(scConfig)
+		report.StorageClasses.Added = append(report.StorageClasses.Added,
+			scConfig.Name)
+	}
+	for range state.Backends {
+		backend, _ := m.AddStorageBackend(ctx, "")
+		report.Backends.Added = append(report.Backends.Added, backend.Name)
+	}
+	return report, nil
+}
+
+func (m *MockOrchestrator) RenameBackend(
+	backendName, newName string,
+) (*storage.StorageBackendExternal, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	backend, ok := m.backends[backendName]
+	if !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Backend %s not found.", backendName)
+	}
+	if backendName == newName {
+		return backend.ConstructExternal(), nil
+	}
+	if _, ok = m.backends[newName]; ok {
+		return nil, errors.Errorf(errors.AlreadyExists,
+			"Backend %s already exists.", newName)
+	}
+	delete(m.backends, backendName)
+	backend.Name = newName
+	m.backends[newName] = backend
+	m.mockBackends[newName] = m.mockBackends[backendName]
+	delete(m.mockBackends, backendName)
+	return backend.ConstructExternal(), nil
+}
+
+// ExplainPlacement reports only which mock backends serve the volume's
+// protocol.
+func (m *MockOrchestrator) ExplainPlacement(
+	volumeConfig *storage.VolumeConfig,
+) (*PlacementReport, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.storageClasses[volumeConfig.StorageClass]; !ok {
+		return nil, errors.Errorf(errors.InvalidInput,
+			"Unknown storage class:  %s", volumeConfig.StorageClass)
+	}
+	names := make([]string, 0, len(m.mockBackends))
+	for name := range m.mockBackends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	report := &PlacementReport{
+		Volume:       volumeConfig.Name,
+		StorageClass: volumeConfig.StorageClass,
+		Pools:        make([]*PoolPlacement, 0, len(names)),
+	}
+	for _, name := range names {
+		placement := &PoolPlacement{Backend: name}
+		protocol := m.mockBackends[name].protocol
+		if volumeConfig.Protocol != config.ProtocolAny &&
+			protocol != volumeConfig.Protocol {
+			placement.Reasons = []string{fmt.Sprintf("Backend provides %s "+
+				"volumes, not %s.", protocol, volumeConfig.Protocol)}
+		}
+		report.Pools = append(report.Pools, placement)
+	}
+	return report, nil
+}
+
+func (m *MockOrchestrator) AddVolume(
+	ctx context.Context, volumeConfig *storage.VolumeConfig,
+) (*VolumeResult, error) {
 	var mockBackends map[string]*mockBackend
 
+	if policy, ok := m.nsPolicies[volumeConfig.Namespace]; ok &&
+		volumeConfig.Namespace != "" {
+		if err := policy.Apply(volumeConfig); err != nil {
+			return nil, errors.Errorf(errors.InvalidInput, "%v", err)
+		}
+	}
+
 	// Don't bother with actually getting the backends from the storage class;
 	// to test that logic, use an instance of the real orchestrator.  Perform
 	// a sanity check on the storage class, though, to catch odd behavior,
 	// like passing in something not intended.
 	if _, ok := m.storageClasses[volumeConfig.StorageClass]; !ok {
-		return nil, fmt.Errorf("Storage class %s not found for volume %s",
-			volumeConfig.StorageClass, volumeConfig.Name)
+		return nil, errors.Errorf(errors.InvalidInput, "Storage class %s not "+
+			"found for volume %s", volumeConfig.StorageClass, volumeConfig.Name)
 	}
 
-	rand.Seed(time.Now().UnixNano())
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 	if volumeConfig.Protocol == config.ProtocolAny {
@@ -177,14 +479,20 @@ func (m *MockOrchestrator) AddVolume(volumeConfig *storage.VolumeConfig) (*stora
 		}
 	}
 	if _, ok := m.volumes[volumeConfig.Name]; ok {
-		return nil, fmt.Errorf("Volume %s already exists.", volumeConfig.Name)
+		return nil, errors.Errorf(errors.AlreadyExists,
+			"Volume %s already exists.", volumeConfig.Name)
 	}
 	if len(mockBackends) == 0 {
 		log.Panic("No mock backends available; something is wrong.")
 	}
-	index := rand.Intn(len(mockBackends))
+	m.provisioning.notify(ProvisioningStarted, volumeConfig.Name,
+		volumeConfig.StorageClass, "", "", "Provisioning volume.")
+	index := m.placementRand.Intn(len(mockBackends))
 	backendName := reflect.ValueOf(mockBackends).MapKeys()[index].String()
 	mockBackend := mockBackends[backendName]
+	m.provisioning.notify(ProvisioningPoolSelected, volumeConfig.Name,
+		volumeConfig.StorageClass, backendName, "",
+		fmt.Sprintf("Creating volume on backend %s.", backendName))
 	// Use something other than the volume config name itself.
 	volumeConfig.InternalName = GetFakeInternalName(volumeConfig.Name)
 	if mockBackend.protocol == config.File {
@@ -199,7 +507,42 @@ func (m *MockOrchestrator) AddVolume(volumeConfig *storage.VolumeConfig) (*stora
 	}
 	mockBackend.volumes[volumeConfig.Name] = volume
 	m.volumes[volumeConfig.Name] = volume
-	return volume.ConstructExternal(), nil
+	m.provisioning.notify(ProvisioningSucceeded, volumeConfig.Name,
+		volumeConfig.StorageClass, backendName, "", "Created volume.")
+	return &VolumeResult{VolumeExternal: volume.ConstructExternal()}, nil
+}
+
+// CreateVolumeFromSnapshot places the new volume on the source volume's
+// backend.  Snapshots are not tracked, so any snapshot name is accepted.
+func (m *MockOrchestrator) CreateVolumeFromSnapshot(
+	ctx context.Context, volumeConfig *storage.VolumeConfig,
+) (*VolumeResult, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.volumes[volumeConfig.Name]; ok {
+		return nil, errors.Errorf(errors.AlreadyExists,
+			"Volume %s already exists.", volumeConfig.Name)
+	}
+	source, ok := m.volumes[volumeConfig.SourceVolume]
+	if !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Source volume %s not found.", volumeConfig.SourceVolume)
+	}
+	volumeConfig.Size = source.Config.Size
+	volumeConfig.Protocol = source.Config.Protocol
+	volumeConfig.InternalName = GetFakeInternalName(volumeConfig.Name)
+	volumeConfig.AccessInfo = source.Config.AccessInfo
+	volumeConfig.AccessInfo.NfsPath = fmt.Sprintf("/%s",
+		GetFakeInternalName(volumeConfig.Name))
+	volume := &storage.Volume{
+		Config:  volumeConfig,
+		Backend: source.Backend,
+		Pool:    source.Pool,
+	}
+	m.mockBackends[source.Backend.Name].volumes[volumeConfig.Name] = volume
+	m.volumes[volumeConfig.Name] = volume
+	return &VolumeResult{VolumeExternal: volume.ConstructExternal()}, nil
 }
 
 func (m *MockOrchestrator) ValidateVolumes(
@@ -234,6 +577,24 @@ func (m *MockOrchestrator) GetVolume(volume string) *storage.VolumeExternal {
 	return vol.ConstructExternal()
 }
 
+func (m *MockOrchestrator) GetVolumeChap(
+	volume string,
+) (*storage.ChapConfig, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	vol, found := m.volumes[volume]
+	if !found {
+		return nil, errors.Errorf(errors.NotFound,
+			"Volume %s not found.", volume)
+	}
+	if !vol.Backend.Chap.IsSet() {
+		return nil, errors.Errorf(errors.NotFound,
+			"Volume %s's backend doesn't use CHAP.", volume)
+	}
+	chap := vol.Backend.Chap
+	return &chap, nil
+}
+
 // Copied verbatim from tridentOrchestrator
 func (m *MockOrchestrator) GetDriverTypeForVolume(
 	vol *storage.VolumeExternal,
@@ -277,7 +638,58 @@ func (m *MockOrchestrator) ListVolumes() []*storage.VolumeExternal {
 	return volumes
 }
 
-func (m *MockOrchestrator) DeleteVolume(volumeName string) (found bool, err error) {
+func (m *MockOrchestrator) WalkVolumes(
+	walk func(*storage.VolumeExternal) bool,
+) {
+	for _, vol := range m.ListVolumes() {
+		if !walk(vol) {
+			return
+		}
+	}
+}
+
+func (m *MockOrchestrator) ListVolumesByMetadata(
+	selector map[string]string,
+) []*storage.VolumeExternal {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	volumes := make([]*storage.VolumeExternal, 0)
+	for _, vol := range m.volumes {
+		if vol.Config.MatchesMetadata(selector) {
+			volumes = append(volumes, vol.ConstructExternal())
+		}
+	}
+	return volumes
+}
+
+func (m *MockOrchestrator) DeleteVolume(
+	ctx context.Context, volumeName string,
+) (found bool, err error) {
+	return m.deleteVolume(volumeName, false)
+}
+
+func (m *MockOrchestrator) ForceDeleteVolume(
+	ctx context.Context, volumeName string,
+) (found bool, err error) {
+	return m.deleteVolume(volumeName, true)
+}
+
+func (m *MockOrchestrator) DeleteVolumes(
+	ctx context.Context, deleteConfig *BulkDeleteConfig,
+) (*BulkDeleteResult, error) {
+	if err := deleteConfig.Validate(); err != nil {
+		return nil, err
+	}
+	volumes := deleteConfig.selectedVolumes(m.ListVolumesByMetadata)
+	found := make([]bool, len(volumes))
+	errs := make([]error, len(volumes))
+	for i, name := range volumes {
+		found[i], errs[i] = m.deleteVolume(name, deleteConfig.Force)
+	}
+	return newBulkDeleteResult(volumes, found, errs), nil
+}
+
+func (m *MockOrchestrator) deleteVolume(volumeName string, force bool) (found bool, err error) {
 
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -285,7 +697,13 @@ func (m *MockOrchestrator) DeleteVolume(volumeName string) (found bool, err erro
 	// Copied verbatim from orchestrator_core so that error returns are identical
 	volume, ok := m.volumes[volumeName]
 	if !ok {
-		return false, fmt.Errorf("Volume %s not found.", volumeName)
+		return false, errors.Errorf(errors.NotFound,
+			"Volume %s not found.", volumeName)
+	}
+	if len(volume.Attachments) > 0 && !force {
+		return true, errors.Errorf(errors.Conflict, "Volume %s is attached to "+
+			"node(s) %s; detach it first or force the deletion.", volumeName,
+			strings.Join(volume.AttachedNodes(), ", "))
 	}
 
 	delete(m.mockBackends[volume.Backend.Name].volumes, volume.Config.Name)
@@ -293,12 +711,633 @@ func (m *MockOrchestrator) DeleteVolume(volumeName string) (found bool, err erro
 	return true, nil
 }
 
+// UndeleteVolume always fails, since the mock orchestrator deletes volumes
+// immediately.
+func (m *MockOrchestrator) UndeleteVolume(
+	volumeName string,
+) (*storage.VolumeExternal, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.volumes[volumeName]; !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Volume %s not found.", volumeName)
+	}
+	return nil, errors.Errorf(errors.Conflict,
+		"Volume %s is not being deleted.", volumeName)
+}
+
+func (m *MockOrchestrator) RenameVolume(
+	volumeName, newName string,
+) (*storage.VolumeExternal, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	volume, ok := m.volumes[volumeName]
+	if !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Volume %s not found.", volumeName)
+	}
+	if volumeName == newName {
+		return volume.ConstructExternal(), nil
+	}
+	if _, ok = m.volumes[newName]; ok {
+		return nil, errors.Errorf(errors.AlreadyExists,
+			"Volume %s already exists.", newName)
+	}
+	mockBackend := m.mockBackends[volume.Backend.Name]
+	delete(m.volumes, volumeName)
+	delete(mockBackend.volumes, volumeName)
+	volume.Config.Name = newName
+	m.volumes[newName] = volume
+	mockBackend.volumes[newName] = volume
+	return volume.ConstructExternal(), nil
+}
+
+func (m *MockOrchestrator) UpdateVolumeMetadata(
+	volumeName string, metadata map[string]string,
+) (*storage.VolumeExternal, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	volume, ok := m.volumes[volumeName]
+	if !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Volume %s not found.", volumeName)
+	}
+	volume.Config.Metadata = metadata
+	return volume.ConstructExternal(), nil
+}
+
+func (m *MockOrchestrator) SetVolumeExpiry(
+	volumeName string, expiresAt *time.Time,
+) (*storage.VolumeExternal, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	volume, ok := m.volumes[volumeName]
+	if !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Volume %s not found.", volumeName)
+	}
+	volume.Config.ExpiresAt, volume.Config.ExpiredAt = expiresAt, nil
+	return volume.ConstructExternal(), nil
+}
+
+// ReattachVolume always fails, since the mock orchestrator doesn't
+// bootstrap and so never has orphaned volumes.
+func (m *MockOrchestrator) ReattachVolume(
+	volumeName, backendName, poolName string,
+) (*storage.VolumeExternal, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.volumes[volumeName]; !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Volume %s not found.", volumeName)
+	}
+	return nil, errors.Errorf(errors.Conflict,
+		"Volume %s is not orphaned.", volumeName)
+}
+
+// The mock orchestrator never retains volumes, so it has none to import.
+func (m *MockOrchestrator) ListRetainedVolumes() []*persistent_store.RetainedVolume {
+	return make([]*persistent_store.RetainedVolume, 0)
+}
+
+func (m *MockOrchestrator) ImportRetainedVolume(
+	volumeName, backendName, poolName string,
+) (*storage.VolumeExternal, error) {
+	return nil, errors.Errorf(errors.NotFound,
+		"Retained volume %s not found.", volumeName)
+}
+
+func (m *MockOrchestrator) DeleteRetainedVolume(
+	volumeName string,
+) (bool, error) {
+	return false, errors.Errorf(errors.NotFound,
+		"Retained volume %s not found.", volumeName)
+}
+
+// The mock orchestrator keeps provisioning retries in memory.
+func (m *MockOrchestrator) RecordProvisioningFailure(
+	volumeName string, provisioningErr error,
+) (*persistent_store.ProvisioningRetry, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	retry, ok := m.retries[volumeName]
+	if !ok {
+		retry = &persistent_store.ProvisioningRetry{Volume: volumeName}
+		m.retries[volumeName] = retry
+	}
+	retry.Attempts++
+	retry.LastAttempt = time.Now().UTC()
+	retry.NextAttempt = retry.LastAttempt.Add(
+		provisioningBackoff(retry.Attempts))
+	if provisioningErr != nil {
+		retry.LastError = provisioningErr.Error()
+	}
+	retryCopy := *retry
+	return &retryCopy, nil
+}
+
+func (m *MockOrchestrator) GetProvisioningRetry(
+	volumeName string,
+) *persistent_store.ProvisioningRetry {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	retry, ok := m.retries[volumeName]
+	if !ok {
+		return nil
+	}
+	retryCopy := *retry
+	return &retryCopy
+}
+
+func (m *MockOrchestrator) ListProvisioningRetries() []*persistent_store.ProvisioningRetry {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	ret := make([]*persistent_store.ProvisioningRetry, 0, len(m.retries))
+	for _, r := range m.retries {
+		retry := *r
+		ret = append(ret, &retry)
+	}
+	return ret
+}
+
+func (m *MockOrchestrator) DeleteProvisioningRetry(
+	volumeName string,
+) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.retries[volumeName]; !ok {
+		return false, errors.Errorf(errors.NotFound,
+			"Provisioning retry for volume %s not found.", volumeName)
+	}
+	delete(m.retries, volumeName)
+	return true, nil
+}
+
+func (m *MockOrchestrator) RetryProvisioningNow(
+	volumeName string,
+) (*persistent_store.ProvisioningRetry, error) {
+	return m.updateProvisioningRetry(volumeName,
+		func(retry *persistent_store.ProvisioningRetry) {
+			retry.NextAttempt = time.Now().UTC()
+			retry.Abandoned = false
+		})
+}
+
+func (m *MockOrchestrator) AbandonProvisioning(
+	volumeName string,
+) (*persistent_store.ProvisioningRetry, error) {
+	return m.updateProvisioningRetry(volumeName,
+		func(retry *persistent_store.ProvisioningRetry) {
+			retry.Abandoned = true
+		})
+}
+
+func (m *MockOrchestrator) updateProvisioningRetry(
+	volumeName string, update func(*persistent_store.ProvisioningRetry),
+) (*persistent_store.ProvisioningRetry, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	retry, ok := m.retries[volumeName]
+	if !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Provisioning retry for volume %s not found.", volumeName)
+	}
+	update(retry)
+	retryCopy := *retry
+	return &retryCopy, nil
+}
+
+func (m *MockOrchestrator) SetVolumeAccess(
+	volumeName string, access *storage.VolumeAccess,
+) (*storage.VolumeExternal, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	volume, ok := m.volumes[volumeName]
+	if !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Volume %s not found.", volumeName)
+	}
+	accessCopy := *access
+	volume.Config.Access = &accessCopy
+	return volume.ConstructExternal(), nil
+}
+
+// The mock orchestrator records replication without creating mirrors;
+// they're always reported as healthy.
+func (m *MockOrchestrator) EnableReplication(
+	volumeName string, replicationConfig *ReplicationConfig,
+) (*storage.VolumeExternal, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	volume, ok := m.volumes[volumeName]
+	if !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Volume %s not found.", volumeName)
+	}
+	if volume.Replication != nil {
+		return nil, errors.Errorf(errors.AlreadyExists,
+			"Volume %s is already replicated.", volumeName)
+	}
+	if _, ok = m.backends[replicationConfig.PeerBackend]; !ok {
+		return nil, errors.Errorf(errors.NotFound, "Backend %s not found.",
+			replicationConfig.PeerBackend)
+	}
+	now := time.Now().UTC()
+	volume.Replication = &storage.VolumeReplication{
+		PeerBackend:      replicationConfig.PeerBackend,
+		PeerPool:         replicationConfig.PeerPool,
+		PeerInternalName: volume.Config.InternalName,
+		CreatedAt:        now,
+	}
+	volume.Replication.SetStatus(&storage.MirrorStatus{
+		State:        storage.MirrorMirrored,
+		Healthy:      true,
+		LastTransfer: now,
+	}, now)
+	return volume.ConstructExternal(), nil
+}
+
+func (m *MockOrchestrator) DisableReplication(
+	volumeName string,
+) (*storage.VolumeExternal, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	volume, ok := m.volumes[volumeName]
+	if !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Volume %s not found.", volumeName)
+	}
+	if volume.Replication == nil {
+		return nil, errors.Errorf(errors.Conflict,
+			"Volume %s is not replicated.", volumeName)
+	}
+	volume.Replication = nil
+	return volume.ConstructExternal(), nil
+}
+
+// FailoverVolume moves the volume to its peer backend, and to the peer
+// pool if the backend has it.
+func (m *MockOrchestrator) FailoverVolume(
+	volumeName string,
+) (*storage.VolumeExternal, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	volume, ok := m.volumes[volumeName]
+	if !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Volume %s not found.", volumeName)
+	}
+	if err := m.failoverVolume(volume); err != nil {
+		return nil, err
+	}
+	return volume.ConstructExternal(), nil
+}
+
+func (m *MockOrchestrator) failoverVolume(volume *storage.Volume) error {
+	if volume.Replication == nil {
+		return errors.Errorf(errors.Conflict,
+			"Volume %s is not replicated.", volume.Config.Name)
+	}
+	peer, ok := m.backends[volume.Replication.PeerBackend]
+	if !ok {
+		return errors.Errorf(errors.NotFound, "Backend %s not found.",
+			volume.Replication.PeerBackend)
+	}
+	volume.Backend = peer
+	if pool, ok := peer.Storage[volume.Replication.PeerPool]; ok {
+		volume.Pool = pool
+	}
+	volume.Replication = nil
+	return nil
+}
+
+// FailoverBackend fails over each of the backend's volumes as
+// FailoverVolume does, and marks the backend offline.
+func (m *MockOrchestrator) FailoverBackend(
+	backendName string,
+) (*BackendFailoverReport, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	backend, ok := m.backends[backendName]
+	if !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Backend %s not found.", backendName)
+	}
+	report := &BackendFailoverReport{
+		Backend:    backendName,
+		FailedOver: make([]*VolumeFailover, 0),
+		Failed:     make([]*VolumeFailover, 0),
+	}
+	names := make([]string, 0)
+	for name, volume := range m.volumes {
+		if volume.Backend.Name == backendName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		volume := m.volumes[name]
+		replication := volume.Replication
+		report.add(name, replication, m.failoverVolume(volume))
+	}
+	backend.Online = false
+	report.Offline = true
+	return report, nil
+}
+
+func (m *MockOrchestrator) SetVolumeFormatted(
+	volumeName, fsType string,
+) (*storage.VolumeExternal, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	volume, ok := m.volumes[volumeName]
+	if !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Volume %s not found.", volumeName)
+	}
+	if volume.Config.FSType != "" && volume.Config.FSType != fsType {
+		return nil, errors.Errorf(errors.Conflict, "Volume %s uses fsType %s; "+
+			"it can't be changed to %s.", volumeName, volume.Config.FSType,
+			fsType)
+	}
+	volume.Config.FSType = fsType
+	volume.Config.Formatted = true
+	return volume.ConstructExternal(), nil
+}
+
+func (m *MockOrchestrator) AttachVolume(
+	volumeName, node string, readOnly bool,
+) (*storage.VolumeExternal, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	volume, ok := m.volumes[volumeName]
+	if !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Volume %s not found.", volumeName)
+	}
+	if volume.GetAttachment(node) == nil {
+		volume.Attachments = append(volume.Attachments,
+			storage.VolumeAttachment{
+				Node:       node,
+				ReadOnly:   readOnly,
+				AttachedAt: time.Now().UTC(),
+			})
+	}
+	return volume.ConstructExternal(), nil
+}
+
+func (m *MockOrchestrator) DetachVolume(
+	volumeName, node string,
+) (*storage.VolumeExternal, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	volume, ok := m.volumes[volumeName]
+	if !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Volume %s not found.", volumeName)
+	}
+	attachments := make([]storage.VolumeAttachment, 0, len(volume.Attachments))
+	for _, a := range volume.Attachments {
+		if a.Node != node {
+			attachments = append(attachments, a)
+		}
+	}
+	volume.Attachments = attachments
+	return volume.ConstructExternal(), nil
+}
+
 func (m *MockOrchestrator) ListVolumesByPlugin(pluginName string) []*storage.VolumeExternal {
 	// Currently returns nil, since this is backend agnostic.  Change this
 	// if we ever have non-apiserver functionality depend on this function.
 	return nil
 }
 
+// ListVolumeUsage reports each volume's size; consumption isn't reported.
+func (m *MockOrchestrator) ListVolumeUsage() []*VolumeUsage {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	ret := make([]*VolumeUsage, 0, len(m.volumes))
+	for name, vol := range m.volumes {
+		size, _ := vol.Config.SizeBytes()
+		ret = append(ret, &VolumeUsage{
+			Volume:           name,
+			Backend:          vol.Backend.Name,
+			Pool:             vol.Pool.Name,
+			StorageClass:     vol.Config.StorageClass,
+			Metadata:         vol.Config.Metadata,
+			CreatedAt:        vol.Config.CreatedAt,
+			ProvisionedBytes: size,
+		})
+	}
+	return ret
+}
+
+// GetRebalanceReport reports no storage classes; the mock doesn't analyze
+// volume placement.
+func (m *MockOrchestrator) GetRebalanceReport() *RebalanceReport {
+	return &RebalanceReport{
+		AnalyzedAt:     time.Now(),
+		StorageClasses: make([]*StorageClassBalance, 0),
+	}
+}
+
+// AddVolumeGroup adds each of the group's volumes with AddVolume, so they
+// aren't necessarily on the same backend.
+func (m *MockOrchestrator) AddVolumeGroup(
+	ctx context.Context, groupConfig *VolumeGroupConfig,
+) (*persistent_store.VolumeGroup, error) {
+	return m.addVolumeGroup(ctx, groupConfig, "")
+}
+
+func (m *MockOrchestrator) addVolumeGroup(
+	ctx context.Context, groupConfig *VolumeGroupConfig, templateName string,
+) (*persistent_store.VolumeGroup, error) {
+	if err := groupConfig.Validate(); err != nil {
+		return nil, err
+	}
+	if _, ok := m.groups[groupConfig.Name]; ok {
+		return nil, errors.Errorf(errors.AlreadyExists,
+			"Volume group %s already exists.", groupConfig.Name)
+	}
+	group := &persistent_store.VolumeGroup{
+		Name:      groupConfig.Name,
+		Volumes:   make([]string, 0, len(groupConfig.Volumes)),
+		CreatedAt: time.Now().UTC(),
+		Template:  templateName,
+	}
+	for _, volConfig := range groupConfig.Volumes {
+		volConfig.Group = groupConfig.Name
+		vol, err := m.AddVolume(ctx, volConfig)
+		if err != nil {
+			for _, name := range group.Volumes {
+				m.deleteVolume(name, true)
+			}
+			return nil, err
+		}
+		group.Backend = vol.Backend
+		group.Volumes = append(group.Volumes, vol.Config.Name)
+	}
+	m.groups[group.Name] = group
+	return copyVolumeGroup(group), nil
+}
+
+func (m *MockOrchestrator) GetVolumeGroup(
+	groupName string,
+) *persistent_store.VolumeGroup {
+	group, ok := m.groups[groupName]
+	if !ok {
+		return nil
+	}
+	return copyVolumeGroup(group)
+}
+
+func (m *MockOrchestrator) ListVolumeGroups() []*persistent_store.VolumeGroup {
+	ret := make([]*persistent_store.VolumeGroup, 0, len(m.groups))
+	for _, group := range m.groups {
+		ret = append(ret, copyVolumeGroup(group))
+	}
+	return ret
+}
+
+// SnapshotVolumeGroup only checks that the group exists, since mock
+// backends don't keep snapshots.
+func (m *MockOrchestrator) SnapshotVolumeGroup(
+	groupName, snapshotName string,
+) error {
+	if _, ok := m.groups[groupName]; !ok {
+		return errors.Errorf(errors.NotFound,
+			"Volume group %s not found.", groupName)
+	}
+	return nil
+}
+
+func (m *MockOrchestrator) DeleteVolumeGroup(
+	ctx context.Context, groupName string,
+) (bool, error) {
+	group, ok := m.groups[groupName]
+	if !ok {
+		return false, errors.Errorf(errors.NotFound,
+			"Volume group %s not found.", groupName)
+	}
+	for _, name := range group.Volumes {
+		m.deleteVolume(name, true)
+	}
+	delete(m.groups, groupName)
+	return true, nil
+}
+
+func (m *MockOrchestrator) AddTemplate(
+	templateConfig *app_template.Config,
+) (*app_template.Config, error) {
+	if err := templateConfig.Validate(); err != nil {
+		return nil, err
+	}
+	if _, ok := m.templates[templateConfig.Name]; ok {
+		return nil, errors.Errorf(errors.AlreadyExists,
+			"Template %s already exists.", templateConfig.Name)
+	}
+	m.templates[templateConfig.Name] = copyTemplate(templateConfig)
+	return copyTemplate(templateConfig), nil
+}
+
+func (m *MockOrchestrator) GetTemplate(
+	templateName string,
+) *app_template.Config {
+	template, ok := m.templates[templateName]
+	if !ok {
+		return nil
+	}
+	return copyTemplate(template)
+}
+
+func (m *MockOrchestrator) ListTemplates() []*app_template.Config {
+	ret := make([]*app_template.Config, 0, len(m.templates))
+	for _, template := range m.templates {
+		ret = append(ret, copyTemplate(template))
+	}
+	return ret
+}
+
+func (m *MockOrchestrator) DeleteTemplate(templateName string) (bool, error) {
+	if _, ok := m.templates[templateName]; !ok {
+		return false, errors.Errorf(errors.NotFound,
+			"Template %s not found.", templateName)
+	}
+	delete(m.templates, templateName)
+	return true, nil
+}
+
+func (m *MockOrchestrator) ProvisionTemplate(
+	ctx context.Context, templateName, instanceName string,
+) (*persistent_store.VolumeGroup, error) {
+	template, ok := m.templates[templateName]
+	if !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Template %s not found.", templateName)
+	}
+	return m.addVolumeGroup(ctx, &VolumeGroupConfig{
+		Name:    instanceName,
+		Volumes: template.VolumeConfigs(instanceName),
+	}, templateName)
+}
+
+func (m *MockOrchestrator) SetNamespacePolicy(
+	policyConfig *namespace_policy.Config,
+) (*namespace_policy.Config, error) {
+	if err := policyConfig.Validate(); err != nil {
+		return nil, errors.Errorf(errors.InvalidInput, "%v", err)
+	}
+	m.nsPolicies[policyConfig.Namespace] = copyNamespacePolicy(policyConfig)
+	return copyNamespacePolicy(policyConfig), nil
+}
+
+func (m *MockOrchestrator) GetNamespacePolicy(
+	namespace string,
+) *namespace_policy.Config {
+	policy, ok := m.nsPolicies[namespace]
+	if !ok {
+		return nil
+	}
+	return copyNamespacePolicy(policy)
+}
+
+func (m *MockOrchestrator) ListNamespacePolicies() []*namespace_policy.Config {
+	ret := make([]*namespace_policy.Config, 0, len(m.nsPolicies))
+	for _, policy := range m.nsPolicies {
+		ret = append(ret, copyNamespacePolicy(policy))
+	}
+	return ret
+}
+
+func (m *MockOrchestrator) DeleteNamespacePolicy(
+	namespace string,
+) (bool, error) {
+	if _, ok := m.nsPolicies[namespace]; !ok {
+		return false, errors.Errorf(errors.NotFound,
+			"Namespace policy %s not found.", namespace)
+	}
+	delete(m.nsPolicies, namespace)
+	return true, nil
+}
+
 func NewMockOrchestrator() *MockOrchestrator {
 	return &MockOrchestrator{
 		backends:       make(map[string]*storage.StorageBackend),
@@ -306,15 +1345,27 @@ func NewMockOrchestrator() *MockOrchestrator {
 		storageClasses: make(map[string]*storage_class.StorageClass),
 		volumes:        make(map[string]*storage.Volume),
 		mutex:          &sync.Mutex{},
+		events:         newEventBus(),
+		provisioning:   newProvisioningNotifier(),
+		hooks:          make(map[string]*hooks.Config),
+		policies:       make(map[string]*snapshot_policy.Config),
+		loggingConfig:  logging.GetConfig(),
+		retries:        make(map[string]*persistent_store.ProvisioningRetry),
+		frontends:      make(map[string]frontend.FrontendPlugin),
+		groups:         make(map[string]*persistent_store.VolumeGroup),
+		templates:      make(map[string]*app_template.Config),
+		placementRand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+		nsPolicies:     make(map[string]*namespace_policy.Config),
 	}
 }
 
 func (m *MockOrchestrator) AddStorageClass(
 	scConfig *storage_class.Config,
-) (*storage_class.StorageClassExternal, error) {
+) (*StorageClassResult, error) {
 	sc := storage_class.New(scConfig)
 	m.storageClasses[sc.GetName()] = sc
-	return sc.ConstructExternal(), nil
+	return &StorageClassResult{StorageClassExternal: sc.ConstructExternal()},
+		nil
 }
 
 func (m *MockOrchestrator) GetStorageClass(scName string) *storage_class.StorageClassExternal {
@@ -332,11 +1383,127 @@ func (m *MockOrchestrator) ListStorageClasses() []*storage_class.StorageClassExt
 	return ret
 }
 
+func (m *MockOrchestrator) UpdateStorageClass(
+	scConfig *storage_class.Config,
+) (*StorageClassResult, error) {
+	if _, ok := m.storageClasses[scConfig.Name]; !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Storage class %s not found.", scConfig.Name)
+	}
+	sc := storage_class.New(scConfig)
+	m.storageClasses[sc.GetName()] = sc
+	return &StorageClassResult{StorageClassExternal: sc.ConstructExternal()},
+		nil
+}
+
 func (m *MockOrchestrator) DeleteStorageClass(scName string) (bool, error) {
 	_, ok := m.storageClasses[scName]
 	if !ok {
-		return false, fmt.Errorf("Storage class %s not found.", scName)
+		return false, errors.Errorf(errors.NotFound,
+			"Storage class %s not found.", scName)
 	}
 	delete(m.storageClasses, scName)
 	return true, nil
 }
+
+func (m *MockOrchestrator) ForceDeleteStorageClass(
+	scName string,
+) (bool, error) {
+	return m.DeleteStorageClass(scName)
+}
+
+// SubscribeEvents returns a channel that never receives events, since the
+// mock orchestrator doesn't publish them.
+func (m *MockOrchestrator) SubscribeEvents() <-chan Event {
+	return m.events.subscribe()
+}
+
+func (m *MockOrchestrator) UnsubscribeEvents(ch <-chan Event) {
+	m.events.unsubscribe(ch)
+}
+
+// The mock orchestrator reports the start and outcome of AddVolume and the
+// backend it selects, which has no pool.
+func (m *MockOrchestrator) AddProvisioningListener(l ProvisioningListener) {
+	m.provisioning.add(l)
+}
+
+func (m *MockOrchestrator) RemoveProvisioningListener(
+	l ProvisioningListener,
+) {
+	m.provisioning.remove(l)
+}
+
+// The mock orchestrator records hooks but never calls them.
+func (m *MockOrchestrator) AddHook(hookConfig *hooks.Config) (*hooks.Config, error) {
+	if err := hookConfig.Validate(); err != nil {
+		return nil, err
+	}
+	if _, ok := m.hooks[hookConfig.Name]; ok {
+		return nil, errors.Errorf(errors.AlreadyExists,
+			"Hook %s already exists.", hookConfig.Name)
+	}
+	hook := *hookConfig
+	m.hooks[hook.Name] = &hook
+	return &hook, nil
+}
+
+func (m *MockOrchestrator) GetHook(hookName string) *hooks.Config {
+	return m.hooks[hookName]
+}
+
+func (m *MockOrchestrator) ListHooks() []*hooks.Config {
+	ret := make([]*hooks.Config, 0, len(m.hooks))
+	for _, hook := range m.hooks {
+		ret = append(ret, hook)
+	}
+	return ret
+}
+
+func (m *MockOrchestrator) DeleteHook(hookName string) (bool, error) {
+	if _, ok := m.hooks[hookName]; !ok {
+		return false, errors.Errorf(errors.NotFound,
+			"Hook %s not found.", hookName)
+	}
+	delete(m.hooks, hookName)
+	return true, nil
+}
+
+// The mock orchestrator records snapshot policies but never runs them.
+func (m *MockOrchestrator) AddSnapshotPolicy(
+	policyConfig *snapshot_policy.Config,
+) (*snapshot_policy.Config, error) {
+	if err := policyConfig.Validate(); err != nil {
+		return nil, err
+	}
+	if _, ok := m.policies[policyConfig.Name]; ok {
+		return nil, errors.Errorf(errors.AlreadyExists,
+			"Snapshot policy %s already exists.", policyConfig.Name)
+	}
+	policy := *policyConfig
+	m.policies[policy.Name] = &policy
+	return &policy, nil
+}
+
+func (m *MockOrchestrator) GetSnapshotPolicy(
+	policyName string,
+) *snapshot_policy.Config {
+	return m.policies[policyName]
+}
+
+func (m *MockOrchestrator) ListSnapshotPolicies() []*snapshot_policy.Config {
+	ret := make([]*snapshot_policy.Config, 0, len(m.policies))
+	for _, policy := range m.policies {
+		ret = append(ret, policy)
+	}
+	return ret
+}
+
+func (m *MockOrchestrator) DeleteSnapshotPolicy(policyName string) (bool, error) {
+	if _, ok := m.policies[policyName]; !ok {
+		return false, errors.Errorf(errors.NotFound,
+			"Snapshot policy %s not found.", policyName)
+	}
+	delete(m.policies, policyName)
+	return true, nil
+}
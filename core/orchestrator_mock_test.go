@@ -6,6 +6,8 @@ import (
 	"reflect"
 	"testing"
 
+	"golang.org/x/net/context"
+
 	"github.com/netapp/trident/config"
 	"github.com/netapp/trident/storage"
 	sc "github.com/netapp/trident/storage_class"
@@ -57,7 +59,7 @@ func addAndRetrieveVolume(
 		t.Fatalf("Unable to add storage class %s (%s):  %v", vc.Name,
 			vc.Protocol, err)
 	}
-	vol, err := m.AddVolume(vc)
+	vol, err := m.AddVolume(context.Background(), vc)
 	if err != nil {
 		t.Fatalf("Unable to add volume %s (%s):  %s", vc.Name, vc.Protocol, err)
 	}
@@ -70,14 +72,14 @@ func addAndRetrieveVolume(
 		t.Errorf("Volume %s (%s) not found.", vc.Name,
 			string(vc.Protocol))
 	}
-	if !reflect.DeepEqual(found.ConstructExternal(), vol) {
+	if !reflect.DeepEqual(found.ConstructExternal(), vol.VolumeExternal) {
 		t.Error("Found incorrect volume in map.")
 	}
 	foundVolume := m.GetVolume(vc.Name)
 	if foundVolume == nil {
 		t.Errorf("Failed to find volume %s (%s)", vc.Name,
 			vc.Protocol)
-	} else if !reflect.DeepEqual(foundVolume, vol) {
+	} else if !reflect.DeepEqual(foundVolume, vol.VolumeExternal) {
 		// Note that both accessor methods return external copies, so we
 		// can't rely on pointer equality to validate success.
 		t.Errorf("Retrieved incorrect volume for %s (%s)", vc.Name,
@@ -0,0 +1,211 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/errors"
+	"github.com/netapp/trident/storage"
+	"github.com/netapp/trident/storage_class"
+)
+
+// PoolPlacement explains whether a volume can be placed in a storage pool.
+type PoolPlacement struct {
+	Backend string `json:"backend"`
+	// Pool is empty for backends that failed to initialize, whose pools
+	// are unknown.
+	Pool string `json:"pool,omitempty"`
+	// Reasons are why the volume can't be placed in the pool; there are
+	// none if it can.
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// PlacementReport explains, pool by pool, where a volume can be placed.
+type PlacementReport struct {
+	Volume       string           `json:"volume"`
+	StorageClass string           `json:"storageClass"`
+	Pools        []*PoolPlacement `json:"pools"`
+}
+
+// Eligible returns the pools that the volume can be placed in.
+func (r *PlacementReport) Eligible() []*PoolPlacement {
+	ret := make([]*PoolPlacement, 0)
+	for _, p := range r.Pools {
+		if len(p.Reasons) == 0 {
+			ret = append(ret, p)
+		}
+	}
+	return ret
+}
+
+// summary lists the reasons against each pool on one line, for error
+// messages.
+func (r *PlacementReport) summary() string {
+	if len(r.Pools) == 0 {
+		return "No backends have been added."
+	}
+	parts := make([]string, 0, len(r.Pools))
+	for _, p := range r.Pools {
+		if len(p.Reasons) == 0 {
+			continue
+		}
+		name := p.Backend
+		if p.Pool != "" {
+			name = fmt.Sprintf("%s/%s", p.Backend, p.Pool)
+		}
+		parts = append(parts, fmt.Sprintf("[%s: %s]", name,
+			strings.Join(p.Reasons, " ")))
+	}
+	return fmt.Sprintf("Storage pools:  %s", strings.Join(parts, ", "))
+}
+
+// ExplainPlacement reports whether a volume with volumeConfig could be
+// placed in each storage pool, without creating anything.  A backend's free
+// space is only known by trying to create the volume, so it isn't checked.
+func (o *tridentOrchestrator) ExplainPlacement(
+	volumeConfig *storage.VolumeConfig,
+) (*PlacementReport, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	storageClass, ok := o.storageClasses[volumeConfig.StorageClass]
+	if !ok {
+		return nil, errors.Errorf(errors.InvalidInput,
+			"Unknown storage class:  %s", volumeConfig.StorageClass)
+	}
+	// Apply the class's defaults to a copy, as AddVolume would.
+	volConfig := *volumeConfig
+	storageClass.ApplyQoSDefaults(&volConfig)
+	if storageClass.RequiresEncryption() {
+		volConfig.Encryption = true
+	}
+	storageClass.ApplySpaceEfficiency(&volConfig)
+	if _, err := o.antiAffinityBackends(&volConfig); err != nil {
+		return nil, err
+	}
+	return o.explainPlacement(&volConfig, storageClass, nil), nil
+}
+
+// placementError returns a BackendUnavailable error whose message is
+// followed by why each storage pool can't hold the volume, and whose
+// details are the placement report.  The caller must hold the orchestrator
+// lock.
+func (o *tridentOrchestrator) placementError(
+	volumeConfig *storage.VolumeConfig, sc *storage_class.StorageClass,
+	message string,
+) error {
+	report := o.explainPlacement(volumeConfig, sc, nil)
+	return errors.ErrorfWithDetails(errors.BackendUnavailable, report,
+		"%s %s", message, report.summary())
+}
+
+// explainPlacement reports whether a volume can be placed in each storage
+// pool, sorted by backend and pool name.  failures, if set, are the errors
+// returned by attempts to create the volume, by pool.  The caller must hold
+// the orchestrator lock.
+func (o *tridentOrchestrator) explainPlacement(
+	volumeConfig *storage.VolumeConfig, sc *storage_class.StorageClass,
+	failures map[*storage.StoragePool]error,
+) *PlacementReport {
+	report := &PlacementReport{
+		Volume:       volumeConfig.Name,
+		StorageClass: sc.GetName(),
+		Pools:        make([]*PoolPlacement, 0),
+	}
+	backendNames := make([]string, 0,
+		len(o.backends)+len(o.failedBackends))
+	for name := range o.backends {
+		backendNames = append(backendNames, name)
+	}
+	for name := range o.failedBackends {
+		backendNames = append(backendNames, name)
+	}
+	sort.Strings(backendNames)
+	// Unknown volumes are reported by antiAffinityBackends' callers.
+	avoid, _ := o.antiAffinityBackends(volumeConfig)
+
+	for _, backendName := range backendNames {
+		backend, ok := o.backends[backendName]
+		if !ok {
+			report.Pools = append(report.Pools, &PoolPlacement{
+				Backend: backendName,
+				Reasons: []string{fmt.Sprintf("Backend failed to "+
+					"initialize:  %v", o.failedBackends[backendName].err)},
+			})
+			continue
+		}
+		poolNames := make([]string, 0, len(backend.Storage))
+		for name := range backend.Storage {
+			poolNames = append(poolNames, name)
+		}
+		sort.Strings(poolNames)
+		for _, poolName := range poolNames {
+			pool := backend.Storage[poolName]
+			reasons := placementReasons(volumeConfig, sc, pool)
+			if reason, ok := avoid[backendName]; ok {
+				reasons = append(reasons, reason)
+			}
+			if err, ok := failures[pool]; ok {
+				reasons = append(reasons, fmt.Sprintf("Volume creation "+
+					"failed:  %v", err))
+			}
+			report.Pools = append(report.Pools, &PoolPlacement{
+				Backend: backendName,
+				Pool:    poolName,
+				Reasons: reasons,
+			})
+		}
+	}
+	return report
+}
+
+// placementReasons returns why a volume can't be placed in pool, checking
+// the same things as addVolume.
+func placementReasons(
+	volumeConfig *storage.VolumeConfig, sc *storage_class.StorageClass,
+	pool *storage.StoragePool,
+) []string {
+	reasons := make([]string, 0)
+	backend := pool.Backend
+	if !backend.Online {
+		reasons = append(reasons, "Backend is offline.")
+	}
+	if volumeConfig.Protocol != config.ProtocolAny &&
+		pool.GetProtocol() != volumeConfig.Protocol {
+		reasons = append(reasons, fmt.Sprintf("Storage pool provides %s "+
+			"volumes, not %s.", pool.GetProtocol(), volumeConfig.Protocol))
+	}
+	if mismatch := sc.Mismatch(pool); mismatch != "" {
+		reasons = append(reasons, mismatch)
+	}
+	if volumeConfig.HasQoS() &&
+		!pool.CanHonorQoS(volumeConfig.MinIOPS, volumeConfig.MaxIOPS) {
+		reasons = append(reasons, fmt.Sprintf("Storage pool cannot provide "+
+			"between %d and %d IOPS.", volumeConfig.MinIOPS,
+			volumeConfig.MaxIOPS))
+	}
+	if volumeConfig.Encryption && !pool.SupportsEncryption() {
+		reasons = append(reasons, "Storage pool doesn't support encryption.")
+	}
+	if !pool.SupportsSpaceEfficiency(volumeConfig) {
+		reasons = append(reasons, "Storage pool doesn't support the "+
+			"requested provisioning type, deduplication, and compression.")
+	}
+	if volumeConfig.Access != nil && !volumeConfig.Access.IsEmpty() &&
+		!backend.SupportsVolumeAccess() {
+		reasons = append(reasons, "Backend doesn't support volume access "+
+			"control.")
+	}
+	if volumeConfig.IsReadOnly() && !backend.SupportsReadOnly() {
+		reasons = append(reasons, "Backend doesn't support read-only "+
+			"volumes.")
+	}
+	if err := backend.CheckLimits(volumeConfig); err != nil {
+		reasons = append(reasons, err.Error())
+	}
+	return reasons
+}
@@ -0,0 +1,97 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"sync"
+	"time"
+)
+
+type ProvisioningEventType string
+
+const (
+	// ProvisioningStarted is sent when the orchestrator starts creating a
+	// volume.
+	ProvisioningStarted ProvisioningEventType = "started"
+	// ProvisioningPoolSelected is sent before the volume is created in a
+	// storage pool.
+	ProvisioningPoolSelected ProvisioningEventType = "poolSelected"
+	// ProvisioningPoolFailed is sent when creating the volume in a pool
+	// fails; the next pool is tried, if any.
+	ProvisioningPoolFailed ProvisioningEventType = "poolFailed"
+	// ProvisioningFailed is sent when the volume can't be created; Message
+	// gives the reason.
+	ProvisioningFailed ProvisioningEventType = "failed"
+	// ProvisioningSucceeded is sent once the volume has been created.
+	ProvisioningSucceeded ProvisioningEventType = "succeeded"
+)
+
+// ProvisioningEvent reports the progress of creating a volume.  Backend and
+// Pool are set once a pool has been selected.
+type ProvisioningEvent struct {
+	Type         ProvisioningEventType `json:"type"`
+	Volume       string                `json:"volume"`
+	StorageClass string                `json:"storageClass"`
+	Backend      string                `json:"backend,omitempty"`
+	Pool         string                `json:"pool,omitempty"`
+	Message      string                `json:"message"`
+	Timestamp    time.Time             `json:"timestamp"`
+}
+
+// ProvisioningListener is called back as volumes are provisioned, so that
+// frontends can report progress to their users.  Listeners are called while
+// the orchestrator is locked, so they must return quickly and must not call
+// the orchestrator.
+type ProvisioningListener interface {
+	OnProvisioningEvent(event ProvisioningEvent)
+}
+
+// provisioningNotifier calls the registered provisioning listeners.
+type provisioningNotifier struct {
+	mutex     *sync.Mutex
+	listeners []ProvisioningListener
+}
+
+func newProvisioningNotifier() *provisioningNotifier {
+	return &provisioningNotifier{
+		mutex:     &sync.Mutex{},
+		listeners: make([]ProvisioningListener, 0),
+	}
+}
+
+func (n *provisioningNotifier) add(l ProvisioningListener) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.listeners = append(n.listeners, l)
+}
+
+func (n *provisioningNotifier) remove(l ProvisioningListener) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	for i, listener := range n.listeners {
+		if listener == l {
+			n.listeners = append(n.listeners[:i], n.listeners[i+1:]...)
+			return
+		}
+	}
+}
+
+func (n *provisioningNotifier) notify(
+	eventType ProvisioningEventType, volumeName, storageClass, backend,
+	pool, message string,
+) {
+	event := ProvisioningEvent{
+		Type:         eventType,
+		Volume:       volumeName,
+		StorageClass: storageClass,
+		Backend:      backend,
+		Pool:         pool,
+		Message:      message,
+		Timestamp:    time.Now(),
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	for _, l := range n.listeners {
+		l.OnProvisioningEvent(event)
+	}
+}
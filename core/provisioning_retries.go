@@ -0,0 +1,197 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"sort"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/errors"
+	"github.com/netapp/trident/persistent_store"
+)
+
+func (o *tridentOrchestrator) bootstrapProvisioningRetries() error {
+	retries, err := o.storeClient.GetProvisioningRetries()
+	if err != nil {
+		return err
+	}
+	for _, r := range retries {
+		if _, ok := o.volumes[r.Volume]; ok {
+			// The volume was provisioned before the record was removed.
+			if err = o.storeClient.DeleteProvisioningRetry(r); err != nil {
+				return err
+			}
+			continue
+		}
+		o.provisioningRetries[r.Volume] = r
+		log.WithFields(log.Fields{
+			"volume":      r.Volume,
+			"attempts":    r.Attempts,
+			"nextAttempt": r.NextAttempt,
+			"handler":     "Bootstrap",
+		}).Debug("Added an existing provisioning retry.")
+	}
+	return nil
+}
+
+// provisioningBackoff returns how long to wait before the next attempt to
+// provision a volume that has failed the given number of times.
+func provisioningBackoff(attempts int) time.Duration {
+	backoff := config.ProvisioningRetryBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= config.MaxProvisioningRetryBackoff {
+			return config.MaxProvisioningRetryBackoff
+		}
+	}
+	return backoff
+}
+
+// RecordProvisioningFailure records a failed attempt to provision a volume
+// and returns the updated record, whose NextAttempt is when the frontend
+// should try again.  The wait doubles with each failure; see
+// config.ProvisioningRetryBackoff.  Records are persisted, so restarting
+// Trident doesn't reset the backoff.
+func (o *tridentOrchestrator) RecordProvisioningFailure(
+	volumeName string, provisioningErr error,
+) (*persistent_store.ProvisioningRetry, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	retry := &persistent_store.ProvisioningRetry{Volume: volumeName}
+	if existing, ok := o.provisioningRetries[volumeName]; ok {
+		*retry = *existing
+	}
+	retry.Attempts++
+	retry.LastAttempt = time.Now().UTC()
+	retry.NextAttempt = retry.LastAttempt.Add(
+		provisioningBackoff(retry.Attempts))
+	if provisioningErr != nil {
+		retry.LastError = provisioningErr.Error()
+	}
+	if err := o.storeClient.AddProvisioningRetry(retry); err != nil {
+		return nil, err
+	}
+	o.provisioningRetries[volumeName] = retry
+	log.WithFields(log.Fields{
+		"volume":      volumeName,
+		"attempts":    retry.Attempts,
+		"nextAttempt": retry.NextAttempt,
+	}).Debug("Recorded failed provisioning attempt.")
+
+	retryCopy := *retry
+	return &retryCopy, nil
+}
+
+// GetProvisioningRetry returns the failed provisioning attempts recorded
+// for a volume, or nil if there are none.
+func (o *tridentOrchestrator) GetProvisioningRetry(
+	volumeName string,
+) *persistent_store.ProvisioningRetry {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	retry, ok := o.provisioningRetries[volumeName]
+	if !ok {
+		return nil
+	}
+	retryCopy := *retry
+	return &retryCopy
+}
+
+// ListProvisioningRetries returns the recorded provisioning retries, sorted
+// by volume name.
+func (o *tridentOrchestrator) ListProvisioningRetries() []*persistent_store.ProvisioningRetry {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	names := make([]string, 0, len(o.provisioningRetries))
+	for name := range o.provisioningRetries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ret := make([]*persistent_store.ProvisioningRetry, 0, len(names))
+	for _, name := range names {
+		retry := *o.provisioningRetries[name]
+		ret = append(ret, &retry)
+	}
+	return ret
+}
+
+// RetryProvisioningNow has the frontend try to provision a volume again at
+// its next opportunity, without waiting for the backoff to end, and resumes
+// an abandoned retry.  The attempts made so far are kept.
+func (o *tridentOrchestrator) RetryProvisioningNow(
+	volumeName string,
+) (*persistent_store.ProvisioningRetry, error) {
+	return o.updateProvisioningRetry(volumeName,
+		func(retry *persistent_store.ProvisioningRetry) {
+			retry.NextAttempt = time.Now().UTC()
+			retry.Abandoned = false
+		})
+}
+
+// AbandonProvisioning stops the frontend from trying to provision a volume
+// again, e.g., while an operator fixes its cause, until it is resumed with
+// RetryProvisioningNow or its record is deleted.
+func (o *tridentOrchestrator) AbandonProvisioning(
+	volumeName string,
+) (*persistent_store.ProvisioningRetry, error) {
+	return o.updateProvisioningRetry(volumeName,
+		func(retry *persistent_store.ProvisioningRetry) {
+			retry.Abandoned = true
+		})
+}
+
+// updateProvisioningRetry applies update to a copy of a volume's retry
+// record, persists it, and returns another copy.
+func (o *tridentOrchestrator) updateProvisioningRetry(
+	volumeName string, update func(*persistent_store.ProvisioningRetry),
+) (*persistent_store.ProvisioningRetry, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	existing, ok := o.provisioningRetries[volumeName]
+	if !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Provisioning retry for volume %s not found.", volumeName)
+	}
+	retry := *existing
+	update(&retry)
+	if err := o.storeClient.AddProvisioningRetry(&retry); err != nil {
+		return nil, err
+	}
+	o.provisioningRetries[volumeName] = &retry
+	log.WithFields(log.Fields{
+		"volume":      volumeName,
+		"nextAttempt": retry.NextAttempt,
+		"abandoned":   retry.Abandoned,
+	}).Info("Updated provisioning retry.")
+
+	retryCopy := retry
+	return &retryCopy, nil
+}
+
+// DeleteProvisioningRetry forgets a volume's failed provisioning attempts,
+// e.g., once it has been provisioned or its request withdrawn.  Deleting
+// the record also lets the frontend retry immediately.
+func (o *tridentOrchestrator) DeleteProvisioningRetry(
+	volumeName string,
+) (bool, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	retry, ok := o.provisioningRetries[volumeName]
+	if !ok {
+		return false, errors.Errorf(errors.NotFound,
+			"Provisioning retry for volume %s not found.", volumeName)
+	}
+	if err := o.storeClient.DeleteProvisioningRetry(retry); err != nil {
+		return true, err
+	}
+	delete(o.provisioningRetries, volumeName)
+	return true, nil
+}
@@ -0,0 +1,310 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"sort"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/storage"
+	"github.com/netapp/trident/storage_class"
+)
+
+// rebalanceThreshold is the imbalance, see StorageClassBalance, above which
+// moves are recommended for a storage class.
+const rebalanceThreshold = 0.2
+
+// BackendLoad is the space provisioned for a storage class's volumes on one
+// of its backends.
+type BackendLoad struct {
+	Backend          string `json:"backend"`
+	Volumes          int    `json:"volumes"`
+	ProvisionedBytes uint64 `json:"provisionedBytes"`
+}
+
+// RebalanceMove recommends moving a volume to another backend.  Trident
+// can't move volumes, so moves are left to the administrator.
+type RebalanceMove struct {
+	Volume      string `json:"volume"`
+	SizeBytes   uint64 `json:"sizeBytes"`
+	FromBackend string `json:"fromBackend"`
+	FromPool    string `json:"fromPool"`
+	ToBackend   string `json:"toBackend"`
+	ToPool      string `json:"toPool"`
+}
+
+// StorageClassBalance reports how a storage class's volumes are spread
+// across its backends.
+type StorageClassBalance struct {
+	StorageClass string `json:"storageClass"`
+	// Backends are the online backends with pools in the class, by name,
+	// as they are before any moves.
+	Backends []*BackendLoad `json:"backends"`
+	// Imbalance is the difference between the space provisioned on the
+	// most and least loaded backends, as a fraction of the mean.
+	Imbalance float64 `json:"imbalance"`
+	// Moves, made in order, would bring the imbalance down to
+	// rebalanceThreshold or as close as the class's volumes allow.
+	Moves []*RebalanceMove `json:"moves,omitempty"`
+}
+
+// RebalanceReport is the result of a rebalancing analysis.
+type RebalanceReport struct {
+	AnalyzedAt     time.Time              `json:"analyzedAt"`
+	StorageClasses []*StorageClassBalance `json:"storageClasses"`
+}
+
+// GetRebalanceReport returns the latest rebalancing analysis, analyzing the
+// volumes now if they haven't been yet.
+func (o *tridentOrchestrator) GetRebalanceReport() *RebalanceReport {
+	o.mutex.Lock()
+	report := o.rebalanceReport
+	o.mutex.Unlock()
+	if report == nil {
+		report = o.analyzeRebalance()
+	}
+	return report
+}
+
+// startRebalanceAnalyzer analyzes the volumes' balance once per interval
+// for the life of the process.
+func (o *tridentOrchestrator) startRebalanceAnalyzer() {
+	go func() {
+		for range time.Tick(config.RebalanceInterval) {
+			o.analyzeRebalance()
+		}
+	}()
+}
+
+// analyzeRebalance analyzes the balance of each storage class's volumes,
+// records the report for GetRebalanceReport, and returns it.
+func (o *tridentOrchestrator) analyzeRebalance() *RebalanceReport {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	names := make([]string, 0, len(o.storageClasses))
+	for name := range o.storageClasses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	report := &RebalanceReport{
+		AnalyzedAt:     time.Now(),
+		StorageClasses: make([]*StorageClassBalance, 0, len(names)),
+	}
+	for _, name := range names {
+		balance := o.analyzeStorageClassBalance(o.storageClasses[name])
+		if len(balance.Moves) > 0 {
+			log.WithFields(log.Fields{
+				"storageClass": name,
+				"imbalance":    balance.Imbalance,
+				"moves":        len(balance.Moves),
+			}).Info("Storage class's volumes are unevenly spread across " +
+				"its backends.")
+		}
+		report.StorageClasses = append(report.StorageClasses, balance)
+	}
+	o.rebalanceReport = report
+	return report
+}
+
+// rebalanceVolume is a volume that could be moved, with its size.
+type rebalanceVolume struct {
+	vol  *storage.Volume
+	size uint64
+}
+
+// analyzeStorageClassBalance reports the balance of a storage class's
+// volumes, and recommends moves from its most to its least loaded backends
+// while the imbalance exceeds rebalanceThreshold.  The caller must hold the
+// orchestrator lock.
+func (o *tridentOrchestrator) analyzeStorageClassBalance(
+	sc *storage_class.StorageClass,
+) *StorageClassBalance {
+	balance := &StorageClassBalance{
+		StorageClass: sc.GetName(),
+		Backends:     make([]*BackendLoad, 0),
+	}
+	loads := make(map[string]*BackendLoad)
+	for _, pool := range sc.GetStoragePoolsForProtocol(config.ProtocolAny) {
+		if _, ok := loads[pool.Backend.Name]; ok || !pool.Backend.Online {
+			continue
+		}
+		load := &BackendLoad{Backend: pool.Backend.Name}
+		loads[load.Backend] = load
+		balance.Backends = append(balance.Backends, load)
+	}
+	sort.Sort(backendLoadsByName(balance.Backends))
+
+	// Only a class's volumes on its own backends count, and only those
+	// that aren't pinned to their backends can be moved.
+	movable := make(map[string][]*rebalanceVolume)
+	for _, vol := range o.volumes {
+		load, ok := loads[vol.Backend.Name]
+		if !ok || vol.Config.StorageClass != sc.GetName() {
+			continue
+		}
+		size, err := vol.Config.SizeBytes()
+		if err != nil {
+			continue
+		}
+		load.Volumes++
+		load.ProvisionedBytes += size
+		if vol.Deletion != nil || vol.Replication != nil ||
+			vol.Config.Group != "" || vol.Config.IsClone() {
+			continue
+		}
+		movable[load.Backend] = append(movable[load.Backend],
+			&rebalanceVolume{vol, size})
+	}
+	if len(balance.Backends) < 2 {
+		return balance
+	}
+
+	// Moves are planned against a copy of the loads.
+	planned := make(map[string]uint64, len(loads))
+	for name, load := range loads {
+		planned[name] = load.ProvisionedBytes
+	}
+	balance.Imbalance = imbalance(planned)
+	for imbalance(planned) > rebalanceThreshold {
+		move := o.planRebalanceMove(sc, planned, movable)
+		if move == nil {
+			break
+		}
+		balance.Moves = append(balance.Moves, move)
+	}
+	return balance
+}
+
+// planRebalanceMove finds a volume on the most loaded backend that can be
+// moved to a less loaded one, and brings the two backends' loads closest
+// together.  It updates planned and movable for the move.
+func (o *tridentOrchestrator) planRebalanceMove(
+	sc *storage_class.StorageClass, planned map[string]uint64,
+	movable map[string][]*rebalanceVolume,
+) *RebalanceMove {
+	backends := make([]string, 0, len(planned))
+	for name := range planned {
+		backends = append(backends, name)
+	}
+	sort.Sort(backendsByLoad{backends, planned})
+	source := backends[len(backends)-1]
+
+	// Try the least loaded backends first.
+	for _, target := range backends[:len(backends)-1] {
+		gap := planned[source] - planned[target]
+		var best *rebalanceVolume
+		var bestPool *storage.StoragePool
+		for _, candidate := range movable[source] {
+			// Moving a volume as large as the gap would only swap the
+			// backends' loads.
+			if candidate.size == 0 || candidate.size >= gap {
+				continue
+			}
+			if best != nil && distance(candidate.size, gap/2) >=
+				distance(best.size, gap/2) {
+				continue
+			}
+			if pool := o.rebalanceTarget(sc, candidate.vol,
+				target); pool != nil {
+				best, bestPool = candidate, pool
+			}
+		}
+		if best == nil {
+			continue
+		}
+		planned[source] -= best.size
+		planned[target] += best.size
+		remaining := make([]*rebalanceVolume, 0, len(movable[source]))
+		for _, candidate := range movable[source] {
+			if candidate != best {
+				remaining = append(remaining, candidate)
+			}
+		}
+		movable[source] = remaining
+		return &RebalanceMove{
+			Volume:      best.vol.Config.Name,
+			SizeBytes:   best.size,
+			FromBackend: source,
+			FromPool:    best.vol.Pool.Name,
+			ToBackend:   target,
+			ToPool:      bestPool.Name,
+		}
+	}
+	return nil
+}
+
+// rebalanceTarget returns the first storage pool, by name, of a backend in
+// the storage class that the volume could be placed in, or nil if there is
+// none.
+func (o *tridentOrchestrator) rebalanceTarget(
+	sc *storage_class.StorageClass, vol *storage.Volume, backendName string,
+) *storage.StoragePool {
+	avoid, _ := o.antiAffinityBackends(vol.Config)
+	if _, ok := avoid[backendName]; ok {
+		return nil
+	}
+	var target *storage.StoragePool
+	for _, pool := range sc.GetStoragePoolsForProtocol(vol.Config.Protocol) {
+		if pool.Backend.Name != backendName ||
+			(target != nil && target.Name < pool.Name) ||
+			len(placementReasons(vol.Config, sc, pool)) > 0 {
+			continue
+		}
+		target = pool
+	}
+	return target
+}
+
+// imbalance returns the difference between the largest and smallest
+// loads, as a fraction of the mean, or 0 if nothing is provisioned.
+func imbalance(loads map[string]uint64) float64 {
+	var total, min, max uint64
+	first := true
+	for _, load := range loads {
+		total += load
+		if first || load < min {
+			min = load
+		}
+		if first || load > max {
+			max = load
+		}
+		first = false
+	}
+	if total == 0 {
+		return 0
+	}
+	mean := float64(total) / float64(len(loads))
+	return float64(max-min) / mean
+}
+
+func distance(a, b uint64) uint64 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+type backendLoadsByName []*BackendLoad
+
+func (a backendLoadsByName) Len() int           { return len(a) }
+func (a backendLoadsByName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a backendLoadsByName) Less(i, j int) bool { return a[i].Backend < a[j].Backend }
+
+// backendsByLoad sorts backend names by their loads, then by name.
+type backendsByLoad struct {
+	names []string
+	loads map[string]uint64
+}
+
+func (b backendsByLoad) Len() int      { return len(b.names) }
+func (b backendsByLoad) Swap(i, j int) { b.names[i], b.names[j] = b.names[j], b.names[i] }
+func (b backendsByLoad) Less(i, j int) bool {
+	if b.loads[b.names[i]] != b.loads[b.names[j]] {
+		return b.loads[b.names[i]] < b.loads[b.names[j]]
+	}
+	return b.names[i] < b.names[j]
+}
@@ -0,0 +1,583 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/netapp/trident/errors"
+	"github.com/netapp/trident/storage"
+)
+
+// replicationMonitorInterval is how often the status of each replicated
+// volume's mirror relationship is checked.
+const replicationMonitorInterval = time.Minute
+
+// ReplicationConfig pairs a volume with the peer backend that is to hold
+// its mirror.  If PeerPool is empty, the first of the peer's pools, by
+// name, that serves the volume's protocol and storage class is used.
+type ReplicationConfig struct {
+	PeerBackend string `json:"peerBackend"`
+	PeerPool    string `json:"peerPool,omitempty"`
+}
+
+// checkNotReplicated returns a Conflict error for volumes that are being
+// mirrored, whose mirrors would otherwise be left behind.
+func checkNotReplicated(volume *storage.Volume) error {
+	if volume.Replication != nil {
+		return errors.Errorf(errors.Conflict, "Volume %s is replicated to "+
+			"backend %s; disable its replication first.",
+			volume.Config.Name, volume.Replication.PeerBackend)
+	}
+	return nil
+}
+
+// mirroredVolumeOnBackend returns the name of a volume whose mirror is on
+// the named backend, or "" if there is none.  The caller must hold the
+// orchestrator lock.
+func (o *tridentOrchestrator) mirroredVolumeOnBackend(
+	backendName string,
+) string {
+	for name, vol := range o.volumes {
+		if vol.Replication != nil &&
+			vol.Replication.PeerBackend == backendName {
+			return name
+		}
+	}
+	return ""
+}
+
+// replicationPool returns the pool of peer that is to hold the mirror of
+// volume.  The caller must hold the orchestrator lock.
+func (o *tridentOrchestrator) replicationPool(
+	volume *storage.Volume, peer *storage.StorageBackend, poolName string,
+) (*storage.StoragePool, error) {
+	protocol := volume.Pool.GetProtocol()
+	if poolName != "" {
+		pool, ok := peer.Storage[poolName]
+		if !ok {
+			return nil, errors.Errorf(errors.NotFound,
+				"Pool %s not found on backend %s.", poolName, peer.Name)
+		}
+		if pool.GetProtocol() != protocol {
+			return nil, errors.Errorf(errors.InvalidInput, "Pool %s of "+
+				"backend %s does not serve %s volumes.", poolName,
+				peer.Name, protocol)
+		}
+		return pool, nil
+	}
+	names := make([]string, 0, len(peer.Storage))
+	for name := range peer.Storage {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sc, hasClass := o.storageClasses[volume.Config.StorageClass]
+	for _, name := range names {
+		pool := peer.Storage[name]
+		if pool.GetProtocol() != protocol {
+			continue
+		}
+		if hasClass && !sc.ContainsPool(pool) {
+			continue
+		}
+		return pool, nil
+	}
+	return nil, errors.Errorf(errors.InvalidInput, "No pool of backend %s "+
+		"can hold the mirror of volume %s.", peer.Name, volume.Config.Name)
+}
+
+// EnableReplication mirrors a volume to a peer backend.  Both backends'
+// drivers must support replication.  The mirror's status is then checked
+// periodically and reported with the volume.
+func (o *tridentOrchestrator) EnableReplication(
+	volumeName string, replicationConfig *ReplicationConfig,
+) (*storage.VolumeExternal, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	volume, ok := o.volumes[volumeName]
+	if !ok {
+		return nil, o.volumeNotFoundError(volumeName)
+	}
+	if err := o.checkNotDeleting(volume); err != nil {
+		return nil, err
+	}
+	if volume.Replication != nil {
+		return nil, errors.Errorf(errors.AlreadyExists, "Volume %s is "+
+			"already replicated to backend %s.", volumeName,
+			volume.Replication.PeerBackend)
+	}
+	if !volume.Backend.SupportsReplication() {
+		return nil, errors.Errorf(errors.Conflict, "Backend %s of volume "+
+			"%s does not support replication.", volume.Backend.Name,
+			volumeName)
+	}
+	peer, ok := o.backends[replicationConfig.PeerBackend]
+	if !ok {
+		return nil, errors.Errorf(errors.NotFound, "Backend %s not found.",
+			replicationConfig.PeerBackend)
+	}
+	if peer == volume.Backend {
+		return nil, errors.Errorf(errors.InvalidInput, "Volume %s can't "+
+			"be replicated to its own backend.", volumeName)
+	}
+	if !peer.Online {
+		return nil, errors.Errorf(errors.Conflict,
+			"Backend %s is offline.", peer.Name)
+	}
+	if !peer.SupportsReplication() {
+		return nil, errors.Errorf(errors.Conflict,
+			"Backend %s does not support replication.", peer.Name)
+	}
+	pool, err := o.replicationPool(volume, peer, replicationConfig.PeerPool)
+	if err != nil {
+		return nil, err
+	}
+
+	replication, err := peer.CreateMirror(volume, pool)
+	if err != nil {
+		return nil, err
+	}
+	volume.Replication = replication
+	if err = o.storeClient.UpdateVolume(volume); err != nil {
+		volume.Replication = nil
+		if removeErr := peer.RemoveMirror(
+			replication.PeerInternalName); removeErr != nil {
+			log.WithFields(log.Fields{
+				"volume":      volumeName,
+				"peerBackend": peer.Name,
+				"mirror":      replication.PeerInternalName,
+				"error":       removeErr,
+			}).Error("Unable to remove mirror after failing to record it.")
+		}
+		return nil, err
+	}
+	log.WithFields(log.Fields{
+		"volume":      volumeName,
+		"peerBackend": peer.Name,
+		"peerPool":    pool.Name,
+		"mirror":      replication.PeerInternalName,
+	}).Info("Enabled volume replication.")
+	externalVol := volume.ConstructExternal()
+	o.markDirty()
+	o.events.publish(EventUpdate, EventObjectVolume, volumeName, externalVol)
+	return externalVol, nil
+}
+
+// DisableReplication breaks a volume's mirror relationship and destroys
+// the mirror.  If the peer backend no longer exists, only the record is
+// removed.
+func (o *tridentOrchestrator) DisableReplication(
+	volumeName string,
+) (*storage.VolumeExternal, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	volume, ok := o.volumes[volumeName]
+	if !ok {
+		return nil, o.volumeNotFoundError(volumeName)
+	}
+	replication := volume.Replication
+	if replication == nil {
+		return nil, errors.Errorf(errors.Conflict,
+			"Volume %s is not replicated.", volumeName)
+	}
+	if peer, ok := o.backends[replication.PeerBackend]; ok {
+		if err := peer.RemoveMirror(
+			replication.PeerInternalName); err != nil {
+			return nil, err
+		}
+	} else {
+		log.WithFields(log.Fields{
+			"volume":      volumeName,
+			"peerBackend": replication.PeerBackend,
+			"mirror":      replication.PeerInternalName,
+		}).Warn("Peer backend not found; its mirror is left in place.")
+	}
+	volume.Replication = nil
+	if err := o.storeClient.UpdateVolume(volume); err != nil {
+		volume.Replication = replication
+		return nil, err
+	}
+	log.WithFields(log.Fields{
+		"volume":      volumeName,
+		"peerBackend": replication.PeerBackend,
+	}).Info("Disabled volume replication.")
+	externalVol := volume.ConstructExternal()
+	o.markDirty()
+	o.events.publish(EventUpdate, EventObjectVolume, volumeName, externalVol)
+	return externalVol, nil
+}
+
+// FailoverVolume performs a planned failover of a replicated volume:  its
+// mirror is brought up to date and broken, and the volume is moved to the
+// mirror, on the peer backend.  The mirror must be healthy and the volume
+// detached.  The former source is left on its backend, outside of Trident;
+// replication may then be enabled again, e.g., back to the original
+// backend.
+func (o *tridentOrchestrator) FailoverVolume(
+	volumeName string,
+) (*storage.VolumeExternal, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	volume, ok := o.volumes[volumeName]
+	if !ok {
+		return nil, o.volumeNotFoundError(volumeName)
+	}
+	if err := o.checkNotDeleting(volume); err != nil {
+		return nil, err
+	}
+	if volume.Replication == nil {
+		return nil, errors.Errorf(errors.Conflict,
+			"Volume %s is not replicated.", volumeName)
+	}
+	if len(volume.Attachments) > 0 {
+		return nil, errors.Errorf(errors.Conflict, "Volume %s is attached "+
+			"to node(s) %s; detach it first.", volumeName,
+			strings.Join(volume.AttachedNodes(), ", "))
+	}
+	if err := o.failoverVolume(volume, true); err != nil {
+		return nil, err
+	}
+	externalVol := volume.ConstructExternal()
+	o.markDirty()
+	o.events.publish(EventUpdate, EventObjectVolume, volumeName, externalVol)
+	return externalVol, nil
+}
+
+// BackendFailoverReport reports the volumes FailoverBackend moved to their
+// mirrors, and those it could not, which remain on the failed backend.
+type BackendFailoverReport struct {
+	Backend    string            `json:"backend"`
+	FailedOver []*VolumeFailover `json:"failedOver"`
+	Failed     []*VolumeFailover `json:"failed"`
+	// Offline is true if the failed backend was offlined.
+	Offline bool `json:"offline"`
+}
+
+// VolumeFailover reports the failover of one volume.
+type VolumeFailover struct {
+	Volume      string `json:"volume"`
+	PeerBackend string `json:"peerBackend,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+func (r *BackendFailoverReport) add(
+	volumeName string, replication *storage.VolumeReplication, err error,
+) {
+	result := &VolumeFailover{Volume: volumeName}
+	if replication != nil {
+		result.PeerBackend = replication.PeerBackend
+	}
+	if err != nil {
+		result.Error = err.Error()
+		r.Failed = append(r.Failed, result)
+		return
+	}
+	r.FailedOver = append(r.FailedOver, result)
+}
+
+// FailoverBackend fails over every volume of a backend that has been lost,
+// e.g., with its site:  each replicated volume's mirror is broken, without
+// first being updated, and the volume is moved to it.  The backend may be
+// one that failed to initialize.  Volumes that aren't replicated, or whose
+// mirrors can't be promoted, are reported and left in place; calling
+// FailoverBackend again retries them.  The backend is then offlined, so
+// that no more volumes are placed on it.  Unlike FailoverVolume, attached
+// volumes are failed over too, keeping their attachments; their nodes must
+// mount them again.
+func (o *tridentOrchestrator) FailoverBackend(
+	backendName string,
+) (*BackendFailoverReport, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	backend, ok := o.backends[backendName]
+	if _, failed := o.failedBackends[backendName]; !ok && !failed {
+		return nil, errors.Errorf(errors.NotFound,
+			"Backend %s not found.", backendName)
+	}
+	report := &BackendFailoverReport{
+		Backend:    backendName,
+		FailedOver: make([]*VolumeFailover, 0),
+		Failed:     make([]*VolumeFailover, 0),
+	}
+
+	names := make([]string, 0)
+	for name, vol := range o.volumes {
+		if vol.Backend.Name == backendName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		vol := o.volumes[name]
+		replication := vol.Replication
+		if replication == nil {
+			report.add(name, nil, errors.Errorf(errors.Conflict,
+				"Volume %s is not replicated.", name))
+			continue
+		}
+		err := o.failoverVolume(vol, false)
+		if err == nil {
+			o.markDirty()
+			o.events.publish(EventUpdate, EventObjectVolume, name,
+				vol.ConstructExternal())
+		}
+		report.add(name, replication, err)
+	}
+
+	// Volumes of a backend that failed to initialize are orphaned.
+	names = names[:0]
+	for name, orphan := range o.orphanedVolumes {
+		if orphan.Backend == backendName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		orphan := o.orphanedVolumes[name]
+		if orphan.Replication == nil {
+			report.add(name, nil, errors.Errorf(errors.Conflict,
+				"Volume %s is not replicated.", name))
+			continue
+		}
+		report.add(name, orphan.Replication, o.failoverOrphanedVolume(orphan))
+	}
+
+	if backend != nil {
+		if _, err := o.offlineBackend(backendName); err != nil {
+			log.WithFields(log.Fields{
+				"backend": backendName,
+				"error":   err,
+			}).Warn("Unable to offline failed-over backend.")
+		} else {
+			report.Offline = true
+		}
+	}
+	logFields := log.Fields{
+		"backend":    backendName,
+		"failedOver": len(report.FailedOver),
+		"failed":     len(report.Failed),
+	}
+	if len(report.Failed) > 0 {
+		log.WithFields(logFields).Warn("Failed backend over; some volumes " +
+			"could not be failed over.")
+	} else {
+		log.WithFields(logFields).Info("Failed backend over.")
+	}
+	return report, nil
+}
+
+// replicationPeer returns the backend and pool that hold a mirror.  The
+// caller must hold the orchestrator lock.
+func (o *tridentOrchestrator) replicationPeer(
+	replication *storage.VolumeReplication,
+) (*storage.StorageBackend, *storage.StoragePool, error) {
+	peer, ok := o.backends[replication.PeerBackend]
+	if !ok {
+		return nil, nil, errors.Errorf(errors.NotFound,
+			"Peer backend %s not found.", replication.PeerBackend)
+	}
+	pool, ok := peer.Storage[replication.PeerPool]
+	if !ok {
+		return nil, nil, errors.Errorf(errors.NotFound,
+			"Pool %s not found on backend %s.", replication.PeerPool,
+			peer.Name)
+	}
+	return peer, pool, nil
+}
+
+// failoverOrphanedVolume moves an orphaned volume, whose backend failed to
+// initialize, to its mirror.  The caller must hold the orchestrator lock.
+func (o *tridentOrchestrator) failoverOrphanedVolume(
+	orphan *storage.VolumeExternal,
+) error {
+	replication := orphan.Replication
+	peer, pool, err := o.replicationPeer(replication)
+	if err != nil {
+		return err
+	}
+	source := storage.NewVolume(orphan.Config, nil, nil)
+	source.Replication = replication
+	promoted, err := peer.PromoteMirror(source, pool)
+	if err != nil {
+		return err
+	}
+	volume := storage.NewVolume(promoted, peer, pool)
+	volume.Attachments = orphan.Attachments
+	volume.Deletion = orphan.Deletion
+	if err = o.storeClient.UpdateVolume(volume); err != nil {
+		return err
+	}
+	pool.AddVolume(volume, false)
+	o.volumes[promoted.Name] = volume
+	delete(o.orphanedVolumes, promoted.Name)
+	log.WithFields(log.Fields{
+		"volume":        promoted.Name,
+		"backend":       peer.Name,
+		"pool":          pool.Name,
+		"formerBackend": orphan.Backend,
+	}).Info("Failed orphaned volume over to its mirror.")
+	externalVol := volume.ConstructExternal()
+	o.markDirty()
+	o.events.publish(EventUpdate, EventObjectVolume, promoted.Name,
+		externalVol)
+	return nil
+}
+
+// failoverVolume moves a replicated volume to its mirror.  A planned
+// failover requires a healthy mirror and brings it up to date first.  The
+// caller must hold the orchestrator lock.
+func (o *tridentOrchestrator) failoverVolume(
+	volume *storage.Volume, planned bool,
+) error {
+	replication := volume.Replication
+	peer, pool, err := o.replicationPeer(replication)
+	if err != nil {
+		return err
+	}
+	if planned {
+		status, err := peer.GetMirrorStatus(replication.PeerInternalName)
+		if err != nil {
+			return err
+		}
+		if !status.Healthy || status.State == storage.MirrorBroken {
+			return errors.Errorf(errors.Conflict, "The mirror of volume %s "+
+				"is %s and unhealthy:  %s", volume.Config.Name, status.State,
+				status.Message)
+		}
+		if err = peer.UpdateMirror(replication.PeerInternalName); err != nil {
+			return err
+		}
+	}
+	promoted, err := peer.PromoteMirror(volume, pool)
+	if err != nil {
+		return err
+	}
+
+	oldConfig, oldBackend, oldPool := volume.Config, volume.Backend, volume.Pool
+	oldPool.DeleteVolume(volume)
+	volume.Config, volume.Backend, volume.Pool = promoted, peer, pool
+	volume.Replication = nil
+	pool.AddVolume(volume, false)
+	if err = o.storeClient.UpdateVolume(volume); err != nil {
+		// The mirror is broken, but the volume still refers to its source,
+		// which remains intact.
+		pool.DeleteVolume(volume)
+		volume.Config, volume.Backend, volume.Pool = oldConfig, oldBackend,
+			oldPool
+		volume.Replication = replication
+		oldPool.AddVolume(volume, false)
+		return err
+	}
+	log.WithFields(log.Fields{
+		"volume":        volume.Config.Name,
+		"backend":       peer.Name,
+		"pool":          pool.Name,
+		"formerBackend": oldBackend.Name,
+		"formerSource":  oldConfig.InternalName,
+		"planned":       planned,
+	}).Info("Failed volume over to its mirror; the former source is left " +
+		"in place.")
+	return nil
+}
+
+// startReplicationMonitor checks the replicated volumes' mirrors once per
+// interval for the life of the process.
+func (o *tridentOrchestrator) startReplicationMonitor() {
+	go func() {
+		for range time.Tick(replicationMonitorInterval) {
+			o.checkReplication()
+		}
+	}()
+}
+
+type replicationCheck struct {
+	volume      string
+	replication *storage.VolumeReplication
+	peer        *storage.StorageBackend
+	status      *storage.MirrorStatus
+	checkedAt   time.Time
+}
+
+// checkReplication records the status of each replicated volume's mirror.
+// Peer backends are queried without holding the orchestrator lock.
+// Records are only rewritten when a relationship's state or health
+// changes, not merely its lag.
+func (o *tridentOrchestrator) checkReplication() {
+	checks := make([]*replicationCheck, 0)
+	o.mutex.Lock()
+	for name, vol := range o.volumes {
+		if vol.Replication == nil {
+			continue
+		}
+		checks = append(checks, &replicationCheck{
+			volume:      name,
+			replication: vol.Replication,
+			peer:        o.backends[vol.Replication.PeerBackend],
+		})
+	}
+	o.mutex.Unlock()
+
+	for _, c := range checks {
+		if c.peer == nil {
+			c.status = &storage.MirrorStatus{
+				State: c.replication.Status.State,
+				Message: "Peer backend " + c.replication.PeerBackend +
+					" not found.",
+			}
+		} else {
+			var err error
+			c.status, err = c.peer.GetMirrorStatus(
+				c.replication.PeerInternalName)
+			if err != nil {
+				c.status = &storage.MirrorStatus{
+					State:   c.replication.Status.State,
+					Message: err.Error(),
+				}
+			}
+		}
+		c.checkedAt = time.Now().UTC()
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	for _, c := range checks {
+		vol, ok := o.volumes[c.volume]
+		// Skip volumes whose replication changed during the check.
+		if !ok || vol.Replication != c.replication {
+			continue
+		}
+		previous := vol.Replication.Status
+		vol.Replication.SetStatus(c.status, c.checkedAt)
+		o.markDirty()
+		if previous.State == c.status.State &&
+			previous.Healthy == c.status.Healthy &&
+			previous.Message == c.status.Message {
+			continue
+		}
+		logFields := log.Fields{
+			"volume":      c.volume,
+			"peerBackend": c.replication.PeerBackend,
+			"state":       c.status.State,
+		}
+		if c.status.Healthy {
+			log.WithFields(logFields).Info("Volume mirror is healthy.")
+		} else {
+			log.WithFields(logFields).Warnf("Volume mirror is unhealthy:  "+
+				"%s", c.status.Message)
+		}
+		if err := o.storeClient.UpdateVolume(vol); err != nil {
+			log.WithFields(logFields).Warnf("Unable to record mirror "+
+				"status:  %v", err)
+		}
+		o.events.publish(EventUpdate, EventObjectVolume, c.volume,
+			vol.ConstructExternal())
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/netapp/trident/storage"
+	sa "github.com/netapp/trident/storage_attribute"
+	"github.com/netapp/trident/storage_class"
+)
+
+// BackendResult is the result of adding or updating a backend:  the backend
+// and any warnings about it that didn't stop it from being added, e.g.,
+// that it satisfies no storage classes.
+type BackendResult struct {
+	*storage.StorageBackendExternal
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// BackendsResult is the result of adding several backends from one
+// document:  the result of adding each, in the document's order.
+type BackendsResult struct {
+	Backends []*BackendItemResult `json:"backends"`
+	// Failed is the number of backends that weren't added.
+	Failed int `json:"failed"`
+}
+
+// BackendItemResult is the result of adding one of several backends;
+// either Backend or Error is set.
+type BackendItemResult struct {
+	// Index is the position of the backend's config in the document.
+	Index   int            `json:"index"`
+	Backend *BackendResult `json:"backend,omitempty"`
+	Error   string         `json:"error,omitempty"`
+	// Err is the error adding the backend, for frontends that report errors
+	// by kind.
+	Err error `json:"-"`
+}
+
+// add records the result of adding the next backend.
+func (r *BackendsResult) add(backend *BackendResult, err error) {
+	item := &BackendItemResult{Index: len(r.Backends)}
+	if err != nil {
+		item.Error = err.Error()
+		item.Err = err
+		r.Failed++
+	} else {
+		item.Backend = backend
+	}
+	r.Backends = append(r.Backends, item)
+}
+
+// AllFailed returns an error if none of the backends were added.
+func (r *BackendsResult) AllFailed() error {
+	if r.Failed < len(r.Backends) {
+		return nil
+	}
+	return fmt.Errorf("None of the %d backends were added.", len(r.Backends))
+}
+
+// VolumeResult is the result of creating a volume:  the volume and any
+// warnings about it that didn't stop it from being created, e.g., that its
+// consumed capacity is unknown.
+type VolumeResult struct {
+	*storage.VolumeExternal
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// StorageClassResult is the result of adding or updating a storage class:
+// the storage class and any warnings about it that didn't stop it from
+// being added, e.g., that it requests a value an attribute doesn't take.
+type StorageClassResult struct {
+	*storage_class.StorageClassExternal
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// backendWarnings returns the warnings about a backend that was just added
+// and whose pools satisfy classes.
+func backendWarnings(
+	backend *storage.StorageBackend, classes []string,
+) []string {
+	var warnings []string
+	if len(classes) == 0 && backend.Online {
+		warnings = append(warnings, fmt.Sprintf("Backend %s satisfies no "+
+			"storage classes; no volumes will be created on it until a "+
+			"storage class matches its pools.", backend.Name))
+	}
+	if !backend.SupportsUsage() {
+		warnings = append(warnings, capacityUnknownWarning(backend))
+	}
+	if backend.Rotation.PendingPassword != "" {
+		warnings = append(warnings, fmt.Sprintf("Backend %s's last "+
+			"credential rotation was not finished; update it with its "+
+			"current password to resume rotation.", backend.Name))
+	}
+	return warnings
+}
+
+// capacityUnknownWarning warns that the space a backend's volumes consume
+// can't be reported.
+func capacityUnknownWarning(backend *storage.StorageBackend) string {
+	return fmt.Sprintf("Backend %s doesn't report the space its volumes "+
+		"consume; their consumed capacity is unknown.", backend.Name)
+}
+
+// volumeWarnings returns the warnings about a volume that was just created
+// after its creation failed in other storage pools.
+func volumeWarnings(
+	vol *storage.Volume, failures map[*storage.StoragePool]error,
+) []string {
+	var warnings []string
+	for pool, err := range failures {
+		warnings = append(warnings, fmt.Sprintf("Unable to create the "+
+			"volume in storage pool %s of backend %s, so it was created in "+
+			"storage pool %s of backend %s:  %v", pool.Name,
+			pool.Backend.Name, vol.Pool.Name, vol.Backend.Name, err))
+	}
+	sort.Strings(warnings)
+	if !vol.Backend.SupportsUsage() {
+		warnings = append(warnings, capacityUnknownWarning(vol.Backend))
+	}
+	return warnings
+}
+
+// storageClassWarnings returns the warnings about a storage class that was
+// just added or updated and is satisfied by added storage pools:  the
+// attribute requests no online pool can satisfy.  The caller must hold the
+// orchestrator lock.
+func (o *tridentOrchestrator) storageClassWarnings(
+	sc *storage_class.StorageClass, added int,
+) []string {
+	if added > 0 {
+		return nil
+	}
+	warnings := []string{fmt.Sprintf("No storage pools satisfy storage "+
+		"class %s; its volumes can't be created until a backend's pools "+
+		"match it.", sc.GetName())}
+	if len(o.backends) == 0 {
+		return warnings
+	}
+	requests := sc.GetAttributes()
+	names := make([]string, 0, len(requests))
+	for name := range requests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if o.attributeOffered(name, requests[name]) {
+			continue
+		}
+		warning := fmt.Sprintf("No storage pool offers %s=%s.", name,
+			requests[name])
+		if schema := sa.GetSchema(name); schema != nil &&
+			len(schema.Drivers) > 0 {
+			warning += fmt.Sprintf("  Only %s pools can.",
+				strings.Join(schema.Drivers, " and "))
+		}
+		warnings = append(warnings, warning)
+	}
+	return warnings
+}
+
+// attributeOffered returns whether a pool of any online backend satisfies
+// an attribute request.  The caller must hold the orchestrator lock.
+func (o *tridentOrchestrator) attributeOffered(
+	name string, request sa.Request,
+) bool {
+	for _, backend := range o.backends {
+		if !backend.Online {
+			continue
+		}
+		for _, pool := range backend.Storage {
+			if offer, ok := pool.Attributes[name]; ok &&
+				offer.Matches(request) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/netapp/trident/errors"
+	"github.com/netapp/trident/persistent_store"
+	"github.com/netapp/trident/storage"
+)
+
+func (o *tridentOrchestrator) bootstrapRetainedVolumes() error {
+	retainedVols, err := o.storeClient.GetRetainedVolumes()
+	if err != nil {
+		return err
+	}
+	for _, r := range retainedVols {
+		if vol, ok := o.volumes[r.Config.Name]; ok && r.Matches(vol) {
+			// The volume was imported, or its deletion was interrupted,
+			// before the record was removed.
+			if err = o.storeClient.DeleteRetainedVolume(r); err != nil {
+				return err
+			}
+			continue
+		}
+		o.retainedVolumes[r.Config.Name] = r
+		log.WithFields(log.Fields{
+			"volume":  r.Config.Name,
+			"backend": r.Backend,
+			"handler": "Bootstrap",
+		}).Info("Added an existing retained volume.")
+	}
+	return nil
+}
+
+// retainVolume records that a volume with the Retain reclaim policy is
+// being removed from Trident but left on its backend.  The caller must hold
+// the orchestrator lock and remove the volume's own record.
+func (o *tridentOrchestrator) retainVolume(volume *storage.Volume) error {
+	retained := persistent_store.NewRetainedVolume(volume)
+	if err := o.storeClient.AddRetainedVolume(retained); err != nil {
+		log.WithFields(log.Fields{
+			"volume": volume.Config.Name,
+		}).Error("Unable to record retained volume.")
+		return err
+	}
+	volume.Pool.DeleteVolume(volume)
+	o.retainedVolumes[volume.Config.Name] = retained
+	o.markDirty()
+	log.WithFields(log.Fields{
+		"volume":       volume.Config.Name,
+		"internalName": volume.Config.InternalName,
+		"backend":      volume.Backend.Name,
+	}).Info("Retained volume on its backend.")
+	return nil
+}
+
+// ListRetainedVolumes returns the volumes that were deleted from Trident
+// but left on their backends.
+func (o *tridentOrchestrator) ListRetainedVolumes() []*persistent_store.RetainedVolume {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	ret := make([]*persistent_store.RetainedVolume, 0,
+		len(o.retainedVolumes))
+	for _, r := range o.retainedVolumes {
+		retained := *r
+		ret = append(ret, &retained)
+	}
+	return ret
+}
+
+// ImportRetainedVolume adds a retained volume back to Trident under its
+// original name.  If backendName or poolName is empty, the volume's previous
+// backend or pool is used; the volume must still exist there with the same
+// internal name.
+func (o *tridentOrchestrator) ImportRetainedVolume(
+	volumeName, backendName, poolName string,
+) (*storage.VolumeExternal, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	retained, ok := o.retainedVolumes[volumeName]
+	if !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Retained volume %s not found.", volumeName)
+	}
+	if o.volumeExists(volumeName) {
+		return nil, errors.Errorf(errors.AlreadyExists,
+			"Volume %s already exists.", volumeName)
+	}
+	if backendName == "" {
+		backendName = retained.Backend
+	}
+	if poolName == "" {
+		poolName = retained.Pool
+	}
+	pool, err := o.getPoolForExistingVolume(retained.Config, backendName,
+		poolName)
+	if err != nil {
+		return nil, err
+	}
+
+	vol := storage.NewVolume(retained.Config, pool.Backend, pool)
+	if err = o.storeClient.AddVolume(vol); err != nil {
+		return nil, err
+	}
+	pool.AddVolume(vol, false)
+	o.volumes[volumeName] = vol
+	delete(o.retainedVolumes, volumeName)
+	if err = o.storeClient.DeleteRetainedVolume(retained); err != nil {
+		log.WithFields(log.Fields{
+			"volume": volumeName,
+		}).Warn("Unable to delete retained volume record; it will be " +
+			"removed on restart.")
+	}
+	log.WithFields(log.Fields{
+		"volume":  volumeName,
+		"backend": backendName,
+		"pool":    poolName,
+	}).Info("Imported retained volume.")
+	externalVol := vol.ConstructExternal()
+	o.markDirty()
+	o.events.publish(EventCreate, EventObjectVolume, volumeName, externalVol)
+	return externalVol, nil
+}
+
+// DeleteRetainedVolume forgets a retained volume.  The volume itself is
+// left on its backend.
+func (o *tridentOrchestrator) DeleteRetainedVolume(
+	volumeName string,
+) (bool, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	retained, ok := o.retainedVolumes[volumeName]
+	if !ok {
+		return false, errors.Errorf(errors.NotFound,
+			"Retained volume %s not found.", volumeName)
+	}
+	if err := o.storeClient.DeleteRetainedVolume(retained); err != nil {
+		return true, err
+	}
+	delete(o.retainedVolumes, volumeName)
+	log.WithFields(log.Fields{
+		"volume":  volumeName,
+		"backend": retained.Backend,
+	}).Warn("Removed retained volume from Trident; it was not deleted " +
+		"from its backend.")
+	return true, nil
+}
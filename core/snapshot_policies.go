@@ -0,0 +1,185 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+
+	"github.com/netapp/trident/errors"
+	"github.com/netapp/trident/snapshot_policy"
+	"github.com/netapp/trident/storage"
+)
+
+// snapshotSchedulerInterval is how often the scheduler checks for policies
+// that are due.  Schedules have a granularity of one minute.
+const snapshotSchedulerInterval = time.Minute
+
+func (o *tridentOrchestrator) bootstrapSnapshotPolicies() error {
+	persistentPolicies, err := o.storeClient.GetSnapshotPolicies()
+	if err != nil {
+		return err
+	}
+	for _, p := range persistentPolicies {
+		o.snapshotPolicies[p.Name] = p
+		log.WithFields(log.Fields{
+			"snapshotPolicy": p.Name,
+			"handler":        "Bootstrap",
+		}).Info("Added an existing snapshot policy.")
+	}
+	return nil
+}
+
+func (o *tridentOrchestrator) AddSnapshotPolicy(
+	policyConfig *snapshot_policy.Config,
+) (*snapshot_policy.Config, error) {
+	if err := policyConfig.Validate(); err != nil {
+		return nil, err
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if _, ok := o.snapshotPolicies[policyConfig.Name]; ok {
+		return nil, errors.Errorf(errors.AlreadyExists,
+			"Snapshot policy %s already exists.", policyConfig.Name)
+	}
+	policy := *policyConfig
+	if err := o.storeClient.AddSnapshotPolicy(&policy); err != nil {
+		return nil, err
+	}
+	o.snapshotPolicies[policy.Name] = &policy
+	log.WithFields(log.Fields{
+		"snapshotPolicy": policy.Name,
+		"schedule":       policy.Schedule,
+		"retention":      policy.Retention,
+	}).Info("Added a new snapshot policy.")
+	ret := policy
+	return &ret, nil
+}
+
+func (o *tridentOrchestrator) GetSnapshotPolicy(
+	policyName string,
+) *snapshot_policy.Config {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	policy, ok := o.snapshotPolicies[policyName]
+	if !ok {
+		return nil
+	}
+	ret := *policy
+	return &ret
+}
+
+func (o *tridentOrchestrator) ListSnapshotPolicies() []*snapshot_policy.Config {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	ret := make([]*snapshot_policy.Config, 0, len(o.snapshotPolicies))
+	for _, policy := range o.snapshotPolicies {
+		policyCopy := *policy
+		ret = append(ret, &policyCopy)
+	}
+	return ret
+}
+
+// DeleteSnapshotPolicy removes a policy.  Volumes that still name it are no
+// longer snapshotted, but their existing snapshots are left in place.
+func (o *tridentOrchestrator) DeleteSnapshotPolicy(policyName string) (bool, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	policy, ok := o.snapshotPolicies[policyName]
+	if !ok {
+		return false, errors.Errorf(errors.NotFound,
+			"Snapshot policy %s not found.", policyName)
+	}
+	if err := o.storeClient.DeleteSnapshotPolicy(policy); err != nil {
+		return true, err
+	}
+	delete(o.snapshotPolicies, policyName)
+	return true, nil
+}
+
+// startSnapshotScheduler checks for due snapshot policies once per
+// interval for the life of the process.
+func (o *tridentOrchestrator) startSnapshotScheduler() {
+	last := time.Now().Truncate(time.Minute)
+	go func() {
+		for now := range time.Tick(snapshotSchedulerInterval) {
+			// Evaluate every minute since the previous tick, so that a late
+			// tick doesn't cause a scheduled snapshot to be skipped.
+			now = now.Truncate(time.Minute)
+			for t := last.Add(time.Minute); !t.After(now); t = t.Add(time.Minute) {
+				o.runScheduledSnapshots(t)
+			}
+			last = now
+		}
+	}()
+}
+
+type scheduledSnapshot struct {
+	policy *snapshot_policy.Config
+	volume *storage.Volume
+}
+
+// runScheduledSnapshots snapshots each volume whose policy is due at t and
+// prunes the policy's snapshots beyond its retention count.  Backends are
+// called without holding the orchestrator lock.
+func (o *tridentOrchestrator) runScheduledSnapshots(t time.Time) {
+	due := make([]scheduledSnapshot, 0)
+	o.mutex.Lock()
+	for name, vol := range o.volumes {
+		policy, ok := o.snapshotPolicies[vol.Config.SnapshotSchedule]
+		if !ok || vol.IsTerminating() || o.deletingVolumes[name] {
+			continue
+		}
+		// The schedule was validated when the policy was added.
+		schedule, err := snapshot_policy.ParseSchedule(policy.Schedule)
+		if err != nil || !schedule.Matches(t) {
+			continue
+		}
+		policyCopy := *policy
+		due = append(due, scheduledSnapshot{&policyCopy, vol})
+	}
+	timeout := o.timeouts.ProvisionTimeout()
+	o.mutex.Unlock()
+
+	for _, s := range due {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		s.run(ctx, t)
+		cancel()
+	}
+}
+
+// run takes the scheduled snapshot at t and prunes the policy's snapshots
+// beyond its retention count.
+func (s scheduledSnapshot) run(ctx context.Context, t time.Time) {
+	logFields := log.Fields{
+		"snapshotPolicy": s.policy.Name,
+		"volume":         s.volume.Config.Name,
+		"backend":        s.volume.Backend.Name,
+	}
+	snapshotName := s.policy.SnapshotName(t)
+	if err := s.volume.Backend.CreateSnapshot(ctx, s.volume,
+		snapshotName); err != nil {
+		log.WithFields(logFields).Warnf("Unable to create scheduled "+
+			"snapshot:  %v", err)
+		return
+	}
+	log.WithFields(logFields).Infof("Created snapshot %s.", snapshotName)
+
+	snapshots, err := s.volume.Backend.ListSnapshots(ctx, s.volume)
+	if err != nil {
+		log.WithFields(logFields).Warnf("Unable to list snapshots for "+
+			"pruning:  %v", err)
+		return
+	}
+	for _, name := range s.policy.Prune(snapshots) {
+		if err = s.volume.Backend.DeleteSnapshot(ctx, s.volume,
+			name); err != nil {
+			log.WithFields(logFields).Warnf("Unable to prune snapshot "+
+				"%s:  %v", name, err)
+			continue
+		}
+		log.WithFields(logFields).Infof("Pruned snapshot %s.", name)
+	}
+}
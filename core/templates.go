@@ -0,0 +1,130 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+
+	"github.com/netapp/trident/app_template"
+	"github.com/netapp/trident/errors"
+	"github.com/netapp/trident/persistent_store"
+)
+
+func copyTemplate(t *app_template.Config) *app_template.Config {
+	ret := *t
+	ret.Volumes = make([]*app_template.VolumeTemplate, 0, len(t.Volumes))
+	for _, v := range t.Volumes {
+		volumeCopy := *v
+		ret.Volumes = append(ret.Volumes, &volumeCopy)
+	}
+	return &ret
+}
+
+func (o *tridentOrchestrator) bootstrapTemplates() error {
+	templates, err := o.storeClient.GetTemplates()
+	if err != nil {
+		return err
+	}
+	for _, t := range templates {
+		o.templates[t.Name] = t
+		log.WithFields(log.Fields{
+			"template": t.Name,
+			"handler":  "Bootstrap",
+		}).Info("Added an existing template.")
+	}
+	return nil
+}
+
+func (o *tridentOrchestrator) AddTemplate(
+	templateConfig *app_template.Config,
+) (*app_template.Config, error) {
+	if err := templateConfig.Validate(); err != nil {
+		return nil, err
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if _, ok := o.templates[templateConfig.Name]; ok {
+		return nil, errors.Errorf(errors.AlreadyExists,
+			"Template %s already exists.", templateConfig.Name)
+	}
+	template := copyTemplate(templateConfig)
+	if err := o.storeClient.AddTemplate(template); err != nil {
+		return nil, err
+	}
+	o.templates[template.Name] = template
+	log.WithFields(log.Fields{
+		"template": template.Name,
+		"volumes":  len(template.Volumes),
+	}).Info("Added a new template.")
+	return copyTemplate(template), nil
+}
+
+func (o *tridentOrchestrator) GetTemplate(
+	templateName string,
+) *app_template.Config {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	template, ok := o.templates[templateName]
+	if !ok {
+		return nil
+	}
+	return copyTemplate(template)
+}
+
+func (o *tridentOrchestrator) ListTemplates() []*app_template.Config {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	ret := make([]*app_template.Config, 0, len(o.templates))
+	for _, template := range o.templates {
+		ret = append(ret, copyTemplate(template))
+	}
+	return ret
+}
+
+// DeleteTemplate removes a template.  Groups provisioned from it are left
+// in place.
+func (o *tridentOrchestrator) DeleteTemplate(templateName string) (bool, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	template, ok := o.templates[templateName]
+	if !ok {
+		return false, errors.Errorf(errors.NotFound,
+			"Template %s not found.", templateName)
+	}
+	if err := o.storeClient.DeleteTemplate(template); err != nil {
+		return true, err
+	}
+	delete(o.templates, templateName)
+	return true, nil
+}
+
+// ProvisionTemplate creates an instance of a template:  a volume group,
+// named for the instance, holding one volume for each of the template's,
+// named "<instance>-<volume>".  The group records the template it was
+// provisioned from, and is snapshotted and deleted like any other.
+func (o *tridentOrchestrator) ProvisionTemplate(
+	ctx context.Context, templateName, instanceName string,
+) (*persistent_store.VolumeGroup, error) {
+	if instanceName == "" {
+		return nil, errors.Errorf(errors.InvalidInput,
+			"Template instances must have a name.")
+	}
+	template := o.GetTemplate(templateName)
+	if template == nil {
+		return nil, errors.Errorf(errors.NotFound,
+			"Template %s not found.", templateName)
+	}
+	group, err := o.addVolumeGroup(ctx, &VolumeGroupConfig{
+		Name:    instanceName,
+		Volumes: template.VolumeConfigs(instanceName),
+	}, templateName)
+	if err != nil {
+		return nil, err
+	}
+	log.WithFields(log.Fields{
+		"template":    templateName,
+		"volumeGroup": group.Name,
+	}).Info("Provisioned template.")
+	return group, nil
+}
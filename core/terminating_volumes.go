@@ -0,0 +1,272 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+
+	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/errors"
+	"github.com/netapp/trident/persistent_store"
+	"github.com/netapp/trident/storage"
+)
+
+// checkNotTerminating returns a Conflict error if volume has been deleted
+// and is waiting out its deletion grace period.
+func checkNotTerminating(volume *storage.Volume) error {
+	if volume.IsTerminating() {
+		return errors.Errorf(errors.Conflict, "Volume %s is being deleted; "+
+			"undelete it first.", volume.Config.Name)
+	}
+	return nil
+}
+
+// checkNotDeleting returns a Conflict error if volume is terminating, as
+// checkNotTerminating does, or is being destroyed by a bulk delete.  The
+// caller must hold the orchestrator lock.
+func (o *tridentOrchestrator) checkNotDeleting(volume *storage.Volume) error {
+	if o.deletingVolumes[volume.Config.Name] {
+		return errors.Errorf(errors.Conflict, "Volume %s is being deleted.",
+			volume.Config.Name)
+	}
+	return checkNotTerminating(volume)
+}
+
+// terminateVolume deletes a volume with the deletion grace period:  the
+// volume is renamed on its backend, if the backend supports it, and kept
+// until the reaper deletes it.  The caller must hold the orchestrator lock.
+func (o *tridentOrchestrator) terminateVolume(
+	ctx, deleteCtx context.Context, volume *storage.Volume,
+) error {
+	now := time.Now()
+	deletion := &storage.VolumeDeletion{
+		DeletedAt:    now,
+		ReapAt:       now.Add(o.deletionGracePeriod),
+		InternalName: volume.Config.InternalName,
+	}
+	newInternalName := volume.Config.InternalName
+	if volume.Backend.SupportsVolumeRename() {
+		newInternalName = fmt.Sprintf("%s_deleted_%d", newInternalName,
+			now.Unix())
+	}
+	if err := o.setVolumeDeletion(ctx, deleteCtx, volume,
+		persistent_store.TerminateVolume, newInternalName,
+		deletion); err != nil {
+		return err
+	}
+	log.WithFields(log.Fields{
+		"volume":       volume.Config.Name,
+		"internalName": newInternalName,
+		"reapAt":       deletion.ReapAt,
+	}).Info("Volume is terminating; it will be deleted when its grace " +
+		"period ends.")
+	o.markDirty()
+	o.events.publish(EventUpdate, EventObjectVolume, volume.Config.Name,
+		volume.ConstructExternal())
+	return nil
+}
+
+// UndeleteVolume restores a terminating volume, giving it back its internal
+// name on its backend.
+func (o *tridentOrchestrator) UndeleteVolume(
+	volumeName string,
+) (*storage.VolumeExternal, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	volume, ok := o.volumes[volumeName]
+	if !ok {
+		return nil, o.volumeNotFoundError(volumeName)
+	}
+	if !volume.IsTerminating() {
+		return nil, errors.Errorf(errors.Conflict,
+			"Volume %s is not being deleted.", volumeName)
+	}
+	if o.deletingVolumes[volumeName] {
+		return nil, errors.Errorf(errors.Conflict, "Volume %s is being "+
+			"deleted from its backend.", volumeName)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(),
+		o.timeouts.DeleteTimeout())
+	defer cancel()
+	if err := o.setVolumeDeletion(ctx, ctx, volume,
+		persistent_store.UndeleteVolume, volume.Deletion.InternalName,
+		nil); err != nil {
+		return nil, err
+	}
+	log.WithFields(log.Fields{
+		"volume":       volumeName,
+		"internalName": volume.Config.InternalName,
+	}).Info("Undeleted volume.")
+	externalVol := volume.ConstructExternal()
+	o.markDirty()
+	o.events.publish(EventUpdate, EventObjectVolume, volumeName, externalVol)
+	return externalVol, nil
+}
+
+// setVolumeDeletion renames a volume on its backend, if newInternalName
+// differs from its internal name, and stores it with the given deletion
+// state.  A transaction ensures that the backend and the stored record
+// agree if Trident stops partway.  The caller must hold the orchestrator
+// lock.
+func (o *tridentOrchestrator) setVolumeDeletion(
+	ctx, backendCtx context.Context, volume *storage.Volume,
+	op persistent_store.VolumeOperation, newInternalName string,
+	deletion *storage.VolumeDeletion,
+) error {
+	oldConfig, oldDeletion := volume.Config, volume.Deletion
+	oldInternalName := oldConfig.InternalName
+	var volTxn *persistent_store.VolumeTransaction
+	if newInternalName != oldInternalName {
+		volTxn = &persistent_store.VolumeTransaction{
+			Config:          oldConfig,
+			Op:              op,
+			NewInternalName: newInternalName,
+		}
+		if err := o.storeClient.WithContext(ctx).AddVolumeTransaction(
+			volTxn); err != nil {
+			return err
+		}
+		tags := map[string]string{
+			"volume":  volume.Config.Name,
+			"backend": volume.Backend.Name,
+		}
+		undoTimeout := o.timeouts.DeleteTimeout()
+		err := callWithContext(backendCtx, "Volume rename", tags, func() error {
+			return volume.Backend.RenameVolume(backendCtx, oldInternalName,
+				newInternalName)
+		}, func() {
+			// backendCtx is done, so the undo is given its own context.
+			undoCtx, cancel := context.WithTimeout(context.Background(),
+				undoTimeout)
+			defer cancel()
+			volume.Backend.RenameVolume(undoCtx, newInternalName,
+				oldInternalName)
+		})
+		if err != nil {
+			o.deleteVolumeDeletionTxn(volTxn)
+			return err
+		}
+	}
+
+	newConfig := *oldConfig
+	newConfig.InternalName = newInternalName
+	volume.Config, volume.Deletion = &newConfig, deletion
+	if err := o.storeClient.UpdateVolume(volume); err != nil {
+		volume.Config, volume.Deletion = oldConfig, oldDeletion
+		if volTxn == nil {
+			return err
+		}
+		restoreCtx, cancel := context.WithTimeout(context.Background(),
+			o.timeouts.DeleteTimeout())
+		defer cancel()
+		if renameErr := volume.Backend.RenameVolume(restoreCtx,
+			newInternalName, oldInternalName); renameErr != nil {
+			// Leave the transaction, so that the rename is undone later.
+			log.WithFields(log.Fields{
+				"volume":       volume.Config.Name,
+				"internalName": newInternalName,
+			}).Warnf("Unable to restore the volume's name on its backend:  "+
+				"%v", renameErr)
+			return err
+		}
+		o.deleteVolumeDeletionTxn(volTxn)
+		return err
+	}
+	if volTxn != nil {
+		o.deleteVolumeDeletionTxn(volTxn)
+	}
+	return nil
+}
+
+func (o *tridentOrchestrator) deleteVolumeDeletionTxn(
+	volTxn *persistent_store.VolumeTransaction,
+) {
+	if err := o.storeClient.DeleteVolumeTransaction(volTxn); err != nil {
+		log.WithFields(log.Fields{
+			"volume": volTxn.Config.Name,
+			"op":     volTxn.Op,
+		}).Warn("Unable to delete volume transaction; it will be resolved " +
+			"later.")
+	}
+}
+
+// rollBackVolumeDeletionChange resolves a TerminateVolume or UndeleteVolume
+// transaction.  The volume is renamed on its backend before its record is
+// updated, so if the record still has the old internal name, any rename is
+// undone.
+func (o *tridentOrchestrator) rollBackVolumeDeletionChange(
+	v *persistent_store.VolumeTransaction,
+) error {
+	vol, ok := o.volumes[v.Config.Name]
+	if ok && vol.Config.InternalName == v.Config.InternalName &&
+		vol.Backend.Driver.Get(v.NewInternalName) == nil {
+		ctx, cancel := context.WithTimeout(context.Background(),
+			o.timeouts.DeleteTimeout())
+		defer cancel()
+		if err := vol.Backend.RenameVolume(ctx, v.NewInternalName,
+			v.Config.InternalName); err != nil {
+			return fmt.Errorf("Unable to restore the name of volume %s on "+
+				"its backend:  %v", v.Config.Name, err)
+		}
+		log.WithFields(log.Fields{
+			"volume":       v.Config.Name,
+			"internalName": v.Config.InternalName,
+		}).Info("Restored the volume's name on its backend.")
+	}
+	if err := o.storeClient.DeleteVolumeTransaction(v); err != nil {
+		return fmt.Errorf("Failed to clean up volume %s transaction:  %v",
+			v.Op, err)
+	}
+	return nil
+}
+
+// startVolumeReaper deletes terminating volumes whose grace period has
+// ended, and handles expired volumes, once per interval for the life of the
+// process.
+func (o *tridentOrchestrator) startVolumeReaper() {
+	go func() {
+		for t := range time.Tick(config.ReaperInterval) {
+			o.reapTerminatingVolumes(t)
+			o.expireVolumes(t)
+		}
+	}()
+}
+
+// reapTerminatingVolumes deletes the terminating volumes whose grace period
+// ended by t and returns the number deleted.
+func (o *tridentOrchestrator) reapTerminatingVolumes(t time.Time) int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	reaped := 0
+	for name, volume := range o.volumes {
+		if !volume.IsTerminating() || t.Before(volume.Deletion.ReapAt) ||
+			o.deletingVolumes[name] {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(),
+			o.timeouts.DeleteTimeout())
+		err := o.deleteVolumeInTxn(ctx, ctx, volume)
+		cancel()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"volume":  name,
+				"handler": "VolumeReaper",
+			}).Warnf("Unable to delete terminating volume; will retry:  %v",
+				err)
+			continue
+		}
+		reaped++
+		log.WithFields(log.Fields{
+			"volume":    name,
+			"deletedAt": volume.Deletion.DeletedAt,
+			"handler":   "VolumeReaper",
+		}).Info("Deleted terminating volume.")
+	}
+	return reaped
+}
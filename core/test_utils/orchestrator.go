@@ -0,0 +1,227 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+// Package test_utils helps exercise the orchestrator without etcd or real
+// storage systems.  Orchestrators created here keep their state in memory,
+// and their backends use the fake driver, whose pools are described by
+// fake.FakeStoragePool; storage_class/test_utils has a varied set.
+package test_utils
+
+import (
+	"fmt"
+	"sync"
+
+	"golang.org/x/net/context"
+
+	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/core"
+	"github.com/netapp/trident/drivers/fake"
+	"github.com/netapp/trident/persistent_store"
+	"github.com/netapp/trident/storage"
+)
+
+// NewOrchestrator returns a bootstrapped orchestrator that persists its
+// state in store, or in a new in-memory store if store is nil.  Passing the
+// same store to a second orchestrator simulates a restart.
+func NewOrchestrator(store persistent_store.Client) (core.Orchestrator, error) {
+	if store == nil {
+		store = persistent_store.NewInMemoryClient()
+	}
+	o := core.NewTridentOrchestrator(store)
+	if err := o.Bootstrap(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// AddFakeBackend adds a backend named name whose storage pools are
+// simulated by the fake driver.
+func AddFakeBackend(
+	o core.Orchestrator, name string, protocol config.Protocol,
+	pools map[string]*fake.FakeStoragePool,
+) (*storage.StorageBackendExternal, error) {
+	configJSON, err := fake.NewFakeStorageDriverConfigJSON(name, protocol,
+		pools)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create fake driver config:  %v",
+			err)
+	}
+	result, err := o.AddStorageBackend(context.Background(), configJSON)
+	if err != nil {
+		return nil, err
+	}
+	return result.StorageBackendExternal, nil
+}
+
+// NewVolumeConfig returns the config of a volume of the given size in GB.
+func NewVolumeConfig(
+	name string, gb int, storageClass string, protocol config.Protocol,
+) *storage.VolumeConfig {
+	return &storage.VolumeConfig{
+		Name:         name,
+		Size:         fmt.Sprintf("%dGB", gb),
+		Protocol:     protocol,
+		StorageClass: storageClass,
+	}
+}
+
+// FaultyClient is a persistent store client that fails chosen requests, so
+// that tests can exercise how the orchestrator recovers, e.g., by rolling
+// back a volume it created when the volume can't be stored.
+type FaultyClient struct {
+	persistent_store.Client
+	mutex    sync.Mutex
+	failures map[string]error
+}
+
+// NewFaultyClient wraps client, initially failing no requests.
+func NewFaultyClient(client persistent_store.Client) *FaultyClient {
+	return &FaultyClient{
+		Client:   client,
+		failures: make(map[string]error),
+	}
+}
+
+// FailOn makes every request to the named method (e.g., "AddVolume") fail
+// with err.  A nil err makes the method succeed again.  Only methods that
+// change stored state can fail.
+func (c *FaultyClient) FailOn(method string, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if err != nil {
+		c.failures[method] = err
+	} else {
+		delete(c.failures, method)
+	}
+}
+
+func (c *FaultyClient) failure(method string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.failures[method]
+}
+
+// WithContext returns c itself, so that failures still apply.
+func (c *FaultyClient) WithContext(ctx context.Context) persistent_store.Client {
+	return c
+}
+
+func (c *FaultyClient) AddBackend(b *storage.StorageBackend) error {
+	if err := c.failure("AddBackend"); err != nil {
+		return err
+	}
+	return c.Client.AddBackend(b)
+}
+
+func (c *FaultyClient) UpdateBackend(b *storage.StorageBackend) error {
+	if err := c.failure("UpdateBackend"); err != nil {
+		return err
+	}
+	return c.Client.UpdateBackend(b)
+}
+
+func (c *FaultyClient) DeleteBackend(b *storage.StorageBackend) error {
+	if err := c.failure("DeleteBackend"); err != nil {
+		return err
+	}
+	return c.Client.DeleteBackend(b)
+}
+
+func (c *FaultyClient) AddVolume(vol *storage.Volume) error {
+	if err := c.failure("AddVolume"); err != nil {
+		return err
+	}
+	return c.Client.AddVolume(vol)
+}
+
+func (c *FaultyClient) UpdateVolume(vol *storage.Volume) error {
+	if err := c.failure("UpdateVolume"); err != nil {
+		return err
+	}
+	return c.Client.UpdateVolume(vol)
+}
+
+func (c *FaultyClient) DeleteVolume(vol *storage.Volume) error {
+	if err := c.failure("DeleteVolume"); err != nil {
+		return err
+	}
+	return c.Client.DeleteVolume(vol)
+}
+
+func (c *FaultyClient) DeleteVolumeIgnoreNotFound(vol *storage.Volume) error {
+	if err := c.failure("DeleteVolumeIgnoreNotFound"); err != nil {
+		return err
+	}
+	return c.Client.DeleteVolumeIgnoreNotFound(vol)
+}
+
+func (c *FaultyClient) AddVolumeTransaction(
+	volTxn *persistent_store.VolumeTransaction,
+) error {
+	if err := c.failure("AddVolumeTransaction"); err != nil {
+		return err
+	}
+	return c.Client.AddVolumeTransaction(volTxn)
+}
+
+func (c *FaultyClient) DeleteVolumeTransaction(
+	volTxn *persistent_store.VolumeTransaction,
+) error {
+	if err := c.failure("DeleteVolumeTransaction"); err != nil {
+		return err
+	}
+	return c.Client.DeleteVolumeTransaction(volTxn)
+}
+
+func (c *FaultyClient) AddBackendTransaction(
+	backendTxn *persistent_store.BackendTransaction,
+) error {
+	if err := c.failure("AddBackendTransaction"); err != nil {
+		return err
+	}
+	return c.Client.AddBackendTransaction(backendTxn)
+}
+
+func (c *FaultyClient) DeleteBackendTransaction(
+	backendTxn *persistent_store.BackendTransaction,
+) error {
+	if err := c.failure("DeleteBackendTransaction"); err != nil {
+		return err
+	}
+	return c.Client.DeleteBackendTransaction(backendTxn)
+}
+
+func (c *FaultyClient) AddRetainedVolume(
+	vol *persistent_store.RetainedVolume,
+) error {
+	if err := c.failure("AddRetainedVolume"); err != nil {
+		return err
+	}
+	return c.Client.AddRetainedVolume(vol)
+}
+
+func (c *FaultyClient) DeleteRetainedVolume(
+	vol *persistent_store.RetainedVolume,
+) error {
+	if err := c.failure("DeleteRetainedVolume"); err != nil {
+		return err
+	}
+	return c.Client.DeleteRetainedVolume(vol)
+}
+
+func (c *FaultyClient) AddProvisioningRetry(
+	retry *persistent_store.ProvisioningRetry,
+) error {
+	if err := c.failure("AddProvisioningRetry"); err != nil {
+		return err
+	}
+	return c.Client.AddProvisioningRetry(retry)
+}
+
+func (c *FaultyClient) DeleteProvisioningRetry(
+	retry *persistent_store.ProvisioningRetry,
+) error {
+	if err := c.failure("DeleteProvisioningRetry"); err != nil {
+		return err
+	}
+	return c.Client.DeleteProvisioningRetry(retry)
+}
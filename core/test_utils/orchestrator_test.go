@@ -0,0 +1,80 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package test_utils
+
+import (
+	"fmt"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/persistent_store"
+	sa "github.com/netapp/trident/storage_attribute"
+	"github.com/netapp/trident/storage_class"
+	tu "github.com/netapp/trident/storage_class/test_utils"
+)
+
+const scName = "fast"
+
+func TestFakeOrchestrator(t *testing.T) {
+	store := NewFaultyClient(persistent_store.NewInMemoryClient())
+	o, err := NewOrchestrator(store)
+	if err != nil {
+		t.Fatal("Unable to bootstrap orchestrator:  ", err)
+	}
+	if _, err = AddFakeBackend(o, "fakeBackend", config.File,
+		tu.GetFakePools()); err != nil {
+		t.Fatal("Unable to add backend:  ", err)
+	}
+	if _, err = o.AddStorageClass(&storage_class.Config{
+		Name: scName,
+		Attributes: map[string]sa.Request{
+			sa.IOPS: sa.NewIntRequest(1000),
+		},
+	}); err != nil {
+		t.Fatal("Unable to add storage class:  ", err)
+	}
+	ctx := context.Background()
+
+	// A volume that can't be stored is removed from its backend, and its
+	// transaction is resolved.
+	store.FailOn("AddVolume", fmt.Errorf("Injected failure."))
+	if _, err = o.AddVolume(ctx,
+		NewVolumeConfig("rollback", 1, scName, config.File)); err == nil {
+		t.Error("Volume was created despite a store failure.")
+	}
+	if o.GetVolume("rollback") != nil {
+		t.Error("Rolled back volume present in orchestrator.")
+	}
+	txns, err := store.GetVolumeTransactions()
+	if err != nil && err.Error() != persistent_store.KeyErrorMsg {
+		t.Error("Unable to list volume transactions:  ", err)
+	} else if len(txns) != 0 {
+		t.Errorf("Found %d volume transactions after rollback.", len(txns))
+	}
+
+	store.FailOn("AddVolume", nil)
+	vol, err := o.AddVolume(ctx,
+		NewVolumeConfig("created", 1, scName, config.File))
+	if err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+	if vol.Backend != "fakeBackend" || vol.Pool == tu.SlowNoSnapshots ||
+		vol.Pool == tu.SlowSnapshots {
+		t.Errorf("Volume placed in unexpected pool %s/%s.", vol.Backend,
+			vol.Pool)
+	}
+
+	// A new orchestrator on the same store sees the volume.
+	restarted, err := NewOrchestrator(store)
+	if err != nil {
+		t.Fatal("Unable to bootstrap second orchestrator:  ", err)
+	}
+	if restarted.GetVolume("created") == nil {
+		t.Error("Volume not bootstrapped from the store.")
+	}
+	if _, err = restarted.DeleteVolume(ctx, "created"); err != nil {
+		t.Error("Unable to delete volume:  ", err)
+	}
+}
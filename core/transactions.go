@@ -0,0 +1,159 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"sort"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/netapp/trident/persistent_store"
+)
+
+// PendingTransaction is a stored volume transaction that hasn't been
+// resolved, usually because the operation that logged it failed partway.
+type PendingTransaction struct {
+	Volume  string                           `json:"volume"`
+	Op      persistent_store.VolumeOperation `json:"op"`
+	OldName string                           `json:"oldName,omitempty"`
+	// RequestID is the idempotency key of the request that logged the
+	// transaction, if any.
+	RequestID string `json:"requestID,omitempty"`
+	// Created and Age are unset for transactions stored by older versions.
+	Created time.Time `json:"created"`
+	Age     string    `json:"age,omitempty"`
+	// Attempts counts the janitor's failed attempts to resolve the
+	// transaction, the last of which failed with LastError.
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"lastError,omitempty"`
+	// Escalated transactions are no longer retried by the janitor; they are
+	// resolved at the next bootstrap.
+	Escalated bool `json:"escalated"`
+}
+
+// txnAttempts records the janitor's failed attempts to resolve a
+// transaction.
+type txnAttempts struct {
+	attempts  int
+	lastError string
+	escalated bool
+}
+
+// ListPendingTransactions returns the stored volume transactions, sorted
+// by volume name.
+func (o *tridentOrchestrator) ListPendingTransactions() (
+	[]*PendingTransaction, error,
+) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	txns, err := o.storeClient.GetVolumeTransactions()
+	if err != nil {
+		if err.Error() == persistent_store.KeyErrorMsg {
+			return make([]*PendingTransaction, 0), nil
+		}
+		return nil, err
+	}
+	now := time.Now()
+	ret := make([]*PendingTransaction, 0, len(txns))
+	for _, txn := range txns {
+		pending := &PendingTransaction{
+			Volume:    txn.Config.Name,
+			Op:        txn.Op,
+			OldName:   txn.OldName,
+			RequestID: txn.Config.RequestID,
+			Created:   txn.Created,
+		}
+		if !txn.Created.IsZero() {
+			pending.Age = now.Sub(txn.Created).String()
+		}
+		if status, ok := o.txnAttempts[txn.Config.Name]; ok {
+			pending.Attempts = status.attempts
+			pending.LastError = status.lastError
+			pending.Escalated = status.escalated
+		}
+		ret = append(ret, pending)
+	}
+	sort.Sort(pendingTransactionsByVolume(ret))
+	return ret, nil
+}
+
+type pendingTransactionsByVolume []*PendingTransaction
+
+func (p pendingTransactionsByVolume) Len() int      { return len(p) }
+func (p pendingTransactionsByVolume) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p pendingTransactionsByVolume) Less(i, j int) bool {
+	return p[i].Volume < p[j].Volume
+}
+
+// startTransactionJanitor resolves stale transactions, and deletes zombie
+// volumes, once per interval for the life of the process.
+func (o *tridentOrchestrator) startTransactionJanitor() {
+	go func() {
+		for range time.Tick(o.janitorConfig.IntervalDuration()) {
+			o.resolveStaleTransactions()
+			o.deleteZombieVolumes()
+		}
+	}()
+}
+
+// resolveStaleTransactions rolls back or completes each transaction older
+// than the janitor's minimum age, as bootstrap would, and returns the number
+// resolved.  Operations hold the orchestrator lock for as long as their
+// transactions exist, so any transaction found while holding it was left by
+// an operation that failed.
+func (o *tridentOrchestrator) resolveStaleTransactions() int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	txns, err := o.storeClient.GetVolumeTransactions()
+	if err != nil {
+		if err.Error() != persistent_store.KeyErrorMsg {
+			log.Warnf("Unable to check for stale volume transactions:  %v",
+				err)
+		}
+		return 0
+	}
+	minAge := o.janitorConfig.MinAgeDuration()
+	resolved := 0
+	for _, txn := range txns {
+		name := txn.Config.Name
+		if !txn.Created.IsZero() && time.Since(txn.Created) < minAge {
+			continue
+		}
+		status, ok := o.txnAttempts[name]
+		if !ok {
+			status = &txnAttempts{}
+			o.txnAttempts[name] = status
+		}
+		if status.escalated {
+			continue
+		}
+		logFields := log.Fields{
+			"volume":  name,
+			"op":      txn.Op,
+			"created": txn.Created,
+			"handler": "TransactionJanitor",
+		}
+		if err = o.rollBackTransaction(txn); err != nil {
+			status.attempts++
+			status.lastError = err.Error()
+			if status.attempts >= o.janitorConfig.MaxAttempts {
+				status.escalated = true
+				log.WithFields(logFields).Errorf("Unable to resolve a "+
+					"volume transaction after %d attempts; it will not be "+
+					"retried until Trident restarts:  %v", status.attempts,
+					err)
+			} else {
+				log.WithFields(logFields).Warnf("Unable to resolve a "+
+					"volume transaction; will retry:  %v", err)
+			}
+			continue
+		}
+		delete(o.txnAttempts, name)
+		resolved++
+		log.WithFields(logFields).Info("Resolved a stale volume transaction.")
+	}
+	return resolved
+}
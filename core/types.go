@@ -3,8 +3,18 @@
 package core
 
 import (
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/netapp/trident/app_template"
 	"github.com/netapp/trident/config"
 	"github.com/netapp/trident/frontend"
+	"github.com/netapp/trident/hooks"
+	"github.com/netapp/trident/logging"
+	"github.com/netapp/trident/namespace_policy"
+	"github.com/netapp/trident/persistent_store"
+	"github.com/netapp/trident/snapshot_policy"
 	"github.com/netapp/trident/storage"
 	"github.com/netapp/trident/storage_class"
 )
@@ -12,23 +22,115 @@ import (
 type Orchestrator interface {
 	Bootstrap() error
 	AddFrontend(f frontend.FrontendPlugin)
+	GetFrontend(name string) (frontend.FrontendPlugin, error)
 	GetVersion() string
+	GetVersionInfo() *VersionInfo
+	IsBootstrapped() bool
+	GetHealth() *HealthStatus
+	ListQuarantinedRecords() ([]*persistent_store.QuarantinedRecord, error)
+	ListPendingTransactions() ([]*PendingTransaction, error)
+	ListZombieVolumes() ([]*persistent_store.ZombieVolume, error)
+	GetLoggingConfig() *logging.Config
+	SetLoggingConfig(c *logging.Config) error
 
-	AddStorageBackend(configJSON string) (*storage.StorageBackendExternal, error)
+	// Operations that take a context stop when it is done, as long as they
+	// can do so without leaving anything half-created or half-deleted.
+	AddStorageBackend(ctx context.Context, configJSON string) (*BackendResult, error)
+	AddStorageBackends(ctx context.Context, configs []string) *BackendsResult
+	UpdateStorageBackend(ctx context.Context, backend, configJSON string) (*BackendResult, error)
+	ValidateStorageBackend(ctx context.Context, configJSON string) (*BackendValidation, error)
+	DiffStorageBackend(ctx context.Context, backend, configJSON string) (*BackendDiff, error)
 	GetBackend(backend string) *storage.StorageBackendExternal
 	ListBackends() []*storage.StorageBackendExternal
 	OfflineBackend(backend string) (bool, error)
+	PreviewOfflineBackend(backend string) (*OfflineBackendReport, error)
+	OfflineBackendWithReport(backend string, confirm bool) (*OfflineBackendReport, error)
+	OnlineBackend(backend string) (*BackendResult, error)
+	RenameBackend(backend, newName string) (*storage.StorageBackendExternal, error)
+	GenerateStorageClasses(backend string) ([]*storage_class.Recommendation, error)
+	ApplyState(ctx context.Context, state *DesiredState) (*StateReport, error)
 
-	AddVolume(volumeConfig *storage.VolumeConfig) (*storage.VolumeExternal, error)
+	AddVolume(ctx context.Context, volumeConfig *storage.VolumeConfig) (*VolumeResult, error)
+	ExplainPlacement(volumeConfig *storage.VolumeConfig) (*PlacementReport, error)
+	CreateVolumeFromSnapshot(ctx context.Context, volumeConfig *storage.VolumeConfig) (*VolumeResult, error)
 	GetVolume(volume string) *storage.VolumeExternal
+	GetVolumeChap(volume string) (*storage.ChapConfig, error)
 	GetDriverTypeForVolume(vol *storage.VolumeExternal) string
 	GetVolumeType(vol *storage.VolumeExternal) config.VolumeType
 	ListVolumes() []*storage.VolumeExternal
-	DeleteVolume(volume string) (found bool, err error)
+	ListVolumesByMetadata(selector map[string]string) []*storage.VolumeExternal
+	WalkVolumes(walk func(*storage.VolumeExternal) bool)
+	DeleteVolume(ctx context.Context, volume string) (found bool, err error)
+	ForceDeleteVolume(ctx context.Context, volume string) (found bool, err error)
+	DeleteVolumes(ctx context.Context, deleteConfig *BulkDeleteConfig) (*BulkDeleteResult, error)
+	UndeleteVolume(volume string) (*storage.VolumeExternal, error)
+	RenameVolume(volume, newName string) (*storage.VolumeExternal, error)
+	UpdateVolumeMetadata(volume string, metadata map[string]string) (*storage.VolumeExternal, error)
+	SetVolumeExpiry(volume string, expiresAt *time.Time) (*storage.VolumeExternal, error)
+	ReattachVolume(volume, backend, pool string) (*storage.VolumeExternal, error)
+	ListRetainedVolumes() []*persistent_store.RetainedVolume
+	ImportRetainedVolume(volume, backend, pool string) (*storage.VolumeExternal, error)
+	DeleteRetainedVolume(volume string) (bool, error)
+	RecordProvisioningFailure(volume string, err error) (*persistent_store.ProvisioningRetry, error)
+	GetProvisioningRetry(volume string) *persistent_store.ProvisioningRetry
+	ListProvisioningRetries() []*persistent_store.ProvisioningRetry
+	DeleteProvisioningRetry(volume string) (bool, error)
+	RetryProvisioningNow(volume string) (*persistent_store.ProvisioningRetry, error)
+	AbandonProvisioning(volume string) (*persistent_store.ProvisioningRetry, error)
+	SetVolumeAccess(volume string, access *storage.VolumeAccess) (*storage.VolumeExternal, error)
+	SetVolumeFormatted(volume, fsType string) (*storage.VolumeExternal, error)
+	AttachVolume(volume, node string, readOnly bool) (*storage.VolumeExternal, error)
+	DetachVolume(volume, node string) (*storage.VolumeExternal, error)
 	ListVolumesByPlugin(pluginName string) []*storage.VolumeExternal
+	ListVolumeUsage() []*VolumeUsage
+	GetRebalanceReport() *RebalanceReport
+
+	EnableReplication(volume string, replicationConfig *ReplicationConfig) (*storage.VolumeExternal, error)
+	DisableReplication(volume string) (*storage.VolumeExternal, error)
+	FailoverVolume(volume string) (*storage.VolumeExternal, error)
+	FailoverBackend(backend string) (*BackendFailoverReport, error)
+
+	AddVolumeGroup(ctx context.Context, groupConfig *VolumeGroupConfig) (*persistent_store.VolumeGroup, error)
+	GetVolumeGroup(groupName string) *persistent_store.VolumeGroup
+	ListVolumeGroups() []*persistent_store.VolumeGroup
+	SnapshotVolumeGroup(groupName, snapshotName string) error
+	DeleteVolumeGroup(ctx context.Context, groupName string) (bool, error)
+
+	AddTemplate(templateConfig *app_template.Config) (*app_template.Config, error)
+	GetTemplate(templateName string) *app_template.Config
+	ListTemplates() []*app_template.Config
+	DeleteTemplate(templateName string) (bool, error)
+	ProvisionTemplate(ctx context.Context, templateName, instanceName string) (*persistent_store.VolumeGroup, error)
 
-	AddStorageClass(scConfig *storage_class.Config) (*storage_class.StorageClassExternal, error)
+	SetNamespacePolicy(policyConfig *namespace_policy.Config) (*namespace_policy.Config, error)
+	GetNamespacePolicy(namespace string) *namespace_policy.Config
+	ListNamespacePolicies() []*namespace_policy.Config
+	DeleteNamespacePolicy(namespace string) (bool, error)
+
+	AddStorageClass(scConfig *storage_class.Config) (*StorageClassResult, error)
 	GetStorageClass(scName string) *storage_class.StorageClassExternal
 	ListStorageClasses() []*storage_class.StorageClassExternal
+	UpdateStorageClass(scConfig *storage_class.Config) (*StorageClassResult, error)
 	DeleteStorageClass(scName string) (bool, error)
+	ForceDeleteStorageClass(scName string) (bool, error)
+
+	AddHook(hookConfig *hooks.Config) (*hooks.Config, error)
+	GetHook(hookName string) *hooks.Config
+	ListHooks() []*hooks.Config
+	DeleteHook(hookName string) (bool, error)
+
+	AddSnapshotPolicy(policyConfig *snapshot_policy.Config) (*snapshot_policy.Config, error)
+	GetSnapshotPolicy(policyName string) *snapshot_policy.Config
+	ListSnapshotPolicies() []*snapshot_policy.Config
+	DeleteSnapshotPolicy(policyName string) (bool, error)
+
+	// SubscribeEvents returns a channel on which create, update, and delete
+	// events for backends, volumes, and storage classes are delivered.
+	SubscribeEvents() <-chan Event
+	UnsubscribeEvents(ch <-chan Event)
+
+	// AddProvisioningListener registers a listener to be called back as
+	// volumes are provisioned.
+	AddProvisioningListener(l ProvisioningListener)
+	RemoveProvisioningListener(l ProvisioningListener)
 }
@@ -0,0 +1,93 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+
+	"github.com/netapp/trident/storage"
+)
+
+// VolumeUsage reports the space a volume is provisioned with and, if its
+// backend can report it, the space it consumes.
+type VolumeUsage struct {
+	Volume           string            `json:"volume"`
+	Backend          string            `json:"backend"`
+	Pool             string            `json:"pool"`
+	StorageClass     string            `json:"storageClass"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+	CreatedAt        time.Time         `json:"createdAt"`
+	ProvisionedBytes uint64            `json:"provisionedBytes"`
+	// ConsumedBytes is nil if the backend couldn't report it.
+	ConsumedBytes *uint64 `json:"consumedBytes,omitempty"`
+}
+
+type usageQuery struct {
+	usage        *VolumeUsage
+	backend      *storage.StorageBackend
+	internalName string
+}
+
+// ListVolumeUsage returns the usage of each volume that isn't terminating.
+// Backends are queried without holding the orchestrator lock.
+func (o *tridentOrchestrator) ListVolumeUsage() []*VolumeUsage {
+	o.mutex.Lock()
+	queries := make([]*usageQuery, 0, len(o.volumes))
+	for name, vol := range o.volumes {
+		if vol.Deletion != nil {
+			continue
+		}
+		size, err := vol.Config.SizeBytes()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"volume": name,
+				"size":   vol.Config.Size,
+			}).Warn("Unable to determine volume size; reporting it as 0.")
+		}
+		var metadata map[string]string
+		if len(vol.Config.Metadata) > 0 {
+			metadata = make(map[string]string, len(vol.Config.Metadata))
+			for k, v := range vol.Config.Metadata {
+				metadata[k] = v
+			}
+		}
+		queries = append(queries, &usageQuery{
+			usage: &VolumeUsage{
+				Volume:           name,
+				Backend:          vol.Backend.Name,
+				Pool:             vol.Pool.Name,
+				StorageClass:     vol.Config.StorageClass,
+				Metadata:         metadata,
+				CreatedAt:        vol.Config.CreatedAt,
+				ProvisionedBytes: size,
+			},
+			backend:      vol.Backend,
+			internalName: vol.Config.InternalName,
+		})
+	}
+	timeout := o.timeouts.ProvisionTimeout()
+	o.mutex.Unlock()
+
+	ret := make([]*VolumeUsage, 0, len(queries))
+	for _, q := range queries {
+		if q.backend.SupportsUsage() {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			used, err := q.backend.GetUsedBytes(ctx, q.internalName)
+			cancel()
+			if err != nil {
+				log.WithFields(log.Fields{
+					"volume":  q.usage.Volume,
+					"backend": q.usage.Backend,
+					"error":   err,
+				}).Warn("Unable to read volume usage.")
+			} else {
+				q.usage.ConsumedBytes = &used
+			}
+		}
+		ret = append(ret, q.usage)
+	}
+	return ret
+}
@@ -0,0 +1,111 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"runtime"
+	"sort"
+
+	dvp "github.com/netapp/netappdvp/storage_drivers"
+
+	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/storage"
+)
+
+// VersionInfo describes the running orchestrator's build, and the drivers
+// and frontends it has loaded.
+type VersionInfo struct {
+	Version    string `json:"version"`
+	APIVersion string `json:"apiVersion"`
+	GitSHA     string `json:"gitSHA"`
+	BuildDate  string `json:"buildDate"`
+	GoVersion  string `json:"goVersion"`
+	// StoreSchemaVersion is the version of the records written to the
+	// persistent store.
+	StoreSchemaVersion string `json:"storeSchemaVersion"`
+	// Drivers lists the storage drivers in use, by name and version.  They
+	// are only reported once Trident has bootstrapped.
+	Drivers []*DriverVersion `json:"drivers"`
+	// Frontends maps each frontend's name to its version.
+	Frontends map[string]string `json:"frontends"`
+}
+
+// DriverVersion reports the version of a storage driver and the backends
+// that use it.  External drivers are listed once per sidecar version.
+type DriverVersion struct {
+	Name     string   `json:"name"`
+	Version  string   `json:"version"`
+	Backends []string `json:"backends"`
+}
+
+type driverVersionsByName []*DriverVersion
+
+func (d driverVersionsByName) Len() int      { return len(d) }
+func (d driverVersionsByName) Swap(i, j int) { d[i], d[j] = d[j], d[i] }
+func (d driverVersionsByName) Less(i, j int) bool {
+	if d[i].Name != d[j].Name {
+		return d[i].Name < d[j].Name
+	}
+	return d[i].Version < d[j].Version
+}
+
+// newVersionInfo returns the build's version information, without any
+// drivers or frontends.
+func newVersionInfo() *VersionInfo {
+	return &VersionInfo{
+		Version:            config.OrchestratorVersion,
+		APIVersion:         config.OrchestratorAPIVersion,
+		GitSHA:             config.GitSHA,
+		BuildDate:          config.BuildDate,
+		GoVersion:          runtime.Version(),
+		StoreSchemaVersion: config.StoreSchemaVersion,
+		Drivers:            make([]*DriverVersion, 0),
+		Frontends:          make(map[string]string),
+	}
+}
+
+// driverVersion returns the version of a backend's driver.  Drivers are
+// built from netappdvp unless they report their own version.
+func driverVersion(backend *storage.StorageBackend) string {
+	if d, ok := backend.Driver.(storage.VersionDriver); ok {
+		return d.DriverVersion()
+	}
+	return dvp.DriverVersion
+}
+
+// GetVersionInfo returns the orchestrator's build information and the
+// versions of its drivers and frontends, which are part of Trident.
+func (o *tridentOrchestrator) GetVersionInfo() *VersionInfo {
+	info := newVersionInfo()
+	for name := range o.frontends {
+		info.Frontends[name] = config.OrchestratorVersion
+	}
+	if !o.IsBootstrapped() {
+		return info
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	drivers := make(map[string]*DriverVersion)
+	for _, backend := range o.backends {
+		name := backend.GetDriverName()
+		version := driverVersion(backend)
+		key := name + "/" + version
+		driver, ok := drivers[key]
+		if !ok {
+			driver = &DriverVersion{
+				Name:     name,
+				Version:  version,
+				Backends: make([]string, 0),
+			}
+			drivers[key] = driver
+			info.Drivers = append(info.Drivers, driver)
+		}
+		driver.Backends = append(driver.Backends, backend.Name)
+	}
+	for _, driver := range info.Drivers {
+		sort.Strings(driver.Backends)
+	}
+	sort.Sort(driverVersionsByName(info.Drivers))
+	return info
+}
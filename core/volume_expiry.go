@@ -0,0 +1,152 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+
+	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/storage"
+)
+
+// SetVolumeExpiryConfig sets whether expired volumes are deleted or only
+// flagged, and how long after their expiry they are deleted.
+func (o *tridentOrchestrator) SetVolumeExpiryConfig(
+	c *config.VolumeExpiryConfig,
+) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.volumeExpiry = *c
+}
+
+// SetVolumeExpiry changes when a volume expires, or, if expiresAt is nil,
+// makes it never expire.  An expired volume is no longer flagged as such;
+// if the new expiry has also passed, it is flagged again at the next check,
+// with a new grace period.
+func (o *tridentOrchestrator) SetVolumeExpiry(
+	volumeName string, expiresAt *time.Time,
+) (*storage.VolumeExternal, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	volume, ok := o.volumes[volumeName]
+	if !ok {
+		return nil, o.volumeNotFoundError(volumeName)
+	}
+	if err := o.checkNotDeleting(volume); err != nil {
+		return nil, err
+	}
+	oldConfig := volume.Config
+	newConfig := *oldConfig
+	newConfig.ExpiresAt, newConfig.ExpiredAt = nil, nil
+	if expiresAt != nil {
+		t := expiresAt.UTC()
+		newConfig.ExpiresAt = &t
+	}
+	volume.Config = &newConfig
+	if err := o.storeClient.UpdateVolume(volume); err != nil {
+		volume.Config = oldConfig
+		return nil, err
+	}
+	log.WithFields(log.Fields{
+		"volume":    volumeName,
+		"expiresAt": newConfig.ExpiresAt,
+	}).Info("Set volume expiry.")
+	externalVol := volume.ConstructExternal()
+	o.markDirty()
+	o.events.publish(EventUpdate, EventObjectVolume, volumeName, externalVol)
+	return externalVol, nil
+}
+
+// expireVolumes flags the volumes that have expired by t and notifies the
+// expiry hooks about them.  If expired volumes are to be deleted, it then
+// deletes those whose expiry grace period ended by t, and returns the
+// number deleted.
+func (o *tridentOrchestrator) expireVolumes(t time.Time) int {
+	for _, vol := range o.flagExpiredVolumes(t) {
+		o.runExpiryHooks(vol)
+	}
+	return o.deleteExpiredVolumes(t)
+}
+
+// flagExpiredVolumes records when each volume that has expired by t, and
+// hasn't been flagged yet, was found to be expired.  It returns the newly
+// flagged volumes.
+func (o *tridentOrchestrator) flagExpiredVolumes(
+	t time.Time,
+) []*storage.VolumeExternal {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	flagged := make([]*storage.VolumeExternal, 0)
+	for name, volume := range o.volumes {
+		if volume.IsTerminating() || o.deletingVolumes[name] ||
+			volume.Config.ExpiredAt != nil || !volume.Config.IsExpired(t) {
+			continue
+		}
+		oldConfig := volume.Config
+		newConfig := *oldConfig
+		expiredAt := t.UTC()
+		newConfig.ExpiredAt = &expiredAt
+		volume.Config = &newConfig
+		if err := o.storeClient.UpdateVolume(volume); err != nil {
+			volume.Config = oldConfig
+			log.WithFields(log.Fields{
+				"volume":  name,
+				"handler": "VolumeReaper",
+			}).Warnf("Unable to flag expired volume; will retry:  %v", err)
+			continue
+		}
+		log.WithFields(log.Fields{
+			"volume":    name,
+			"expiresAt": newConfig.ExpiresAt,
+			"handler":   "VolumeReaper",
+		}).Info("Volume has expired.")
+		externalVol := volume.ConstructExternal()
+		o.markDirty()
+		o.events.publish(EventUpdate, EventObjectVolume, name, externalVol)
+		flagged = append(flagged, externalVol)
+	}
+	return flagged
+}
+
+// deleteExpiredVolumes deletes the flagged volumes whose expiry grace period
+// ended by t, as DeleteVolume would, unless expired volumes are only to be
+// flagged.  Volumes that can't be deleted, e.g., because they are attached,
+// are retried at the next check.
+func (o *tridentOrchestrator) deleteExpiredVolumes(t time.Time) int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.volumeExpiry.Action != config.ExpiryDelete {
+		return 0
+	}
+	gracePeriod := o.volumeExpiry.GracePeriodDuration()
+	deleted := 0
+	for name, volume := range o.volumes {
+		expiredAt := volume.Config.ExpiredAt
+		if volume.IsTerminating() || o.deletingVolumes[name] ||
+			expiredAt == nil || !volume.Config.IsExpired(t) ||
+			t.Before(expiredAt.Add(gracePeriod)) {
+			continue
+		}
+		if _, err := o.deleteVolumeLocked(context.Background(), name,
+			false); err != nil {
+			log.WithFields(log.Fields{
+				"volume":  name,
+				"handler": "VolumeReaper",
+			}).Warnf("Unable to delete expired volume; will retry:  %v", err)
+			continue
+		}
+		deleted++
+		log.WithFields(log.Fields{
+			"volume":    name,
+			"expiredAt": *expiredAt,
+			"handler":   "VolumeReaper",
+		}).Info("Deleted expired volume.")
+	}
+	return deleted
+}
@@ -0,0 +1,350 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+
+	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/errors"
+	"github.com/netapp/trident/persistent_store"
+	"github.com/netapp/trident/storage"
+	"github.com/netapp/trident/tracing"
+)
+
+// VolumeGroupConfig requests a volume group:  its volumes are created
+// together on one backend, and are snapshotted and deleted as a unit.
+type VolumeGroupConfig struct {
+	Name    string                  `json:"name"`
+	Volumes []*storage.VolumeConfig `json:"volumes"`
+}
+
+func (c *VolumeGroupConfig) Validate() error {
+	if c.Name == "" {
+		return errors.Errorf(errors.InvalidInput,
+			"Volume groups must have a name.")
+	}
+	if len(c.Volumes) == 0 {
+		return errors.Errorf(errors.InvalidInput,
+			"Volume group %s has no volumes.", c.Name)
+	}
+	names := make(map[string]bool, len(c.Volumes))
+	for _, volConfig := range c.Volumes {
+		if volConfig == nil || volConfig.Name == "" {
+			return errors.Errorf(errors.InvalidInput,
+				"Each volume of group %s must have a name.", c.Name)
+		}
+		if names[volConfig.Name] {
+			return errors.Errorf(errors.InvalidInput,
+				"Volume %s is listed more than once in group %s.",
+				volConfig.Name, c.Name)
+		}
+		names[volConfig.Name] = true
+		// Clones are placed with their sources, not with the group.
+		if volConfig.IsClone() {
+			return errors.Errorf(errors.InvalidInput,
+				"Volume %s of group %s can't be created from a snapshot.",
+				volConfig.Name, c.Name)
+		}
+		if volConfig.Group != "" && volConfig.Group != c.Name {
+			return errors.Errorf(errors.InvalidInput,
+				"Volume %s of group %s names group %s.", volConfig.Name,
+				c.Name, volConfig.Group)
+		}
+	}
+	return nil
+}
+
+func copyVolumeGroup(
+	group *persistent_store.VolumeGroup,
+) *persistent_store.VolumeGroup {
+	ret := *group
+	ret.Volumes = append([]string{}, group.Volumes...)
+	return &ret
+}
+
+func (o *tridentOrchestrator) bootstrapVolumeGroups() error {
+	groups, err := o.storeClient.GetVolumeGroups()
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		o.volumeGroups[g.Name] = g
+		log.WithFields(log.Fields{
+			"volumeGroup": g.Name,
+			"backend":     g.Backend,
+			"handler":     "Bootstrap",
+		}).Info("Added an existing volume group.")
+	}
+	return nil
+}
+
+// AddVolumeGroup creates a group's volumes, one after another, on the
+// backend chosen for the first of them.  If any volume can't be created,
+// those already created are deleted and no group is added.  The group is
+// recorded once all of its volumes exist; if Trident stops before then, the
+// volumes that were created remain, outside of any group.
+func (o *tridentOrchestrator) AddVolumeGroup(
+	ctx context.Context, groupConfig *VolumeGroupConfig,
+) (*persistent_store.VolumeGroup, error) {
+	return o.addVolumeGroup(ctx, groupConfig, "")
+}
+
+// addVolumeGroup adds a group, recording the template it was provisioned
+// from, if any.
+func (o *tridentOrchestrator) addVolumeGroup(
+	ctx context.Context, groupConfig *VolumeGroupConfig, templateName string,
+) (group *persistent_store.VolumeGroup, err error) {
+	ctx, span := tracing.StartSpan(ctx, tracing.Core, "AddVolumeGroup",
+		map[string]string{"volumeGroup": groupConfig.Name})
+	defer func() {
+		span.Finish(err)
+	}()
+	if err = groupConfig.Validate(); err != nil {
+		return nil, err
+	}
+	o.mutex.Lock()
+	if _, ok := o.volumeGroups[groupConfig.Name]; ok {
+		o.mutex.Unlock()
+		return nil, errors.Errorf(errors.AlreadyExists,
+			"Volume group %s already exists.", groupConfig.Name)
+	}
+	if _, ok := o.pendingVolumeGroups[groupConfig.Name]; ok {
+		o.mutex.Unlock()
+		return nil, errors.Errorf(errors.AlreadyExists,
+			"Volume group %s is being created.", groupConfig.Name)
+	}
+	for _, volConfig := range groupConfig.Volumes {
+		if o.volumeExists(volConfig.Name) {
+			o.mutex.Unlock()
+			return nil, errors.Errorf(errors.AlreadyExists,
+				"Volume %s already exists.", volConfig.Name)
+		}
+	}
+	// The group is reserved until its volumes are created; its backend is
+	// set once the first one is.
+	o.pendingVolumeGroups[groupConfig.Name] = ""
+	o.mutex.Unlock()
+
+	created := make([]string, 0, len(groupConfig.Volumes))
+	defer func() {
+		o.mutex.Lock()
+		defer o.mutex.Unlock()
+		delete(o.pendingVolumeGroups, groupConfig.Name)
+		if err != nil {
+			o.removeVolumeGroupVolumes(groupConfig.Name, created)
+		}
+	}()
+
+	var backendName string
+	for _, volConfig := range groupConfig.Volumes {
+		volConfig.Group = groupConfig.Name
+		vol, addErr := o.AddVolume(ctx, volConfig)
+		if addErr != nil {
+			return nil, fmt.Errorf("Unable to create volume %s of group "+
+				"%s:  %v", volConfig.Name, groupConfig.Name, addErr)
+		}
+		created = append(created, vol.Config.Name)
+		if backendName == "" {
+			backendName = vol.Backend
+			o.mutex.Lock()
+			o.pendingVolumeGroups[groupConfig.Name] = backendName
+			o.mutex.Unlock()
+		}
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	group = &persistent_store.VolumeGroup{
+		Name:      groupConfig.Name,
+		Backend:   backendName,
+		Volumes:   created,
+		CreatedAt: time.Now().UTC(),
+		Template:  templateName,
+	}
+	if err = o.storeClient.AddVolumeGroup(group); err != nil {
+		return nil, err
+	}
+	o.volumeGroups[group.Name] = group
+	log.WithFields(log.Fields{
+		"volumeGroup": group.Name,
+		"backend":     group.Backend,
+		"volumes":     group.Volumes,
+	}).Info("Added a new volume group.")
+	return copyVolumeGroup(group), nil
+}
+
+// removeVolumeGroupVolumes deletes the volumes created for a group that
+// couldn't be added.  Failures are logged; the volumes that remain can be
+// deleted individually.  The caller must hold the orchestrator lock.
+func (o *tridentOrchestrator) removeVolumeGroupVolumes(
+	groupName string, volumeNames []string,
+) {
+	deadline := time.Now().Add(o.timeouts.DeleteTimeout())
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	for _, volumeName := range volumeNames {
+		vol, ok := o.volumes[volumeName]
+		if !ok {
+			continue
+		}
+		// The volume was never handed over, so it isn't retained.
+		vol.Config.ReclaimPolicy = config.ReclaimDelete
+		if err := o.deleteVolumeInTxn(ctx, ctx, vol); err != nil {
+			log.WithFields(log.Fields{
+				"volumeGroup": groupName,
+				"volume":      volumeName,
+				"error":       err,
+			}).Error("Unable to delete volume of a group that failed to " +
+				"be created.")
+		}
+	}
+}
+
+func (o *tridentOrchestrator) GetVolumeGroup(
+	groupName string,
+) *persistent_store.VolumeGroup {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	group, ok := o.volumeGroups[groupName]
+	if !ok {
+		return nil
+	}
+	return copyVolumeGroup(group)
+}
+
+func (o *tridentOrchestrator) ListVolumeGroups() []*persistent_store.VolumeGroup {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	ret := make([]*persistent_store.VolumeGroup, 0, len(o.volumeGroups))
+	for _, group := range o.volumeGroups {
+		ret = append(ret, copyVolumeGroup(group))
+	}
+	return ret
+}
+
+// getVolumeGroupVolumes returns the volumes of a group that still exist.
+// The caller must hold the orchestrator lock.
+func (o *tridentOrchestrator) getVolumeGroupVolumes(
+	group *persistent_store.VolumeGroup,
+) []*storage.Volume {
+	vols := make([]*storage.Volume, 0, len(group.Volumes))
+	for _, volumeName := range group.Volumes {
+		if vol, ok := o.volumes[volumeName]; ok {
+			vols = append(vols, vol)
+		}
+	}
+	return vols
+}
+
+// SnapshotVolumeGroup snapshots a group's volumes at the same point in time,
+// giving each snapshot the same name.  The backend must support consistent
+// group snapshots; the snapshots aren't taken one by one, since they
+// wouldn't be consistent with each other.  The backend is called without
+// holding the orchestrator lock.
+func (o *tridentOrchestrator) SnapshotVolumeGroup(
+	groupName, snapshotName string,
+) error {
+	if snapshotName == "" {
+		return errors.Errorf(errors.InvalidInput,
+			"Snapshots must have a name.")
+	}
+	o.mutex.Lock()
+	group, ok := o.volumeGroups[groupName]
+	if !ok {
+		o.mutex.Unlock()
+		return errors.Errorf(errors.NotFound,
+			"Volume group %s not found.", groupName)
+	}
+	vols := o.getVolumeGroupVolumes(group)
+	timeout := o.timeouts.ProvisionTimeout()
+	o.mutex.Unlock()
+	if len(vols) == 0 {
+		return errors.Errorf(errors.Conflict,
+			"Volume group %s has no volumes.", groupName)
+	}
+	for _, vol := range vols {
+		if err := checkNotTerminating(vol); err != nil {
+			return err
+		}
+	}
+
+	backend := vols[0].Backend
+	if !backend.SupportsGroupSnapshots() {
+		return errors.Errorf(errors.Conflict, "Backend %s of volume group "+
+			"%s does not support consistent group snapshots.", backend.Name,
+			groupName)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := backend.CreateGroupSnapshot(ctx, vols,
+		snapshotName); err != nil {
+		return err
+	}
+	log.WithFields(log.Fields{
+		"volumeGroup": groupName,
+		"backend":     backend.Name,
+	}).Infof("Created group snapshot %s.", snapshotName)
+	return nil
+}
+
+// DeleteVolumeGroup deletes a group and all of its volumes, as long as none
+// of them are attached.  If a volume can't be deleted, the group is kept
+// with the volumes that remain, and deleting it again resumes.  Deletion
+// grace periods don't apply; volumes with the Retain reclaim policy are
+// retained as usual.
+func (o *tridentOrchestrator) DeleteVolumeGroup(
+	ctx context.Context, groupName string,
+) (found bool, err error) {
+	ctx, span := tracing.StartSpan(ctx, tracing.Core, "DeleteVolumeGroup",
+		map[string]string{"volumeGroup": groupName})
+	defer func() {
+		span.Finish(err)
+	}()
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	group, ok := o.volumeGroups[groupName]
+	if !ok {
+		return false, errors.Errorf(errors.NotFound,
+			"Volume group %s not found.", groupName)
+	}
+	vols := o.getVolumeGroupVolumes(group)
+	for _, vol := range vols {
+		if len(vol.Attachments) > 0 {
+			return true, errors.Errorf(errors.Conflict, "Volume %s of "+
+				"group %s is attached to node(s) %s; detach it first.",
+				vol.Config.Name, groupName,
+				strings.Join(vol.AttachedNodes(), ", "))
+		}
+		if err := checkNotReplicated(vol); err != nil {
+			return true, err
+		}
+	}
+
+	deadline := time.Now().Add(o.timeouts.DeleteTimeout())
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+	deleteCtx, deleteCancel := context.WithDeadline(tracing.Detach(ctx),
+		deadline)
+	defer deleteCancel()
+	for _, vol := range vols {
+		if err := o.deleteVolumeInTxn(ctx, deleteCtx, vol); err != nil {
+			return true, fmt.Errorf("Unable to delete volume %s of group "+
+				"%s:  %v", vol.Config.Name, groupName, err)
+		}
+	}
+	if err := o.storeClient.DeleteVolumeGroup(group); err != nil {
+		return true, err
+	}
+	delete(o.volumeGroups, groupName)
+	log.WithFields(log.Fields{
+		"volumeGroup": groupName,
+	}).Info("Deleted volume group.")
+	return true, nil
+}
@@ -0,0 +1,187 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package core
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+
+	"github.com/netapp/trident/persistent_store"
+	"github.com/netapp/trident/storage"
+)
+
+// removeFailedVolume deletes a volume whose creation failed from its
+// backend and the store.  If the backend can't delete it, the volume is
+// recorded as a zombie for the janitor to delete later, which counts as
+// cleaning it up, so that the creation's transaction can be removed and the
+// user can try again.  The caller must hold the orchestrator lock.
+func (o *tridentOrchestrator) removeFailedVolume(
+	backend *storage.StorageBackend, vol *storage.Volume,
+) error {
+	// The request's context may be done, so the deletion is given its own.
+	ctx, cancel := context.WithTimeout(context.Background(),
+		o.timeouts.DeleteTimeout())
+	defer cancel()
+	if err := backend.RemoveVolume(ctx, vol); err != nil {
+		err = fmt.Errorf("Unable to delete volume from backend during "+
+			"cleanup:  %v", err)
+		if zombieErr := o.recordZombieVolume(vol, err); zombieErr != nil {
+			return fmt.Errorf("%v; %v", err, zombieErr)
+		}
+	}
+	if err := o.storeClient.DeleteVolumeIgnoreNotFound(vol); err != nil {
+		return fmt.Errorf("Unable to delete volume from the store during "+
+			"cleanup:  %v", err)
+	}
+	return nil
+}
+
+// recordZombieVolume records a volume that couldn't be deleted from its
+// backend, after deleting it failed with err.  The caller must hold the
+// orchestrator lock.
+func (o *tridentOrchestrator) recordZombieVolume(
+	vol *storage.Volume, err error,
+) error {
+	zombie := persistent_store.NewZombieVolume(vol, err)
+	if storeErr := o.storeClient.AddZombieVolume(zombie); storeErr != nil {
+		return fmt.Errorf("Unable to record volume %s for cleanup:  %v",
+			vol.Config.Name, storeErr)
+	}
+	log.WithFields(log.Fields{
+		"volume":       vol.Config.Name,
+		"internalName": vol.Config.InternalName,
+		"backend":      vol.Backend.Name,
+	}).Warnf("Unable to delete a volume left by a failed operation; the "+
+		"janitor will keep trying to delete it:  %v", err)
+	return nil
+}
+
+// ListZombieVolumes returns the volumes left on their backends by failed
+// operations that the janitor hasn't deleted yet, sorted by backend and
+// internal name.
+func (o *tridentOrchestrator) ListZombieVolumes() (
+	[]*persistent_store.ZombieVolume, error,
+) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	zombies, err := o.storeClient.GetZombieVolumes()
+	if err != nil {
+		if err.Error() == persistent_store.KeyErrorMsg {
+			return make([]*persistent_store.ZombieVolume, 0), nil
+		}
+		return nil, err
+	}
+	sort.Sort(zombieVolumesByLocation(zombies))
+	return zombies, nil
+}
+
+type zombieVolumesByLocation []*persistent_store.ZombieVolume
+
+func (z zombieVolumesByLocation) Len() int      { return len(z) }
+func (z zombieVolumesByLocation) Swap(i, j int) { z[i], z[j] = z[j], z[i] }
+func (z zombieVolumesByLocation) Less(i, j int) bool {
+	if z[i].Backend != z[j].Backend {
+		return z[i].Backend < z[j].Backend
+	}
+	return z[i].Config.InternalName < z[j].Config.InternalName
+}
+
+// deleteZombieVolumes tries again to delete each zombie volume from its
+// backend, and returns the number deleted.  Zombies are retried for as long
+// as they exist, since each is storage that nothing else will reclaim.
+func (o *tridentOrchestrator) deleteZombieVolumes() int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	zombies, err := o.storeClient.GetZombieVolumes()
+	if err != nil {
+		if err.Error() != persistent_store.KeyErrorMsg {
+			log.Warnf("Unable to check for zombie volumes:  %v", err)
+		}
+		return 0
+	}
+	deleted := 0
+	for _, zombie := range zombies {
+		logFields := log.Fields{
+			"volume":       zombie.Config.Name,
+			"internalName": zombie.Config.InternalName,
+			"backend":      zombie.Backend,
+			"handler":      "ZombieVolumeJanitor",
+		}
+		if o.zombieInUse(zombie) {
+			// A later volume got the same name on the same backend, so the
+			// volume there is no longer the zombie.
+			if err = o.storeClient.DeleteZombieVolume(zombie); err == nil {
+				log.WithFields(logFields).Warn("Forgot a zombie volume whose " +
+					"internal name is now used by another volume.")
+			}
+			continue
+		}
+		if err = o.deleteZombieVolume(zombie); err != nil {
+			zombie.Attempts++
+			zombie.LastAttempt = time.Now().UTC()
+			zombie.LastError = err.Error()
+			if storeErr := o.storeClient.AddZombieVolume(
+				zombie); storeErr != nil {
+				log.WithFields(logFields).Warnf("Unable to record a failed "+
+					"attempt to delete a zombie volume:  %v", storeErr)
+			}
+			log.WithFields(logFields).Warnf("Unable to delete a zombie "+
+				"volume after %d attempts; will retry:  %v",
+				zombie.Attempts, err)
+			continue
+		}
+		if err = o.storeClient.DeleteZombieVolume(zombie); err != nil {
+			log.WithFields(logFields).Warnf("Deleted a zombie volume, but "+
+				"unable to forget it:  %v", err)
+			continue
+		}
+		deleted++
+		log.WithFields(logFields).Info("Deleted a zombie volume.")
+	}
+	return deleted
+}
+
+// deleteZombieVolume deletes a zombie volume from its backend.  The caller
+// must hold the orchestrator lock.
+func (o *tridentOrchestrator) deleteZombieVolume(
+	zombie *persistent_store.ZombieVolume,
+) error {
+	backend, ok := o.backends[zombie.Backend]
+	if !ok {
+		return fmt.Errorf("Backend %s not found.", zombie.Backend)
+	}
+	if !backend.Online {
+		return fmt.Errorf("Backend %s is offline.", zombie.Backend)
+	}
+	pool, ok := backend.Storage[zombie.Pool]
+	if !ok {
+		// The pool was removed from the backend's config; the volume is
+		// still there.
+		pool = storage.NewStoragePool(backend, zombie.Pool)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(),
+		o.timeouts.DeleteTimeout())
+	defer cancel()
+	return backend.DestroyVolume(ctx, storage.NewVolume(zombie.Config,
+		backend, pool))
+}
+
+// zombieInUse returns true if a volume on the zombie's backend has its
+// internal name.  The caller must hold the orchestrator lock.
+func (o *tridentOrchestrator) zombieInUse(
+	zombie *persistent_store.ZombieVolume,
+) bool {
+	for _, vol := range o.volumes {
+		if vol.Backend.Name == zombie.Backend &&
+			vol.Config.InternalName == zombie.Config.InternalName {
+			return true
+		}
+	}
+	return false
+}
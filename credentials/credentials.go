@@ -0,0 +1,224 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+// Package credentials resolves backend credentials kept in an external
+// secret store, so that backend configs, and the copies of them Trident
+// stores, needn't hold them in plaintext.
+package credentials
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// Secret credentials are read from a Kubernetes Secret, named as
+	// <namespace>/<name>.  They are only available with the Kubernetes
+	// frontend.
+	Secret = "secret"
+	// Vault credentials are read from a path in HashiCorp Vault.
+	Vault = "vault"
+
+	// Redacted replaces resolved credentials in API output.
+	Redacted = "<redacted>"
+)
+
+// Config refers to a secret holding some of a backend's config fields,
+// typically username and password.  Each key of the secret names a field
+// whose value it replaces when the backend is initialized.
+type Config struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	// Fields are the config fields last set from the secret.
+	Fields []string `json:"-"`
+}
+
+// Resolver reads secrets from one kind of secret store.
+type Resolver interface {
+	// Resolve returns the values in the named secret, keyed by field.
+	Resolve(name string) (map[string]string, error)
+}
+
+var (
+	mutex     sync.Mutex
+	resolvers = map[string]Resolver{
+		Vault: newVaultResolver(),
+	}
+)
+
+// Register makes r resolve secrets of the given type.
+func Register(credType string, r Resolver) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	resolvers[credType] = r
+}
+
+func getResolver(credType string) (Resolver, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	r, ok := resolvers[credType]
+	if !ok {
+		if credType == Secret {
+			return nil, fmt.Errorf("Kubernetes secrets can only be used " +
+				"with the Kubernetes frontend.")
+		}
+		return nil, fmt.Errorf("Unknown credentials type %q; must be %s "+
+			"or %s.", credType, Secret, Vault)
+	}
+	return r, nil
+}
+
+// Parse reads the credentials setting of a backend config.  It returns nil
+// if the config has none.
+func Parse(configJSON string) (*Config, error) {
+	var backendConfig struct {
+		Credentials *Config `json:"credentials"`
+	}
+	if err := json.Unmarshal([]byte(configJSON), &backendConfig); err != nil {
+		return nil, fmt.Errorf("Unable to parse backend credentials:  %v",
+			err)
+	}
+	c := backendConfig.Credentials
+	if c != nil && c.Name == "" {
+		return nil, fmt.Errorf("Backend credentials must name a secret.")
+	}
+	return c, nil
+}
+
+// Resolve returns configJSON with the fields held by the secret set to the
+// secret's values, and records which fields those are.
+func (c *Config) Resolve(configJSON string) (string, error) {
+	r, err := getResolver(c.Type)
+	if err != nil {
+		return "", err
+	}
+	values, err := r.Resolve(c.Name)
+	if err != nil {
+		return "", fmt.Errorf("Unable to read %s credentials %s:  %v",
+			c.Type, c.Name, err)
+	}
+	var configMap map[string]json.RawMessage
+	if err = json.Unmarshal([]byte(configJSON), &configMap); err != nil {
+		return "", err
+	}
+	c.Fields = make([]string, 0, len(values))
+	for field, value := range values {
+		valueJSON, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		configMap[field] = valueJSON
+		c.Fields = append(c.Fields, field)
+	}
+	sort.Strings(c.Fields)
+	resolved, err := json.Marshal(configMap)
+	if err != nil {
+		return "", err
+	}
+	return string(resolved), nil
+}
+
+// Redact returns a copy of v, a pointer to a config struct, with the string
+// fields set from the secret replaced.  Embedded and nested config structs
+// are copied and redacted as well.
+func (c *Config) Redact(v interface{}, replacement string) interface{} {
+	fields := make(map[string]bool, len(c.Fields))
+	for _, field := range c.Fields {
+		fields[strings.ToLower(field)] = true
+	}
+	// The replacement function never fails.
+	redacted, _ := replaceFields(v, func(field, value string) (string, error) {
+		if fields[strings.ToLower(field)] {
+			return replacement, nil
+		}
+		return value, nil
+	})
+	return redacted
+}
+
+// replaceFields returns a copy of v, a pointer to a config struct, with each
+// string field set to the result of calling replace with its JSON name and
+// value.  Embedded and nested config structs, including those held in
+// interface fields, are copied as well.
+func replaceFields(
+	v interface{}, replace func(field, value string) (string, error),
+) (interface{}, error) {
+	original := reflect.ValueOf(v)
+	if original.Kind() != reflect.Ptr || original.IsNil() ||
+		original.Elem().Kind() != reflect.Struct {
+		return v, nil
+	}
+	copied := reflect.New(original.Elem().Type())
+	copied.Elem().Set(original.Elem())
+	if err := replaceStructFields(copied.Elem(), replace); err != nil {
+		return nil, err
+	}
+	return copied.Interface(), nil
+}
+
+func replaceStructFields(
+	v reflect.Value, replace func(field, value string) (string, error),
+) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		fv := v.Field(i)
+		switch fv.Kind() {
+		case reflect.String:
+			if !fv.CanSet() {
+				continue
+			}
+			value, err := replace(jsonName(t.Field(i)), fv.String())
+			if err != nil {
+				return err
+			}
+			fv.SetString(value)
+		case reflect.Struct:
+			if err := replaceStructFields(fv, replace); err != nil {
+				return err
+			}
+		case reflect.Ptr:
+			if !fv.CanSet() || fv.IsNil() ||
+				fv.Elem().Kind() != reflect.Struct {
+				continue
+			}
+			copied := reflect.New(fv.Elem().Type())
+			copied.Elem().Set(fv.Elem())
+			if err := replaceStructFields(copied.Elem(), replace); err != nil {
+				return err
+			}
+			fv.Set(copied)
+		case reflect.Interface:
+			if !fv.CanSet() || fv.IsNil() {
+				continue
+			}
+			replaced, err := replaceFields(fv.Interface(), replace)
+			if err != nil {
+				return err
+			}
+			fv.Set(reflect.ValueOf(replaced))
+		}
+	}
+	return nil
+}
+
+// jsonName returns the name of a struct field in JSON.
+func jsonName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// Lookup returns the values in the named secret of the given type, e.g., a
+// new password requested from Vault.
+func Lookup(credType, name string) (map[string]string, error) {
+	r, err := getResolver(credType)
+	if err != nil {
+		return nil, err
+	}
+	return r.Resolve(name)
+}
@@ -0,0 +1,190 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package credentials
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// encryptedPrefix marks stored values encrypted with the local key.
+const encryptedPrefix = "encrypted:"
+
+// signingKeyLabel derives the signing key from the encryption key, so that
+// the two are never the same.
+const signingKeyLabel = "trident signing key"
+
+var (
+	aead       cipher.AEAD
+	signingKey []byte
+)
+
+// SetEncryptionKey makes Encrypt encrypt secrets with AES-GCM under key,
+// which must be 16, 24, or 32 bytes long.  A nil key stops encryption.
+func SetEncryptionKey(key []byte) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if key == nil {
+		aead = nil
+		signingKey = nil
+		return nil
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return fmt.Errorf("Invalid encryption key:  %v", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	aead = gcm
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(signingKeyLabel))
+	signingKey = mac.Sum(nil)
+	return nil
+}
+
+// LoadEncryptionKey reads a base64-encoded key from a file and sets it with
+// SetEncryptionKey.
+func LoadEncryptionKey(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Unable to read encryption key:  %v", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(
+		string(data)))
+	if err != nil {
+		return fmt.Errorf("Unable to decode encryption key:  %v", err)
+	}
+	return SetEncryptionKey(key)
+}
+
+func getAEAD() cipher.AEAD {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return aead
+}
+
+// Sign returns a base64-encoded HMAC-SHA256 signature of data, e.g., a
+// support bundle manifest, under a key derived from the encryption key.  It
+// returns "" if no key is set.
+func Sign(data []byte) string {
+	mutex.Lock()
+	key := signingKey
+	mutex.Unlock()
+	if key == nil {
+		return ""
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Verify returns true if signature is the signature of data; see Sign.
+func Verify(data []byte, signature string) bool {
+	expected := Sign(data)
+	return expected != "" && hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// Encrypt returns a copy of v, a pointer to a config struct, with its
+// secrets encrypted for storage.  If no key is set, v is returned as is.
+func Encrypt(v interface{}) (interface{}, error) {
+	gcm := getAEAD()
+	if gcm == nil {
+		return v, nil
+	}
+	return replaceFields(v, func(field, value string) (string, error) {
+		if value == "" || strings.HasPrefix(value, encryptedPrefix) ||
+			(!IsSensitive(field) && urlPassword(value) == "") {
+			return value, nil
+		}
+		nonce := make([]byte, gcm.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return "", fmt.Errorf("Unable to encrypt %s:  %v", field, err)
+		}
+		sealed := gcm.Seal(nonce, nonce, []byte(value), nil)
+		return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed),
+			nil
+	})
+}
+
+// DecryptJSON returns a stored backend config with any encrypted secrets,
+// including those in nested objects, decrypted.
+func DecryptJSON(configJSON []byte) ([]byte, error) {
+	decrypted, _, err := decryptObject(configJSON)
+	return decrypted, err
+}
+
+// decryptObject decrypts the values in a JSON object, returning whether it
+// held any encrypted values.
+func decryptObject(configJSON []byte) ([]byte, bool, error) {
+	var configMap map[string]json.RawMessage
+	if err := json.Unmarshal(configJSON, &configMap); err != nil {
+		return nil, false, err
+	}
+	changed := false
+	for field, raw := range configMap {
+		var (
+			value        string
+			fieldChanged bool
+			err          error
+		)
+		if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+			raw, fieldChanged, err = decryptObject(raw)
+			if err != nil {
+				return nil, false, err
+			}
+		} else if json.Unmarshal(raw, &value) == nil &&
+			strings.HasPrefix(value, encryptedPrefix) {
+			if value, err = decrypt(strings.TrimPrefix(value,
+				encryptedPrefix)); err != nil {
+				return nil, false, fmt.Errorf("Unable to decrypt %s:  %v",
+					field, err)
+			}
+			if raw, err = json.Marshal(value); err != nil {
+				return nil, false, err
+			}
+			fieldChanged = true
+		}
+		if fieldChanged {
+			configMap[field] = raw
+			changed = true
+		}
+	}
+	if !changed {
+		return configJSON, false, nil
+	}
+	decrypted, err := json.Marshal(configMap)
+	return decrypted, true, err
+}
+
+func decrypt(value string) (string, error) {
+	gcm := getAEAD()
+	if gcm == nil {
+		return "", fmt.Errorf("the value is encrypted, but no encryption " +
+			"key is set")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("the encrypted value is too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package credentials
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+// sensitiveWords mark the names of backend config fields that hold
+// secrets, e.g., password and passwordArray.
+var sensitiveWords = []string{"password", "secret", "apikey", "privatekey",
+	"token"}
+
+// IsSensitive returns true if the named config field holds a secret.
+func IsSensitive(field string) bool {
+	field = strings.ToLower(field)
+	for _, word := range sensitiveWords {
+		if strings.Contains(field, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// urlPassword returns the password embedded in value if it's a URL, e.g.,
+// a SolidFire endpoint, or "" if it has none.
+func urlPassword(value string) string {
+	if !strings.Contains(value, "@") {
+		return ""
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.User == nil {
+		return ""
+	}
+	password, _ := u.User.Password()
+	return password
+}
+
+// sanitizeValue returns value with any secret in it replaced by Redacted.
+func sanitizeValue(field, value string) string {
+	if value == "" {
+		return value
+	}
+	if IsSensitive(field) {
+		return Redacted
+	}
+	if password := urlPassword(value); password != "" {
+		sanitized := strings.Replace(value, ":"+password+"@",
+			":"+Redacted+"@", 1)
+		if sanitized == value {
+			// The password is escaped in the URL.
+			return Redacted
+		}
+		return sanitized
+	}
+	return value
+}
+
+// Sanitize returns a copy of v, a pointer to a config struct, with its
+// secrets redacted, for reporting through the API.
+func Sanitize(v interface{}) interface{} {
+	// The replacement function never fails.
+	sanitized, _ := replaceFields(v, func(field, value string) (string, error) {
+		return sanitizeValue(field, value), nil
+	})
+	return sanitized
+}
+
+// SanitizeJSON returns a backend config with its secrets redacted, for
+// logging.  A config that can't be parsed is redacted entirely.
+func SanitizeJSON(configJSON string) string {
+	var configMap map[string]interface{}
+	if err := json.Unmarshal([]byte(configJSON), &configMap); err != nil {
+		return Redacted
+	}
+	sanitizeMap(configMap)
+	sanitized, err := json.Marshal(configMap)
+	if err != nil {
+		return Redacted
+	}
+	return string(sanitized)
+}
+
+func sanitizeMap(configMap map[string]interface{}) {
+	for field, value := range configMap {
+		configMap[field] = sanitizeJSONValue(field, value)
+	}
+}
+
+// sanitizeJSONValue returns a decoded JSON value of a config field with its
+// secrets redacted.  Objects and arrays are sanitized throughout; an array's
+// elements are treated as values of its field, e.g., passwordArray.
+func sanitizeJSONValue(field string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return sanitizeValue(field, v)
+	case map[string]interface{}:
+		sanitizeMap(v)
+	case []interface{}:
+		for i, element := range v {
+			v[i] = sanitizeJSONValue(field, element)
+		}
+	}
+	return value
+}
+
+// SecretValues returns the secrets in a backend config, including those in
+// nested objects and arrays, so that they can be kept out of log output.
+func SecretValues(configJSON string) []string {
+	var configMap map[string]interface{}
+	if err := json.Unmarshal([]byte(configJSON), &configMap); err != nil {
+		return nil
+	}
+	found := make(map[string]bool)
+	secretValues("", configMap, found)
+	secrets := make([]string, 0, len(found))
+	for secret := range found {
+		secrets = append(secrets, secret)
+	}
+	return secrets
+}
+
+// secretValues adds the secrets in a decoded JSON value of a config field to
+// found, treating an array's elements as values of its field.
+func secretValues(field string, value interface{}, found map[string]bool) {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return
+		}
+		if IsSensitive(field) {
+			found[v] = true
+		} else if password := urlPassword(v); password != "" {
+			found[password] = true
+		}
+	case map[string]interface{}:
+		for name, element := range v {
+			secretValues(name, element, found)
+		}
+	case []interface{}:
+		for _, element := range v {
+			secretValues(field, element, found)
+		}
+	}
+}
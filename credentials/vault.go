@@ -0,0 +1,83 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package credentials
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const vaultTimeout = 10 * time.Second
+
+// vaultResolver reads secrets from the Vault server at $VAULT_ADDR, using
+// the token in $VAULT_TOKEN, as the Vault CLI does.
+type vaultResolver struct {
+	client *http.Client
+}
+
+func newVaultResolver() *vaultResolver {
+	return &vaultResolver{client: &http.Client{Timeout: vaultTimeout}}
+}
+
+func (r *vaultResolver) Resolve(path string) (map[string]string, error) {
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return nil, fmt.Errorf("VAULT_ADDR is not set.")
+	}
+	url := strings.TrimSuffix(addr, "/") + "/v1/" + strings.TrimPrefix(path,
+		"/")
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", os.Getenv("VAULT_TOKEN"))
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Vault returned %s.", resp.Status)
+	}
+	return parseVaultSecret(body)
+}
+
+// parseVaultSecret reads the values of a secret from a Vault response.
+// Secrets in version 2 key/value stores are nested in a second data field.
+func parseVaultSecret(body []byte) (map[string]string, error) {
+	var secret struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return nil, fmt.Errorf("Unable to parse Vault response:  %v", err)
+	}
+	data := secret.Data
+	if nested, ok := data["data"]; ok {
+		if _, ok = data["metadata"]; ok {
+			data = nil
+			if err := json.Unmarshal(nested, &data); err != nil {
+				return nil, fmt.Errorf("Unable to parse Vault secret:  %v",
+					err)
+			}
+		}
+	}
+	values := make(map[string]string, len(data))
+	for key, raw := range data {
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, fmt.Errorf("Vault secret value %s is not a string.",
+				key)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
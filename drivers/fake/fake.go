@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	dvp "github.com/netapp/netappdvp/storage_drivers"
 
@@ -18,17 +20,63 @@ const (
 	FakePoolAttribute     = "pool"
 )
 
+var (
+	unreachableMutex     sync.Mutex
+	unreachableInstances = make(map[string]bool)
+	instanceDelays       = make(map[string]time.Duration)
+)
+
+// SetUnreachable makes drivers for the named instance fail to initialize,
+// as if their storage system were down.
+func SetUnreachable(instanceName string, unreachable bool) {
+	unreachableMutex.Lock()
+	defer unreachableMutex.Unlock()
+	if unreachable {
+		unreachableInstances[instanceName] = true
+	} else {
+		delete(unreachableInstances, instanceName)
+	}
+}
+
+func isUnreachable(instanceName string) bool {
+	unreachableMutex.Lock()
+	defer unreachableMutex.Unlock()
+	return unreachableInstances[instanceName]
+}
+
+// SetDelay makes drivers for the named instance wait before creating a
+// volume, as if their storage system were slow to respond.
+func SetDelay(instanceName string, delay time.Duration) {
+	unreachableMutex.Lock()
+	defer unreachableMutex.Unlock()
+	if delay > 0 {
+		instanceDelays[instanceName] = delay
+	} else {
+		delete(instanceDelays, instanceName)
+	}
+}
+
+func getDelay(instanceName string) time.Duration {
+	unreachableMutex.Lock()
+	defer unreachableMutex.Unlock()
+	return instanceDelays[instanceName]
+}
+
 type FakeStoragePool struct {
 	Attrs map[string]sa.Offer
 	Bytes uint64
+	// Protocol, if set, overrides the driver's protocol for the pool's
+	// volumes, so that one backend can serve several protocols.
+	Protocol config.Protocol `json:",omitempty"`
 }
 
 // UnmarshalJSON implements json.Unmarshaler and allows FakeStoragePool
 // to be unmarshaled with the Attrs map correctly defined.
 func (m *FakeStoragePool) UnmarshalJSON(data []byte) error {
 	var tmp struct {
-		Attrs json.RawMessage
-		Bytes uint64
+		Attrs    json.RawMessage
+		Bytes    uint64
+		Protocol config.Protocol
 	}
 
 	err := json.Unmarshal(data, &tmp)
@@ -40,6 +88,7 @@ func (m *FakeStoragePool) UnmarshalJSON(data []byte) error {
 		return err
 	}
 	m.Bytes = tmp.Bytes
+	m.Protocol = tmp.Protocol
 	return nil
 }
 
@@ -49,6 +98,10 @@ type FakeStorageDriverConfig struct {
 	// pools represents the possible buckets into which a given volume should go
 	Pools        map[string]*FakeStoragePool
 	InstanceName string
+	// Portals are the iSCSI target portals reported by block backends.
+	Portals []string
+	// Password is the password of the fake storage system's account.
+	Password string `json:",omitempty"`
 }
 
 type FakeStorageDriver struct {
@@ -62,6 +115,59 @@ type FakeStorageDriver struct {
 	// different driver instances with the same config won't actually share
 	// state.
 	DestroyedVolumes map[string]bool
+	// Snapshots maps volume names to their snapshots.
+	Snapshots map[string][]dvp.CommonSnapshot
+	// Mirrors maps the names of volumes that are mirror destinations to
+	// their relationships.
+	Mirrors map[string]*FakeMirror
+	// UsedBytes maps volume names to the space they report consuming;
+	// volumes that aren't listed consume none.
+	UsedBytes map[string]uint64
+	// mutex guards Volumes and the pools' space while volumes are created
+	// and destroyed, which may continue without the orchestrator's lock
+	// after it abandons them.
+	mutex sync.Mutex
+	// calls counts the creations and deletions in progress, and overlapped
+	// records whether two were ever in progress at once; see Overlapped.
+	calls      int
+	overlapped bool
+}
+
+// startCall records the start of a creation or deletion and returns a
+// function that records its end.
+func (m *FakeStorageDriver) startCall() func() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.calls++
+	if m.calls > 1 {
+		m.overlapped = true
+	}
+	return func() {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		m.calls--
+	}
+}
+
+// Overlapped returns true if the driver was ever asked to create or destroy
+// volumes concurrently, which backends serialize since real drivers aren't
+// safe for concurrent use.
+func (m *FakeStorageDriver) Overlapped() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.overlapped
+}
+
+// FakeMirror is a mirror relationship of a fake volume.  Transfers complete
+// immediately, so a mirror is never behind its source unless a test sets
+// LastTransfer.
+type FakeMirror struct {
+	Source       string
+	Broken       bool
+	LastTransfer time.Time
+	// Error, if set, is reported as the reason the relationship is
+	// unhealthy.
+	Error string
 }
 
 func newFakeStorageDriverConfigJSON(
@@ -106,9 +212,16 @@ func (m *FakeStorageDriver) Initialize(configJSON string) error {
 	if err != nil {
 		return fmt.Errorf("Unable to initialize fake driver:  %v", err)
 	}
+	if isUnreachable(m.Config.InstanceName) {
+		return fmt.Errorf("Unable to reach fake instance %s.",
+			m.Config.InstanceName)
+	}
 	m.Volumes = make(map[string]string)
 	m.VolumesAdded = 0
 	m.DestroyedVolumes = make(map[string]bool)
+	m.Snapshots = make(map[string][]dvp.CommonSnapshot)
+	m.Mirrors = make(map[string]*FakeMirror)
+	m.UsedBytes = make(map[string]uint64)
 	return nil
 }
 
@@ -117,6 +230,10 @@ func (m *FakeStorageDriver) Validate() error {
 }
 
 func (m *FakeStorageDriver) Create(name string, sizeBytes uint64, opts map[string]string) error {
+	defer m.startCall()()
+	time.Sleep(getDelay(m.Config.InstanceName))
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 
 	poolName, ok := opts[FakePoolAttribute]
 	if !ok {
@@ -141,13 +258,17 @@ func (m *FakeStorageDriver) Create(name string, sizeBytes uint64, opts map[strin
 }
 
 func (m *FakeStorageDriver) Destroy(name string) error {
+	defer m.startCall()()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	m.DestroyedVolumes[name] = true
 	if _, ok := m.Volumes[name]; !ok {
-		// TODO:  return the standard volume not found error once that gets
-		// added to the nDVP.
-		return nil
+		return fmt.Errorf("Could not find volume %s.", name)
 	}
 	delete(m.Volumes, name)
+	delete(m.Snapshots, name)
+	delete(m.Mirrors, name)
+	delete(m.UsedBytes, name)
 	return nil
 }
 
@@ -163,10 +284,35 @@ func (m *FakeStorageDriver) DefaultStoragePrefix() string {
 	return "fake"
 }
 
+// CreateClone creates name in the same pool as source.  Since the fake
+// driver doesn't track space per volume, the clone consumes no space.
 func (d *FakeStorageDriver) CreateClone(
 	name, source, snapshot, newSnapshotPrefix string,
 ) error {
-	return errors.New("Fake driver does not support CreateClone")
+	defer d.startCall()()
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	poolName, ok := d.Volumes[source]
+	if !ok {
+		return fmt.Errorf("Could not find volume %s.", source)
+	}
+	if _, ok = d.Volumes[name]; ok {
+		return fmt.Errorf("Volume %s already exists", name)
+	}
+	found := false
+	for _, s := range d.Snapshots[source] {
+		if s.Name == snapshot {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("Could not find snapshot %s for volume %s.",
+			snapshot, source)
+	}
+	d.Volumes[name] = poolName
+	d.VolumesAdded++
+	return nil
 }
 
 func (d *FakeStorageDriver) DefaultSnapshotPrefix() string {
@@ -174,7 +320,64 @@ func (d *FakeStorageDriver) DefaultSnapshotPrefix() string {
 }
 
 func (d *FakeStorageDriver) SnapshotList(name string) ([]dvp.CommonSnapshot, error) {
-	return nil, errors.New("Fake driver does not support SnapshotList")
+	if _, ok := d.Volumes[name]; !ok {
+		return nil, fmt.Errorf("Could not find volume %s.", name)
+	}
+	return d.Snapshots[name], nil
+}
+
+func (d *FakeStorageDriver) CreateSnapshot(volumeName, snapshotName string) error {
+	if _, ok := d.Volumes[volumeName]; !ok {
+		return fmt.Errorf("Could not find volume %s.", volumeName)
+	}
+	for _, snapshot := range d.Snapshots[volumeName] {
+		if snapshot.Name == snapshotName {
+			return fmt.Errorf("Snapshot %s already exists for volume %s.",
+				snapshotName, volumeName)
+		}
+	}
+	d.Snapshots[volumeName] = append(d.Snapshots[volumeName],
+		dvp.CommonSnapshot{
+			Name:    snapshotName,
+			Created: time.Now().UTC().Format(time.RFC3339),
+		})
+	return nil
+}
+
+// CreateGroupSnapshot snapshots each volume, failing before any snapshot
+// is created if one can't be.
+func (d *FakeStorageDriver) CreateGroupSnapshot(
+	volumeNames []string, snapshotName string,
+) error {
+	for _, volumeName := range volumeNames {
+		if _, ok := d.Volumes[volumeName]; !ok {
+			return fmt.Errorf("Could not find volume %s.", volumeName)
+		}
+		for _, snapshot := range d.Snapshots[volumeName] {
+			if snapshot.Name == snapshotName {
+				return fmt.Errorf("Snapshot %s already exists for volume "+
+					"%s.", snapshotName, volumeName)
+			}
+		}
+	}
+	for _, volumeName := range volumeNames {
+		if err := d.CreateSnapshot(volumeName, snapshotName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *FakeStorageDriver) DeleteSnapshot(volumeName, snapshotName string) error {
+	snapshots := d.Snapshots[volumeName]
+	for i, snapshot := range snapshots {
+		if snapshot.Name == snapshotName {
+			d.Snapshots[volumeName] = append(snapshots[:i], snapshots[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("Could not find snapshot %s for volume %s.",
+		snapshotName, volumeName)
 }
 
 func (m *FakeStorageDriver) List(prefix string) ([]string, error) {
@@ -186,11 +389,17 @@ func (m *FakeStorageDriver) List(prefix string) ([]string, error) {
 }
 
 func (d *FakeStorageDriver) Get(name string) error {
-
-	_, ok := d.Volumes[name]
-	if !ok {
+	if _, ok := d.VolumePool(name); !ok {
 		return fmt.Errorf("Could not find volume %s.", name)
 	}
-
 	return nil
 }
+
+// VolumePool returns the name of the pool holding a volume, and whether the
+// volume exists.
+func (d *FakeStorageDriver) VolumePool(name string) (string, bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	poolName, ok := d.Volumes[name]
+	return poolName, ok
+}
@@ -0,0 +1,78 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+// Package errors defines the types of error returned by the orchestrator,
+// so that frontends can report each appropriately and clients can tell them
+// apart.  Errors without a type are unexpected failures.
+package errors
+
+import (
+	"fmt"
+)
+
+// Type identifies a kind of error.
+type Type string
+
+const (
+	// NotFound means a named object doesn't exist.
+	NotFound Type = "NotFound"
+	// AlreadyExists means an object with the same name already exists.
+	AlreadyExists Type = "AlreadyExists"
+	// InvalidInput means a request is malformed or names something that
+	// can't be used.
+	InvalidInput Type = "InvalidInput"
+	// BackendUnavailable means no backend can currently satisfy a request.
+	BackendUnavailable Type = "BackendUnavailable"
+	// Conflict means a request can't be carried out in the object's current
+	// state, e.g., deleting an attached volume.
+	Conflict Type = "Conflict"
+)
+
+// Error is an error with a type.
+type Error struct {
+	Type    Type
+	Message string
+	// Details, if set, explain the error in a form that clients can parse,
+	// e.g., why no storage pool could hold a volume.
+	Details interface{}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Errorf formats an error of the given type in the manner of fmt.Errorf.
+func Errorf(errorType Type, format string, a ...interface{}) error {
+	return &Error{Type: errorType, Message: fmt.Sprintf(format, a...)}
+}
+
+// ErrorfWithDetails formats an error like Errorf and attaches details.
+func ErrorfWithDetails(
+	errorType Type, details interface{}, format string, a ...interface{},
+) error {
+	return &Error{
+		Type:    errorType,
+		Message: fmt.Sprintf(format, a...),
+		Details: details,
+	}
+}
+
+// GetType returns the type of err, or "" if it has none.
+func GetType(err error) Type {
+	if typedErr, ok := err.(*Error); ok {
+		return typedErr.Type
+	}
+	return ""
+}
+
+// IsNotFound returns true if err is a NotFound error.
+func IsNotFound(err error) bool {
+	return GetType(err) == NotFound
+}
+
+// GetDetails returns the details of err, or nil if it has none.
+func GetDetails(err error) interface{} {
+	if typedErr, ok := err.(*Error); ok {
+		return typedErr.Details
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package errors
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGetType(t *testing.T) {
+	for _, test := range []struct {
+		err      error
+		expected Type
+	}{
+		{Errorf(NotFound, "Volume %s not found.", "vol1"), NotFound},
+		{Errorf(Conflict, "Volume is attached."), Conflict},
+		{fmt.Errorf("Untyped error."), ""},
+		{nil, ""},
+	} {
+		if errorType := GetType(test.err); errorType != test.expected {
+			t.Errorf("%v:  expected type %q, got %q.", test.err,
+				test.expected, errorType)
+		}
+	}
+	err := Errorf(AlreadyExists, "Volume %s already exists.", "vol1")
+	if err.Error() != "Volume vol1 already exists." {
+		t.Errorf("Unexpected message:  %s", err.Error())
+	}
+	if IsNotFound(err) {
+		t.Error("AlreadyExists error reported as NotFound.")
+	}
+}
+
+func TestGetDetails(t *testing.T) {
+	details := []string{"pool1", "pool2"}
+	err := ErrorfWithDetails(BackendUnavailable, details, "No pools.")
+	if GetType(err) != BackendUnavailable || err.Error() != "No pools." {
+		t.Errorf("Unexpected error:  %v", err)
+	}
+	if got, ok := GetDetails(err).([]string); !ok || len(got) != 2 {
+		t.Errorf("Expected details %v; got %v.", details, GetDetails(err))
+	}
+	for _, err = range []error{Errorf(NotFound, "Not found."),
+		fmt.Errorf("Untyped error."), nil} {
+		if GetDetails(err) != nil {
+			t.Errorf("%v:  expected no details.", err)
+		}
+	}
+}
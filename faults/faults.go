@@ -0,0 +1,153 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+// Package faults injects failures at fixed points in volume creation and
+// deletion, so that operators and tests can verify that Trident recovers
+// from them.  No failure point is armed unless configured.
+package faults
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	/* Failure points */
+	// VolumeTxnLogged is after a volume creation transaction is stored and
+	// before the volume is created on a backend.
+	VolumeTxnLogged = "volume_txn_logged"
+	// VolumeCreatedOnBackend is after a volume is created on a backend and
+	// before it is stored.
+	VolumeCreatedOnBackend = "volume_created_on_backend"
+	// VolumeStored is after a new volume is stored and before its
+	// transaction is resolved.
+	VolumeStored = "volume_stored"
+	// VolumeDeletedFromBackend is after a volume is deleted from its backend
+	// and before it is removed from the store.
+	VolumeDeletedFromBackend = "volume_deleted_from_backend"
+
+	/* Failure modes */
+	// ModeError fails the operation, which then cleans up after itself.
+	ModeError = "error"
+	// ModeCrash fails the operation without any cleanup, as if Trident had
+	// crashed, leaving the transaction to be rolled back at the next
+	// bootstrap.  Trident should be restarted after such a failure.
+	ModeCrash = "crash"
+)
+
+var (
+	validPoints = map[string]bool{
+		VolumeTxnLogged:          true,
+		VolumeCreatedOnBackend:   true,
+		VolumeStored:             true,
+		VolumeDeletedFromBackend: true,
+	}
+
+	mutex sync.Mutex
+	armed = make(map[string]string)
+)
+
+// Error is the error returned at an armed failure point.
+type Error struct {
+	Point string
+	Mode  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("Injected failure at %s.", e.Point)
+}
+
+// IsCrash returns true if err simulates a crash, in which case the caller
+// must not clean up.
+func IsCrash(err error) bool {
+	faultErr, ok := err.(*Error)
+	return ok && faultErr.Mode == ModeCrash
+}
+
+// GetPoints returns the names of all failure points.
+func GetPoints() []string {
+	ret := make([]string, 0, len(validPoints))
+	for point := range validPoints {
+		ret = append(ret, point)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// Validate returns an error if point or mode is unknown.
+func Validate(point, mode string) error {
+	if !validPoints[point] {
+		return fmt.Errorf("Unknown failure point %q; must be one of %s.",
+			point, strings.Join(GetPoints(), ", "))
+	}
+	if mode != ModeError && mode != ModeCrash {
+		return fmt.Errorf("Invalid failure mode %q for %s; must be %s or %s.",
+			mode, point, ModeError, ModeCrash)
+	}
+	return nil
+}
+
+// Arm makes Check fail at point in the given mode until Disarm is called.
+func Arm(point, mode string) error {
+	if err := Validate(point, mode); err != nil {
+		return err
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	armed[point] = mode
+	log.WithFields(log.Fields{
+		"point": point,
+		"mode":  mode,
+	}).Warn("Armed a failure point; operations reaching it will fail.")
+	return nil
+}
+
+// Disarm stops failing at point.
+func Disarm(point string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	delete(armed, point)
+}
+
+// DisarmAll stops failing at every point.
+func DisarmAll() {
+	mutex.Lock()
+	defer mutex.Unlock()
+	armed = make(map[string]string)
+}
+
+// Check returns an *Error if point is armed.
+func Check(point string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+	mode, ok := armed[point]
+	if !ok {
+		return nil
+	}
+	log.WithFields(log.Fields{
+		"point": point,
+		"mode":  mode,
+	}).Warn("Injecting failure.")
+	return &Error{Point: point, Mode: mode}
+}
+
+// ParsePoints parses a list of point=mode pairs, e.g.,
+// "volume_stored=crash,volume_txn_logged=error".
+func ParsePoints(spec string) (map[string]string, error) {
+	points := make(map[string]string)
+	if spec == "" {
+		return points, nil
+	}
+	for _, pair := range strings.Split(spec, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("Invalid failure point %q; expected "+
+				"point=mode.", pair)
+		}
+		points[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return points, nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package faults
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePoints(t *testing.T) {
+	points, err := ParsePoints("volume_stored=crash, volume_txn_logged=error")
+	if err != nil {
+		t.Fatal("Unable to parse failure points:  ", err)
+	}
+	expected := map[string]string{
+		VolumeStored:    ModeCrash,
+		VolumeTxnLogged: ModeError,
+	}
+	if !reflect.DeepEqual(points, expected) {
+		t.Errorf("Expected %v; got %v.", expected, points)
+	}
+	for _, spec := range []string{
+		"volume_stored", "=crash", "volume_stored=crash,",
+	} {
+		if _, err = ParsePoints(spec); err == nil {
+			t.Errorf("Expected an error parsing %q.", spec)
+		}
+	}
+}
+
+func TestArm(t *testing.T) {
+	defer DisarmAll()
+	if err := Arm("nowhere", ModeError); err == nil {
+		t.Error("Armed an unknown failure point.")
+	}
+	if err := Arm(VolumeStored, "explode"); err == nil {
+		t.Error("Armed a failure point with an unknown mode.")
+	}
+	if err := Check(VolumeStored); err != nil {
+		t.Error("Unarmed failure point failed:  ", err)
+	}
+	if err := Arm(VolumeStored, ModeCrash); err != nil {
+		t.Fatal("Unable to arm failure point:  ", err)
+	}
+	if err := Check(VolumeStored); !IsCrash(err) {
+		t.Errorf("Expected a crash; got %v.", err)
+	}
+	if err := Check(VolumeTxnLogged); err != nil {
+		t.Error("Unarmed failure point failed:  ", err)
+	}
+	Disarm(VolumeStored)
+	if err := Check(VolumeStored); err != nil {
+		t.Error("Disarmed failure point failed:  ", err)
+	}
+}
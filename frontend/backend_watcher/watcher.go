@@ -0,0 +1,212 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+// Package backend_watcher keeps Trident's backends in line with a set of
+// backend configs, e.g., files kept in version control, adding, updating,
+// and offlining backends as the configs are added, changed, and removed.
+package backend_watcher
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+
+	"github.com/netapp/trident/core"
+)
+
+const Name = "backend_watcher"
+
+// Source reads a set of backend configs, keyed by a name that identifies
+// each config within the set, e.g., its file name.
+type Source interface {
+	Read() (map[string]string, error)
+	String() string
+}
+
+type directorySource struct {
+	dir string
+}
+
+// NewDirectorySource returns a Source that reads each *.json file in dir as
+// a backend config.  Kubernetes ConfigMaps mounted as volumes may be read
+// this way.
+func NewDirectorySource(dir string) Source {
+	return &directorySource{dir: dir}
+}
+
+func (s *directorySource) Read() (map[string]string, error) {
+	// A missing directory mustn't look like an empty one, which would
+	// offline every backend.
+	if _, err := os.Stat(s.dir); err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	configs := make(map[string]string, len(paths))
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		configs[filepath.Base(path)] = string(data)
+	}
+	return configs, nil
+}
+
+func (s *directorySource) String() string {
+	return fmt.Sprintf("directory %s", s.dir)
+}
+
+// watchedConfig is a config that has been applied and the backend it added.
+type watchedConfig struct {
+	config  string
+	backend string
+}
+
+// Watcher is a frontend that periodically reads its source and adds or
+// updates a backend for each new or changed config, and offlines the
+// backends of configs that have been removed.  It only offlines backends
+// that it added since Trident started; backends added through the API are
+// left alone.
+type Watcher struct {
+	orchestrator core.Orchestrator
+	source       Source
+	interval     time.Duration
+	// watched holds the applied configs, by key.
+	watched map[string]*watchedConfig
+	stop    chan bool
+	done    sync.WaitGroup
+}
+
+func NewWatcher(
+	orchestrator core.Orchestrator, source Source, interval time.Duration,
+) *Watcher {
+	return &Watcher{
+		orchestrator: orchestrator,
+		source:       source,
+		interval:     interval,
+		watched:      make(map[string]*watchedConfig),
+	}
+}
+
+func (w *Watcher) GetName() string {
+	return Name
+}
+
+// Activate applies the configs and starts watching them for changes.
+func (w *Watcher) Activate() error {
+	w.stop = make(chan bool)
+	w.done.Add(1)
+	go w.run()
+	log.WithFields(log.Fields{
+		"source":   w.source.String(),
+		"interval": w.interval,
+	}).Info("Watching backend configs.")
+	return nil
+}
+
+func (w *Watcher) Deactivate() error {
+	close(w.stop)
+	w.done.Wait()
+	return nil
+}
+
+func (w *Watcher) run() {
+	defer w.done.Done()
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		w.sync()
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync applies any new or changed configs and offlines the backends of
+// removed ones.  If the source can't be read, nothing changes.  A config
+// that fails to apply is retried at the next check, and the backend added
+// from its previous version, if any, is kept.
+func (w *Watcher) sync() {
+	configs, err := w.source.Read()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"source": w.source.String(),
+			"error":  err,
+		}).Error("Unable to read backend configs.")
+		return
+	}
+	previous := make(map[string]bool, len(w.watched))
+	for _, watched := range w.watched {
+		previous[watched.backend] = true
+	}
+
+	keys := make([]string, 0, len(configs))
+	for key := range configs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		configJSON := configs[key]
+		if watched, ok := w.watched[key]; ok && watched.config == configJSON {
+			continue
+		}
+		backend, err := w.orchestrator.AddStorageBackend(
+			context.Background(), configJSON)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"config": key,
+				"error":  err,
+			}).Error("Unable to apply backend config.")
+			continue
+		}
+		w.watched[key] = &watchedConfig{
+			config:  configJSON,
+			backend: backend.Name,
+		}
+		log.WithFields(log.Fields{
+			"config":  key,
+			"backend": backend.Name,
+		}).Info("Applied backend config.")
+		for _, warning := range backend.Warnings {
+			log.WithFields(log.Fields{
+				"config":  key,
+				"backend": backend.Name,
+			}).Warn(warning)
+		}
+	}
+
+	current := make(map[string]bool, len(configs))
+	for key, watched := range w.watched {
+		if _, ok := configs[key]; !ok {
+			delete(w.watched, key)
+			continue
+		}
+		current[watched.backend] = true
+	}
+	for backend := range previous {
+		if current[backend] {
+			continue
+		}
+		if _, err := w.orchestrator.OfflineBackend(backend); err != nil {
+			log.WithFields(log.Fields{
+				"backend": backend,
+				"error":   err,
+			}).Error("Unable to offline backend whose config was removed.")
+			continue
+		}
+		log.WithFields(log.Fields{
+			"backend": backend,
+		}).Info("Offlined backend whose config was removed.")
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package backend_watcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/core"
+	"github.com/netapp/trident/drivers/fake"
+	"github.com/netapp/trident/persistent_store"
+	sa "github.com/netapp/trident/storage_attribute"
+)
+
+func writeBackendConfig(
+	t *testing.T, dir, file, backendName string, pools ...string,
+) {
+	fakePools := make(map[string]*fake.FakeStoragePool, len(pools))
+	for _, pool := range pools {
+		fakePools[pool] = &fake.FakeStoragePool{
+			Attrs: map[string]sa.Offer{
+				sa.Media: sa.NewStringOffer("hdd"),
+			},
+			Bytes: 100 * 1024 * 1024 * 1024,
+		}
+	}
+	configJSON, err := fake.NewFakeStorageDriverConfigJSON(backendName,
+		config.File, fakePools)
+	if err != nil {
+		t.Fatal("Unable to generate config JSON:  ", err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, file), []byte(configJSON),
+		0644); err != nil {
+		t.Fatal("Unable to write backend config:  ", err)
+	}
+}
+
+func TestWatchDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backends")
+	if err != nil {
+		t.Fatal("Unable to create config directory:  ", err)
+	}
+	defer os.RemoveAll(dir)
+	orchestrator := core.NewTridentOrchestrator(
+		persistent_store.NewInMemoryClient())
+	if err = orchestrator.Bootstrap(); err != nil {
+		t.Fatal("Unable to bootstrap orchestrator:  ", err)
+	}
+	watcher := NewWatcher(orchestrator, NewDirectorySource(dir),
+		config.BackendWatchInterval)
+
+	writeBackendConfig(t, dir, "first.json", "first", "primary")
+	writeBackendConfig(t, dir, "second.json", "second", "primary")
+	if err = ioutil.WriteFile(filepath.Join(dir, "README"),
+		[]byte("Not a backend."), 0644); err != nil {
+		t.Fatal("Unable to write file:  ", err)
+	}
+	watcher.sync()
+	for _, name := range []string{"first", "second"} {
+		if orchestrator.GetBackend(name) == nil {
+			t.Errorf("Backend %s not added.", name)
+		}
+	}
+
+	// Changed configs update their backends.
+	writeBackendConfig(t, dir, "first.json", "first", "primary",
+		"secondary")
+	watcher.sync()
+	backend := orchestrator.GetBackend("first")
+	if backend == nil {
+		t.Fatal("Backend first not found.")
+	}
+	if _, ok := backend.Storage["secondary"]; !ok {
+		t.Error("Backend first not updated.")
+	}
+
+	// An invalid config leaves the backend added from its last version.
+	if err = ioutil.WriteFile(filepath.Join(dir, "second.json"),
+		[]byte("{"), 0644); err != nil {
+		t.Fatal("Unable to write file:  ", err)
+	}
+	watcher.sync()
+	if orchestrator.GetBackend("second") == nil {
+		t.Error("Backend second removed by an invalid config.")
+	}
+
+	// Removed configs offline their backends.
+	if err = os.Remove(filepath.Join(dir, "second.json")); err != nil {
+		t.Fatal("Unable to remove config:  ", err)
+	}
+	watcher.sync()
+	if orchestrator.GetBackend("second") != nil {
+		t.Error("Backend second not offlined.")
+	}
+	if orchestrator.GetBackend("first") == nil {
+		t.Error("Backend first offlined.")
+	}
+
+	// An unreadable source changes nothing.
+	if err = os.RemoveAll(dir); err != nil {
+		t.Fatal("Unable to remove config directory:  ", err)
+	}
+	watcher.sync()
+	if orchestrator.GetBackend("first") == nil {
+		t.Error("Backend first offlined when its directory was unreadable.")
+	}
+}
@@ -7,3 +7,30 @@ type FrontendPlugin interface {
 	Deactivate() error
 	GetName() string
 }
+
+// PersistentVolumeRequest asks a container orchestrator frontend for a
+// persistent volume object for an existing Trident volume, e.g., one that
+// was imported or retained, so that a specific claim can consume it.
+type PersistentVolumeRequest struct {
+	Volume string `json:"volume"`
+	// ClaimNamespace and ClaimName, if set, pre-bind the persistent volume
+	// to that claim.
+	ClaimNamespace string `json:"claimNamespace,omitempty"`
+	ClaimName      string `json:"claimName,omitempty"`
+	// StorageClass defaults to the volume's storage class.
+	StorageClass string `json:"storageClass,omitempty"`
+	// AccessModes default to those of the volume's access mode.
+	AccessModes []string `json:"accessModes,omitempty"`
+	// ReclaimPolicy is Retain or Delete; it defaults to Retain.
+	ReclaimPolicy string `json:"reclaimPolicy,omitempty"`
+	// DryRun returns the persistent volume without creating it.
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// PersistentVolumeCreator is implemented by frontends that can create
+// persistent volume objects in their container orchestrator.
+type PersistentVolumeCreator interface {
+	CreatePersistentVolume(
+		request *PersistentVolumeRequest,
+	) (interface{}, error)
+}
@@ -0,0 +1,66 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/client-go/pkg/api/v1"
+
+	"github.com/netapp/trident/storage"
+)
+
+// resolveCloneSource sets volConfig's source volume and snapshot from the
+// claim's AnnCloneFromPVC and AnnCloneFromSnapshot annotations, if it has
+// them.  Users can only clone claims they could use themselves, so the
+// source must be a bound claim in the same namespace, and its volume must be
+// the one Trident provisioned for it.
+func (p *KubernetesPlugin) resolveCloneSource(
+	claim *v1.PersistentVolumeClaim, volConfig *storage.VolumeConfig,
+) error {
+	sourceName := getAnnotation(claim.Annotations, AnnCloneFromPVC)
+	if sourceName == "" {
+		return nil
+	}
+	if volConfig.SourceVolume != "" {
+		return fmt.Errorf("%s and %s must not be specified together.",
+			AnnCloneFromPVC, AnnSourceVolume)
+	}
+	if strings.Contains(sourceName, "/") {
+		return fmt.Errorf("PVC %s/%s can only be cloned from a PVC in its "+
+			"own namespace, not %s.", claim.Namespace, claim.Name,
+			sourceName)
+	}
+	snapshot := getAnnotation(claim.Annotations, AnnCloneFromSnapshot)
+	if snapshot == "" {
+		snapshot = volConfig.SourceSnapshot
+	}
+	if snapshot == "" {
+		return fmt.Errorf("%s requires %s, the snapshot of PVC %s to clone.",
+			AnnCloneFromPVC, AnnCloneFromSnapshot, sourceName)
+	}
+
+	source, err := p.kubeClient.Core().PersistentVolumeClaims(
+		claim.Namespace).Get(sourceName)
+	if err != nil {
+		return fmt.Errorf("Unable to get source PVC %s/%s:  %v",
+			claim.Namespace, sourceName, err)
+	}
+	if source.Status.Phase != v1.ClaimBound || source.Spec.VolumeName == "" {
+		return fmt.Errorf("Source PVC %s/%s is not bound.", claim.Namespace,
+			sourceName)
+	}
+	// The volume's name includes the claim's namespace and UID, so a claim
+	// bound to another claim's volume, or to a volume Trident didn't
+	// provision, can't be cloned.
+	volName := getUniqueClaimName(source)
+	if source.Spec.VolumeName != volName ||
+		p.orchestrator.GetVolume(volName) == nil {
+		return fmt.Errorf("Source PVC %s/%s is not bound to a volume "+
+			"Trident provisioned for it.", claim.Namespace, sourceName)
+	}
+	volConfig.SourceVolume = volName
+	volConfig.SourceSnapshot = snapshot
+	return nil
+}
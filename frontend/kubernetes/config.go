@@ -16,18 +16,56 @@ const (
 	AnnClass                  = "volume.beta.kubernetes.io/storage-class"
 	AnnDynamicallyProvisioned = "pv.kubernetes.io/provisioned-by"
 	AnnStorageProvisioner     = "volume.beta.kubernetes.io/storage-provisioner"
+	AnnPVMountOptions         = "volume.beta.kubernetes.io/mount-options"
 	// Provisioner-defined annotations
-	AnnProvisioner     = "netapp.io/" + config.OrchestratorName
-	AnnPrefix          = config.OrchestratorName + ".netapp.io"
-	AnnReclaimPolicy   = AnnPrefix + "/reclaimPolicy"
-	AnnProtocol        = AnnPrefix + "/protocol"
-	AnnSpaceReserve    = AnnPrefix + "/spaceReserve"
-	AnnSnapshotPolicy  = AnnPrefix + "/snapshotPolicy"
-	AnnSnapshotDir     = AnnPrefix + "/snapshotDirectory"
-	AnnUnixPermissions = AnnPrefix + "/unixPermissions"
-	AnnVendor          = AnnPrefix + "/vendor"
-	AnnBackendID       = AnnPrefix + "/backendID"
-	AnnExportPolicy    = AnnPrefix + "/exportPolicy"
+	AnnProvisioner      = "netapp.io/" + config.OrchestratorName
+	AnnPrefix           = config.OrchestratorName + ".netapp.io"
+	AnnReclaimPolicy    = AnnPrefix + "/reclaimPolicy"
+	AnnProtocol         = AnnPrefix + "/protocol"
+	AnnSpaceReserve     = AnnPrefix + "/spaceReserve"
+	AnnSnapshotPolicy   = AnnPrefix + "/snapshotPolicy"
+	AnnSnapshotDir      = AnnPrefix + "/snapshotDirectory"
+	AnnSnapshotReserve  = AnnPrefix + "/snapshotReserve"
+	AnnUnixPermissions  = AnnPrefix + "/unixPermissions"
+	AnnVendor           = AnnPrefix + "/vendor"
+	AnnBackendID        = AnnPrefix + "/backendID"
+	AnnExportPolicy     = AnnPrefix + "/exportPolicy"
+	AnnMinIOPS          = AnnPrefix + "/minIOPS"
+	AnnMaxIOPS          = AnnPrefix + "/maxIOPS"
+	AnnBurstIOPS        = AnnPrefix + "/burstIOPS"
+	AnnEncryption       = AnnPrefix + "/encryption"
+	AnnProvisioningType = AnnPrefix + "/provisioningType"
+	AnnDeduplication    = AnnPrefix + "/deduplication"
+	AnnCompression      = AnnPrefix + "/compression"
+	AnnSnapshotSchedule = AnnPrefix + "/snapshotSchedule"
+	AnnSourceVolume     = AnnPrefix + "/sourceVolume"
+	AnnSourceSnapshot   = AnnPrefix + "/sourceSnapshot"
+	AnnFSType           = AnnPrefix + "/fsType"
+	AnnMountOptions     = AnnPrefix + "/mountOptions"
+
+	// AnnCloneFromPVC names a PVC in the claim's namespace whose volume
+	// the claim's volume is cloned from, at the snapshot named by
+	// AnnCloneFromSnapshot.
+	AnnCloneFromPVC      = AnnPrefix + "/cloneFromPVC"
+	AnnCloneFromSnapshot = AnnPrefix + "/cloneFromSnapshot"
+
+	// AnnAntiAffinity lists, comma-separated, the volumes, i.e., PVs, whose
+	// backends the claim's volume must not be placed on.  Claims with the
+	// same AnnSpreadGroup in a namespace have their volumes placed on
+	// different backends.
+	AnnAntiAffinity = AnnPrefix + "/antiAffinity"
+	AnnSpreadGroup  = AnnPrefix + "/spreadGroup"
+
+	// AnnReadOnly makes the claim's volume read-only on its backend, as
+	// the ReadOnlyMany access mode does.
+	AnnReadOnly = AnnPrefix + "/readOnly"
+
+	// Namespace annotations that set the namespace's policy; see
+	// namespace_policy.Config.  AnnAllowedStorageClasses is
+	// comma-separated.
+	AnnAllowedStorageClasses = AnnPrefix + "/allowedStorageClasses"
+	AnnDefaultStorageClass   = AnnPrefix + "/defaultStorageClass"
+	AnnMaxVolumeSize         = AnnPrefix + "/maxVolumeSize"
 
 	// Minimum and maximum supported Kubernetes versions
 	KubernetesVersionMin = "1.4"
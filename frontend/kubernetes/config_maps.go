@@ -0,0 +1,53 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/netapp/trident/frontend/backend_watcher"
+)
+
+// configMapSource reads backend configs from a ConfigMap, one per key.
+type configMapSource struct {
+	kubeClient kubernetes.Interface
+	namespace  string
+	name       string
+}
+
+// BackendConfigSource returns a source of backend configs for the backend
+// watcher that reads the ConfigMap named as <namespace>/<name>.
+func (p *KubernetesPlugin) BackendConfigSource(
+	name string,
+) (backend_watcher.Source, error) {
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("ConfigMap %q must be named as "+
+			"<namespace>/<name>.", name)
+	}
+	return &configMapSource{
+		kubeClient: p.kubeClient,
+		namespace:  parts[0],
+		name:       parts[1],
+	}, nil
+}
+
+func (s *configMapSource) Read() (map[string]string, error) {
+	configMap, err := s.kubeClient.Core().ConfigMaps(s.namespace).Get(
+		s.name)
+	if err != nil {
+		return nil, err
+	}
+	configs := make(map[string]string, len(configMap.Data))
+	for key, value := range configMap.Data {
+		configs[key] = value
+	}
+	return configs, nil
+}
+
+func (s *configMapSource) String() string {
+	return fmt.Sprintf("ConfigMap %s/%s", s.namespace, s.name)
+}
@@ -0,0 +1,68 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package kubernetes
+
+import (
+	"fmt"
+	"reflect"
+
+	log "github.com/Sirupsen/logrus"
+	"k8s.io/client-go/pkg/api/v1"
+
+	"github.com/netapp/trident/namespace_policy"
+)
+
+// getNamespacePolicy returns the policy set by a namespace's annotations,
+// or nil if it has none of them.
+func getNamespacePolicy(namespace *v1.Namespace) *namespace_policy.Config {
+	annotations := namespace.Annotations
+	policy := &namespace_policy.Config{
+		Namespace: namespace.Name,
+		AllowedStorageClasses: getListAnnotation(annotations,
+			AnnAllowedStorageClasses),
+		DefaultStorageClass: getAnnotation(annotations,
+			AnnDefaultStorageClass),
+		MaxVolumeSize: getAnnotation(annotations, AnnMaxVolumeSize),
+	}
+	if policy.AllowedStorageClasses == nil &&
+		policy.DefaultStorageClass == "" && policy.MaxVolumeSize == "" {
+		return nil
+	}
+	return policy
+}
+
+// syncNamespacePolicy sets the orchestrator's policy for a namespace from
+// the namespace's annotations, if it has any, and returns the namespace's
+// policy, or nil if it has none.  Annotations replace a policy set through
+// the orchestrator's API.
+func (p *KubernetesPlugin) syncNamespacePolicy(
+	name string,
+) (*namespace_policy.Config, error) {
+	namespace, err := p.kubeClient.Core().Namespaces().Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get namespace %s:  %v", name, err)
+	}
+	current := p.orchestrator.GetNamespacePolicy(name)
+	policy := getNamespacePolicy(namespace)
+	if policy == nil {
+		return current, nil
+	}
+	if current != nil {
+		policy.Version = current.Version
+		if reflect.DeepEqual(policy, current) {
+			return current, nil
+		}
+	}
+	if policy, err = p.orchestrator.SetNamespacePolicy(policy); err != nil {
+		return nil, fmt.Errorf("Invalid policy annotations on namespace "+
+			"%s:  %v", name, err)
+	}
+	log.WithFields(log.Fields{
+		"namespace":             name,
+		"allowedStorageClasses": policy.AllowedStorageClasses,
+		"defaultStorageClass":   policy.DefaultStorageClass,
+		"maxVolumeSize":         policy.MaxVolumeSize,
+	}).Info("Kubernetes frontend set the namespace's policy from its " +
+		"annotations.")
+	return policy, nil
+}
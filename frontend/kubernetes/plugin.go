@@ -5,13 +5,17 @@ package kubernetes
 import (
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	version "github.com/hashicorp/go-version"
 	dvp "github.com/netapp/netappdvp/storage_drivers"
+	"golang.org/x/net/context"
 	"k8s.io/client-go/kubernetes"
 	core_v1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/pkg/api"
+	"k8s.io/client-go/pkg/api/resource"
 	"k8s.io/client-go/pkg/api/unversioned"
 	"k8s.io/client-go/pkg/api/v1"
 	k8s_storage "k8s.io/client-go/pkg/apis/storage/v1beta1"
@@ -26,6 +30,9 @@ import (
 
 	"github.com/netapp/trident/config"
 	"github.com/netapp/trident/core"
+	"github.com/netapp/trident/credentials"
+	"github.com/netapp/trident/errors"
+	"github.com/netapp/trident/frontend"
 	"github.com/netapp/trident/storage"
 	"github.com/netapp/trident/storage_attribute"
 	"github.com/netapp/trident/storage_class"
@@ -67,6 +74,15 @@ type KubernetesPlugin struct {
 	classControllerStopChan      chan struct{}
 	classSource                  cache.ListerWatcher
 	containerOrchestratorVersion *k8s_version.Info
+	// provisioningClaims maps the names of volumes being provisioned to
+	// their claims, so that the orchestrator's provisioning events can be
+	// recorded on the claims.
+	provisioningClaims map[string]*v1.PersistentVolumeClaim
+	claimsMutex        *sync.Mutex
+	// ctx is canceled when the plugin is deactivated, abandoning any
+	// in-flight provisioning.
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 func NewPlugin(
@@ -142,7 +158,12 @@ func newKubernetesPlugin(
 		classControllerStopChan:      make(chan struct{}),
 		pendingClaimMatchMap:         make(map[string]*v1.PersistentVolume),
 		containerOrchestratorVersion: containerOrchestratorVersion,
+		claimsMutex:                  &sync.Mutex{},
 	}
+	ret.provisioningClaims = make(map[string]*v1.PersistentVolumeClaim)
+	ret.ctx, ret.cancel = context.WithCancel(context.Background())
+	// Backends may keep their credentials in Secrets.
+	credentials.Register(credentials.Secret, &secretResolver{kubeClient})
 	broadcaster := record.NewBroadcaster()
 	broadcaster.StartRecordingToSink(
 		&core_v1.EventSinkImpl{
@@ -234,6 +255,7 @@ func newKubernetesPlugin(
 }
 
 func (p *KubernetesPlugin) Activate() error {
+	p.orchestrator.AddProvisioningListener(p)
 	go p.claimController.Run(p.claimControllerStopChan)
 	go p.volumeController.Run(p.volumeControllerStopChan)
 	go p.classController.Run(p.classControllerStopChan)
@@ -244,11 +266,13 @@ func (p *KubernetesPlugin) Deactivate() error {
 	close(p.claimControllerStopChan)
 	close(p.volumeControllerStopChan)
 	close(p.classControllerStopChan)
+	p.cancel()
+	p.orchestrator.RemoveProvisioningListener(p)
 	return nil
 }
 
 func (km *KubernetesPlugin) GetName() string {
-	return "kubernetes"
+	return config.KubernetesFrontendName
 }
 
 func (p *KubernetesPlugin) addClaim(obj interface{}) {
@@ -303,7 +327,13 @@ func (p *KubernetesPlugin) processClaim(
 		// For k8s version < 1.5
 		pvcStorageClass := getClaimClass(claim)
 		if pvcStorageClass == "" {
-			return
+			// Claims without a class are provisioned in namespaces whose
+			// policy gives them a default class.
+			policy, err := p.syncNamespacePolicy(claim.Namespace)
+			if err != nil || policy == nil {
+				return
+			}
+			pvcStorageClass = policy.DefaultStorageClass
 		}
 		if p.orchestrator.GetStorageClass(pvcStorageClass) == nil {
 			return
@@ -428,7 +458,7 @@ func (p *KubernetesPlugin) processLostClaim(claim *v1.PersistentVolumeClaim) {
 	if p.orchestrator.GetVolume(volName) == nil {
 		return
 	}
-	_, err := p.orchestrator.DeleteVolume(volName)
+	_, err := p.orchestrator.DeleteVolume(p.ctx, volName)
 	if err != nil {
 		message := "Kubernetes frontend failed to delete the provisioned " +
 			"volume for the lost PVC (will retry upon resync)."
@@ -458,6 +488,7 @@ func (p *KubernetesPlugin) processDeletedClaim(claim *v1.PersistentVolumeClaim)
 	// handled by processUpdatedVolume.
 	// Remove the pending claim, if present.
 	delete(p.pendingClaimMatchMap, getUniqueClaimName(claim))
+	p.clearProvisioningRetry(getUniqueClaimName(claim))
 }
 
 // processPendingClaim processes PVCs in the pending phase.
@@ -489,9 +520,40 @@ func (p *KubernetesPlugin) processPendingClaim(claim *v1.PersistentVolumeClaim)
 		delete(p.pendingClaimMatchMap, orchestratorClaimName)
 	}
 
-	// We need to provision a new volume for this claim.
+	// We need to provision a new volume for this claim, unless we're
+	// backing off after failed attempts.
+	retry := p.orchestrator.GetProvisioningRetry(orchestratorClaimName)
+	if retry != nil {
+		if retry.Abandoned {
+			log.WithFields(log.Fields{
+				"PVC":      claim.Name,
+				"attempts": retry.Attempts,
+			}).Debug("Kubernetes frontend is not provisioning an abandoned " +
+				"claim.")
+			return
+		}
+		if time.Now().Before(retry.NextAttempt) {
+			log.WithFields(log.Fields{
+				"PVC":         claim.Name,
+				"attempts":    retry.Attempts,
+				"nextAttempt": retry.NextAttempt,
+			}).Debug("Kubernetes frontend is backing off provisioning.")
+			return
+		}
+		p.updateClaimWithEvent(claim, v1.EventTypeNormal,
+			"ProvisioningRetry", fmt.Sprintf("Kubernetes frontend is "+
+				"retrying provisioning after %d failed attempt(s).",
+				retry.Attempts))
+	}
+	p.claimsMutex.Lock()
+	p.provisioningClaims[orchestratorClaimName] = claim
+	p.claimsMutex.Unlock()
 	pv, err := p.createVolumeAndPV(orchestratorClaimName, claim)
+	p.claimsMutex.Lock()
+	delete(p.provisioningClaims, orchestratorClaimName)
+	p.claimsMutex.Unlock()
 	if err != nil {
+		p.recordProvisioningFailure(claim, orchestratorClaimName, err)
 		if pv == nil {
 			p.updateClaimWithEvent(claim, v1.EventTypeNormal,
 				"ProvisioningFailed", err.Error())
@@ -501,6 +563,9 @@ func (p *KubernetesPlugin) processPendingClaim(claim *v1.PersistentVolumeClaim)
 		}
 		return
 	}
+	if retry != nil {
+		p.clearProvisioningRetry(orchestratorClaimName)
+	}
 	p.pendingClaimMatchMap[orchestratorClaimName] = pv
 	message := "Kubernetes frontend provisioned a volume and a PV for the PVC."
 	p.updateClaimWithEvent(claim, v1.EventTypeNormal,
@@ -512,20 +577,52 @@ func (p *KubernetesPlugin) processPendingClaim(claim *v1.PersistentVolumeClaim)
 	}).Info(message)
 }
 
+// recordProvisioningFailure records a failed attempt to provision a claim
+// with the orchestrator, which persists it and sets when to try again.
+func (p *KubernetesPlugin) recordProvisioningFailure(
+	claim *v1.PersistentVolumeClaim, volumeName string, err error,
+) {
+	retry, recordErr := p.orchestrator.RecordProvisioningFailure(volumeName,
+		err)
+	if recordErr != nil {
+		log.WithFields(log.Fields{
+			"PVC":    claim.Name,
+			"volume": volumeName,
+		}).Warnf("Kubernetes frontend couldn't record the failed "+
+			"provisioning attempt; will retry upon resync: %v", recordErr)
+		return
+	}
+	log.WithFields(log.Fields{
+		"PVC":         claim.Name,
+		"volume":      volumeName,
+		"attempts":    retry.Attempts,
+		"nextAttempt": retry.NextAttempt,
+	}).Info("Kubernetes frontend will retry provisioning after backing off.")
+}
+
+// clearProvisioningRetry forgets a claim's failed provisioning attempts.
+func (p *KubernetesPlugin) clearProvisioningRetry(volumeName string) {
+	found, err := p.orchestrator.DeleteProvisioningRetry(volumeName)
+	if err != nil && found {
+		log.WithFields(log.Fields{
+			"volume": volumeName,
+		}).Warnf("Kubernetes frontend couldn't clear the failed "+
+			"provisioning attempts: %v", err)
+	}
+}
+
 func (p *KubernetesPlugin) createVolumeAndPV(uniqueName string,
 	claim *v1.PersistentVolumeClaim,
 ) (pv *v1.PersistentVolume, err error) {
-	var (
-		nfsSource   *v1.NFSVolumeSource
-		iscsiSource *v1.ISCSIVolumeSource
-		vol         *storage.VolumeExternal
-	)
+	var vol *storage.VolumeExternal
 
 	defer func() {
 		if vol != nil && err != nil {
 			err1 := err
-			// Delete the volume on the backend
-			_, err = p.orchestrator.DeleteVolume(vol.Config.Name)
+			// Delete the volume on the backend, even if the plugin is
+			// being deactivated.
+			_, err = p.orchestrator.DeleteVolume(context.Background(),
+				vol.Config.Name)
 			if err != nil {
 				err2 := "Kubernetes frontend couldn't delete the volume " +
 					"after failed creation: " + err.Error()
@@ -545,8 +642,28 @@ func (p *KubernetesPlugin) createVolumeAndPV(uniqueName string,
 	annotations := claim.Annotations
 
 	// TODO: log volume creation in etcd
-	vol, err = p.orchestrator.AddVolume(
-		getVolumeConfig(accessModes, uniqueName, size, annotations))
+	volConfig := getVolumeConfig(accessModes, uniqueName, size, annotations)
+	volConfig.Namespace = claim.Namespace
+	// The core enforces the namespace's policy, which the namespace's
+	// annotations may have changed.
+	if _, err = p.syncNamespacePolicy(claim.Namespace); err != nil {
+		log.WithFields(log.Fields{
+			"volume": uniqueName,
+		}).Warnf("Kubernetes frontend couldn't apply the namespace's "+
+			"policy: %s (will retry upon resync)", err.Error())
+		return
+	}
+	if err = p.resolveCloneSource(claim, volConfig); err != nil {
+		log.WithFields(log.Fields{
+			"volume": uniqueName,
+		}).Warnf("Kubernetes frontend couldn't clone a volume: %s "+
+			"(will retry upon resync)", err.Error())
+		return
+	}
+	// Use the claim's UID as the request ID, so that a resync that retries a
+	// creation that timed out gets the volume instead of an error.
+	volConfig.RequestID = string(claim.UID)
+	result, err := p.orchestrator.AddVolume(p.ctx, volConfig)
 	if err != nil {
 		log.WithFields(log.Fields{
 			"volume": uniqueName,
@@ -554,6 +671,11 @@ func (p *KubernetesPlugin) createVolumeAndPV(uniqueName string,
 			"(will retry upon resync)", err.Error())
 		return
 	}
+	vol = result.VolumeExternal
+	for _, warning := range result.Warnings {
+		p.updateClaimWithEvent(claim, v1.EventTypeWarning,
+			"ProvisioningWarning", warning)
+	}
 
 	claimRef := v1.ObjectReference{
 		Namespace: claim.Namespace,
@@ -587,34 +709,165 @@ func (p *KubernetesPlugin) createVolumeAndPV(uniqueName string,
 			v1.PersistentVolumeReclaimRetain
 	}
 
-	driverType := p.orchestrator.GetDriverTypeForVolume(vol)
-	switch {
-	case driverType == dvp.SolidfireSANStorageDriverName ||
-		driverType == dvp.OntapSANStorageDriverName ||
-		driverType == dvp.EseriesIscsiStorageDriverName:
-		iscsiSource = CreateISCSIVolumeSource(vol.Config)
-		pv.Spec.ISCSI = iscsiSource
-	case driverType == dvp.OntapNASStorageDriverName:
-		nfsSource = CreateNFSVolumeSource(vol.Config)
-		pv.Spec.NFS = nfsSource
-	default:
+	if err = p.setVolumeSource(pv, vol); err != nil {
 		// Unknown driver for the frontend plugin or for Kubernetes.
 		// Provisioned volume should get deleted.
 		log.WithFields(log.Fields{
 			"volume": vol.Config.Name,
 			"type":   p.orchestrator.GetVolumeType(vol),
-			"driver": driverType,
 		}).Warn("Kubernetes frontend doesn't recognize this type of volume; ",
 			"deleting the provisioned volume.")
-		err = fmt.Errorf("Unrecognized volume type by Kubernetes")
 		return
 	}
 	pv, err = p.kubeClient.Core().PersistentVolumes().Create(pv)
 	return
 }
 
+// setVolumeSource sets the NFS or iSCSI source of a PV for vol, according
+// to the driver of vol's backend, and the volume's mount options.  Volumes of
+// registered drivers get a source according to their protocol.
+func (p *KubernetesPlugin) setVolumeSource(
+	pv *v1.PersistentVolume, vol *storage.VolumeExternal,
+) error {
+	if vol.Config.MountOptions != "" {
+		pv.Annotations[AnnPVMountOptions] = vol.Config.MountOptions
+	}
+	driverType := p.orchestrator.GetDriverTypeForVolume(vol)
+	switch {
+	case vol.AccessInfo != nil && vol.AccessInfo.Protocol == storage.AccessNVMe:
+		return fmt.Errorf("Kubernetes doesn't support NVMe volumes.")
+	case driverType == dvp.SolidfireSANStorageDriverName ||
+		driverType == dvp.OntapSANStorageDriverName ||
+		driverType == dvp.EseriesIscsiStorageDriverName:
+		pv.Spec.ISCSI = CreateISCSIVolumeSource(vol.Config)
+	case driverType == dvp.OntapNASStorageDriverName:
+		pv.Spec.NFS = CreateNFSVolumeSource(vol.Config)
+	case vol.AccessInfo == nil:
+		return fmt.Errorf("Volume %s has no access information.",
+			vol.Config.Name)
+	case vol.AccessInfo.Protocol == storage.AccessISCSI:
+		pv.Spec.ISCSI = CreateISCSIVolumeSource(vol.Config)
+	case vol.AccessInfo.Protocol == storage.AccessNFS:
+		pv.Spec.NFS = CreateNFSVolumeSource(vol.Config)
+	default:
+		return fmt.Errorf("Unrecognized volume type by Kubernetes")
+	}
+	return nil
+}
+
+// CreatePersistentVolume creates a PV for an existing Trident volume, e.g.,
+// an imported or retained one, so that it can be consumed by a claim.  The
+// PV is named after the volume and, if the request names a claim, is
+// pre-bound to it.  With DryRun set, the PV is returned without being
+// created.
+func (p *KubernetesPlugin) CreatePersistentVolume(
+	request *frontend.PersistentVolumeRequest,
+) (interface{}, error) {
+	vol := p.orchestrator.GetVolume(request.Volume)
+	if vol == nil {
+		return nil, errors.Errorf(errors.NotFound,
+			"Volume %s not found.", request.Volume)
+	}
+	pv, err := p.getStaticPV(vol, request)
+	if err != nil {
+		return nil, err
+	}
+	if request.DryRun {
+		return pv, nil
+	}
+	pv, err = p.kubeClient.Core().PersistentVolumes().Create(pv)
+	if err != nil {
+		if strings.HasSuffix(err.Error(), "already exists") {
+			return nil, errors.Errorf(errors.AlreadyExists,
+				"PV %s already exists.", vol.Config.Name)
+		}
+		return nil, fmt.Errorf("Kubernetes frontend failed to create the "+
+			"PV: %v", err)
+	}
+	log.WithFields(log.Fields{
+		"PV":    pv.Name,
+		"claim": request.ClaimName,
+	}).Info("Kubernetes frontend created a PV for an existing volume.")
+	return pv, nil
+}
+
+// getStaticPV returns a PV for an existing volume.  The PV is annotated as
+// provisioned by Trident, so that Trident deletes the volume when a PV with
+// the Delete reclaim policy is released; the policy defaults to Retain.
+func (p *KubernetesPlugin) getStaticPV(
+	vol *storage.VolumeExternal, request *frontend.PersistentVolumeRequest,
+) (*v1.PersistentVolume, error) {
+	size, err := resource.ParseQuantity(vol.Config.Size)
+	if err != nil {
+		return nil, errors.Errorf(errors.InvalidInput,
+			"Volume %s has an invalid size %s:  %v", vol.Config.Name,
+			vol.Config.Size, err)
+	}
+	class := request.StorageClass
+	if class == "" {
+		class = vol.Config.StorageClass
+	}
+	pv := &v1.PersistentVolume{
+		TypeMeta: unversioned.TypeMeta{
+			Kind:       "PersistentVolume",
+			APIVersion: "v1",
+		},
+		ObjectMeta: v1.ObjectMeta{
+			Name: vol.Config.Name,
+			Annotations: map[string]string{
+				AnnClass:                  class,
+				AnnDynamicallyProvisioned: AnnProvisioner,
+			},
+		},
+		Spec: v1.PersistentVolumeSpec{
+			Capacity: v1.ResourceList{v1.ResourceStorage: size},
+		},
+	}
+
+	switch request.ReclaimPolicy {
+	case "", string(v1.PersistentVolumeReclaimRetain):
+		pv.Spec.PersistentVolumeReclaimPolicy =
+			v1.PersistentVolumeReclaimRetain
+	case string(v1.PersistentVolumeReclaimDelete):
+		pv.Spec.PersistentVolumeReclaimPolicy =
+			v1.PersistentVolumeReclaimDelete
+	default:
+		return nil, errors.Errorf(errors.InvalidInput,
+			"Unsupported reclaim policy %s.", request.ReclaimPolicy)
+	}
+
+	if len(request.AccessModes) > 0 {
+		for _, mode := range request.AccessModes {
+			pv.Spec.AccessModes = append(pv.Spec.AccessModes,
+				v1.PersistentVolumeAccessMode(mode))
+		}
+	} else {
+		pv.Spec.AccessModes = getAccessModes(vol.Config.AccessMode)
+	}
+
+	if request.ClaimName != "" {
+		namespace := request.ClaimNamespace
+		if namespace == "" {
+			namespace = v1.NamespaceDefault
+		}
+		pv.Spec.ClaimRef = &v1.ObjectReference{
+			Kind:       "PersistentVolumeClaim",
+			APIVersion: "v1",
+			Namespace:  namespace,
+			Name:       request.ClaimName,
+		}
+	}
+
+	if err = p.setVolumeSource(pv, vol); err != nil {
+		return nil, errors.Errorf(errors.InvalidInput,
+			"Volume %s can't be used by Kubernetes:  %v", vol.Config.Name,
+			err)
+	}
+	return pv, nil
+}
+
 func (p *KubernetesPlugin) deleteVolumeAndPV(volume *v1.PersistentVolume) error {
-	found, err := p.orchestrator.DeleteVolume(volume.GetName())
+	found, err := p.orchestrator.DeleteVolume(p.ctx, volume.GetName())
 	if found && err != nil {
 		message := fmt.Sprintf(
 			"Kubernetes frontend failed to delete the volume "+
@@ -745,7 +998,7 @@ func (p *KubernetesPlugin) processUpdatedVolume(volume *v1.PersistentVolume) {
 		if volume.Spec.PersistentVolumeReclaimPolicy != v1.PersistentVolumeReclaimDelete {
 			return
 		}
-		found, err := p.orchestrator.DeleteVolume(volume.Name)
+		found, err := p.orchestrator.DeleteVolume(p.ctx, volume.Name)
 		if found && err != nil {
 			// Updating the PV's phase to "VolumeFailed", so that
 			// a storage admin can take action.
@@ -842,6 +1095,29 @@ func (p *KubernetesPlugin) updateClaimWithEvent(
 	return claim, nil
 }
 
+// OnProvisioningEvent records the orchestrator's progress in provisioning a
+// volume as events on the volume's claim.  Failures and successes are
+// recorded by processPendingClaim, since they also cover creating the PV.
+func (p *KubernetesPlugin) OnProvisioningEvent(event core.ProvisioningEvent) {
+	p.claimsMutex.Lock()
+	claim, ok := p.provisioningClaims[event.Volume]
+	p.claimsMutex.Unlock()
+	if !ok {
+		return
+	}
+	switch event.Type {
+	case core.ProvisioningStarted:
+		p.updateClaimWithEvent(claim, v1.EventTypeNormal,
+			"Provisioning", event.Message)
+	case core.ProvisioningPoolSelected:
+		p.updateClaimWithEvent(claim, v1.EventTypeNormal,
+			"BackendSelected", event.Message)
+	case core.ProvisioningPoolFailed:
+		p.updateClaimWithEvent(claim, v1.EventTypeWarning,
+			"BackendFailed", event.Message)
+	}
+}
+
 func (p *KubernetesPlugin) addClass(obj interface{}) {
 	class, ok := obj.(*k8s_storage.StorageClass)
 	if !ok {
@@ -915,6 +1191,21 @@ func (p *KubernetesPlugin) processAddedClass(class *k8s_storage.StorageClass) {
 			scConfig.BackendStoragePools = backendVCs
 			continue
 		}
+		if k == storage_attribute.Selector {
+			// format:     selector: "label1=value1,label2=value2"
+			selector, err := storage_attribute.CreateSelectorFromEncodedString(v)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"StorageClass":             class.Name,
+					"StorageClass_provisioner": class.Provisioner,
+					"StorageClass_parameters":  class.Parameters,
+				}).Error("Kubernetes frontend couldn't process the selector "+
+					"parameter: ", err)
+				return
+			}
+			scConfig.Selector = selector
+			continue
+		}
 		// format:     attribute: "type:value"
 		req, err := storage_attribute.CreateAttributeRequestFromTypedValue(k, v)
 		if err != nil {
@@ -944,12 +1235,24 @@ func (p *KubernetesPlugin) processAddedClass(class *k8s_storage.StorageClass) {
 			"StorageClass_provisioner": class.Provisioner,
 			"StorageClass_parameters":  class.Parameters,
 		}).Info("Kubernetes frontend successfully added the StorageClass.")
+		for _, warning := range sc.Warnings {
+			log.WithFields(log.Fields{
+				"StorageClass": class.Name,
+			}).Warn(warning)
+		}
 	}
 	return
 }
 
 func (p *KubernetesPlugin) processDeletedClass(class *k8s_storage.StorageClass) {
 	deleted, err := p.orchestrator.DeleteStorageClass(class.Name)
+	if errors.GetType(err) == errors.Conflict {
+		log.WithFields(log.Fields{
+			"StorageClass": class.Name,
+		}).Info("Kubernetes frontend left the StorageClass pending deletion: ",
+			err)
+		return
+	}
 	if err != nil {
 		log.WithFields(log.Fields{
 			"StorageClass": class.Name,
@@ -5,10 +5,12 @@ package kubernetes
 import (
 	"fmt"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
 
 	"k8s.io/client-go/kubernetes/fake"
 	core_v1 "k8s.io/client-go/kubernetes/typed/core/v1"
@@ -17,13 +19,17 @@ import (
 	"k8s.io/client-go/pkg/api/v1"
 	k8s_storage "k8s.io/client-go/pkg/apis/storage/v1beta1"
 	"k8s.io/client-go/pkg/conversion"
+	"k8s.io/client-go/pkg/runtime"
 	"k8s.io/client-go/pkg/types"
 	"k8s.io/client-go/pkg/util/diff"
+	k8s_testing "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 
 	"github.com/netapp/trident/config"
 	"github.com/netapp/trident/core"
+	"github.com/netapp/trident/errors"
+	"github.com/netapp/trident/frontend"
 	"github.com/netapp/trident/frontend/kubernetes/framework"
 	"github.com/netapp/trident/storage"
 	sa "github.com/netapp/trident/storage_attribute"
@@ -180,7 +186,10 @@ func newTestPlugin(
 		volumeControllerStopChan: make(chan struct{}),
 		classControllerStopChan:  make(chan struct{}),
 		pendingClaimMatchMap:     make(map[string]*v1.PersistentVolume),
+		provisioningClaims:       make(map[string]*v1.PersistentVolumeClaim),
+		claimsMutex:              &sync.Mutex{},
 	}
+	ret.ctx, ret.cancel = context.WithCancel(context.Background())
 	ret.claimSource = claimSource
 	_, ret.claimController = cache.NewInformer(
 		ret.claimSource,
@@ -879,3 +888,274 @@ func TestStorageClassController(t *testing.T) {
 		}
 	}
 }
+
+func TestOnProvisioningEvent(t *testing.T) {
+	claim := &v1.PersistentVolumeClaim{
+		ObjectMeta: v1.ObjectMeta{Name: "claim", Namespace: testNamespace},
+	}
+	recorder := record.NewFakeRecorder(10)
+	p := &KubernetesPlugin{
+		eventRecorder: recorder,
+		provisioningClaims: map[string]*v1.PersistentVolumeClaim{
+			"vol": claim,
+		},
+		claimsMutex: &sync.Mutex{},
+	}
+	for _, test := range []struct {
+		event    core.ProvisioningEvent
+		expected string
+	}{
+		{core.ProvisioningEvent{Type: core.ProvisioningStarted,
+			Volume: "vol", Message: "Started."},
+			"Normal Provisioning Started."},
+		{core.ProvisioningEvent{Type: core.ProvisioningPoolSelected,
+			Volume: "vol", Message: "Selected."},
+			"Normal BackendSelected Selected."},
+		{core.ProvisioningEvent{Type: core.ProvisioningPoolFailed,
+			Volume: "vol", Message: "Failed."},
+			"Warning BackendFailed Failed."},
+	} {
+		p.OnProvisioningEvent(test.event)
+		select {
+		case event := <-recorder.Events:
+			if event != test.expected {
+				t.Errorf("Expected event %q; got %q.", test.expected, event)
+			}
+		default:
+			t.Errorf("No event recorded for %s.", test.event.Type)
+		}
+	}
+
+	// Outcomes are recorded by processPendingClaim, and volumes that aren't
+	// for claims are ignored.
+	p.OnProvisioningEvent(core.ProvisioningEvent{
+		Type: core.ProvisioningFailed, Volume: "vol"})
+	p.OnProvisioningEvent(core.ProvisioningEvent{
+		Type: core.ProvisioningStarted, Volume: "other"})
+	select {
+	case event := <-recorder.Events:
+		t.Errorf("Unexpected event recorded:  %q", event)
+	default:
+	}
+}
+
+func TestGetStaticPV(t *testing.T) {
+	orchestrator := core.NewMockOrchestrator()
+	orchestrator.AddMockONTAPNFSBackend("nfs", testNFSServer)
+	if _, err := orchestrator.AddStorageClass(&sc.Config{
+		Name: "silver"}); err != nil {
+		t.Fatal("Unable to add storage class:  ", err)
+	}
+	vol, err := orchestrator.AddVolume(context.Background(),
+		&storage.VolumeConfig{
+			Name:         "staticVol",
+			Size:         "1073741824",
+			StorageClass: "silver",
+			AccessMode:   config.ReadWriteMany,
+			Protocol:     config.File,
+			MountOptions: "nfsvers=4.1",
+		})
+	if err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+	p := &KubernetesPlugin{orchestrator: orchestrator}
+	external := vol.VolumeExternal
+
+	pv, err := p.getStaticPV(external, &frontend.PersistentVolumeRequest{
+		Volume:    "staticVol",
+		ClaimName: "claim",
+	})
+	if err != nil {
+		t.Fatal("Unable to get PV:  ", err)
+	}
+	if pv.Name != "staticVol" ||
+		pv.Annotations[AnnClass] != "silver" ||
+		pv.Annotations[AnnDynamicallyProvisioned] != AnnProvisioner ||
+		pv.Annotations[AnnPVMountOptions] != "nfsvers=4.1" {
+		t.Errorf("Unexpected PV metadata:  %v", pv.ObjectMeta)
+	}
+	size := pv.Spec.Capacity[v1.ResourceStorage]
+	if size.Value() != 1073741824 {
+		t.Errorf("Expected a 1GiB PV; got %s.", size.String())
+	}
+	if !reflect.DeepEqual(pv.Spec.AccessModes,
+		[]v1.PersistentVolumeAccessMode{v1.ReadWriteMany}) {
+		t.Errorf("Unexpected access modes:  %v", pv.Spec.AccessModes)
+	}
+	if pv.Spec.PersistentVolumeReclaimPolicy !=
+		v1.PersistentVolumeReclaimRetain {
+		t.Errorf("Expected the Retain reclaim policy; got %s.",
+			pv.Spec.PersistentVolumeReclaimPolicy)
+	}
+	if pv.Spec.ClaimRef == nil || pv.Spec.ClaimRef.Name != "claim" ||
+		pv.Spec.ClaimRef.Namespace != v1.NamespaceDefault {
+		t.Errorf("Unexpected claim reference:  %v", pv.Spec.ClaimRef)
+	}
+	if pv.Spec.NFS == nil || pv.Spec.NFS.Server != testNFSServer ||
+		pv.Spec.NFS.Path != vol.Config.AccessInfo.NfsPath {
+		t.Errorf("Unexpected NFS source:  %v", pv.Spec.NFS)
+	}
+
+	pv, err = p.getStaticPV(external, &frontend.PersistentVolumeRequest{
+		Volume:        "staticVol",
+		ReclaimPolicy: "Delete",
+		AccessModes:   []string{"ReadOnlyMany"},
+	})
+	if err != nil {
+		t.Fatal("Unable to get PV:  ", err)
+	}
+	if pv.Spec.ClaimRef != nil ||
+		pv.Spec.PersistentVolumeReclaimPolicy !=
+			v1.PersistentVolumeReclaimDelete ||
+		!reflect.DeepEqual(pv.Spec.AccessModes,
+			[]v1.PersistentVolumeAccessMode{v1.ReadOnlyMany}) {
+		t.Errorf("PV does not reflect the request:  %v", pv.Spec)
+	}
+	if _, err = p.getStaticPV(external, &frontend.PersistentVolumeRequest{
+		Volume:        "staticVol",
+		ReclaimPolicy: "Recycle",
+	}); errors.GetType(err) != errors.InvalidInput {
+		t.Errorf("Expected InvalidInput for the Recycle policy; got %v.",
+			err)
+	}
+	if _, err = p.CreatePersistentVolume(&frontend.PersistentVolumeRequest{
+		Volume: "missing",
+	}); !errors.IsNotFound(err) {
+		t.Errorf("Expected NotFound for a missing volume; got %v.", err)
+	}
+}
+
+func TestResolveCloneSource(t *testing.T) {
+	source := &v1.PersistentVolumeClaim{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "data",
+			Namespace: testNamespace,
+			UID:       "f0a1b2c3-d4e5",
+		},
+		Status: v1.PersistentVolumeClaimStatus{Phase: v1.ClaimBound},
+	}
+	volName := getUniqueClaimName(source)
+	source.Spec.VolumeName = volName
+
+	orchestrator := core.NewMockOrchestrator()
+	orchestrator.AddMockONTAPNFSBackend("nfs", testNFSServer)
+	if _, err := orchestrator.AddStorageClass(&sc.Config{
+		Name: "silver"}); err != nil {
+		t.Fatal("Unable to add storage class:  ", err)
+	}
+	if _, err := orchestrator.AddVolume(context.Background(),
+		&storage.VolumeConfig{
+			Name:         volName,
+			Size:         "1073741824",
+			StorageClass: "silver",
+			Protocol:     config.File,
+		}); err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+	client := &fake.Clientset{}
+	client.AddReactor("get", "persistentvolumeclaims",
+		func(action k8s_testing.Action) (bool, runtime.Object, error) {
+			name := action.(k8s_testing.GetAction).GetName()
+			if action.GetNamespace() != source.Namespace ||
+				name != source.Name {
+				return true, nil, fmt.Errorf("PVC %s not found.", name)
+			}
+			return true, source, nil
+		})
+	p := &KubernetesPlugin{orchestrator: orchestrator, kubeClient: client}
+
+	clone := func(annotations map[string]string) (
+		*storage.VolumeConfig, error,
+	) {
+		claim := &v1.PersistentVolumeClaim{
+			ObjectMeta: v1.ObjectMeta{
+				Name:        "copy",
+				Namespace:   testNamespace,
+				Annotations: annotations,
+			},
+		}
+		volConfig := getVolumeConfig(nil, "copy", resource.Quantity{},
+			annotations)
+		return volConfig, p.resolveCloneSource(claim, volConfig)
+	}
+	volConfig, err := clone(map[string]string{
+		AnnCloneFromPVC:      "data",
+		AnnCloneFromSnapshot: "nightly",
+	})
+	if err != nil {
+		t.Fatal("Unable to resolve clone source:  ", err)
+	}
+	if volConfig.SourceVolume != volName ||
+		volConfig.SourceSnapshot != "nightly" {
+		t.Errorf("Expected to clone %s@nightly; got %s@%s.", volName,
+			volConfig.SourceVolume, volConfig.SourceSnapshot)
+	}
+
+	for _, annotations := range []map[string]string{
+		{AnnCloneFromPVC: "data"},
+		{AnnCloneFromPVC: "other/data", AnnCloneFromSnapshot: "nightly"},
+		{AnnCloneFromPVC: "missing", AnnCloneFromSnapshot: "nightly"},
+		{
+			AnnCloneFromPVC:      "data",
+			AnnCloneFromSnapshot: "nightly",
+			AnnSourceVolume:      "vol",
+		},
+	} {
+		if _, err := clone(annotations); err == nil {
+			t.Errorf("Clone with annotations %v was accepted.", annotations)
+		}
+	}
+
+	source.Spec.VolumeName = "someone-elses-volume"
+	if _, err := clone(map[string]string{
+		AnnCloneFromPVC:      "data",
+		AnnCloneFromSnapshot: "nightly",
+	}); err == nil {
+		t.Error("PVC bound to another volume was cloned.")
+	}
+}
+
+func TestSyncNamespacePolicy(t *testing.T) {
+	namespace := &v1.Namespace{
+		ObjectMeta: v1.ObjectMeta{
+			Name: testNamespace,
+			Annotations: map[string]string{
+				AnnAllowedStorageClasses: "gold, silver",
+				AnnDefaultStorageClass:   "silver",
+			},
+		},
+	}
+	client := &fake.Clientset{}
+	client.AddReactor("get", "namespaces",
+		func(action k8s_testing.Action) (bool, runtime.Object, error) {
+			return true, namespace, nil
+		})
+	orchestrator := core.NewMockOrchestrator()
+	p := &KubernetesPlugin{orchestrator: orchestrator, kubeClient: client}
+
+	policy, err := p.syncNamespacePolicy(testNamespace)
+	if err != nil {
+		t.Fatal("Unable to sync namespace policy:  ", err)
+	}
+	expected := []string{"gold", "silver"}
+	if policy == nil || policy.DefaultStorageClass != "silver" ||
+		!reflect.DeepEqual(policy.AllowedStorageClasses, expected) {
+		t.Fatalf("Unexpected namespace policy %v.", policy)
+	}
+	if orchestrator.GetNamespacePolicy(testNamespace) == nil {
+		t.Error("Namespace policy not set in the orchestrator.")
+	}
+
+	namespace.Annotations[AnnDefaultStorageClass] = "bronze"
+	if _, err = p.syncNamespacePolicy(testNamespace); err == nil {
+		t.Error("Invalid namespace policy annotations were accepted.")
+	}
+
+	// Without annotations, the orchestrator's policy stands.
+	namespace.Annotations = nil
+	if policy, err = p.syncNamespacePolicy(testNamespace); err != nil ||
+		policy == nil || policy.DefaultStorageClass != "silver" {
+		t.Errorf("Expected the orchestrator's policy; got %v, %v.", policy,
+			err)
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// secretResolver reads backend credentials from Kubernetes Secrets, named
+// as <namespace>/<name>.
+type secretResolver struct {
+	kubeClient kubernetes.Interface
+}
+
+func (r *secretResolver) Resolve(name string) (map[string]string, error) {
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("Secret %q must be named as "+
+			"<namespace>/<name>.", name)
+	}
+	secret, err := r.kubeClient.Core().Secrets(parts[0]).Get(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(secret.Data))
+	for key, value := range secret.Data {
+		values[key] = string(value)
+	}
+	return values, nil
+}
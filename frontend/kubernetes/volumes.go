@@ -4,6 +4,8 @@ package kubernetes
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	log "github.com/Sirupsen/logrus"
 	"k8s.io/client-go/pkg/api/resource"
@@ -61,6 +63,57 @@ func getAnnotation(annotations map[string]string, key string) string {
 	return ""
 }
 
+// getIntAnnotation returns the integer value of an annotation, or zero if
+// it is missing or malformed.
+func getIntAnnotation(annotations map[string]string, key string) int {
+	val := getAnnotation(annotations, key)
+	if val == "" {
+		return 0
+	}
+	i, err := strconv.Atoi(val)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"annotation": key,
+			"value":      val,
+		}).Warn("Kubernetes frontend ignored a non-integer annotation.")
+		return 0
+	}
+	return i
+}
+
+// getBoolAnnotation returns the boolean value of an annotation, or false if
+// it is missing or malformed.
+func getBoolAnnotation(annotations map[string]string, key string) bool {
+	val := getAnnotation(annotations, key)
+	if val == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"annotation": key,
+			"value":      val,
+		}).Warn("Kubernetes frontend ignored a non-boolean annotation.")
+		return false
+	}
+	return b
+}
+
+// getListAnnotation returns the comma-separated values of an annotation,
+// or nil if it is missing.
+func getListAnnotation(annotations map[string]string, key string) []string {
+	ret := make([]string, 0)
+	for _, val := range strings.Split(getAnnotation(annotations, key), ",") {
+		if val = strings.TrimSpace(val); val != "" {
+			ret = append(ret, val)
+		}
+	}
+	if len(ret) == 0 {
+		return nil
+	}
+	return ret
+}
+
 // getVolumeConfig generates a NetApp DVP volume config from the specs pulled
 // from the PVC.
 func getVolumeConfig(
@@ -78,31 +131,68 @@ func getVolumeConfig(
 		accessMode = config.AccessMode(accessModes[0])
 	}
 	return &storage.VolumeConfig{
-		Name:            name,
-		Size:            fmt.Sprintf("%d", size.Value()),
-		Protocol:        config.Protocol(getAnnotation(annotations, AnnProtocol)),
-		SnapshotPolicy:  getAnnotation(annotations, AnnSnapshotPolicy),
-		ExportPolicy:    getAnnotation(annotations, AnnExportPolicy),
-		SnapshotDir:     getAnnotation(annotations, AnnSnapshotDir),
-		UnixPermissions: getAnnotation(annotations, AnnUnixPermissions),
-		StorageClass:    getAnnotation(annotations, AnnClass),
-		AccessMode:      accessMode,
+		Name:             name,
+		Size:             fmt.Sprintf("%d", size.Value()),
+		Protocol:         config.Protocol(getAnnotation(annotations, AnnProtocol)),
+		SnapshotPolicy:   getAnnotation(annotations, AnnSnapshotPolicy),
+		ExportPolicy:     getAnnotation(annotations, AnnExportPolicy),
+		SnapshotDir:      getAnnotation(annotations, AnnSnapshotDir),
+		SnapshotReserve:  getAnnotation(annotations, AnnSnapshotReserve),
+		UnixPermissions:  getAnnotation(annotations, AnnUnixPermissions),
+		StorageClass:     getAnnotation(annotations, AnnClass),
+		AccessMode:       accessMode,
+		MinIOPS:          getIntAnnotation(annotations, AnnMinIOPS),
+		MaxIOPS:          getIntAnnotation(annotations, AnnMaxIOPS),
+		BurstIOPS:        getIntAnnotation(annotations, AnnBurstIOPS),
+		Encryption:       getBoolAnnotation(annotations, AnnEncryption),
+		ProvisioningType: getAnnotation(annotations, AnnProvisioningType),
+		Deduplication:    getAnnotation(annotations, AnnDeduplication),
+		Compression:      getAnnotation(annotations, AnnCompression),
+		SnapshotSchedule: getAnnotation(annotations, AnnSnapshotSchedule),
+		SourceVolume:     getAnnotation(annotations, AnnSourceVolume),
+		SourceSnapshot:   getAnnotation(annotations, AnnSourceSnapshot),
+		FSType:           getAnnotation(annotations, AnnFSType),
+		MountOptions:     getAnnotation(annotations, AnnMountOptions),
+		AntiAffinity:     getListAnnotation(annotations, AnnAntiAffinity),
+		SpreadGroup:      getAnnotation(annotations, AnnSpreadGroup),
+		ReadOnly:         getBoolAnnotation(annotations, AnnReadOnly),
+	}
+}
+
+// getAccessModes returns the PV access modes for a Trident access mode;
+// volumes with no access mode are ReadWriteOnce.
+func getAccessModes(
+	accessMode config.AccessMode,
+) []v1.PersistentVolumeAccessMode {
+	switch accessMode {
+	case config.ReadWriteMany:
+		return []v1.PersistentVolumeAccessMode{v1.ReadWriteMany}
+	case config.ReadOnlyMany:
+		return []v1.PersistentVolumeAccessMode{v1.ReadOnlyMany}
+	default:
+		return []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce}
 	}
 }
 
 func CreateNFSVolumeSource(volConfig *storage.VolumeConfig) *v1.NFSVolumeSource {
 	return &v1.NFSVolumeSource{
-		Server: volConfig.AccessInfo.NfsServerIP,
-		Path:   volConfig.AccessInfo.NfsPath,
+		Server:   volConfig.AccessInfo.NfsServerIP,
+		Path:     volConfig.AccessInfo.NfsPath,
+		ReadOnly: volConfig.ReadOnly,
 	}
 }
 
 func CreateISCSIVolumeSource(volConfig *storage.VolumeConfig) *v1.ISCSIVolumeSource {
+	fsType := volConfig.FSType
+	if fsType == "" {
+		fsType = config.DefaultFSType
+	}
 	return &v1.ISCSIVolumeSource{
 		TargetPortal:   volConfig.AccessInfo.IscsiTargetPortal,
 		IQN:            volConfig.AccessInfo.IscsiTargetIQN,
 		Lun:            volConfig.AccessInfo.IscsiLunNumber,
 		ISCSIInterface: volConfig.AccessInfo.IscsiInterface,
-		FSType:         "ext4",
+		FSType:         fsType,
+		ReadOnly:       volConfig.ReadOnly,
 	}
 }
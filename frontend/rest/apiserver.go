@@ -3,7 +3,9 @@
 package rest
 
 import (
+	"crypto/tls"
 	"log"
+	"net"
 	"net/http"
 	"time"
 
@@ -22,12 +24,15 @@ func init() {
 var orchestrator core.Orchestrator
 
 type APIServer struct {
-	router *mux.Router
-	port   string
-	server *graceful.Server
+	router   *mux.Router
+	port     string
+	server   *graceful.Server
+	reloader *certReloader
 }
 
-func NewAPIServer(p core.Orchestrator, port string) *APIServer {
+// NewAPIServer returns an API server listening on the given address and
+// port; an empty address listens on all interfaces.
+func NewAPIServer(p core.Orchestrator, address, port string) *APIServer {
 	orchestrator = p
 	router := NewRouter()
 	return &APIServer{
@@ -36,16 +41,58 @@ func NewAPIServer(p core.Orchestrator, port string) *APIServer {
 		server: &graceful.Server{
 			Timeout: httpTimeout,
 			Server: &http.Server{
-				Addr:    ":" + port,
+				Addr:    net.JoinHostPort(address, port),
 				Handler: router,
 			},
 		},
 	}
 }
 
+// NewTLSAPIServer returns an API server that serves HTTPS using the given
+// certificate and key, which are reloaded on SIGHUP or when either file
+// changes.  If clientCAFile is non-empty, client certificates are required
+// and verified against it.
+func NewTLSAPIServer(
+	p core.Orchestrator, address, port, certFile, keyFile, clientCAFile string,
+) (*APIServer, error) {
+	reloader, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := newTLSConfig(reloader, clientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	server := NewAPIServer(p, address, port)
+	server.reloader = reloader
+	server.server.TLSConfig = tlsConfig
+	return server, nil
+}
+
+// SetRateLimits throttles requests according to rateConfig.  It must be
+// called before Activate.
+func (server *APIServer) SetRateLimits(rateConfig *RateLimitConfig) {
+	server.server.Handler = newRateLimiter(server.router, rateConfig)
+}
+
 func (server *APIServer) Activate() error {
+	if server.reloader == nil {
+		go func() {
+			err := server.server.ListenAndServe()
+			if err != nil {
+				log.Fatal(err)
+			}
+		}()
+		return nil
+	}
+	go server.reloader.watch()
 	go func() {
-		err := server.server.ListenAndServe()
+		listener, err := net.Listen("tcp", server.server.Addr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = server.server.Serve(
+			tls.NewListener(listener, server.server.TLSConfig))
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -55,6 +102,9 @@ func (server *APIServer) Activate() error {
 
 func (server *APIServer) Deactivate() error {
 	server.server.Stop(httpTimeout)
+	if server.reloader != nil {
+		server.reloader.Stop()
+	}
 	return nil
 }
 
@@ -0,0 +1,42 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package rest
+
+import (
+	"net/http"
+
+	log "github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+
+	"github.com/netapp/trident/tracing"
+)
+
+// requestContext returns a context that is canceled if the client
+// disconnects, so that the orchestrator abandons work nobody is waiting
+// for.  If the client sent B3 trace headers, the request's spans join its
+// trace.  The caller must call the returned function when the request is
+// done.
+func requestContext(
+	w http.ResponseWriter, r *http.Request,
+) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(tracing.ContextWithRemoteParent(
+		context.Background(), r.Header.Get("X-B3-TraceId"),
+		r.Header.Get("X-B3-SpanId")))
+	notifier, ok := w.(http.CloseNotifier)
+	if !ok {
+		return ctx, cancel
+	}
+	closed := notifier.CloseNotify()
+	go func() {
+		select {
+		case <-closed:
+			log.WithFields(log.Fields{
+				"method": r.Method,
+				"uri":    r.RequestURI,
+			}).Info("Client disconnected; canceling the request.")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
@@ -0,0 +1,74 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/netapp/trident/core"
+)
+
+// eventKeepAliveInterval is how often a comment is written to idle event
+// streams so that proxies don't time them out.
+const eventKeepAliveInterval = 30 * time.Second
+
+// GetEvents streams orchestrator events to the client as server-sent
+// events until the client disconnects.  The optional "objectType" query
+// parameter (backend, volume, or storageClass) restricts the stream to one
+// kind of object.
+func GetEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported.",
+			http.StatusInternalServerError)
+		return
+	}
+	objectType := core.EventObjectType(r.URL.Query().Get("objectType"))
+
+	events := orchestrator.SubscribeEvents()
+	defer orchestrator.UnsubscribeEvents(events)
+
+	var closed <-chan bool
+	if notifier, ok := w.(http.CloseNotifier); ok {
+		closed = notifier.CloseNotify()
+	}
+	keepAlive := time.NewTicker(eventKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if objectType != "" && event.ObjectType != objectType {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"handler": "GetEvents",
+					"name":    event.Name,
+				}).Errorf("Unable to marshal event:  %v", err)
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// fieldsAlwaysSelected are the top-level response fields returned whatever
+// fields are selected, so that errors and pagination aren't lost.
+var fieldsAlwaysSelected = []string{"error", "pagination"}
+
+// fieldSelection is a tree of the fields selected from a JSON value, by
+// name.  A field whose selection is nil is returned whole.
+type fieldSelection map[string]fieldSelection
+
+// parseFields parses the comma-separated dotted field paths of a "fields"
+// query parameter, e.g., "volumes.config.name,volumes.config.size".  Paths
+// name fields within each element of arrays they pass through.
+func parseFields(value string) (fieldSelection, error) {
+	selection := make(fieldSelection)
+	for _, path := range strings.Split(value, ",") {
+		path = strings.TrimSpace(path)
+		names := strings.Split(path, ".")
+		for _, name := range names {
+			if name == "" {
+				return nil, fmt.Errorf("Invalid field %q.", path)
+			}
+		}
+		node := selection
+		for i, name := range names {
+			child, ok := node[name]
+			if ok && child == nil {
+				// The field is already selected whole.
+				break
+			}
+			if i == len(names)-1 {
+				node[name] = nil
+				break
+			}
+			if !ok {
+				child = make(fieldSelection)
+				node[name] = child
+			}
+			node = child
+		}
+	}
+	return selection, nil
+}
+
+// apply returns the selected fields of a value decoded from JSON.  Arrays
+// have the selection applied to each element; other values are returned
+// as is.
+func (s fieldSelection) apply(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		selected := make(map[string]interface{})
+		for name, child := range s {
+			field, ok := value[name]
+			if !ok {
+				continue
+			}
+			if child == nil {
+				selected[name] = field
+			} else {
+				selected[name] = child.apply(field)
+			}
+		}
+		return selected
+	case []interface{}:
+		selected := make([]interface{}, 0, len(value))
+		for _, element := range value {
+			selected = append(selected, s.apply(element))
+		}
+		return selected
+	}
+	return v
+}
+
+var marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// selectFields returns the selected fields of v, a value to be written as
+// JSON, by their JSON names.  Structs and maps become maps of their
+// selected fields, and slices have the selection applied to each element,
+// so the fields that aren't selected are never encoded.  Values that encode
+// themselves, e.g., storage class configs, are encoded and decoded again
+// before their fields are selected.
+func selectFields(v interface{}, s fieldSelection) interface{} {
+	return s.selectValue(reflect.ValueOf(v))
+}
+
+func (s fieldSelection) selectValue(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+	}
+	if v.Type().Implements(marshalerType) || (v.CanAddr() &&
+		reflect.PtrTo(v.Type()).Implements(marshalerType)) {
+		return s.selectJSON(wholeField(v))
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return s.selectValue(v.Elem())
+	case reflect.Struct:
+		selected := make(map[string]interface{})
+		s.selectStruct(v, selected, false)
+		return selected
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return s.selectJSON(v.Interface())
+		}
+		selected := make(map[string]interface{})
+		for name, child := range s {
+			field := v.MapIndex(reflect.ValueOf(name).Convert(
+				v.Type().Key()))
+			if field.IsValid() {
+				selected[name] = child.selectField(field)
+			}
+		}
+		return selected
+	case reflect.Slice, reflect.Array:
+		// Byte slices are encoded as strings.
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+		selected := make([]interface{}, v.Len())
+		for i := range selected {
+			selected[i] = s.selectValue(v.Index(i))
+		}
+		return selected
+	}
+	return v.Interface()
+}
+
+// selectStruct adds the selected fields of a struct to selected, named and
+// omitted as encoding/json would.  The fields of embedded structs are
+// promoted, unless the outer struct has a field of the same name.
+func (s fieldSelection) selectStruct(
+	v reflect.Value, selected map[string]interface{}, promoted bool,
+) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options := tag, ""
+		if comma := strings.Index(tag, ","); comma >= 0 {
+			name, options = tag[:comma], tag[comma:]
+		}
+		field := v.Field(i)
+		if f.Anonymous && name == "" {
+			for field.Kind() == reflect.Ptr && !field.IsNil() {
+				field = field.Elem()
+			}
+			if field.Kind() == reflect.Struct {
+				s.selectStruct(field, selected, true)
+			}
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		child, ok := s[name]
+		if !ok || (strings.Contains(options, ",omitempty") &&
+			isEmptyValue(field)) {
+			continue
+		}
+		if _, exists := selected[name]; exists && promoted {
+			continue
+		}
+		selected[name] = child.selectField(field)
+	}
+}
+
+// selectField returns a field with its own selection applied, or whole if
+// it has none.
+func (s fieldSelection) selectField(v reflect.Value) interface{} {
+	if s == nil {
+		return wholeField(v)
+	}
+	return s.selectValue(v)
+}
+
+// selectJSON selects the fields of a value that encodes itself.
+func (s fieldSelection) selectJSON(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		// Leave the error to the response's encoder.
+		return v
+	}
+	var decoded interface{}
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		return v
+	}
+	return s.apply(decoded)
+}
+
+// wholeField returns a value to be encoded as is.  Fields whose pointers
+// encode themselves are returned as pointers, as encoding/json would
+// encode them within their struct.
+func wholeField(v reflect.Value) interface{} {
+	if v.Kind() != reflect.Ptr && v.CanAddr() &&
+		reflect.PtrTo(v.Type()).Implements(marshalerType) {
+		return v.Addr().Interface()
+	}
+	return v.Interface()
+}
+
+// isEmptyValue returns true if encoding/json omits v from a field tagged
+// omitempty.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// requestFields returns the fields selected by a GET request's "fields"
+// query parameter, including those always returned, or nil if the request
+// doesn't select any.
+func requestFields(r *http.Request) fieldSelection {
+	value := r.URL.Query().Get("fields")
+	if r.Method != "GET" || value == "" {
+		return nil
+	}
+	// The fields were checked before the handler was called.
+	selection, err := parseFields(value)
+	if err != nil {
+		return nil
+	}
+	for _, field := range fieldsAlwaysSelected {
+		selection[field] = nil
+	}
+	return selection
+}
+
+// encodeResponse writes a handler's JSON response, with only the fields
+// the request selects, if any.
+func encodeResponse(
+	w io.Writer, r *http.Request, response interface{},
+) error {
+	if selection := requestFields(r); selection != nil {
+		response = selectFields(response, selection)
+	}
+	return json.NewEncoder(w).Encode(response)
+}
+
+// fieldsSelectable returns true if a route's response fields can be
+// selected:  it must be a GET that returns JSON.
+func fieldsSelectable(route Route) bool {
+	body := routeBodies[route.Name]
+	return route.Method == "GET" && !body.Events && !body.Archive &&
+		route.Name != "GetAPIDocument"
+}
+
+// checkFields rejects requests whose "fields" query parameter can't be
+// parsed.  The handlers select the fields as they encode their responses;
+// see encodeResponse.
+func checkFields(inner http.Handler, name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if value := r.URL.Query().Get("fields"); value != "" {
+			if _, err := parseFields(value); err != nil {
+				rejectBadRequest(w, name, ErrorCodeInvalidInput,
+					err.Error())
+				return
+			}
+		}
+		inner.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,195 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseFields(t *testing.T) {
+	for _, test := range []struct {
+		value    string
+		expected fieldSelection
+	}{
+		{"name", fieldSelection{"name": nil}},
+		{"volume.config.name, volume.config.size", fieldSelection{
+			"volume": {"config": {"name": nil, "size": nil}},
+		}},
+		// Selecting a field whole overrides selecting its fields, in
+		// either order.
+		{"volume.config.name,volume", fieldSelection{"volume": nil}},
+		{"volume,volume.config.name", fieldSelection{"volume": nil}},
+		{"a.b,a.c.d", fieldSelection{"a": {"b": nil, "c": {"d": nil}}}},
+	} {
+		selection, err := parseFields(test.value)
+		if err != nil {
+			t.Errorf("Unable to parse %q:  %v", test.value, err)
+		} else if !reflect.DeepEqual(selection, test.expected) {
+			t.Errorf("Expected %v for %q; got %v.", test.expected,
+				test.value, selection)
+		}
+	}
+	for _, value := range []string{"a..b", ".a", "a.", "a,,b", " "} {
+		if _, err := parseFields(value); err == nil {
+			t.Errorf("Expected fields %q to be rejected.", value)
+		}
+	}
+}
+
+type testFieldsInner struct {
+	Name string            `json:"name"`
+	Size string            `json:"size,omitempty"`
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+type testFieldsEmbedded struct {
+	Owner string `json:"owner"`
+	Name  string `json:"name"`
+}
+
+type testFieldsMarshaler struct {
+	value string
+}
+
+func (m *testFieldsMarshaler) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{"value": m.value, "other": "x"})
+}
+
+type testFieldsOuter struct {
+	testFieldsEmbedded
+	Name      string               `json:"name"`
+	Config    *testFieldsInner     `json:"config"`
+	Items     []*testFieldsInner   `json:"items"`
+	Created   time.Time            `json:"created"`
+	Custom    *testFieldsMarshaler `json:"custom"`
+	Hidden    string               `json:"-"`
+	Untagged  int
+	unexposed string
+}
+
+// encodeSelected returns the JSON of v's selected fields, decoded again.
+func encodeSelected(
+	t *testing.T, v interface{}, fields string,
+) map[string]interface{} {
+	selection, err := parseFields(fields)
+	if err != nil {
+		t.Fatalf("Unable to parse %q:  %v", fields, err)
+	}
+	data, err := json.Marshal(selectFields(v, selection))
+	if err != nil {
+		t.Fatalf("Unable to encode fields %q:  %v", fields, err)
+	}
+	decoded := make(map[string]interface{})
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unable to decode fields %q:  %v", fields, err)
+	}
+	return decoded
+}
+
+func TestSelectFields(t *testing.T) {
+	created := time.Date(2016, 11, 1, 12, 0, 0, 0, time.UTC)
+	v := &testFieldsOuter{
+		testFieldsEmbedded: testFieldsEmbedded{Owner: "ops", Name: "inner"},
+		Name:               "outer",
+		Config: &testFieldsInner{Name: "vol1", Size: "1GiB",
+			Tags: map[string]string{"team": "storage", "tier": "1"}},
+		Items: []*testFieldsInner{
+			{Name: "a", Size: "1"}, {Name: "b"}, nil,
+		},
+		Created:   created,
+		Custom:    &testFieldsMarshaler{value: "v"},
+		Hidden:    "hidden",
+		Untagged:  3,
+		unexposed: "unexposed",
+	}
+	for _, test := range []struct {
+		fields   string
+		expected string
+	}{
+		{"config.name,config.size",
+			`{"config": {"name": "vol1", "size": "1GiB"}}`},
+		{"config.tags.team", `{"config": {"tags": {"team": "storage"}}}`},
+		// Lists have the selection applied to each element, and empty
+		// fields are omitted as they would be otherwise.
+		{"items.size", `{"items": [{"size": "1"}, {}, null]}`},
+		{"items", `{"items": [{"name": "a", "size": "1"}, {"name": "b"},
+			null]}`},
+		// Unknown fields, and fields that aren't encoded, are ignored.
+		{"config.unknown,unknown,Hidden,unexposed,config.name.first",
+			`{"config": {"name": "vol1"}}`},
+		// Embedded fields are promoted unless the outer struct has a field
+		// of the same name.
+		{"owner,name,Untagged",
+			`{"owner": "ops", "name": "outer", "Untagged": 3}`},
+		{"created", `{"created": "2016-11-01T12:00:00Z"}`},
+		{"custom.value", `{"custom": {"value": "v"}}`},
+	} {
+		expected := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(test.expected),
+			&expected); err != nil {
+			t.Fatalf("Invalid expected JSON for %q:  %v", test.fields, err)
+		}
+		if selected := encodeSelected(t, v, test.fields); !reflect.DeepEqual(
+			selected, expected) {
+			t.Errorf("Expected %v for %q; got %v.", expected, test.fields,
+				selected)
+		}
+	}
+
+	if selected := encodeSelected(t, &testFieldsOuter{},
+		"config.name"); !reflect.DeepEqual(selected,
+		map[string]interface{}{"config": nil}) {
+		t.Errorf("Expected a null config; got %v.", selected)
+	}
+}
+
+func TestEncodeResponse(t *testing.T) {
+	response := &ListResponseV2{
+		Items:      []string{"vol1"},
+		Pagination: &Pagination{Total: 1},
+		Error:      &ErrorV2{Code: ErrorCodeInvalidInput, Message: "Bad."},
+	}
+	for _, test := range []struct {
+		method   string
+		query    string
+		expected []string
+	}{
+		{"GET", "", []string{"items", "pagination", "error"}},
+		// Errors and pagination are always returned.
+		{"GET", "?fields=unknown", []string{"pagination", "error"}},
+		{"GET", "?fields=items", []string{"items", "pagination", "error"}},
+		// Only GET responses have their fields selected.
+		{"POST", "?fields=unknown", []string{"items", "pagination",
+			"error"}},
+	} {
+		r, err := http.NewRequest(test.method, "/trident/v2/volume"+
+			test.query, nil)
+		if err != nil {
+			t.Fatal("Unable to create request:  ", err)
+		}
+		var buf bytes.Buffer
+		if err = encodeResponse(&buf, r, response); err != nil {
+			t.Fatalf("Unable to encode response for %s %s:  %v",
+				test.method, test.query, err)
+		}
+		decoded := make(map[string]interface{})
+		if err = json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+			t.Fatal("Unable to decode response:  ", err)
+		}
+		if len(decoded) != len(test.expected) {
+			t.Errorf("Expected fields %v for %s %s; got %v.", test.expected,
+				test.method, test.query, decoded)
+		}
+		for _, field := range test.expected {
+			if _, ok := decoded[field]; !ok {
+				t.Errorf("Expected field %s for %s %s; got %v.", field,
+					test.method, test.query, decoded)
+			}
+		}
+	}
+}
@@ -8,15 +8,45 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"sort"
+	"strconv"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/gorilla/mux"
 
+	"github.com/netapp/trident/app_template"
 	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/core"
+	"github.com/netapp/trident/errors"
+	"github.com/netapp/trident/frontend"
+	"github.com/netapp/trident/hooks"
+	"github.com/netapp/trident/logging"
+	"github.com/netapp/trident/namespace_policy"
+	"github.com/netapp/trident/persistent_store"
+	"github.com/netapp/trident/snapshot_policy"
 	"github.com/netapp/trident/storage"
+	"github.com/netapp/trident/storage/factory"
+	sa "github.com/netapp/trident/storage_attribute"
 	"github.com/netapp/trident/storage_class"
 )
 
+// httpStatusForError returns the HTTP status that reports an error returned
+// by the orchestrator, or defaultStatus if the error has no type.
+func httpStatusForError(err error, defaultStatus int) int {
+	switch errors.GetType(err) {
+	case errors.NotFound:
+		return http.StatusNotFound
+	case errors.AlreadyExists, errors.Conflict:
+		return http.StatusConflict
+	case errors.InvalidInput:
+		return http.StatusBadRequest
+	case errors.BackendUnavailable:
+		return http.StatusServiceUnavailable
+	}
+	return defaultStatus
+}
+
 type listResponse interface {
 	setList([]string)
 }
@@ -31,7 +61,7 @@ func ListGeneric(
 	payload := lister()
 	response.setList(payload)
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+	if err := encodeResponse(w, r, response); err != nil {
 		panic(err)
 	}
 }
@@ -45,7 +75,7 @@ func GetGeneric(w http.ResponseWriter,
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 
 	defer func() {
-		if err := json.NewEncoder(w).Encode(response); err != nil {
+		if err := encodeResponse(w, r, response); err != nil {
 			panic(err)
 		}
 	}()
@@ -63,7 +93,7 @@ func GetGenericNoArg(w http.ResponseWriter,
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 
 	defer func() {
-		if err := json.NewEncoder(w).Encode(response); err != nil {
+		if err := encodeResponse(w, r, response); err != nil {
 			panic(err)
 		}
 	}()
@@ -82,15 +112,16 @@ func AddGeneric(
 	w http.ResponseWriter,
 	r *http.Request,
 	response addResponse,
-	add func([]byte),
+	add func([]byte) error,
 ) {
 	var err error = nil
+	status := http.StatusBadRequest
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 
 	defer func() {
 		if response.isError() {
 			response.logFailure()
-			w.WriteHeader(http.StatusBadRequest)
+			w.WriteHeader(status)
 		} else {
 			response.logSuccess()
 			w.WriteHeader(http.StatusCreated)
@@ -109,7 +140,10 @@ func AddGeneric(
 		response.setError(err)
 		return
 	}
-	add(body)
+	if err = add(body); err != nil {
+		response.setError(err)
+		status = httpStatusForError(err, http.StatusBadRequest)
+	}
 }
 
 type DeleteResponse struct {
@@ -133,11 +167,11 @@ func DeleteGeneric(
 	found, err := d(toDelete)
 	headerCode := http.StatusOK
 	if err != nil {
+		headerCode = http.StatusInternalServerError
 		if !found {
 			headerCode = http.StatusNotFound
-		} else {
-			headerCode = http.StatusInternalServerError
 		}
+		headerCode = httpStatusForError(err, headerCode)
 		response.Error = err.Error()
 	}
 	w.WriteHeader(headerCode)
@@ -148,7 +182,13 @@ func DeleteGeneric(
 
 type AddBackendResponse struct {
 	BackendID string `json:"backend"`
-	Error     string `json:"error,omitempty"`
+	// Warnings describe problems with the backend that didn't stop it from
+	// being added.
+	Warnings []string `json:"warnings,omitempty"`
+	// Backends holds the result of adding each backend when the request
+	// holds a list of configs.
+	Backends []*core.BackendItemResult `json:"backends,omitempty"`
+	Error    string                    `json:"error,omitempty"`
 }
 
 func (a *AddBackendResponse) setError(err error) {
@@ -160,6 +200,13 @@ func (a *AddBackendResponse) isError() bool {
 }
 
 func (a *AddBackendResponse) logSuccess() {
+	if len(a.Backends) > 0 {
+		log.WithFields(log.Fields{
+			"backends": len(a.Backends),
+			"handler":  "AddBackend",
+		}).Info("Added new backends.")
+		return
+	}
 	log.WithFields(log.Fields{
 		"backend": a.BackendID,
 		"handler": "AddBackend",
@@ -173,43 +220,226 @@ func (a *AddBackendResponse) logFailure() {
 	}).Error(a.Error)
 }
 
+// GetVersionResponse describes the orchestrator's build, drivers, and
+// frontends; its version field is the orchestrator's version, as before.
 type GetVersionResponse struct {
-	Version string `json:"version"`
-	Error   string `json:"error,omitempty"`
+	*core.VersionInfo
+	Error string `json:"error,omitempty"`
 }
 
 func GetVersion(w http.ResponseWriter, r *http.Request) {
 	response := &GetVersionResponse{}
 	GetGenericNoArg(w, r, response,
 		func() int {
-			version := orchestrator.GetVersion()
-			if version == "" {
+			info := orchestrator.GetVersionInfo()
+			if info.Version == "" {
 				response.Error = fmt.Sprintf("Couldn't retrieve %v's version!",
 					config.OrchestratorName)
 				return http.StatusNotFound
 			}
-			response.Version = version
+			response.VersionInfo = info
 			return http.StatusOK
 		},
 	)
 }
 
+// GetHealth reports the orchestrator's health.  It succeeds whenever
+// Trident is running, so it may be used as a liveness probe.
+func GetHealth(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, r, orchestrator.GetHealth(), http.StatusOK)
+}
+
+// GetReadiness reports the orchestrator's health, failing with 503 unless
+// Trident is ready to serve requests, so it may be used as a readiness
+// probe.
+func GetReadiness(w http.ResponseWriter, r *http.Request) {
+	status := orchestrator.GetHealth()
+	if status.Ready {
+		writeHealth(w, r, status, http.StatusOK)
+	} else {
+		writeHealth(w, r, status, http.StatusServiceUnavailable)
+	}
+}
+
+func writeHealth(
+	w http.ResponseWriter, r *http.Request, status *core.HealthStatus,
+	code int,
+) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(code)
+	if err := encodeResponse(w, r, status); err != nil {
+		panic(err)
+	}
+}
+
 func AddBackend(w http.ResponseWriter, r *http.Request) {
 	response := &AddBackendResponse{
 		BackendID: "",
 		Error:     "",
 	}
 	AddGeneric(w, r, response,
-		func(body []byte) {
-			if backend, err := orchestrator.AddStorageBackend(string(body)); err != nil {
-				response.Error = err.Error()
-			} else if backend != nil {
+		func(body []byte) error {
+			configs, multiple, err := factory.SplitBackendConfigs(body)
+			if err != nil {
+				return err
+			}
+			ctx, cancel := requestContext(w, r)
+			defer cancel()
+			if multiple {
+				result := orchestrator.AddStorageBackends(ctx, configs)
+				response.Backends = result.Backends
+				return result.AllFailed()
+			}
+			backend, err := orchestrator.AddStorageBackend(ctx, configs[0])
+			if err != nil {
+				return err
+			}
+			if backend != nil {
 				response.BackendID = backend.Name
+				response.Warnings = backend.Warnings
 			}
+			return nil
 		},
 	)
 }
 
+type ValidateBackendResponse struct {
+	Validation *core.BackendValidation `json:"validation,omitempty"`
+	Error      string                  `json:"error,omitempty"`
+}
+
+// ValidateBackend checks the backend config in the request body, reporting
+// the backend and storage pools it describes, without adding it.
+func ValidateBackend(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	response := &ValidateBackendResponse{}
+	status := http.StatusOK
+
+	defer func() {
+		if response.Error != "" {
+			log.WithFields(log.Fields{
+				"handler": "ValidateBackend",
+			}).Info(response.Error)
+		}
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			panic(err)
+		}
+	}()
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, config.MaxRESTRequestSize))
+	if err == nil {
+		err = r.Body.Close()
+	}
+	if err != nil {
+		response.Error = err.Error()
+		status = http.StatusBadRequest
+		return
+	}
+	ctx, cancel := requestContext(w, r)
+	defer cancel()
+	response.Validation, err = orchestrator.ValidateStorageBackend(ctx,
+		string(body))
+	if err != nil {
+		response.Error = err.Error()
+		status = httpStatusForError(err, http.StatusBadRequest)
+	}
+}
+
+type DiffBackendResponse struct {
+	Diff  *core.BackendDiff `json:"diff,omitempty"`
+	Error string            `json:"error,omitempty"`
+}
+
+// DiffBackend reports how the backend config in the request body would
+// change an existing backend, without changing it.
+func DiffBackend(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	response := &DiffBackendResponse{}
+	status := http.StatusOK
+	backendName := mux.Vars(r)["backend"]
+
+	defer func() {
+		if response.Error != "" {
+			log.WithFields(log.Fields{
+				"handler": "DiffBackend",
+				"backend": backendName,
+			}).Info(response.Error)
+		}
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			panic(err)
+		}
+	}()
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body,
+		config.MaxRESTRequestSize))
+	if err == nil {
+		err = r.Body.Close()
+	}
+	if err != nil {
+		response.Error = err.Error()
+		status = http.StatusBadRequest
+		return
+	}
+	ctx, cancel := requestContext(w, r)
+	defer cancel()
+	response.Diff, err = orchestrator.DiffStorageBackend(ctx, backendName,
+		string(body))
+	if err != nil {
+		response.Error = err.Error()
+		status = httpStatusForError(err, http.StatusBadRequest)
+	}
+}
+
+type ApplyStateResponse struct {
+	Report *core.StateReport `json:"report,omitempty"`
+	Error  string            `json:"error,omitempty"`
+}
+
+// ApplyState reconciles the backends and storage classes with the desired
+// state in the request body, reporting what changed.
+func ApplyState(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	response := &ApplyStateResponse{}
+	status := http.StatusOK
+
+	defer func() {
+		if response.Error != "" {
+			log.WithFields(log.Fields{
+				"handler": "ApplyState",
+			}).Error(response.Error)
+		}
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			panic(err)
+		}
+	}()
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, config.MaxRESTRequestSize))
+	if err == nil {
+		err = r.Body.Close()
+	}
+	if err != nil {
+		response.Error = err.Error()
+		status = http.StatusBadRequest
+		return
+	}
+	state := &core.DesiredState{}
+	if err = json.Unmarshal(body, state); err != nil {
+		response.Error = "Invalid JSON: " + err.Error()
+		status = http.StatusBadRequest
+		return
+	}
+	ctx, cancel := requestContext(w, r)
+	defer cancel()
+	response.Report, err = orchestrator.ApplyState(ctx, state)
+	if err != nil {
+		response.Error = err.Error()
+		status = httpStatusForError(err, http.StatusBadRequest)
+	}
+}
+
 type ListBackendsResponse struct {
 	Backends []string `json:"backends"`
 	Error    string   `json:"error,omitempty"`
@@ -222,17 +452,19 @@ func (l *ListBackendsResponse) setList(payload []string) {
 func ListBackends(w http.ResponseWriter, r *http.Request) {
 	ListGeneric(w, r,
 		&ListBackendsResponse{},
-		func() []string {
-			backends := orchestrator.ListBackends()
-			backendNames := make([]string, 0, len(backends))
-			for _, b := range backends {
-				backendNames = append(backendNames, b.Name)
-			}
-			return backendNames
-		},
+		listBackendNames,
 	)
 }
 
+func listBackendNames() []string {
+	backends := orchestrator.ListBackends()
+	backendNames := make([]string, 0, len(backends))
+	for _, b := range backends {
+		backendNames = append(backendNames, b.Name)
+	}
+	return backendNames
+}
+
 type GetBackendResponse struct {
 	Backend *storage.StorageBackendExternal `json:"backend"`
 	Error   string                          `json:"error,omitempty"`
@@ -254,20 +486,340 @@ func GetBackend(w http.ResponseWriter, r *http.Request) {
 	)
 }
 
-// DeleteBackend calls OfflineBackend in the orchestrator, as we currently do
-// not allow for full deletion of backends due to the potential for race
-// conditions and the additional bookkeeping that would be required.
+type ListBackendPoolsResponse struct {
+	Pools []*storage.StoragePoolExternal `json:"pools"`
+	Error string                         `json:"error,omitempty"`
+}
+
+// ListBackendPools returns a backend's storage pools, sorted by name, with
+// their attributes and the storage classes they satisfy.
+func ListBackendPools(w http.ResponseWriter, r *http.Request) {
+	response := &ListBackendPoolsResponse{}
+	GetGeneric(w, r, "backend", response,
+		func(backendName string) int {
+			backend := orchestrator.GetBackend(backendName)
+			if backend == nil {
+				response.Error = fmt.Sprintf("Backend %v was not found!",
+					backendName)
+				return http.StatusNotFound
+			}
+			poolNames := make([]string, 0, len(backend.Storage))
+			for name := range backend.Storage {
+				poolNames = append(poolNames, name)
+			}
+			sort.Strings(poolNames)
+			response.Pools = make([]*storage.StoragePoolExternal, 0,
+				len(poolNames))
+			for _, name := range poolNames {
+				response.Pools = append(response.Pools,
+					backend.Storage[name])
+			}
+			return http.StatusOK
+		},
+	)
+}
+
+type OfflineBackendResponse struct {
+	Report *core.OfflineBackendReport `json:"report,omitempty"`
+	Error  string                     `json:"error,omitempty"`
+}
+
+// DeleteBackend offlines a backend, as we currently do not allow for full
+// deletion of backends due to the potential for race conditions and the
+// additional bookkeeping that would be required.  A backend whose volumes
+// would be stranded is only offlined if the confirm query parameter is set.
+// The storage classes and volumes affected are reported either way.
 func DeleteBackend(w http.ResponseWriter, r *http.Request) {
-	DeleteGeneric(w, r, orchestrator.OfflineBackend, "backend")
+	confirm, _ := strconv.ParseBool(r.URL.Query().Get("confirm"))
+	writeOfflineBackendResponse(w, r, "DeleteBackend",
+		func(backendName string) (*core.OfflineBackendReport, error) {
+			return orchestrator.OfflineBackendWithReport(backendName,
+				confirm)
+		},
+	)
+}
+
+// PreviewOfflineBackend reports the storage classes and volumes that
+// deleting a backend would affect, without deleting it.
+func PreviewOfflineBackend(w http.ResponseWriter, r *http.Request) {
+	writeOfflineBackendResponse(w, r, "PreviewOfflineBackend",
+		orchestrator.PreviewOfflineBackend)
+}
+
+func writeOfflineBackendResponse(
+	w http.ResponseWriter, r *http.Request, handler string,
+	offline func(string) (*core.OfflineBackendReport, error),
+) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	response := &OfflineBackendResponse{}
+	status := http.StatusOK
+	backendName := mux.Vars(r)["backend"]
+
+	defer func() {
+		if response.Error != "" {
+			log.WithFields(log.Fields{
+				"handler": handler,
+				"backend": backendName,
+			}).Error(response.Error)
+		}
+		w.WriteHeader(status)
+		if err := encodeResponse(w, r, response); err != nil {
+			panic(err)
+		}
+	}()
+
+	var err error
+	response.Report, err = offline(backendName)
+	if err != nil {
+		response.Error = err.Error()
+		status = httpStatusForError(err, http.StatusInternalServerError)
+		if report, ok := errors.GetDetails(
+			err).(*core.OfflineBackendReport); ok {
+			response.Report = report
+		}
+	}
+}
+
+type RenameBackendRequest struct {
+	Name string `json:"name"`
+}
+
+type UpdateBackendResponse struct {
+	Backend *storage.StorageBackendExternal `json:"backend,omitempty"`
+	// Warnings describe problems with the backend that didn't stop it from
+	// being updated.
+	Warnings []string `json:"warnings,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// UpdateBackend merges the config fields in the request body into a
+// backend's config, keeping the rest, and whether the backend is online.
+func UpdateBackend(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	response := &UpdateBackendResponse{}
+	status := http.StatusOK
+	backendName := mux.Vars(r)["backend"]
+
+	defer func() {
+		logFields := log.Fields{
+			"handler": "UpdateBackend",
+			"backend": backendName,
+		}
+		if response.Error != "" {
+			log.WithFields(logFields).Error(response.Error)
+		} else {
+			log.WithFields(logFields).Info("Updated backend.")
+		}
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			panic(err)
+		}
+	}()
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body,
+		config.MaxRESTRequestSize))
+	if err == nil {
+		err = r.Body.Close()
+	}
+	if err != nil {
+		response.Error = err.Error()
+		status = http.StatusBadRequest
+		return
+	}
+	ctx, cancel := requestContext(w, r)
+	defer cancel()
+	result, err := orchestrator.UpdateStorageBackend(ctx, backendName,
+		string(body))
+	if err != nil {
+		response.Error = err.Error()
+		status = httpStatusForError(err, http.StatusBadRequest)
+		return
+	}
+	response.Backend = result.StorageBackendExternal
+	response.Warnings = result.Warnings
+}
+
+type OnlineBackendResponse struct {
+	Backend *storage.StorageBackendExternal `json:"backend,omitempty"`
+	// Warnings describe problems with the backend that didn't stop it from
+	// being brought online.
+	Warnings []string `json:"warnings,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// OnlineBackend brings an offline backend back online, adding its storage
+// pools back to the storage classes they satisfy.
+func OnlineBackend(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	response := &OnlineBackendResponse{}
+	status := http.StatusOK
+	backendName := mux.Vars(r)["backend"]
+
+	defer func() {
+		logFields := log.Fields{
+			"handler": "OnlineBackend",
+			"backend": backendName,
+		}
+		if response.Error != "" {
+			log.WithFields(logFields).Error(response.Error)
+		} else {
+			log.WithFields(logFields).Info("Brought backend online.")
+		}
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			panic(err)
+		}
+	}()
+
+	result, err := orchestrator.OnlineBackend(backendName)
+	if err != nil {
+		response.Error = err.Error()
+		status = httpStatusForError(err, http.StatusBadRequest)
+		return
+	}
+	response.Backend = result.StorageBackendExternal
+	response.Warnings = result.Warnings
+}
+
+type RenameBackendResponse struct {
+	Backend *storage.StorageBackendExternal `json:"backend,omitempty"`
+	Error   string                          `json:"error,omitempty"`
+}
+
+// RenameBackend changes a backend's name to the one in the request body.
+func RenameBackend(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	response := &RenameBackendResponse{}
+	status := http.StatusOK
+	backendName := mux.Vars(r)["backend"]
+
+	defer func() {
+		logFields := log.Fields{
+			"handler": "RenameBackend",
+			"backend": backendName,
+		}
+		if response.Error != "" {
+			log.WithFields(logFields).Error(response.Error)
+		} else {
+			log.WithFields(logFields).Info("Renamed backend.")
+		}
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			panic(err)
+		}
+	}()
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, config.MaxRESTRequestSize))
+	if err == nil {
+		err = r.Body.Close()
+	}
+	if err != nil {
+		response.Error = err.Error()
+		status = http.StatusBadRequest
+		return
+	}
+	request := new(RenameBackendRequest)
+	if err = json.Unmarshal(body, request); err != nil {
+		response.Error = fmt.Sprintf("Invalid JSON: %v", err)
+		status = http.StatusBadRequest
+		return
+	}
+	response.Backend, err = orchestrator.RenameBackend(backendName,
+		request.Name)
+	if err != nil {
+		response.Error = err.Error()
+		status = httpStatusForError(err, http.StatusBadRequest)
+	}
+}
+
+type GenerateStorageClassesResponse struct {
+	StorageClasses []*storage_class.Recommendation `json:"storageClasses"`
+	Error          string                          `json:"error,omitempty"`
+}
+
+// GenerateStorageClasses returns suggested storage classes for a backend's
+// pools.
+func GenerateStorageClasses(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	response := &GenerateStorageClassesResponse{}
+	status := http.StatusOK
+	backendName := mux.Vars(r)["backend"]
+
+	defer func() {
+		if response.Error != "" {
+			log.WithFields(log.Fields{
+				"handler": "GenerateStorageClasses",
+				"backend": backendName,
+			}).Error(response.Error)
+		}
+		w.WriteHeader(status)
+		if err := encodeResponse(w, r, response); err != nil {
+			panic(err)
+		}
+	}()
+
+	var err error
+	response.StorageClasses, err = orchestrator.GenerateStorageClasses(
+		backendName)
+	if err != nil {
+		response.Error = err.Error()
+		status = httpStatusForError(err, http.StatusBadRequest)
+	}
+}
+
+type FailoverBackendResponse struct {
+	Report *core.BackendFailoverReport `json:"report,omitempty"`
+	Error  string                      `json:"error,omitempty"`
+}
+
+// FailoverBackend fails over the replicated volumes of a lost backend to
+// their mirrors, reporting those that could not be failed over.
+func FailoverBackend(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	response := &FailoverBackendResponse{}
+	status := http.StatusOK
+	backendName := mux.Vars(r)["backend"]
+
+	defer func() {
+		logFields := log.Fields{
+			"handler": "FailoverBackend",
+			"backend": backendName,
+		}
+		if response.Error != "" {
+			log.WithFields(logFields).Error(response.Error)
+		} else {
+			log.WithFields(logFields).Info("Failed backend over.")
+		}
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			panic(err)
+		}
+	}()
+
+	var err error
+	response.Report, err = orchestrator.FailoverBackend(backendName)
+	if err != nil {
+		response.Error = err.Error()
+		status = httpStatusForError(err, http.StatusBadRequest)
+	}
 }
 
 type AddVolumeResponse struct {
 	BackendID string `json:"backend"`
-	Error     string `json:"error,omitempty"`
+	// Warnings describe problems with the volume that didn't stop it from
+	// being created.
+	Warnings []string `json:"warnings,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	// Placement explains, when no storage pool could hold the volume, why
+	// each pool was unsuitable.
+	Placement *core.PlacementReport `json:"placement,omitempty"`
 }
 
 func (a *AddVolumeResponse) setError(err error) {
 	a.Error = err.Error()
+	if report, ok := errors.GetDetails(err).(*core.PlacementReport); ok {
+		a.Placement = report
+	}
 }
 
 func (a *AddVolumeResponse) isError() bool {
@@ -292,51 +844,146 @@ func AddVolume(w http.ResponseWriter, r *http.Request) {
 		Error:     "",
 	}
 	AddGeneric(w, r, response,
-		func(body []byte) {
+		func(body []byte) error {
 			volumeConfig := new(storage.VolumeConfig)
 			err := json.Unmarshal(body, volumeConfig)
 			if err != nil {
-				response.Error = "Invalid JSON: " + err.Error()
-				return
+				return fmt.Errorf("Invalid JSON: %v", err)
 			}
 			if err = volumeConfig.Validate(); err != nil {
-				response.setError(err)
-				return
-			}
-			volume, err := orchestrator.AddVolume(volumeConfig)
-			if err != nil {
-				response.setError(err)
+				return err
 			}
+			ctx, cancel := requestContext(w, r)
+			defer cancel()
+			volume, err := orchestrator.AddVolume(ctx, volumeConfig)
 			if volume != nil {
 				response.BackendID = volume.Backend
+				response.Warnings = volume.Warnings
 			}
+			return err
 		},
 	)
 }
 
-type ListVolumesResponse struct {
-	Volumes []string `json:"volumes"`
-	Error   string   `json:"error,omitempty"`
+type ExplainPlacementResponse struct {
+	Placement *core.PlacementReport `json:"placement,omitempty"`
+	Error     string                `json:"error,omitempty"`
 }
 
-func (l *ListVolumesResponse) setList(payload []string) {
-	l.Volumes = payload
+// ExplainPlacement reports whether the volume in the request body could be
+// placed in each storage pool, without creating it.
+func ExplainPlacement(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	response := &ExplainPlacementResponse{}
+	status := http.StatusOK
+
+	defer func() {
+		if response.Error != "" {
+			log.WithFields(log.Fields{
+				"handler": "ExplainPlacement",
+			}).Error(response.Error)
+		}
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			panic(err)
+		}
+	}()
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, config.MaxRESTRequestSize))
+	if err == nil {
+		err = r.Body.Close()
+	}
+	if err != nil {
+		response.Error = err.Error()
+		status = http.StatusBadRequest
+		return
+	}
+	volumeConfig := new(storage.VolumeConfig)
+	if err = json.Unmarshal(body, volumeConfig); err != nil {
+		response.Error = fmt.Sprintf("Invalid JSON: %v", err)
+		status = http.StatusBadRequest
+		return
+	}
+	if err = volumeConfig.Validate(); err != nil {
+		response.Error = err.Error()
+		status = http.StatusBadRequest
+		return
+	}
+	response.Placement, err = orchestrator.ExplainPlacement(volumeConfig)
+	if err != nil {
+		response.Error = err.Error()
+		status = httpStatusForError(err, http.StatusBadRequest)
+	}
+}
+
+type ListVolumesResponse struct {
+	Volumes []string `json:"volumes"`
+	Error   string   `json:"error,omitempty"`
+}
+
+func (l *ListVolumesResponse) setList(payload []string) {
+	l.Volumes = payload
 }
 
 func ListVolumes(w http.ResponseWriter, r *http.Request) {
+	lister, err := volumeLister(r)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.WriteHeader(http.StatusBadRequest)
+		if err = json.NewEncoder(w).Encode(
+			&ListVolumesResponse{Error: err.Error()}); err != nil {
+			panic(err)
+		}
+		return
+	}
 	ListGeneric(w, r,
 		&ListVolumesResponse{},
-		func() []string {
-			volumes := orchestrator.ListVolumes()
-			volumeNames := make([]string, 0, len(volumes))
-			for _, v := range volumes {
-				volumeNames = append(volumeNames, v.Config.Name)
-			}
-			return volumeNames
-		},
+		lister,
 	)
 }
 
+func listVolumeNames() []string {
+	return volumeNames(orchestrator.ListVolumes())
+}
+
+func volumeNames(volumes []*storage.VolumeExternal) []string {
+	names := make([]string, 0, len(volumes))
+	for _, v := range volumes {
+		names = append(names, v.Config.Name)
+	}
+	return names
+}
+
+// volumeLister lists the names of all volumes or, if the metadata query
+// parameter is set (e.g., "?metadata=owner=alice,team=db"), of the volumes
+// with matching metadata.
+func volumeLister(r *http.Request) (func() []string, error) {
+	selector, err := volumeSelector(r)
+	if err != nil {
+		return nil, err
+	}
+	if selector == nil {
+		return listVolumeNames, nil
+	}
+	return func() []string {
+		return volumeNames(orchestrator.ListVolumesByMetadata(selector))
+	}, nil
+}
+
+// volumeSelector returns the selector in the metadata query parameter, or
+// nil if the parameter isn't set.
+func volumeSelector(r *http.Request) (map[string]string, error) {
+	encoded := r.URL.Query().Get("metadata")
+	if encoded == "" {
+		return nil, nil
+	}
+	selector, err := sa.CreateSelectorFromEncodedString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid metadata selector:  %v", err)
+	}
+	return selector, nil
+}
+
 type GetVolumeResponse struct {
 	Volume *storage.VolumeExternal `json:"volume"`
 	Error  string                  `json:"error,omitempty"`
@@ -361,104 +1008,1527 @@ func GetVolume(w http.ResponseWriter, r *http.Request) {
 	)
 }
 
-func DeleteVolume(w http.ResponseWriter, r *http.Request) {
-	DeleteGeneric(w, r, orchestrator.DeleteVolume, "volume")
+type GetVolumeChapResponse struct {
+	Chap  *storage.ChapConfig `json:"chap"`
+	Error string              `json:"error,omitempty"`
 }
 
-type AddStorageClassResponse struct {
-	StorageClassID string `json:"storageClass"`
-	Error          string `json:"error,omitempty"`
+// GetVolumeChap returns the CHAP credentials that hosts need to attach a
+// volume.  Unlike the volume itself, the response includes the secrets.
+func GetVolumeChap(w http.ResponseWriter, r *http.Request) {
+	response := &GetVolumeChapResponse{}
+	GetGeneric(w, r, "volume", response,
+		func(volName string) int {
+			chap, err := orchestrator.GetVolumeChap(volName)
+			if err != nil {
+				response.Error = err.Error()
+				return httpStatusForError(err, http.StatusInternalServerError)
+			}
+			response.Chap = chap
+			return http.StatusOK
+		},
+	)
 }
 
-func (a *AddStorageClassResponse) setError(err error) {
-	a.Error = err.Error()
+// DeleteVolume refuses to delete attached volumes unless the force query
+// parameter is set.
+func DeleteVolume(w http.ResponseWriter, r *http.Request) {
+	DeleteGeneric(w, r, volumeDeleteFunc(w, r), "volume")
 }
 
-func (a *AddStorageClassResponse) isError() bool {
-	return a.Error != ""
+func volumeDeleteFunc(w http.ResponseWriter, r *http.Request) deleteFunc {
+	deleteVolume := orchestrator.DeleteVolume
+	if force, _ := strconv.ParseBool(r.URL.Query().Get("force")); force {
+		deleteVolume = orchestrator.ForceDeleteVolume
+	}
+	return func(volumeName string) (bool, error) {
+		ctx, cancel := requestContext(w, r)
+		defer cancel()
+		return deleteVolume(ctx, volumeName)
+	}
 }
 
-func (a *AddStorageClassResponse) logSuccess() {
-	log.WithFields(log.Fields{
-		"handler":      "AddStorageClass",
-		"storageClass": a.StorageClassID,
-	}).Info("Added a new storage class.")
+type DeleteVolumesResponse struct {
+	Result *core.BulkDeleteResult `json:"result,omitempty"`
+	Error  string                 `json:"error,omitempty"`
 }
-func (a *AddStorageClassResponse) logFailure() {
-	log.WithFields(log.Fields{
-		"handler":      "AddStorageClass",
-		"storageClass": a.StorageClassID,
-	}).Error(a.Error)
+
+// DeleteVolumes deletes the volumes named, or selected by metadata, in the
+// request body, reporting the outcome for each.  It fails only if the
+// request is invalid or none of the volumes were deleted.
+func DeleteVolumes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	response := &DeleteVolumesResponse{}
+	status := http.StatusOK
+
+	defer func() {
+		if response.Error != "" {
+			log.WithFields(log.Fields{
+				"handler": "DeleteVolumes",
+			}).Error(response.Error)
+		}
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			panic(err)
+		}
+	}()
+
+	body, err := ioutil.ReadAll(
+		io.LimitReader(r.Body, config.MaxRESTRequestSize))
+	if err == nil {
+		err = r.Body.Close()
+	}
+	if err != nil {
+		response.Error = err.Error()
+		status = http.StatusBadRequest
+		return
+	}
+	deleteConfig := &core.BulkDeleteConfig{}
+	if err = json.Unmarshal(body, deleteConfig); err != nil {
+		response.Error = "Invalid JSON: " + err.Error()
+		status = http.StatusBadRequest
+		return
+	}
+	ctx, cancel := requestContext(w, r)
+	defer cancel()
+	response.Result, err = orchestrator.DeleteVolumes(ctx, deleteConfig)
+	if err == nil {
+		err = response.Result.AllFailed()
+	}
+	if err != nil {
+		response.Error = err.Error()
+		status = httpStatusForError(err, http.StatusBadRequest)
+	}
 }
 
-func AddStorageClass(w http.ResponseWriter, r *http.Request) {
-	response := &AddStorageClassResponse{
-		StorageClassID: "",
-		Error:          "",
+// UpdateVolumeResponse is returned by the handlers that change an existing
+// volume.
+type UpdateVolumeResponse struct {
+	Volume *storage.VolumeExternal `json:"volume,omitempty"`
+	Error  string                  `json:"error,omitempty"`
+}
+
+// UpdateVolumeGeneric reads the request body and passes it to update for the
+// volume named in the URL, returning 404 if the volume doesn't exist.
+func UpdateVolumeGeneric(
+	w http.ResponseWriter,
+	r *http.Request,
+	handler string,
+	update func(volName string, body []byte) (*storage.VolumeExternal, error),
+) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	response := &UpdateVolumeResponse{}
+	status := http.StatusOK
+	volName := mux.Vars(r)["volume"]
+
+	defer func() {
+		logFields := log.Fields{
+			"handler": handler,
+			"volume":  volName,
+		}
+		if response.Error != "" {
+			log.WithFields(logFields).Error(response.Error)
+		} else {
+			log.WithFields(logFields).Info("Updated volume.")
+		}
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			panic(err)
+		}
+	}()
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, config.MaxRESTRequestSize))
+	if err == nil {
+		err = r.Body.Close()
 	}
-	AddGeneric(w, r, response,
-		func(body []byte) {
-			scConfig := new(storage_class.Config)
-			err := json.Unmarshal(body, scConfig)
-			if err != nil {
-				response.Error = "Invalid JSON: " + err.Error()
-				return
-			}
-			sc, err := orchestrator.AddStorageClass(scConfig)
-			if err != nil {
-				response.setError(err)
+	if err != nil {
+		response.Error = err.Error()
+		status = http.StatusBadRequest
+		return
+	}
+	if orchestrator.GetVolume(volName) == nil {
+		response.Error = fmt.Sprintf("Volume %v was not found!", volName)
+		status = http.StatusNotFound
+		return
+	}
+	if response.Volume, err = update(volName, body); err != nil {
+		response.Error = err.Error()
+		status = httpStatusForError(err, http.StatusBadRequest)
+	}
+}
+
+// SetVolumeAccess replaces a volume's access list with the one in the
+// request body.
+func SetVolumeAccess(w http.ResponseWriter, r *http.Request) {
+	UpdateVolumeGeneric(w, r, "SetVolumeAccess",
+		func(volName string, body []byte) (*storage.VolumeExternal, error) {
+			access := new(storage.VolumeAccess)
+			if err := json.Unmarshal(body, access); err != nil {
+				return nil, fmt.Errorf("Invalid JSON: %v", err)
 			}
-			if sc != nil {
-				response.StorageClassID = sc.GetName()
+			return orchestrator.SetVolumeAccess(volName, access)
+		},
+	)
+}
+
+type UpdateVolumeMetadataRequest struct {
+	Metadata map[string]string `json:"metadata"`
+}
+
+// UpdateVolumeMetadata replaces a volume's metadata with the one in the
+// request body.
+func UpdateVolumeMetadata(w http.ResponseWriter, r *http.Request) {
+	UpdateVolumeGeneric(w, r, "UpdateVolumeMetadata",
+		func(volName string, body []byte) (*storage.VolumeExternal, error) {
+			request := new(UpdateVolumeMetadataRequest)
+			if err := json.Unmarshal(body, request); err != nil {
+				return nil, fmt.Errorf("Invalid JSON: %v", err)
 			}
+			return orchestrator.UpdateVolumeMetadata(volName, request.Metadata)
 		},
 	)
 }
 
-type ListStorageClassesResponse struct {
-	StorageClasses []string `json:"storageClasses"`
-	Error          string   `json:"error,omitempty"`
+// SetVolumeExpiryRequest sets a volume's expiry to ExpiresAt, or to TTL
+// from now.  If neither is set, the volume never expires.
+type SetVolumeExpiryRequest struct {
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	TTL       string     `json:"ttl,omitempty"`
 }
 
-func (l *ListStorageClassesResponse) setList(payload []string) {
-	l.StorageClasses = payload
+// SetVolumeExpiry changes when a volume expires, e.g., to extend the life
+// of an expired volume within its grace period.
+func SetVolumeExpiry(w http.ResponseWriter, r *http.Request) {
+	UpdateVolumeGeneric(w, r, "SetVolumeExpiry",
+		func(volName string, body []byte) (*storage.VolumeExternal, error) {
+			request := new(SetVolumeExpiryRequest)
+			if err := json.Unmarshal(body, request); err != nil {
+				return nil, fmt.Errorf("Invalid JSON: %v", err)
+			}
+			if err := storage.ValidateExpiry(request.TTL,
+				request.ExpiresAt); err != nil {
+				return nil, err
+			}
+			expiresAt := request.ExpiresAt
+			if request.TTL != "" {
+				ttl, _ := time.ParseDuration(request.TTL)
+				t := time.Now().Add(ttl)
+				expiresAt = &t
+			}
+			return orchestrator.SetVolumeExpiry(volName, expiresAt)
+		},
+	)
 }
 
-func ListStorageClasses(w http.ResponseWriter, r *http.Request) {
-	ListGeneric(w, r,
-		&ListStorageClassesResponse{},
-		func() []string {
-			storageClasses := orchestrator.ListStorageClasses()
-			storageClassNames := make([]string, 0, len(storageClasses))
-			for _, sc := range storageClasses {
-				storageClassNames = append(storageClassNames, sc.GetName())
+type ReattachVolumeRequest struct {
+	Backend string `json:"backend"`
+	Pool    string `json:"pool,omitempty"`
+}
+
+// ReattachVolume binds an orphaned volume to the backend in the request
+// body.
+func ReattachVolume(w http.ResponseWriter, r *http.Request) {
+	UpdateVolumeGeneric(w, r, "ReattachVolume",
+		func(volName string, body []byte) (*storage.VolumeExternal, error) {
+			request := new(ReattachVolumeRequest)
+			if err := json.Unmarshal(body, request); err != nil {
+				return nil, fmt.Errorf("Invalid JSON: %v", err)
 			}
-			return storageClassNames
+			return orchestrator.ReattachVolume(volName, request.Backend,
+				request.Pool)
 		},
 	)
 }
 
-type GetStorageClassResponse struct {
-	StorageClass *storage_class.StorageClassExternal `json:"storageClass"`
-	Error        string                              `json:"error,omitempty"`
+type ListRetainedVolumesResponse struct {
+	Volumes []*persistent_store.RetainedVolume `json:"volumes"`
 }
 
-func GetStorageClass(w http.ResponseWriter, r *http.Request) {
-	response := &GetStorageClassResponse{}
-	GetGeneric(w, r, "storageClass", response,
-		func(scName string) int {
-			storageClass := orchestrator.GetStorageClass(scName)
-			if storageClass == nil {
-				response.Error = fmt.Sprintf("StorageClass %s was not found!",
-					scName)
+// ListRetainedVolumes returns the volumes that were deleted from Trident
+// with the Retain reclaim policy and left on their backends.
+func ListRetainedVolumes(w http.ResponseWriter, r *http.Request) {
+	response := &ListRetainedVolumesResponse{
+		Volumes: orchestrator.ListRetainedVolumes(),
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := encodeResponse(w, r, response); err != nil {
+		panic(err)
+	}
+}
+
+type ImportRetainedVolumeRequest struct {
+	Backend string `json:"backend,omitempty"`
+	Pool    string `json:"pool,omitempty"`
+}
+
+// ImportRetainedVolume adds a retained volume back to Trident, optionally
+// on the backend and pool in the request body.
+func ImportRetainedVolume(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	response := &UpdateVolumeResponse{}
+	status := http.StatusCreated
+	volName := mux.Vars(r)["volume"]
+
+	defer func() {
+		logFields := log.Fields{
+			"handler": "ImportRetainedVolume",
+			"volume":  volName,
+		}
+		if response.Error != "" {
+			log.WithFields(logFields).Error(response.Error)
+		} else {
+			log.WithFields(logFields).Info("Imported retained volume.")
+		}
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			panic(err)
+		}
+	}()
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, config.MaxRESTRequestSize))
+	if err == nil {
+		err = r.Body.Close()
+	}
+	if err != nil {
+		response.Error = err.Error()
+		status = http.StatusBadRequest
+		return
+	}
+	request := new(ImportRetainedVolumeRequest)
+	if len(body) > 0 {
+		if err = json.Unmarshal(body, request); err != nil {
+			response.Error = fmt.Sprintf("Invalid JSON: %v", err)
+			status = http.StatusBadRequest
+			return
+		}
+	}
+	response.Volume, err = orchestrator.ImportRetainedVolume(volName,
+		request.Backend, request.Pool)
+	if err != nil {
+		response.Error = err.Error()
+		status = httpStatusForError(err, http.StatusBadRequest)
+	}
+}
+
+// DeleteRetainedVolume forgets a retained volume, leaving it on its backend.
+func DeleteRetainedVolume(w http.ResponseWriter, r *http.Request) {
+	DeleteGeneric(w, r, orchestrator.DeleteRetainedVolume, "volume")
+}
+
+type CreatePersistentVolumeResponse struct {
+	PersistentVolume interface{} `json:"persistentVolume,omitempty"`
+	Error            string      `json:"error,omitempty"`
+}
+
+// CreatePersistentVolume creates a Kubernetes PV for an existing volume,
+// optionally pre-bound to a claim; see frontend.PersistentVolumeRequest.
+func CreatePersistentVolume(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	response := &CreatePersistentVolumeResponse{}
+	status := http.StatusCreated
+	volName := mux.Vars(r)["volume"]
+
+	defer func() {
+		if response.Error != "" {
+			log.WithFields(log.Fields{
+				"handler": "CreatePersistentVolume",
+				"volume":  volName,
+			}).Error(response.Error)
+		}
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			panic(err)
+		}
+	}()
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, config.MaxRESTRequestSize))
+	if err == nil {
+		err = r.Body.Close()
+	}
+	if err != nil {
+		response.Error = err.Error()
+		status = http.StatusBadRequest
+		return
+	}
+	request := new(frontend.PersistentVolumeRequest)
+	if len(body) > 0 {
+		if err = json.Unmarshal(body, request); err != nil {
+			response.Error = fmt.Sprintf("Invalid JSON: %v", err)
+			status = http.StatusBadRequest
+			return
+		}
+	}
+	request.Volume = volName
+
+	f, err := orchestrator.GetFrontend(config.KubernetesFrontendName)
+	creator, ok := f.(frontend.PersistentVolumeCreator)
+	if err != nil || !ok {
+		response.Error = "The Kubernetes frontend is not enabled."
+		status = http.StatusBadRequest
+		return
+	}
+	response.PersistentVolume, err = creator.CreatePersistentVolume(request)
+	if err != nil {
+		response.Error = err.Error()
+		status = httpStatusForError(err, http.StatusInternalServerError)
+		return
+	}
+	if request.DryRun {
+		status = http.StatusOK
+	}
+}
+
+type ListProvisioningRetriesResponse struct {
+	Retries []*persistent_store.ProvisioningRetry `json:"retries"`
+}
+
+// ListProvisioningRetries returns the volumes whose provisioning has
+// failed, with their attempt counts and when they will next be tried.
+func ListProvisioningRetries(w http.ResponseWriter, r *http.Request) {
+	response := &ListProvisioningRetriesResponse{
+		Retries: orchestrator.ListProvisioningRetries(),
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := encodeResponse(w, r, response); err != nil {
+		panic(err)
+	}
+}
+
+type GetProvisioningRetryResponse struct {
+	Retry *persistent_store.ProvisioningRetry `json:"retry"`
+	Error string                              `json:"error,omitempty"`
+}
+
+func GetProvisioningRetry(w http.ResponseWriter, r *http.Request) {
+	response := &GetProvisioningRetryResponse{}
+	GetGeneric(w, r, "volume", response,
+		func(volName string) int {
+			response.Retry = orchestrator.GetProvisioningRetry(volName)
+			if response.Retry == nil {
+				response.Error = fmt.Sprintf("Provisioning retry for "+
+					"volume %v was not found!", volName)
 				return http.StatusNotFound
 			}
-			response.StorageClass = storageClass
 			return http.StatusOK
 		},
 	)
 }
 
+// DeleteProvisioningRetry resets a volume's backoff, so that its frontend
+// retries provisioning at its next opportunity.
+func DeleteProvisioningRetry(w http.ResponseWriter, r *http.Request) {
+	DeleteGeneric(w, r, orchestrator.DeleteProvisioningRetry, "volume")
+}
+
+// RetryProvisioningNow has a volume's frontend retry provisioning at its
+// next opportunity, keeping its attempt count, and resumes it if abandoned.
+func RetryProvisioningNow(w http.ResponseWriter, r *http.Request) {
+	updateProvisioningRetry(w, r, "RetryProvisioningNow",
+		orchestrator.RetryProvisioningNow)
+}
+
+// AbandonProvisioning stops a volume's frontend from retrying provisioning.
+func AbandonProvisioning(w http.ResponseWriter, r *http.Request) {
+	updateProvisioningRetry(w, r, "AbandonProvisioning",
+		orchestrator.AbandonProvisioning)
+}
+
+func updateProvisioningRetry(
+	w http.ResponseWriter, r *http.Request, handler string,
+	update func(string) (*persistent_store.ProvisioningRetry, error),
+) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	response := &GetProvisioningRetryResponse{}
+	status := http.StatusOK
+	volName := mux.Vars(r)["volume"]
+
+	defer func() {
+		logFields := log.Fields{
+			"handler": handler,
+			"volume":  volName,
+		}
+		if response.Error != "" {
+			log.WithFields(logFields).Error(response.Error)
+		} else {
+			log.WithFields(logFields).Info("Updated provisioning retry.")
+		}
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			panic(err)
+		}
+	}()
+
+	var err error
+	response.Retry, err = update(volName)
+	if err != nil {
+		response.Error = err.Error()
+		status = httpStatusForError(err, http.StatusInternalServerError)
+	}
+}
+
+// UndeleteVolume restores a volume that is terminating, i.e., deleted but
+// within its deletion grace period.
+func UndeleteVolume(w http.ResponseWriter, r *http.Request) {
+	UpdateVolumeGeneric(w, r, "UndeleteVolume",
+		func(volName string, body []byte) (*storage.VolumeExternal, error) {
+			return orchestrator.UndeleteVolume(volName)
+		},
+	)
+}
+
+type RenameVolumeRequest struct {
+	Name string `json:"name"`
+}
+
+// RenameVolume changes a volume's name to the one in the request body.
+func RenameVolume(w http.ResponseWriter, r *http.Request) {
+	UpdateVolumeGeneric(w, r, "RenameVolume",
+		func(volName string, body []byte) (*storage.VolumeExternal, error) {
+			request := new(RenameVolumeRequest)
+			if err := json.Unmarshal(body, request); err != nil {
+				return nil, fmt.Errorf("Invalid JSON: %v", err)
+			}
+			return orchestrator.RenameVolume(volName, request.Name)
+		},
+	)
+}
+
+type SetVolumeFormattedRequest struct {
+	FSType string `json:"fsType"`
+}
+
+// SetVolumeFormatted records that a block volume has been formatted.
+func SetVolumeFormatted(w http.ResponseWriter, r *http.Request) {
+	UpdateVolumeGeneric(w, r, "SetVolumeFormatted",
+		func(volName string, body []byte) (*storage.VolumeExternal, error) {
+			request := new(SetVolumeFormattedRequest)
+			if err := json.Unmarshal(body, request); err != nil {
+				return nil, fmt.Errorf("Invalid JSON: %v", err)
+			}
+			return orchestrator.SetVolumeFormatted(volName, request.FSType)
+		},
+	)
+}
+
+type AttachVolumeRequest struct {
+	Node     string `json:"node"`
+	ReadOnly bool   `json:"readOnly,omitempty"`
+}
+
+func AttachVolume(w http.ResponseWriter, r *http.Request) {
+	UpdateVolumeGeneric(w, r, "AttachVolume",
+		func(volName string, body []byte) (*storage.VolumeExternal, error) {
+			request := new(AttachVolumeRequest)
+			if err := json.Unmarshal(body, request); err != nil {
+				return nil, fmt.Errorf("Invalid JSON: %v", err)
+			}
+			return orchestrator.AttachVolume(volName, request.Node,
+				request.ReadOnly)
+		},
+	)
+}
+
+func DetachVolume(w http.ResponseWriter, r *http.Request) {
+	node := mux.Vars(r)["node"]
+	UpdateVolumeGeneric(w, r, "DetachVolume",
+		func(volName string, body []byte) (*storage.VolumeExternal, error) {
+			return orchestrator.DetachVolume(volName, node)
+		},
+	)
+}
+
+// EnableReplication mirrors a volume to the peer backend in the request
+// body.
+func EnableReplication(w http.ResponseWriter, r *http.Request) {
+	UpdateVolumeGeneric(w, r, "EnableReplication",
+		func(volName string, body []byte) (*storage.VolumeExternal, error) {
+			replicationConfig := new(core.ReplicationConfig)
+			if err := json.Unmarshal(body, replicationConfig); err != nil {
+				return nil, fmt.Errorf("Invalid JSON: %v", err)
+			}
+			return orchestrator.EnableReplication(volName, replicationConfig)
+		},
+	)
+}
+
+func DisableReplication(w http.ResponseWriter, r *http.Request) {
+	UpdateVolumeGeneric(w, r, "DisableReplication",
+		func(volName string, body []byte) (*storage.VolumeExternal, error) {
+			return orchestrator.DisableReplication(volName)
+		},
+	)
+}
+
+// FailoverVolume moves a replicated volume to its mirror.
+func FailoverVolume(w http.ResponseWriter, r *http.Request) {
+	UpdateVolumeGeneric(w, r, "FailoverVolume",
+		func(volName string, body []byte) (*storage.VolumeExternal, error) {
+			return orchestrator.FailoverVolume(volName)
+		},
+	)
+}
+
+type AddStorageClassResponse struct {
+	StorageClassID string `json:"storageClass"`
+	// Warnings describe problems with the storage class that didn't stop
+	// it from being added.
+	Warnings []string `json:"warnings,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+func (a *AddStorageClassResponse) setError(err error) {
+	a.Error = err.Error()
+}
+
+func (a *AddStorageClassResponse) isError() bool {
+	return a.Error != ""
+}
+
+func (a *AddStorageClassResponse) logSuccess() {
+	log.WithFields(log.Fields{
+		"handler":      "AddStorageClass",
+		"storageClass": a.StorageClassID,
+	}).Info("Added a new storage class.")
+}
+func (a *AddStorageClassResponse) logFailure() {
+	log.WithFields(log.Fields{
+		"handler":      "AddStorageClass",
+		"storageClass": a.StorageClassID,
+	}).Error(a.Error)
+}
+
+func AddStorageClass(w http.ResponseWriter, r *http.Request) {
+	response := &AddStorageClassResponse{
+		StorageClassID: "",
+		Error:          "",
+	}
+	AddGeneric(w, r, response,
+		func(body []byte) error {
+			scConfig := new(storage_class.Config)
+			err := json.Unmarshal(body, scConfig)
+			if err != nil {
+				return fmt.Errorf("Invalid JSON: %v", err)
+			}
+			sc, err := orchestrator.AddStorageClass(scConfig)
+			if sc != nil {
+				response.StorageClassID = sc.GetName()
+				response.Warnings = sc.Warnings
+			}
+			return err
+		},
+	)
+}
+
+type UpdateStorageClassResponse struct {
+	StorageClass *storage_class.StorageClassExternal `json:"storageClass,omitempty"`
+	Warnings     []string                            `json:"warnings,omitempty"`
+	Error        string                              `json:"error,omitempty"`
+}
+
+// UpdateStorageClass replaces a storage class's config with the one in the
+// request body, whose name, if set, must match the storage class's.
+func UpdateStorageClass(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	response := &UpdateStorageClassResponse{}
+	status := http.StatusOK
+	scName := mux.Vars(r)["storageClass"]
+
+	defer func() {
+		logFields := log.Fields{
+			"handler":      "UpdateStorageClass",
+			"storageClass": scName,
+		}
+		if response.Error != "" {
+			log.WithFields(logFields).Error(response.Error)
+		} else {
+			log.WithFields(logFields).Info("Updated storage class.")
+		}
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			panic(err)
+		}
+	}()
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, config.MaxRESTRequestSize))
+	if err == nil {
+		err = r.Body.Close()
+	}
+	if err != nil {
+		response.Error = err.Error()
+		status = http.StatusBadRequest
+		return
+	}
+	scConfig := new(storage_class.Config)
+	if err = json.Unmarshal(body, scConfig); err != nil {
+		response.Error = fmt.Sprintf("Invalid JSON: %v", err)
+		status = http.StatusBadRequest
+		return
+	}
+	if scConfig.Name == "" {
+		scConfig.Name = scName
+	} else if scConfig.Name != scName {
+		response.Error = fmt.Sprintf("Storage class name %s doesn't match "+
+			"%s; storage classes can't be renamed.", scConfig.Name, scName)
+		status = http.StatusBadRequest
+		return
+	}
+	result, err := orchestrator.UpdateStorageClass(scConfig)
+	if err != nil {
+		response.Error = err.Error()
+		status = httpStatusForError(err, http.StatusBadRequest)
+		return
+	}
+	response.StorageClass = result.StorageClassExternal
+	response.Warnings = result.Warnings
+}
+
+type ListStorageClassesResponse struct {
+	StorageClasses []string `json:"storageClasses"`
+	Error          string   `json:"error,omitempty"`
+}
+
+func (l *ListStorageClassesResponse) setList(payload []string) {
+	l.StorageClasses = payload
+}
+
+func ListStorageClasses(w http.ResponseWriter, r *http.Request) {
+	ListGeneric(w, r,
+		&ListStorageClassesResponse{},
+		listStorageClassNames,
+	)
+}
+
+func listStorageClassNames() []string {
+	storageClasses := orchestrator.ListStorageClasses()
+	storageClassNames := make([]string, 0, len(storageClasses))
+	for _, sc := range storageClasses {
+		storageClassNames = append(storageClassNames, sc.GetName())
+	}
+	return storageClassNames
+}
+
+type GetStorageClassResponse struct {
+	StorageClass *storage_class.StorageClassExternal `json:"storageClass"`
+	Error        string                              `json:"error,omitempty"`
+}
+
+func GetStorageClass(w http.ResponseWriter, r *http.Request) {
+	response := &GetStorageClassResponse{}
+	GetGeneric(w, r, "storageClass", response,
+		func(scName string) int {
+			storageClass := orchestrator.GetStorageClass(scName)
+			if storageClass == nil {
+				response.Error = fmt.Sprintf("StorageClass %s was not found!",
+					scName)
+				return http.StatusNotFound
+			}
+			response.StorageClass = storageClass
+			return http.StatusOK
+		},
+	)
+}
+
+// DeleteStorageClass deletes a storage class even if volumes still use it
+// when the force query parameter is set.
 func DeleteStorageClass(w http.ResponseWriter, r *http.Request) {
-	DeleteGeneric(w, r, orchestrator.DeleteStorageClass, "storageClass")
+	DeleteGeneric(w, r, storageClassDeleteFunc(r), "storageClass")
+}
+
+func storageClassDeleteFunc(r *http.Request) deleteFunc {
+	if force, _ := strconv.ParseBool(r.URL.Query().Get("force")); force {
+		return orchestrator.ForceDeleteStorageClass
+	}
+	return orchestrator.DeleteStorageClass
+}
+
+type AddVolumeGroupResponse struct {
+	VolumeGroup *persistent_store.VolumeGroup `json:"volumeGroup,omitempty"`
+	Error       string                        `json:"error,omitempty"`
+}
+
+func (a *AddVolumeGroupResponse) setError(err error) {
+	a.Error = err.Error()
+}
+
+func (a *AddVolumeGroupResponse) isError() bool {
+	return a.Error != ""
+}
+
+func (a *AddVolumeGroupResponse) logSuccess() {
+	log.WithFields(log.Fields{
+		"handler":     "AddVolumeGroup",
+		"volumeGroup": a.VolumeGroup.Name,
+	}).Info("Added a new volume group.")
+}
+
+func (a *AddVolumeGroupResponse) logFailure() {
+	log.WithFields(log.Fields{
+		"handler": "AddVolumeGroup",
+	}).Error(a.Error)
+}
+
+func AddVolumeGroup(w http.ResponseWriter, r *http.Request) {
+	response := &AddVolumeGroupResponse{}
+	AddGeneric(w, r, response,
+		func(body []byte) error {
+			groupConfig := new(core.VolumeGroupConfig)
+			err := json.Unmarshal(body, groupConfig)
+			if err != nil {
+				return fmt.Errorf("Invalid JSON: %v", err)
+			}
+			ctx, cancel := requestContext(w, r)
+			defer cancel()
+			response.VolumeGroup, err = orchestrator.AddVolumeGroup(ctx,
+				groupConfig)
+			return err
+		},
+	)
+}
+
+type ListVolumeGroupsResponse struct {
+	VolumeGroups []string `json:"volumeGroups"`
+	Error        string   `json:"error,omitempty"`
+}
+
+func (l *ListVolumeGroupsResponse) setList(payload []string) {
+	l.VolumeGroups = payload
+}
+
+func ListVolumeGroups(w http.ResponseWriter, r *http.Request) {
+	ListGeneric(w, r,
+		&ListVolumeGroupsResponse{},
+		func() []string {
+			groups := orchestrator.ListVolumeGroups()
+			groupNames := make([]string, 0, len(groups))
+			for _, g := range groups {
+				groupNames = append(groupNames, g.Name)
+			}
+			return groupNames
+		},
+	)
+}
+
+type GetVolumeGroupResponse struct {
+	VolumeGroup *persistent_store.VolumeGroup `json:"volumeGroup"`
+	Error       string                        `json:"error,omitempty"`
+}
+
+func GetVolumeGroup(w http.ResponseWriter, r *http.Request) {
+	response := &GetVolumeGroupResponse{}
+	GetGeneric(w, r, "group", response,
+		func(groupName string) int {
+			group := orchestrator.GetVolumeGroup(groupName)
+			if group == nil {
+				response.Error = fmt.Sprintf("Volume group %s was not "+
+					"found!", groupName)
+				return http.StatusNotFound
+			}
+			response.VolumeGroup = group
+			return http.StatusOK
+		},
+	)
+}
+
+// DeleteVolumeGroup deletes a volume group and its volumes.
+func DeleteVolumeGroup(w http.ResponseWriter, r *http.Request) {
+	DeleteGeneric(w, r, func(groupName string) (bool, error) {
+		ctx, cancel := requestContext(w, r)
+		defer cancel()
+		return orchestrator.DeleteVolumeGroup(ctx, groupName)
+	}, "group")
+}
+
+type SnapshotVolumeGroupRequest struct {
+	Name string `json:"name"`
+}
+
+type SnapshotVolumeGroupResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// SnapshotVolumeGroup snapshots a volume group's volumes consistently, with
+// the snapshot name in the request body.
+func SnapshotVolumeGroup(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	response := &SnapshotVolumeGroupResponse{}
+	status := http.StatusCreated
+	groupName := mux.Vars(r)["group"]
+
+	defer func() {
+		logFields := log.Fields{
+			"handler":     "SnapshotVolumeGroup",
+			"volumeGroup": groupName,
+		}
+		if response.Error != "" {
+			log.WithFields(logFields).Error(response.Error)
+		}
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			panic(err)
+		}
+	}()
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, config.MaxRESTRequestSize))
+	if err == nil {
+		err = r.Body.Close()
+	}
+	if err != nil {
+		response.Error = err.Error()
+		status = http.StatusBadRequest
+		return
+	}
+	request := new(SnapshotVolumeGroupRequest)
+	if err = json.Unmarshal(body, request); err != nil {
+		response.Error = fmt.Sprintf("Invalid JSON: %v", err)
+		status = http.StatusBadRequest
+		return
+	}
+	if err = orchestrator.SnapshotVolumeGroup(groupName,
+		request.Name); err != nil {
+		response.Error = err.Error()
+		status = httpStatusForError(err, http.StatusInternalServerError)
+	}
+}
+
+type AddTemplateResponse struct {
+	TemplateID string `json:"template"`
+	Error      string `json:"error,omitempty"`
+}
+
+func (a *AddTemplateResponse) setError(err error) {
+	a.Error = err.Error()
+}
+
+func (a *AddTemplateResponse) isError() bool {
+	return a.Error != ""
+}
+
+func (a *AddTemplateResponse) logSuccess() {
+	log.WithFields(log.Fields{
+		"handler":  "AddTemplate",
+		"template": a.TemplateID,
+	}).Info("Added a new template.")
+}
+
+func (a *AddTemplateResponse) logFailure() {
+	log.WithFields(log.Fields{
+		"handler":  "AddTemplate",
+		"template": a.TemplateID,
+	}).Error(a.Error)
+}
+
+func AddTemplate(w http.ResponseWriter, r *http.Request) {
+	response := &AddTemplateResponse{}
+	AddGeneric(w, r, response,
+		func(body []byte) error {
+			templateConfig := new(app_template.Config)
+			err := json.Unmarshal(body, templateConfig)
+			if err != nil {
+				return fmt.Errorf("Invalid JSON: %v", err)
+			}
+			template, err := orchestrator.AddTemplate(templateConfig)
+			if template != nil {
+				response.TemplateID = template.Name
+			}
+			return err
+		},
+	)
+}
+
+type ListTemplatesResponse struct {
+	Templates []string `json:"templates"`
+	Error     string   `json:"error,omitempty"`
+}
+
+func (l *ListTemplatesResponse) setList(payload []string) {
+	l.Templates = payload
+}
+
+func ListTemplates(w http.ResponseWriter, r *http.Request) {
+	ListGeneric(w, r,
+		&ListTemplatesResponse{},
+		func() []string {
+			templates := orchestrator.ListTemplates()
+			templateNames := make([]string, 0, len(templates))
+			for _, t := range templates {
+				templateNames = append(templateNames, t.Name)
+			}
+			return templateNames
+		},
+	)
+}
+
+type GetTemplateResponse struct {
+	Template *app_template.Config `json:"template"`
+	Error    string               `json:"error,omitempty"`
+}
+
+func GetTemplate(w http.ResponseWriter, r *http.Request) {
+	response := &GetTemplateResponse{}
+	GetGeneric(w, r, "template", response,
+		func(templateName string) int {
+			template := orchestrator.GetTemplate(templateName)
+			if template == nil {
+				response.Error = fmt.Sprintf("Template %s was not found!",
+					templateName)
+				return http.StatusNotFound
+			}
+			response.Template = template
+			return http.StatusOK
+		},
+	)
+}
+
+func DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	DeleteGeneric(w, r, orchestrator.DeleteTemplate, "template")
+}
+
+type ProvisionTemplateRequest struct {
+	Name string `json:"name"`
+}
+
+type ProvisionTemplateResponse struct {
+	VolumeGroup *persistent_store.VolumeGroup `json:"volumeGroup,omitempty"`
+	Error       string                        `json:"error,omitempty"`
+}
+
+func (p *ProvisionTemplateResponse) setError(err error) {
+	p.Error = err.Error()
+}
+
+func (p *ProvisionTemplateResponse) isError() bool {
+	return p.Error != ""
+}
+
+func (p *ProvisionTemplateResponse) logSuccess() {
+	log.WithFields(log.Fields{
+		"handler":     "ProvisionTemplate",
+		"template":    p.VolumeGroup.Template,
+		"volumeGroup": p.VolumeGroup.Name,
+	}).Info("Provisioned template.")
+}
+
+func (p *ProvisionTemplateResponse) logFailure() {
+	log.WithFields(log.Fields{
+		"handler": "ProvisionTemplate",
+	}).Error(p.Error)
+}
+
+// ProvisionTemplate creates an instance of a template as a volume group,
+// with the instance name in the request body.
+func ProvisionTemplate(w http.ResponseWriter, r *http.Request) {
+	response := &ProvisionTemplateResponse{}
+	templateName := mux.Vars(r)["template"]
+	AddGeneric(w, r, response,
+		func(body []byte) error {
+			request := new(ProvisionTemplateRequest)
+			err := json.Unmarshal(body, request)
+			if err != nil {
+				return fmt.Errorf("Invalid JSON: %v", err)
+			}
+			ctx, cancel := requestContext(w, r)
+			defer cancel()
+			response.VolumeGroup, err = orchestrator.ProvisionTemplate(ctx,
+				templateName, request.Name)
+			return err
+		},
+	)
+}
+
+type SetNamespacePolicyResponse struct {
+	Policy *namespace_policy.Config `json:"namespacePolicy,omitempty"`
+	Error  string                   `json:"error,omitempty"`
+}
+
+// SetNamespacePolicy sets a namespace's policy to the one in the request
+// body, whose namespace, if set, must match the one in the URL.
+func SetNamespacePolicy(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	response := &SetNamespacePolicyResponse{}
+	status := http.StatusOK
+	namespace := mux.Vars(r)["namespace"]
+
+	defer func() {
+		logFields := log.Fields{
+			"handler":   "SetNamespacePolicy",
+			"namespace": namespace,
+		}
+		if response.Error != "" {
+			log.WithFields(logFields).Error(response.Error)
+		} else {
+			log.WithFields(logFields).Info("Set namespace policy.")
+		}
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			panic(err)
+		}
+	}()
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body,
+		config.MaxRESTRequestSize))
+	if err == nil {
+		err = r.Body.Close()
+	}
+	if err != nil {
+		response.Error = err.Error()
+		status = http.StatusBadRequest
+		return
+	}
+	policyConfig := new(namespace_policy.Config)
+	if err = json.Unmarshal(body, policyConfig); err != nil {
+		response.Error = fmt.Sprintf("Invalid JSON: %v", err)
+		status = http.StatusBadRequest
+		return
+	}
+	if policyConfig.Namespace == "" {
+		policyConfig.Namespace = namespace
+	} else if policyConfig.Namespace != namespace {
+		response.Error = fmt.Sprintf("Namespace %s doesn't match %s.",
+			policyConfig.Namespace, namespace)
+		status = http.StatusBadRequest
+		return
+	}
+	response.Policy, err = orchestrator.SetNamespacePolicy(policyConfig)
+	if err != nil {
+		response.Error = err.Error()
+		status = httpStatusForError(err, http.StatusInternalServerError)
+	}
+}
+
+type ListNamespacePoliciesResponse struct {
+	Namespaces []string `json:"namespaces"`
+	Error      string   `json:"error,omitempty"`
+}
+
+func (l *ListNamespacePoliciesResponse) setList(payload []string) {
+	l.Namespaces = payload
+}
+
+func ListNamespacePolicies(w http.ResponseWriter, r *http.Request) {
+	ListGeneric(w, r,
+		&ListNamespacePoliciesResponse{},
+		func() []string {
+			policies := orchestrator.ListNamespacePolicies()
+			namespaces := make([]string, 0, len(policies))
+			for _, p := range policies {
+				namespaces = append(namespaces, p.Namespace)
+			}
+			return namespaces
+		},
+	)
+}
+
+type GetNamespacePolicyResponse struct {
+	Policy *namespace_policy.Config `json:"namespacePolicy"`
+	Error  string                   `json:"error,omitempty"`
+}
+
+func GetNamespacePolicy(w http.ResponseWriter, r *http.Request) {
+	response := &GetNamespacePolicyResponse{}
+	GetGeneric(w, r, "namespace", response,
+		func(namespace string) int {
+			policy := orchestrator.GetNamespacePolicy(namespace)
+			if policy == nil {
+				response.Error = fmt.Sprintf("Namespace policy %s was not "+
+					"found!", namespace)
+				return http.StatusNotFound
+			}
+			response.Policy = policy
+			return http.StatusOK
+		},
+	)
+}
+
+func DeleteNamespacePolicy(w http.ResponseWriter, r *http.Request) {
+	DeleteGeneric(w, r, orchestrator.DeleteNamespacePolicy, "namespace")
+}
+
+type AddHookResponse struct {
+	HookID string `json:"hook"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (a *AddHookResponse) setError(err error) {
+	a.Error = err.Error()
+}
+
+func (a *AddHookResponse) isError() bool {
+	return a.Error != ""
+}
+
+func (a *AddHookResponse) logSuccess() {
+	log.WithFields(log.Fields{
+		"handler": "AddHook",
+		"hook":    a.HookID,
+	}).Info("Added a new hook.")
+}
+
+func (a *AddHookResponse) logFailure() {
+	log.WithFields(log.Fields{
+		"handler": "AddHook",
+		"hook":    a.HookID,
+	}).Error(a.Error)
+}
+
+func AddHook(w http.ResponseWriter, r *http.Request) {
+	response := &AddHookResponse{
+		HookID: "",
+		Error:  "",
+	}
+	AddGeneric(w, r, response,
+		func(body []byte) error {
+			hookConfig := new(hooks.Config)
+			err := json.Unmarshal(body, hookConfig)
+			if err != nil {
+				return fmt.Errorf("Invalid JSON: %v", err)
+			}
+			hook, err := orchestrator.AddHook(hookConfig)
+			if hook != nil {
+				response.HookID = hook.Name
+			}
+			return err
+		},
+	)
+}
+
+type ListHooksResponse struct {
+	Hooks []string `json:"hooks"`
+	Error string   `json:"error,omitempty"`
+}
+
+func (l *ListHooksResponse) setList(payload []string) {
+	l.Hooks = payload
+}
+
+func ListHooks(w http.ResponseWriter, r *http.Request) {
+	ListGeneric(w, r,
+		&ListHooksResponse{},
+		func() []string {
+			hookList := orchestrator.ListHooks()
+			hookNames := make([]string, 0, len(hookList))
+			for _, h := range hookList {
+				hookNames = append(hookNames, h.Name)
+			}
+			return hookNames
+		},
+	)
+}
+
+type GetHookResponse struct {
+	Hook  *hooks.Config `json:"hook"`
+	Error string        `json:"error,omitempty"`
+}
+
+func GetHook(w http.ResponseWriter, r *http.Request) {
+	response := &GetHookResponse{}
+	GetGeneric(w, r, "hook", response,
+		func(hookName string) int {
+			hook := orchestrator.GetHook(hookName)
+			if hook == nil {
+				response.Error = fmt.Sprintf("Hook %s was not found!",
+					hookName)
+				return http.StatusNotFound
+			}
+			response.Hook = hook
+			return http.StatusOK
+		},
+	)
+}
+
+func DeleteHook(w http.ResponseWriter, r *http.Request) {
+	DeleteGeneric(w, r, orchestrator.DeleteHook, "hook")
+}
+
+type AddSnapshotPolicyResponse struct {
+	SnapshotPolicyID string `json:"snapshotPolicy"`
+	Error            string `json:"error,omitempty"`
+}
+
+func (a *AddSnapshotPolicyResponse) setError(err error) {
+	a.Error = err.Error()
+}
+
+func (a *AddSnapshotPolicyResponse) isError() bool {
+	return a.Error != ""
+}
+
+func (a *AddSnapshotPolicyResponse) logSuccess() {
+	log.WithFields(log.Fields{
+		"handler":        "AddSnapshotPolicy",
+		"snapshotPolicy": a.SnapshotPolicyID,
+	}).Info("Added a new snapshot policy.")
+}
+
+func (a *AddSnapshotPolicyResponse) logFailure() {
+	log.WithFields(log.Fields{
+		"handler":        "AddSnapshotPolicy",
+		"snapshotPolicy": a.SnapshotPolicyID,
+	}).Error(a.Error)
+}
+
+func AddSnapshotPolicy(w http.ResponseWriter, r *http.Request) {
+	response := &AddSnapshotPolicyResponse{
+		SnapshotPolicyID: "",
+		Error:            "",
+	}
+	AddGeneric(w, r, response,
+		func(body []byte) error {
+			policyConfig := new(snapshot_policy.Config)
+			err := json.Unmarshal(body, policyConfig)
+			if err != nil {
+				return fmt.Errorf("Invalid JSON: %v", err)
+			}
+			policy, err := orchestrator.AddSnapshotPolicy(policyConfig)
+			if policy != nil {
+				response.SnapshotPolicyID = policy.Name
+			}
+			return err
+		},
+	)
+}
+
+type ListSnapshotPoliciesResponse struct {
+	SnapshotPolicies []string `json:"snapshotPolicies"`
+	Error            string   `json:"error,omitempty"`
+}
+
+func (l *ListSnapshotPoliciesResponse) setList(payload []string) {
+	l.SnapshotPolicies = payload
+}
+
+func ListSnapshotPolicies(w http.ResponseWriter, r *http.Request) {
+	ListGeneric(w, r,
+		&ListSnapshotPoliciesResponse{},
+		func() []string {
+			policyList := orchestrator.ListSnapshotPolicies()
+			policyNames := make([]string, 0, len(policyList))
+			for _, p := range policyList {
+				policyNames = append(policyNames, p.Name)
+			}
+			return policyNames
+		},
+	)
+}
+
+type GetSnapshotPolicyResponse struct {
+	SnapshotPolicy *snapshot_policy.Config `json:"snapshotPolicy"`
+	Error          string                  `json:"error,omitempty"`
+}
+
+func GetSnapshotPolicy(w http.ResponseWriter, r *http.Request) {
+	response := &GetSnapshotPolicyResponse{}
+	GetGeneric(w, r, "snapshotPolicy", response,
+		func(policyName string) int {
+			policy := orchestrator.GetSnapshotPolicy(policyName)
+			if policy == nil {
+				response.Error = fmt.Sprintf("Snapshot policy %s was not "+
+					"found!", policyName)
+				return http.StatusNotFound
+			}
+			response.SnapshotPolicy = policy
+			return http.StatusOK
+		},
+	)
+}
+
+func DeleteSnapshotPolicy(w http.ResponseWriter, r *http.Request) {
+	DeleteGeneric(w, r, orchestrator.DeleteSnapshotPolicy, "snapshotPolicy")
+}
+
+type ListQuarantinedRecordsResponse struct {
+	Records []*persistent_store.QuarantinedRecord `json:"records"`
+	Error   string                                `json:"error,omitempty"`
+}
+
+// ListQuarantinedRecords returns the stored records that were set aside
+// because they couldn't be bootstrapped.
+func ListQuarantinedRecords(w http.ResponseWriter, r *http.Request) {
+	response := &ListQuarantinedRecordsResponse{}
+	status := http.StatusOK
+	records, err := orchestrator.ListQuarantinedRecords()
+	if err != nil {
+		response.Error = err.Error()
+		status = http.StatusInternalServerError
+	} else {
+		response.Records = records
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	if err = encodeResponse(w, r, response); err != nil {
+		panic(err)
+	}
+}
+
+type ListPendingTransactionsResponse struct {
+	Transactions []*core.PendingTransaction `json:"transactions"`
+	Error        string                     `json:"error,omitempty"`
+}
+
+// ListPendingTransactions returns the volume transactions left by
+// operations that haven't completed or been rolled back.
+func ListPendingTransactions(w http.ResponseWriter, r *http.Request) {
+	response := &ListPendingTransactionsResponse{}
+	status := http.StatusOK
+	txns, err := orchestrator.ListPendingTransactions()
+	if err != nil {
+		response.Error = err.Error()
+		status = http.StatusInternalServerError
+	} else {
+		response.Transactions = txns
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	if err = encodeResponse(w, r, response); err != nil {
+		panic(err)
+	}
+}
+
+type ListZombieVolumesResponse struct {
+	Volumes []*persistent_store.ZombieVolume `json:"volumes"`
+	Error   string                           `json:"error,omitempty"`
+}
+
+// ListZombieVolumes returns the volumes left on their backends by failed
+// operations, which Trident is still trying to delete.
+func ListZombieVolumes(w http.ResponseWriter, r *http.Request) {
+	response := &ListZombieVolumesResponse{}
+	status := http.StatusOK
+	zombies, err := orchestrator.ListZombieVolumes()
+	if err != nil {
+		response.Error = err.Error()
+		status = http.StatusInternalServerError
+	} else {
+		response.Volumes = zombies
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	if err = encodeResponse(w, r, response); err != nil {
+		panic(err)
+	}
+}
+
+type GetRebalanceReportResponse struct {
+	Report *core.RebalanceReport `json:"report"`
+}
+
+// GetRebalanceReport returns the latest analysis of how each storage
+// class's volumes are spread across its backends, with recommended moves.
+func GetRebalanceReport(w http.ResponseWriter, r *http.Request) {
+	response := &GetRebalanceReportResponse{
+		Report: orchestrator.GetRebalanceReport(),
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := encodeResponse(w, r, response); err != nil {
+		panic(err)
+	}
+}
+
+type LoggingConfigResponse struct {
+	Logging *logging.Config `json:"logging,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+func writeLoggingConfigResponse(
+	w http.ResponseWriter, r *http.Request, response *LoggingConfigResponse,
+	status int,
+) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	if err := encodeResponse(w, r, response); err != nil {
+		panic(err)
+	}
+}
+
+func GetLoggingConfig(w http.ResponseWriter, r *http.Request) {
+	writeLoggingConfigResponse(w, r, &LoggingConfigResponse{
+		Logging: orchestrator.GetLoggingConfig(),
+	}, http.StatusOK)
+}
+
+// SetLoggingConfigRequest holds the logging settings to change; those that
+// are absent are left unchanged.  SubsystemLevels, if present, replaces all
+// of the subsystem levels, so an empty object clears them.
+type SetLoggingConfigRequest struct {
+	Level           *string           `json:"level"`
+	SubsystemLevels map[string]string `json:"subsystemLevels"`
+	Format          *string           `json:"format"`
+	BufferSize      *int              `json:"bufferSize"`
+}
+
+// SetLoggingConfig changes the log levels and format at runtime.
+func SetLoggingConfig(w http.ResponseWriter, r *http.Request) {
+	response := &LoggingConfigResponse{}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, config.MaxRESTRequestSize))
+	if err == nil {
+		err = r.Body.Close()
+	}
+	if err != nil {
+		response.Error = err.Error()
+		writeLoggingConfigResponse(w, r, response, http.StatusBadRequest)
+		return
+	}
+	request := &SetLoggingConfigRequest{}
+	if err = json.Unmarshal(body, request); err != nil {
+		response.Error = "Invalid JSON: " + err.Error()
+		writeLoggingConfigResponse(w, r, response, http.StatusBadRequest)
+		return
+	}
+	loggingConfig := orchestrator.GetLoggingConfig()
+	if request.Level != nil {
+		loggingConfig.Level = *request.Level
+	}
+	if request.SubsystemLevels != nil {
+		loggingConfig.SubsystemLevels = request.SubsystemLevels
+	}
+	if request.Format != nil {
+		loggingConfig.Format = *request.Format
+	}
+	if request.BufferSize != nil {
+		loggingConfig.BufferSize = *request.BufferSize
+	}
+	if err = orchestrator.SetLoggingConfig(loggingConfig); err != nil {
+		response.Error = err.Error()
+		writeLoggingConfigResponse(w, r, response, http.StatusBadRequest)
+		return
+	}
+	response.Logging = orchestrator.GetLoggingConfig()
+	writeLoggingConfigResponse(w, r, response, http.StatusOK)
+}
+
+type ListLogsResponse struct {
+	Entries []*logging.Entry `json:"entries"`
+	Error   string           `json:"error,omitempty"`
+}
+
+// ListLogs returns the most recent log entries kept in memory, optionally
+// filtered by level and subsystem.
+func ListLogs(w http.ResponseWriter, r *http.Request) {
+	response := &ListLogsResponse{}
+	status := http.StatusOK
+	query := r.URL.Query()
+	filter := &logging.EntryFilter{
+		Level:     query.Get("level"),
+		Subsystem: query.Get("subsystem"),
+	}
+	var err error
+	if l := query.Get("limit"); l != "" {
+		if filter.Limit, err = strconv.Atoi(l); err != nil {
+			err = fmt.Errorf("Invalid limit %q.", l)
+		}
+	}
+	if err == nil {
+		response.Entries, err = logging.RecentEntries(filter)
+	}
+	if err != nil {
+		response.Error = err.Error()
+		status = http.StatusBadRequest
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	if err = encodeResponse(w, r, response); err != nil {
+		panic(err)
+	}
 }
@@ -0,0 +1,657 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package rest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+	"golang.org/x/net/context"
+
+	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/errors"
+	"github.com/netapp/trident/storage"
+	"github.com/netapp/trident/storage/factory"
+	"github.com/netapp/trident/storage_class"
+)
+
+// Error codes reported by the v2 API.
+const (
+	ErrorCodeInvalidJSON     = "InvalidJSON"
+	ErrorCodeInvalidInput    = "InvalidInput"
+	ErrorCodeNotFound        = "NotFound"
+	ErrorCodeAlreadyExists   = "AlreadyExists"
+	ErrorCodeConflict        = "Conflict"
+	ErrorCodeOperationFailed = "OperationFailed"
+	ErrorCodeInternal        = "InternalError"
+	ErrorCodeRateLimited     = "RateLimited"
+	ErrorCodeUnavailable     = "Unavailable"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ErrorV2 is the structured error body returned by every v2 handler.
+type ErrorV2 struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields,omitempty"`
+	// Details explain the error further, e.g., why no storage pool could
+	// hold a volume.
+	Details interface{} `json:"details,omitempty"`
+}
+
+func newErrorV2(code string, err error) *ErrorV2 {
+	return &ErrorV2{
+		Code:    code,
+		Message: err.Error(),
+		Details: errors.GetDetails(err),
+	}
+}
+
+// errorV2ForError reports an error returned by the orchestrator with the
+// code for its type, or with defaultCode if it has no type.
+func errorV2ForError(err error, defaultCode string) *ErrorV2 {
+	code := defaultCode
+	switch errors.GetType(err) {
+	case errors.NotFound:
+		code = ErrorCodeNotFound
+	case errors.AlreadyExists:
+		code = ErrorCodeAlreadyExists
+	case errors.InvalidInput:
+		code = ErrorCodeInvalidInput
+	case errors.BackendUnavailable:
+		code = ErrorCodeUnavailable
+	case errors.Conflict:
+		code = ErrorCodeConflict
+	}
+	return newErrorV2(code, err)
+}
+
+// httpStatusForErrorCode returns the HTTP status that reports an error
+// code, or defaultStatus for codes without a more specific status.
+func httpStatusForErrorCode(code string, defaultStatus int) int {
+	switch code {
+	case ErrorCodeInvalidJSON, ErrorCodeInvalidInput:
+		return http.StatusBadRequest
+	case ErrorCodeNotFound:
+		return http.StatusNotFound
+	case ErrorCodeAlreadyExists, ErrorCodeConflict:
+		return http.StatusConflict
+	case ErrorCodeUnavailable:
+		return http.StatusServiceUnavailable
+	case ErrorCodeInternal:
+		return http.StatusInternalServerError
+	}
+	return defaultStatus
+}
+
+// Pagination describes the page of results returned by a v2 list call.
+// Continue is an opaque token that, when passed back via the "continue"
+// query parameter, returns the next page; it is empty on the last page.
+type Pagination struct {
+	Total    int    `json:"total"`
+	Continue string `json:"continue,omitempty"`
+}
+
+type ListResponseV2 struct {
+	Items      []string    `json:"items"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+	Error      *ErrorV2    `json:"error,omitempty"`
+}
+
+type DeleteResponseV2 struct {
+	Error *ErrorV2 `json:"error,omitempty"`
+}
+
+// writeResponseV2 writes a v2 response, with only the fields the request
+// selects, if any.
+func writeResponseV2(
+	w http.ResponseWriter, r *http.Request, status int, response interface{},
+) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	if err := encodeResponse(w, r, response); err != nil {
+		panic(err)
+	}
+}
+
+// paginate sorts names and returns the page selected by the "limit" and
+// "continue" query parameters.  A limit of zero (the default) returns
+// everything after the continue token.  The token encodes the last name
+// returned, rather than an offset, so that a page starts in the right
+// place even if objects were added or deleted since the previous one.
+func paginate(r *http.Request, names []string) ([]string, *Pagination, *ErrorV2) {
+	var (
+		limit       int
+		after       string
+		err         error
+		fieldErrors []FieldError
+	)
+	query := r.URL.Query()
+	if l := query.Get("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil || limit < 0 {
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:   "limit",
+				Message: "must be a non-negative integer",
+			})
+		}
+	}
+	if c := query.Get("continue"); c != "" {
+		if after, err = decodeContinueToken(c); err != nil {
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:   "continue",
+				Message: "invalid continue token",
+			})
+		}
+	}
+	if len(fieldErrors) > 0 {
+		return nil, nil, &ErrorV2{
+			Code:    ErrorCodeInvalidInput,
+			Message: "Invalid pagination parameters.",
+			Fields:  fieldErrors,
+		}
+	}
+
+	sort.Strings(names)
+	page := &Pagination{Total: len(names)}
+	start := 0
+	if after != "" {
+		start = sort.SearchStrings(names, after)
+		if start < len(names) && names[start] == after {
+			start++
+		}
+	}
+	end := len(names)
+	if limit > 0 && start+limit < end {
+		end = start + limit
+		page.Continue = encodeContinueToken(names[end-1])
+	}
+	return names[start:end], page, nil
+}
+
+// encodeContinueToken returns the continue token for the page after name.
+func encodeContinueToken(name string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(name))
+}
+
+// decodeContinueToken returns the name a continue token follows.
+func decodeContinueToken(token string) (string, error) {
+	name, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", err
+	}
+	if len(name) == 0 {
+		return "", fmt.Errorf("Empty continue token.")
+	}
+	return string(name), nil
+}
+
+func ListGenericV2(
+	w http.ResponseWriter,
+	r *http.Request,
+	lister func() []string,
+) {
+	response := &ListResponseV2{Items: make([]string, 0)}
+	items, page, errV2 := paginate(r, lister())
+	if errV2 != nil {
+		response.Error = errV2
+		writeResponseV2(w, r, http.StatusBadRequest, response)
+		return
+	}
+	response.Items = items
+	response.Pagination = page
+	writeResponseV2(w, r, http.StatusOK, response)
+}
+
+// GetGenericV2 looks up the object named by varName.  get returns nil if
+// the object doesn't exist.
+func GetGenericV2(
+	w http.ResponseWriter,
+	r *http.Request,
+	varName string,
+	objectType string,
+	get func(string) interface{},
+) {
+	name := mux.Vars(r)[varName]
+	object := get(name)
+	if object == nil {
+		writeResponseV2(w, r, http.StatusNotFound, map[string]interface{}{
+			"error": &ErrorV2{
+				Code:    ErrorCodeNotFound,
+				Message: fmt.Sprintf("%s %s was not found.", objectType, name),
+			},
+		})
+		return
+	}
+	writeResponseV2(w, r, http.StatusOK, map[string]interface{}{
+		varName: object,
+	})
+}
+
+// AddGenericV2 reads the request body and hands it to add, which returns
+// the name of the created object and any warnings about it, or a structured
+// error.
+func AddGenericV2(
+	w http.ResponseWriter,
+	r *http.Request,
+	varName string,
+	add func([]byte) (string, []string, *ErrorV2),
+) {
+	var (
+		name     string
+		warnings []string
+	)
+	body, errV2 := readBodyV2(r)
+	if errV2 == nil {
+		name, warnings, errV2 = add(body)
+	}
+	writeAddResponseV2(w, r, varName, name, warnings, errV2)
+}
+
+// readBodyV2 reads a request's body, up to the maximum request size.
+func readBodyV2(r *http.Request) ([]byte, *ErrorV2) {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, config.MaxRESTRequestSize))
+	if err == nil {
+		err = r.Body.Close()
+	}
+	if err != nil {
+		return nil, newErrorV2(ErrorCodeInternal, err)
+	}
+	return body, nil
+}
+
+// writeAddResponseV2 reports the object an add created, with any warnings
+// about it, or the error that stopped it from being created.
+func writeAddResponseV2(
+	w http.ResponseWriter, r *http.Request, varName, name string,
+	warnings []string, errV2 *ErrorV2,
+) {
+	if errV2 != nil {
+		log.WithFields(log.Fields{
+			"handler": "Add" + varName,
+			"code":    errV2.Code,
+		}).Error(errV2.Message)
+		status := httpStatusForErrorCode(errV2.Code, http.StatusBadRequest)
+		writeResponseV2(w, r, status, map[string]interface{}{"error": errV2})
+		return
+	}
+	log.WithFields(log.Fields{
+		"handler": "Add" + varName,
+		varName:   name,
+	}).Info("Created a new object.")
+	response := map[string]interface{}{varName: name}
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+	writeResponseV2(w, r, http.StatusCreated, response)
+}
+
+func DeleteGenericV2(
+	w http.ResponseWriter, r *http.Request, d deleteFunc, varName string,
+) {
+	response := &DeleteResponseV2{}
+	found, err := d(mux.Vars(r)[varName])
+	status := http.StatusOK
+	if err != nil {
+		code := ErrorCodeOperationFailed
+		if !found {
+			code = ErrorCodeNotFound
+		}
+		response.Error = errorV2ForError(err, code)
+		status = httpStatusForErrorCode(response.Error.Code,
+			http.StatusInternalServerError)
+	}
+	writeResponseV2(w, r, status, response)
+}
+
+func GetVersionV2(w http.ResponseWriter, r *http.Request) {
+	writeResponseV2(w, r, http.StatusOK, map[string]string{
+		"version":    orchestrator.GetVersion(),
+		"apiVersion": config.OrchestratorAPIVersionV2,
+	})
+}
+
+func ListBackendsV2(w http.ResponseWriter, r *http.Request) {
+	ListGenericV2(w, r, listBackendNames)
+}
+
+func GetBackendV2(w http.ResponseWriter, r *http.Request) {
+	GetGenericV2(w, r, "backend", "Backend",
+		func(name string) interface{} {
+			if backend := orchestrator.GetBackend(name); backend != nil {
+				return backend
+			}
+			return nil
+		},
+	)
+}
+
+// AddBackendV2 adds the backend whose config is the request body, or, if
+// the body holds a list of configs, each of those backends.
+func AddBackendV2(w http.ResponseWriter, r *http.Request) {
+	body, errV2 := readBodyV2(r)
+	if errV2 != nil {
+		writeAddResponseV2(w, r, "backend", "", nil, errV2)
+		return
+	}
+	configs, multiple, err := factory.SplitBackendConfigs(body)
+	if err != nil {
+		writeAddResponseV2(w, r, "backend", "", nil,
+			newErrorV2(ErrorCodeInvalidJSON, err))
+		return
+	}
+	ctx, cancel := requestContext(w, r)
+	defer cancel()
+	if multiple {
+		addBackendsV2(ctx, w, r, configs)
+		return
+	}
+	var backendConfig map[string]interface{}
+	if err = json.Unmarshal([]byte(configs[0]), &backendConfig); err != nil {
+		writeAddResponseV2(w, r, "backend", "", nil,
+			newErrorV2(ErrorCodeInvalidJSON, err))
+		return
+	}
+	backend, err := orchestrator.AddStorageBackend(ctx, configs[0])
+	if err != nil {
+		writeAddResponseV2(w, r, "backend", "", nil,
+			errorV2ForError(err, ErrorCodeOperationFailed))
+		return
+	}
+	writeAddResponseV2(w, r, "backend", backend.Name, backend.Warnings, nil)
+}
+
+// addBackendsV2 adds several backends, reporting the result of each.  The
+// request only fails if none of them are added.
+func addBackendsV2(
+	ctx context.Context, w http.ResponseWriter, r *http.Request,
+	configs []string,
+) {
+	result := orchestrator.AddStorageBackends(ctx, configs)
+	items := make([]map[string]interface{}, 0, len(result.Backends))
+	for _, b := range result.Backends {
+		item := map[string]interface{}{"index": b.Index}
+		if b.Err != nil {
+			item["error"] = errorV2ForError(b.Err, ErrorCodeOperationFailed)
+		} else {
+			item["backend"] = b.Backend.Name
+			if len(b.Backend.Warnings) > 0 {
+				item["warnings"] = b.Backend.Warnings
+			}
+		}
+		items = append(items, item)
+	}
+	response := map[string]interface{}{"backends": items}
+	status := http.StatusCreated
+	if err := result.AllFailed(); err != nil {
+		errV2 := newErrorV2(ErrorCodeOperationFailed, err)
+		response["error"] = errV2
+		status = httpStatusForErrorCode(errV2.Code, http.StatusBadRequest)
+	}
+	log.WithFields(log.Fields{
+		"handler":  "Addbackend",
+		"backends": len(configs),
+		"failed":   result.Failed,
+	}).Info("Added backends.")
+	writeResponseV2(w, r, status, response)
+}
+
+// DeleteBackendV2 offlines a backend, reporting the storage classes and
+// volumes affected.  A backend whose volumes would be stranded is only
+// offlined if the confirm query parameter is set; otherwise the Conflict
+// error's details are the report.
+func DeleteBackendV2(w http.ResponseWriter, r *http.Request) {
+	confirm, _ := strconv.ParseBool(r.URL.Query().Get("confirm"))
+	report, err := orchestrator.OfflineBackendWithReport(
+		mux.Vars(r)["backend"], confirm)
+	if err != nil {
+		errV2 := errorV2ForError(err, ErrorCodeOperationFailed)
+		writeResponseV2(w, r, httpStatusForErrorCode(errV2.Code,
+			http.StatusInternalServerError), &DeleteResponseV2{Error: errV2})
+		return
+	}
+	writeResponseV2(w, r, http.StatusOK, map[string]interface{}{
+		"report": report,
+	})
+}
+
+// PreviewOfflineBackendV2 reports the storage classes and volumes that
+// deleting a backend would affect, without deleting it.
+func PreviewOfflineBackendV2(w http.ResponseWriter, r *http.Request) {
+	report, err := orchestrator.PreviewOfflineBackend(mux.Vars(r)["backend"])
+	if err != nil {
+		errV2 := errorV2ForError(err, ErrorCodeOperationFailed)
+		writeResponseV2(w, r, httpStatusForErrorCode(errV2.Code,
+			http.StatusInternalServerError), map[string]interface{}{
+			"error": errV2,
+		})
+		return
+	}
+	writeResponseV2(w, r, http.StatusOK, map[string]interface{}{
+		"report": report,
+	})
+}
+
+// validateVolumeConfigV2 reports every problem with a volume config rather
+// than stopping at the first one, as VolumeConfig.Validate does.
+func validateVolumeConfigV2(c *storage.VolumeConfig) []FieldError {
+	fieldErrors := make([]FieldError, 0)
+	if c.Name == "" {
+		fieldErrors = append(fieldErrors,
+			FieldError{Field: "name", Message: "is required"})
+	}
+	// Volumes created from snapshots default to the source's size and
+	// storage class.
+	if c.Size == "" && !c.IsClone() {
+		fieldErrors = append(fieldErrors,
+			FieldError{Field: "size", Message: "is required"})
+	}
+	if c.StorageClass == "" && !c.IsClone() {
+		fieldErrors = append(fieldErrors,
+			FieldError{Field: "storageClass", Message: "is required"})
+	}
+	if c.SourceSnapshot != "" && c.SourceVolume == "" {
+		fieldErrors = append(fieldErrors, FieldError{
+			Field:   "sourceVolume",
+			Message: "is required with sourceSnapshot",
+		})
+	}
+	if c.SourceVolume != "" && c.SourceSnapshot == "" {
+		fieldErrors = append(fieldErrors, FieldError{
+			Field:   "sourceSnapshot",
+			Message: "is required with sourceVolume",
+		})
+	}
+	if err := storage.ValidateQoS(c.MinIOPS, c.MaxIOPS, c.BurstIOPS); err != nil {
+		fieldErrors = append(fieldErrors,
+			FieldError{Field: "iops", Message: err.Error()})
+	}
+	if err := storage.ValidateExpiry(c.TTL, c.ExpiresAt); err != nil {
+		fieldErrors = append(fieldErrors,
+			FieldError{Field: "ttl", Message: err.Error()})
+	}
+	if !config.IsValidProtocol(c.Protocol) {
+		fieldErrors = append(fieldErrors, FieldError{
+			Field:   "protocol",
+			Message: fmt.Sprintf("must be one of %v", config.GetValidProtocolNames()),
+		})
+	}
+	return fieldErrors
+}
+
+func ListVolumesV2(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("stream") == "true" {
+		streamVolumesV2(w, r)
+		return
+	}
+	lister, err := volumeLister(r)
+	if err != nil {
+		writeResponseV2(w, r, http.StatusBadRequest, &ListResponseV2{
+			Items: make([]string, 0),
+			Error: newErrorV2(ErrorCodeInvalidInput, err),
+		})
+		return
+	}
+	ListGenericV2(w, r, lister)
+}
+
+// streamFlushInterval is how many volumes are written to a volume stream
+// between flushes.
+const streamFlushInterval = 100
+
+// streamVolumesV2 writes the volumes, in full, as newline-delimited JSON,
+// one volume per line, for clients that would otherwise have to get each
+// volume listed.  The volumes are written as they are read, in no
+// particular order, so that tens of thousands of them needn't be held in
+// memory at once; pagination doesn't apply.
+func streamVolumesV2(w http.ResponseWriter, r *http.Request) {
+	selector, err := volumeSelector(r)
+	if err != nil {
+		writeResponseV2(w, r, http.StatusBadRequest, &ListResponseV2{
+			Items: make([]string, 0),
+			Error: newErrorV2(ErrorCodeInvalidInput, err),
+		})
+		return
+	}
+	query := r.URL.Query()
+	if query.Get("limit") != "" || query.Get("continue") != "" {
+		writeResponseV2(w, r, http.StatusBadRequest, &ListResponseV2{
+			Items: make([]string, 0),
+			Error: &ErrorV2{
+				Code:    ErrorCodeInvalidInput,
+				Message: "Streamed lists aren't paginated.",
+			},
+		})
+		return
+	}
+	var selection fieldSelection
+	if fields := query.Get("fields"); fields != "" {
+		// The fields were checked before the handler was called.
+		selection, _ = parseFields(fields)
+	}
+	flusher, _ := w.(http.Flusher)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+	written := 0
+	orchestrator.WalkVolumes(func(volume *storage.VolumeExternal) bool {
+		if selector != nil && !volume.Config.MatchesMetadata(selector) {
+			return true
+		}
+		var line interface{} = volume
+		if selection != nil {
+			line = selectFields(volume, selection)
+		}
+		// Encode ends each volume with a newline.
+		if err := encoder.Encode(line); err != nil {
+			log.WithFields(log.Fields{
+				"handler": "ListVolumesV2",
+				"volume":  volume.Config.Name,
+			}).Debugf("Stopped streaming volumes:  %v", err)
+			return false
+		}
+		written++
+		if flusher != nil && written%streamFlushInterval == 0 {
+			flusher.Flush()
+		}
+		return true
+	})
+}
+
+func GetVolumeV2(w http.ResponseWriter, r *http.Request) {
+	GetGenericV2(w, r, "volume", "Volume",
+		func(name string) interface{} {
+			if volume := orchestrator.GetVolume(name); volume != nil {
+				return volume
+			}
+			return nil
+		},
+	)
+}
+
+func AddVolumeV2(w http.ResponseWriter, r *http.Request) {
+	AddGenericV2(w, r, "volume",
+		func(body []byte) (string, []string, *ErrorV2) {
+			volumeConfig := new(storage.VolumeConfig)
+			if err := json.Unmarshal(body, volumeConfig); err != nil {
+				return "", nil, newErrorV2(ErrorCodeInvalidJSON, err)
+			}
+			if fieldErrors := validateVolumeConfigV2(volumeConfig); len(fieldErrors) > 0 {
+				return "", nil, &ErrorV2{
+					Code:    ErrorCodeInvalidInput,
+					Message: "Invalid volume configuration.",
+					Fields:  fieldErrors,
+				}
+			}
+			ctx, cancel := requestContext(w, r)
+			defer cancel()
+			volume, err := orchestrator.AddVolume(ctx, volumeConfig)
+			if err != nil {
+				return "", nil, errorV2ForError(err,
+					ErrorCodeOperationFailed)
+			}
+			return volume.Config.Name, volume.Warnings, nil
+		},
+	)
+}
+
+func DeleteVolumeV2(w http.ResponseWriter, r *http.Request) {
+	DeleteGenericV2(w, r, volumeDeleteFunc(w, r), "volume")
+}
+
+func ListStorageClassesV2(w http.ResponseWriter, r *http.Request) {
+	ListGenericV2(w, r, listStorageClassNames)
+}
+
+func GetStorageClassV2(w http.ResponseWriter, r *http.Request) {
+	GetGenericV2(w, r, "storageClass", "Storage class",
+		func(name string) interface{} {
+			if sc := orchestrator.GetStorageClass(name); sc != nil {
+				return sc
+			}
+			return nil
+		},
+	)
+}
+
+func AddStorageClassV2(w http.ResponseWriter, r *http.Request) {
+	AddGenericV2(w, r, "storageClass",
+		func(body []byte) (string, []string, *ErrorV2) {
+			scConfig := new(storage_class.Config)
+			if err := json.Unmarshal(body, scConfig); err != nil {
+				return "", nil, newErrorV2(ErrorCodeInvalidJSON, err)
+			}
+			if scConfig.Name == "" {
+				return "", nil, &ErrorV2{
+					Code:    ErrorCodeInvalidInput,
+					Message: "Invalid storage class configuration.",
+					Fields: []FieldError{
+						{Field: "name", Message: "is required"},
+					},
+				}
+			}
+			sc, err := orchestrator.AddStorageClass(scConfig)
+			if err != nil {
+				return "", nil, errorV2ForError(err,
+					ErrorCodeOperationFailed)
+			}
+			return sc.GetName(), sc.Warnings, nil
+		},
+	)
+}
+
+func DeleteStorageClassV2(w http.ResponseWriter, r *http.Request) {
+	DeleteGenericV2(w, r, storageClassDeleteFunc(r), "storageClass")
+}
@@ -0,0 +1,100 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package rest
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func paginateQuery(
+	t *testing.T, names []string, query url.Values,
+) ([]string, *Pagination, *ErrorV2) {
+	r, err := http.NewRequest("GET", "/trident/v2/volume?"+query.Encode(),
+		nil)
+	if err != nil {
+		t.Fatal("Unable to create request:  ", err)
+	}
+	return paginate(r, append([]string(nil), names...))
+}
+
+func TestPaginate(t *testing.T) {
+	names := []string{"e", "a", "d", "c", "b"}
+	for _, test := range []struct {
+		name     string
+		query    url.Values
+		expected []string
+		more     bool
+	}{
+		{"All", url.Values{}, []string{"a", "b", "c", "d", "e"}, false},
+		{"NoLimit", url.Values{"limit": {"0"}},
+			[]string{"a", "b", "c", "d", "e"}, false},
+		{"FirstPage", url.Values{"limit": {"2"}}, []string{"a", "b"}, true},
+		{"ExactPage", url.Values{"limit": {"5"}},
+			[]string{"a", "b", "c", "d", "e"}, false},
+		{"LimitPastEnd", url.Values{"limit": {"10"}},
+			[]string{"a", "b", "c", "d", "e"}, false},
+		{"Continue", url.Values{"limit": {"2"},
+			"continue": {encodeContinueToken("b")}}, []string{"c", "d"},
+			true},
+		{"LastPage", url.Values{"limit": {"2"},
+			"continue": {encodeContinueToken("d")}}, []string{"e"}, false},
+		{"PastEnd", url.Values{"continue": {encodeContinueToken("z")}},
+			[]string{}, false},
+		// The page after a deleted name starts where the name was.
+		{"DeletedName", url.Values{"limit": {"2"},
+			"continue": {encodeContinueToken("bb")}}, []string{"c", "d"},
+			true},
+	} {
+		items, page, errV2 := paginateQuery(t, names, test.query)
+		if errV2 != nil {
+			t.Errorf("%s:  unexpected error %v.", test.name, errV2)
+			continue
+		}
+		if !reflect.DeepEqual(items, test.expected) {
+			t.Errorf("%s:  expected %v; got %v.", test.name, test.expected,
+				items)
+		}
+		if page.Total != len(names) {
+			t.Errorf("%s:  expected a total of %d; got %d.", test.name,
+				len(names), page.Total)
+		}
+		if more := page.Continue != ""; more != test.more {
+			t.Errorf("%s:  expected more pages %t; got token %q.",
+				test.name, test.more, page.Continue)
+		}
+	}
+
+	for _, query := range []url.Values{
+		{"limit": {"-1"}},
+		{"limit": {"two"}},
+		{"continue": {"not base64!"}},
+		{"limit": {"-1"}, "continue": {"%%%"}},
+	} {
+		if _, _, errV2 := paginateQuery(t, names, query); errV2 == nil {
+			t.Errorf("Expected query %v to be rejected.", query)
+		} else if errV2.Code != ErrorCodeInvalidInput ||
+			len(errV2.Fields) != len(query) {
+			t.Errorf("Unexpected error for query %v:  %+v", query, errV2)
+		}
+	}
+}
+
+// TestPaginateDeletion checks that deleting a returned name doesn't skip or
+// repeat names on the next page.
+func TestPaginateDeletion(t *testing.T) {
+	names := []string{"a", "b", "c", "d"}
+	items, page, _ := paginateQuery(t, names, url.Values{"limit": {"2"}})
+	if !reflect.DeepEqual(items, []string{"a", "b"}) {
+		t.Fatalf("Unexpected first page %v.", items)
+	}
+	items, _, _ = paginateQuery(t, []string{"b", "c", "d"}, url.Values{
+		"limit":    {"2"},
+		"continue": {page.Continue},
+	})
+	if !reflect.DeepEqual(items, []string{"c", "d"}) {
+		t.Errorf("Expected the second page to be [c d]; got %v.", items)
+	}
+}
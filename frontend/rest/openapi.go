@@ -0,0 +1,568 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package rest
+
+import (
+	"encoding/json"
+	"go/ast"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/netapp/trident/app_template"
+	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/core"
+	"github.com/netapp/trident/frontend"
+	"github.com/netapp/trident/hooks"
+	"github.com/netapp/trident/namespace_policy"
+	"github.com/netapp/trident/snapshot_policy"
+	"github.com/netapp/trident/storage"
+	"github.com/netapp/trident/storage_class"
+)
+
+// routeBody describes a route's request and response bodies with values of
+// their types; the OpenAPI document's schemas are generated from the types,
+// so that it stays in line with the handlers.
+type routeBody struct {
+	// Request is nil if the route takes no body.
+	Request  interface{}
+	Response interface{}
+	// Status is the status of a successful response; it defaults to 200.
+	Status int
+	// Query names the route's query parameters.
+	Query []string
+	// Events is true if the route streams server-sent events, each holding
+	// a Response.
+	Events bool
+	// Archive is true if the route returns a gzipped tar archive.
+	Archive bool
+}
+
+// The v2 handlers build their bodies as maps; these types describe them.
+type (
+	errorResponseV2 struct {
+		Error *ErrorV2 `json:"error"`
+	}
+	versionResponseV2 struct {
+		Version    string `json:"version"`
+		APIVersion string `json:"apiVersion"`
+	}
+	// Requests holding a list of backend configs report each backend in
+	// backends, rather than backend and warnings.
+	addBackendResponseV2 struct {
+		Backend  string             `json:"backend,omitempty"`
+		Warnings []string           `json:"warnings,omitempty"`
+		Backends []addBackendItemV2 `json:"backends,omitempty"`
+		Error    *ErrorV2           `json:"error,omitempty"`
+	}
+	addBackendItemV2 struct {
+		Index    int      `json:"index"`
+		Backend  string   `json:"backend,omitempty"`
+		Warnings []string `json:"warnings,omitempty"`
+		Error    *ErrorV2 `json:"error,omitempty"`
+	}
+	getBackendResponseV2 struct {
+		Backend *storage.StorageBackendExternal `json:"backend"`
+	}
+	// A Conflict error's details are the report of the volumes that would
+	// be stranded.
+	offlineBackendResponseV2 struct {
+		Report *core.OfflineBackendReport `json:"report,omitempty"`
+		Error  *ErrorV2                   `json:"error,omitempty"`
+	}
+	addVolumeResponseV2 struct {
+		Volume   string   `json:"volume"`
+		Warnings []string `json:"warnings,omitempty"`
+	}
+	getVolumeResponseV2 struct {
+		Volume *storage.VolumeExternal `json:"volume"`
+	}
+	addStorageClassResponseV2 struct {
+		StorageClass string   `json:"storageClass"`
+		Warnings     []string `json:"warnings,omitempty"`
+	}
+	getStorageClassResponseV2 struct {
+		StorageClass *storage_class.StorageClassExternal `json:"storageClass"`
+	}
+)
+
+var (
+	paginationQuery = []string{"limit", "continue"}
+	// rawJSON stands for bodies that are passed on as they are, such as
+	// backend configurations.
+	rawJSON = json.RawMessage{}
+)
+
+// routeBodies describes the bodies of each route, by route name.
+var routeBodies = map[string]routeBody{
+	"GetHealth":      {Response: core.HealthStatus{}},
+	"GetReadiness":   {Response: core.HealthStatus{}},
+	"GetAPIDocument": {Response: rawJSON},
+	"GetVersion":     {Response: GetVersionResponse{}},
+	"AddBackend": {
+		Request:  rawJSON,
+		Response: AddBackendResponse{},
+		Status:   http.StatusCreated,
+	},
+	"ValidateBackend": {
+		Request:  rawJSON,
+		Response: ValidateBackendResponse{},
+	},
+	"GetBackend":   {Response: GetBackendResponse{}},
+	"ListBackends": {Response: ListBackendsResponse{}},
+	"UpdateBackend": {
+		Request:  rawJSON,
+		Response: UpdateBackendResponse{},
+	},
+	"DiffBackend": {
+		Request:  rawJSON,
+		Response: DiffBackendResponse{},
+	},
+	"DeleteBackend": {
+		Response: OfflineBackendResponse{},
+		Query:    []string{"confirm"},
+	},
+	"RenameBackend": {
+		Request:  RenameBackendRequest{},
+		Response: RenameBackendResponse{},
+	},
+	"ListBackendPools":       {Response: ListBackendPoolsResponse{}},
+	"GenerateStorageClasses": {Response: GenerateStorageClassesResponse{}},
+	"FailoverBackend":        {Response: FailoverBackendResponse{}},
+	"OnlineBackend":          {Response: OnlineBackendResponse{}},
+	"PreviewOfflineBackend":  {Response: OfflineBackendResponse{}},
+	"AddVolume": {
+		Request:  storage.VolumeConfig{},
+		Response: AddVolumeResponse{},
+		Status:   http.StatusCreated,
+	},
+	"GetVolume": {Response: GetVolumeResponse{}},
+	"ListVolumes": {
+		Response: ListVolumesResponse{},
+		Query:    []string{"metadata"},
+	},
+	"DeleteVolume": {
+		Response: DeleteResponse{},
+		Query:    []string{"force"},
+	},
+	"DeleteVolumes": {
+		Request:  core.BulkDeleteConfig{},
+		Response: DeleteVolumesResponse{},
+	},
+	"SetVolumeAccess": {
+		Request:  storage.VolumeAccess{},
+		Response: UpdateVolumeResponse{},
+	},
+	"GetVolumeChap": {Response: GetVolumeChapResponse{}},
+	"UpdateVolumeMetadata": {
+		Request:  UpdateVolumeMetadataRequest{},
+		Response: UpdateVolumeResponse{},
+	},
+	"SetVolumeExpiry": {
+		Request:  SetVolumeExpiryRequest{},
+		Response: UpdateVolumeResponse{},
+	},
+	"ReattachVolume": {
+		Request:  ReattachVolumeRequest{},
+		Response: UpdateVolumeResponse{},
+	},
+	"ListRetainedVolumes": {Response: ListRetainedVolumesResponse{}},
+	"ImportRetainedVolume": {
+		Request:  ImportRetainedVolumeRequest{},
+		Response: UpdateVolumeResponse{},
+		Status:   http.StatusCreated,
+	},
+	"DeleteRetainedVolume": {Response: DeleteResponse{}},
+	"CreatePersistentVolume": {
+		Request:  frontend.PersistentVolumeRequest{},
+		Response: CreatePersistentVolumeResponse{},
+		Status:   http.StatusCreated,
+	},
+	"ListProvisioningRetries": {Response: ListProvisioningRetriesResponse{}},
+	"GetProvisioningRetry":    {Response: GetProvisioningRetryResponse{}},
+	"DeleteProvisioningRetry": {Response: DeleteResponse{}},
+	"RetryProvisioningNow":    {Response: GetProvisioningRetryResponse{}},
+	"AbandonProvisioning":     {Response: GetProvisioningRetryResponse{}},
+	"UndeleteVolume":          {Response: UpdateVolumeResponse{}},
+	"RenameVolume": {
+		Request:  RenameVolumeRequest{},
+		Response: UpdateVolumeResponse{},
+	},
+	"SetVolumeFormatted": {
+		Request:  SetVolumeFormattedRequest{},
+		Response: UpdateVolumeResponse{},
+	},
+	"AttachVolume": {
+		Request:  AttachVolumeRequest{},
+		Response: UpdateVolumeResponse{},
+	},
+	"DetachVolume": {Response: UpdateVolumeResponse{}},
+	"EnableReplication": {
+		Request:  core.ReplicationConfig{},
+		Response: UpdateVolumeResponse{},
+	},
+	"DisableReplication": {Response: UpdateVolumeResponse{}},
+	"FailoverVolume":     {Response: UpdateVolumeResponse{}},
+	"AddStorageClass": {
+		Request:  storage_class.Config{},
+		Response: AddStorageClassResponse{},
+		Status:   http.StatusCreated,
+	},
+	"GetStorageClass":    {Response: GetStorageClassResponse{}},
+	"ListStorageClasses": {Response: ListStorageClassesResponse{}},
+	"UpdateStorageClass": {
+		Request:  storage_class.Config{},
+		Response: UpdateStorageClassResponse{},
+	},
+	"DeleteStorageClass": {
+		Response: DeleteResponse{},
+		Query:    []string{"force"},
+	},
+	"GetEvents": {
+		Response: core.Event{},
+		Query:    []string{"objectType"},
+		Events:   true,
+	},
+	"AddHook": {
+		Request:  hooks.Config{},
+		Response: AddHookResponse{},
+		Status:   http.StatusCreated,
+	},
+	"GetHook":    {Response: GetHookResponse{}},
+	"ListHooks":  {Response: ListHooksResponse{}},
+	"DeleteHook": {Response: DeleteResponse{}},
+	"AddSnapshotPolicy": {
+		Request:  snapshot_policy.Config{},
+		Response: AddSnapshotPolicyResponse{},
+		Status:   http.StatusCreated,
+	},
+	"GetSnapshotPolicy":       {Response: GetSnapshotPolicyResponse{}},
+	"ListSnapshotPolicies":    {Response: ListSnapshotPoliciesResponse{}},
+	"DeleteSnapshotPolicy":    {Response: DeleteResponse{}},
+	"ListQuarantinedRecords":  {Response: ListQuarantinedRecordsResponse{}},
+	"ListPendingTransactions": {Response: ListPendingTransactionsResponse{}},
+	"ListZombieVolumes":       {Response: ListZombieVolumesResponse{}},
+	"GetRebalanceReport":      {Response: GetRebalanceReportResponse{}},
+	"GetLoggingConfig":        {Response: LoggingConfigResponse{}},
+	"SetLoggingConfig": {
+		Request:  SetLoggingConfigRequest{},
+		Response: LoggingConfigResponse{},
+	},
+	"ListLogs": {
+		Response: ListLogsResponse{},
+		Query:    []string{"level", "subsystem", "limit"},
+	},
+	"AddVolumeGroup": {
+		Request:  core.VolumeGroupConfig{},
+		Response: AddVolumeGroupResponse{},
+		Status:   http.StatusCreated,
+	},
+	"GetVolumeGroup":    {Response: GetVolumeGroupResponse{}},
+	"ListVolumeGroups":  {Response: ListVolumeGroupsResponse{}},
+	"DeleteVolumeGroup": {Response: DeleteResponse{}},
+	"SnapshotVolumeGroup": {
+		Request:  SnapshotVolumeGroupRequest{},
+		Response: SnapshotVolumeGroupResponse{},
+		Status:   http.StatusCreated,
+	},
+	"AddTemplate": {
+		Request:  app_template.Config{},
+		Response: AddTemplateResponse{},
+		Status:   http.StatusCreated,
+	},
+	"GetTemplate":    {Response: GetTemplateResponse{}},
+	"ListTemplates":  {Response: ListTemplatesResponse{}},
+	"DeleteTemplate": {Response: DeleteResponse{}},
+	"ProvisionTemplate": {
+		Request:  ProvisionTemplateRequest{},
+		Response: ProvisionTemplateResponse{},
+		Status:   http.StatusCreated,
+	},
+	"SetNamespacePolicy": {
+		Request:  namespace_policy.Config{},
+		Response: SetNamespacePolicyResponse{},
+	},
+	"GetNamespacePolicy":    {Response: GetNamespacePolicyResponse{}},
+	"ListNamespacePolicies": {Response: ListNamespacePoliciesResponse{}},
+	"DeleteNamespacePolicy": {Response: DeleteResponse{}},
+	"ApplyState": {
+		Request:  core.DesiredState{},
+		Response: ApplyStateResponse{},
+	},
+	"ExplainPlacement": {
+		Request:  storage.VolumeConfig{},
+		Response: ExplainPlacementResponse{},
+	},
+	"GetSupportBundle": {Archive: true},
+
+	"GetVersionV2": {Response: versionResponseV2{}},
+	"AddBackendV2": {
+		Request:  rawJSON,
+		Response: addBackendResponseV2{},
+		Status:   http.StatusCreated,
+	},
+	"GetBackendV2": {Response: getBackendResponseV2{}},
+	"ListBackendsV2": {
+		Response: ListResponseV2{},
+		Query:    paginationQuery,
+	},
+	"DeleteBackendV2": {
+		Response: offlineBackendResponseV2{},
+		Query:    []string{"confirm"},
+	},
+	"PreviewOfflineBackendV2": {Response: offlineBackendResponseV2{}},
+	"AddVolumeV2": {
+		Request:  storage.VolumeConfig{},
+		Response: addVolumeResponseV2{},
+		Status:   http.StatusCreated,
+	},
+	"GetVolumeV2": {Response: getVolumeResponseV2{}},
+	"ListVolumesV2": {
+		Response: ListResponseV2{},
+		Query:    []string{"metadata", "limit", "continue", "stream"},
+	},
+	"DeleteVolumeV2": {
+		Response: DeleteResponseV2{},
+		Query:    []string{"force"},
+	},
+	"AddStorageClassV2": {
+		Request:  storage_class.Config{},
+		Response: addStorageClassResponseV2{},
+		Status:   http.StatusCreated,
+	},
+	"GetStorageClassV2": {Response: getStorageClassResponseV2{}},
+	"ListStorageClassesV2": {
+		Response: ListResponseV2{},
+		Query:    paginationQuery,
+	},
+	"DeleteStorageClassV2": {
+		Response: DeleteResponseV2{},
+		Query:    []string{"force"},
+	},
+	"GetEventsV2": {
+		Response: core.Event{},
+		Query:    []string{"objectType"},
+		Events:   true,
+	},
+}
+
+var (
+	pathParameter  = regexp.MustCompile(`{([^}]+)}`)
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// apiDocument is the OpenAPI document served at config.APIDocumentURL.  It
+// is generated by NewRouter.
+var apiDocument []byte
+
+// schemaGenerator builds JSON schemas for Go types, as encoding/json
+// encodes them.  Named struct types are added to its definitions and
+// referred to by name.
+type schemaGenerator struct {
+	definitions map[string]interface{}
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) map[string]interface{} {
+	switch t {
+	case timeType:
+		return map[string]interface{}{
+			"type":   "string",
+			"format": "date-time",
+		}
+	case rawMessageType:
+		return map[string]interface{}{"type": "object"}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.schema(t.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8,
+		reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{
+				"type":   "string",
+				"format": "byte",
+			}
+		}
+		return map[string]interface{}{
+			"type":  "array",
+			"items": g.schema(t.Elem()),
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": g.schema(t.Elem()),
+		}
+	case reflect.Struct:
+		// Anonymous and unexported types are described inline.
+		if !ast.IsExported(t.Name()) {
+			return g.structSchema(t)
+		}
+		name := path.Base(t.PkgPath()) + "." + t.Name()
+		if _, ok := g.definitions[name]; !ok {
+			// Claim the name first, in case the type refers to itself.
+			g.definitions[name] = nil
+			g.definitions[name] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/definitions/" + name}
+	}
+	// Interfaces may hold any value.
+	return map[string]interface{}{}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	g.addProperties(t, properties)
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+}
+
+// addProperties adds the schemas of a struct's fields to properties,
+// including those of embedded structs.
+func (g *schemaGenerator) addProperties(
+	t reflect.Type, properties map[string]interface{},
+) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" &&
+			fieldType.Kind() == reflect.Struct {
+			g.addProperties(fieldType, properties)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = g.schema(field.Type)
+	}
+}
+
+// newAPIDocument returns an OpenAPI 2.0 document describing routes.
+func newAPIDocument(routes Routes) ([]byte, error) {
+	g := &schemaGenerator{definitions: make(map[string]interface{})}
+	errorV2 := g.schema(reflect.TypeOf(errorResponseV2{}))
+	paths := make(map[string]map[string]interface{})
+	operationIDs := make(map[string]bool)
+	for _, route := range routes {
+		body := routeBodies[route.Name]
+		operation := make(map[string]interface{})
+		// Routes served at several paths have an ID only at the first.
+		if !operationIDs[route.Name] {
+			operation["operationId"] = route.Name
+			operationIDs[route.Name] = true
+		}
+
+		parameters := make([]interface{}, 0)
+		for _, match := range pathParameter.FindAllStringSubmatch(
+			route.Pattern, -1) {
+			parameters = append(parameters, map[string]interface{}{
+				"name":     match[1],
+				"in":       "path",
+				"required": true,
+				"type":     "string",
+			})
+		}
+		query := body.Query
+		if fieldsSelectable(route) {
+			query = append(append([]string{}, query...), "fields")
+		}
+		for _, name := range query {
+			parameters = append(parameters, map[string]interface{}{
+				"name": name,
+				"in":   "query",
+				"type": "string",
+			})
+		}
+		if body.Request != nil {
+			parameters = append(parameters, map[string]interface{}{
+				"name":     "body",
+				"in":       "body",
+				"required": true,
+				"schema":   g.schema(reflect.TypeOf(body.Request)),
+			})
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+
+		status := body.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := map[string]interface{}{"description": "Success."}
+		failure := map[string]interface{}{"description": "Failure."}
+		if body.Response != nil {
+			success["schema"] = g.schema(reflect.TypeOf(body.Response))
+			// v1 handlers report errors in their usual response.
+			failure["schema"] = success["schema"]
+		}
+		if strings.HasSuffix(route.Name, "V2") {
+			failure["schema"] = errorV2
+		}
+		if body.Events {
+			success["description"] = "A stream of server-sent events, " +
+				"each of which holds the schema as JSON."
+			operation["produces"] = []string{"text/event-stream"}
+		}
+		if body.Archive {
+			success["description"] = "A gzipped tar archive."
+			success["schema"] = map[string]interface{}{"type": "file"}
+			operation["produces"] = []string{"application/gzip"}
+		}
+		operation["responses"] = map[string]interface{}{
+			strconv.Itoa(status): success,
+			"default":            failure,
+		}
+
+		if _, ok := paths[route.Pattern]; !ok {
+			paths[route.Pattern] = make(map[string]interface{})
+		}
+		paths[route.Pattern][strings.ToLower(route.Method)] = operation
+	}
+
+	return json.MarshalIndent(map[string]interface{}{
+		"swagger": "2.0",
+		"info": map[string]string{
+			"title":   "Trident",
+			"version": config.OrchestratorVersion,
+		},
+		"consumes":    []string{"application/json", "application/yaml"},
+		"produces":    []string{"application/json"},
+		"paths":       paths,
+		"definitions": g.definitions,
+	}, "", "  ")
+}
+
+// GetAPIDocument returns the OpenAPI document describing the REST API.
+func GetAPIDocument(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(apiDocument)
+}
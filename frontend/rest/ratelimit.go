@@ -0,0 +1,198 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+)
+
+// provisioningRoutes are the routes counted against
+// RateLimitConfig.MaxInFlightProvisioning.
+var provisioningRoutes = map[string]bool{
+	"AddVolume":         true,
+	"AddVolumeV2":       true,
+	"AddVolumeGroup":    true,
+	"ProvisionTemplate": true,
+}
+
+// RateLimitConfig configures request throttling for the REST frontend.
+// Rates are in requests per second and apply per route name (e.g.,
+// "AddVolume"); a rate of zero leaves the route unlimited.
+type RateLimitConfig struct {
+	DefaultRate float64
+	Burst       int
+	RouteRates  map[string]float64
+	// MaxInFlightProvisioning caps the number of concurrent volume creation
+	// requests; zero means no cap.
+	MaxInFlightProvisioning int
+}
+
+// ParseRouteRates parses a comma-separated list of route=rate pairs, e.g.,
+// "AddVolume=2,AddBackend=0.5".  Unknown route names are rejected, since a
+// misspelled route would otherwise silently go unlimited.
+func ParseRouteRates(s string) (map[string]float64, error) {
+	rates := make(map[string]float64)
+	if s == "" {
+		return rates, nil
+	}
+	known := make(map[string]bool)
+	for _, route := range append(append(Routes{}, routes...), routesV2...) {
+		known[route.Name] = true
+	}
+	for _, pair := range strings.Split(s, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Invalid rate limit %s; expected "+
+				"route=rate.", pair)
+		}
+		name := strings.TrimSpace(parts[0])
+		if !known[name] {
+			return nil, fmt.Errorf("Unknown route %s in rate limit %s.",
+				name, pair)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil || rate < 0 {
+			return nil, fmt.Errorf("Invalid rate for route %s:  %s",
+				parts[0], parts[1])
+		}
+		rates[name] = rate
+	}
+	return rates, nil
+}
+
+type tokenBucket struct {
+	mutex  *sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		mutex:  &sync.Mutex{},
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// take consumes a token if one is available.  Otherwise, it returns how
+// long the caller should wait before retrying.
+func (b *tokenBucket) take() (bool, time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	now := time.Now()
+	b.tokens = math.Min(b.burst,
+		b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := (1 - b.tokens) / b.rate
+	return false, time.Duration(wait * float64(time.Second))
+}
+
+type rateLimiter struct {
+	router       *mux.Router
+	buckets      map[string]*tokenBucket
+	provisioning chan bool
+}
+
+// newRateLimiter wraps router with the limits in rateConfig.  Requests that
+// don't match a route are passed through untouched.
+func newRateLimiter(router *mux.Router, rateConfig *RateLimitConfig) http.Handler {
+	limiter := &rateLimiter{
+		router:  router,
+		buckets: make(map[string]*tokenBucket),
+	}
+	for _, route := range append(append(Routes{}, routes...), routesV2...) {
+		rate := rateConfig.DefaultRate
+		if r, ok := rateConfig.RouteRates[route.Name]; ok {
+			rate = r
+		}
+		if rate > 0 {
+			limiter.buckets[route.Name] = newTokenBucket(rate, rateConfig.Burst)
+		}
+	}
+	if rateConfig.MaxInFlightProvisioning > 0 {
+		limiter.provisioning = make(chan bool,
+			rateConfig.MaxInFlightProvisioning)
+	}
+	return limiter
+}
+
+func (l *rateLimiter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var match mux.RouteMatch
+	if !l.router.Match(r, &match) || match.Route == nil {
+		l.router.ServeHTTP(w, r)
+		return
+	}
+	name := match.Route.GetName()
+
+	if bucket, ok := l.buckets[name]; ok {
+		if allowed, wait := bucket.take(); !allowed {
+			rejectRequest(w, name, http.StatusTooManyRequests,
+				ErrorCodeRateLimited, wait,
+				fmt.Sprintf("Rate limit exceeded for %s.", name))
+			return
+		}
+	}
+	if l.provisioning != nil && provisioningRoutes[name] {
+		select {
+		case l.provisioning <- true:
+			defer func() { <-l.provisioning }()
+		default:
+			rejectRequest(w, name, http.StatusServiceUnavailable,
+				ErrorCodeUnavailable, time.Second,
+				"Too many volume creations in progress.")
+			return
+		}
+	}
+	l.router.ServeHTTP(w, r)
+}
+
+// rejectRequest writes an error in the format expected by the route's API
+// version along with a Retry-After header.
+func rejectRequest(
+	w http.ResponseWriter, route string, status int, code string,
+	retryAfter time.Duration, msg string,
+) {
+	log.WithFields(log.Fields{
+		"route":      route,
+		"status":     status,
+		"retryAfter": retryAfter,
+	}).Warn(msg)
+
+	var response interface{} = map[string]string{"error": msg}
+	if strings.HasSuffix(route, "V2") {
+		response = map[string]interface{}{
+			"error": &ErrorV2{Code: code, Message: msg},
+		}
+	}
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		panic(err)
+	}
+}
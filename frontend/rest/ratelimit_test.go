@@ -0,0 +1,173 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRouteRates(t *testing.T) {
+	for _, test := range []struct {
+		input    string
+		expected map[string]float64
+	}{
+		{"", map[string]float64{}},
+		{"AddVolume=2", map[string]float64{"AddVolume": 2}},
+		{" AddVolume = 2 ,AddBackend=0.5,AddVolumeV2=0",
+			map[string]float64{
+				"AddVolume":   2,
+				"AddBackend":  0.5,
+				"AddVolumeV2": 0,
+			}},
+		// The last rate given for a route wins.
+		{"AddVolume=2,AddVolume=3", map[string]float64{"AddVolume": 3}},
+	} {
+		rates, err := ParseRouteRates(test.input)
+		if err != nil {
+			t.Errorf("Unable to parse %q:  %v", test.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(rates, test.expected) {
+			t.Errorf("Parsing %q:  expected %v; got %v.", test.input,
+				test.expected, rates)
+		}
+	}
+
+	for _, input := range []string{
+		"AddVolume",
+		"AddVolume=",
+		"AddVolume=fast",
+		"AddVolume=-1",
+		"AddVolume=2,",
+		",AddVolume=2",
+		"=2",
+		"NoSuchRoute=2",
+		"addvolume=2",
+		"AddVolume=2,NoSuchRoute=1",
+	} {
+		if rates, err := ParseRouteRates(input); err == nil {
+			t.Errorf("Expected %q to be rejected; got %v.", input, rates)
+		}
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		rate    float64
+		burst   int
+		elapsed time.Duration
+		// allowed is how many requests succeed after the bucket is
+		// emptied and elapsed time has passed.
+		allowed int
+	}{
+		{"NoRefill", 2, 3, 0, 0},
+		{"PartialToken", 2, 3, 400 * time.Millisecond, 0},
+		{"OneToken", 2, 3, 600 * time.Millisecond, 1},
+		{"TwoTokens", 2, 3, 1100 * time.Millisecond, 2},
+		{"CappedAtBurst", 2, 3, time.Hour, 3},
+		{"MinimumBurst", 2, 0, time.Hour, 1},
+	} {
+		bucket := newTokenBucket(test.rate, test.burst)
+		burst := test.burst
+		if burst < 1 {
+			burst = 1
+		}
+		// A new bucket starts full.
+		for i := 0; i < burst; i++ {
+			if allowed, _ := bucket.take(); !allowed {
+				t.Errorf("%s:  request %d of the initial burst rejected.",
+					test.name, i+1)
+			}
+		}
+		if allowed, _ := bucket.take(); allowed {
+			t.Errorf("%s:  request beyond the burst allowed.", test.name)
+		}
+
+		// Pretend the time has passed rather than sleeping.
+		bucket.last = bucket.last.Add(-test.elapsed)
+		allowed := 0
+		for {
+			ok, wait := bucket.take()
+			if !ok {
+				if wait <= 0 || wait > time.Duration(float64(time.Second)/
+					test.rate) {
+					t.Errorf("%s:  unexpected wait %v.", test.name, wait)
+				}
+				break
+			}
+			allowed++
+		}
+		if allowed != test.allowed {
+			t.Errorf("%s:  expected %d requests allowed after %v; got %d.",
+				test.name, test.allowed, test.elapsed, allowed)
+		}
+	}
+}
+
+func TestTokenBucketWait(t *testing.T) {
+	bucket := newTokenBucket(4, 1)
+	bucket.take()
+	bucket.last = bucket.last.Add(-100 * time.Millisecond)
+	// Refilling 0.4 tokens leaves 0.6 to go, which takes 150ms at 4/s.
+	allowed, wait := bucket.take()
+	if allowed {
+		t.Fatal("Request allowed with an empty bucket.")
+	}
+	if wait < 140*time.Millisecond || wait > 150*time.Millisecond {
+		t.Errorf("Expected a wait of about 150ms; got %v.", wait)
+	}
+}
+
+func TestRejectRequest(t *testing.T) {
+	for _, test := range []struct {
+		route      string
+		retryAfter time.Duration
+		expected   string
+		v2         bool
+	}{
+		{"AddVolume", 0, "1", false},
+		{"AddVolume", 100 * time.Millisecond, "1", false},
+		{"AddVolume", time.Second, "1", false},
+		{"AddVolume", 1500 * time.Millisecond, "2", false},
+		{"AddVolumeV2", 2500 * time.Millisecond, "3", true},
+	} {
+		w := httptest.NewRecorder()
+		rejectRequest(w, test.route, http.StatusTooManyRequests,
+			ErrorCodeRateLimited, test.retryAfter, "Slow down.")
+		if w.Code != http.StatusTooManyRequests {
+			t.Errorf("%s:  expected status %d; got %d.", test.route,
+				http.StatusTooManyRequests, w.Code)
+		}
+		if retry := w.Header().Get("Retry-After"); retry != test.expected {
+			t.Errorf("%s after %v:  expected Retry-After %s; got %s.",
+				test.route, test.retryAfter, test.expected, retry)
+		}
+
+		var response map[string]json.RawMessage
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Errorf("%s:  unable to parse response:  %v", test.route, err)
+			continue
+		}
+		if test.v2 {
+			var errV2 ErrorV2
+			if err := json.Unmarshal(response["error"], &errV2); err != nil ||
+				errV2.Code != ErrorCodeRateLimited {
+				t.Errorf("%s:  unexpected v2 error %s.", test.route,
+					response["error"])
+			}
+		} else {
+			var msg string
+			if err := json.Unmarshal(response["error"], &msg); err != nil ||
+				msg != "Slow down." {
+				t.Errorf("%s:  unexpected v1 error %s.", test.route,
+					response["error"])
+			}
+		}
+	}
+}
@@ -3,18 +3,54 @@
 package rest
 
 import (
+	"encoding/json"
 	"net/http"
+	"strings"
+	"time"
 
+	log "github.com/Sirupsen/logrus"
 	"github.com/gorilla/mux"
 )
 
+// bootstrapRetryAfter is how long clients are asked to wait before retrying
+// a request rejected while Trident is bootstrapping.
+const bootstrapRetryAfter = 5 * time.Second
+
+// unbootstrappedRoutes are the routes served while Trident is
+// bootstrapping; all others fail with 503 until it has bootstrapped.
+var unbootstrappedRoutes = map[string]bool{
+	"GetHealth":      true,
+	"GetReadiness":   true,
+	"GetAPIDocument": true,
+	"GetVersion":     true,
+	"GetVersionV2":   true,
+}
+
 func NewRouter() *mux.Router {
 
 	router := mux.NewRouter().StrictSlash(true)
-	for _, route := range routes {
+	allRoutes := make(Routes, 0, len(routes)+len(routesV2))
+	allRoutes = append(allRoutes, routes...)
+	allRoutes = append(allRoutes, routesV2...)
+	document, err := newAPIDocument(allRoutes)
+	if err != nil {
+		log.Fatalf("Unable to generate the API document:  %v", err)
+	}
+	apiDocument = document
+	for _, route := range allRoutes {
 		var handler http.Handler
 
 		handler = route.HandlerFunc
+		if routeBodies[route.Name].Request != nil &&
+			!yamlNativeRoutes[route.Name] {
+			handler = convertYAML(handler, route.Name)
+		}
+		if fieldsSelectable(route) {
+			handler = checkFields(handler, route.Name)
+		}
+		if !unbootstrappedRoutes[route.Name] {
+			handler = requireBootstrap(handler, route.Name)
+		}
 		handler = Logger(handler, route.Name)
 
 		router.
@@ -27,3 +63,37 @@ func NewRouter() *mux.Router {
 
 	return router
 }
+
+// requireBootstrap rejects requests until the orchestrator has bootstrapped,
+// since it has no state to serve them from before then.
+func requireBootstrap(inner http.Handler, name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !orchestrator.IsBootstrapped() {
+			rejectRequest(w, name, http.StatusServiceUnavailable,
+				ErrorCodeUnavailable, bootstrapRetryAfter,
+				"Trident is bootstrapping.")
+			return
+		}
+		inner.ServeHTTP(w, r)
+	})
+}
+
+// rejectBadRequest fails a request that a handler can't be given, e.g.,
+// because its body or query parameters can't be read, with a 400 error.
+func rejectBadRequest(w http.ResponseWriter, route, code, msg string) {
+	log.WithFields(log.Fields{
+		"route": route,
+	}).Warn(msg)
+
+	var response interface{} = map[string]string{"error": msg}
+	if strings.HasSuffix(route, "V2") {
+		response = map[string]interface{}{
+			"error": &ErrorV2{Code: code, Message: msg},
+		}
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusBadRequest)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		panic(err)
+	}
+}
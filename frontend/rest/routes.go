@@ -19,6 +19,24 @@ type Route struct {
 type Routes []Route
 
 var routes = Routes{
+	Route{
+		"GetHealth",
+		"GET",
+		config.HealthURL,
+		GetHealth,
+	},
+	Route{
+		"GetReadiness",
+		"GET",
+		config.ReadyURL,
+		GetReadiness,
+	},
+	Route{
+		"GetAPIDocument",
+		"GET",
+		config.APIDocumentURL,
+		GetAPIDocument,
+	},
 	Route{
 		"GetVersion",
 		"GET",
@@ -38,6 +56,12 @@ var routes = Routes{
 		config.BackendURL,
 		AddBackend,
 	},
+	Route{
+		"ValidateBackend",
+		"POST",
+		config.BackendURL + "/validate",
+		ValidateBackend,
+	},
 	Route{
 		"GetBackend",
 		"GET",
@@ -50,12 +74,60 @@ var routes = Routes{
 		config.BackendURL,
 		ListBackends,
 	},
+	Route{
+		"UpdateBackend",
+		"PUT",
+		config.BackendURL + "/{backend}",
+		UpdateBackend,
+	},
+	Route{
+		"DiffBackend",
+		"POST",
+		config.BackendURL + "/{backend}/diff",
+		DiffBackend,
+	},
 	Route{
 		"DeleteBackend",
 		"DELETE",
 		config.BackendURL + "/{backend}",
 		DeleteBackend,
 	},
+	Route{
+		"RenameBackend",
+		"PUT",
+		config.BackendURL + "/{backend}/name",
+		RenameBackend,
+	},
+	Route{
+		"ListBackendPools",
+		"GET",
+		config.BackendURL + "/{backend}/pools",
+		ListBackendPools,
+	},
+	Route{
+		"GenerateStorageClasses",
+		"GET",
+		config.BackendURL + "/{backend}/storageclasses",
+		GenerateStorageClasses,
+	},
+	Route{
+		"FailoverBackend",
+		"POST",
+		config.BackendURL + "/{backend}/failover",
+		FailoverBackend,
+	},
+	Route{
+		"OnlineBackend",
+		"POST",
+		config.BackendURL + "/{backend}/online",
+		OnlineBackend,
+	},
+	Route{
+		"PreviewOfflineBackend",
+		"GET",
+		config.BackendURL + "/{backend}/offline",
+		PreviewOfflineBackend,
+	},
 	Route{
 		"AddVolume",
 		"POST",
@@ -80,6 +152,144 @@ var routes = Routes{
 		config.VolumeURL + "/{volume}",
 		DeleteVolume,
 	},
+	Route{
+		"DeleteVolumes",
+		"POST",
+		config.VolumeURL + "/delete",
+		DeleteVolumes,
+	},
+	Route{
+		"SetVolumeAccess",
+		"PUT",
+		config.VolumeURL + "/{volume}/access",
+		SetVolumeAccess,
+	},
+	Route{
+		"GetVolumeChap",
+		"GET",
+		config.VolumeURL + "/{volume}/chap",
+		GetVolumeChap,
+	},
+	Route{
+		"UpdateVolumeMetadata",
+		"PUT",
+		config.VolumeURL + "/{volume}/metadata",
+		UpdateVolumeMetadata,
+	},
+	Route{
+		"SetVolumeExpiry",
+		"PUT",
+		config.VolumeURL + "/{volume}/expiry",
+		SetVolumeExpiry,
+	},
+	Route{
+		"ReattachVolume",
+		"PUT",
+		config.VolumeURL + "/{volume}/backend",
+		ReattachVolume,
+	},
+	Route{
+		"ListRetainedVolumes",
+		"GET",
+		config.RetainedVolumeURL,
+		ListRetainedVolumes,
+	},
+	Route{
+		"ImportRetainedVolume",
+		"POST",
+		config.RetainedVolumeURL + "/{volume}/import",
+		ImportRetainedVolume,
+	},
+	Route{
+		"DeleteRetainedVolume",
+		"DELETE",
+		config.RetainedVolumeURL + "/{volume}",
+		DeleteRetainedVolume,
+	},
+	Route{
+		"CreatePersistentVolume",
+		"POST",
+		config.VolumeURL + "/{volume}/pv",
+		CreatePersistentVolume,
+	},
+	Route{
+		"ListProvisioningRetries",
+		"GET",
+		config.ProvisioningRetryURL,
+		ListProvisioningRetries,
+	},
+	Route{
+		"GetProvisioningRetry",
+		"GET",
+		config.ProvisioningRetryURL + "/{volume}",
+		GetProvisioningRetry,
+	},
+	Route{
+		"DeleteProvisioningRetry",
+		"DELETE",
+		config.ProvisioningRetryURL + "/{volume}",
+		DeleteProvisioningRetry,
+	},
+	Route{
+		"RetryProvisioningNow",
+		"POST",
+		config.ProvisioningRetryURL + "/{volume}/now",
+		RetryProvisioningNow,
+	},
+	Route{
+		"AbandonProvisioning",
+		"POST",
+		config.ProvisioningRetryURL + "/{volume}/abandon",
+		AbandonProvisioning,
+	},
+	Route{
+		"UndeleteVolume",
+		"POST",
+		config.VolumeURL + "/{volume}/undelete",
+		UndeleteVolume,
+	},
+	Route{
+		"RenameVolume",
+		"PUT",
+		config.VolumeURL + "/{volume}/name",
+		RenameVolume,
+	},
+	Route{
+		"SetVolumeFormatted",
+		"PUT",
+		config.VolumeURL + "/{volume}/formatted",
+		SetVolumeFormatted,
+	},
+	Route{
+		"AttachVolume",
+		"POST",
+		config.VolumeURL + "/{volume}/attachment",
+		AttachVolume,
+	},
+	Route{
+		"DetachVolume",
+		"DELETE",
+		config.VolumeURL + "/{volume}/attachment/{node}",
+		DetachVolume,
+	},
+	Route{
+		"EnableReplication",
+		"PUT",
+		config.VolumeURL + "/{volume}/replication",
+		EnableReplication,
+	},
+	Route{
+		"DisableReplication",
+		"DELETE",
+		config.VolumeURL + "/{volume}/replication",
+		DisableReplication,
+	},
+	Route{
+		"FailoverVolume",
+		"POST",
+		config.VolumeURL + "/{volume}/failover",
+		FailoverVolume,
+	},
 	Route{
 		"AddStorageClass",
 		"POST",
@@ -98,10 +308,309 @@ var routes = Routes{
 		config.StorageClassURL,
 		ListStorageClasses,
 	},
+	Route{
+		"UpdateStorageClass",
+		"PUT",
+		config.StorageClassURL + "/{storageClass}",
+		UpdateStorageClass,
+	},
 	Route{
 		"DeleteStorageClass",
 		"DELETE",
 		config.StorageClassURL + "/{storageClass}",
 		DeleteStorageClass,
 	},
+	Route{
+		"GetEvents",
+		"GET",
+		config.EventsURL,
+		GetEvents,
+	},
+	Route{
+		"AddHook",
+		"POST",
+		config.HookURL,
+		AddHook,
+	},
+	Route{
+		"GetHook",
+		"GET",
+		config.HookURL + "/{hook}",
+		GetHook,
+	},
+	Route{
+		"ListHooks",
+		"GET",
+		config.HookURL,
+		ListHooks,
+	},
+	Route{
+		"DeleteHook",
+		"DELETE",
+		config.HookURL + "/{hook}",
+		DeleteHook,
+	},
+	Route{
+		"AddSnapshotPolicy",
+		"POST",
+		config.SnapshotPolicyURL,
+		AddSnapshotPolicy,
+	},
+	Route{
+		"GetSnapshotPolicy",
+		"GET",
+		config.SnapshotPolicyURL + "/{snapshotPolicy}",
+		GetSnapshotPolicy,
+	},
+	Route{
+		"ListSnapshotPolicies",
+		"GET",
+		config.SnapshotPolicyURL,
+		ListSnapshotPolicies,
+	},
+	Route{
+		"DeleteSnapshotPolicy",
+		"DELETE",
+		config.SnapshotPolicyURL + "/{snapshotPolicy}",
+		DeleteSnapshotPolicy,
+	},
+	Route{
+		"ListQuarantinedRecords",
+		"GET",
+		config.FailedURL,
+		ListQuarantinedRecords,
+	},
+	Route{
+		"ListPendingTransactions",
+		"GET",
+		config.TransactionURL,
+		ListPendingTransactions,
+	},
+	Route{
+		"ListZombieVolumes",
+		"GET",
+		config.ZombieVolumeURL,
+		ListZombieVolumes,
+	},
+	Route{
+		"GetRebalanceReport",
+		"GET",
+		config.RebalanceURL,
+		GetRebalanceReport,
+	},
+	Route{
+		"GetLoggingConfig",
+		"GET",
+		config.LoggingURL,
+		GetLoggingConfig,
+	},
+	Route{
+		"SetLoggingConfig",
+		"PUT",
+		config.LoggingURL,
+		SetLoggingConfig,
+	},
+	Route{
+		"ListLogs",
+		"GET",
+		config.LogsURL,
+		ListLogs,
+	},
+	Route{
+		"AddVolumeGroup",
+		"POST",
+		config.VolumeGroupURL,
+		AddVolumeGroup,
+	},
+	Route{
+		"GetVolumeGroup",
+		"GET",
+		config.VolumeGroupURL + "/{group}",
+		GetVolumeGroup,
+	},
+	Route{
+		"ListVolumeGroups",
+		"GET",
+		config.VolumeGroupURL,
+		ListVolumeGroups,
+	},
+	Route{
+		"DeleteVolumeGroup",
+		"DELETE",
+		config.VolumeGroupURL + "/{group}",
+		DeleteVolumeGroup,
+	},
+	Route{
+		"SnapshotVolumeGroup",
+		"POST",
+		config.VolumeGroupURL + "/{group}/snapshot",
+		SnapshotVolumeGroup,
+	},
+	Route{
+		"AddTemplate",
+		"POST",
+		config.TemplateURL,
+		AddTemplate,
+	},
+	Route{
+		"GetTemplate",
+		"GET",
+		config.TemplateURL + "/{template}",
+		GetTemplate,
+	},
+	Route{
+		"ListTemplates",
+		"GET",
+		config.TemplateURL,
+		ListTemplates,
+	},
+	Route{
+		"DeleteTemplate",
+		"DELETE",
+		config.TemplateURL + "/{template}",
+		DeleteTemplate,
+	},
+	Route{
+		"ProvisionTemplate",
+		"POST",
+		config.TemplateURL + "/{template}/provision",
+		ProvisionTemplate,
+	},
+	Route{
+		"SetNamespacePolicy",
+		"PUT",
+		config.NamespacePolicyURL + "/{namespace}",
+		SetNamespacePolicy,
+	},
+	Route{
+		"GetNamespacePolicy",
+		"GET",
+		config.NamespacePolicyURL + "/{namespace}",
+		GetNamespacePolicy,
+	},
+	Route{
+		"ListNamespacePolicies",
+		"GET",
+		config.NamespacePolicyURL,
+		ListNamespacePolicies,
+	},
+	Route{
+		"DeleteNamespacePolicy",
+		"DELETE",
+		config.NamespacePolicyURL + "/{namespace}",
+		DeleteNamespacePolicy,
+	},
+	Route{
+		"ApplyState",
+		"PUT",
+		config.StateURL,
+		ApplyState,
+	},
+	Route{
+		"ExplainPlacement",
+		"POST",
+		config.DebugURL + "/placement",
+		ExplainPlacement,
+	},
+	Route{
+		"GetSupportBundle",
+		"GET",
+		config.SupportBundleURL,
+		GetSupportBundle,
+	},
+}
+
+// routesV2 exposes the same operations as routes, but with structured error
+// bodies and paginated list responses.
+var routesV2 = Routes{
+	Route{
+		"GetVersionV2",
+		"GET",
+		config.VersionURLV2,
+		GetVersionV2,
+	},
+	Route{
+		"AddBackendV2",
+		"POST",
+		config.BackendURLV2,
+		AddBackendV2,
+	},
+	Route{
+		"GetBackendV2",
+		"GET",
+		config.BackendURLV2 + "/{backend}",
+		GetBackendV2,
+	},
+	Route{
+		"ListBackendsV2",
+		"GET",
+		config.BackendURLV2,
+		ListBackendsV2,
+	},
+	Route{
+		"DeleteBackendV2",
+		"DELETE",
+		config.BackendURLV2 + "/{backend}",
+		DeleteBackendV2,
+	},
+	Route{
+		"PreviewOfflineBackendV2",
+		"GET",
+		config.BackendURLV2 + "/{backend}/offline",
+		PreviewOfflineBackendV2,
+	},
+	Route{
+		"AddVolumeV2",
+		"POST",
+		config.VolumeURLV2,
+		AddVolumeV2,
+	},
+	Route{
+		"GetVolumeV2",
+		"GET",
+		config.VolumeURLV2 + "/{volume}",
+		GetVolumeV2,
+	},
+	Route{
+		"ListVolumesV2",
+		"GET",
+		config.VolumeURLV2,
+		ListVolumesV2,
+	},
+	Route{
+		"DeleteVolumeV2",
+		"DELETE",
+		config.VolumeURLV2 + "/{volume}",
+		DeleteVolumeV2,
+	},
+	Route{
+		"AddStorageClassV2",
+		"POST",
+		config.StorageClassURLV2,
+		AddStorageClassV2,
+	},
+	Route{
+		"GetStorageClassV2",
+		"GET",
+		config.StorageClassURLV2 + "/{storageClass}",
+		GetStorageClassV2,
+	},
+	Route{
+		"ListStorageClassesV2",
+		"GET",
+		config.StorageClassURLV2,
+		ListStorageClassesV2,
+	},
+	Route{
+		"DeleteStorageClassV2",
+		"DELETE",
+		config.StorageClassURLV2 + "/{storageClass}",
+		DeleteStorageClassV2,
+	},
+	Route{
+		"GetEventsV2",
+		"GET",
+		config.EventsURLV2,
+		GetEvents,
+	},
 }
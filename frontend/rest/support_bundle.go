@@ -0,0 +1,176 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package rest
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/credentials"
+	"github.com/netapp/trident/logging"
+)
+
+// supportBundleVersion is the version of the support bundle layout.  It
+// changes whenever files are renamed or their formats change.
+const supportBundleVersion = "1"
+
+// supportBundleManifest is the bundle's manifest.json.  It lists the
+// SHA-256 digest of each other file, and is signed if an encryption key is
+// set; see credentials.Sign.
+type supportBundleManifest struct {
+	BundleVersion       string            `json:"bundleVersion"`
+	OrchestratorVersion string            `json:"orchestratorVersion"`
+	Created             time.Time         `json:"created"`
+	Files               map[string]string `json:"files"`
+	// Errors records the state that couldn't be collected.
+	Errors    []string `json:"errors,omitempty"`
+	Signature string   `json:"signature,omitempty"`
+}
+
+// collectSupportBundle returns the files of a support bundle, by name, and
+// the errors collecting any of them.  Backends are reported as the API
+// reports them, without their secrets, and the log entries are those kept
+// in memory, with secrets scrubbed.
+func collectSupportBundle() (map[string][]byte, []string) {
+	files := make(map[string][]byte)
+	errs := make([]string, 0)
+	addJSON := func(name string, v interface{}) {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s:  %v", name, err))
+			return
+		}
+		files[name] = data
+	}
+	addJSON("version.json", orchestrator.GetVersionInfo())
+	addJSON("health.json", orchestrator.GetHealth())
+	addJSON("logging.json", orchestrator.GetLoggingConfig())
+	addJSON("backends.json", orchestrator.ListBackends())
+	addJSON("volumes.json", orchestrator.ListVolumes())
+	addJSON("storageclasses.json", orchestrator.ListStorageClasses())
+	txns, err := orchestrator.ListPendingTransactions()
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("transactions.json:  %v", err))
+	} else {
+		addJSON("transactions.json", txns)
+	}
+	entries, err := logging.RecentEntries(&logging.EntryFilter{})
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("logs.json:  %v", err))
+	} else {
+		addJSON("logs.json", entries)
+	}
+	return files, errs
+}
+
+// newSupportBundleManifest returns the signed manifest of a bundle's files.
+func newSupportBundleManifest(
+	files map[string][]byte, errs []string, created time.Time,
+) (*supportBundleManifest, error) {
+	manifest := &supportBundleManifest{
+		BundleVersion:       supportBundleVersion,
+		OrchestratorVersion: config.OrchestratorVersion,
+		Created:             created,
+		Files:               make(map[string]string, len(files)),
+	}
+	if len(errs) > 0 {
+		manifest.Errors = errs
+	}
+	for name, data := range files {
+		digest := sha256.Sum256(data)
+		manifest.Files[name] = hex.EncodeToString(digest[:])
+	}
+	unsigned, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	manifest.Signature = credentials.Sign(unsigned)
+	return manifest, nil
+}
+
+// writeSupportBundle writes a bundle's files and manifest as a gzipped tar
+// archive, with the files in a directory named for the bundle.
+func writeSupportBundle(
+	w io.Writer, dir string, files map[string][]byte,
+	manifest *supportBundleManifest,
+) error {
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+	write := func(name string, data []byte) error {
+		if err := archive.WriteHeader(&tar.Header{
+			Name:    dir + "/" + name,
+			Mode:    0600,
+			Size:    int64(len(data)),
+			ModTime: manifest.Created,
+		}); err != nil {
+			return err
+		}
+		_, err := archive.Write(data)
+		return err
+	}
+	if err = write("manifest.json", manifestJSON); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err = write(name, files[name]); err != nil {
+			return err
+		}
+	}
+	if err = archive.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// GetSupportBundle returns a gzipped tar archive of Trident's state, with
+// secrets redacted, to attach to support cases.
+func GetSupportBundle(w http.ResponseWriter, r *http.Request) {
+	created := time.Now().UTC()
+	files, errs := collectSupportBundle()
+	manifest, err := newSupportBundleManifest(files, errs, created)
+	var buf bytes.Buffer
+	dir := fmt.Sprintf("%s-support-%s", config.OrchestratorName,
+		created.Format("20060102T150405Z"))
+	if err == nil {
+		err = writeSupportBundle(&buf, dir, files, manifest)
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"handler": "GetSupportBundle",
+		}).Error(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, e := range errs {
+		log.WithFields(log.Fields{
+			"handler": "GetSupportBundle",
+		}).Warnf("Unable to collect support bundle state:  %s", e)
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf("attachment; filename=%q", dir+".tar.gz"))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,145 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package rest
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/netapp/trident/credentials"
+)
+
+var testBundleFiles = map[string][]byte{
+	"volumes.json":  []byte(`[{"name": "vol1"}]`),
+	"backends.json": []byte(`[]`),
+	"logs.json":     []byte(`[{"msg": "Started."}]`),
+}
+
+func TestNewSupportBundleManifest(t *testing.T) {
+	created := time.Date(2016, 11, 1, 12, 0, 0, 0, time.UTC)
+	errs := []string{"transactions.json:  store is offline"}
+	manifest, err := newSupportBundleManifest(testBundleFiles, errs, created)
+	if err != nil {
+		t.Fatal("Unable to create manifest:  ", err)
+	}
+	if len(manifest.Files) != len(testBundleFiles) {
+		t.Errorf("Expected %d digests; got %v.", len(testBundleFiles),
+			manifest.Files)
+	}
+	for name, data := range testBundleFiles {
+		digest := sha256.Sum256(data)
+		if manifest.Files[name] != hex.EncodeToString(digest[:]) {
+			t.Errorf("Digest of %s doesn't match its contents:  %s", name,
+				manifest.Files[name])
+		}
+	}
+	if !reflect.DeepEqual(manifest.Errors, errs) ||
+		!manifest.Created.Equal(created) {
+		t.Errorf("Unexpected manifest %+v.", manifest)
+	}
+	if manifest.Signature != "" {
+		t.Errorf("Expected no signature without a key; got %q.",
+			manifest.Signature)
+	}
+
+	if err = credentials.SetEncryptionKey(
+		[]byte("0123456789abcdef")); err != nil {
+		t.Fatal("Unable to set key:  ", err)
+	}
+	defer credentials.SetEncryptionKey(nil)
+	manifest, err = newSupportBundleManifest(testBundleFiles, nil, created)
+	if err != nil {
+		t.Fatal("Unable to create manifest:  ", err)
+	}
+	if manifest.Errors != nil {
+		t.Errorf("Expected no errors; got %v.", manifest.Errors)
+	}
+
+	// The signature covers the manifest without its signature.
+	unsigned := *manifest
+	unsigned.Signature = ""
+	data, err := json.Marshal(&unsigned)
+	if err != nil {
+		t.Fatal("Unable to marshal manifest:  ", err)
+	}
+	if manifest.Signature == "" ||
+		!credentials.Verify(data, manifest.Signature) {
+		t.Errorf("Signature %q not verified.", manifest.Signature)
+	}
+	unsigned.Files = map[string]string{"volumes.json": ""}
+	if data, err = json.Marshal(&unsigned); err != nil {
+		t.Fatal("Unable to marshal manifest:  ", err)
+	}
+	if credentials.Verify(data, manifest.Signature) {
+		t.Error("Signature verified for a changed manifest.")
+	}
+}
+
+func TestWriteSupportBundle(t *testing.T) {
+	created := time.Date(2016, 11, 1, 12, 0, 0, 0, time.UTC)
+	manifest, err := newSupportBundleManifest(testBundleFiles, nil, created)
+	if err != nil {
+		t.Fatal("Unable to create manifest:  ", err)
+	}
+	var buf bytes.Buffer
+	if err = writeSupportBundle(&buf, "bundle", testBundleFiles,
+		manifest); err != nil {
+		t.Fatal("Unable to write bundle:  ", err)
+	}
+
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal("Bundle isn't gzipped:  ", err)
+	}
+	archive := tar.NewReader(gz)
+	names := make([]string, 0)
+	contents := make(map[string][]byte)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal("Unable to read bundle:  ", err)
+		}
+		if header.Mode != 0600 || !header.ModTime.Equal(created) {
+			t.Errorf("Unexpected header for %s:  %+v", header.Name, header)
+		}
+		data, err := ioutil.ReadAll(archive)
+		if err != nil {
+			t.Fatal("Unable to read bundle:  ", err)
+		}
+		names = append(names, header.Name)
+		contents[header.Name] = data
+	}
+
+	// The manifest comes first, followed by the files in order.
+	expected := []string{"bundle/manifest.json", "bundle/backends.json",
+		"bundle/logs.json", "bundle/volumes.json"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("Expected files %v; got %v.", expected, names)
+	}
+	for name, data := range testBundleFiles {
+		if !bytes.Equal(contents["bundle/"+name], data) {
+			t.Errorf("Unexpected contents of %s:  %s", name,
+				contents["bundle/"+name])
+		}
+	}
+	written := &supportBundleManifest{}
+	if err = json.Unmarshal(contents["bundle/manifest.json"],
+		written); err != nil {
+		t.Fatal("Unable to parse manifest:  ", err)
+	}
+	if !reflect.DeepEqual(written.Files, manifest.Files) ||
+		written.BundleVersion != supportBundleVersion {
+		t.Errorf("Expected manifest %+v; got %+v.", manifest, written)
+	}
+}
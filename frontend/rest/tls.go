@@ -0,0 +1,168 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package rest
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// certCheckInterval is how often the certificate and key files are checked
+// for changes.
+const certCheckInterval = 30 * time.Second
+
+// certReloader serves the REST frontend's TLS certificate and reloads it
+// from disk on SIGHUP or whenever the certificate or key file changes.
+type certReloader struct {
+	mutex       *sync.RWMutex
+	certFile    string
+	keyFile     string
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+	stop        chan bool
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	c := &certReloader{
+		mutex:    &sync.RWMutex{},
+		certFile: certFile,
+		keyFile:  keyFile,
+		stop:     make(chan bool),
+	}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *certReloader) reload() error {
+	certInfo, err := os.Stat(c.certFile)
+	if err != nil {
+		return fmt.Errorf("Unable to read TLS certificate:  %v", err)
+	}
+	keyInfo, err := os.Stat(c.keyFile)
+	if err != nil {
+		return fmt.Errorf("Unable to read TLS key:  %v", err)
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("Unable to load TLS key pair:  %v", err)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.cert = &cert
+	c.certModTime = certInfo.ModTime()
+	c.keyModTime = keyInfo.ModTime()
+	return nil
+}
+
+// changed reports whether either file has been modified since the last
+// successful reload.
+func (c *certReloader) changed() bool {
+	certInfo, err := os.Stat(c.certFile)
+	if err != nil {
+		return false
+	}
+	keyInfo, err := os.Stat(c.keyFile)
+	if err != nil {
+		return false
+	}
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return !certInfo.ModTime().Equal(c.certModTime) ||
+		!keyInfo.ModTime().Equal(c.keyModTime)
+}
+
+func (c *certReloader) GetCertificate(
+	*tls.ClientHelloInfo,
+) (*tls.Certificate, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.cert, nil
+}
+
+// watch reloads the certificate until Stop is called.  Failed reloads are
+// logged and the previous certificate stays in use.
+func (c *certReloader) watch() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	ticker := time.NewTicker(certCheckInterval)
+	defer func() {
+		ticker.Stop()
+		signal.Stop(hup)
+	}()
+	c.run(hup, ticker.C)
+}
+
+// run reloads the certificate whenever hup receives a signal, and whenever
+// tick fires if the files have changed, until Stop is called.
+func (c *certReloader) run(hup <-chan os.Signal, tick <-chan time.Time) {
+	for {
+		var reason string
+		select {
+		case <-c.stop:
+			return
+		case <-hup:
+			reason = "SIGHUP"
+		case <-tick:
+			if !c.changed() {
+				continue
+			}
+			reason = "file change"
+		}
+		if err := c.reload(); err != nil {
+			log.WithFields(log.Fields{
+				"certFile": c.certFile,
+				"keyFile":  c.keyFile,
+				"trigger":  reason,
+			}).Errorf("Failed to reload TLS certificate:  %v", err)
+			continue
+		}
+		log.WithFields(log.Fields{
+			"certFile": c.certFile,
+			"trigger":  reason,
+		}).Info("Reloaded TLS certificate.")
+	}
+}
+
+func (c *certReloader) Stop() {
+	close(c.stop)
+}
+
+// newTLSConfig builds the server TLS configuration.  If clientCAFile is
+// set, clients must present a certificate signed by one of its CAs.  Unlike
+// the server's certificate, the CAs are only read here, so Trident must be
+// restarted to rotate them.
+func newTLSConfig(reloader *certReloader, clientCAFile string) (
+	*tls.Config, error,
+) {
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+	if clientCAFile != "" {
+		caPEM, err := ioutil.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read client CA file:  %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("No valid certificates found in client "+
+				"CA file %s.", clientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
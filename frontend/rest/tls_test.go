@@ -0,0 +1,205 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package rest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCert writes a new self-signed certificate and its key, returning
+// the certificate's DER bytes.
+func writeTestCert(t *testing.T, certFile, keyFile string) []byte {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal("Unable to generate key:  ", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "trident"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage: x509.KeyUsageCertSign |
+			x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template,
+		&key.PublicKey, key)
+	if err != nil {
+		t.Fatal("Unable to create certificate:  ", err)
+	}
+	if err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{
+		Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal("Unable to write certificate:  ", err)
+	}
+	if err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}), 0600); err != nil {
+		t.Fatal("Unable to write key:  ", err)
+	}
+	return der
+}
+
+// touch moves a file's modification time forward, so that it's seen as
+// changed even on file systems with coarse timestamps.
+func touch(t *testing.T, path string, offset time.Duration) {
+	when := time.Now().Add(offset)
+	if err := os.Chtimes(path, when, when); err != nil {
+		t.Fatal("Unable to change file times:  ", err)
+	}
+}
+
+func servedCert(t *testing.T, c *certReloader) []byte {
+	cert, err := c.GetCertificate(nil)
+	if err != nil || cert == nil {
+		t.Fatalf("Unable to get certificate:  %v, %v", cert, err)
+	}
+	return cert.Certificate[0]
+}
+
+func TestCertReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trident-tls")
+	if err != nil {
+		t.Fatal("Unable to create directory:  ", err)
+	}
+	defer os.RemoveAll(dir)
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+
+	first := writeTestCert(t, certFile, keyFile)
+	reloader, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal("Unable to load certificate:  ", err)
+	}
+	if !bytes.Equal(servedCert(t, reloader), first) {
+		t.Error("The loaded certificate isn't served.")
+	}
+	if reloader.changed() {
+		t.Error("Unchanged files reported as changed.")
+	}
+
+	hup := make(chan os.Signal)
+	tick := make(chan time.Time)
+	done := make(chan bool)
+	go func() {
+		reloader.run(hup, tick)
+		close(done)
+	}()
+	// Each send waits for the previous trigger to be handled, so a second
+	// send ensures the first reload has finished.
+	trigger := func(hupped bool) {
+		for i := 0; i < 2; i++ {
+			if hupped {
+				hup <- os.Interrupt
+			} else {
+				tick <- time.Now()
+			}
+		}
+	}
+
+	// A file change is picked up on the next tick.
+	second := writeTestCert(t, certFile, keyFile)
+	touch(t, certFile, time.Minute)
+	if !reloader.changed() {
+		t.Error("Changed files not reported as changed.")
+	}
+	trigger(false)
+	if !bytes.Equal(servedCert(t, reloader), second) {
+		t.Error("Certificate not reloaded after the files changed.")
+	}
+
+	// SIGHUP reloads the certificate even if the files seem unchanged.
+	reloader.mutex.RLock()
+	certModTime, keyModTime := reloader.certModTime, reloader.keyModTime
+	reloader.mutex.RUnlock()
+	third := writeTestCert(t, certFile, keyFile)
+	if err = os.Chtimes(certFile, certModTime, certModTime); err != nil {
+		t.Fatal("Unable to change file times:  ", err)
+	}
+	if err = os.Chtimes(keyFile, keyModTime, keyModTime); err != nil {
+		t.Fatal("Unable to change file times:  ", err)
+	}
+	if reloader.changed() {
+		t.Error("Files with their old times reported as changed.")
+	}
+	trigger(true)
+	if !bytes.Equal(servedCert(t, reloader), third) {
+		t.Error("Certificate not reloaded on SIGHUP.")
+	}
+
+	// A certificate that can't be loaded leaves the previous one in use.
+	if err = ioutil.WriteFile(certFile, []byte("invalid"), 0600); err != nil {
+		t.Fatal("Unable to write certificate:  ", err)
+	}
+	touch(t, certFile, 2*time.Minute)
+	trigger(false)
+	trigger(true)
+	if !bytes.Equal(servedCert(t, reloader), third) {
+		t.Error("A failed reload replaced the certificate.")
+	}
+
+	reloader.Stop()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Reloader didn't stop.")
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trident-tls")
+	if err != nil {
+		t.Fatal("Unable to create directory:  ", err)
+	}
+	defer os.RemoveAll(dir)
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	writeTestCert(t, certFile, keyFile)
+	reloader, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal("Unable to load certificate:  ", err)
+	}
+
+	tlsConfig, err := newTLSConfig(reloader, "")
+	if err != nil {
+		t.Fatal("Unable to build TLS config:  ", err)
+	}
+	if tlsConfig.ClientAuth != tls.NoClientCert || tlsConfig.ClientCAs != nil {
+		t.Error("Client certificates required without a client CA.")
+	}
+	if tlsConfig.MinVersion != tls.VersionTLS12 {
+		t.Errorf("Unexpected minimum TLS version %x.", tlsConfig.MinVersion)
+	}
+
+	// The server's certificate serves as the client CA.
+	tlsConfig, err = newTLSConfig(reloader, certFile)
+	if err != nil {
+		t.Fatal("Unable to build TLS config:  ", err)
+	}
+	if tlsConfig.ClientAuth != tls.RequireAndVerifyClientCert ||
+		tlsConfig.ClientCAs == nil {
+		t.Error("Client certificates not required with a client CA.")
+	}
+
+	if _, err = newTLSConfig(reloader,
+		filepath.Join(dir, "missing.pem")); err == nil {
+		t.Error("Expected a missing client CA file to be rejected.")
+	}
+	if _, err = newTLSConfig(reloader, keyFile); err == nil {
+		t.Error("Expected a client CA file without certificates to be " +
+			"rejected.")
+	}
+}
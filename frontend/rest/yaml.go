@@ -0,0 +1,68 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package rest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/netapp/trident/config"
+)
+
+// yamlContentTypes are the media types of YAML request bodies.
+var yamlContentTypes = map[string]bool{
+	"application/yaml":   true,
+	"application/x-yaml": true,
+	"text/yaml":          true,
+	"text/x-yaml":        true,
+}
+
+// yamlNativeRoutes parse YAML bodies themselves, since they may hold several
+// documents.
+var yamlNativeRoutes = map[string]bool{
+	"AddBackend":   true,
+	"AddBackendV2": true,
+}
+
+// isYAML returns true if a request's Content-Type says its body is YAML.
+func isYAML(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && yamlContentTypes[mediaType]
+}
+
+// convertYAML converts YAML request bodies to JSON before passing them to
+// inner, so that handlers need only parse JSON.  Other bodies are passed
+// through untouched.
+func convertYAML(inner http.Handler, name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isYAML(r) {
+			inner.ServeHTTP(w, r)
+			return
+		}
+		body, err := ioutil.ReadAll(
+			io.LimitReader(r.Body, config.MaxRESTRequestSize))
+		if err == nil {
+			err = r.Body.Close()
+		}
+		if err == nil {
+			body, err = yaml.YAMLToJSON(body)
+		}
+		if err != nil {
+			rejectBadRequest(w, name, ErrorCodeInvalidJSON,
+				fmt.Sprintf("Invalid YAML request body:  %v", err))
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		inner.ServeHTTP(w, r)
+	})
+}
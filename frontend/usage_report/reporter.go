@@ -0,0 +1,231 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+// Package usage_report periodically reports the space Trident's volumes
+// use, by volume, storage class, and tenant, for chargeback.
+package usage_report
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/netapp/trident/core"
+)
+
+const Name = "usage_report"
+
+type RecordType string
+
+const (
+	VolumeRecord       RecordType = "volume"
+	StorageClassRecord RecordType = "storageClass"
+	TenantRecord       RecordType = "tenant"
+)
+
+// Record reports the usage of a volume, or of all of a storage class's or a
+// tenant's volumes, at the end of a reporting period.
+type Record struct {
+	Type        RecordType `json:"type"`
+	Name        string     `json:"name"`
+	PeriodStart time.Time  `json:"periodStart"`
+	PeriodEnd   time.Time  `json:"periodEnd"`
+	// StorageClass, Tenant, and Backend are only set for volume records.
+	StorageClass     string `json:"storageClass,omitempty"`
+	Tenant           string `json:"tenant,omitempty"`
+	Backend          string `json:"backend,omitempty"`
+	Volumes          int    `json:"volumes"`
+	ProvisionedBytes uint64 `json:"provisionedBytes"`
+	// ConsumedBytes totals the volumes whose backends report consumption;
+	// it is nil if none do.
+	ConsumedBytes *uint64 `json:"consumedBytes,omitempty"`
+	// DurationSeconds is how long the volume existed during the period, or
+	// the total for a storage class's or tenant's volumes.
+	DurationSeconds int64 `json:"durationSeconds"`
+}
+
+func (r *Record) add(volume *Record) {
+	r.Volumes++
+	r.ProvisionedBytes += volume.ProvisionedBytes
+	r.DurationSeconds += volume.DurationSeconds
+	if volume.ConsumedBytes != nil {
+		consumed := *volume.ConsumedBytes
+		if r.ConsumedBytes != nil {
+			consumed += *r.ConsumedBytes
+		}
+		r.ConsumedBytes = &consumed
+	}
+}
+
+// BuildRecords returns a record for each volume, storage class, and tenant,
+// in that order and by name, for the period from start to end.  A volume's
+// tenant is the value of its tenantKey metadata; volumes without a tenant
+// or a storage class aren't counted in those totals.  Volumes deleted
+// during the period aren't reported.
+func BuildRecords(
+	usage []*core.VolumeUsage, start, end time.Time, tenantKey string,
+) []*Record {
+	volumes := make([]*Record, 0, len(usage))
+	classes := make(map[string]*Record)
+	tenants := make(map[string]*Record)
+	for _, u := range usage {
+		volumeStart := start
+		if u.CreatedAt.After(start) {
+			volumeStart = u.CreatedAt
+		}
+		var duration int64
+		if end.After(volumeStart) {
+			duration = int64(end.Sub(volumeStart) / time.Second)
+		}
+		record := &Record{
+			Type:             VolumeRecord,
+			Name:             u.Volume,
+			PeriodStart:      start,
+			PeriodEnd:        end,
+			StorageClass:     u.StorageClass,
+			Tenant:           u.Metadata[tenantKey],
+			Backend:          u.Backend,
+			Volumes:          1,
+			ProvisionedBytes: u.ProvisionedBytes,
+			ConsumedBytes:    u.ConsumedBytes,
+			DurationSeconds:  duration,
+		}
+		volumes = append(volumes, record)
+		if record.StorageClass != "" {
+			totalRecord(classes, StorageClassRecord, record.StorageClass,
+				start, end).add(record)
+		}
+		if record.Tenant != "" {
+			totalRecord(tenants, TenantRecord, record.Tenant, start,
+				end).add(record)
+		}
+	}
+	sort.Sort(recordsByName(volumes))
+	records := volumes
+	records = append(records, sortedRecords(classes)...)
+	return append(records, sortedRecords(tenants)...)
+}
+
+func totalRecord(
+	totals map[string]*Record, recordType RecordType, name string,
+	start, end time.Time,
+) *Record {
+	record, ok := totals[name]
+	if !ok {
+		record = &Record{
+			Type:        recordType,
+			Name:        name,
+			PeriodStart: start,
+			PeriodEnd:   end,
+		}
+		totals[name] = record
+	}
+	return record
+}
+
+type recordsByName []*Record
+
+func (r recordsByName) Len() int           { return len(r) }
+func (r recordsByName) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r recordsByName) Less(i, j int) bool { return r[i].Name < r[j].Name }
+
+func sortedRecords(totals map[string]*Record) []*Record {
+	records := make([]*Record, 0, len(totals))
+	for _, record := range totals {
+		records = append(records, record)
+	}
+	sort.Sort(recordsByName(records))
+	return records
+}
+
+// Reporter is a frontend that writes usage records to its sinks once per
+// interval, and once more, for the partial period, when it is deactivated.
+// Each report covers the period since the previous one, or since the
+// reporter was activated.
+type Reporter struct {
+	orchestrator core.Orchestrator
+	sinks        []Sink
+	interval     time.Duration
+	tenantKey    string
+	periodStart  time.Time
+	stop         chan bool
+	done         sync.WaitGroup
+}
+
+func NewReporter(
+	orchestrator core.Orchestrator, sinks []Sink, interval time.Duration,
+	tenantKey string,
+) *Reporter {
+	return &Reporter{
+		orchestrator: orchestrator,
+		sinks:        sinks,
+		interval:     interval,
+		tenantKey:    tenantKey,
+	}
+}
+
+func (r *Reporter) GetName() string {
+	return Name
+}
+
+func (r *Reporter) Activate() error {
+	r.periodStart = time.Now().UTC()
+	r.stop = make(chan bool)
+	r.done.Add(1)
+	go r.run()
+	sinks := make([]string, 0, len(r.sinks))
+	for _, sink := range r.sinks {
+		sinks = append(sinks, sink.String())
+	}
+	log.WithFields(log.Fields{
+		"sinks":    sinks,
+		"interval": r.interval,
+	}).Info("Reporting storage usage.")
+	return nil
+}
+
+func (r *Reporter) Deactivate() error {
+	close(r.stop)
+	r.done.Wait()
+	return nil
+}
+
+func (r *Reporter) run() {
+	defer r.done.Done()
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			r.report(time.Now().UTC())
+			return
+		case <-ticker.C:
+			r.report(time.Now().UTC())
+		}
+	}
+}
+
+// report writes the records for the period ending at end to each sink.  A
+// sink that fails misses the period's records; the next report starts
+// where this one ends regardless.
+func (r *Reporter) report(end time.Time) {
+	records := BuildRecords(r.orchestrator.ListVolumeUsage(), r.periodStart,
+		end, r.tenantKey)
+	for _, sink := range r.sinks {
+		if err := sink.Write(records); err != nil {
+			log.WithFields(log.Fields{
+				"sink":        sink.String(),
+				"periodStart": r.periodStart,
+				"periodEnd":   end,
+				"error":       err,
+			}).Error("Unable to write usage records.")
+		}
+	}
+	log.WithFields(log.Fields{
+		"records":     len(records),
+		"periodStart": r.periodStart,
+		"periodEnd":   end,
+	}).Debug("Reported storage usage.")
+	r.periodStart = end
+}
@@ -0,0 +1,205 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package usage_report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/core"
+	"github.com/netapp/trident/storage"
+)
+
+func bytesPtr(b uint64) *uint64 {
+	return &b
+}
+
+func TestBuildRecords(t *testing.T) {
+	start := time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	usage := []*core.VolumeUsage{
+		{
+			Volume:           "b",
+			Backend:          "ontap",
+			StorageClass:     "gold",
+			Metadata:         map[string]string{"team": "web"},
+			ProvisionedBytes: 100,
+			ConsumedBytes:    bytesPtr(40),
+		},
+		{
+			Volume:           "a",
+			Backend:          "ontap",
+			StorageClass:     "gold",
+			Metadata:         map[string]string{"team": "db"},
+			CreatedAt:        start.Add(45 * time.Minute),
+			ProvisionedBytes: 50,
+		},
+		{
+			Volume:           "c",
+			Backend:          "solidfire",
+			Metadata:         map[string]string{"team": "web"},
+			CreatedAt:        start.Add(-time.Hour),
+			ProvisionedBytes: 10,
+			ConsumedBytes:    bytesPtr(5),
+		},
+	}
+	records := BuildRecords(usage, start, end, "team")
+
+	expected := []struct {
+		recordType RecordType
+		name       string
+		volumes    int
+		size       uint64
+		consumed   *uint64
+		duration   int64
+	}{
+		{VolumeRecord, "a", 1, 50, nil, 900},
+		{VolumeRecord, "b", 1, 100, bytesPtr(40), 3600},
+		{VolumeRecord, "c", 1, 10, bytesPtr(5), 3600},
+		{StorageClassRecord, "gold", 2, 150, bytesPtr(40), 4500},
+		{TenantRecord, "db", 1, 50, nil, 900},
+		{TenantRecord, "web", 2, 110, bytesPtr(45), 7200},
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("Expected %d records; got %d.", len(expected),
+			len(records))
+	}
+	for i, e := range expected {
+		r := records[i]
+		if r.Type != e.recordType || r.Name != e.name ||
+			r.Volumes != e.volumes || r.ProvisionedBytes != e.size ||
+			!reflect.DeepEqual(r.ConsumedBytes, e.consumed) ||
+			r.DurationSeconds != e.duration {
+			t.Errorf("Record %d:  expected %v; got %v.", i, e, r)
+		}
+		if !r.PeriodStart.Equal(start) || !r.PeriodEnd.Equal(end) {
+			t.Errorf("Record %d has the wrong period.", i)
+		}
+	}
+	if records[0].Tenant != "db" || records[0].StorageClass != "gold" ||
+		records[0].Backend != "ontap" {
+		t.Errorf("Wrong volume record %v.", records[0])
+	}
+}
+
+func TestReporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "usage")
+	if err != nil {
+		t.Fatal("Unable to create report directory:  ", err)
+	}
+	defer os.RemoveAll(dir)
+	orchestrator := core.NewMockOrchestrator()
+	orchestrator.AddMockONTAPNFSBackend("ontap", "10.0.0.1")
+	if _, err = orchestrator.AddVolume(context.Background(),
+		&storage.VolumeConfig{
+			Name:         "vol1",
+			Size:         "1073741824",
+			Protocol:     config.File,
+			StorageClass: "gold",
+			Metadata:     map[string]string{"tenant": "web"},
+		}); err != nil {
+		t.Fatal("Unable to add volume:  ", err)
+	}
+
+	posted := make([][]*Record, 0)
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			body := &struct {
+				Records []*Record `json:"records"`
+			}{}
+			if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			posted = append(posted, body.Records)
+		}))
+	defer server.Close()
+	csvPath := filepath.Join(dir, "usage.csv")
+	jsonPath := filepath.Join(dir, "usage.json")
+	csvSink, err := NewFileSink(csvPath, FormatCSV)
+	if err != nil {
+		t.Fatal("Unable to create CSV sink:  ", err)
+	}
+	jsonSink, err := NewFileSink(jsonPath, FormatJSON)
+	if err != nil {
+		t.Fatal("Unable to create JSON sink:  ", err)
+	}
+	if _, err = NewFileSink(csvPath, "xml"); err == nil {
+		t.Error("Created a sink with an invalid format.")
+	}
+	reporter := NewReporter(orchestrator, []Sink{csvSink, jsonSink,
+		NewHTTPSink(server.URL, config.UsageReportTimeout)}, time.Hour,
+		config.UsageReportTenantKey)
+
+	start := time.Now().UTC()
+	reporter.periodStart = start
+	reporter.report(start.Add(time.Hour))
+	reporter.report(start.Add(2 * time.Hour))
+	if !reporter.periodStart.Equal(start.Add(2 * time.Hour)) {
+		t.Error("Reporting period did not advance.")
+	}
+
+	// Each report has a record for the volume, its class, and its tenant.
+	f, err := os.Open(csvPath)
+	if err != nil {
+		t.Fatal("Unable to open CSV report:  ", err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal("Unable to read CSV report:  ", err)
+	}
+	if len(rows) != 7 || !reflect.DeepEqual(rows[0], csvHeader) {
+		t.Fatalf("Expected a header and 6 rows; got %v.", rows)
+	}
+	if !reflect.DeepEqual(rows[1], []string{"volume", "vol1",
+		start.Format(time.RFC3339),
+		start.Add(time.Hour).Format(time.RFC3339), "gold", "web", "ontap",
+		"1", "1073741824", "", "3600"}) {
+		t.Errorf("Wrong volume row %v.", rows[1])
+	}
+
+	data, err := ioutil.ReadFile(jsonPath)
+	if err != nil {
+		t.Fatal("Unable to read JSON report:  ", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	count := 0
+	for decoder.More() {
+		record := &Record{}
+		if err = decoder.Decode(record); err != nil {
+			t.Fatal("Unable to decode JSON record:  ", err)
+		}
+		count++
+	}
+	if count != 6 {
+		t.Errorf("Expected 6 JSON records; got %d.", count)
+	}
+
+	if len(posted) != 2 || len(posted[1]) != 3 ||
+		posted[1][2].Type != TenantRecord || posted[1][2].Name != "web" {
+		t.Errorf("Wrong records posted:  %v", posted)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Unavailable.", http.StatusServiceUnavailable)
+		}))
+	defer failing.Close()
+	if err = NewHTTPSink(failing.URL, time.Second).Write(
+		[]*Record{}); err == nil {
+		t.Error("Expected an error from a failing endpoint.")
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package usage_report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+var csvHeader = []string{
+	"type", "name", "periodStart", "periodEnd", "storageClass", "tenant",
+	"backend", "volumes", "provisionedBytes", "consumedBytes",
+	"durationSeconds",
+}
+
+// Sink receives each report's records.
+type Sink interface {
+	Write(records []*Record) error
+	String() string
+}
+
+type fileSink struct {
+	path   string
+	format string
+}
+
+// NewFileSink returns a Sink that appends records to a file, as CSV rows
+// under a header written when the file is created, or as JSON objects, one
+// per line.
+func NewFileSink(path, format string) (Sink, error) {
+	if format != FormatCSV && format != FormatJSON {
+		return nil, fmt.Errorf("Invalid usage report format %q; must be "+
+			"%s or %s.", format, FormatCSV, FormatJSON)
+	}
+	return &fileSink{path: path, format: format}, nil
+}
+
+func (s *fileSink) Write(records []*Record) error {
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if s.format == FormatJSON {
+		encoder := json.NewEncoder(f)
+		for _, record := range records {
+			if err = encoder.Encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	if info.Size() == 0 {
+		if err = w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	for _, record := range records {
+		consumed := ""
+		if record.ConsumedBytes != nil {
+			consumed = strconv.FormatUint(*record.ConsumedBytes, 10)
+		}
+		if err = w.Write([]string{
+			string(record.Type),
+			record.Name,
+			record.PeriodStart.Format(time.RFC3339),
+			record.PeriodEnd.Format(time.RFC3339),
+			record.StorageClass,
+			record.Tenant,
+			record.Backend,
+			strconv.Itoa(record.Volumes),
+			strconv.FormatUint(record.ProvisionedBytes, 10),
+			consumed,
+			strconv.FormatInt(record.DurationSeconds, 10),
+		}); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func (s *fileSink) String() string {
+	return fmt.Sprintf("%s file %s", s.format, s.path)
+}
+
+type httpSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPSink returns a Sink that posts each report's records to url as a
+// JSON object of the form {"records": [...]}.
+func NewHTTPSink(url string, timeout time.Duration) Sink {
+	return &httpSink{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (s *httpSink) Write(records []*Record) error {
+	body, err := json.Marshal(&struct {
+		Records []*Record `json:"records"`
+	}{records})
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Post(s.url, "application/json",
+		bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("Usage report endpoint responded with %s:  %s",
+			resp.Status, bytes.TrimSpace(message))
+	}
+	return nil
+}
+
+func (s *httpSink) String() string {
+	return s.url
+}
@@ -0,0 +1,143 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+// Package hooks implements provisioning webhooks:  HTTP callbacks that are
+// consulted before a volume is created, and notified after.
+package hooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+
+	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/storage"
+)
+
+type Phase string
+type FailurePolicy string
+
+const (
+	// PreProvision hooks run before a volume is created and may modify or
+	// reject its configuration.
+	PreProvision Phase = "preProvision"
+	// PostProvision hooks are notified after a volume has been created.
+	// Their responses are ignored.
+	PostProvision Phase = "postProvision"
+	// Expiry hooks are notified when a volume expires, before it is
+	// deleted.  Their responses are ignored.
+	Expiry Phase = "expiry"
+
+	// FailurePolicyFail rejects the request if the hook can't be reached or
+	// returns an invalid response.
+	FailurePolicyFail FailurePolicy = "fail"
+	// FailurePolicyIgnore proceeds as though the hook had allowed the
+	// request.
+	FailurePolicyIgnore FailurePolicy = "ignore"
+
+	DefaultTimeout = 10 * time.Second
+)
+
+type Config struct {
+	Version        string        `json:"version"`
+	Name           string        `json:"name"`
+	Phase          Phase         `json:"phase"`
+	URL            string        `json:"url"`
+	TimeoutSeconds int           `json:"timeoutSeconds,omitempty"`
+	FailurePolicy  FailurePolicy `json:"failurePolicy,omitempty"`
+}
+
+// Request is the body POSTed to a hook.  Backend and Pool are only set for
+// PostProvision and Expiry hooks.
+type Request struct {
+	Phase   Phase                 `json:"phase"`
+	Volume  *storage.VolumeConfig `json:"volume"`
+	Backend string                `json:"backend,omitempty"`
+	Pool    string                `json:"pool,omitempty"`
+}
+
+// Response is the body a hook returns.  If Volume is set by a PreProvision
+// hook, it replaces the volume configuration for the remainder of the
+// request.
+type Response struct {
+	Allowed bool                  `json:"allowed"`
+	Reason  string                `json:"reason,omitempty"`
+	Volume  *storage.VolumeConfig `json:"volume,omitempty"`
+}
+
+func (c *Config) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("Hook name must be specified.")
+	}
+	switch c.Phase {
+	case PreProvision, PostProvision, Expiry:
+	default:
+		return fmt.Errorf("Hook phase must be %s, %s, or %s.", PreProvision,
+			PostProvision, Expiry)
+	}
+	switch c.FailurePolicy {
+	case "":
+		c.FailurePolicy = FailurePolicyFail
+	case FailurePolicyFail, FailurePolicyIgnore:
+	default:
+		return fmt.Errorf("Hook failure policy must be %s or %s.",
+			FailurePolicyFail, FailurePolicyIgnore)
+	}
+	if c.TimeoutSeconds < 0 {
+		return fmt.Errorf("Hook timeout must not be negative.")
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("Hook URL %s is not a valid HTTP(S) URL.", c.URL)
+	}
+	c.Version = config.OrchestratorMajorVersion
+	return nil
+}
+
+func (c *Config) timeout() time.Duration {
+	if c.TimeoutSeconds == 0 {
+		return DefaultTimeout
+	}
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
+
+// Call sends req to the hook and returns its response.  Errors indicate that
+// the hook couldn't be consulted; a rejection is reported via
+// Response.Allowed.  The call is abandoned if ctx is done.
+func (c *Config) Call(ctx context.Context, req *Request) (*Response, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: c.timeout()}
+	resp, err := ctxhttp.Post(ctx, client, c.URL, "application/json",
+		bytes.NewBuffer(body))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to call hook %s:  %v", c.Name, err)
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read response from hook %s:  %v",
+			c.Name, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("Hook %s returned %s.", c.Name, resp.Status)
+	}
+	response := &Response{}
+	if c.Phase == PostProvision && len(respBody) == 0 {
+		response.Allowed = true
+		return response, nil
+	}
+	if err = json.Unmarshal(respBody, response); err != nil {
+		return nil, fmt.Errorf("Invalid response from hook %s:  %v", c.Name,
+			err)
+	}
+	return response, nil
+}
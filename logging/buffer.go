@@ -0,0 +1,148 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package logging
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Entry is a log entry kept in memory, with secrets scrubbed from its
+// message and fields.
+type Entry struct {
+	Time  time.Time `json:"time"`
+	Level string    `json:"level"`
+	// Subsystem is the subsystem that logged the entry, or "" if it wasn't
+	// logged by one.
+	Subsystem string            `json:"subsystem,omitempty"`
+	Message   string            `json:"message"`
+	Fields    map[string]string `json:"fields,omitempty"`
+}
+
+func newEntry(entry *log.Entry, subsystem string) *Entry {
+	e := &Entry{
+		Time:      entry.Time,
+		Level:     entry.Level.String(),
+		Subsystem: subsystem,
+		Message:   scrubString(entry.Message),
+	}
+	if len(entry.Data) > 0 {
+		e.Fields = make(map[string]string, len(entry.Data))
+		for k, v := range entry.Data {
+			e.Fields[k] = scrubString(fmt.Sprint(v))
+		}
+	}
+	return e
+}
+
+// EntryFilter selects the recent log entries returned by RecentEntries.
+type EntryFilter struct {
+	// Level, if set, is the least severe level returned, e.g., "warning"
+	// returns warnings and errors.
+	Level string
+	// Subsystem, if set, returns only the entries logged by a subsystem.
+	Subsystem string
+	// Limit, if positive, returns only the most recent matching entries.
+	Limit int
+}
+
+func (f *EntryFilter) Validate() error {
+	if f.Level != "" {
+		if _, err := log.ParseLevel(f.Level); err != nil {
+			return fmt.Errorf("Invalid log level %q.", f.Level)
+		}
+	}
+	if f.Subsystem != "" {
+		switch f.Subsystem {
+		case Core, PersistentStore, Drivers, Frontends:
+		default:
+			return fmt.Errorf("Unknown logging subsystem %q.", f.Subsystem)
+		}
+	}
+	if f.Limit < 0 {
+		return fmt.Errorf("Limit must not be negative.")
+	}
+	return nil
+}
+
+// entryBuffer is a ring buffer of the most recent log entries.
+type entryBuffer struct {
+	mutex   sync.Mutex
+	entries []*Entry
+	next    int
+}
+
+var recent = newEntryBuffer(DefaultBufferSize)
+
+func newEntryBuffer(size int) *entryBuffer {
+	return &entryBuffer{entries: make([]*Entry, 0, size)}
+}
+
+func (b *entryBuffer) add(entry *Entry) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if len(b.entries) < cap(b.entries) {
+		b.entries = append(b.entries, entry)
+		return
+	}
+	b.entries[b.next] = entry
+	b.next = (b.next + 1) % len(b.entries)
+}
+
+// ordered returns the entries, oldest first.  The caller must hold the
+// buffer's lock.
+func (b *entryBuffer) ordered() []*Entry {
+	ret := make([]*Entry, 0, len(b.entries))
+	ret = append(ret, b.entries[b.next:]...)
+	return append(ret, b.entries[:b.next]...)
+}
+
+// resize changes the number of entries kept, keeping the most recent.
+func (b *entryBuffer) resize(size int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if size == cap(b.entries) {
+		return
+	}
+	entries := b.ordered()
+	if len(entries) > size {
+		entries = entries[len(entries)-size:]
+	}
+	b.entries = append(make([]*Entry, 0, size), entries...)
+	b.next = 0
+}
+
+// RecentEntries returns the most recent log entries that match filter,
+// oldest first.  Entries are only kept once SetConfig has been called, and
+// only if they were logged at the configured levels.
+func RecentEntries(filter *EntryFilter) ([]*Entry, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	level := log.DebugLevel
+	if filter.Level != "" {
+		level, _ = log.ParseLevel(filter.Level)
+	}
+	recent.mutex.Lock()
+	entries := recent.ordered()
+	recent.mutex.Unlock()
+
+	ret := make([]*Entry, 0, len(entries))
+	for _, e := range entries {
+		entryLevel, err := log.ParseLevel(e.Level)
+		if err != nil || entryLevel > level {
+			continue
+		}
+		if filter.Subsystem != "" && e.Subsystem != filter.Subsystem {
+			continue
+		}
+		ret = append(ret, e)
+	}
+	if filter.Limit > 0 && len(ret) > filter.Limit {
+		ret = ret[len(ret)-filter.Limit:]
+	}
+	return ret, nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package logging
+
+import (
+	"testing"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+func TestRecentEntries(t *testing.T) {
+	saved := recent
+	recent = newEntryBuffer(4)
+	defer func() { recent = saved }()
+
+	AddSecret("hunter2")
+	for _, e := range []struct {
+		level     log.Level
+		subsystem string
+		message   string
+	}{
+		{log.InfoLevel, Core, "a"},
+		{log.WarnLevel, Drivers, "b"},
+		{log.DebugLevel, Core, "c"},
+		{log.ErrorLevel, Core, "d"},
+		{log.InfoLevel, Frontends, "Logged in with hunter2."},
+	} {
+		entry := log.WithField("password", "hunter2")
+		entry.Level = e.level
+		entry.Message = e.message
+		recent.add(newEntry(entry, e.subsystem))
+	}
+
+	for _, test := range []struct {
+		filter   EntryFilter
+		expected []string
+	}{
+		{EntryFilter{}, []string{"b", "c", "d", "Logged in with <redacted>."}},
+		{EntryFilter{Level: "warning"}, []string{"b", "d"}},
+		{EntryFilter{Subsystem: Core}, []string{"c", "d"}},
+		{EntryFilter{Limit: 1}, []string{"Logged in with <redacted>."}},
+	} {
+		entries, err := RecentEntries(&test.filter)
+		if err != nil {
+			t.Fatalf("Unable to get entries for %+v:  %v", test.filter, err)
+		}
+		messages := make([]string, 0, len(entries))
+		for _, e := range entries {
+			messages = append(messages, e.Message)
+			if e.Fields["password"] != redacted {
+				t.Errorf("Secret not scrubbed from fields %v.", e.Fields)
+			}
+		}
+		if len(messages) != len(test.expected) {
+			t.Errorf("Filter %+v:  expected %v; got %v.", test.filter,
+				test.expected, messages)
+			continue
+		}
+		for i := range messages {
+			if messages[i] != test.expected[i] {
+				t.Errorf("Filter %+v:  expected %v; got %v.", test.filter,
+					test.expected, messages)
+				break
+			}
+		}
+	}
+
+	// Shrinking the buffer keeps the most recent entries.
+	recent.resize(2)
+	entries, _ := RecentEntries(&EntryFilter{})
+	if len(entries) != 2 || entries[0].Message != "d" {
+		t.Errorf("Unexpected entries after resizing:  %v", entries)
+	}
+
+	for _, filter := range []*EntryFilter{
+		{Level: "verbose"},
+		{Subsystem: "kernel"},
+		{Limit: -1},
+	} {
+		if _, err := RecentEntries(filter); err == nil {
+			t.Errorf("Expected an error for filter %+v.", filter)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+// Package logging controls the level and format of Trident's log output at
+// runtime, optionally with a different level for each subsystem.
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	/* Subsystems with independently settable log levels */
+	Core            = "core"
+	PersistentStore = "persistent_store"
+	Drivers         = "drivers"
+	Frontends       = "frontends"
+
+	/* Log formats */
+	FormatText = "text"
+	FormatJSON = "json"
+
+	tridentPackage = "github.com/netapp/trident/"
+
+	// redacted replaces secrets in log output.
+	redacted = "<redacted>"
+	// minSecretLength is the length below which values aren't scrubbed from
+	// log output, lest common words be redacted.
+	minSecretLength = 4
+	// DefaultBufferSize is the number of recent log entries kept if the
+	// config doesn't set BufferSize.
+	DefaultBufferSize = 1000
+)
+
+// subsystemPackages maps package path prefixes, relative to the Trident
+// package, to the subsystem whose log level applies to them.
+var subsystemPackages = []struct {
+	prefix    string
+	subsystem string
+}{
+	{"core.", Core},
+	{"persistent_store.", PersistentStore},
+	{"storage.", Drivers},
+	{"storage/", Drivers},
+	{"drivers/", Drivers},
+	{"frontend/", Frontends},
+}
+
+// Config is the logging configuration.  Subsystems absent from
+// SubsystemLevels log at Level.
+type Config struct {
+	Level           string            `json:"level"`
+	SubsystemLevels map[string]string `json:"subsystemLevels,omitempty"`
+	Format          string            `json:"format"`
+	// BufferSize is the number of recent log entries kept for
+	// RecentEntries; 0 keeps DefaultBufferSize.
+	BufferSize int `json:"bufferSize,omitempty"`
+}
+
+func (c *Config) Validate() error {
+	if _, err := log.ParseLevel(c.Level); err != nil {
+		return fmt.Errorf("Invalid log level %q.", c.Level)
+	}
+	for subsystem, level := range c.SubsystemLevels {
+		switch subsystem {
+		case Core, PersistentStore, Drivers, Frontends:
+		default:
+			return fmt.Errorf("Unknown logging subsystem %q; must be one of "+
+				"%s.", subsystem, strings.Join(GetSubsystems(), ", "))
+		}
+		if _, err := log.ParseLevel(level); err != nil {
+			return fmt.Errorf("Invalid log level %q for subsystem %s.", level,
+				subsystem)
+		}
+	}
+	if c.Format != FormatText && c.Format != FormatJSON {
+		return fmt.Errorf("Invalid log format %q; must be %s or %s.",
+			c.Format, FormatText, FormatJSON)
+	}
+	if c.BufferSize < 0 {
+		return fmt.Errorf("Log buffer size must not be negative.")
+	}
+	return nil
+}
+
+// GetSubsystems returns the names of the subsystems whose log levels can be
+// set individually.
+func GetSubsystems() []string {
+	ret := []string{Core, PersistentStore, Drivers, Frontends}
+	sort.Strings(ret)
+	return ret
+}
+
+// levelFilter is a formatter that discards entries above the level of the
+// subsystem that logged them.  The logger's own level is set to the most
+// verbose level in use, so that entries reach the filter at all.
+type levelFilter struct {
+	log.Formatter
+	level           log.Level
+	subsystemLevels map[string]log.Level
+}
+
+func (f *levelFilter) Format(entry *log.Entry) ([]byte, error) {
+	subsystem := callerSubsystem()
+	if len(f.subsystemLevels) > 0 {
+		level := f.level
+		if subsystemLevel, ok := f.subsystemLevels[subsystem]; ok {
+			level = subsystemLevel
+		}
+		if entry.Level > level {
+			return nil, nil
+		}
+	}
+	out, err := f.Formatter.Format(entry)
+	if err != nil {
+		return out, err
+	}
+	recent.add(newEntry(entry, subsystem))
+	return scrubSecrets(out), nil
+}
+
+var (
+	secretsMutex sync.RWMutex
+	secrets      = make(map[string]bool)
+)
+
+// AddSecret keeps a value, e.g., a backend password, out of all subsequent
+// log output.
+func AddSecret(secret string) {
+	if len(secret) < minSecretLength {
+		return
+	}
+	secretsMutex.Lock()
+	defer secretsMutex.Unlock()
+	secrets[secret] = true
+}
+
+// scrubSecrets replaces the secrets added with AddSecret in formatted log
+// output.
+func scrubSecrets(out []byte) []byte {
+	secretsMutex.RLock()
+	defer secretsMutex.RUnlock()
+	for secret := range secrets {
+		out = bytes.Replace(out, []byte(secret), []byte(redacted), -1)
+	}
+	return out
+}
+
+// scrubString replaces the secrets added with AddSecret in s.
+func scrubString(s string) string {
+	secretsMutex.RLock()
+	defer secretsMutex.RUnlock()
+	for secret := range secrets {
+		s = strings.Replace(s, secret, redacted, -1)
+	}
+	return s
+}
+
+// callerSubsystem returns the subsystem of the function that logged the
+// entry being formatted, or "" if it isn't part of a subsystem.
+func callerSubsystem() string {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(3, pcs)
+	for _, pc := range pcs[:n] {
+		fn := runtime.FuncForPC(pc)
+		if fn == nil {
+			continue
+		}
+		name := fn.Name()
+		if strings.Contains(name, "/Sirupsen/logrus.") {
+			continue
+		}
+		if strings.Contains(name, "/netapp/netappdvp/") {
+			return Drivers
+		}
+		i := strings.LastIndex(name, tridentPackage)
+		if i < 0 {
+			return ""
+		}
+		name = name[i+len(tridentPackage):]
+		for _, p := range subsystemPackages {
+			if strings.HasPrefix(name, p.prefix) {
+				return p.subsystem
+			}
+		}
+		return ""
+	}
+	return ""
+}
+
+var (
+	mutex   sync.Mutex
+	current = &Config{
+		Level:  log.InfoLevel.String(),
+		Format: FormatText,
+	}
+)
+
+// SetConfig applies a logging configuration to the standard logger.
+func SetConfig(c *Config) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	level, _ := log.ParseLevel(c.Level)
+	filter := &levelFilter{
+		level:           level,
+		subsystemLevels: make(map[string]log.Level, len(c.SubsystemLevels)),
+	}
+	loggerLevel := level
+	for subsystem, l := range c.SubsystemLevels {
+		subsystemLevel, _ := log.ParseLevel(l)
+		filter.subsystemLevels[subsystem] = subsystemLevel
+		if subsystemLevel > loggerLevel {
+			loggerLevel = subsystemLevel
+		}
+	}
+	if c.Format == FormatJSON {
+		filter.Formatter = &log.JSONFormatter{}
+	} else {
+		filter.Formatter = &log.TextFormatter{}
+	}
+
+	bufferSize := c.BufferSize
+	if bufferSize == 0 {
+		bufferSize = DefaultBufferSize
+	}
+	recent.resize(bufferSize)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	log.SetFormatter(filter)
+	log.SetLevel(loggerLevel)
+	current = copyConfig(c)
+	log.WithFields(log.Fields{
+		"level":           c.Level,
+		"subsystemLevels": c.SubsystemLevels,
+		"format":          c.Format,
+	}).Info("Set logging configuration.")
+	return nil
+}
+
+// GetConfig returns the logging configuration last set.
+func GetConfig() *Config {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return copyConfig(current)
+}
+
+func copyConfig(c *Config) *Config {
+	ret := *c
+	if c.SubsystemLevels != nil {
+		ret.SubsystemLevels = make(map[string]string, len(c.SubsystemLevels))
+		for k, v := range c.SubsystemLevels {
+			ret.SubsystemLevels[k] = v
+		}
+	}
+	return &ret
+}
+
+// ParseSubsystemLevels parses a list of subsystem=level pairs, e.g.,
+// "core=debug,drivers=warn".
+func ParseSubsystemLevels(spec string) (map[string]string, error) {
+	levels := make(map[string]string)
+	if spec == "" {
+		return levels, nil
+	}
+	for _, pair := range strings.Split(spec, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("Invalid subsystem log level %q; expected "+
+				"subsystem=level.", pair)
+		}
+		levels[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return levels, nil
+}
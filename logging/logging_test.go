@@ -0,0 +1,110 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package logging
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+func TestParseSubsystemLevels(t *testing.T) {
+	levels, err := ParseSubsystemLevels("core=debug, drivers=warn")
+	if err != nil {
+		t.Fatal("Unable to parse subsystem levels:  ", err)
+	}
+	expected := map[string]string{Core: "debug", Drivers: "warn"}
+	if !reflect.DeepEqual(levels, expected) {
+		t.Errorf("Expected %v; got %v.", expected, levels)
+	}
+	for _, spec := range []string{"core", "=debug", "core=debug,"} {
+		if _, err = ParseSubsystemLevels(spec); err == nil {
+			t.Errorf("Expected an error parsing %q.", spec)
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	for _, c := range []*Config{
+		{Level: "verbose", Format: FormatText},
+		{Level: "info", Format: "xml"},
+		{Level: "info", Format: FormatText,
+			SubsystemLevels: map[string]string{"kernel": "debug"}},
+		{Level: "info", Format: FormatText,
+			SubsystemLevels: map[string]string{Core: "verbose"}},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("Expected a validation error for %+v.", c)
+		}
+	}
+}
+
+func TestSetConfig(t *testing.T) {
+	original := GetConfig()
+	defer SetConfig(original)
+
+	c := &Config{
+		Level:           "warn",
+		SubsystemLevels: map[string]string{Core: "debug"},
+		Format:          FormatJSON,
+	}
+	if err := SetConfig(c); err != nil {
+		t.Fatal("Unable to set logging config:  ", err)
+	}
+	if !reflect.DeepEqual(GetConfig(), c) {
+		t.Errorf("Expected %+v; got %+v.", c, GetConfig())
+	}
+	// The logger must pass the most verbose subsystem's entries.
+	if log.GetLevel() != log.DebugLevel {
+		t.Errorf("Expected the logger level to be debug; got %v.",
+			log.GetLevel())
+	}
+	c.SubsystemLevels[Core] = "error"
+	if GetConfig().SubsystemLevels[Core] != "debug" {
+		t.Error("Logging config shares the caller's map.")
+	}
+}
+
+func TestLevelFilter(t *testing.T) {
+	filter := &levelFilter{
+		Formatter:       &log.TextFormatter{DisableColors: true},
+		level:           log.WarnLevel,
+		subsystemLevels: map[string]log.Level{Core: log.DebugLevel},
+	}
+	// This package isn't in a subsystem, so the default level applies.
+	entry := log.NewEntry(log.StandardLogger())
+	entry.Level = log.InfoLevel
+	entry.Message = "filtered"
+	if out, err := filter.Format(entry); err != nil || len(out) != 0 {
+		t.Errorf("Expected an info entry to be discarded; got %q.", out)
+	}
+	entry.Level = log.WarnLevel
+	if out, err := filter.Format(entry); err != nil || len(out) == 0 {
+		t.Error("Expected a warning entry to be formatted.")
+	}
+}
+
+func TestAddSecret(t *testing.T) {
+	filter := &levelFilter{
+		Formatter: &log.TextFormatter{DisableColors: true},
+		level:     log.InfoLevel,
+	}
+	AddSecret("hunter2")
+	AddSecret("pw")
+	entry := log.WithFields(log.Fields{"password": "hunter2", "user": "pw"})
+	entry.Level = log.InfoLevel
+	entry.Message = "Logged in with hunter2."
+	out, err := filter.Format(entry)
+	if err != nil {
+		t.Fatal("Unable to format entry:  ", err)
+	}
+	if bytes.Contains(out, []byte("hunter2")) {
+		t.Errorf("Secret not scrubbed from %q.", out)
+	}
+	if !bytes.Contains(out, []byte(redacted)) ||
+		!bytes.Contains(out, []byte("user=pw")) {
+		t.Errorf("Unexpected log output %q.", out)
+	}
+}
@@ -11,15 +11,34 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 
+	"github.com/netapp/trident/config"
 	"github.com/netapp/trident/core"
+	"github.com/netapp/trident/credentials"
+	"github.com/netapp/trident/faults"
 	"github.com/netapp/trident/frontend"
+	"github.com/netapp/trident/frontend/backend_watcher"
 	"github.com/netapp/trident/frontend/kubernetes"
 	"github.com/netapp/trident/frontend/rest"
+	"github.com/netapp/trident/frontend/usage_report"
+	"github.com/netapp/trident/logging"
 	"github.com/netapp/trident/persistent_store"
+	"github.com/netapp/trident/tracing"
+	// The external driver registers itself with the storage factory.
+	_ "github.com/netapp/trident/storage/sidecar"
 )
 
 var (
-	debug        = flag.Bool("debug", false, "Enable debugging output")
+	configFile = flag.String("config", "", "YAML or JSON file of "+
+		"orchestrator settings.  Command-line flags override it.")
+	debug     = flag.Bool("debug", false, "Enable debugging output")
+	logLevel  = flag.String("log_level", config.DefaultLogLevel, "Logging level")
+	logFormat = flag.String("log_format", config.DefaultLogFormat,
+		"Logging format (text or json)")
+	logBufferSize = flag.Int("log_buffer_size", 0, "Number of recent log "+
+		"entries kept in memory for the logs API (default 1000)")
+	subsystemLogLevels = flag.String("subsystem_log_levels", "", "Per-"+
+		"subsystem logging levels overriding -log_level (e.g., "+
+		"core=debug,drivers=warn)")
 	k8sAPIServer = flag.String("k8s_api_server", "", "Kubernetes API server "+
 		"address to enable dynamic storage provisioning for Kubernetes.",
 	)
@@ -27,37 +46,295 @@ var (
 		" for Kubernetes if running in a pod.")
 	etcdV2 = flag.String("etcd_v2", "", "etcd server (v2 API) for"+
 		"persisting orchestrator state (e.g., -etcd_v2=http://127.0.0.1:8001)")
-	port = flag.String("port", "8000", "Storage orchestrator "+
+	address = flag.String("address", "", "Storage orchestrator listen "+
+		"address (default all interfaces)")
+	port = flag.String("port", config.DefaultPort, "Storage orchestrator "+
 		"port")
 	useInMemory = flag.Bool("no_persistence", false, "Does not persist "+
 		"any metadata.  WILL LOSE TRACK OF VOLUMES ON REBOOT/CRASH.")
-	storeClient persistent_store.Client
+	tlsCert = flag.String("tls_cert", "", "Certificate file for serving "+
+		"the REST API over HTTPS.  Reloaded on SIGHUP or file change.")
+	tlsKey = flag.String("tls_key", "", "Private key file for serving "+
+		"the REST API over HTTPS.")
+	tlsClientCA = flag.String("tls_client_ca", "", "CA bundle used to "+
+		"verify REST client certificates, read only at startup.  Requires "+
+		"-tls_cert and -tls_key.")
+	rateLimit = flag.Float64("rate_limit", 0, "Default per-route REST "+
+		"request rate limit in requests per second (0 for no limit)")
+	rateLimitBurst = flag.Int("rate_limit_burst", 10, "Number of REST "+
+		"requests per route allowed to exceed the rate limit in a burst")
+	routeRateLimits = flag.String("route_rate_limits", "", "Per-route "+
+		"REST rate limits overriding -rate_limit (e.g., AddVolume=2,AddBackend=0.5)")
+	maxInFlightProvisioning = flag.Int("max_inflight_provisioning", 0,
+		"Maximum number of concurrent volume creation requests (0 for no limit)")
+	resilientBootstrap = flag.Bool("resilient_bootstrap", false, "Quarantine "+
+		"corrupt stored backends and volumes at startup instead of failing")
+	deferBackendInit = flag.Bool("defer_backend_init", false, "Start even "+
+		"if some backends' storage systems are unreachable, retrying them "+
+		"in the background")
+	maxBootstrapAttempts = flag.Int("max_bootstrap_attempts",
+		config.MaxBootstrapAttempts, "Number of times, a second apart, to "+
+			"try reaching the persistent store at startup")
+	bootstrapWorkers = flag.Int("bootstrap_workers", config.BootstrapWorkers,
+		"Number of backends initialized concurrently at startup")
+	backendInitRetryInterval = flag.Duration("backend_init_retry_interval",
+		config.BackendInitRetryInterval, "Interval between retries of "+
+			"backends deferred by -defer_backend_init")
+	placementPolicy = flag.String("placement_policy", config.PlacementRandom,
+		"Order in which a storage class's pools are tried for new volumes "+
+			"(random or ordered)")
+	placementSeed = flag.Int64("placement_seed", 0, "Seed for the random "+
+		"placement policy, to reproduce placement; 0 seeds it from the clock")
+	strictStorageClasses = flag.Bool("strict_storage_classes", false,
+		"Reject storage classes requesting attribute values that aren't "+
+			"allowed, rather than adding them with warnings")
+	protectStorageClasses = flag.Bool("protect_storage_classes", false,
+		"Refuse to delete storage classes that volumes still use unless "+
+			"forced; they're deleted once their last volume is")
+	provisionTimeout = flag.Duration("provision_timeout",
+		config.ProvisionTimeout, "Maximum time to create a volume")
+	deleteTimeout = flag.Duration("delete_timeout", config.DeleteTimeout,
+		"Maximum time to delete a volume")
+	backendAddTimeout = flag.Duration("backend_add_timeout",
+		config.BackendAddTimeout, "Maximum time to add or update a backend")
+	storeTimeout = flag.Duration("store_timeout",
+		config.PersistentStoreTimeout, "Maximum time for each persistent "+
+			"store request")
+	janitorInterval = flag.Duration("txn_janitor_interval",
+		config.JanitorInterval, "Interval between checks for volume "+
+			"transactions left by failed operations")
+	janitorMinAge = flag.Duration("txn_janitor_min_age", config.JanitorMinAge,
+		"Age at which a volume transaction is rolled back or completed")
+	janitorMaxAttempts = flag.Int("txn_janitor_max_attempts",
+		config.JanitorMaxAttempts, "Number of failed attempts to resolve a "+
+			"volume transaction before leaving it for the next restart")
+	deletionGracePeriod = flag.Duration("deletion_grace_period", 0,
+		"Time for which deleted volumes are kept, renamed, on their "+
+			"backends and can be undeleted (0 to delete them immediately)")
+	volumeExpiryAction = flag.String("volume_expiry_action",
+		config.ExpiryDelete, "What to do with volumes whose TTL has passed "+
+			"(delete or flag)")
+	volumeExpiryGracePeriod = flag.Duration("volume_expiry_grace_period",
+		config.VolumeExpiryGracePeriod, "Time between a volume's expiry "+
+			"and its deletion")
+	secretKeyFile = flag.String("secret_key_file", "", "File holding a "+
+		"base64-encoded AES key with which to encrypt backend secrets in "+
+		"the persistent store")
+	backendConfigDir = flag.String("backend_config_dir", "", "Directory of "+
+		"backend config files (*.json) to add, update, and offline "+
+		"backends from")
+	backendConfigMap = flag.String("backend_config_map", "", "Kubernetes "+
+		"ConfigMap (<namespace>/<name>) of backend configs to add, update, "+
+		"and offline backends from")
+	backendWatchInterval = flag.Duration("backend_watch_interval",
+		config.BackendWatchInterval, "Interval between checks of the "+
+			"backend configs in -backend_config_dir or -backend_config_map")
+	usageReportFile = flag.String("usage_report_file", "", "File to which "+
+		"volume usage records are appended for chargeback")
+	usageReportFormat = flag.String("usage_report_format", "csv", "Format "+
+		"of -usage_report_file (csv or json)")
+	usageReportURL = flag.String("usage_report_url", "", "HTTP endpoint to "+
+		"which volume usage records are posted for chargeback")
+	usageReportInterval = flag.Duration("usage_report_interval",
+		config.UsageReportInterval, "Interval between usage reports")
+	usageReportTenantKey = flag.String("usage_report_tenant_key",
+		config.UsageReportTenantKey, "Volume metadata key naming each "+
+			"volume's tenant in usage reports")
+	tracingExporter = flag.String("tracing_exporter", "", "Exporter for "+
+		"traces of orchestrator, store, and driver calls (log or zipkin)")
+	tracingURL = flag.String("tracing_url", "", "Zipkin-compatible "+
+		"collector endpoint to which spans are posted")
+	faultPoints = flag.String("fault_points", "", "Failure points to arm "+
+		"for testing transaction recovery (e.g., volume_stored=crash); "+
+		"never use in production")
+
+	orchestratorConfig *config.OrchestratorConfig
+	storeClient        persistent_store.Client
 
 	enableKubernetes bool
 )
 
+// applyFlags overrides the settings in c with any flags set on the command
+// line.
+func applyFlags(c *config.OrchestratorConfig) {
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "debug":
+			if *debug {
+				c.LogLevel = "debug"
+			}
+		case "log_level":
+			c.LogLevel = *logLevel
+		case "log_format":
+			c.LogFormat = *logFormat
+		case "log_buffer_size":
+			c.LogBufferSize = *logBufferSize
+		case "subsystem_log_levels":
+			levels, err := logging.ParseSubsystemLevels(*subsystemLogLevels)
+			if err != nil {
+				log.Fatal(err)
+			}
+			c.SubsystemLogLevels = levels
+		case "k8s_api_server":
+			c.K8sAPIServer = *k8sAPIServer
+		case "k8s_pod":
+			c.K8sPod = *k8sPod
+		case "etcd_v2":
+			c.EtcdV2 = *etcdV2
+		case "address":
+			c.Address = *address
+		case "port":
+			c.Port = *port
+		case "no_persistence":
+			c.NoPersistence = *useInMemory
+		case "tls_cert":
+			c.TLS.CertFile = *tlsCert
+		case "tls_key":
+			c.TLS.KeyFile = *tlsKey
+		case "tls_client_ca":
+			c.TLS.ClientCAFile = *tlsClientCA
+		case "rate_limit":
+			c.RateLimit.Rate = *rateLimit
+		case "rate_limit_burst":
+			c.RateLimit.Burst = *rateLimitBurst
+		case "route_rate_limits":
+			c.RateLimit.RouteRates = *routeRateLimits
+		case "max_inflight_provisioning":
+			c.RateLimit.MaxInFlightProvisioning = *maxInFlightProvisioning
+		case "resilient_bootstrap":
+			c.Bootstrap.Resilient = *resilientBootstrap
+		case "defer_backend_init":
+			c.Bootstrap.DeferBackendInit = *deferBackendInit
+		case "max_bootstrap_attempts":
+			c.Bootstrap.MaxAttempts = *maxBootstrapAttempts
+		case "bootstrap_workers":
+			c.Bootstrap.Workers = *bootstrapWorkers
+		case "backend_init_retry_interval":
+			c.Bootstrap.BackendInitRetryInterval =
+				backendInitRetryInterval.String()
+		case "placement_policy":
+			c.PlacementPolicy = *placementPolicy
+		case "placement_seed":
+			c.PlacementSeed = *placementSeed
+		case "strict_storage_classes":
+			c.StrictStorageClasses = *strictStorageClasses
+		case "protect_storage_classes":
+			c.ProtectStorageClasses = *protectStorageClasses
+		case "provision_timeout":
+			c.Timeouts.Provision = provisionTimeout.String()
+		case "delete_timeout":
+			c.Timeouts.Delete = deleteTimeout.String()
+		case "backend_add_timeout":
+			c.Timeouts.BackendAdd = backendAddTimeout.String()
+		case "store_timeout":
+			c.Timeouts.Store = storeTimeout.String()
+		case "txn_janitor_interval":
+			c.Janitor.Interval = janitorInterval.String()
+		case "txn_janitor_min_age":
+			c.Janitor.MinAge = janitorMinAge.String()
+		case "txn_janitor_max_attempts":
+			c.Janitor.MaxAttempts = *janitorMaxAttempts
+		case "deletion_grace_period":
+			c.DeletionGracePeriod = deletionGracePeriod.String()
+		case "volume_expiry_action":
+			c.VolumeExpiry.Action = *volumeExpiryAction
+		case "volume_expiry_grace_period":
+			c.VolumeExpiry.GracePeriod = volumeExpiryGracePeriod.String()
+		case "secret_key_file":
+			c.SecretKeyFile = *secretKeyFile
+		case "backend_config_dir":
+			c.BackendWatcher.Directory = *backendConfigDir
+		case "backend_config_map":
+			c.BackendWatcher.ConfigMap = *backendConfigMap
+		case "backend_watch_interval":
+			c.BackendWatcher.Interval = backendWatchInterval.String()
+		case "usage_report_file":
+			c.UsageReport.File = *usageReportFile
+		case "usage_report_format":
+			c.UsageReport.Format = *usageReportFormat
+		case "usage_report_url":
+			c.UsageReport.URL = *usageReportURL
+		case "usage_report_interval":
+			c.UsageReport.Interval = usageReportInterval.String()
+		case "usage_report_tenant_key":
+			c.UsageReport.TenantKey = *usageReportTenantKey
+		case "tracing_exporter":
+			c.Tracing.Exporter = *tracingExporter
+		case "tracing_url":
+			c.Tracing.URL = *tracingURL
+		case "fault_points":
+			points, err := faults.ParsePoints(*faultPoints)
+			if err != nil {
+				log.Fatal(err)
+			}
+			c.FaultPoints = points
+		}
+	})
+}
+
 func processCmdLineArgs() {
 	var err error
-	if *debug {
-		log.SetLevel(log.DebugLevel)
+	if *configFile != "" {
+		orchestratorConfig, err = config.LoadOrchestratorConfig(*configFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		orchestratorConfig = config.NewOrchestratorConfig()
+	}
+	applyFlags(orchestratorConfig)
+	if err = orchestratorConfig.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	if err = logging.SetConfig(&logging.Config{
+		Level:           orchestratorConfig.LogLevel,
+		SubsystemLevels: orchestratorConfig.SubsystemLogLevels,
+		Format:          orchestratorConfig.LogFormat,
+		BufferSize:      orchestratorConfig.LogBufferSize,
+	}); err != nil {
+		log.Fatal(err)
+	}
+	if orchestratorConfig.SecretKeyFile != "" {
+		err = credentials.LoadEncryptionKey(orchestratorConfig.SecretKeyFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if tracingConfig := orchestratorConfig.Tracing; tracingConfig.IsSet() {
+		serviceName := tracingConfig.ServiceName
+		if serviceName == "" {
+			serviceName = config.OrchestratorName
+		}
+		if tracingConfig.Exporter == tracing.ExporterZipkin {
+			tracing.SetExporter(tracing.NewZipkinExporter(tracingConfig.URL,
+				serviceName, config.TracingFlushInterval,
+				config.TracingTimeout))
+		} else {
+			tracing.SetExporter(tracing.NewLogExporter())
+		}
+	}
+	for point, mode := range orchestratorConfig.FaultPoints {
+		if err = faults.Arm(point, mode); err != nil {
+			log.Fatal(err)
+		}
 	}
 	// Don't bother validating the Kubernetes API server address; we'll know if
 	// it's invalid during start-up.  Given that users can specify DNS names,
 	// validation would be more trouble than it's worth.
-	if *etcdV2 != "" {
-		storeClient, err = persistent_store.NewEtcdClient(*etcdV2)
+	if orchestratorConfig.EtcdV2 != "" {
+		etcdClient, err := persistent_store.NewEtcdClient(
+			orchestratorConfig.EtcdV2)
 		if err != nil {
 			panic(err)
 		}
-	} else if *etcdV2 != "" && *useInMemory {
-		log.Fatal("Cannot skip persistence and use etcdV2.")
-	} else if *useInMemory {
-		storeClient = persistent_store.NewInMemoryClient()
+		etcdClient.SetWorkers(orchestratorConfig.Bootstrap.Workers)
+		etcdClient.SetTimeout(orchestratorConfig.Timeouts.StoreTimeout())
+		storeClient = etcdClient
 	} else {
-		log.Fatal("Must specify a valid persistent store (currently " +
-			"supporting etcdV2) or no persistence.")
+		storeClient = persistent_store.NewInMemoryClient()
 	}
-	enableKubernetes = *k8sPod || *k8sAPIServer != ""
+	enableKubernetes = orchestratorConfig.K8sPod ||
+		orchestratorConfig.K8sAPIServer != ""
 }
 
 func main() {
@@ -68,15 +345,24 @@ func main() {
 	processCmdLineArgs()
 
 	orchestrator := core.NewTridentOrchestrator(storeClient)
+	orchestrator.SetBootstrapConfig(&orchestratorConfig.Bootstrap)
+	orchestrator.SetPlacementPolicy(orchestratorConfig.PlacementPolicy)
+	orchestrator.SetPlacementSeed(orchestratorConfig.PlacementSeed)
+	orchestrator.SetStrictStorageClasses(
+		orchestratorConfig.StrictStorageClasses)
+	orchestrator.SetProtectStorageClasses(
+		orchestratorConfig.ProtectStorageClasses)
+	orchestrator.SetTimeouts(&orchestratorConfig.Timeouts)
+	orchestrator.SetJanitorConfig(&orchestratorConfig.Janitor)
+	orchestrator.SetDeletionGracePeriod(orchestratorConfig.GracePeriod())
+	orchestrator.SetVolumeExpiryConfig(&orchestratorConfig.VolumeExpiry)
 
+	var kubernetesFrontend *kubernetes.KubernetesPlugin
 	if enableKubernetes {
-		var (
-			kubernetesFrontend frontend.FrontendPlugin
-			err                error
-		)
-		if *k8sAPIServer != "" {
+		var err error
+		if orchestratorConfig.K8sAPIServer != "" {
 			kubernetesFrontend, err = kubernetes.NewPlugin(orchestrator,
-				*k8sAPIServer)
+				orchestratorConfig.K8sAPIServer)
 		} else {
 			kubernetesFrontend, err = kubernetes.NewPluginInCluster(orchestrator)
 		}
@@ -86,8 +372,75 @@ func main() {
 		orchestrator.AddFrontend(kubernetesFrontend)
 		frontends = append(frontends, kubernetesFrontend)
 	}
-	restServer := rest.NewAPIServer(orchestrator, *port)
+	var restServer *rest.APIServer
+	if tlsConfig := orchestratorConfig.TLS; tlsConfig.IsSet() {
+		var err error
+		restServer, err = rest.NewTLSAPIServer(orchestrator,
+			orchestratorConfig.Address, orchestratorConfig.Port,
+			tlsConfig.CertFile, tlsConfig.KeyFile, tlsConfig.ClientCAFile)
+		if err != nil {
+			log.Fatal("Unable to start the REST frontend:  ", err)
+		}
+	} else {
+		restServer = rest.NewAPIServer(orchestrator,
+			orchestratorConfig.Address, orchestratorConfig.Port)
+	}
+	if rateConfig := orchestratorConfig.RateLimit; rateConfig.IsSet() {
+		routeRates, err := rest.ParseRouteRates(rateConfig.RouteRates)
+		if err != nil {
+			log.Fatal(err)
+		}
+		restServer.SetRateLimits(&rest.RateLimitConfig{
+			DefaultRate:             rateConfig.Rate,
+			Burst:                   rateConfig.Burst,
+			RouteRates:              routeRates,
+			MaxInFlightProvisioning: rateConfig.MaxInFlightProvisioning,
+		})
+	}
 	frontends = append(frontends, restServer)
+	watcherConfig := orchestratorConfig.BackendWatcher
+	if watcherConfig.IsSet() {
+		var source backend_watcher.Source
+		if watcherConfig.ConfigMap != "" {
+			var err error
+			source, err = kubernetesFrontend.BackendConfigSource(
+				watcherConfig.ConfigMap)
+			if err != nil {
+				log.Fatal(err)
+			}
+		} else {
+			source = backend_watcher.NewDirectorySource(
+				watcherConfig.Directory)
+		}
+		watcher := backend_watcher.NewWatcher(orchestrator, source,
+			watcherConfig.IntervalDuration())
+		orchestrator.AddFrontend(watcher)
+		frontends = append(frontends, watcher)
+	}
+	if reportConfig := orchestratorConfig.UsageReport; reportConfig.IsSet() {
+		sinks := make([]usage_report.Sink, 0, 2)
+		if reportConfig.File != "" {
+			sink, err := usage_report.NewFileSink(reportConfig.File,
+				reportConfig.Format)
+			if err != nil {
+				log.Fatal(err)
+			}
+			sinks = append(sinks, sink)
+		}
+		if reportConfig.URL != "" {
+			sinks = append(sinks, usage_report.NewHTTPSink(reportConfig.URL,
+				config.UsageReportTimeout))
+		}
+		reporter := usage_report.NewReporter(orchestrator, sinks,
+			reportConfig.IntervalDuration(), reportConfig.TenantKey)
+		orchestrator.AddFrontend(reporter)
+		frontends = append(frontends, reporter)
+	}
+	// The REST frontend is activated first, so that health and readiness
+	// probes are answered while the orchestrator bootstraps; other
+	// requests are rejected until it has.
+	restServer.Activate()
+
 	// Bootstrapping the orchestrator
 	if err := orchestrator.Bootstrap(); err != nil {
 		log.Fatal(err.Error())
@@ -97,7 +450,9 @@ func main() {
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 
 	for _, frontend := range frontends {
-		frontend.Activate()
+		if frontend != restServer {
+			frontend.Activate()
+		}
 	}
 	<-c
 	log.Info("Shutting down.")
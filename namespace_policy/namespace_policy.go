@@ -0,0 +1,111 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+// Package namespace_policy defines per-namespace provisioning policies:  the
+// storage classes a namespace's volumes may use, the class they get if they
+// name none, and how large they may be.
+package namespace_policy
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/netapp/netappdvp/utils"
+
+	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/storage"
+)
+
+type Config struct {
+	Version string `json:"version"`
+	// Namespace is the namespace whose volumes the policy applies to.
+	Namespace string `json:"namespace"`
+	// AllowedStorageClasses, if set, are the only storage classes the
+	// namespace's volumes may use.
+	AllowedStorageClasses []string `json:"allowedStorageClasses,omitempty"`
+	// DefaultStorageClass is given to volumes that name no storage class.
+	DefaultStorageClass string `json:"defaultStorageClass,omitempty"`
+	// MaxVolumeSize, if set, is the largest volume the namespace may
+	// create, e.g., 100Gi.
+	MaxVolumeSize string `json:"maxVolumeSize,omitempty"`
+}
+
+func (c *Config) Validate() error {
+	if c.Namespace == "" {
+		return fmt.Errorf("Namespace policy namespace must be specified.")
+	}
+	seen := make(map[string]bool, len(c.AllowedStorageClasses))
+	for _, name := range c.AllowedStorageClasses {
+		if name == "" {
+			return fmt.Errorf("Allowed storage class names of namespace %s "+
+				"must not be empty.", c.Namespace)
+		}
+		if seen[name] {
+			return fmt.Errorf("Storage class %s is allowed more than once "+
+				"in namespace %s.", name, c.Namespace)
+		}
+		seen[name] = true
+	}
+	if c.DefaultStorageClass != "" &&
+		!c.AllowsStorageClass(c.DefaultStorageClass) {
+		return fmt.Errorf("Default storage class %s of namespace %s is not "+
+			"an allowed storage class.", c.DefaultStorageClass, c.Namespace)
+	}
+	if _, err := c.MaxVolumeBytes(); err != nil {
+		return fmt.Errorf("Invalid maxVolumeSize of namespace %s:  %v",
+			c.Namespace, err)
+	}
+	c.Version = config.OrchestratorMajorVersion
+	return nil
+}
+
+// AllowsStorageClass returns true if the namespace's volumes may use the
+// named storage class.
+func (c *Config) AllowsStorageClass(name string) bool {
+	if len(c.AllowedStorageClasses) == 0 {
+		return true
+	}
+	for _, allowed := range c.AllowedStorageClasses {
+		if allowed == name {
+			return true
+		}
+	}
+	return false
+}
+
+// MaxVolumeBytes returns MaxVolumeSize in bytes, or 0 if it isn't set.
+func (c *Config) MaxVolumeBytes() (uint64, error) {
+	if c.MaxVolumeSize == "" {
+		return 0, nil
+	}
+	size, err := utils.ConvertSizeToBytes(c.MaxVolumeSize)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(size, 10, 64)
+}
+
+// Apply gives a volume that names no storage class the policy's default
+// class, and then returns an error if the volume's class or size violates
+// the policy.
+func (c *Config) Apply(volConfig *storage.VolumeConfig) error {
+	if volConfig.StorageClass == "" {
+		volConfig.StorageClass = c.DefaultStorageClass
+	}
+	if !c.AllowsStorageClass(volConfig.StorageClass) {
+		return fmt.Errorf("Storage class %q is not allowed in namespace %s.",
+			volConfig.StorageClass, c.Namespace)
+	}
+	maxBytes, err := c.MaxVolumeBytes()
+	if err != nil || maxBytes == 0 {
+		return err
+	}
+	size, err := volConfig.SizeBytes()
+	if err != nil {
+		return err
+	}
+	if size > maxBytes {
+		return fmt.Errorf("Volume %s is larger than the %s allowed in "+
+			"namespace %s.", volConfig.Name, c.MaxVolumeSize, c.Namespace)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package namespace_policy
+
+import (
+	"testing"
+
+	"github.com/netapp/trident/storage"
+)
+
+func TestValidate(t *testing.T) {
+	for _, policy := range []*Config{
+		{},
+		{Namespace: "ns", AllowedStorageClasses: []string{""}},
+		{Namespace: "ns", AllowedStorageClasses: []string{"gold", "gold"}},
+		{Namespace: "ns", AllowedStorageClasses: []string{"gold"},
+			DefaultStorageClass: "bronze"},
+		{Namespace: "ns", MaxVolumeSize: "lots"},
+	} {
+		if err := policy.Validate(); err == nil {
+			t.Errorf("Expected an error validating %v.", policy)
+		}
+	}
+	policy := &Config{
+		Namespace:             "ns",
+		AllowedStorageClasses: []string{"gold", "bronze"},
+		DefaultStorageClass:   "bronze",
+		MaxVolumeSize:         "10737418240",
+	}
+	if err := policy.Validate(); err != nil {
+		t.Error("Unable to validate policy:  ", err)
+	}
+}
+
+func TestApply(t *testing.T) {
+	policy := &Config{
+		Namespace:             "ns",
+		AllowedStorageClasses: []string{"gold", "bronze"},
+		DefaultStorageClass:   "bronze",
+		MaxVolumeSize:         "10737418240",
+	}
+	for _, test := range []struct {
+		storageClass string
+		size         string
+		expectedSC   string
+		valid        bool
+	}{
+		{"", "1073741824", "bronze", true},
+		{"gold", "10737418240", "gold", true},
+		{"silver", "1073741824", "silver", false},
+		{"gold", "11811160064", "gold", false},
+	} {
+		volConfig := &storage.VolumeConfig{
+			Name:         "vol",
+			StorageClass: test.storageClass,
+			Size:         test.size,
+		}
+		err := policy.Apply(volConfig)
+		if (err == nil) != test.valid {
+			t.Errorf("Class %q, size %s:  expected valid %t; got %v.",
+				test.storageClass, test.size, test.valid, err)
+		}
+		if volConfig.StorageClass != test.expectedSC {
+			t.Errorf("Class %q:  expected class %s; got %s.",
+				test.storageClass, test.expectedSC, volConfig.StorageClass)
+		}
+	}
+}
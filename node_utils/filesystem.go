@@ -0,0 +1,47 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package node_utils
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// GetFSType returns the filesystem on device, or "" if it has none.
+func GetFSType(device string) (string, error) {
+	out, err := execCommand("blkid", "-o", "value", "-s", "TYPE", device)
+	fsType := strings.TrimSpace(string(out))
+	if err != nil {
+		// blkid exits with an error and no output when it finds no
+		// filesystem signature.
+		if fsType == "" {
+			return "", nil
+		}
+		return "", fmt.Errorf("Unable to determine filesystem on %s:  %v "+
+			"(%s)", device, err, fsType)
+	}
+	return fsType, nil
+}
+
+// FormatVolume creates a filesystem of fsType on device.  options are
+// passed to mkfs, separated by spaces.
+func FormatVolume(device, fsType, options string) error {
+	args := make([]string, 0)
+	if strings.HasPrefix(fsType, "ext") {
+		// Don't prompt when formatting a whole device.
+		args = append(args, "-F")
+	}
+	args = append(args, strings.Fields(options)...)
+	args = append(args, device)
+	if out, err := execCommand("mkfs."+fsType, args...); err != nil {
+		return fmt.Errorf("Unable to format %s as %s:  %v (%s)", device,
+			fsType, err, strings.TrimSpace(string(out)))
+	}
+	log.WithFields(log.Fields{
+		"device": device,
+		"fsType": fsType,
+	}).Info("Formatted volume.")
+	return nil
+}
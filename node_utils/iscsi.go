@@ -0,0 +1,160 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package node_utils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// These locate iSCSI and multipath devices.  Tests point them elsewhere.
+var (
+	devicePathDir     = "/dev/disk/by-path"
+	sysBlockDir       = "/sys/block"
+	deviceWaitTimeout = 20 * time.Second
+	deviceWaitPoll    = 500 * time.Millisecond
+)
+
+const defaultISCSIPort = "3260"
+
+func portalWithPort(portal string) string {
+	if !strings.Contains(portal, ":") {
+		return portal + ":" + defaultISCSIPort
+	}
+	return portal
+}
+
+// hasISCSISession returns true if the node is logged in to iqn through
+// portal.
+func hasISCSISession(portal, iqn string) bool {
+	// iscsiadm exits with an error when there are no sessions.
+	out, err := execCommand("iscsiadm", "-m", "session")
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		// e.g. "tcp: [1] 10.0.0.1:3260,1035 iqn.1992-08.com.netapp:sn.1 (non-flash)"
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		if strings.SplitN(fields[2], ",", 2)[0] == portal && fields[3] == iqn {
+			return true
+		}
+	}
+	return false
+}
+
+// ISCSILogin discovers the targets behind portal and logs in to iqn, unless
+// the node is already logged in.
+func ISCSILogin(portal, iqn string) error {
+	portal = portalWithPort(portal)
+	if hasISCSISession(portal, iqn) {
+		log.WithFields(log.Fields{
+			"portal": portal,
+			"iqn":    iqn,
+		}).Debug("Already logged in to iSCSI target.")
+		return nil
+	}
+	if out, err := execCommand("iscsiadm", "-m", "discoverydb", "-t", "st",
+		"-p", portal, "--discover"); err != nil {
+		return fmt.Errorf("Unable to discover iSCSI targets at %s:  %v (%s)",
+			portal, err, strings.TrimSpace(string(out)))
+	}
+	if out, err := execCommand("iscsiadm", "-m", "node", "-T", iqn, "-p",
+		portal, "--login"); err != nil {
+		return fmt.Errorf("Unable to log in to iSCSI target %s at %s:  %v "+
+			"(%s)", iqn, portal, err, strings.TrimSpace(string(out)))
+	}
+	log.WithFields(log.Fields{
+		"portal": portal,
+		"iqn":    iqn,
+	}).Info("Logged in to iSCSI target.")
+	return nil
+}
+
+// ISCSILogout logs out of iqn.  Every volume on the target becomes
+// unavailable, so callers must be sure that none are in use.
+func ISCSILogout(portal, iqn string) error {
+	portal = portalWithPort(portal)
+	if out, err := execCommand("iscsiadm", "-m", "node", "-T", iqn, "-p",
+		portal, "--logout"); err != nil {
+		return fmt.Errorf("Unable to log out of iSCSI target %s at %s:  %v "+
+			"(%s)", iqn, portal, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func iscsiDevicePath(portal, iqn string, lun int32) string {
+	return path.Join(devicePathDir, fmt.Sprintf("ip-%s-iscsi-%s-lun-%d",
+		portalWithPort(portal), iqn, lun))
+}
+
+// waitForISCSIDevice waits for a LUN's device to appear after login and
+// returns its /dev path.
+func waitForISCSIDevice(portal, iqn string, lun int32) (string, error) {
+	byPath := iscsiDevicePath(portal, iqn, lun)
+	for start := time.Now(); ; time.Sleep(deviceWaitPoll) {
+		if device, err := filepath.EvalSymlinks(byPath); err == nil {
+			return device, nil
+		}
+		if time.Since(start) >= deviceWaitTimeout {
+			return "", fmt.Errorf("Device %s did not appear within %v.",
+				byPath, deviceWaitTimeout)
+		}
+	}
+}
+
+// multipathDevice returns the multipath device that device belongs to, or
+// device itself if it isn't part of one.
+func multipathDevice(device string) string {
+	holders, err := ioutil.ReadDir(path.Join(sysBlockDir, path.Base(device),
+		"holders"))
+	if err != nil {
+		return device
+	}
+	for _, holder := range holders {
+		if strings.HasPrefix(holder.Name(), "dm-") {
+			return path.Join(path.Dir(device), holder.Name())
+		}
+	}
+	return device
+}
+
+// removeBlockDevice flushes device, if it is a multipath device, and
+// removes it and the devices under it from the node.
+func removeBlockDevice(device string) error {
+	name := path.Base(device)
+	devices := []string{name}
+	if strings.HasPrefix(name, "dm-") {
+		slaves, err := ioutil.ReadDir(path.Join(sysBlockDir, name, "slaves"))
+		if err != nil {
+			return fmt.Errorf("Unable to list devices under %s:  %v", device,
+				err)
+		}
+		if out, err := execCommand("multipath", "-f", device); err != nil {
+			return fmt.Errorf("Unable to flush multipath device %s:  %v "+
+				"(%s)", device, err, strings.TrimSpace(string(out)))
+		}
+		devices = make([]string, 0, len(slaves))
+		for _, slave := range slaves {
+			devices = append(devices, slave.Name())
+		}
+	}
+	for _, d := range devices {
+		deletePath := path.Join(sysBlockDir, d, "device", "delete")
+		if err := ioutil.WriteFile(deletePath, []byte("1"), 0200); err != nil {
+			return fmt.Errorf("Unable to remove device %s:  %v", d, err)
+		}
+		log.WithFields(log.Fields{
+			"device": d,
+		}).Debug("Removed block device.")
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package node_utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// procMountsPath lists the node's mounts.  Tests point it elsewhere.
+var procMountsPath = "/proc/mounts"
+
+// mountSource returns the device or export mounted at mountpoint, or "" if
+// nothing is mounted there.
+func mountSource(mountpoint string) (string, error) {
+	f, err := os.Open(procMountsPath)
+	if err != nil {
+		return "", fmt.Errorf("Unable to read mounts:  %v", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		// /proc/mounts escapes spaces in paths.
+		if strings.Replace(fields[1], `\040`, " ", -1) == mountpoint {
+			return fields[0], nil
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return "", fmt.Errorf("Unable to read mounts:  %v", err)
+	}
+	return "", nil
+}
+
+// IsMounted returns true if anything is mounted at mountpoint.
+func IsMounted(mountpoint string) (bool, error) {
+	source, err := mountSource(mountpoint)
+	return source != "", err
+}
+
+// Mount mounts source at mountpoint, creating mountpoint if necessary.
+func Mount(source, mountpoint, fsType, options string) error {
+	if err := os.MkdirAll(mountpoint, 0755); err != nil {
+		return fmt.Errorf("Unable to create mount point %s:  %v", mountpoint,
+			err)
+	}
+	args := make([]string, 0)
+	if fsType != "" {
+		args = append(args, "-t", fsType)
+	}
+	if options != "" {
+		args = append(args, "-o", options)
+	}
+	args = append(args, source, mountpoint)
+	if out, err := execCommand("mount", args...); err != nil {
+		return fmt.Errorf("Unable to mount %s at %s:  %v (%s)", source,
+			mountpoint, err, strings.TrimSpace(string(out)))
+	}
+	log.WithFields(log.Fields{
+		"source":     source,
+		"mountpoint": mountpoint,
+		"fsType":     fsType,
+	}).Info("Mounted volume.")
+	return nil
+}
+
+// MountNFS mounts an NFS export at mountpoint.
+func MountNFS(server, path, mountpoint, options string) error {
+	return Mount(fmt.Sprintf("%s:%s", server, path), mountpoint, "nfs",
+		options)
+}
+
+func Unmount(mountpoint string) error {
+	if out, err := execCommand("umount", mountpoint); err != nil {
+		return fmt.Errorf("Unable to unmount %s:  %v (%s)", mountpoint, err,
+			strings.TrimSpace(string(out)))
+	}
+	log.WithFields(log.Fields{
+		"mountpoint": mountpoint,
+	}).Info("Unmounted volume.")
+	return nil
+}
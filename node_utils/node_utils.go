@@ -0,0 +1,164 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+// Package node_utils attaches Trident volumes to the node it runs on:  it
+// logs in to iSCSI targets, finds multipath devices, formats new block
+// volumes, and mounts and unmounts NFS and block volumes.  Frontends that
+// run on the node share it so that there is one implementation of the
+// node-side steps.
+package node_utils
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// DefaultFSType is used for block volumes that don't specify a filesystem.
+const DefaultFSType = "ext4"
+
+// VolumeAttachInfo describes how to reach a volume from the node.  It
+// mirrors storage.VolumeAccessInfo, so that node-side code doesn't depend on
+// the storage drivers, and adds the node-side options.
+type VolumeAttachInfo struct {
+	NfsServerIP       string `json:"nfsServerIp,omitempty"`
+	NfsPath           string `json:"nfsPath,omitempty"`
+	IscsiTargetPortal string `json:"iscsiTargetPortal,omitempty"`
+	IscsiTargetIQN    string `json:"iscsiTargetIqn,omitempty"`
+	IscsiLunNumber    int32  `json:"iscsiLunNumber,omitempty"`
+	// FSType is the filesystem to create on an unformatted block volume.
+	FSType       string `json:"fsType,omitempty"`
+	MkfsOptions  string `json:"mkfsOptions,omitempty"`
+	MountOptions string `json:"mountOptions,omitempty"`
+	// Formatted is set once Trident has formatted a block volume, so that a
+	// volume that appears blank is never reformatted.
+	Formatted bool `json:"formatted,omitempty"`
+}
+
+func (i *VolumeAttachInfo) isNFS() bool {
+	return i.NfsServerIP != ""
+}
+
+func (i *VolumeAttachInfo) isISCSI() bool {
+	return i.IscsiTargetIQN != ""
+}
+
+func (i *VolumeAttachInfo) Validate() error {
+	switch {
+	case i.isNFS() && i.isISCSI():
+		return fmt.Errorf("Volume attach info must be for NFS or iSCSI, " +
+			"not both.")
+	case i.isNFS():
+		if i.NfsPath == "" {
+			return fmt.Errorf("NFS volumes require a path.")
+		}
+	case i.isISCSI():
+		if i.IscsiTargetPortal == "" {
+			return fmt.Errorf("iSCSI volumes require a target portal.")
+		}
+	default:
+		return fmt.Errorf("Volume attach info has no NFS or iSCSI details.")
+	}
+	return nil
+}
+
+// execCommand runs a command and returns its combined output.  Tests
+// replace it to avoid touching the node.
+var execCommand = func(name string, args ...string) ([]byte, error) {
+	log.WithFields(log.Fields{
+		"command": name,
+		"args":    args,
+	}).Debug("Running command.")
+	return exec.Command(name, args...).CombinedOutput()
+}
+
+// AttachVolume makes a volume available at mountpoint.  Block volumes are
+// logged in to and formatted if they have no filesystem; the returned bool
+// is true if the volume was formatted, so that the caller can record it.
+// Attaching a volume that is already mounted at mountpoint does nothing.
+func AttachVolume(info *VolumeAttachInfo, mountpoint string) (bool, error) {
+	if err := info.Validate(); err != nil {
+		return false, err
+	}
+	mounted, err := IsMounted(mountpoint)
+	if err != nil {
+		return false, err
+	}
+	if mounted {
+		log.WithFields(log.Fields{
+			"mountpoint": mountpoint,
+		}).Debug("Volume is already mounted.")
+		return false, nil
+	}
+	if info.isNFS() {
+		return false, MountNFS(info.NfsServerIP, info.NfsPath, mountpoint,
+			info.MountOptions)
+	}
+
+	if err = ISCSILogin(info.IscsiTargetPortal, info.IscsiTargetIQN); err != nil {
+		return false, err
+	}
+	device, err := waitForISCSIDevice(info.IscsiTargetPortal,
+		info.IscsiTargetIQN, info.IscsiLunNumber)
+	if err != nil {
+		return false, err
+	}
+	device = multipathDevice(device)
+	fsType, err := GetFSType(device)
+	if err != nil {
+		return false, err
+	}
+	wantFSType := info.FSType
+	if wantFSType == "" {
+		wantFSType = DefaultFSType
+	}
+	formatted := false
+	switch {
+	case fsType == "" && info.Formatted:
+		return false, fmt.Errorf("Device %s has no filesystem, but the "+
+			"volume was already formatted; refusing to reformat it.", device)
+	case fsType == "":
+		if err = FormatVolume(device, wantFSType, info.MkfsOptions); err != nil {
+			return false, err
+		}
+		fsType = wantFSType
+		formatted = true
+	case info.FSType != "" && fsType != info.FSType:
+		return false, fmt.Errorf("Device %s is formatted as %s, not %s.",
+			device, fsType, info.FSType)
+	}
+	return formatted, Mount(device, mountpoint, fsType, info.MountOptions)
+}
+
+// DetachVolume unmounts a volume.  For block volumes, it also removes the
+// volume's devices from the node; the iSCSI session is left in place, since
+// other volumes on the same target may be using it.
+func DetachVolume(info *VolumeAttachInfo, mountpoint string) error {
+	if err := info.Validate(); err != nil {
+		return err
+	}
+	mounted, err := IsMounted(mountpoint)
+	if err != nil {
+		return err
+	}
+	if mounted {
+		if err = Unmount(mountpoint); err != nil {
+			return err
+		}
+	}
+	if !info.isISCSI() {
+		return nil
+	}
+	device, err := filepath.EvalSymlinks(iscsiDevicePath(
+		info.IscsiTargetPortal, info.IscsiTargetIQN, info.IscsiLunNumber))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"portal": info.IscsiTargetPortal,
+			"iqn":    info.IscsiTargetIQN,
+			"lun":    info.IscsiLunNumber,
+		}).Debug("No device found for volume.")
+		return nil
+	}
+	return removeBlockDevice(multipathDevice(device))
+}
@@ -0,0 +1,239 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package node_utils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeResult struct {
+	out string
+	err error
+}
+
+// fakeNode replaces the node's commands and files for the duration of a
+// test.  Commands not in results succeed with no output.
+type fakeNode struct {
+	root     string
+	commands []string
+	results  map[string]fakeResult
+}
+
+func newFakeNode(t *testing.T) *fakeNode {
+	root, err := ioutil.TempDir("", "node_utils")
+	if err != nil {
+		t.Fatal("Unable to create temporary directory:  ", err)
+	}
+	n := &fakeNode{root: root, results: make(map[string]fakeResult)}
+	for _, dir := range []string{"by-path", "sys", "dev"} {
+		if err = os.MkdirAll(path.Join(root, dir), 0755); err != nil {
+			t.Fatal("Unable to create directory:  ", err)
+		}
+	}
+	procMountsPath = path.Join(root, "mounts")
+	devicePathDir = path.Join(root, "by-path")
+	sysBlockDir = path.Join(root, "sys")
+	deviceWaitTimeout = 10 * time.Millisecond
+	deviceWaitPoll = time.Millisecond
+	n.setMounts("")
+	execCommand = func(name string, args ...string) ([]byte, error) {
+		command := strings.Join(append([]string{name}, args...), " ")
+		n.commands = append(n.commands, command)
+		result := n.results[command]
+		return []byte(result.out), result.err
+	}
+	return n
+}
+
+func (n *fakeNode) cleanup() {
+	os.RemoveAll(n.root)
+}
+
+func (n *fakeNode) path(elem ...string) string {
+	return path.Join(append([]string{n.root}, elem...)...)
+}
+
+func (n *fakeNode) setMounts(mounts string) {
+	if err := ioutil.WriteFile(procMountsPath, []byte(mounts), 0644); err != nil {
+		panic(err)
+	}
+}
+
+func (n *fakeNode) mkdir(elem ...string) {
+	if err := os.MkdirAll(n.path(elem...), 0755); err != nil {
+		panic(err)
+	}
+}
+
+func (n *fakeNode) touch(elem ...string) {
+	if err := ioutil.WriteFile(n.path(elem...), nil, 0644); err != nil {
+		panic(err)
+	}
+}
+
+func TestAttachNFSVolume(t *testing.T) {
+	n := newFakeNode(t)
+	defer n.cleanup()
+	mountpoint := n.path("mnt", "nfs")
+	info := &VolumeAttachInfo{
+		NfsServerIP:  "10.0.0.1",
+		NfsPath:      "/trident_vol",
+		MountOptions: "nfsvers=3",
+	}
+	if _, err := AttachVolume(info, mountpoint); err != nil {
+		t.Fatal("Unable to attach volume:  ", err)
+	}
+	expected := []string{fmt.Sprintf(
+		"mount -t nfs -o nfsvers=3 10.0.0.1:/trident_vol %s", mountpoint)}
+	if !reflect.DeepEqual(n.commands, expected) {
+		t.Errorf("Expected commands %v; got %v.", expected, n.commands)
+	}
+
+	// Attaching a mounted volume does nothing.
+	n.commands = nil
+	n.setMounts(fmt.Sprintf("10.0.0.1:/trident_vol %s nfs rw 0 0\n",
+		mountpoint))
+	if _, err := AttachVolume(info, mountpoint); err != nil {
+		t.Fatal("Unable to attach mounted volume:  ", err)
+	}
+	if len(n.commands) != 0 {
+		t.Errorf("Expected no commands; got %v.", n.commands)
+	}
+
+	if err := DetachVolume(info, mountpoint); err != nil {
+		t.Fatal("Unable to detach volume:  ", err)
+	}
+	expected = []string{"umount " + mountpoint}
+	if !reflect.DeepEqual(n.commands, expected) {
+		t.Errorf("Expected commands %v; got %v.", expected, n.commands)
+	}
+}
+
+func TestAttachISCSIVolume(t *testing.T) {
+	n := newFakeNode(t)
+	defer n.cleanup()
+	const (
+		portal = "10.0.0.2"
+		iqn    = "iqn.1992-08.com.netapp:sn.1"
+	)
+	mountpoint := n.path("mnt", "iscsi")
+	info := &VolumeAttachInfo{
+		IscsiTargetPortal: portal,
+		IscsiTargetIQN:    iqn,
+		IscsiLunNumber:    3,
+		MkfsOptions:       "-E nodiscard",
+	}
+
+	// The device never appears.
+	if _, err := AttachVolume(info, mountpoint); err == nil {
+		t.Error("Attached a volume without a device.")
+	}
+
+	n.commands = nil
+	n.touch("dev", "sdb")
+	n.mkdir("dev", "dm-0")
+	n.mkdir("sys", "sdb", "holders", "dm-0")
+	if err := os.Symlink(n.path("dev", "sdb"), iscsiDevicePath(portal, iqn,
+		3)); err != nil {
+		t.Fatal("Unable to create device link:  ", err)
+	}
+	n.results["iscsiadm -m session"] = fakeResult{
+		err: fmt.Errorf("exit status 21")}
+	multipath := n.path("dev", "dm-0")
+	n.results["blkid -o value -s TYPE "+multipath] = fakeResult{
+		err: fmt.Errorf("exit status 2")}
+	formatted, err := AttachVolume(info, mountpoint)
+	if err != nil {
+		t.Fatal("Unable to attach volume:  ", err)
+	}
+	if !formatted {
+		t.Error("Expected the volume to be formatted.")
+	}
+	expected := []string{
+		"iscsiadm -m session",
+		"iscsiadm -m discoverydb -t st -p 10.0.0.2:3260 --discover",
+		"iscsiadm -m node -T " + iqn + " -p 10.0.0.2:3260 --login",
+		"blkid -o value -s TYPE " + multipath,
+		"mkfs.ext4 -F -E nodiscard " + multipath,
+		fmt.Sprintf("mount -t ext4 %s %s", multipath, mountpoint),
+	}
+	if !reflect.DeepEqual(n.commands, expected) {
+		t.Errorf("Expected commands %v; got %v.", expected, n.commands)
+	}
+
+	// A formatted volume that appears blank is never reformatted.
+	n.commands = nil
+	n.results["iscsiadm -m session"] = fakeResult{
+		out: "tcp: [1] 10.0.0.2:3260,1035 " + iqn + " (non-flash)\n"}
+	info.Formatted = true
+	if _, err = AttachVolume(info, mountpoint); err == nil {
+		t.Error("Reformatted a formatted volume.")
+	}
+
+	// Nor is a device with a different filesystem mounted.
+	n.results["blkid -o value -s TYPE "+multipath] = fakeResult{
+		out: "xfs\n"}
+	info.FSType = "ext4"
+	if _, err = AttachVolume(info, mountpoint); err == nil {
+		t.Error("Mounted an xfs device as ext4.")
+	}
+
+	// A formatted device on an existing session is only mounted.
+	n.commands = nil
+	info.FSType = "xfs"
+	if formatted, err = AttachVolume(info, mountpoint); err != nil {
+		t.Fatal("Unable to attach volume:  ", err)
+	}
+	if formatted {
+		t.Error("Expected the volume not to be formatted.")
+	}
+	expected = []string{
+		"iscsiadm -m session",
+		"blkid -o value -s TYPE " + multipath,
+		fmt.Sprintf("mount -t xfs %s %s", multipath, mountpoint),
+	}
+	if !reflect.DeepEqual(n.commands, expected) {
+		t.Errorf("Expected commands %v; got %v.", expected, n.commands)
+	}
+
+	n.commands = nil
+	n.setMounts(fmt.Sprintf("%s %s xfs rw 0 0\n", multipath, mountpoint))
+	n.mkdir("sys", "dm-0", "slaves", "sdb")
+	n.mkdir("sys", "sdb", "device")
+	n.touch("sys", "sdb", "device", "delete")
+	if err := DetachVolume(info, mountpoint); err != nil {
+		t.Fatal("Unable to detach volume:  ", err)
+	}
+	expected = []string{
+		"umount " + mountpoint,
+		"multipath -f " + multipath,
+	}
+	if !reflect.DeepEqual(n.commands, expected) {
+		t.Errorf("Expected commands %v; got %v.", expected, n.commands)
+	}
+	if deleted, _ := ioutil.ReadFile(n.path("sys", "sdb", "device",
+		"delete")); string(deleted) != "1" {
+		t.Error("Device was not removed.")
+	}
+}
+
+func TestValidateAttachInfo(t *testing.T) {
+	for _, info := range []*VolumeAttachInfo{
+		{},
+		{NfsServerIP: "10.0.0.1"},
+		{IscsiTargetIQN: "iqn.1992-08.com.netapp:sn.1"},
+		{NfsServerIP: "10.0.0.1", NfsPath: "/vol",
+			IscsiTargetIQN: "iqn.1992-08.com.netapp:sn.1"},
+	} {
+		if err := info.Validate(); err == nil {
+			t.Errorf("Expected an error validating %+v.", info)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package persistent_store
+
+import (
+	"time"
+)
+
+type BackendOperation string
+
+const (
+	RenameBackend BackendOperation = "renameBackend"
+)
+
+// BackendTransaction records a backend operation that spans several stored
+// records, so that it can be completed if Trident stops partway through.
+type BackendTransaction struct {
+	Op BackendOperation
+	// Name is the backend's new name for RenameBackend transactions.
+	Name string
+	// OldName is the backend's previous name for RenameBackend
+	// transactions.
+	OldName string `json:",omitempty"`
+	// Created is when the transaction was first stored.
+	Created time.Time
+}
+
+// setCreated records the creation time of a transaction being stored.
+func (bt *BackendTransaction) setCreated() {
+	if bt.Created.IsZero() {
+		bt.Created = time.Now()
+	}
+}
+
+// getKey returns a unique identifier for the BackendTransaction.  Like
+// volume transactions, backend transactions are identified by name.
+func (bt *BackendTransaction) getKey() string {
+	return bt.Name
+}
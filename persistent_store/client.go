@@ -3,6 +3,12 @@
 package persistent_store
 
 import (
+	"golang.org/x/net/context"
+
+	"github.com/netapp/trident/app_template"
+	"github.com/netapp/trident/hooks"
+	"github.com/netapp/trident/namespace_policy"
+	"github.com/netapp/trident/snapshot_policy"
 	"github.com/netapp/trident/storage"
 	"github.com/netapp/trident/storage_class"
 )
@@ -29,8 +35,54 @@ type Client interface {
 		error)
 	DeleteVolumeTransaction(volTxn *VolumeTransaction) error
 
+	AddBackendTransaction(backendTxn *BackendTransaction) error
+	GetBackendTransactions() ([]*BackendTransaction, error)
+	DeleteBackendTransaction(backendTxn *BackendTransaction) error
+
 	AddStorageClass(sc *storage_class.StorageClass) error
 	GetStorageClass(scName string) (*storage_class.StorageClassPersistent, error)
 	GetStorageClasses() ([]*storage_class.StorageClassPersistent, error)
+	UpdateStorageClass(sc *storage_class.StorageClass) error
 	DeleteStorageClass(sc *storage_class.StorageClass) error
+
+	AddHook(h *hooks.Config) error
+	GetHook(hookName string) (*hooks.Config, error)
+	GetHooks() ([]*hooks.Config, error)
+	DeleteHook(h *hooks.Config) error
+
+	AddSnapshotPolicy(p *snapshot_policy.Config) error
+	GetSnapshotPolicy(policyName string) (*snapshot_policy.Config, error)
+	GetSnapshotPolicies() ([]*snapshot_policy.Config, error)
+	DeleteSnapshotPolicy(p *snapshot_policy.Config) error
+
+	AddRetainedVolume(vol *RetainedVolume) error
+	GetRetainedVolumes() ([]*RetainedVolume, error)
+	DeleteRetainedVolume(vol *RetainedVolume) error
+
+	AddProvisioningRetry(retry *ProvisioningRetry) error
+	GetProvisioningRetries() ([]*ProvisioningRetry, error)
+	DeleteProvisioningRetry(retry *ProvisioningRetry) error
+
+	AddZombieVolume(zombie *ZombieVolume) error
+	GetZombieVolumes() ([]*ZombieVolume, error)
+	DeleteZombieVolume(zombie *ZombieVolume) error
+
+	AddVolumeGroup(group *VolumeGroup) error
+	GetVolumeGroups() ([]*VolumeGroup, error)
+	DeleteVolumeGroup(group *VolumeGroup) error
+
+	AddTemplate(t *app_template.Config) error
+	GetTemplates() ([]*app_template.Config, error)
+	DeleteTemplate(t *app_template.Config) error
+
+	AddNamespacePolicy(p *namespace_policy.Config) error
+	GetNamespacePolicies() ([]*namespace_policy.Config, error)
+	DeleteNamespacePolicy(p *namespace_policy.Config) error
+
+	QuarantineRecord(recordType RecordType, name, reason string) error
+	GetQuarantinedRecords() ([]*QuarantinedRecord, error)
+
+	// WithContext returns a client whose requests are abandoned when ctx is
+	// done.
+	WithContext(ctx context.Context) Client
 }
@@ -6,20 +6,32 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 
 	etcdclientv2 "github.com/coreos/etcd/client"
 	"golang.org/x/net/context"
 
+	"github.com/netapp/trident/app_template"
 	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/hooks"
+	"github.com/netapp/trident/namespace_policy"
+	"github.com/netapp/trident/snapshot_policy"
 	"github.com/netapp/trident/storage"
 	"github.com/netapp/trident/storage_class"
+	"github.com/netapp/trident/tracing"
 )
 
 type EtcdClient struct {
 	clientV2 *etcdclientv2.Client
 	keysAPI  etcdclientv2.KeysAPI
+	workers  int
+	// timeout bounds each etcd request.
+	timeout time.Duration
+	// ctx bounds every etcd request; see WithContext.
+	ctx context.Context
 }
 
 func NewEtcdClient(etcdIP string) (*EtcdClient, error) {
@@ -33,14 +45,47 @@ func NewEtcdClient(etcdIP string) (*EtcdClient, error) {
 	return &EtcdClient{
 		clientV2: &c,
 		keysAPI:  etcdclientv2.NewKeysAPI(c),
+		workers:  config.BootstrapWorkers,
+		timeout:  config.PersistentStoreTimeout,
+		ctx:      context.Background(),
 	}, nil
 }
 
+// WithContext returns a client whose requests are canceled when ctx is
+// done, in addition to timing out; see SetTimeout.
+func (p *EtcdClient) WithContext(ctx context.Context) Client {
+	client := *p
+	client.ctx = ctx
+	return &client
+}
+
+// SetWorkers sets how many records GetBackends and GetVolumes read
+// concurrently.
+func (p *EtcdClient) SetWorkers(workers int) {
+	p.workers = workers
+}
+
+// SetTimeout sets how long each etcd request may take.  The default is
+// config.PersistentStoreTimeout.
+func (p *EtcdClient) SetTimeout(timeout time.Duration) {
+	p.timeout = timeout
+}
+
+// startSpan traces an etcd request within the trace of the request p.ctx
+// belongs to, if any.
+func (p *EtcdClient) startSpan(operation, key string) *tracing.Span {
+	_, span := tracing.StartChildSpan(p.ctx, tracing.PersistentStore,
+		"etcd "+operation, map[string]string{"key": key})
+	return span
+}
+
 // the abstract CRUD interface
 func (p *EtcdClient) Create(key, value string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), config.PersistentStoreTimeout)
+	span := p.startSpan("create", key)
+	ctx, cancel := context.WithTimeout(p.ctx, p.timeout)
 	_, err := p.keysAPI.Create(ctx, key, value)
 	cancel()
+	span.Finish(err)
 	if err != nil {
 		return err
 	}
@@ -48,9 +93,11 @@ func (p *EtcdClient) Create(key, value string) error {
 }
 
 func (p *EtcdClient) Read(key string) (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), config.PersistentStoreTimeout)
+	span := p.startSpan("get", key)
+	ctx, cancel := context.WithTimeout(p.ctx, p.timeout)
 	resp, err := p.keysAPI.Get(ctx, key, &etcdclientv2.GetOptions{true, true, true})
 	cancel()
+	span.Finish(err)
 	if err != nil {
 		if etcdErr, ok := err.(etcdclientv2.Error); ok && etcdErr.Code == etcdclientv2.ErrorCodeKeyNotFound {
 			return "", KeyError{Key: key}
@@ -63,9 +110,11 @@ func (p *EtcdClient) Read(key string) (string, error) {
 // This method returns all the keys with the designated prefix
 func (p *EtcdClient) ReadKeys(keyPrefix string) ([]string, error) {
 	keys := make([]string, 0)
-	ctx, cancel := context.WithTimeout(context.Background(), config.PersistentStoreTimeout)
+	span := p.startSpan("list", keyPrefix)
+	ctx, cancel := context.WithTimeout(p.ctx, p.timeout)
 	resp, err := p.keysAPI.Get(ctx, keyPrefix, &etcdclientv2.GetOptions{true, true, true})
 	cancel()
+	span.Finish(err)
 	if err != nil {
 		if err.Error() == etcdclientv2.ErrClusterUnavailable.Error() {
 			log.Warn("etcd not yet online; waiting.")
@@ -88,10 +137,38 @@ func (p *EtcdClient) ReadKeys(keyPrefix string) ([]string, error) {
 	return keys, nil
 }
 
+// forEachKey calls get for each key, running up to p.workers calls
+// concurrently, and returns the error for the first failing key.
+func (p *EtcdClient) forEachKey(
+	keys []string, get func(i int, key string) error,
+) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(keys))
+	sem := make(chan struct{}, p.workers)
+	for i, key := range keys {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, key string) {
+			defer wg.Done()
+			errs[i] = get(i, key)
+			<-sem
+		}(i, key)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (p *EtcdClient) Update(key, value string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), config.PersistentStoreTimeout)
+	span := p.startSpan("update", key)
+	ctx, cancel := context.WithTimeout(p.ctx, p.timeout)
 	_, err := p.keysAPI.Update(ctx, key, value)
 	cancel()
+	span.Finish(err)
 	if err != nil {
 		return err
 	}
@@ -99,9 +176,11 @@ func (p *EtcdClient) Update(key, value string) error {
 }
 
 func (p *EtcdClient) Set(key, value string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), config.PersistentStoreTimeout)
+	span := p.startSpan("set", key)
+	ctx, cancel := context.WithTimeout(p.ctx, p.timeout)
 	_, err := p.keysAPI.Set(ctx, key, value, &etcdclientv2.SetOptions{})
 	cancel()
+	span.Finish(err)
 	if err != nil {
 		return err
 	}
@@ -109,9 +188,11 @@ func (p *EtcdClient) Set(key, value string) error {
 }
 
 func (p *EtcdClient) Delete(key string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), config.PersistentStoreTimeout)
+	span := p.startSpan("delete", key)
+	ctx, cancel := context.WithTimeout(p.ctx, p.timeout)
 	_, err := p.keysAPI.Delete(ctx, key, &etcdclientv2.DeleteOptions{Recursive: true})
 	cancel()
+	span.Finish(err)
 	if err != nil {
 		return err
 	}
@@ -121,6 +202,9 @@ func (p *EtcdClient) Delete(key string) error {
 // This method saves the minimally required backend state to the persistent store
 func (p *EtcdClient) AddBackend(b *storage.StorageBackend) error {
 	backend := b.ConstructPersistent()
+	if err := backend.EncryptSecrets(); err != nil {
+		return err
+	}
 	backendJSON, err := json.Marshal(backend)
 	if err != nil {
 		return err
@@ -141,7 +225,11 @@ func (p *EtcdClient) GetBackend(backendName string) (*storage.StorageBackendPers
 	}
 	err = json.Unmarshal([]byte(backendJSON), &backend)
 	if err != nil {
-		return nil, err
+		return nil, &CorruptRecordError{BackendRecord, backendName, err}
+	}
+	if backend.Name == "" {
+		return nil, &CorruptRecordError{BackendRecord, backendName,
+			fmt.Errorf("record has no name")}
 	}
 	return &backend, nil
 }
@@ -149,6 +237,9 @@ func (p *EtcdClient) GetBackend(backendName string) (*storage.StorageBackendPers
 // This method updates the backend state on the persistent store
 func (p *EtcdClient) UpdateBackend(b *storage.StorageBackend) error {
 	backend := b.ConstructPersistent()
+	if err := backend.EncryptSecrets(); err != nil {
+		return err
+	}
 	backendJSON, err := json.Marshal(backend)
 	if err != nil {
 		return err
@@ -171,17 +262,18 @@ func (p *EtcdClient) DeleteBackend(backend *storage.StorageBackend) error {
 
 // This method retrieves all backends
 func (p *EtcdClient) GetBackends() ([]*storage.StorageBackendPersistent, error) {
-	backendList := make([]*storage.StorageBackendPersistent, 0)
 	keys, err := p.ReadKeys(config.BackendURL)
 	if err != nil {
 		return nil, err
 	}
-	for _, key := range keys {
+	backendList := make([]*storage.StorageBackendPersistent, len(keys))
+	err = p.forEachKey(keys, func(i int, key string) error {
 		backend, err := p.GetBackend(strings.TrimPrefix(key, config.BackendURL+"/"))
-		if err != nil {
-			return nil, err
-		}
-		backendList = append(backendList, backend)
+		backendList[i] = backend
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 	return backendList, nil
 }
@@ -223,7 +315,11 @@ func (p *EtcdClient) GetVolume(volName string) (*storage.VolumeExternal, error)
 	volExternal := &storage.VolumeExternal{}
 	err = json.Unmarshal([]byte(volJSON), volExternal)
 	if err != nil {
-		return nil, err
+		return nil, &CorruptRecordError{VolumeRecord, volName, err}
+	}
+	if volExternal.Config == nil || volExternal.Config.Name == "" {
+		return nil, &CorruptRecordError{VolumeRecord, volName,
+			fmt.Errorf("record has no volume config")}
 	}
 	return volExternal, nil
 }
@@ -261,17 +357,20 @@ func (p *EtcdClient) DeleteVolumeIgnoreNotFound(vol *storage.Volume) error {
 
 // This method retrieves all volumes
 func (p *EtcdClient) GetVolumes() ([]*storage.VolumeExternal, error) {
-	volumeList := make([]*storage.VolumeExternal, 0)
 	keys, err := p.ReadKeys(config.VolumeURL)
 	if err != nil {
 		return nil, err
 	}
-	for _, key := range keys {
-		vol, err := p.GetVolume(strings.TrimPrefix(key, config.VolumeURL))
-		if err != nil {
-			return nil, err
-		}
-		volumeList = append(volumeList, vol)
+	volumeList := make([]*storage.VolumeExternal, len(keys))
+	err = p.forEachKey(keys, func(i int, key string) error {
+		// Trim the separator too, so that corrupt records are reported,
+		// and quarantined, by the volume's name.
+		vol, err := p.GetVolume(strings.TrimPrefix(key, config.VolumeURL+"/"))
+		volumeList[i] = vol
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 	return volumeList, nil
 }
@@ -292,6 +391,7 @@ func (p *EtcdClient) DeleteVolumes() error {
 
 // This method logs an AddVolume operation
 func (p *EtcdClient) AddVolumeTransaction(volTxn *VolumeTransaction) error {
+	volTxn.setCreated()
 	volTxnJSON, err := json.Marshal(volTxn)
 	if err != nil {
 		return err
@@ -360,6 +460,49 @@ func (p *EtcdClient) DeleteVolumeTransaction(volTxn *VolumeTransaction) error {
 	return nil
 }
 
+// AddBackendTransaction stores a backend operation, replacing any
+// transaction with the same key.
+func (p *EtcdClient) AddBackendTransaction(
+	backendTxn *BackendTransaction,
+) error {
+	backendTxn.setCreated()
+	backendTxnJSON, err := json.Marshal(backendTxn)
+	if err != nil {
+		return err
+	}
+	return p.Set(config.BackendTransactionURL+"/"+backendTxn.getKey(),
+		string(backendTxnJSON))
+}
+
+// GetBackendTransactions retrieves the stored backend operations.
+func (p *EtcdClient) GetBackendTransactions() ([]*BackendTransaction, error) {
+	backendTxnList := make([]*BackendTransaction, 0)
+	keys, err := p.ReadKeys(config.BackendTransactionURL)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		backendTxn := &BackendTransaction{}
+		backendTxnJSON, err := p.Read(key)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal([]byte(backendTxnJSON), backendTxn)
+		if err != nil {
+			return nil, err
+		}
+		backendTxnList = append(backendTxnList, backendTxn)
+	}
+	return backendTxnList, nil
+}
+
+// DeleteBackendTransaction removes a completed backend operation.
+func (p *EtcdClient) DeleteBackendTransaction(
+	backendTxn *BackendTransaction,
+) error {
+	return p.Delete(config.BackendTransactionURL + "/" + backendTxn.getKey())
+}
+
 func (p *EtcdClient) AddStorageClass(sc *storage_class.StorageClass) error {
 	storageClass := sc.ConstructPersistent()
 	storageClassJSON, err := json.Marshal(storageClass)
@@ -410,6 +553,17 @@ func (p *EtcdClient) GetStorageClasses() ([]*storage_class.StorageClassPersisten
 	return ret, nil
 }
 
+// UpdateStorageClass replaces the stored config of an existing storage
+// class.
+func (p *EtcdClient) UpdateStorageClass(sc *storage_class.StorageClass) error {
+	storageClassJSON, err := json.Marshal(sc.ConstructPersistent())
+	if err != nil {
+		return err
+	}
+	return p.Update(config.StorageClassURL+"/"+sc.GetName(),
+		string(storageClassJSON))
+}
+
 // DeleteStorageClass deletes a storage class's state from the persistent store
 func (p *EtcdClient) DeleteStorageClass(sc *storage_class.StorageClass) error {
 	err := p.Delete(config.StorageClassURL + "/" + sc.GetName())
@@ -418,3 +572,342 @@ func (p *EtcdClient) DeleteStorageClass(sc *storage_class.StorageClass) error {
 	}
 	return nil
 }
+
+func (p *EtcdClient) AddHook(h *hooks.Config) error {
+	hookJSON, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	return p.Create(config.HookURL+"/"+h.Name, string(hookJSON))
+}
+
+func (p *EtcdClient) GetHook(hookName string) (*hooks.Config, error) {
+	var hook hooks.Config
+	hookJSON, err := p.Read(config.HookURL + "/" + hookName)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal([]byte(hookJSON), &hook); err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+func (p *EtcdClient) GetHooks() ([]*hooks.Config, error) {
+	keys, err := p.ReadKeys(config.HookURL)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*hooks.Config, 0, len(keys))
+	for _, key := range keys {
+		hook, err := p.GetHook(strings.TrimPrefix(key, config.HookURL+"/"))
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, hook)
+	}
+	return ret, nil
+}
+
+func (p *EtcdClient) DeleteHook(h *hooks.Config) error {
+	return p.Delete(config.HookURL + "/" + h.Name)
+}
+
+func (p *EtcdClient) AddSnapshotPolicy(policy *snapshot_policy.Config) error {
+	policyJSON, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	return p.Create(config.SnapshotPolicyURL+"/"+policy.Name,
+		string(policyJSON))
+}
+
+func (p *EtcdClient) GetSnapshotPolicy(policyName string) (
+	*snapshot_policy.Config, error,
+) {
+	var policy snapshot_policy.Config
+	policyJSON, err := p.Read(config.SnapshotPolicyURL + "/" + policyName)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal([]byte(policyJSON), &policy); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (p *EtcdClient) GetSnapshotPolicies() ([]*snapshot_policy.Config, error) {
+	keys, err := p.ReadKeys(config.SnapshotPolicyURL)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*snapshot_policy.Config, 0, len(keys))
+	for _, key := range keys {
+		policy, err := p.GetSnapshotPolicy(
+			strings.TrimPrefix(key, config.SnapshotPolicyURL+"/"))
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, policy)
+	}
+	return ret, nil
+}
+
+func (p *EtcdClient) DeleteSnapshotPolicy(policy *snapshot_policy.Config) error {
+	return p.Delete(config.SnapshotPolicyURL + "/" + policy.Name)
+}
+
+// AddRetainedVolume records a volume left on its backend, replacing any
+// record of a volume with the same name.
+func (p *EtcdClient) AddRetainedVolume(vol *RetainedVolume) error {
+	volJSON, err := json.Marshal(vol)
+	if err != nil {
+		return err
+	}
+	return p.Set(config.RetainedVolumeURL+"/"+vol.Config.Name,
+		string(volJSON))
+}
+
+func (p *EtcdClient) GetRetainedVolumes() ([]*RetainedVolume, error) {
+	keys, err := p.ReadKeys(config.RetainedVolumeURL)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*RetainedVolume, 0, len(keys))
+	for _, key := range keys {
+		volJSON, err := p.Read(key)
+		if err != nil {
+			return nil, err
+		}
+		vol := &RetainedVolume{}
+		if err = json.Unmarshal([]byte(volJSON), vol); err != nil {
+			return nil, err
+		}
+		ret = append(ret, vol)
+	}
+	return ret, nil
+}
+
+func (p *EtcdClient) DeleteRetainedVolume(vol *RetainedVolume) error {
+	return p.Delete(config.RetainedVolumeURL + "/" + vol.Config.Name)
+}
+
+func (p *EtcdClient) AddVolumeGroup(group *VolumeGroup) error {
+	groupJSON, err := json.Marshal(group)
+	if err != nil {
+		return err
+	}
+	return p.Create(config.VolumeGroupURL+"/"+group.Name, string(groupJSON))
+}
+
+func (p *EtcdClient) GetVolumeGroups() ([]*VolumeGroup, error) {
+	keys, err := p.ReadKeys(config.VolumeGroupURL)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*VolumeGroup, 0, len(keys))
+	for _, key := range keys {
+		groupJSON, err := p.Read(key)
+		if err != nil {
+			return nil, err
+		}
+		group := &VolumeGroup{}
+		if err = json.Unmarshal([]byte(groupJSON), group); err != nil {
+			return nil, err
+		}
+		ret = append(ret, group)
+	}
+	return ret, nil
+}
+
+func (p *EtcdClient) DeleteVolumeGroup(group *VolumeGroup) error {
+	return p.Delete(config.VolumeGroupURL + "/" + group.Name)
+}
+
+func (p *EtcdClient) AddTemplate(t *app_template.Config) error {
+	templateJSON, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return p.Create(config.TemplateURL+"/"+t.Name, string(templateJSON))
+}
+
+func (p *EtcdClient) GetTemplates() ([]*app_template.Config, error) {
+	keys, err := p.ReadKeys(config.TemplateURL)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*app_template.Config, 0, len(keys))
+	for _, key := range keys {
+		templateJSON, err := p.Read(key)
+		if err != nil {
+			return nil, err
+		}
+		t := &app_template.Config{}
+		if err = json.Unmarshal([]byte(templateJSON), t); err != nil {
+			return nil, err
+		}
+		ret = append(ret, t)
+	}
+	return ret, nil
+}
+
+func (p *EtcdClient) DeleteTemplate(t *app_template.Config) error {
+	return p.Delete(config.TemplateURL + "/" + t.Name)
+}
+
+// AddNamespacePolicy records a namespace's policy, replacing any it had.
+func (p *EtcdClient) AddNamespacePolicy(policy *namespace_policy.Config) error {
+	policyJSON, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	return p.Set(config.NamespacePolicyURL+"/"+policy.Namespace,
+		string(policyJSON))
+}
+
+func (p *EtcdClient) GetNamespacePolicies() (
+	[]*namespace_policy.Config, error,
+) {
+	keys, err := p.ReadKeys(config.NamespacePolicyURL)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*namespace_policy.Config, 0, len(keys))
+	for _, key := range keys {
+		policyJSON, err := p.Read(key)
+		if err != nil {
+			return nil, err
+		}
+		policy := &namespace_policy.Config{}
+		if err = json.Unmarshal([]byte(policyJSON), policy); err != nil {
+			return nil, err
+		}
+		ret = append(ret, policy)
+	}
+	return ret, nil
+}
+
+func (p *EtcdClient) DeleteNamespacePolicy(
+	policy *namespace_policy.Config,
+) error {
+	return p.Delete(config.NamespacePolicyURL + "/" + policy.Namespace)
+}
+
+// AddProvisioningRetry records a volume's failed provisioning attempts,
+// replacing any earlier record for the volume.
+func (p *EtcdClient) AddProvisioningRetry(retry *ProvisioningRetry) error {
+	retryJSON, err := json.Marshal(retry)
+	if err != nil {
+		return err
+	}
+	return p.Set(config.ProvisioningRetryURL+"/"+retry.Volume,
+		string(retryJSON))
+}
+
+func (p *EtcdClient) GetProvisioningRetries() ([]*ProvisioningRetry, error) {
+	keys, err := p.ReadKeys(config.ProvisioningRetryURL)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*ProvisioningRetry, 0, len(keys))
+	for _, key := range keys {
+		retryJSON, err := p.Read(key)
+		if err != nil {
+			return nil, err
+		}
+		retry := &ProvisioningRetry{}
+		if err = json.Unmarshal([]byte(retryJSON), retry); err != nil {
+			return nil, err
+		}
+		ret = append(ret, retry)
+	}
+	return ret, nil
+}
+
+func (p *EtcdClient) DeleteProvisioningRetry(retry *ProvisioningRetry) error {
+	return p.Delete(config.ProvisioningRetryURL + "/" + retry.Volume)
+}
+
+// AddZombieVolume records a volume to delete from its backend, replacing
+// any earlier record for it.
+func (p *EtcdClient) AddZombieVolume(zombie *ZombieVolume) error {
+	zombieJSON, err := json.Marshal(zombie)
+	if err != nil {
+		return err
+	}
+	return p.Set(config.ZombieVolumeURL+"/"+zombie.getKey(),
+		string(zombieJSON))
+}
+
+func (p *EtcdClient) GetZombieVolumes() ([]*ZombieVolume, error) {
+	keys, err := p.ReadKeys(config.ZombieVolumeURL)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*ZombieVolume, 0, len(keys))
+	for _, key := range keys {
+		zombieJSON, err := p.Read(key)
+		if err != nil {
+			return nil, err
+		}
+		zombie := &ZombieVolume{}
+		if err = json.Unmarshal([]byte(zombieJSON), zombie); err != nil {
+			return nil, err
+		}
+		ret = append(ret, zombie)
+	}
+	return ret, nil
+}
+
+func (p *EtcdClient) DeleteZombieVolume(zombie *ZombieVolume) error {
+	return p.Delete(config.ZombieVolumeURL + "/" + zombie.getKey())
+}
+
+// QuarantineRecord moves a backend or volume record under config.FailedURL,
+// keeping its original value.
+func (p *EtcdClient) QuarantineRecord(
+	recordType RecordType, name, reason string,
+) error {
+	key := recordType.url() + "/" + name
+	value, err := p.Read(key)
+	if err != nil {
+		return err
+	}
+	record := &QuarantinedRecord{
+		Type:          recordType,
+		Name:          name,
+		Value:         value,
+		Reason:        reason,
+		QuarantinedAt: time.Now().UTC(),
+	}
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err = p.Set(config.FailedURL+"/"+record.getKey(),
+		string(recordJSON)); err != nil {
+		return err
+	}
+	return p.Delete(key)
+}
+
+func (p *EtcdClient) GetQuarantinedRecords() ([]*QuarantinedRecord, error) {
+	keys, err := p.ReadKeys(config.FailedURL)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*QuarantinedRecord, 0, len(keys))
+	for _, key := range keys {
+		recordJSON, err := p.Read(key)
+		if err != nil {
+			return nil, err
+		}
+		record := &QuarantinedRecord{}
+		if err = json.Unmarshal([]byte(recordJSON), record); err != nil {
+			return nil, err
+		}
+		ret = append(ret, record)
+	}
+	return ret, nil
+}
@@ -8,11 +8,14 @@ import (
 	"reflect"
 	"strconv"
 	"testing"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	dvp "github.com/netapp/netappdvp/storage_drivers"
 
+	"github.com/netapp/trident/app_template"
 	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/namespace_policy"
 	"github.com/netapp/trident/storage"
 	"github.com/netapp/trident/storage/ontap"
 	"github.com/netapp/trident/storage/solidfire"
@@ -648,4 +651,254 @@ func TestEtcdv2AddStorageClass(t *testing.T) {
 			}
 		}
 	}
+
+	bronzeConfig.Attributes["media"] = storage_attribute.NewStringRequest("ssd")
+	if err = p.UpdateStorageClass(storage_class.New(bronzeConfig)); err != nil {
+		t.Fatal(err.Error())
+	}
+	retrievedSC, err = p.GetStorageClass(bronzeConfig.Name)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	media := retrievedSC.Config.Attributes["media"]
+	if media == nil || media.Value().(string) != "ssd" {
+		t.Errorf("Storage class was not updated; got media %v.", media)
+	}
+}
+
+func TestEtcdv2QuarantineRecord(t *testing.T) {
+	p, err := NewEtcdClient(*etcdV2)
+
+	// Storing a corrupt volume record
+	key := config.VolumeURL + "/corruptVol"
+	if err = p.Set(key, "{\"config\":"); err != nil {
+		t.Fatal(err.Error())
+	}
+	_, err = p.GetVolume("corruptVol")
+	if _, ok := err.(*CorruptRecordError); !ok {
+		t.Errorf("Expected a corrupt record error; got %v.", err)
+	}
+	// Listing the volumes must report the record by the name it's
+	// quarantined under.
+	_, err = p.GetVolumes()
+	if corrupt, ok := err.(*CorruptRecordError); !ok ||
+		corrupt.Name != "corruptVol" {
+		t.Errorf("Expected a corrupt record error for corruptVol; got %v.",
+			err)
+	}
+
+	// Quarantining it
+	if err = p.QuarantineRecord(VolumeRecord, "corruptVol",
+		"test"); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, err = p.Read(key); err == nil || err.Error() != KeyErrorMsg {
+		t.Error("Quarantined record was not removed!")
+	}
+	records, err := p.GetQuarantinedRecords()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	found := false
+	for _, r := range records {
+		if r.Type == VolumeRecord && r.Name == "corruptVol" {
+			found = true
+			if r.Value != "{\"config\":" {
+				t.Error("Quarantined record value does not match!")
+			}
+		}
+	}
+	if !found {
+		t.Error("Quarantined record not found!")
+	}
+	if err = p.Delete(config.FailedURL); err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestEtcdv2RetainedVolumes(t *testing.T) {
+	p, err := NewEtcdClient(*etcdV2)
+
+	vol := &storage.Volume{
+		Config: &storage.VolumeConfig{
+			Version:       string(config.OrchestratorMajorVersion),
+			Name:          "retainedVol",
+			InternalName:  "trident_retainedVol",
+			Size:          "1GB",
+			Protocol:      config.File,
+			ReclaimPolicy: config.ReclaimRetain,
+		},
+		Backend: &storage.StorageBackend{Name: "nfs_server"},
+		Pool:    &storagePool,
+	}
+	retained := NewRetainedVolume(vol)
+	if err = p.AddRetainedVolume(retained); err != nil {
+		t.Fatal(err.Error())
+	}
+	retainedVols, err := p.GetRetainedVolumes()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	found := false
+	for _, v := range retainedVols {
+		if v.Config.Name == "retainedVol" {
+			found = true
+			if v.Backend != "nfs_server" || v.Pool != storagePool.Name ||
+				v.Config.InternalName != "trident_retainedVol" {
+				t.Error("Retained volume does not match!")
+			}
+		}
+	}
+	if !found {
+		t.Error("Retained volume not found!")
+	}
+	if err = p.DeleteRetainedVolume(retained); err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestEtcdv2ProvisioningRetries(t *testing.T) {
+	p, err := NewEtcdClient(*etcdV2)
+
+	retry := &ProvisioningRetry{
+		Volume:      "retryVol",
+		Attempts:    2,
+		LastAttempt: time.Now().UTC(),
+		NextAttempt: time.Now().UTC().Add(time.Minute),
+		LastError:   "No suitable backends.",
+	}
+	if err = p.AddProvisioningRetry(retry); err != nil {
+		t.Fatal(err.Error())
+	}
+	retries, err := p.GetProvisioningRetries()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	found := false
+	for _, r := range retries {
+		if r.Volume == "retryVol" {
+			found = true
+			if r.Attempts != 2 || r.LastError != retry.LastError ||
+				!r.NextAttempt.Equal(retry.NextAttempt) {
+				t.Error("Provisioning retry does not match!")
+			}
+		}
+	}
+	if !found {
+		t.Error("Provisioning retry not found!")
+	}
+	if err = p.DeleteProvisioningRetry(retry); err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestEtcdv2VolumeGroups(t *testing.T) {
+	p, err := NewEtcdClient(*etcdV2)
+
+	group := &VolumeGroup{
+		Name:      "group",
+		Backend:   "nfs_server",
+		Volumes:   []string{"data", "log"},
+		CreatedAt: time.Now().UTC(),
+	}
+	if err = p.AddVolumeGroup(group); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err = p.AddVolumeGroup(group); err == nil {
+		t.Error("Volume group added twice!")
+	}
+	groups, err := p.GetVolumeGroups()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	found := false
+	for _, g := range groups {
+		if g.Name == "group" {
+			found = true
+			if g.Backend != "nfs_server" ||
+				!reflect.DeepEqual(g.Volumes, group.Volumes) {
+				t.Error("Volume group does not match!")
+			}
+		}
+	}
+	if !found {
+		t.Error("Volume group not found!")
+	}
+	if err = p.DeleteVolumeGroup(group); err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestEtcdv2Templates(t *testing.T) {
+	p, err := NewEtcdClient(*etcdV2)
+
+	template := &app_template.Config{
+		Name: "mongodb",
+		Volumes: []*app_template.VolumeTemplate{
+			{Name: "data", Size: "100G", MountOptions: "noatime"},
+			{Name: "journal", Size: "10G", MaxIOPS: 1000},
+		},
+	}
+	if err = p.AddTemplate(template); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err = p.AddTemplate(template); err == nil {
+		t.Error("Template added twice!")
+	}
+	templates, err := p.GetTemplates()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	found := false
+	for _, tmpl := range templates {
+		if tmpl.Name == "mongodb" {
+			found = true
+			if !reflect.DeepEqual(tmpl, template) {
+				t.Error("Template does not match!")
+			}
+		}
+	}
+	if !found {
+		t.Error("Template not found!")
+	}
+	if err = p.DeleteTemplate(template); err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestEtcdv2NamespacePolicies(t *testing.T) {
+	p, err := NewEtcdClient(*etcdV2)
+
+	policy := &namespace_policy.Config{
+		Namespace:             "team-a",
+		AllowedStorageClasses: []string{"gold", "bronze"},
+		DefaultStorageClass:   "bronze",
+	}
+	if err = p.AddNamespacePolicy(policy); err != nil {
+		t.Fatal(err.Error())
+	}
+	// Adding a namespace's policy again replaces it.
+	policy.MaxVolumeSize = "10737418240"
+	if err = p.AddNamespacePolicy(policy); err != nil {
+		t.Fatal(err.Error())
+	}
+	policies, err := p.GetNamespacePolicies()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	found := false
+	for _, nsPolicy := range policies {
+		if nsPolicy.Namespace == "team-a" {
+			found = true
+			if !reflect.DeepEqual(nsPolicy, policy) {
+				t.Error("Namespace policy does not match!")
+			}
+		}
+	}
+	if !found {
+		t.Error("Namespace policy not found!")
+	}
+	if err = p.DeleteNamespacePolicy(policy); err != nil {
+		t.Error(err.Error())
+	}
 }
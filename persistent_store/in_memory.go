@@ -3,8 +3,17 @@
 package persistent_store
 
 import (
+	"encoding/json"
 	"fmt"
+	"sync"
+	"time"
 
+	"golang.org/x/net/context"
+
+	"github.com/netapp/trident/app_template"
+	"github.com/netapp/trident/hooks"
+	"github.com/netapp/trident/namespace_policy"
+	"github.com/netapp/trident/snapshot_policy"
 	"github.com/netapp/trident/storage"
 	sc "github.com/netapp/trident/storage_class"
 )
@@ -18,15 +27,56 @@ type InMemoryClient struct {
 	storageClassesAdded int
 	volumeTxns          map[string]*VolumeTransaction
 	volumeTxnsAdded     int
+	backendTxns         map[string]*BackendTransaction
+	backendTxnsAdded    int
+	hooks               map[string]*hooks.Config
+	hooksAdded          int
+	policies            map[string]*snapshot_policy.Config
+	policiesAdded       int
+	retained            map[string]*RetainedVolume
+	retainedAdded       int
+	retries             map[string]*ProvisioningRetry
+	retriesAdded        int
+	zombies             map[string]*ZombieVolume
+	zombiesAdded        int
+	quarantined         map[string]*QuarantinedRecord
+	quarantinedAdded    int
+	groups              map[string]*VolumeGroup
+	groupsAdded         int
+	templates           map[string]*app_template.Config
+	templatesAdded      int
+	nsPolicies          map[string]*namespace_policy.Config
+	nsPoliciesAdded     int
+	// volumeTxnsMutex guards volumeTxns, which health checks read without
+	// the orchestrator lock.
+	volumeTxnsMutex *sync.Mutex
 }
 
 func NewInMemoryClient() *InMemoryClient {
-	return &InMemoryClient{
+	client := &InMemoryClient{
 		backends:       make(map[string]*storage.StorageBackendPersistent),
 		volumes:        make(map[string]*storage.VolumeExternal),
 		storageClasses: make(map[string]*sc.StorageClassPersistent),
 		volumeTxns:     make(map[string]*VolumeTransaction),
+		backendTxns:    make(map[string]*BackendTransaction),
+		hooks:          make(map[string]*hooks.Config),
+		policies:       make(map[string]*snapshot_policy.Config),
+		retained:       make(map[string]*RetainedVolume),
+		retries:        make(map[string]*ProvisioningRetry),
+		zombies:        make(map[string]*ZombieVolume),
+		quarantined:    make(map[string]*QuarantinedRecord),
+		groups:         make(map[string]*VolumeGroup),
+		templates:      make(map[string]*app_template.Config),
+		nsPolicies:     make(map[string]*namespace_policy.Config),
 	}
+	client.volumeTxnsMutex = &sync.Mutex{}
+	return client
+}
+
+// WithContext returns the client itself, since its requests complete
+// immediately.
+func (c *InMemoryClient) WithContext(ctx context.Context) Client {
+	return c
 }
 
 func (c *InMemoryClient) ClearAdded() {
@@ -34,6 +84,15 @@ func (c *InMemoryClient) ClearAdded() {
 	c.volumesAdded = 0
 	c.storageClassesAdded = 0
 	c.volumeTxnsAdded = 0
+	c.backendTxnsAdded = 0
+	c.hooksAdded = 0
+	c.policiesAdded = 0
+	c.retainedAdded = 0
+	c.retriesAdded = 0
+	c.quarantinedAdded = 0
+	c.groupsAdded = 0
+	c.templatesAdded = 0
+	c.nsPoliciesAdded = 0
 }
 
 func (c *InMemoryClient) AddBackend(b *storage.StorageBackend) error {
@@ -169,12 +228,17 @@ func (c *InMemoryClient) DeleteVolumes() error {
 
 func (c *InMemoryClient) AddVolumeTransaction(volTxn *VolumeTransaction) error {
 	// AddVolumeTransaction overwrites existing keys, unlike the other methods
+	c.volumeTxnsMutex.Lock()
+	defer c.volumeTxnsMutex.Unlock()
+	volTxn.setCreated()
 	c.volumeTxns[volTxn.getKey()] = volTxn
 	c.volumeTxnsAdded++
 	return nil
 }
 
 func (c *InMemoryClient) GetVolumeTransactions() ([]*VolumeTransaction, error) {
+	c.volumeTxnsMutex.Lock()
+	defer c.volumeTxnsMutex.Unlock()
 	if c.volumeTxnsAdded == 0 {
 		// Try to match etcd semantics as closely as possible.
 		return nil, KeyError{Key: "VolumeTransactions"}
@@ -189,6 +253,8 @@ func (c *InMemoryClient) GetVolumeTransactions() ([]*VolumeTransaction, error) {
 func (c *InMemoryClient) GetExistingVolumeTransaction(
 	volTxn *VolumeTransaction) (*VolumeTransaction, error,
 ) {
+	c.volumeTxnsMutex.Lock()
+	defer c.volumeTxnsMutex.Unlock()
 	vt, ok := c.volumeTxns[volTxn.getKey()]
 	if !ok {
 		return nil, nil
@@ -197,6 +263,8 @@ func (c *InMemoryClient) GetExistingVolumeTransaction(
 }
 
 func (c *InMemoryClient) DeleteVolumeTransaction(volTxn *VolumeTransaction) error {
+	c.volumeTxnsMutex.Lock()
+	defer c.volumeTxnsMutex.Unlock()
 	if _, ok := c.volumeTxns[volTxn.getKey()]; !ok {
 		// TODO:  Use a KeyError here if the etcdclient delete starts
 		// returning them.
@@ -207,6 +275,41 @@ func (c *InMemoryClient) DeleteVolumeTransaction(volTxn *VolumeTransaction) erro
 	return nil
 }
 
+func (c *InMemoryClient) AddBackendTransaction(
+	backendTxn *BackendTransaction,
+) error {
+	// Like AddVolumeTransaction, this overwrites existing keys.
+	backendTxn.setCreated()
+	c.backendTxns[backendTxn.getKey()] = backendTxn
+	c.backendTxnsAdded++
+	return nil
+}
+
+func (c *InMemoryClient) GetBackendTransactions() (
+	[]*BackendTransaction, error,
+) {
+	if c.backendTxnsAdded == 0 {
+		// Try to match etcd semantics as closely as possible.
+		return nil, KeyError{Key: "BackendTransactions"}
+	}
+	ret := make([]*BackendTransaction, 0, len(c.backendTxns))
+	for _, bt := range c.backendTxns {
+		ret = append(ret, bt)
+	}
+	return ret, nil
+}
+
+func (c *InMemoryClient) DeleteBackendTransaction(
+	backendTxn *BackendTransaction,
+) error {
+	if _, ok := c.backendTxns[backendTxn.getKey()]; !ok {
+		return fmt.Errorf("Unable to delete %s:  key not found.",
+			backendTxn.getKey())
+	}
+	delete(c.backendTxns, backendTxn.getKey())
+	return nil
+}
+
 func (c *InMemoryClient) AddStorageClass(s *sc.StorageClass) error {
 	storageClass := s.ConstructPersistent()
 	if _, ok := c.storageClasses[storageClass.GetName()]; ok {
@@ -241,6 +344,14 @@ func (c *InMemoryClient) GetStorageClasses() (
 	return ret, nil
 }
 
+func (c *InMemoryClient) UpdateStorageClass(s *sc.StorageClass) error {
+	if _, ok := c.storageClasses[s.GetName()]; !ok {
+		return fmt.Errorf("Unable to update %s:  key not found.", s.GetName())
+	}
+	c.storageClasses[s.GetName()] = s.ConstructPersistent()
+	return nil
+}
+
 func (c *InMemoryClient) DeleteStorageClass(s *sc.StorageClass) error {
 	if _, ok := c.storageClasses[s.GetName()]; !ok {
 		// TODO:  Use a KeyError here if the etcdclient delete starts
@@ -250,3 +361,330 @@ func (c *InMemoryClient) DeleteStorageClass(s *sc.StorageClass) error {
 	delete(c.storageClasses, s.GetName())
 	return nil
 }
+
+func (c *InMemoryClient) AddHook(h *hooks.Config) error {
+	if _, ok := c.hooks[h.Name]; ok {
+		return fmt.Errorf("Hook %s already exists.", h.Name)
+	}
+	hookCopy := *h
+	c.hooks[h.Name] = &hookCopy
+	c.hooksAdded++
+	return nil
+}
+
+func (c *InMemoryClient) GetHook(hookName string) (*hooks.Config, error) {
+	ret, ok := c.hooks[hookName]
+	if !ok {
+		return nil, KeyError{Key: hookName}
+	}
+	return ret, nil
+}
+
+func (c *InMemoryClient) GetHooks() ([]*hooks.Config, error) {
+	if c.hooksAdded == 0 {
+		// Try to match etcd semantics as closely as possible.
+		return nil, KeyError{Key: "Hooks"}
+	}
+	ret := make([]*hooks.Config, 0, len(c.hooks))
+	for _, h := range c.hooks {
+		ret = append(ret, h)
+	}
+	return ret, nil
+}
+
+func (c *InMemoryClient) DeleteHook(h *hooks.Config) error {
+	if _, ok := c.hooks[h.Name]; !ok {
+		// TODO:  Use a KeyError here if the etcdclient delete starts
+		// returning them.
+		return fmt.Errorf("Unable to delete %s:  key not found.", h.Name)
+	}
+	delete(c.hooks, h.Name)
+	return nil
+}
+
+func (c *InMemoryClient) AddSnapshotPolicy(p *snapshot_policy.Config) error {
+	if _, ok := c.policies[p.Name]; ok {
+		return fmt.Errorf("Snapshot policy %s already exists.", p.Name)
+	}
+	policyCopy := *p
+	c.policies[p.Name] = &policyCopy
+	c.policiesAdded++
+	return nil
+}
+
+func (c *InMemoryClient) GetSnapshotPolicy(policyName string) (
+	*snapshot_policy.Config, error,
+) {
+	ret, ok := c.policies[policyName]
+	if !ok {
+		return nil, KeyError{Key: policyName}
+	}
+	return ret, nil
+}
+
+func (c *InMemoryClient) GetSnapshotPolicies() ([]*snapshot_policy.Config, error) {
+	if c.policiesAdded == 0 {
+		// Try to match etcd semantics as closely as possible.
+		return nil, KeyError{Key: "SnapshotPolicies"}
+	}
+	ret := make([]*snapshot_policy.Config, 0, len(c.policies))
+	for _, p := range c.policies {
+		ret = append(ret, p)
+	}
+	return ret, nil
+}
+
+func (c *InMemoryClient) DeleteSnapshotPolicy(p *snapshot_policy.Config) error {
+	if _, ok := c.policies[p.Name]; !ok {
+		// TODO:  Use a KeyError here if the etcdclient delete starts
+		// returning them.
+		return fmt.Errorf("Unable to delete %s:  key not found.", p.Name)
+	}
+	delete(c.policies, p.Name)
+	return nil
+}
+
+func (c *InMemoryClient) AddRetainedVolume(vol *RetainedVolume) error {
+	// Like AddVolumeTransaction, this overwrites existing keys.
+	volCopy := *vol
+	c.retained[vol.Config.Name] = &volCopy
+	c.retainedAdded++
+	return nil
+}
+
+func (c *InMemoryClient) GetRetainedVolumes() ([]*RetainedVolume, error) {
+	if c.retainedAdded == 0 {
+		// Try to match etcd semantics as closely as possible.
+		return nil, KeyError{Key: "RetainedVolumes"}
+	}
+	ret := make([]*RetainedVolume, 0, len(c.retained))
+	for _, v := range c.retained {
+		ret = append(ret, v)
+	}
+	return ret, nil
+}
+
+func (c *InMemoryClient) DeleteRetainedVolume(vol *RetainedVolume) error {
+	if _, ok := c.retained[vol.Config.Name]; !ok {
+		return fmt.Errorf("Unable to delete %s:  key not found.",
+			vol.Config.Name)
+	}
+	delete(c.retained, vol.Config.Name)
+	return nil
+}
+
+func (c *InMemoryClient) AddVolumeGroup(group *VolumeGroup) error {
+	if _, ok := c.groups[group.Name]; ok {
+		return fmt.Errorf("Volume group %s already exists.", group.Name)
+	}
+	groupCopy := *group
+	groupCopy.Volumes = append([]string{}, group.Volumes...)
+	c.groups[group.Name] = &groupCopy
+	c.groupsAdded++
+	return nil
+}
+
+func (c *InMemoryClient) GetVolumeGroups() ([]*VolumeGroup, error) {
+	if c.groupsAdded == 0 {
+		// Try to match etcd semantics as closely as possible.
+		return nil, KeyError{Key: "VolumeGroups"}
+	}
+	ret := make([]*VolumeGroup, 0, len(c.groups))
+	for _, g := range c.groups {
+		ret = append(ret, g)
+	}
+	return ret, nil
+}
+
+func (c *InMemoryClient) DeleteVolumeGroup(group *VolumeGroup) error {
+	if _, ok := c.groups[group.Name]; !ok {
+		return fmt.Errorf("Unable to delete %s:  key not found.", group.Name)
+	}
+	delete(c.groups, group.Name)
+	return nil
+}
+
+func (c *InMemoryClient) AddTemplate(t *app_template.Config) error {
+	if _, ok := c.templates[t.Name]; ok {
+		return fmt.Errorf("Template %s already exists.", t.Name)
+	}
+	templateCopy := *t
+	templateCopy.Volumes = append([]*app_template.VolumeTemplate{},
+		t.Volumes...)
+	c.templates[t.Name] = &templateCopy
+	c.templatesAdded++
+	return nil
+}
+
+func (c *InMemoryClient) GetTemplates() ([]*app_template.Config, error) {
+	if c.templatesAdded == 0 {
+		// Try to match etcd semantics as closely as possible.
+		return nil, KeyError{Key: "Templates"}
+	}
+	ret := make([]*app_template.Config, 0, len(c.templates))
+	for _, t := range c.templates {
+		ret = append(ret, t)
+	}
+	return ret, nil
+}
+
+func (c *InMemoryClient) DeleteTemplate(t *app_template.Config) error {
+	if _, ok := c.templates[t.Name]; !ok {
+		return fmt.Errorf("Unable to delete %s:  key not found.", t.Name)
+	}
+	delete(c.templates, t.Name)
+	return nil
+}
+
+func (c *InMemoryClient) AddNamespacePolicy(
+	p *namespace_policy.Config,
+) error {
+	// Like AddVolumeTransaction, this overwrites existing keys.
+	policyCopy := *p
+	if p.AllowedStorageClasses != nil {
+		policyCopy.AllowedStorageClasses = append([]string{},
+			p.AllowedStorageClasses...)
+	}
+	c.nsPolicies[p.Namespace] = &policyCopy
+	c.nsPoliciesAdded++
+	return nil
+}
+
+func (c *InMemoryClient) GetNamespacePolicies() (
+	[]*namespace_policy.Config, error,
+) {
+	if c.nsPoliciesAdded == 0 {
+		// Try to match etcd semantics as closely as possible.
+		return nil, KeyError{Key: "NamespacePolicies"}
+	}
+	ret := make([]*namespace_policy.Config, 0, len(c.nsPolicies))
+	for _, p := range c.nsPolicies {
+		ret = append(ret, p)
+	}
+	return ret, nil
+}
+
+func (c *InMemoryClient) DeleteNamespacePolicy(
+	p *namespace_policy.Config,
+) error {
+	if _, ok := c.nsPolicies[p.Namespace]; !ok {
+		return fmt.Errorf("Unable to delete %s:  key not found.",
+			p.Namespace)
+	}
+	delete(c.nsPolicies, p.Namespace)
+	return nil
+}
+
+func (c *InMemoryClient) AddProvisioningRetry(
+	retry *ProvisioningRetry,
+) error {
+	// Like AddVolumeTransaction, this overwrites existing keys.
+	retryCopy := *retry
+	c.retries[retry.Volume] = &retryCopy
+	c.retriesAdded++
+	return nil
+}
+
+func (c *InMemoryClient) GetProvisioningRetries() (
+	[]*ProvisioningRetry, error,
+) {
+	if c.retriesAdded == 0 {
+		// Try to match etcd semantics as closely as possible.
+		return nil, KeyError{Key: "ProvisioningRetries"}
+	}
+	ret := make([]*ProvisioningRetry, 0, len(c.retries))
+	for _, r := range c.retries {
+		ret = append(ret, r)
+	}
+	return ret, nil
+}
+
+func (c *InMemoryClient) DeleteProvisioningRetry(
+	retry *ProvisioningRetry,
+) error {
+	if _, ok := c.retries[retry.Volume]; !ok {
+		return fmt.Errorf("Unable to delete %s:  key not found.",
+			retry.Volume)
+	}
+	delete(c.retries, retry.Volume)
+	return nil
+}
+
+func (c *InMemoryClient) AddZombieVolume(zombie *ZombieVolume) error {
+	// Like AddVolumeTransaction, this overwrites existing keys.
+	zombieCopy := *zombie
+	c.zombies[zombie.getKey()] = &zombieCopy
+	c.zombiesAdded++
+	return nil
+}
+
+func (c *InMemoryClient) GetZombieVolumes() ([]*ZombieVolume, error) {
+	if c.zombiesAdded == 0 {
+		// Try to match etcd semantics as closely as possible.
+		return nil, KeyError{Key: "ZombieVolumes"}
+	}
+	ret := make([]*ZombieVolume, 0, len(c.zombies))
+	for _, z := range c.zombies {
+		ret = append(ret, z)
+	}
+	return ret, nil
+}
+
+func (c *InMemoryClient) DeleteZombieVolume(zombie *ZombieVolume) error {
+	key := zombie.getKey()
+	if _, ok := c.zombies[key]; !ok {
+		return fmt.Errorf("Unable to delete %s:  key not found.", key)
+	}
+	delete(c.zombies, key)
+	return nil
+}
+
+func (c *InMemoryClient) QuarantineRecord(
+	recordType RecordType, name, reason string,
+) error {
+	var value interface{}
+	switch recordType {
+	case BackendRecord:
+		backend, ok := c.backends[name]
+		if !ok {
+			return KeyError{Key: name}
+		}
+		delete(c.backends, name)
+		value = backend
+	case VolumeRecord:
+		volume, ok := c.volumes[name]
+		if !ok {
+			return KeyError{Key: name}
+		}
+		delete(c.volumes, name)
+		value = volume
+	default:
+		return fmt.Errorf("Unknown record type %s.", recordType)
+	}
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	record := &QuarantinedRecord{
+		Type:          recordType,
+		Name:          name,
+		Value:         string(valueJSON),
+		Reason:        reason,
+		QuarantinedAt: time.Now().UTC(),
+	}
+	c.quarantined[record.getKey()] = record
+	c.quarantinedAdded++
+	return nil
+}
+
+func (c *InMemoryClient) GetQuarantinedRecords() ([]*QuarantinedRecord, error) {
+	if c.quarantinedAdded == 0 {
+		// Try to match etcd semantics as closely as possible.
+		return nil, KeyError{Key: "QuarantinedRecords"}
+	}
+	ret := make([]*QuarantinedRecord, 0, len(c.quarantined))
+	for _, r := range c.quarantined {
+		ret = append(ret, r)
+	}
+	return ret, nil
+}
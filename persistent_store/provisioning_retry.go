@@ -0,0 +1,20 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package persistent_store
+
+import (
+	"time"
+)
+
+// ProvisioningRetry records the failed attempts to provision a volume, so
+// that frontends can back off between attempts, even across restarts.
+type ProvisioningRetry struct {
+	Volume      string    `json:"volume"`
+	Attempts    int       `json:"attempts"`
+	LastAttempt time.Time `json:"lastAttempt"`
+	NextAttempt time.Time `json:"nextAttempt"`
+	LastError   string    `json:"lastError"`
+	// Abandoned is set when an operator stops the frontend from retrying,
+	// until the retry is resumed or deleted.
+	Abandoned bool `json:"abandoned,omitempty"`
+}
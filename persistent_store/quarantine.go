@@ -0,0 +1,58 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package persistent_store
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/netapp/trident/config"
+)
+
+// RecordType identifies the kind of object held by a persisted record.
+type RecordType string
+
+const (
+	BackendRecord RecordType = "backend"
+	VolumeRecord  RecordType = "volume"
+)
+
+// url returns the key prefix under which records of the type are stored.
+func (t RecordType) url() string {
+	switch t {
+	case BackendRecord:
+		return config.BackendURL
+	case VolumeRecord:
+		return config.VolumeURL
+	default:
+		return ""
+	}
+}
+
+// QuarantinedRecord is a persisted record that couldn't be bootstrapped.
+// It is moved under config.FailedURL so that it no longer prevents Trident
+// from starting, and kept, unparsed, for an administrator to repair.
+type QuarantinedRecord struct {
+	Type          RecordType `json:"type"`
+	Name          string     `json:"name"`
+	Value         string     `json:"value"`
+	Reason        string     `json:"reason"`
+	QuarantinedAt time.Time  `json:"quarantinedAt"`
+}
+
+func (r *QuarantinedRecord) getKey() string {
+	return string(r.Type) + "-" + r.Name
+}
+
+// CorruptRecordError is returned when a stored record can't be parsed or is
+// missing required fields.
+type CorruptRecordError struct {
+	Type RecordType
+	Name string
+	Err  error
+}
+
+func (e *CorruptRecordError) Error() string {
+	return fmt.Sprintf("Stored %s record %s is corrupt:  %v", e.Type, e.Name,
+		e.Err)
+}
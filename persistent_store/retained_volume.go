@@ -0,0 +1,35 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package persistent_store
+
+import (
+	"time"
+
+	"github.com/netapp/trident/storage"
+)
+
+// RetainedVolume records a volume with the Retain reclaim policy that was
+// deleted from Trident but left on its backend, so that it can be imported
+// again later.
+type RetainedVolume struct {
+	Config     *storage.VolumeConfig `json:"config"`
+	Backend    string                `json:"backend"`
+	Pool       string                `json:"pool"`
+	RetainedAt time.Time             `json:"retainedAt"`
+}
+
+func NewRetainedVolume(vol *storage.Volume) *RetainedVolume {
+	return &RetainedVolume{
+		Config:     vol.Config,
+		Backend:    vol.Backend.Name,
+		Pool:       vol.Pool.Name,
+		RetainedAt: time.Now().UTC(),
+	}
+}
+
+// Matches returns true if vol is the retained volume, i.e., has the same
+// name and internal name.
+func (r *RetainedVolume) Matches(vol *storage.Volume) bool {
+	return r.Config.Name == vol.Config.Name &&
+		r.Config.InternalName == vol.Config.InternalName
+}
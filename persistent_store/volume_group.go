@@ -0,0 +1,18 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package persistent_store
+
+import (
+	"time"
+)
+
+// VolumeGroup records the volumes that were created together as a group on
+// one backend, so that they can be snapshotted and deleted as a unit.
+type VolumeGroup struct {
+	Name      string    `json:"name"`
+	Backend   string    `json:"backend"`
+	Volumes   []string  `json:"volumes"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Template names the template the group was provisioned from, if any.
+	Template string `json:"template,omitempty"`
+}
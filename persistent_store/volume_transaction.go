@@ -4,6 +4,7 @@ package persistent_store
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/netapp/trident/storage"
 )
@@ -13,11 +14,33 @@ type VolumeOperation string
 const (
 	AddVolume    VolumeOperation = "addVolume"
 	DeleteVolume VolumeOperation = "deleteVolume"
+	RenameVolume VolumeOperation = "renameVolume"
+	// TerminateVolume and UndeleteVolume rename a volume on its backend as
+	// it is deleted with a grace period or undeleted.
+	TerminateVolume VolumeOperation = "terminateVolume"
+	UndeleteVolume  VolumeOperation = "undeleteVolume"
 )
 
 type VolumeTransaction struct {
 	Config *storage.VolumeConfig
 	Op     VolumeOperation
+	// OldName is the volume's previous name for RenameVolume transactions,
+	// whose Config holds the new name.
+	OldName string `json:",omitempty"`
+	// NewInternalName is the volume's new name on its backend for
+	// TerminateVolume and UndeleteVolume transactions, whose Config holds
+	// the volume's config before the operation.
+	NewInternalName string `json:",omitempty"`
+	// Created is when the transaction was first stored; it is zero for
+	// transactions stored by older versions.
+	Created time.Time
+}
+
+// setCreated records the creation time of a transaction being stored.
+func (vt *VolumeTransaction) setCreated() {
+	if vt.Created.IsZero() {
+		vt.Created = time.Now()
+	}
 }
 
 // getKey returns a unique identifier for the VolumeTransaction.  Volume
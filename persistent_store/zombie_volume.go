@@ -0,0 +1,43 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package persistent_store
+
+import (
+	"time"
+
+	"github.com/netapp/trident/storage"
+)
+
+// ZombieVolume records a volume left on its backend by a failed creation
+// whose rollback also failed to delete it.  The orchestrator keeps trying to
+// delete it in the background, instead of waiting for the user to retry.
+type ZombieVolume struct {
+	Config   *storage.VolumeConfig `json:"config"`
+	Backend  string                `json:"backend"`
+	Pool     string                `json:"pool"`
+	Recorded time.Time             `json:"recorded"`
+	// Attempts counts the failed attempts to delete the volume since it was
+	// recorded, the last of which failed with LastError.
+	Attempts    int       `json:"attempts"`
+	LastAttempt time.Time `json:"lastAttempt,omitempty"`
+	LastError   string    `json:"lastError,omitempty"`
+}
+
+func NewZombieVolume(vol *storage.Volume, err error) *ZombieVolume {
+	now := time.Now().UTC()
+	return &ZombieVolume{
+		Config:      vol.Config,
+		Backend:     vol.Backend.Name,
+		Pool:        vol.Pool.Name,
+		Recorded:    now,
+		Attempts:    1,
+		LastAttempt: now,
+		LastError:   err.Error(),
+	}
+}
+
+// getKey identifies the volume by where it is, rather than by its Trident
+// name, which a later volume may reuse.
+func (z *ZombieVolume) getKey() string {
+	return z.Backend + "-" + z.Config.InternalName
+}
@@ -0,0 +1,80 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package snapshot_policy
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *",
+		"* * 0 * *", "* * * 13 *", "* * * * 7", "*/0 * * * *", "5-1 * * * *",
+		"a * * * *", "@yearly"} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("Expected an error parsing %q.", spec)
+		}
+	}
+	for _, spec := range []string{"* * * * *", "0 */6 * * *",
+		"15,45 9-17 * * 1-5", "0 0 1 */3 *", "@daily"} {
+		if _, err := ParseSchedule(spec); err != nil {
+			t.Errorf("Unable to parse %q:  %v", spec, err)
+		}
+	}
+}
+
+func TestScheduleMatches(t *testing.T) {
+	// 2017-03-01 was a Wednesday.
+	wed := time.Date(2017, time.March, 1, 9, 15, 30, 0, time.UTC)
+	for _, test := range []struct {
+		spec     string
+		t        time.Time
+		expected bool
+	}{
+		{"* * * * *", wed, true},
+		{"15 9 * * *", wed, true},
+		{"16 9 * * *", wed, false},
+		{"15,45 9-17 * * 1-5", wed, true},
+		{"15,45 9-17 * * 0,6", wed, false},
+		{"*/5 * * * *", wed, true},
+		{"*/10 * * * *", wed, false},
+		{"@hourly", wed, false},
+		{"@hourly", wed.Add(45 * time.Minute), true},
+		// When both day fields are restricted, either may match.
+		{"15 9 1 * 0", wed, true},
+		{"15 9 2 * 3", wed, true},
+		{"15 9 2 * 0", wed, false},
+		{"15 9 1 * *", wed, true},
+		{"15 9 * 4 *", wed, false},
+	} {
+		s, err := ParseSchedule(test.spec)
+		if err != nil {
+			t.Fatalf("Unable to parse %q:  %v", test.spec, err)
+		}
+		if s.Matches(test.t) != test.expected {
+			t.Errorf("%q:  expected Matches(%v) to return %t.", test.spec,
+				test.t, test.expected)
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	policy := &Config{Name: "hourly", Schedule: "@hourly", Retention: 2}
+	start := time.Date(2017, time.March, 1, 9, 0, 0, 0, time.UTC)
+	names := []string{"manual", "trident-daily-20170301T0000Z"}
+	for i := 3; i >= 0; i-- {
+		names = append(names,
+			policy.SnapshotName(start.Add(time.Duration(i)*time.Hour)))
+	}
+	expected := []string{
+		policy.SnapshotName(start),
+		policy.SnapshotName(start.Add(time.Hour)),
+	}
+	if pruned := policy.Prune(names); !reflect.DeepEqual(pruned, expected) {
+		t.Errorf("Expected %v to be pruned; got %v.", expected, pruned)
+	}
+	if pruned := policy.Prune(names[:4]); len(pruned) != 0 {
+		t.Errorf("Expected nothing to be pruned; got %v.", pruned)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+// Package snapshot_policy defines scheduled snapshot policies:  a cron-like
+// schedule on which volumes using the policy are snapshotted, and the number
+// of those snapshots to retain.
+package snapshot_policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/netapp/trident/config"
+)
+
+// snapshotTimeFormat sorts lexically in chronological order, which lets
+// Prune find the oldest snapshots by name alone.
+const snapshotTimeFormat = "20060102T1504Z"
+
+type Config struct {
+	Version   string `json:"version"`
+	Name      string `json:"name"`
+	Schedule  string `json:"schedule"`
+	Retention int    `json:"retention"`
+}
+
+func (c *Config) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("Snapshot policy name must be specified.")
+	}
+	if _, err := ParseSchedule(c.Schedule); err != nil {
+		return err
+	}
+	if c.Retention < 1 {
+		return fmt.Errorf("Snapshot policy retention must be at least 1.")
+	}
+	c.Version = config.OrchestratorMajorVersion
+	return nil
+}
+
+// SnapshotPrefix returns the prefix shared by the names of all snapshots
+// created for the policy.
+func (c *Config) SnapshotPrefix() string {
+	return config.OrchestratorName + "-" + c.Name + "-"
+}
+
+// SnapshotName returns the name of the snapshot taken for the policy at t.
+func (c *Config) SnapshotName(t time.Time) string {
+	return c.SnapshotPrefix() + t.UTC().Format(snapshotTimeFormat)
+}
+
+// Prune returns the snapshots, from among names, that were created by the
+// policy and exceed its retention count, oldest first.
+func (c *Config) Prune(names []string) []string {
+	owned := make([]string, 0, len(names))
+	for _, name := range names {
+		if strings.HasPrefix(name, c.SnapshotPrefix()) {
+			owned = append(owned, name)
+		}
+	}
+	if len(owned) <= c.Retention {
+		return []string{}
+	}
+	sort.Strings(owned)
+	return owned[:len(owned)-c.Retention]
+}
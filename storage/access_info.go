@@ -0,0 +1,98 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package storage
+
+// AccessProtocol is the protocol over which hosts reach a volume.
+type AccessProtocol string
+
+const (
+	AccessNFS   AccessProtocol = "nfs"
+	AccessISCSI AccessProtocol = "iscsi"
+	AccessSMB   AccessProtocol = "smb"
+	AccessNVMe  AccessProtocol = "nvme"
+)
+
+// AccessDetails describes how hosts reach a volume, in the same form for
+// every driver, so that frontends can tell hosts how to mount it without
+// knowing which driver created it.  Only the field for Protocol is set.
+type AccessDetails struct {
+	Protocol AccessProtocol `json:"protocol"`
+	NFS      *NFSAccess     `json:"nfs,omitempty"`
+	ISCSI    *ISCSIAccess   `json:"iscsi,omitempty"`
+	SMB      *SMBAccess     `json:"smb,omitempty"`
+	NVMe     *NVMeAccess    `json:"nvme,omitempty"`
+}
+
+type NFSAccess struct {
+	Server string `json:"server"`
+	Path   string `json:"path"`
+}
+
+type ISCSIAccess struct {
+	TargetIQN string `json:"targetIqn"`
+	LUN       int32  `json:"lun"`
+	// Portals lists every portal through which the target can be reached,
+	// starting with the one to log in to first.
+	Portals []string `json:"portals"`
+	// Interface is the iSCSI interface hosts should use, if not the
+	// default.
+	Interface string `json:"interface,omitempty"`
+}
+
+type SMBAccess struct {
+	Server string `json:"server"`
+	Share  string `json:"share"`
+}
+
+type NVMeAccess struct {
+	SubsystemNQN string `json:"subsystemNqn"`
+	// Namespaces lists the UUIDs of the volume's namespaces in the
+	// subsystem.
+	Namespaces []string `json:"namespaces"`
+	// Transport is one of the NVMeTransport constants.
+	Transport       string   `json:"transport"`
+	TargetAddresses []string `json:"targetAddresses"`
+}
+
+// Details returns the access information as AccessDetails, or nil if the
+// volume's driver hasn't set any.
+func (i *VolumeAccessInfo) Details() *AccessDetails {
+	switch {
+	case i.NvmeSubsystemNQN != "":
+		return &AccessDetails{
+			Protocol: AccessNVMe,
+			NVMe: &NVMeAccess{
+				SubsystemNQN: i.NvmeSubsystemNQN,
+				Namespaces:   []string{i.NvmeNamespaceUUID},
+				Transport:    i.NvmeTransport,
+				TargetAddresses: append([]string(nil),
+					i.NvmeTargetAddresses...),
+			},
+		}
+	case i.IscsiTargetPortal != "":
+		portals := i.IscsiPortals
+		if len(portals) == 0 {
+			portals = []string{i.IscsiTargetPortal}
+		}
+		return &AccessDetails{
+			Protocol: AccessISCSI,
+			ISCSI: &ISCSIAccess{
+				TargetIQN: i.IscsiTargetIQN,
+				LUN:       i.IscsiLunNumber,
+				Portals:   append([]string(nil), portals...),
+				Interface: i.IscsiInterface,
+			},
+		}
+	case i.NfsServerIP != "":
+		return &AccessDetails{
+			Protocol: AccessNFS,
+			NFS:      &NFSAccess{Server: i.NfsServerIP, Path: i.NfsPath},
+		}
+	case i.SmbServer != "":
+		return &AccessDetails{
+			Protocol: AccessSMB,
+			SMB:      &SMBAccess{Server: i.SmbServer, Share: i.SmbShare},
+		}
+	}
+	return nil
+}
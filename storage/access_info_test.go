@@ -0,0 +1,54 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAccessInfoDetails(t *testing.T) {
+	info := &VolumeAccessInfo{}
+	if details := info.Details(); details != nil {
+		t.Errorf("Expected no details without access info; got %v.",
+			details)
+	}
+
+	info.IscsiTargetPortal = "10.0.0.1:3260"
+	info.IscsiTargetIQN = "iqn.1992-08.com.netapp:sn.1"
+	info.IscsiLunNumber = 2
+	details := info.Details()
+	if details == nil || details.Protocol != AccessISCSI ||
+		details.NFS != nil || details.SMB != nil {
+		t.Fatalf("Expected iSCSI details only; got %v.", details)
+	}
+	if details.ISCSI.TargetIQN != info.IscsiTargetIQN ||
+		details.ISCSI.LUN != 2 || !reflect.DeepEqual(details.ISCSI.Portals,
+		[]string{"10.0.0.1:3260"}) {
+		t.Errorf("Unexpected iSCSI details:  %v", details.ISCSI)
+	}
+	info.IscsiPortals = []string{"10.0.0.1:3260", "10.0.0.2:3260"}
+	if portals := info.Details().ISCSI.Portals; !reflect.DeepEqual(portals,
+		info.IscsiPortals) {
+		t.Errorf("Expected every portal; got %v.", portals)
+	}
+
+	info = &VolumeAccessInfo{}
+	info.NfsServerIP = "10.0.0.3"
+	info.NfsPath = "/trident_vol"
+	details = info.Details()
+	if details == nil || details.Protocol != AccessNFS ||
+		details.NFS.Server != "10.0.0.3" ||
+		details.NFS.Path != "/trident_vol" {
+		t.Errorf("Unexpected NFS details:  %v", details)
+	}
+
+	info = &VolumeAccessInfo{}
+	info.SmbServer = "cifs.example.com"
+	info.SmbShare = "vol"
+	details = info.Details()
+	if details == nil || details.Protocol != AccessSMB ||
+		details.SMB.Share != "vol" {
+		t.Errorf("Unexpected SMB details:  %v", details)
+	}
+}
@@ -5,14 +5,19 @@ package storage
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strconv"
+	"sync"
 
 	log "github.com/Sirupsen/logrus"
 	dvp "github.com/netapp/netappdvp/storage_drivers"
 	"github.com/netapp/netappdvp/utils"
+	"golang.org/x/net/context"
 
 	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/credentials"
 	"github.com/netapp/trident/drivers/fake"
+	"github.com/netapp/trident/errors"
 	"github.com/netapp/trident/storage_attribute"
 )
 
@@ -42,20 +47,101 @@ type TridentDriver interface {
 	GetExternalConfig() interface{}
 }
 
+// SnapshotDriver is implemented by drivers that can create and delete
+// snapshots on demand.  Snapshots are listed with dvp.StorageDriver's
+// SnapshotList.
+type SnapshotDriver interface {
+	CreateSnapshot(volumeName, snapshotName string) error
+	DeleteSnapshot(volumeName, snapshotName string) error
+}
+
+// GroupSnapshotDriver is implemented by drivers that can snapshot several
+// volumes at the same point in time, e.g., with a consistency group, so
+// that the snapshots are crash consistent with each other.
+type GroupSnapshotDriver interface {
+	CreateGroupSnapshot(volumeNames []string, snapshotName string) error
+}
+
+// UsageDriver is implemented by drivers that can report how much space
+// volumes consume on their storage systems, e.g., after thin provisioning.
+type UsageDriver interface {
+	GetUsedBytes(volumeName string) (uint64, error)
+}
+
+// VersionDriver is implemented by drivers that aren't built from the
+// netappdvp release Trident vendors, so that they can report their own
+// version.
+type VersionDriver interface {
+	DriverVersion() string
+}
+
+// AccessDriver is implemented by drivers that can restrict a volume to the
+// hosts in a VolumeAccess.  Drivers apply volConfig.Access when a volume is
+// created; SetVolumeAccess replaces it afterwards.
+type AccessDriver interface {
+	SetVolumeAccess(volConfig *VolumeConfig, access *VolumeAccess) error
+}
+
+// ReadOnlyDriver is implemented by drivers that can make volumes read-only
+// on their storage systems:  NFS exports that allow no writes, and
+// write-protected LUNs.
+type ReadOnlyDriver interface {
+	SetVolumeReadOnly(volConfig *VolumeConfig, readOnly bool) error
+}
+
+// RenameDriver is implemented by drivers that can rename volumes on their
+// storage systems.
+type RenameDriver interface {
+	Rename(name, newName string) error
+}
+
 // This shadows the dvp.StorageDriver interface, combining it with the Trident
 // specific methods.  Implementing structs should in-line an instance of
-// dvp.StorageDriver
+// dvp.StorageDriver.  Destroy must return a NotFound error from the errors
+// package for a volume that doesn't exist, as DestroyVolume does.
 type StorageDriver interface {
 	dvp.StorageDriver
 	TridentDriver
 }
 
+// DestroyVolume destroys a volume with an nDVP driver.  The nDVP drivers
+// don't report missing volumes consistently, so if Destroy fails and the
+// driver can't find the volume either, a NotFound error is returned.
+func DestroyVolume(driver dvp.StorageDriver, name string) error {
+	err := driver.Destroy(name)
+	if err != nil && driver.Get(name) != nil {
+		return errors.Errorf(errors.NotFound, "Volume %s not found:  %v",
+			name, err)
+	}
+	return err
+}
+
 type StorageBackend struct {
 	Driver StorageDriver
 	Name   string
 	//TODO: the granualarity of online should probably be a StoragePool, not the whole backend, which in the case of ONTAP can be the whole cluster.
 	Online  bool
 	Storage map[string]*StoragePool
+	Limits  BackendLimits
+	Pools   PoolConfig
+	Portals PortalConfig
+	// Chap holds the backend's CHAP credentials, if it uses CHAP; see
+	// ApplyChap.
+	Chap ChapConfig
+	// Rotation schedules the rotation of the driver's password, if set.
+	Rotation RotationConfig
+	// Credentials, if set, refers to a secret holding some of the driver's
+	// config fields.  Those fields are neither stored nor reported.
+	Credentials *credentials.Config
+	// Sessions bounds the driver operations running or waiting to run, as
+	// set by Limits.MaxSessions.
+	Sessions *SessionPool
+	// driverLock serializes the driver operations; see lockDriver.
+	driverLock chan struct{}
+	driverOnce sync.Once
+	// Naming checks the names of new volumes against the storage system's
+	// rules and, if set, replaces the driver's naming convention.
+	Naming NamingConfig
 }
 
 func NewStorageBackend(driver StorageDriver) (*StorageBackend, error) {
@@ -69,6 +155,9 @@ func NewStorageBackend(driver StorageDriver) (*StorageBackend, error) {
 	if err := backend.Driver.GetStorageBackendSpecs(&backend); err != nil {
 		return nil, err
 	}
+	if err := backend.ValidateNVMe(); err != nil {
+		return nil, err
+	}
 
 	return &backend, nil
 }
@@ -81,11 +170,65 @@ func (b *StorageBackend) GetDriverName() string {
 	return b.Driver.GetDriverName()
 }
 
+// GetProtocol returns the driver's protocol, which is ProtocolAny for
+// drivers that serve several protocols from different pools.  Use
+// Protocols or StoragePool.GetProtocol to find the protocols actually
+// served.
 func (b *StorageBackend) GetProtocol() config.Protocol {
 	return b.Driver.GetProtocol()
 }
 
+// Protocols returns the protocols of the backend's storage pools, sorted.
+func (b *StorageBackend) Protocols() []config.Protocol {
+	found := make(map[config.Protocol]bool)
+	for _, pool := range b.Storage {
+		found[pool.GetProtocol()] = true
+	}
+	names := make([]string, 0, len(found))
+	for protocol := range found {
+		names = append(names, string(protocol))
+	}
+	sort.Strings(names)
+	protocols := make([]config.Protocol, len(names))
+	for i, name := range names {
+		protocols[i] = config.Protocol(name)
+	}
+	return protocols
+}
+
+// SupportsProtocol returns true if any of the backend's storage pools
+// serves protocol.
+func (b *StorageBackend) SupportsProtocol(protocol config.Protocol) bool {
+	for _, pool := range b.Storage {
+		if pool.GetProtocol() == protocol {
+			return true
+		}
+	}
+	return false
+}
+
+// AddVolume creates a volume in storagePool, as CreateVolume does, and adds
+// it to the pool.
 func (b *StorageBackend) AddVolume(
+	ctx context.Context,
+	volConfig *VolumeConfig,
+	storagePool *StoragePool,
+	volumeAttributes map[string]storage_attribute.Request,
+) (*Volume, error) {
+	vol, err := b.CreateVolume(ctx, volConfig, storagePool,
+		volumeAttributes)
+	if vol != nil {
+		storagePool.AddVolume(vol, false)
+	}
+	return vol, err
+}
+
+// CreateVolume creates a volume in storagePool on the storage system, or
+// returns nil if the pool can't hold it.  The volume isn't added to the
+// pool, so that CreateVolume may run, and be abandoned, without the
+// orchestrator's lock; the caller adds it once it holds the lock.
+func (b *StorageBackend) CreateVolume(
+	ctx context.Context,
 	volConfig *VolumeConfig,
 	storagePool *StoragePool,
 	volumeAttributes map[string]storage_attribute.Request,
@@ -101,20 +244,32 @@ func (b *StorageBackend) AddVolume(
 		return nil, fmt.Errorf("%v is an invalid volume size: %v", volConfig.Size, err)
 	}
 
+	// Virtual pools fill in their defaults and create volumes in their
+	// physical pool.
+	volConfig = storagePool.applyVirtualPoolDefaults(volConfig)
+
 	log.WithFields(log.Fields{
 		"storagePool": storagePool.Name,
 		"size":        volSize,
 		"volConfig.StorageClass": volConfig.StorageClass,
 	}).Debug("Attempting volume create.")
 
+	if err = b.lockDriver(ctx); err != nil {
+		return nil, err
+	}
+	defer b.unlockDriver()
+
 	// CreatePrepare should perform the following tasks:
 	// 1. Sanitize the volume name
 	// 2. Ensure no volume with the same name exists on that backend
 	if b.Driver.CreatePrepare(volConfig) {
+		if err = b.applyNaming(volConfig); err != nil {
+			return nil, err
+		}
 
 		// add volume to the backend
-		args, err := b.Driver.GetVolumeOpts(volConfig, storagePool,
-			volumeAttributes)
+		args, err := b.Driver.GetVolumeOpts(volConfig,
+			storagePool.physical(), volumeAttributes)
 		if err != nil {
 			// An error on GetVolumeOpts is almost certainly going to indicate
 			// a formatting mistake, so go ahead and return an error, rather
@@ -136,9 +291,9 @@ func (b *StorageBackend) AddVolume(
 			}
 		}
 
-		if err = b.Driver.CreateFollowup(volConfig); err != nil {
+		if err = b.createFollowup(volConfig, storagePool); err != nil {
 			errDestroy := b.Driver.Destroy(volConfig.InternalName)
-			if errDestroy != nil {
+			if errDestroy != nil && !errors.IsNotFound(errDestroy) {
 				log.WithFields(log.Fields{
 					"backend": b.Name,
 					"volume":  volConfig.InternalName,
@@ -149,9 +304,8 @@ func (b *StorageBackend) AddVolume(
 			}
 			return nil, err
 		}
-		vol := NewVolume(volConfig, b, storagePool)
-		storagePool.AddVolume(vol, false)
-		return vol, err
+		b.setIscsiPortals(volConfig, storagePool)
+		return NewVolume(volConfig, b, storagePool), nil
 	} else {
 		log.WithFields(log.Fields{
 			"storagePoolName":       storagePool.Name,
@@ -162,6 +316,91 @@ func (b *StorageBackend) AddVolume(
 	return nil, nil
 }
 
+// createFollowup makes a new volume in pool accessible to hosts, through
+// the NVMe hook for NVMe pools, and then makes it read-only if it must be.
+func (b *StorageBackend) createFollowup(
+	volConfig *VolumeConfig, pool *StoragePool,
+) error {
+	if !pool.IsNVMe() {
+		if err := b.Driver.CreateFollowup(volConfig); err != nil {
+			return err
+		}
+	} else if nvmeDriver, ok := b.Driver.(NVMeDriver); !ok {
+		return fmt.Errorf("Backend %s doesn't support NVMe.", b.Name)
+	} else if err := nvmeDriver.CreateNVMeFollowup(volConfig); err != nil {
+		return err
+	}
+	if !volConfig.ReadOnly {
+		return nil
+	}
+	readOnlyDriver, ok := b.Driver.(ReadOnlyDriver)
+	if !ok {
+		return fmt.Errorf("Backend %s doesn't support read-only volumes.",
+			b.Name)
+	}
+	return readOnlyDriver.SetVolumeReadOnly(volConfig, true)
+}
+
+// CloneVolume creates a volume from a snapshot of source, in the same
+// storage pool as source.
+func (b *StorageBackend) CloneVolume(
+	ctx context.Context, volConfig *VolumeConfig, source *Volume,
+	snapshotName string,
+) (*Volume, error) {
+	vol, err := b.CreateClone(ctx, volConfig, source, snapshotName)
+	if err != nil {
+		return nil, err
+	}
+	source.Pool.AddVolume(vol, false)
+	return vol, nil
+}
+
+// CreateClone creates a volume from a snapshot of source on the storage
+// system, but, like CreateVolume, doesn't add it to source's pool.
+func (b *StorageBackend) CreateClone(
+	ctx context.Context, volConfig *VolumeConfig, source *Volume,
+	snapshotName string,
+) (*Volume, error) {
+	if err := b.lockDriver(ctx); err != nil {
+		return nil, err
+	}
+	defer b.unlockDriver()
+
+	log.WithFields(log.Fields{
+		"backend":        b.Name,
+		"volume":         volConfig.Name,
+		"sourceVolume":   source.Config.Name,
+		"sourceSnapshot": snapshotName,
+	}).Debug("Attempting volume clone.")
+
+	if !b.Driver.CreatePrepare(volConfig) {
+		return nil, fmt.Errorf("Backend %s cannot create volume %s.", b.Name,
+			volConfig.Name)
+	}
+	if err := b.applyNaming(volConfig); err != nil {
+		return nil, err
+	}
+	if err := b.Driver.CreateClone(volConfig.InternalName,
+		source.Config.InternalName, snapshotName, ""); err != nil {
+		return nil, err
+	}
+	if err := b.createFollowup(volConfig, source.Pool); err != nil {
+		errDestroy := b.Driver.Destroy(volConfig.InternalName)
+		if errDestroy != nil && !errors.IsNotFound(errDestroy) {
+			log.WithFields(log.Fields{
+				"backend": b.Name,
+				"volume":  volConfig.InternalName,
+			}).Warnf("Mapping the cloned volume failed "+
+				"and %s wasn't able to delete it afterwards: %s. "+
+				"Volume needs to be manually deleted.",
+				config.OrchestratorName, errDestroy)
+		}
+		return nil, err
+	}
+	b.setIscsiPortals(volConfig, source.Pool)
+	return NewVolume(volConfig, b, source.Pool), nil
+}
+
 // HasVolumes returns true if the StorageBackend has one or more volumes
 // provisioned on it.
 func (b *StorageBackend) HasVolumes() bool {
@@ -173,10 +412,12 @@ func (b *StorageBackend) HasVolumes() bool {
 	return false
 }
 
-func (b *StorageBackend) RemoveVolume(vol *Volume) error {
-	if err := b.Driver.Destroy(vol.Config.InternalName); err != nil {
-		// TODO:  Check the error being returned once the nDVP throws errors
-		// for volumes that aren't found.
+// RemoveVolume destroys vol on the backend and removes it from its pool.
+// A volume that is already gone from the backend is simply removed.
+func (b *StorageBackend) RemoveVolume(
+	ctx context.Context, vol *Volume,
+) error {
+	if err := b.DestroyVolume(ctx, vol); err != nil {
 		return err
 	}
 	// Don't bother checking whether the volume exists in the pool, as
@@ -185,12 +426,208 @@ func (b *StorageBackend) RemoveVolume(vol *Volume) error {
 	return nil
 }
 
+// DestroyVolume destroys vol on the backend, but, like CreateVolume, leaves
+// its pool to the caller.  A volume that is already gone isn't an error.
+func (b *StorageBackend) DestroyVolume(
+	ctx context.Context, vol *Volume,
+) error {
+	if err := b.lockDriver(ctx); err != nil {
+		return err
+	}
+	defer b.unlockDriver()
+
+	err := b.Driver.Destroy(vol.Config.InternalName)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func (b *StorageBackend) getSnapshotDriver() (SnapshotDriver, error) {
+	snapshotDriver, ok := b.Driver.(SnapshotDriver)
+	if !ok {
+		return nil, fmt.Errorf("Backend %s does not support snapshots.",
+			b.Name)
+	}
+	return snapshotDriver, nil
+}
+
+func (b *StorageBackend) CreateSnapshot(
+	ctx context.Context, vol *Volume, snapshotName string,
+) error {
+	if err := b.lockDriver(ctx); err != nil {
+		return err
+	}
+	defer b.unlockDriver()
+
+	snapshotDriver, err := b.getSnapshotDriver()
+	if err != nil {
+		return err
+	}
+	return snapshotDriver.CreateSnapshot(vol.Config.InternalName, snapshotName)
+}
+
+func (b *StorageBackend) DeleteSnapshot(
+	ctx context.Context, vol *Volume, snapshotName string,
+) error {
+	if err := b.lockDriver(ctx); err != nil {
+		return err
+	}
+	defer b.unlockDriver()
+
+	snapshotDriver, err := b.getSnapshotDriver()
+	if err != nil {
+		return err
+	}
+	return snapshotDriver.DeleteSnapshot(vol.Config.InternalName, snapshotName)
+}
+
+// SupportsGroupSnapshots returns true if the backend can snapshot several
+// volumes consistently.
+func (b *StorageBackend) SupportsGroupSnapshots() bool {
+	_, ok := b.Driver.(GroupSnapshotDriver)
+	return ok
+}
+
+// CreateGroupSnapshot snapshots vols, which must be on this backend, at the
+// same point in time.  Each snapshot is named snapshotName.
+func (b *StorageBackend) CreateGroupSnapshot(
+	ctx context.Context, vols []*Volume, snapshotName string,
+) error {
+	if err := b.lockDriver(ctx); err != nil {
+		return err
+	}
+	defer b.unlockDriver()
+
+	groupDriver, ok := b.Driver.(GroupSnapshotDriver)
+	if !ok {
+		return fmt.Errorf("Backend %s does not support consistent group "+
+			"snapshots.", b.Name)
+	}
+	names := make([]string, 0, len(vols))
+	for _, vol := range vols {
+		names = append(names, vol.Config.InternalName)
+	}
+	return groupDriver.CreateGroupSnapshot(names, snapshotName)
+}
+
+// SupportsUsage returns true if the backend can report the space its
+// volumes consume.
+func (b *StorageBackend) SupportsUsage() bool {
+	_, ok := b.Driver.(UsageDriver)
+	return ok
+}
+
+// GetUsedBytes returns the space one of the backend's volumes consumes on
+// the storage system.
+func (b *StorageBackend) GetUsedBytes(
+	ctx context.Context, internalName string,
+) (uint64, error) {
+	if err := b.lockDriver(ctx); err != nil {
+		return 0, err
+	}
+	defer b.unlockDriver()
+
+	usageDriver, ok := b.Driver.(UsageDriver)
+	if !ok {
+		return 0, fmt.Errorf("Backend %s does not report volume usage.",
+			b.Name)
+	}
+	return usageDriver.GetUsedBytes(internalName)
+}
+
+// SupportsVolumeAccess returns true if the backend can manage per-volume
+// access lists.
+func (b *StorageBackend) SupportsVolumeAccess() bool {
+	_, ok := b.Driver.(AccessDriver)
+	return ok
+}
+
+func (b *StorageBackend) SetVolumeAccess(
+	ctx context.Context, vol *Volume, access *VolumeAccess,
+) error {
+	if err := b.lockDriver(ctx); err != nil {
+		return err
+	}
+	defer b.unlockDriver()
+
+	accessDriver, ok := b.Driver.(AccessDriver)
+	if !ok {
+		return fmt.Errorf("Backend %s does not support volume access control.",
+			b.Name)
+	}
+	return accessDriver.SetVolumeAccess(vol.Config, access)
+}
+
+// SupportsReadOnly returns true if the backend can make volumes read-only.
+func (b *StorageBackend) SupportsReadOnly() bool {
+	_, ok := b.Driver.(ReadOnlyDriver)
+	return ok
+}
+
+// SupportsVolumeRename returns true if the backend can rename volumes.
+func (b *StorageBackend) SupportsVolumeRename() bool {
+	_, ok := b.Driver.(RenameDriver)
+	return ok
+}
+
+// RenameVolume renames a volume on the backend.  The caller updates the
+// volume's internal name.
+func (b *StorageBackend) RenameVolume(
+	ctx context.Context, internalName, newInternalName string,
+) error {
+	if err := b.lockDriver(ctx); err != nil {
+		return err
+	}
+	defer b.unlockDriver()
+
+	renameDriver, ok := b.Driver.(RenameDriver)
+	if !ok {
+		return fmt.Errorf("Backend %s does not support renaming volumes.",
+			b.Name)
+	}
+	return renameDriver.Rename(internalName, newInternalName)
+}
+
+// ListSnapshots returns the names of a volume's snapshots.
+func (b *StorageBackend) ListSnapshots(
+	ctx context.Context, vol *Volume,
+) ([]string, error) {
+	if err := b.lockDriver(ctx); err != nil {
+		return nil, err
+	}
+	defer b.unlockDriver()
+
+	snapshots, err := b.Driver.SnapshotList(vol.Config.InternalName)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]string, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		ret = append(ret, snapshot.Name)
+	}
+	return ret, nil
+}
+
 type StorageBackendExternal struct {
 	Name    string                          `json:"name"`
 	Config  interface{}                     `json:"config"`
 	Storage map[string]*StoragePoolExternal `json:"storage"`
 	Online  bool                            `json:"online"`
 	Volumes []string                        `json:"volumes"`
+	Limits  *BackendLimits                  `json:"limits,omitempty"`
+	Portals *PortalConfig                   `json:"portals,omitempty"`
+	// Chap is reported with its secrets redacted.
+	Chap *ChapConfig `json:"chap,omitempty"`
+	// CredentialRotation is reported without any pending password.
+	CredentialRotation *RotationConfig `json:"credentialRotation,omitempty"`
+	// Protocols are those of the backend's storage pools.
+	Protocols []config.Protocol `json:"protocols"`
+	// InitError is set for backends whose driver has not yet initialized.
+	InitError   string              `json:"initError,omitempty"`
+	Credentials *credentials.Config `json:"credentials,omitempty"`
+	// Capabilities are those of the backend's driver.
+	Capabilities *Capabilities `json:"capabilities,omitempty"`
 }
 
 func (b *StorageBackend) ConstructExternal() *StorageBackendExternal {
@@ -201,6 +638,29 @@ func (b *StorageBackend) ConstructExternal() *StorageBackendExternal {
 		Online:  b.Online,
 		Volumes: make([]string, 0),
 	}
+	backendExternal.Protocols = b.Protocols()
+	backendExternal.Capabilities = b.Capabilities()
+	if b.Limits.IsSet() {
+		limits := b.Limits
+		backendExternal.Limits = &limits
+	}
+	if b.Portals.IsSet() {
+		portals := b.Portals
+		backendExternal.Portals = &portals
+	}
+	if b.Chap.IsSet() {
+		backendExternal.Chap = b.Chap.Redacted()
+	}
+	if b.Rotation.IsSet() {
+		backendExternal.CredentialRotation = b.Rotation.Redacted()
+	}
+	if b.Credentials != nil {
+		backendExternal.Config = b.Credentials.Redact(backendExternal.Config,
+			credentials.Redacted)
+		backendExternal.Credentials = b.Credentials
+	}
+	// Never report passwords and keys, whatever their source.
+	backendExternal.Config = credentials.Sanitize(backendExternal.Config)
 
 	// TODO: Consider reporting the aggregate space occupied by the provisioned
 	// volumes here.
@@ -222,6 +682,9 @@ type PersistentStorageBackendConfig struct {
 	SolidfireConfig         *dvp.SolidfireStorageDriverConfig `json:"solidfire_config,omitempty"`
 	EseriesConfig           *dvp.ESeriesStorageDriverConfig   `json:"eseries_config,omitempty"`
 	FakeStorageDriverConfig *fake.FakeStorageDriverConfig     `json:"fake_config,omitempty"`
+	// DriverConfig holds the config of drivers registered with the storage
+	// factory, as a pointer to the driver's config struct.
+	DriverConfig interface{} `json:"driver_config,omitempty"`
 }
 
 type StorageBackendPersistent struct {
@@ -229,6 +692,16 @@ type StorageBackendPersistent struct {
 	Config  PersistentStorageBackendConfig `json:"config"`
 	Name    string                         `json:"name"`
 	Online  bool                           `json:"online"`
+	Limits  *BackendLimits                 `json:"limits,omitempty"`
+	Pools   *PoolConfig                    `json:"pools,omitempty"`
+	Portals *PortalConfig                  `json:"portals,omitempty"`
+	Chap    *ChapConfig                    `json:"chap,omitempty"`
+	Naming  *NamingConfig                  `json:"naming,omitempty"`
+	// Rotation holds the backend's credential rotation settings and state.
+	Rotation *RotationConfig `json:"credentialRotation,omitempty"`
+	// Credentials refers to the secret from which the fields missing from
+	// Config are read.
+	Credentials *credentials.Config `json:"credentials,omitempty"`
 }
 
 func (b *StorageBackend) ConstructPersistent() *StorageBackendPersistent {
@@ -239,9 +712,64 @@ func (b *StorageBackend) ConstructPersistent() *StorageBackendPersistent {
 		Online:  b.Online,
 	}
 	b.Driver.StoreConfig(&persistentBackend.Config)
+	if b.Limits.IsSet() {
+		limits := b.Limits
+		persistentBackend.Limits = &limits
+	}
+	if b.Pools.IsSet() {
+		pools := b.Pools
+		persistentBackend.Pools = &pools
+	}
+	if b.Portals.IsSet() {
+		portals := b.Portals
+		persistentBackend.Portals = &portals
+	}
+	if b.Chap.IsSet() {
+		chap := b.Chap
+		persistentBackend.Chap = &chap
+	}
+	if b.Naming.IsSet() {
+		naming := b.Naming
+		persistentBackend.Naming = &naming
+	}
+	if b.Rotation.IsSet() {
+		rotation := b.Rotation
+		persistentBackend.Rotation = &rotation
+	}
+	if b.Credentials != nil {
+		// Store the reference to the secret rather than its values.
+		persistentBackend.Config = *b.Credentials.Redact(
+			&persistentBackend.Config, "").(*PersistentStorageBackendConfig)
+		persistentBackend.Credentials = b.Credentials
+	}
 	return persistentBackend
 }
 
+// EncryptSecrets encrypts the secrets in the backend's config for storage,
+// if an encryption key is set; see credentials.SetEncryptionKey.
+func (p *StorageBackendPersistent) EncryptSecrets() error {
+	encrypted, err := credentials.Encrypt(&p.Config)
+	if err != nil {
+		return err
+	}
+	p.Config = *encrypted.(*PersistentStorageBackendConfig)
+	if p.Chap != nil {
+		encrypted, err = credentials.Encrypt(p.Chap)
+		if err != nil {
+			return err
+		}
+		p.Chap = encrypted.(*ChapConfig)
+	}
+	if p.Rotation != nil {
+		encrypted, err = credentials.Encrypt(p.Rotation)
+		if err != nil {
+			return err
+		}
+		p.Rotation = encrypted.(*RotationConfig)
+	}
+	return nil
+}
+
 // Unfortunately, this method appears to be necessary to avoid arbitrary values
 // ending up in the json.RawMessage fields of CommonStorageDriverConfig.
 // Ideally, StorageBackendPersistent would just store a serialized config, but
@@ -260,11 +788,83 @@ func (p *StorageBackendPersistent) MarshalConfig() (string, error) {
 		bytes, err = json.Marshal(p.Config.EseriesConfig)
 	case p.Config.FakeStorageDriverConfig != nil:
 		bytes, err = json.Marshal(p.Config.FakeStorageDriverConfig)
+	case p.Config.DriverConfig != nil:
+		bytes, err = json.Marshal(p.Config.DriverConfig)
 	default:
 		return "", fmt.Errorf("No recognized config found for backend %s.", p.Name)
 	}
 	if err != nil {
 		return "", err
 	}
+	if bytes, err = credentials.DecryptJSON(bytes); err != nil {
+		return "", fmt.Errorf("Unable to read config for backend %s:  %v",
+			p.Name, err)
+	}
+	if p.Limits != nil {
+		if bytes, err = mergeConfigJSON(bytes, p.Limits); err != nil {
+			return "", err
+		}
+	}
+	if p.Pools != nil {
+		if bytes, err = mergeConfigJSON(bytes, p.Pools); err != nil {
+			return "", err
+		}
+	}
+	if p.Portals != nil {
+		if bytes, err = mergeConfigJSON(bytes, p.Portals); err != nil {
+			return "", err
+		}
+	}
+	if p.Naming != nil {
+		if bytes, err = mergeConfigJSON(bytes, p.Naming); err != nil {
+			return "", err
+		}
+	}
+	if p.Chap != nil {
+		chapJSON, err := json.Marshal(p.Chap)
+		if err != nil {
+			return "", err
+		}
+		if chapJSON, err = credentials.DecryptJSON(chapJSON); err != nil {
+			return "", fmt.Errorf("Unable to read CHAP credentials for "+
+				"backend %s:  %v", p.Name, err)
+		}
+		if bytes, err = mergeConfigJSON(bytes,
+			json.RawMessage(chapJSON)); err != nil {
+			return "", err
+		}
+	}
+	if p.Rotation != nil {
+		rotationJSON, err := json.Marshal(p.Rotation)
+		if err != nil {
+			return "", err
+		}
+		rotationJSON, err = credentials.DecryptJSON(rotationJSON)
+		if err != nil {
+			return "", fmt.Errorf("Unable to read credential rotation "+
+				"state for backend %s:  %v", p.Name, err)
+		}
+		if bytes, err = mergeConfigJSON(bytes,
+			map[string]json.RawMessage{
+				"credentialRotation": rotationJSON,
+			}); err != nil {
+			return "", err
+		}
+	}
+	if p.Credentials != nil {
+		bytes, err = mergeConfigJSON(bytes,
+			map[string]*credentials.Config{"credentials": p.Credentials})
+		if err != nil {
+			return "", err
+		}
+	}
+	// Keep the stored name, which may differ from the one the driver
+	// derives if the backend was renamed.
+	if p.Name != "" {
+		bytes, err = mergeConfigJSON(bytes, &backendNameConfig{p.Name})
+		if err != nil {
+			return "", err
+		}
+	}
 	return string(bytes), err
 }
@@ -0,0 +1,70 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package storage
+
+import (
+	"fmt"
+
+	sa "github.com/netapp/trident/storage_attribute"
+)
+
+// Capabilities describes what a backend's driver can do with the volumes
+// it creates.  Storage classes and volumes that need a capability are only
+// placed on backends that have it.
+type Capabilities struct {
+	SupportsClones    bool `json:"supportsClones"`
+	SupportsSnapshots bool `json:"supportsSnapshots"`
+	SupportsResize    bool `json:"supportsResize"`
+	SupportsQoS       bool `json:"supportsQoS"`
+	// MaxVolumeSize is the size, in bytes, of the largest volume the driver
+	// can create, or 0 if it doesn't know of a limit.
+	MaxVolumeSize uint64 `json:"maxVolumeSize,omitempty"`
+}
+
+// CapabilityDriver is implemented by drivers that report their
+// capabilities.  The capabilities of other drivers are inferred from the
+// interfaces they implement and the attributes their pools offer.
+type CapabilityDriver interface {
+	GetCapabilities() Capabilities
+}
+
+// Capabilities returns the capabilities of the backend's driver.
+func (b *StorageBackend) Capabilities() *Capabilities {
+	if d, ok := b.Driver.(CapabilityDriver); ok {
+		capabilities := d.GetCapabilities()
+		return &capabilities
+	}
+	_, snapshots := b.Driver.(SnapshotDriver)
+	return &Capabilities{
+		SupportsClones:    true,
+		SupportsSnapshots: snapshots,
+		SupportsQoS:       b.offersIOPS(),
+	}
+}
+
+// offersIOPS returns true if any of the backend's pools offers IOPS, and
+// so can apply QoS limits to its volumes.
+func (b *StorageBackend) offersIOPS() bool {
+	for _, pool := range b.Storage {
+		if _, ok := pool.Attributes[sa.IOPS]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkMaxVolumeSize returns an error if the backend's driver can't create
+// a volume as large as volConfig's.
+func (b *StorageBackend) checkMaxVolumeSize(volConfig *VolumeConfig) error {
+	maxSize := b.Capabilities().MaxVolumeSize
+	if maxSize == 0 {
+		return nil
+	}
+	// Volumes whose size can't be determined are rejected elsewhere.
+	size, err := sizeInBytes(volConfig.Size)
+	if err != nil || size <= maxSize {
+		return nil
+	}
+	return fmt.Errorf("Volume size %s exceeds the maximum of %d bytes "+
+		"backend %s's driver can create.", volConfig.Size, maxSize, b.Name)
+}
@@ -0,0 +1,189 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/credentials"
+)
+
+const (
+	// chapSecretLength is the length of generated CHAP secrets.  iSCSI
+	// targets commonly require secrets of 12 to 16 characters.
+	chapSecretLength = 16
+	chapSecretMin    = 12
+	chapSecretMax    = 16
+	chapSecretChars  = "abcdefghijklmnopqrstuvwxyz" +
+		"ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+// ChapConfig enables bidirectional CHAP authentication for a SAN backend's
+// volumes.  It is read from the backend config alongside the driver
+// settings.  Secrets that aren't set are generated when the backend is
+// created and stored, encrypted if a key is set, with the backend.
+type ChapConfig struct {
+	UseCHAP bool `json:"useCHAP,omitempty"`
+	// ChapUsername and ChapInitiatorSecret authenticate hosts to the
+	// storage system.
+	ChapUsername        string `json:"chapUsername,omitempty"`
+	ChapInitiatorSecret string `json:"chapInitiatorSecret,omitempty"`
+	// ChapTargetUsername and ChapTargetInitiatorSecret authenticate the
+	// storage system to hosts.
+	ChapTargetUsername        string `json:"chapTargetUsername,omitempty"`
+	ChapTargetInitiatorSecret string `json:"chapTargetInitiatorSecret,omitempty"`
+}
+
+// ChapDriver is implemented by drivers that can require CHAP for their
+// volumes.  SetChap applies the credentials to the storage system, e.g., to
+// its igroups or accounts; drivers may fill in credentials the storage
+// system dictates, such as the username.
+type ChapDriver interface {
+	SetChap(chap *ChapConfig) error
+}
+
+// ParseChapConfig reads any CHAP settings in a backend config.
+func ParseChapConfig(configJSON string) (*ChapConfig, error) {
+	chap := &ChapConfig{}
+	if err := json.Unmarshal([]byte(configJSON), chap); err != nil {
+		return nil, fmt.Errorf("Unable to parse CHAP settings:  %v", err)
+	}
+	if err := chap.Validate(); err != nil {
+		return nil, err
+	}
+	return chap, nil
+}
+
+func (c *ChapConfig) Validate() error {
+	if !c.UseCHAP {
+		if c.ChapUsername != "" || c.ChapInitiatorSecret != "" ||
+			c.ChapTargetUsername != "" || c.ChapTargetInitiatorSecret != "" {
+			return fmt.Errorf("CHAP credentials are set, but useCHAP " +
+				"isn't.")
+		}
+		return nil
+	}
+	for field, secret := range map[string]string{
+		"chapInitiatorSecret":       c.ChapInitiatorSecret,
+		"chapTargetInitiatorSecret": c.ChapTargetInitiatorSecret,
+	} {
+		if secret != "" &&
+			(len(secret) < chapSecretMin || len(secret) > chapSecretMax) {
+			return fmt.Errorf("%s must be %d to %d characters long.", field,
+				chapSecretMin, chapSecretMax)
+		}
+	}
+	if c.ChapInitiatorSecret != "" &&
+		c.ChapInitiatorSecret == c.ChapTargetInitiatorSecret {
+		return fmt.Errorf("chapInitiatorSecret and " +
+			"chapTargetInitiatorSecret must differ.")
+	}
+	return nil
+}
+
+// IsSet returns true if CHAP is enabled.
+func (c *ChapConfig) IsSet() bool {
+	return c.UseCHAP
+}
+
+// Generate fills in any usernames and secrets that aren't set.
+func (c *ChapConfig) Generate() error {
+	if c.ChapUsername == "" {
+		c.ChapUsername = config.OrchestratorName
+	}
+	if c.ChapTargetUsername == "" {
+		c.ChapTargetUsername = config.OrchestratorName + "-target"
+	}
+	for _, secret := range []*string{&c.ChapInitiatorSecret,
+		&c.ChapTargetInitiatorSecret} {
+		if *secret != "" {
+			continue
+		}
+		generated, err := generateChapSecret()
+		if err != nil {
+			return fmt.Errorf("Unable to generate CHAP secret:  %v", err)
+		}
+		*secret = generated
+	}
+	return nil
+}
+
+// Redacted returns a copy of the settings with the secrets redacted, for
+// reporting through the API.
+func (c *ChapConfig) Redacted() *ChapConfig {
+	return credentials.Sanitize(c).(*ChapConfig)
+}
+
+func generateChapSecret() (string, error) {
+	return generateSecret(chapSecretLength)
+}
+
+// generateSecret returns a random alphanumeric secret of the given length.
+func generateSecret(length int) (string, error) {
+	secret := make([]byte, length)
+	max := big.NewInt(int64(len(chapSecretChars)))
+	for i := range secret {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		secret[i] = chapSecretChars[n.Int64()]
+	}
+	return string(secret), nil
+}
+
+// Inherit fills in any credentials that aren't set from other, e.g., the
+// settings of the backend being updated, so that updating a backend doesn't
+// replace its generated secrets.
+func (c *ChapConfig) Inherit(other *ChapConfig) {
+	if !other.UseCHAP {
+		return
+	}
+	if c.ChapUsername == "" {
+		c.ChapUsername = other.ChapUsername
+	}
+	if c.ChapInitiatorSecret == "" {
+		c.ChapInitiatorSecret = other.ChapInitiatorSecret
+	}
+	if c.ChapTargetUsername == "" {
+		c.ChapTargetUsername = other.ChapTargetUsername
+	}
+	if c.ChapTargetInitiatorSecret == "" {
+		c.ChapTargetInitiatorSecret = other.ChapTargetInitiatorSecret
+	}
+}
+
+// CheckChap returns an error if the backend can't use CHAP:  if it doesn't
+// provide block volumes or its driver doesn't support CHAP.
+func (b *StorageBackend) CheckChap() error {
+	if !b.SupportsProtocol(config.Block) {
+		return fmt.Errorf("CHAP only applies to %s backends.", config.Block)
+	}
+	if _, ok := b.Driver.(ChapDriver); !ok {
+		return fmt.Errorf("The %s driver doesn't support CHAP.",
+			b.GetDriverName())
+	}
+	return nil
+}
+
+// ApplyChap generates any missing CHAP credentials for the backend and
+// applies them to its storage system.  It fails if the backend can't use
+// CHAP; see CheckChap.
+func (b *StorageBackend) ApplyChap() error {
+	if err := b.CheckChap(); err != nil {
+		return err
+	}
+	chapDriver := b.Driver.(ChapDriver)
+	if err := b.Chap.Generate(); err != nil {
+		return err
+	}
+	if err := chapDriver.SetChap(&b.Chap); err != nil {
+		return fmt.Errorf("Unable to set CHAP credentials for backend %s:  "+
+			"%v", b.Name, err)
+	}
+	return nil
+}
@@ -54,6 +54,12 @@ func GetCommonStorageDriverConfigExternal(
 func GetCommonInternalVolumeName(
 	c *dvp.CommonStorageDriverConfig, name string,
 ) string {
+	return fmt.Sprintf("%s-%s", GetStoragePrefix(c), name)
+}
+
+// GetStoragePrefix returns the storagePrefix set in a driver config, or
+// the orchestrator's name if none is.
+func GetStoragePrefix(c *dvp.CommonStorageDriverConfig) string {
 	prefixToUse := ""
 	// BEGIN Copied from the NetApp DVP.
 	storagePrefixRaw := c.StoragePrefixRaw // this is a raw version of the json value, we will get quotes in it
@@ -70,5 +76,44 @@ func GetCommonInternalVolumeName(
 	if prefixToUse == "" {
 		prefixToUse = config.OrchestratorName
 	}
-	return fmt.Sprintf("%s-%s", prefixToUse, name)
+	return prefixToUse
+}
+
+// backendNameConfig holds the backend config setting that overrides the name
+// a driver derives for its backend.
+type backendNameConfig struct {
+	BackendName string `json:"backendName,omitempty"`
+}
+
+// ParseBackendName returns the backendName set in a backend config, or "" if
+// the backend should have the name its driver derives for it.
+func ParseBackendName(configJSON string) (string, error) {
+	nameConfig := &backendNameConfig{}
+	if err := json.Unmarshal([]byte(configJSON), nameConfig); err != nil {
+		return "", fmt.Errorf("Unable to parse backend name:  %v", err)
+	}
+	return nameConfig.BackendName, nil
+}
+
+// mergeConfigJSON adds the fields of v to a serialized driver config.  This
+// lets settings that Trident reads from the backend config, but that the
+// driver doesn't know about, survive a round trip through the persistent
+// store.
+func mergeConfigJSON(configJSON []byte, v interface{}) ([]byte, error) {
+	var configMap map[string]json.RawMessage
+	if err := json.Unmarshal(configJSON, &configMap); err != nil {
+		return nil, err
+	}
+	extraJSON, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var extraMap map[string]json.RawMessage
+	if err = json.Unmarshal(extraJSON, &extraMap); err != nil {
+		return nil, err
+	}
+	for k, v := range extraMap {
+		configMap[k] = v
+	}
+	return json.Marshal(configMap)
 }
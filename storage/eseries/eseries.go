@@ -55,9 +55,13 @@ func (d *EseriesStorageDriver) GetStorageBackendSpecs(backend *storage.StorageBa
 			vc.Attributes[sa.Media] = sa.NewStringOffer(sa.SSD)
 		}
 
-		// No snapshots or thin provisioning on E-series
+		// No snapshots, thin provisioning, or storage efficiency on
+		// E-series
 		vc.Attributes[sa.Snapshots] = sa.NewBoolOffer(false)
-		vc.Attributes[sa.ProvisioningType] = sa.NewStringOffer("thick")
+		vc.Attributes[sa.Encryption] = sa.NewBoolOffer(false)
+		vc.Attributes[sa.ProvisioningType] = sa.NewStringOffer(sa.Thick)
+		vc.Attributes[sa.Deduplication] = sa.NewBoolOffer(false)
+		vc.Attributes[sa.Compression] = sa.NewBoolOffer(false)
 
 		backend.AddStoragePool(vc)
 
@@ -202,6 +206,18 @@ func (d *EseriesStorageDriver) CreateFollowup(volConfig *storage.VolumeConfig) e
 	return nil
 }
 
+// Destroy deletes a volume, failing with a NotFound error if it doesn't
+// exist.
+func (d *EseriesStorageDriver) Destroy(name string) error {
+	return storage.DestroyVolume(&d.ESeriesStorageDriver, name)
+}
+
+// GetCapabilities reports that E-Series volumes can't be cloned or
+// snapshotted, and have no QoS.
+func (d *EseriesStorageDriver) GetCapabilities() storage.Capabilities {
+	return storage.Capabilities{}
+}
+
 func (d *EseriesStorageDriver) GetProtocol() config.Protocol {
 	return config.Block
 }
@@ -0,0 +1,85 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package factory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/ghodss/yaml"
+)
+
+// yamlSeparatorRegex matches the lines separating the documents of a YAML
+// stream.
+var yamlSeparatorRegex = regexp.MustCompile(`(?m)^---[ \t]*\r?$`)
+
+// SplitBackendConfigs returns the JSON config of each backend in document,
+// which holds either a JSON object, a JSON array of objects, or YAML with
+// one config per document or a list of configs.  multiple is true unless
+// document holds a single config rather than a list of them.  A single JSON
+// config is returned as is.
+func SplitBackendConfigs(document []byte) (
+	configs []string, multiple bool, err error,
+) {
+	trimmed := bytes.TrimSpace(document)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return []string{string(document)}, false, nil
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		configs, err = splitJSONList(trimmed)
+		return configs, true, err
+	}
+
+	docs := yamlSeparatorRegex.Split(string(document), -1)
+	multiple = len(docs) > 1
+	for _, doc := range docs {
+		if len(bytes.TrimSpace([]byte(doc))) == 0 {
+			continue
+		}
+		docJSON, err := yaml.YAMLToJSON([]byte(doc))
+		if err != nil {
+			return nil, false, fmt.Errorf("Invalid backend config YAML:  %v",
+				err)
+		}
+		switch {
+		case bytes.Equal(docJSON, []byte("null")):
+			continue
+		case bytes.HasPrefix(docJSON, []byte("[")):
+			list, err := splitJSONList(docJSON)
+			if err != nil {
+				return nil, false, err
+			}
+			configs = append(configs, list...)
+			multiple = true
+		case bytes.HasPrefix(docJSON, []byte("{")):
+			configs = append(configs, string(docJSON))
+		default:
+			return nil, false, fmt.Errorf("Backend config is not an object.")
+		}
+	}
+	if len(configs) == 0 {
+		return nil, false, fmt.Errorf("Document holds no backend configs.")
+	}
+	return configs, multiple, nil
+}
+
+// splitJSONList returns the configs in a JSON array.
+func splitJSONList(list []byte) ([]string, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(list, &items); err != nil {
+		return nil, fmt.Errorf("Invalid backend config list:  %v", err)
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("Document holds no backend configs.")
+	}
+	configs := make([]string, 0, len(items))
+	for i, item := range items {
+		if !bytes.HasPrefix(bytes.TrimSpace(item), []byte("{")) {
+			return nil, fmt.Errorf("Backend config %d is not an object.", i)
+		}
+		configs = append(configs, string(item))
+	}
+	return configs, nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package factory
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSplitBackendConfigs(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		document string
+		names    []string
+		multiple bool
+	}{
+		{"JSON object", `{"backendName": "a"}`, []string{"a"}, false},
+		{"JSON array", `[{"backendName": "a"}, {"backendName": "b"}]`,
+			[]string{"a", "b"}, true},
+		{"YAML", "backendName: a\n", []string{"a"}, false},
+		{"YAML documents", "---\nbackendName: a\n---\nbackendName: b\n",
+			[]string{"a", "b"}, true},
+		{"YAML list", "- backendName: a\n- backendName: b\n",
+			[]string{"a", "b"}, true},
+	} {
+		configs, multiple, err := SplitBackendConfigs([]byte(test.document))
+		if err != nil {
+			t.Errorf("%s:  unable to split document:  %v", test.name, err)
+			continue
+		}
+		if multiple != test.multiple {
+			t.Errorf("%s:  expected multiple to be %t.", test.name,
+				test.multiple)
+		}
+		names := make([]string, 0, len(configs))
+		for _, config := range configs {
+			var backend struct {
+				BackendName string `json:"backendName"`
+			}
+			if err = json.Unmarshal([]byte(config), &backend); err != nil {
+				t.Errorf("%s:  invalid config %s:  %v", test.name, config,
+					err)
+			}
+			names = append(names, backend.BackendName)
+		}
+		if !reflect.DeepEqual(names, test.names) {
+			t.Errorf("%s:  expected backends %v; got %v.", test.name,
+				test.names, names)
+		}
+	}
+
+	for _, document := range []string{
+		``,
+		`[]`,
+		`[{"backendName": "a"}, "b"]`,
+		"---\n---\n",
+		"backendName: [a\n",
+		"just a string",
+	} {
+		if _, _, err := SplitBackendConfigs([]byte(document)); err == nil {
+			t.Errorf("Split invalid document %q.", document)
+		}
+	}
+}
@@ -12,6 +12,8 @@ import (
 	fake_driver "github.com/netapp/trident/drivers/fake"
 
 	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/credentials"
+	"github.com/netapp/trident/logging"
 	"github.com/netapp/trident/storage"
 	"github.com/netapp/trident/storage/eseries"
 	"github.com/netapp/trident/storage/fake"
@@ -25,7 +27,22 @@ func isPassed(s string) bool {
 	return s == passed
 }
 
-func NewStorageBackendForConfig(configJSON string) (
+// NewStorageBackendForConfig creates a backend from a config supplied by a
+// user, after expanding the environment variables and files it references;
+// see expandTemplate.
+func NewStorageBackendForConfig(
+	configJSON string,
+) (*storage.StorageBackend, error) {
+	expanded, err := expandTemplate(configJSON)
+	if err != nil {
+		return nil, err
+	}
+	return NewStorageBackendForStoredConfig(expanded)
+}
+
+// NewStorageBackendForStoredConfig creates a backend from a config Trident
+// stored, whose references were expanded when the backend was added.
+func NewStorageBackendForStoredConfig(configJSON string) (
 	sb *storage.StorageBackend, err error,
 ) {
 	var storageDriver storage.StorageDriver
@@ -38,11 +55,61 @@ func NewStorageBackendForConfig(configJSON string) (
 		}
 	}()
 
+	creds, err := credentials.Parse(configJSON)
+	if err != nil {
+		return
+	}
+	if creds != nil {
+		if configJSON, err = creds.Resolve(configJSON); err != nil {
+			return
+		}
+	}
+	// Keep the backend's secrets out of the log, whatever logs them.
+	for _, secret := range credentials.SecretValues(configJSON) {
+		logging.AddSecret(secret)
+	}
+	log.WithFields(log.Fields{
+		"config": credentials.SanitizeJSON(configJSON),
+	}).Debug("Creating backend.")
+
 	commonConfig, err := dvp.ValidateCommonSettings(configJSON)
 	if err != nil {
 		err = fmt.Errorf("Input failed validation: %v", err)
 		return
 	}
+	limits, err := storage.ParseBackendLimits(configJSON)
+	if err != nil {
+		return
+	}
+	poolConfig, err := storage.ParsePoolConfig(configJSON)
+	if err != nil {
+		return
+	}
+	portalConfig, err := storage.ParsePortalConfig(configJSON)
+	if err != nil {
+		return
+	}
+	chapConfig, err := storage.ParseChapConfig(configJSON)
+	if err != nil {
+		return
+	}
+	rotationConfig, err := storage.ParseRotationConfig(configJSON)
+	if err != nil {
+		return
+	}
+	backendName, err := storage.ParseBackendName(configJSON)
+	if err != nil {
+		return
+	}
+	namingConfig, err := storage.ParseNamingConfig(configJSON)
+	if err != nil {
+		return
+	}
+	if err = namingConfig.Validate(
+		commonConfig.StorageDriverName); err != nil {
+		return
+	}
+	namingConfig.Prefix = storage.GetStoragePrefix(commonConfig)
 	// Pre-driver initialization setup
 	switch commonConfig.StorageDriverName {
 	case dvp.OntapNASStorageDriverName:
@@ -56,9 +123,13 @@ func NewStorageBackendForConfig(configJSON string) (
 	case fake_driver.FakeStorageDriverName:
 		storageDriver = &fake.FakeStorageDriver{}
 	default:
-		err = fmt.Errorf("Unknown storage driver: %v",
-			commonConfig.StorageDriverName)
-		return
+		driverFactory, ok := getDriverFactory(commonConfig.StorageDriverName)
+		if !ok {
+			err = fmt.Errorf("Unknown storage driver: %v",
+				commonConfig.StorageDriverName)
+			return
+		}
+		storageDriver = driverFactory.NewDriver()
 	}
 
 	// Warn about ignored fields in common config if any are set
@@ -254,10 +325,34 @@ func NewStorageBackendForConfig(configJSON string) (
 
 	case fake_driver.FakeStorageDriverName:
 	default:
-		err = fmt.Errorf("Unknown storage driver: %v",
-			commonConfig.StorageDriverName)
-		return
+		driverFactory, _ := getDriverFactory(commonConfig.StorageDriverName)
+		if validateErr := driverFactory.Validate(
+			storageDriver); validateErr != nil {
+			err = fmt.Errorf("Problem validating storage driver: '%v' "+
+				"error: %v", commonConfig.StorageDriverName, validateErr)
+			return
+		}
 	}
 	sb, err = storage.NewStorageBackend(storageDriver)
+	if err != nil {
+		return
+	}
+	if backendName != "" {
+		sb.Name = backendName
+	}
+	sb.Limits = *limits
+	sb.Sessions = storage.NewSessionPool(limits.MaxSessions)
+	sb.Portals = *portalConfig
+	sb.Naming = *namingConfig
+	// CHAP is applied when the backend is added, so that an updated
+	// backend can keep its credentials; see StorageBackend.ApplyChap.
+	sb.Chap = *chapConfig
+	sb.Rotation = *rotationConfig
+	sb.Credentials = creds
+	if poolConfig.IsSet() {
+		if err = sb.ApplyPoolConfig(poolConfig); err != nil {
+			return nil, err
+		}
+	}
 	return
 }
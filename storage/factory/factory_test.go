@@ -7,6 +7,12 @@ import (
 	"testing"
 
 	dvp "github.com/netapp/netappdvp/storage_drivers"
+
+	"github.com/netapp/trident/config"
+	fake_driver "github.com/netapp/trident/drivers/fake"
+	"github.com/netapp/trident/storage"
+	"github.com/netapp/trident/storage/fake"
+	sa "github.com/netapp/trident/storage_attribute"
 )
 
 // TestInitializeRecovery intentionally passes a bogus config to
@@ -36,3 +42,84 @@ func TestInitializeRecovery(t *testing.T) {
 		t.Error("Failed to get error for invalid configuration.")
 	}
 }
+
+const registeredDriverName = "registered-fake"
+
+// registeredFactory creates fake drivers under another name, to stand in
+// for an out-of-tree driver.
+type registeredFactory struct {
+	validated bool
+}
+
+func (f *registeredFactory) NewDriver() storage.StorageDriver {
+	return &fake.FakeStorageDriver{}
+}
+
+func (f *registeredFactory) Validate(driver storage.StorageDriver) error {
+	f.validated = true
+	return nil
+}
+
+func TestRegisterDriver(t *testing.T) {
+	f := &registeredFactory{}
+	if err := RegisterDriver(registeredDriverName, f); err != nil {
+		t.Fatal("Unable to register driver:  ", err)
+	}
+	if err := RegisterDriver(registeredDriverName, f); err == nil {
+		t.Error("Registered the same driver twice.")
+	}
+	if err := RegisterDriver(dvp.OntapNASStorageDriverName, f); err == nil {
+		t.Error("Replaced a built-in driver.")
+	}
+	found := false
+	for _, name := range RegisteredDrivers() {
+		if name == registeredDriverName {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("%s not listed in registered drivers.", registeredDriverName)
+	}
+
+	configJSON, err := fake_driver.NewFakeStorageDriverConfigJSON(
+		"registered", config.File, map[string]*fake_driver.FakeStoragePool{
+			"pool": &fake_driver.FakeStoragePool{
+				Attrs: map[string]sa.Offer{
+					sa.Media: sa.NewStringOffer("hdd"),
+				},
+				Bytes: 100 * 1024 * 1024 * 1024,
+			},
+		})
+	if err != nil {
+		t.Fatal("Unable to create fake config:  ", err)
+	}
+	var configMap map[string]interface{}
+	if err = json.Unmarshal([]byte(configJSON), &configMap); err != nil {
+		t.Fatal("Unable to parse fake config:  ", err)
+	}
+	configMap["storageDriverName"] = registeredDriverName
+	registeredJSON, err := json.Marshal(configMap)
+	if err != nil {
+		t.Fatal("Unable to marshal config:  ", err)
+	}
+	backend, err := NewStorageBackendForConfig(string(registeredJSON))
+	if err != nil {
+		t.Fatal("Unable to create backend for registered driver:  ", err)
+	}
+	if !f.validated {
+		t.Error("Registered driver wasn't validated.")
+	}
+	if _, ok := backend.Storage["pool"]; !ok {
+		t.Error("Registered driver's pool not found.")
+	}
+
+	configMap["storageDriverName"] = "unregistered-fake"
+	unregisteredJSON, err := json.Marshal(configMap)
+	if err != nil {
+		t.Fatal("Unable to marshal config:  ", err)
+	}
+	_, err = NewStorageBackendForConfig(string(unregisteredJSON))
+	if err == nil {
+		t.Error("Created backend for unknown driver.")
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package factory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// MergeBackendConfig applies an update to a backend's stored config as a
+// JSON merge patch (RFC 7386):  members of the update replace those of the
+// stored config, objects are merged member by member, members set to null
+// are removed, and members the update leaves out are kept.  References in
+// the update to environment variables and files are expanded first; see
+// expandTemplate.  The stored config isn't expanded again.
+func MergeBackendConfig(storedJSON, updateJSON string) (string, error) {
+	expanded, err := expandTemplate(updateJSON)
+	if err != nil {
+		return "", err
+	}
+	update, err := decodeConfigObject(expanded)
+	if err != nil {
+		return "", fmt.Errorf("Invalid config update:  %v", err)
+	}
+	stored, err := decodeConfigObject(storedJSON)
+	if err != nil {
+		return "", fmt.Errorf("Invalid stored config:  %v", err)
+	}
+	merged, err := json.Marshal(mergePatch(stored, update))
+	if err != nil {
+		return "", err
+	}
+	return string(merged), nil
+}
+
+// decodeConfigObject decodes a JSON object, keeping its numbers as they
+// were written, so that large integers survive being merged.
+func decodeConfigObject(configJSON string) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(configJSON)))
+	decoder.UseNumber()
+	var object map[string]interface{}
+	if err := decoder.Decode(&object); err != nil {
+		return nil, err
+	}
+	if object == nil {
+		return nil, fmt.Errorf("The config must be a JSON object.")
+	}
+	return object, nil
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{}, len(patchObject))
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = mergePatch(targetObject[name], value)
+	}
+	return targetObject
+}
@@ -0,0 +1,69 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package factory
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestMergeBackendConfig(t *testing.T) {
+	os.Setenv("TRIDENT_TEST_PASSWORD", "new")
+	defer os.Unsetenv("TRIDENT_TEST_PASSWORD")
+
+	const stored = `{"svm": "svm1", "password": "old", "limitVolumeSize": ` +
+		`"10Gi", "defaults": {"spaceReserve": "none", "snapshotReserve": ` +
+		`"10"}, "maxVolumes": 12345678901234567}`
+	for _, test := range []struct {
+		name     string
+		update   string
+		expected string
+	}{
+		{"Empty", `{}`, stored},
+		{"Replace", `{"password": "${TRIDENT_TEST_PASSWORD}"}`,
+			`{"svm": "svm1", "password": "new", "limitVolumeSize": "10Gi", ` +
+				`"defaults": {"spaceReserve": "none", "snapshotReserve": ` +
+				`"10"}, "maxVolumes": 12345678901234567}`},
+		{"Nested", `{"defaults": {"snapshotReserve": "20"}}`,
+			`{"svm": "svm1", "password": "old", "limitVolumeSize": "10Gi", ` +
+				`"defaults": {"spaceReserve": "none", "snapshotReserve": ` +
+				`"20"}, "maxVolumes": 12345678901234567}`},
+		{"Remove", `{"limitVolumeSize": null, "defaults": null}`,
+			`{"svm": "svm1", "password": "old", ` +
+				`"maxVolumes": 12345678901234567}`},
+	} {
+		merged, err := MergeBackendConfig(stored, test.update)
+		if err != nil {
+			t.Errorf("%s:  unable to merge config:  %v", test.name, err)
+			continue
+		}
+		var actual, expected interface{}
+		json.Unmarshal([]byte(merged), &actual)
+		json.Unmarshal([]byte(test.expected), &expected)
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%s:  expected %s; got %s.", test.name, test.expected,
+				merged)
+		}
+	}
+
+	// Large integers keep their digits.
+	merged, _ := MergeBackendConfig(stored, `{}`)
+	var object map[string]json.RawMessage
+	json.Unmarshal([]byte(merged), &object)
+	if string(object["maxVolumes"]) != "12345678901234567" {
+		t.Errorf("Integer changed to %s.", object["maxVolumes"])
+	}
+
+	for _, update := range []string{
+		`[]`,
+		`null`,
+		`{"svm": `,
+		`{"password": "${TRIDENT_TEST_UNSET}"}`,
+	} {
+		if _, err := MergeBackendConfig(stored, update); err == nil {
+			t.Errorf("Merged invalid update %s.", update)
+		}
+	}
+}
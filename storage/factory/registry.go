@@ -0,0 +1,80 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package factory
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	dvp "github.com/netapp/netappdvp/storage_drivers"
+
+	fake_driver "github.com/netapp/trident/drivers/fake"
+	"github.com/netapp/trident/storage"
+)
+
+// DriverFactory creates the drivers of one storage driver type.  Drivers
+// that aren't built into NewStorageBackendForConfig, e.g., for cloud storage
+// services, are added by registering a DriverFactory with RegisterDriver.
+//
+// Registered drivers parse and validate their own config in Initialize and
+// report their storage pools in GetStorageBackendSpecs, as the built-in
+// drivers do.  They store their config in the DriverConfig field of
+// storage.PersistentStorageBackendConfig.
+type DriverFactory interface {
+	// NewDriver returns an uninitialized driver.
+	NewDriver() storage.StorageDriver
+	// Validate checks an initialized driver before its backend is created,
+	// e.g., that the objects Trident needs exist on the storage system.
+	Validate(driver storage.StorageDriver) error
+}
+
+var (
+	driverMutex     sync.Mutex
+	driverFactories = make(map[string]DriverFactory)
+	builtInDrivers  = map[string]bool{
+		dvp.OntapNASStorageDriverName:     true,
+		dvp.OntapSANStorageDriverName:     true,
+		dvp.SolidfireSANStorageDriverName: true,
+		dvp.EseriesIscsiStorageDriverName: true,
+		fake_driver.FakeStorageDriverName: true,
+	}
+)
+
+// RegisterDriver makes backend configs whose storageDriverName is name use
+// drivers created by f.  Built-in drivers can't be replaced, and each name
+// can only be registered once.
+func RegisterDriver(name string, f DriverFactory) error {
+	if name == "" {
+		return fmt.Errorf("Storage driver names must not be empty.")
+	}
+	if builtInDrivers[name] {
+		return fmt.Errorf("Storage driver %s is built in.", name)
+	}
+	driverMutex.Lock()
+	defer driverMutex.Unlock()
+	if _, ok := driverFactories[name]; ok {
+		return fmt.Errorf("Storage driver %s is already registered.", name)
+	}
+	driverFactories[name] = f
+	return nil
+}
+
+// RegisteredDrivers returns the names of the registered drivers, sorted.
+func RegisteredDrivers() []string {
+	driverMutex.Lock()
+	defer driverMutex.Unlock()
+	names := make([]string, 0, len(driverFactories))
+	for name := range driverFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func getDriverFactory(name string) (DriverFactory, bool) {
+	driverMutex.Lock()
+	defer driverMutex.Unlock()
+	f, ok := driverFactories[name]
+	return f, ok
+}
@@ -0,0 +1,83 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package factory
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/netapp/trident/logging"
+)
+
+// fileReferencePrefix marks a placeholder naming a file, e.g., a mounted
+// secret, rather than an environment variable.
+const fileReferencePrefix = "file:"
+
+// placeholderRegex matches the placeholders in a backend config and the
+// escaped "$$" that stands for a literal "$".
+var placeholderRegex = regexp.MustCompile(`\$\$|\$\{([^}]*)\}`)
+
+// expandTemplate returns a backend config with each ${NAME} placeholder
+// replaced by the value of the environment variable NAME, and each
+// ${file:PATH} placeholder by the contents of the file at PATH, less any
+// trailing newline.  Values are escaped for JSON, so placeholders may stand
+// for all or part of a string.  Values read from files are treated as
+// secrets and kept out of the log.
+func expandTemplate(configJSON string) (string, error) {
+	var err error
+	expanded := placeholderRegex.ReplaceAllStringFunc(configJSON,
+		func(match string) string {
+			if err != nil {
+				return match
+			}
+			if match == "$$" {
+				return "$"
+			}
+			var value string
+			value, err = placeholderValue(match[2 : len(match)-1])
+			return escapeJSON(value)
+		})
+	if err != nil {
+		return "", err
+	}
+	return expanded, nil
+}
+
+// placeholderValue returns the value a placeholder, without its "${" and
+// "}", stands for.
+func placeholderValue(name string) (string, error) {
+	if strings.HasPrefix(name, fileReferencePrefix) {
+		path := strings.TrimPrefix(name, fileReferencePrefix)
+		if path == "" {
+			return "", fmt.Errorf("File reference ${%s} names no file.", name)
+		}
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("Unable to read file %s referenced by the "+
+				"backend config:  %v", path, err)
+		}
+		value := strings.TrimRight(string(contents), "\r\n")
+		logging.AddSecret(value)
+		return value, nil
+	}
+	if name == "" {
+		return "", fmt.Errorf("Backend config contains an empty " +
+			"placeholder ${}.")
+	}
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("Environment variable %s referenced by the "+
+			"backend config is not set.", name)
+	}
+	return value, nil
+}
+
+// escapeJSON returns s escaped for use within a JSON string.
+func escapeJSON(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted[1 : len(quoted)-1])
+}
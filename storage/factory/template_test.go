@@ -0,0 +1,63 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package factory
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	os.Setenv("TRIDENT_TEST_SVM", "svm1")
+	os.Setenv("TRIDENT_TEST_QUOTED", `a"b`)
+	defer os.Unsetenv("TRIDENT_TEST_SVM")
+	defer os.Unsetenv("TRIDENT_TEST_QUOTED")
+	os.Unsetenv("TRIDENT_TEST_UNSET")
+
+	file, err := ioutil.TempFile("", "password")
+	if err != nil {
+		t.Fatal("Unable to create password file:  ", err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("secret\n")
+	file.Close()
+
+	for _, test := range []struct {
+		name     string
+		config   string
+		expected string
+	}{
+		{"None", `{"svm": "svm1"}`, `{"svm": "svm1"}`},
+		{"Variable", `{"svm": "${TRIDENT_TEST_SVM}"}`, `{"svm": "svm1"}`},
+		{"Partial", `{"prefix": "${TRIDENT_TEST_SVM}_"}`,
+			`{"prefix": "svm1_"}`},
+		{"Escaped value", `{"name": "${TRIDENT_TEST_QUOTED}"}`,
+			`{"name": "a\"b"}`},
+		{"File", `{"password": "${file:` + file.Name() + `}"}`,
+			`{"password": "secret"}`},
+		{"Literal", `{"password": "$${TRIDENT_TEST_SVM}$"}`,
+			`{"password": "${TRIDENT_TEST_SVM}$"}`},
+	} {
+		expanded, err := expandTemplate(test.config)
+		if err != nil {
+			t.Errorf("%s:  unable to expand config:  %v", test.name, err)
+			continue
+		}
+		if expanded != test.expected {
+			t.Errorf("%s:  expected %s; got %s.", test.name, test.expected,
+				expanded)
+		}
+	}
+
+	for _, config := range []string{
+		`{"svm": "${TRIDENT_TEST_UNSET}"}`,
+		`{"password": "${file:/nonexistent/password}"}`,
+		`{"password": "${file:}"}`,
+		`{"svm": "${}"}`,
+	} {
+		if _, err := expandTemplate(config); err == nil {
+			t.Errorf("Expanded invalid config %s.", config)
+		}
+	}
+}
@@ -3,14 +3,26 @@
 package fake
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/netapp/trident/config"
 	"github.com/netapp/trident/drivers/fake"
+	"github.com/netapp/trident/errors"
 	"github.com/netapp/trident/storage"
 	sa "github.com/netapp/trident/storage_attribute"
 )
 
 type FakeStorageDriver struct {
 	fake.FakeStorageDriver
+	// VolumeAccess maps internal volume names to their access lists.
+	VolumeAccess map[string]storage.VolumeAccess
+	// Chap holds the CHAP credentials set on the backend, if any.
+	Chap *storage.ChapConfig
+	// ReadOnly holds the internal names of the read-only volumes.
+	ReadOnly map[string]bool
+	// CredentialCheckError, if set, is returned by CheckCredentials.
+	CredentialCheckError error
 }
 
 func (m *FakeStorageDriver) GetStorageBackendSpecs(
@@ -24,6 +36,7 @@ func (m *FakeStorageDriver) GetStorageBackendSpecs(
 			Volumes:        make(map[string]*storage.Volume, 0),
 			Backend:        backend,
 			Attributes:     pool.Attrs,
+			Protocol:       pool.Protocol,
 		}
 		vc.Attributes[sa.BackendType] = sa.NewStringOffer(m.Name())
 		backend.AddStoragePool(vc)
@@ -56,6 +69,232 @@ func (m *FakeStorageDriver) CreatePrepare(
 func (m *FakeStorageDriver) CreateFollowup(
 	volConfig *storage.VolumeConfig,
 ) error {
+	if m.volumeProtocol(volConfig) == config.Block &&
+		len(m.Config.Portals) > 0 {
+		volConfig.AccessInfo.IscsiTargetPortal = m.Config.Portals[0]
+	}
+	if volConfig.Access != nil {
+		return m.SetVolumeAccess(volConfig, volConfig.Access)
+	}
+	return nil
+}
+
+// volumeProtocol returns the protocol of the pool holding a volume.
+func (m *FakeStorageDriver) volumeProtocol(
+	volConfig *storage.VolumeConfig,
+) config.Protocol {
+	poolName, ok := m.VolumePool(volConfig.InternalName)
+	if !ok {
+		return m.Config.Protocol
+	}
+	if pool, ok := m.Config.Pools[poolName]; ok &&
+		pool.Protocol != config.ProtocolAny {
+		return pool.Protocol
+	}
+	return m.Config.Protocol
+}
+
+// CreateNVMeFollowup maps a volume to a subsystem named after the backend,
+// reachable through the backend's portals.
+func (m *FakeStorageDriver) CreateNVMeFollowup(
+	volConfig *storage.VolumeConfig,
+) error {
+	if _, ok := m.VolumePool(volConfig.InternalName); !ok {
+		return fmt.Errorf("Volume %s not found.", volConfig.InternalName)
+	}
+	volConfig.AccessInfo.NvmeSubsystemNQN = "nqn.1992-08.com.netapp:" +
+		m.Config.InstanceName
+	volConfig.AccessInfo.NvmeNamespaceUUID = volConfig.InternalName
+	volConfig.AccessInfo.NvmeTransport = storage.NVMeTransportTCP
+	volConfig.AccessInfo.NvmeTargetAddresses = m.Config.Portals
+	if volConfig.Access != nil {
+		return m.SetVolumeAccess(volConfig, volConfig.Access)
+	}
+	return nil
+}
+
+func (m *FakeStorageDriver) GetIscsiPortals() ([]string, error) {
+	return m.Config.Portals, nil
+}
+
+func (m *FakeStorageDriver) SetVolumeAccess(
+	volConfig *storage.VolumeConfig, access *storage.VolumeAccess,
+) error {
+	if _, ok := m.VolumePool(volConfig.InternalName); !ok {
+		return fmt.Errorf("Volume %s not found.", volConfig.InternalName)
+	}
+	if m.VolumeAccess == nil {
+		m.VolumeAccess = make(map[string]storage.VolumeAccess)
+	}
+	m.VolumeAccess[volConfig.InternalName] = *access
+	return nil
+}
+
+func (m *FakeStorageDriver) SetVolumeReadOnly(
+	volConfig *storage.VolumeConfig, readOnly bool,
+) error {
+	if _, ok := m.VolumePool(volConfig.InternalName); !ok {
+		return fmt.Errorf("Volume %s not found.", volConfig.InternalName)
+	}
+	if m.ReadOnly == nil {
+		m.ReadOnly = make(map[string]bool)
+	}
+	if readOnly {
+		m.ReadOnly[volConfig.InternalName] = true
+	} else {
+		delete(m.ReadOnly, volConfig.InternalName)
+	}
+	return nil
+}
+
+func (m *FakeStorageDriver) SetChap(chap *storage.ChapConfig) error {
+	chapCopy := *chap
+	m.Chap = &chapCopy
+	return nil
+}
+
+// GetPassword returns the password in the driver's config.
+func (m *FakeStorageDriver) GetPassword() string {
+	return m.Config.Password
+}
+
+// ChangePassword replaces the password in the driver's config.
+func (m *FakeStorageDriver) ChangePassword(password string) error {
+	m.Config.Password = password
+	return nil
+}
+
+// CheckCredentials returns CredentialCheckError.
+func (m *FakeStorageDriver) CheckCredentials() error {
+	return m.CredentialCheckError
+}
+
+// Destroy deletes a volume, failing with a NotFound error if it doesn't
+// exist.
+func (m *FakeStorageDriver) Destroy(name string) error {
+	return storage.DestroyVolume(&m.FakeStorageDriver, name)
+}
+
+// Rename moves a volume, with its snapshots, access list, mirror
+// relationship, and usage, to a new name.
+func (m *FakeStorageDriver) Rename(name, newName string) error {
+	pool, ok := m.Volumes[name]
+	if !ok {
+		return fmt.Errorf("Volume %s not found.", name)
+	}
+	if _, ok = m.Volumes[newName]; ok {
+		return fmt.Errorf("Volume %s already exists.", newName)
+	}
+	m.Volumes[newName] = pool
+	delete(m.Volumes, name)
+	if snapshots, ok := m.Snapshots[name]; ok {
+		m.Snapshots[newName] = snapshots
+		delete(m.Snapshots, name)
+	}
+	if access, ok := m.VolumeAccess[name]; ok {
+		m.VolumeAccess[newName] = access
+		delete(m.VolumeAccess, name)
+	}
+	if m.ReadOnly[name] {
+		m.ReadOnly[newName] = true
+		delete(m.ReadOnly, name)
+	}
+	if mirror, ok := m.Mirrors[name]; ok {
+		m.Mirrors[newName] = mirror
+		delete(m.Mirrors, name)
+	}
+	if used, ok := m.UsedBytes[name]; ok {
+		m.UsedBytes[newName] = used
+		delete(m.UsedBytes, name)
+	}
+	return nil
+}
+
+// GetUsedBytes returns the usage a test set for a volume.
+func (m *FakeStorageDriver) GetUsedBytes(volumeName string) (uint64, error) {
+	if _, ok := m.Volumes[volumeName]; !ok {
+		return 0, errors.Errorf(errors.NotFound,
+			"Volume %s not found.", volumeName)
+	}
+	return m.UsedBytes[volumeName], nil
+}
+
+// MirrorEndpoint names a volume by its instance and volume names.
+func (m *FakeStorageDriver) MirrorEndpoint(volumeName string) (string, error) {
+	if _, ok := m.Volumes[volumeName]; !ok {
+		return "", fmt.Errorf("Volume %s not found.", volumeName)
+	}
+	return m.Config.InstanceName + ":" + volumeName, nil
+}
+
+// CreateMirror creates a volume in the pool named in opts, with a
+// relationship whose baseline transfer completes immediately.
+func (m *FakeStorageDriver) CreateMirror(
+	volumeName string, sizeBytes uint64, opts map[string]string,
+	sourceEndpoint string,
+) error {
+	if err := m.Create(volumeName, sizeBytes, opts); err != nil {
+		return err
+	}
+	m.Mirrors[volumeName] = &fake.FakeMirror{
+		Source:       sourceEndpoint,
+		LastTransfer: time.Now().UTC(),
+	}
+	return nil
+}
+
+func (m *FakeStorageDriver) getMirror(
+	volumeName string,
+) (*fake.FakeMirror, error) {
+	mirror, ok := m.Mirrors[volumeName]
+	if !ok {
+		return nil, errors.Errorf(errors.NotFound,
+			"Volume %s is not a mirror.", volumeName)
+	}
+	return mirror, nil
+}
+
+func (m *FakeStorageDriver) GetMirrorStatus(
+	volumeName string,
+) (*storage.MirrorStatus, error) {
+	mirror, err := m.getMirror(volumeName)
+	if err != nil {
+		return nil, err
+	}
+	status := &storage.MirrorStatus{
+		State:        storage.MirrorMirrored,
+		Healthy:      mirror.Error == "",
+		LastTransfer: mirror.LastTransfer,
+		Message:      mirror.Error,
+	}
+	if mirror.Broken {
+		status.State = storage.MirrorBroken
+	}
+	return status, nil
+}
+
+func (m *FakeStorageDriver) UpdateMirror(volumeName string) error {
+	mirror, err := m.getMirror(volumeName)
+	if err != nil {
+		return err
+	}
+	if mirror.Broken {
+		return fmt.Errorf("Mirror %s is broken.", volumeName)
+	}
+	if mirror.Error != "" {
+		return fmt.Errorf("Unable to update mirror %s:  %s", volumeName,
+			mirror.Error)
+	}
+	mirror.LastTransfer = time.Now().UTC()
+	return nil
+}
+
+func (m *FakeStorageDriver) BreakMirror(volumeName string) error {
+	mirror, err := m.getMirror(volumeName)
+	if err != nil {
+		return err
+	}
+	mirror.Broken = true
 	return nil
 }
 
@@ -67,6 +306,12 @@ func (d *FakeStorageDriver) GetDriverName() string {
 	return d.Config.StorageDriverName
 }
 
+// DriverVersion returns Trident's version, since the fake driver is part of
+// Trident rather than netappdvp.
+func (d *FakeStorageDriver) DriverVersion() string {
+	return config.OrchestratorVersion
+}
+
 func (d *FakeStorageDriver) StoreConfig(
 	b *storage.PersistentStorageBackendConfig,
 ) {
@@ -0,0 +1,133 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/netapp/netappdvp/utils"
+)
+
+// BackendLimits are administrator-set bounds on how much of a backend
+// Trident may provision.  They are read from the backend config alongside
+// the driver settings.  Unset (zero) limits are not enforced.
+type BackendLimits struct {
+	// LimitVolumeSize is the largest volume that may be created.
+	LimitVolumeSize string `json:"limitVolumeSize,omitempty"`
+	// LimitAggregateUsage caps the combined size of all volumes on the
+	// backend.
+	LimitAggregateUsage string `json:"limitAggregateUsage,omitempty"`
+	// MaxVolumes caps the number of volumes on the backend.
+	MaxVolumes int `json:"maxVolumes,omitempty"`
+	// MaxSessions caps the number of concurrent operations on the
+	// backend's storage system; see SessionPool.
+	MaxSessions int `json:"maxSessions,omitempty"`
+}
+
+// ParseBackendLimits reads any limits set in a backend config.
+func ParseBackendLimits(configJSON string) (*BackendLimits, error) {
+	limits := &BackendLimits{}
+	if err := json.Unmarshal([]byte(configJSON), limits); err != nil {
+		return nil, fmt.Errorf("Unable to parse backend limits:  %v", err)
+	}
+	if err := limits.Validate(); err != nil {
+		return nil, err
+	}
+	return limits, nil
+}
+
+func (l *BackendLimits) Validate() error {
+	if l.MaxVolumes < 0 {
+		return fmt.Errorf("maxVolumes must not be negative.")
+	}
+	if l.MaxSessions < 0 {
+		return fmt.Errorf("maxSessions must not be negative.")
+	}
+	if _, err := limitInBytes(l.LimitVolumeSize); err != nil {
+		return fmt.Errorf("Invalid limitVolumeSize:  %v", err)
+	}
+	if _, err := limitInBytes(l.LimitAggregateUsage); err != nil {
+		return fmt.Errorf("Invalid limitAggregateUsage:  %v", err)
+	}
+	return nil
+}
+
+// IsSet returns true if any limit is set.
+func (l *BackendLimits) IsSet() bool {
+	return l.LimitVolumeSize != "" || l.LimitAggregateUsage != "" ||
+		l.MaxVolumes != 0 || l.MaxSessions != 0
+}
+
+func sizeInBytes(size string) (uint64, error) {
+	requestedSize, err := utils.ConvertSizeToBytes(size)
+	if err != nil {
+		return 0, fmt.Errorf("Could not convert size %s: %v", size, err)
+	}
+	bytes, err := strconv.ParseUint(requestedSize, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%v is an invalid size: %v", size, err)
+	}
+	return bytes, nil
+}
+
+func limitInBytes(limit string) (uint64, error) {
+	if limit == "" {
+		return 0, nil
+	}
+	return sizeInBytes(limit)
+}
+
+// CheckLimits returns an error if creating volConfig on the backend would
+// exceed any of its limits.
+func (b *StorageBackend) CheckLimits(volConfig *VolumeConfig) error {
+	if err := b.checkMaxVolumeSize(volConfig); err != nil {
+		return err
+	}
+	if !b.Limits.IsSet() {
+		return nil
+	}
+	volSize, err := sizeInBytes(volConfig.Size)
+	if err != nil {
+		return err
+	}
+	var (
+		volCount  int
+		usedBytes uint64
+	)
+	for _, pool := range b.Storage {
+		for _, vol := range pool.Volumes {
+			volCount++
+			size, err := sizeInBytes(vol.Config.Size)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"backend": b.Name,
+					"volume":  vol.Config.Name,
+					"size":    vol.Config.Size,
+				}).Warn("Unable to determine volume size; ignoring it for " +
+					"backend limits.")
+				continue
+			}
+			usedBytes += size
+		}
+	}
+	if b.Limits.MaxVolumes != 0 && volCount >= b.Limits.MaxVolumes {
+		return fmt.Errorf("Backend %s already has its maximum of %d volumes.",
+			b.Name, b.Limits.MaxVolumes)
+	}
+	// The limits were validated when the backend was created.
+	sizeLimit, _ := limitInBytes(b.Limits.LimitVolumeSize)
+	if sizeLimit != 0 && volSize > sizeLimit {
+		return fmt.Errorf("Volume size %s exceeds the limit of %s for "+
+			"backend %s.", volConfig.Size, b.Limits.LimitVolumeSize, b.Name)
+	}
+	usageLimit, _ := limitInBytes(b.Limits.LimitAggregateUsage)
+	if usageLimit != 0 && usedBytes+volSize > usageLimit {
+		return fmt.Errorf("Volume size %s would exceed the usage limit of "+
+			"%s for backend %s.", volConfig.Size,
+			b.Limits.LimitAggregateUsage, b.Name)
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	dvp "github.com/netapp/netappdvp/storage_drivers"
+)
+
+// Placeholders in volume name templates.
+const (
+	// NamePrefix is the backend's storage prefix, or "trident".
+	NamePrefix = "{prefix}"
+	// NameVolume is the volume's Trident name.
+	NameVolume = "{name}"
+	// NameNamespace is the namespace of the claim that requested the volume,
+	// or "" if the volume wasn't requested by a claim.
+	NameNamespace = "{namespace}"
+)
+
+var namePlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// volumeNameRules are a storage system's rules for volume names.
+type volumeNameRules struct {
+	// maxLength is the longest name allowed, or 0 if any length is.
+	maxLength int
+	// invalid matches the characters names can't contain, which are
+	// replaced with replacement.
+	invalid     *regexp.Regexp
+	replacement string
+}
+
+// driverVolumeNameRules holds the naming rules of the built-in drivers.
+// Names on other storage systems are unrestricted, beyond what their
+// drivers' own checks enforce.
+var driverVolumeNameRules = map[string]*volumeNameRules{
+	dvp.OntapNASStorageDriverName: {
+		maxLength:   203,
+		invalid:     regexp.MustCompile(`[^A-Za-z0-9_]`),
+		replacement: "_",
+	},
+	dvp.OntapSANStorageDriverName: {
+		maxLength:   203,
+		invalid:     regexp.MustCompile(`[^A-Za-z0-9_]`),
+		replacement: "_",
+	},
+	dvp.SolidfireSANStorageDriverName: {
+		maxLength:   64,
+		invalid:     regexp.MustCompile(`[^A-Za-z0-9-]`),
+		replacement: "-",
+	},
+}
+
+// Volume name truncation strategies, for names longer than the storage
+// system allows.
+const (
+	// TruncateFail fails to create the volume on the backend.
+	TruncateFail = "fail"
+	// TruncateHash shortens the name, replacing its end with a hash of the
+	// whole name, so that distinct names stay distinct.
+	TruncateHash = "hash"
+)
+
+// nameHashLength is the number of hex digits of the hash that ends a
+// truncated name.
+const nameHashLength = 8
+
+// NamingConfig sets how the names of a backend's volumes on its storage
+// system are derived from their Trident names.  It is read from the backend
+// config alongside the driver settings.
+type NamingConfig struct {
+	// VolumeNameTemplate, e.g., "{prefix}_{namespace}_{name}", replaces the
+	// driver's naming convention.
+	VolumeNameTemplate string `json:"volumeNameTemplate,omitempty"`
+	// VolumeNameTruncation is how names longer than the storage system
+	// allows are handled:  TruncateFail (the default) or TruncateHash.
+	VolumeNameTruncation string `json:"volumeNameTruncation,omitempty"`
+	// Prefix is the backend's storage prefix, which replaces NamePrefix.
+	// It is set from the driver config rather than read.
+	Prefix string `json:"-"`
+	rules  *volumeNameRules
+}
+
+// ParseNamingConfig reads any naming settings in a backend config.
+func ParseNamingConfig(configJSON string) (*NamingConfig, error) {
+	naming := &NamingConfig{}
+	if err := json.Unmarshal([]byte(configJSON), naming); err != nil {
+		return nil, fmt.Errorf("Unable to parse naming settings:  %v", err)
+	}
+	return naming, nil
+}
+
+// Validate checks the settings against the naming rules of the backend's
+// driver, and applies the rules to the names of the backend's volumes.
+func (n *NamingConfig) Validate(driverName string) error {
+	n.rules = driverVolumeNameRules[driverName]
+	switch n.VolumeNameTruncation {
+	case "", TruncateFail, TruncateHash:
+	default:
+		return fmt.Errorf("Invalid volumeNameTruncation %q; must be %s or "+
+			"%s.", n.VolumeNameTruncation, TruncateFail, TruncateHash)
+	}
+	if n.VolumeNameTemplate == "" {
+		return nil
+	}
+	if driverName == dvp.EseriesIscsiStorageDriverName {
+		return fmt.Errorf("E-Series backends don't support " +
+			"volumeNameTemplate; their volume names are generated.")
+	}
+	if !strings.Contains(n.VolumeNameTemplate, NameVolume) {
+		return fmt.Errorf("volumeNameTemplate must contain %s, so that "+
+			"volume names are unique.", NameVolume)
+	}
+	for _, placeholder := range namePlaceholder.FindAllString(
+		n.VolumeNameTemplate, -1) {
+		switch placeholder {
+		case NamePrefix, NameVolume, NameNamespace:
+		default:
+			return fmt.Errorf("Unknown placeholder %s in volumeNameTemplate; "+
+				"must be %s, %s, or %s.", placeholder, NamePrefix,
+				NameVolume, NameNamespace)
+		}
+	}
+	// The placeholders may be empty, except for the volume's name.  Names
+	// that are too long anyway can only be made to fit by a hash.
+	fixed := n.expand(&VolumeConfig{Name: "v"})
+	if n.tooLong(fixed) && n.VolumeNameTruncation != TruncateHash {
+		return fmt.Errorf("volumeNameTemplate makes names longer than the "+
+			"%d characters %s allows.", n.rules.maxLength, driverName)
+	}
+	return nil
+}
+
+// IsSet returns true if any naming setting is set.
+func (n *NamingConfig) IsSet() bool {
+	return n.VolumeNameTemplate != "" || n.VolumeNameTruncation != ""
+}
+
+func (n *NamingConfig) expand(volConfig *VolumeConfig) string {
+	return strings.NewReplacer(
+		NamePrefix, n.Prefix,
+		NameVolume, volConfig.Name,
+		NameNamespace, volConfig.Namespace,
+	).Replace(n.VolumeNameTemplate)
+}
+
+func (n *NamingConfig) tooLong(name string) bool {
+	return n.rules != nil && n.rules.maxLength != 0 &&
+		len(name) > n.rules.maxLength
+}
+
+// truncate shortens name to the storage system's maximum length, ending it
+// with a hash of the whole name.
+func (n *NamingConfig) truncate(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	hash := hex.EncodeToString(sum[:])[:nameHashLength]
+	keep := n.rules.maxLength - len(n.rules.replacement) - nameHashLength
+	return name[:keep] + n.rules.replacement + hash
+}
+
+// InternalName returns the name a volume gets on the storage system, given
+// the name the driver would give it:  the template's name, if there is a
+// template, with the characters the storage system doesn't allow replaced.
+// A name that is too long is truncated, if VolumeNameTruncation allows;
+// untruncated is then the name before truncation, and otherwise "".
+func (n *NamingConfig) InternalName(
+	volConfig *VolumeConfig, driverName string,
+) (name, untruncated string, err error) {
+	name = driverName
+	if n.VolumeNameTemplate != "" {
+		name = n.expand(volConfig)
+	}
+	if n.rules == nil {
+		return name, "", nil
+	}
+	name = n.rules.invalid.ReplaceAllString(name, n.rules.replacement)
+	if !n.tooLong(name) {
+		return name, "", nil
+	}
+	if n.VolumeNameTruncation != TruncateHash {
+		return "", "", fmt.Errorf("Internal name %s of volume %s is longer "+
+			"than the %d characters the backend allows.", name,
+			volConfig.Name, n.rules.maxLength)
+	}
+	return n.truncate(name), name, nil
+}
+
+// InternalVolumeName returns the name a volume has, or would have, on the
+// backend's storage system.
+func (b *StorageBackend) InternalVolumeName(
+	volConfig *VolumeConfig,
+) (string, error) {
+	name, _, err := b.Naming.InternalName(volConfig,
+		b.Driver.GetInternalVolumeName(volConfig.Name))
+	return name, err
+}
+
+// applyNaming checks the internal name the driver gave a volume against
+// the storage system's naming rules, and replaces it with the one the
+// backend's volume name template makes, if it has one.  If the name is
+// truncated, the name before truncation is recorded with the volume.
+func (b *StorageBackend) applyNaming(volConfig *VolumeConfig) error {
+	name, untruncated, err := b.Naming.InternalName(volConfig,
+		volConfig.InternalName)
+	if err != nil {
+		return err
+	}
+	if untruncated != "" {
+		log.WithFields(log.Fields{
+			"backend":      b.Name,
+			"volume":       volConfig.Name,
+			"internalName": name,
+			"untruncated":  untruncated,
+		}).Info("Truncated the volume's internal name.")
+	}
+	volConfig.InternalName = name
+	volConfig.UntruncatedInternalName = untruncated
+	return nil
+}
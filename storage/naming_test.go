@@ -0,0 +1,106 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package storage
+
+import (
+	"strings"
+	"testing"
+
+	dvp "github.com/netapp/netappdvp/storage_drivers"
+)
+
+func TestNamingConfigValidate(t *testing.T) {
+	for template, driver := range map[string]string{
+		"{prefix}_{namespace}":             dvp.OntapNASStorageDriverName,
+		"{prefix}_{name}_{uid}":            dvp.OntapNASStorageDriverName,
+		"{name}":                           dvp.EseriesIscsiStorageDriverName,
+		strings.Repeat("x", 64) + "{name}": dvp.SolidfireSANStorageDriverName,
+	} {
+		naming := &NamingConfig{VolumeNameTemplate: template}
+		if err := naming.Validate(driver); err == nil {
+			t.Errorf("Template %s was accepted for %s.", template, driver)
+		}
+	}
+	naming := &NamingConfig{VolumeNameTemplate: "{prefix}_{name}"}
+	if err := naming.Validate(dvp.OntapSANStorageDriverName); err != nil {
+		t.Error("Valid template was rejected:  ", err)
+	}
+}
+
+func TestNamingConfigInternalName(t *testing.T) {
+	volConfig := &VolumeConfig{Name: "db-data", Namespace: "team.a"}
+	for driver, expected := range map[string]string{
+		dvp.OntapNASStorageDriverName:     "k8s_team_a_db_data",
+		dvp.SolidfireSANStorageDriverName: "k8s-team-a-db-data",
+		"fake":                            "k8s_team.a_db-data",
+	} {
+		naming := &NamingConfig{
+			VolumeNameTemplate: "{prefix}_{namespace}_{name}",
+			Prefix:             "k8s",
+		}
+		if err := naming.Validate(driver); err != nil {
+			t.Fatalf("Unable to validate template for %s:  %v", driver, err)
+		}
+		name, _, err := naming.InternalName(volConfig, "")
+		if err != nil || name != expected {
+			t.Errorf("Expected %s for %s; got %s, %v.", expected, driver,
+				name, err)
+		}
+	}
+
+	naming := &NamingConfig{VolumeNameTemplate: "{name}"}
+	naming.Validate(dvp.SolidfireSANStorageDriverName)
+	if _, _, err := naming.InternalName(&VolumeConfig{
+		Name: strings.Repeat("v", 65),
+	}, ""); err == nil {
+		t.Error("Name longer than the backend allows was accepted.")
+	}
+}
+
+func TestNamingConfigTruncation(t *testing.T) {
+	naming := &NamingConfig{VolumeNameTruncation: "shorten"}
+	if err := naming.Validate(dvp.OntapNASStorageDriverName); err == nil {
+		t.Error("Unknown truncation strategy was accepted.")
+	}
+
+	// Driver-generated names are checked, too.
+	naming = &NamingConfig{}
+	naming.Validate(dvp.SolidfireSANStorageDriverName)
+	long := strings.Repeat("v", 70)
+	if _, _, err := naming.InternalName(&VolumeConfig{}, long); err == nil {
+		t.Error("Driver name longer than the backend allows was accepted.")
+	}
+
+	naming = &NamingConfig{
+		VolumeNameTemplate:   strings.Repeat("x", 64) + "{name}",
+		VolumeNameTruncation: TruncateHash,
+	}
+	if err := naming.Validate(dvp.SolidfireSANStorageDriverName); err != nil {
+		t.Fatal("Long template was rejected despite truncation:  ", err)
+	}
+	names := make(map[string]bool)
+	for _, volName := range []string{"a", "b"} {
+		name, untruncated, err := naming.InternalName(
+			&VolumeConfig{Name: volName}, "")
+		if err != nil {
+			t.Fatalf("Unable to truncate name of %s:  %v", volName, err)
+		}
+		if len(name) != 64 {
+			t.Errorf("Truncated name %s has length %d.", name, len(name))
+		}
+		if untruncated != strings.Repeat("x", 64)+volName {
+			t.Errorf("Wrong untruncated name %s for %s.", untruncated,
+				volName)
+		}
+		names[name] = true
+	}
+	if len(names) != 2 {
+		t.Error("Distinct names were truncated to the same name.")
+	}
+
+	first, _, _ := naming.InternalName(&VolumeConfig{Name: "a"}, "")
+	second, _, _ := naming.InternalName(&VolumeConfig{Name: "a"}, "")
+	if first != second {
+		t.Error("Truncation isn't deterministic.")
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package storage
+
+import (
+	"fmt"
+
+	sa "github.com/netapp/trident/storage_attribute"
+)
+
+// NVMe over Fabrics transports.
+const (
+	NVMeTransportTCP  = "tcp"
+	NVMeTransportFC   = "fc"
+	NVMeTransportRDMA = "rdma"
+)
+
+// NVMeDriver is implemented by block drivers that can serve volumes as NVMe
+// namespaces, from pools that offer the nvme attribute.  Volumes in those
+// pools are created like any other block volume, but are mapped to an NVMe
+// subsystem rather than to an iSCSI target.
+type NVMeDriver interface {
+	// CreateNVMeFollowup adds a new volume's namespace to the subsystem
+	// that hosts connect to, and records its NvmeAccessInfo.  It replaces
+	// CreateFollowup for volumes in NVMe pools.
+	CreateNVMeFollowup(volConfig *VolumeConfig) error
+}
+
+// IsNVMe returns true if the pool serves its volumes as NVMe namespaces.
+func (vc *StoragePool) IsNVMe() bool {
+	offer, ok := vc.Attributes[sa.NVMe]
+	return ok && offer.Matches(sa.NewBoolRequest(true))
+}
+
+// ValidateNVMe checks that the backend's driver can serve the NVMe pools it
+// reports.
+func (b *StorageBackend) ValidateNVMe() error {
+	if _, ok := b.Driver.(NVMeDriver); ok {
+		return nil
+	}
+	for _, pool := range b.Storage {
+		if pool.IsNVMe() {
+			return fmt.Errorf("Storage pool %s of backend %s offers NVMe, "+
+				"but its driver doesn't support NVMe.", pool.Name, b.Name)
+		}
+	}
+	return nil
+}
@@ -40,6 +40,11 @@ func getCommonONTAPStoragePoolAttributes(pool *storage.StoragePool) {
 	pool.Attributes[sa.Snapshots] = sa.NewBoolOffer(true)
 	// ONTAP volumes support both thick and thin provisioning.
 	pool.Attributes[sa.ProvisioningType] = sa.NewStringOffer("thick", "thin")
+	// ONTAP volumes may be encrypted with NetApp Volume Encryption.
+	pool.Attributes[sa.Encryption] = sa.NewBoolOffer(true)
+	// Storage efficiency may be enabled or disabled on each volume.
+	pool.Attributes[sa.Deduplication] = sa.NewBoolOffer(true)
+	pool.Attributes[sa.Compression] = sa.NewBoolOffer(true)
 }
 
 // getStorageBackendSpecsCommon discovers the aggregates assigned to the configured SVM, and it updates the specified StorageBackend
@@ -217,21 +222,13 @@ func getVolumeOptsCommon(
 ) map[string]string {
 	opts := make(map[string]string)
 	opts["aggregate"] = pool.Name
-	if provisioningTypeReq, ok := requests[sa.ProvisioningType]; ok {
+	// The orchestrator sets the provisioning type from the storage class;
+	// the request is only consulted for volumes created without it.
+	provisioningType := volConfig.ProvisioningType
+	if provisioningTypeReq, ok := requests[sa.ProvisioningType]; ok &&
+		provisioningType == "" {
 		if p, ok := provisioningTypeReq.Value().(string); ok {
-			if p == "thin" {
-				opts["spaceReserve"] = "none"
-			} else if p == "thick" {
-				// p will equal "thick" here
-				opts["spaceReserve"] = "volume"
-			} else {
-				log.WithFields(log.Fields{
-					"provisioner":      "ONTAP",
-					"method":           "getVolumeOptsCommon",
-					"provisioningType": provisioningTypeReq.Value(),
-				}).Warnf("Expected 'thick' or 'thin' for %s; ignoring.",
-					sa.ProvisioningType)
-			}
+			provisioningType = p
 		} else {
 			log.WithFields(log.Fields{
 				"provisioner":      "ONTAP",
@@ -240,6 +237,26 @@ func getVolumeOptsCommon(
 			}).Warnf("Expected string for %s; ignoring.", sa.ProvisioningType)
 		}
 	}
+	switch provisioningType {
+	case "":
+	case sa.Thin:
+		opts["spaceReserve"] = "none"
+	case sa.Thick:
+		opts["spaceReserve"] = "volume"
+	default:
+		log.WithFields(log.Fields{
+			"provisioner":      "ONTAP",
+			"method":           "getVolumeOptsCommon",
+			"provisioningType": provisioningType,
+		}).Warnf("Expected 'thick' or 'thin' for %s; ignoring.",
+			sa.ProvisioningType)
+	}
+	if volConfig.Deduplication != "" {
+		opts["deduplication"] = volConfig.Deduplication
+	}
+	if volConfig.Compression != "" {
+		opts["compression"] = volConfig.Compression
+	}
 	if volConfig.SnapshotPolicy != "" {
 		opts["snapshotPolicy"] = volConfig.SnapshotPolicy
 	}
@@ -249,9 +266,15 @@ func getVolumeOptsCommon(
 	if volConfig.SnapshotDir != "" {
 		opts["snapshotDir"] = volConfig.SnapshotDir
 	}
+	if volConfig.SnapshotReserve != "" {
+		opts["snapshotReserve"] = volConfig.SnapshotReserve
+	}
 	if volConfig.ExportPolicy != "" {
 		opts["exportPolicy"] = volConfig.ExportPolicy
 	}
+	if volConfig.Encryption {
+		opts["encryption"] = "true"
+	}
 	return opts
 }
 
@@ -288,3 +311,46 @@ func getExternalConfig(config dvp.OntapStorageDriverConfig) interface{} {
 		SVM:           config.SVM,
 	}
 }
+
+func createSnapshotCommon(
+	d dvp.OntapStorageDriver, volumeName, snapshotName string,
+) error {
+	response, err := d.GetAPI().SnapshotCreate(snapshotName, volumeName)
+	if err != nil {
+		return fmt.Errorf("Unable to create snapshot %s of volume %s:  %v",
+			snapshotName, volumeName, err)
+	}
+	if response.Result.ResultStatusAttr != "passed" {
+		return fmt.Errorf("Unable to create snapshot %s of volume %s:  %v",
+			snapshotName, volumeName, response.Result.ResultReasonAttr)
+	}
+	return nil
+}
+
+func deleteSnapshotCommon(
+	d dvp.OntapStorageDriver, volumeName, snapshotName string,
+) error {
+	response, err := d.GetAPI().SnapshotDelete(snapshotName, volumeName)
+	if err != nil {
+		return fmt.Errorf("Unable to delete snapshot %s of volume %s:  %v",
+			snapshotName, volumeName, err)
+	}
+	if response.Result.ResultStatusAttr != "passed" {
+		return fmt.Errorf("Unable to delete snapshot %s of volume %s:  %v",
+			snapshotName, volumeName, response.Result.ResultReasonAttr)
+	}
+	return nil
+}
+
+func renameVolumeCommon(d dvp.OntapStorageDriver, name, newName string) error {
+	response, err := d.GetAPI().VolumeRename(name, newName)
+	if err != nil {
+		return fmt.Errorf("Unable to rename volume %s to %s:  %v", name,
+			newName, err)
+	}
+	if response.Result.ResultStatusAttr != "passed" {
+		return fmt.Errorf("Unable to rename volume %s to %s:  %v", name,
+			newName, response.Result.ResultReasonAttr)
+	}
+	return nil
+}
@@ -76,3 +76,21 @@ func (d *OntapNASStorageDriver) StoreConfig(
 func (d *OntapNASStorageDriver) GetExternalConfig() interface{} {
 	return getExternalConfig(d.Config)
 }
+
+// Destroy deletes a volume, failing with a NotFound error if it doesn't
+// exist.
+func (d *OntapNASStorageDriver) Destroy(name string) error {
+	return storage.DestroyVolume(&d.OntapNASStorageDriver, name)
+}
+
+func (d *OntapNASStorageDriver) CreateSnapshot(volumeName, snapshotName string) error {
+	return createSnapshotCommon(d, volumeName, snapshotName)
+}
+
+func (d *OntapNASStorageDriver) DeleteSnapshot(volumeName, snapshotName string) error {
+	return deleteSnapshotCommon(d, volumeName, snapshotName)
+}
+
+func (d *OntapNASStorageDriver) Rename(name, newName string) error {
+	return renameVolumeCommon(d, name, newName)
+}
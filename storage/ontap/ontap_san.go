@@ -68,6 +68,17 @@ func (d *OntapSANStorageDriver) mapOntapSANLun(volConfig *storage.VolumeConfig)
 		}
 	)
 
+	// Volumes created with an access list get their own igroup; others
+	// are mapped to the backend's igroup.
+	igroupName := d.Config.IgroupName
+	if volConfig.Access != nil && len(volConfig.Access.IscsiInitiators) > 0 {
+		igroupName = volConfig.InternalName
+		if err := d.createVolumeIgroup(igroupName,
+			volConfig.Access.IscsiInitiators); err != nil {
+			return err
+		}
+	}
+
 	response, err := d.API.IscsiServiceGetIterRequest()
 	if response.Result.ResultStatusAttr != "passed" || err != nil {
 		return fmt.Errorf("Problem retrieving iSCSI services: %v, %v",
@@ -91,7 +102,7 @@ func (d *OntapSANStorageDriver) mapOntapSANLun(volConfig *storage.VolumeConfig)
 	// (The signature for netappdvp/apis/ontap/ontap.go:LunMap() needs to change.)
 	lunPath := fmt.Sprintf("/vol/%v/lun0", volConfig.InternalName)
 	for i := 0; i < 4096; i++ {
-		response, err := d.API.LunMap(d.Config.IgroupName, lunPath, i)
+		response, err := d.API.LunMap(igroupName, lunPath, i)
 		if err != nil {
 			return fmt.Errorf("Problem mapping lun: %v error: %v,%v",
 				lunPath, err, response.Result.ResultErrnoAttr)
@@ -115,7 +126,7 @@ func (d *OntapSANStorageDriver) mapOntapSANLun(volConfig *storage.VolumeConfig)
 	volConfig.AccessInfo.IscsiTargetPortal = d.Config.DataLIF
 	volConfig.AccessInfo.IscsiTargetIQN = targetIQN
 	volConfig.AccessInfo.IscsiLunNumber = lunID
-	volConfig.AccessInfo.IscsiIgroup = d.Config.IgroupName
+	volConfig.AccessInfo.IscsiIgroup = igroupName
 	log.WithFields(log.Fields{
 		"volume":          volConfig.Name,
 		"volume_internal": volConfig.InternalName,
@@ -127,6 +138,107 @@ func (d *OntapSANStorageDriver) mapOntapSANLun(volConfig *storage.VolumeConfig)
 	return nil
 }
 
+// GetIscsiPortals returns the SVM's iSCSI data LIFs.
+func (d *OntapSANStorageDriver) GetIscsiPortals() ([]string, error) {
+	dataLIFs, err := d.API.NetInterfaceGetDataLIFs("iscsi")
+	if err != nil {
+		return nil, fmt.Errorf("Problem retrieving iSCSI data LIFs: %v", err)
+	}
+	return dataLIFs, nil
+}
+
+func (d *OntapSANStorageDriver) createVolumeIgroup(
+	igroupName string, initiators []string,
+) error {
+	response, err := d.API.IgroupCreate(igroupName, "iscsi", "linux")
+	if response.Result.ResultStatusAttr != "passed" &&
+		response.Result.ResultErrnoAttr != azgo.EVDISK_ERROR_INITGROUP_EXISTS {
+		return fmt.Errorf("Problem creating igroup %v: %v, %v", igroupName,
+			err, response.Result.ResultErrnoAttr)
+	}
+	for _, initiator := range initiators {
+		if err = d.addIgroupInitiator(igroupName, initiator); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *OntapSANStorageDriver) addIgroupInitiator(
+	igroupName, initiator string,
+) error {
+	response, err := d.API.IgroupAdd(igroupName, initiator)
+	if response.Result.ResultStatusAttr != "passed" &&
+		response.Result.ResultErrnoAttr != azgo.EVDISK_ERROR_INITGROUP_HAS_NODE {
+		return fmt.Errorf("Problem adding initiator %v to igroup %v: %v, %v",
+			initiator, igroupName, err, response.Result.ResultErrnoAttr)
+	}
+	return nil
+}
+
+// SetVolumeAccess replaces the initiators in a volume's igroup.  Only
+// volumes created with an access list have an igroup of their own.
+func (d *OntapSANStorageDriver) SetVolumeAccess(
+	volConfig *storage.VolumeConfig, access *storage.VolumeAccess,
+) error {
+	if len(access.NfsClients) > 0 {
+		return fmt.Errorf("ONTAP SAN volumes don't support NFS clients.")
+	}
+	igroupName := volConfig.AccessInfo.IscsiIgroup
+	if igroupName != volConfig.InternalName {
+		return fmt.Errorf("Volume %s is mapped to the shared igroup %s; "+
+			"access can only be managed for volumes created with an "+
+			"access list.", volConfig.Name, igroupName)
+	}
+	current := make(map[string]bool)
+	if volConfig.Access != nil {
+		for _, initiator := range volConfig.Access.IscsiInitiators {
+			current[initiator] = true
+		}
+	}
+	for _, initiator := range access.IscsiInitiators {
+		if current[initiator] {
+			delete(current, initiator)
+			continue
+		}
+		if err := d.addIgroupInitiator(igroupName, initiator); err != nil {
+			return err
+		}
+	}
+	for initiator := range current {
+		response, err := d.API.IgroupRemove(igroupName, initiator, false)
+		if response.Result.ResultStatusAttr != "passed" {
+			return fmt.Errorf("Problem removing initiator %v from igroup "+
+				"%v: %v, %v", initiator, igroupName, err,
+				response.Result.ResultErrnoAttr)
+		}
+	}
+	log.WithFields(log.Fields{
+		"volume":     volConfig.Name,
+		"igroup":     igroupName,
+		"initiators": access.IscsiInitiators,
+	}).Debug("Updated ONTAP igroup.")
+	return nil
+}
+
+// Destroy deletes a volume along with its igroup, if it has one.
+func (d *OntapSANStorageDriver) Destroy(name string) error {
+	if err := storage.DestroyVolume(&d.OntapSANStorageDriver, name); err != nil {
+		return err
+	}
+	// Volumes without an access list have no igroup of their own, so a
+	// failure here is expected for most volumes.
+	response, err := d.API.IgroupDestroy(name)
+	if response.Result.ResultStatusAttr != "passed" {
+		log.WithFields(log.Fields{
+			"volume": name,
+			"error":  err,
+			"errno":  response.Result.ResultErrnoAttr,
+		}).Debug("No igroup deleted for volume.")
+	}
+	return nil
+}
+
 func (d *OntapSANStorageDriver) GetProtocol() config.Protocol {
 	return config.Block
 }
@@ -165,3 +277,15 @@ func DiscoverIscsiTarget(targetIP string) error {
 	}
 	return nil
 }
+
+func (d *OntapSANStorageDriver) CreateSnapshot(volumeName, snapshotName string) error {
+	return createSnapshotCommon(d, volumeName, snapshotName)
+}
+
+func (d *OntapSANStorageDriver) DeleteSnapshot(volumeName, snapshotName string) error {
+	return deleteSnapshotCommon(d, volumeName, snapshotName)
+}
+
+func (d *OntapSANStorageDriver) Rename(name, newName string) error {
+	return renameVolumeCommon(d, name, newName)
+}
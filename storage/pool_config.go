@@ -0,0 +1,110 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// PoolConfig restricts which of a backend's storage pools Trident uses and
+// labels them for selection by storage classes.  Like BackendLimits, it is
+// read from the backend config alongside the driver settings.
+type PoolConfig struct {
+	// IncludePools, if set, lists the only pools Trident may use.
+	IncludePools []string `json:"includePools,omitempty"`
+	// ExcludePools lists pools Trident must not use.
+	ExcludePools []string `json:"excludePools,omitempty"`
+	// Labels are applied to every pool on the backend.
+	Labels map[string]string `json:"labels,omitempty"`
+	// PoolLabels are applied to individual pools, keyed by pool name, and
+	// take precedence over Labels.
+	PoolLabels map[string]map[string]string `json:"poolLabels,omitempty"`
+	// VirtualPools, if set, replace the backend's pools, keyed by name.
+	VirtualPools map[string]*VirtualPool `json:"virtualPools,omitempty"`
+}
+
+// ParsePoolConfig reads any pool settings in a backend config.
+func ParsePoolConfig(configJSON string) (*PoolConfig, error) {
+	poolConfig := &PoolConfig{}
+	if err := json.Unmarshal([]byte(configJSON), poolConfig); err != nil {
+		return nil, fmt.Errorf("Unable to parse backend pool settings:  %v",
+			err)
+	}
+	return poolConfig, nil
+}
+
+// IsSet returns true if any pool setting is set.
+func (c *PoolConfig) IsSet() bool {
+	return len(c.IncludePools) > 0 || len(c.ExcludePools) > 0 ||
+		len(c.Labels) > 0 || len(c.PoolLabels) > 0 || len(c.VirtualPools) > 0
+}
+
+// ApplyPoolConfig removes the backend's excluded pools and labels the rest,
+// then replaces them with any virtual pools.  It fails if a named pool
+// doesn't exist or if no pools remain.
+func (b *StorageBackend) ApplyPoolConfig(c *PoolConfig) error {
+	unknown := make([]string, 0)
+	for _, list := range [][]string{c.IncludePools, c.ExcludePools} {
+		for _, name := range list {
+			if _, ok := b.Storage[name]; !ok {
+				unknown = append(unknown, name)
+			}
+		}
+	}
+	for name := range c.PoolLabels {
+		if _, ok := b.Storage[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("Backend %s has no storage pool(s) named %s.",
+			b.Name, strings.Join(unknown, ", "))
+	}
+
+	if len(c.IncludePools) > 0 {
+		included := make(map[string]bool, len(c.IncludePools))
+		for _, name := range c.IncludePools {
+			included[name] = true
+		}
+		for name := range b.Storage {
+			if !included[name] {
+				delete(b.Storage, name)
+			}
+		}
+	}
+	for _, name := range c.ExcludePools {
+		delete(b.Storage, name)
+	}
+	if len(b.Storage) == 0 {
+		return fmt.Errorf("All storage pools of backend %s are excluded.",
+			b.Name)
+	}
+
+	for name, pool := range b.Storage {
+		pool.Labels = make(map[string]string)
+		for k, v := range c.Labels {
+			pool.Labels[k] = v
+		}
+		for k, v := range c.PoolLabels[name] {
+			pool.Labels[k] = v
+		}
+		log.WithFields(log.Fields{
+			"backend": b.Name,
+			"pool":    name,
+			"labels":  pool.Labels,
+		}).Debug("Applied backend pool settings.")
+	}
+	if len(c.VirtualPools) > 0 {
+		if err := b.applyVirtualPools(c.VirtualPools); err != nil {
+			return err
+		}
+	}
+	b.Pools = *c
+	return nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/netapp/trident/config"
+)
+
+// PortalDriver is implemented by block drivers that can enumerate the iSCSI
+// target portals, e.g., the data LIFs, through which their volumes can be
+// reached.  Hosts log in to each portal to build multipath devices.
+type PortalDriver interface {
+	GetIscsiPortals() ([]string, error)
+}
+
+// PortalConfig restricts which of a backend's iSCSI portals are reported
+// for its volumes.  It is read from the backend config alongside the driver
+// settings.
+type PortalConfig struct {
+	// ReportedPortals are the addresses of the portals to report; if empty,
+	// all of them are reported.
+	ReportedPortals []string `json:"reportedPortals,omitempty"`
+}
+
+// ParsePortalConfig reads any portal settings in a backend config.
+func ParsePortalConfig(configJSON string) (*PortalConfig, error) {
+	portals := &PortalConfig{}
+	if err := json.Unmarshal([]byte(configJSON), portals); err != nil {
+		return nil, fmt.Errorf("Unable to parse portal settings:  %v", err)
+	}
+	if err := portals.Validate(); err != nil {
+		return nil, err
+	}
+	return portals, nil
+}
+
+func (p *PortalConfig) Validate() error {
+	for _, portal := range p.ReportedPortals {
+		if strings.TrimSpace(portal) == "" {
+			return fmt.Errorf("reportedPortals must not contain empty " +
+				"addresses.")
+		}
+	}
+	return nil
+}
+
+// IsSet returns true if the reported portals are restricted.
+func (p *PortalConfig) IsSet() bool {
+	return len(p.ReportedPortals) > 0
+}
+
+// portalHost returns a portal's address without its port, if any.
+func portalHost(portal string) string {
+	if host, _, err := net.SplitHostPort(portal); err == nil {
+		return host
+	}
+	return portal
+}
+
+// Allows returns true if portal may be reported.  Ports are ignored.
+func (p *PortalConfig) Allows(portal string) bool {
+	if !p.IsSet() {
+		return true
+	}
+	for _, allowed := range p.ReportedPortals {
+		if portalHost(allowed) == portalHost(portal) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetIscsiPortals returns the backend's iSCSI target portals, less any that
+// its portal config excludes.  Backends whose drivers can't enumerate their
+// portals return none.
+func (b *StorageBackend) GetIscsiPortals() ([]string, error) {
+	if !b.SupportsProtocol(config.Block) {
+		return nil, fmt.Errorf("Backend %s doesn't provide %s volumes.",
+			b.Name, config.Block)
+	}
+	portalDriver, ok := b.Driver.(PortalDriver)
+	if !ok {
+		return []string{}, nil
+	}
+	portals, err := portalDriver.GetIscsiPortals()
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]string, 0, len(portals))
+	for _, portal := range portals {
+		if b.Portals.Allows(portal) {
+			ret = append(ret, portal)
+		}
+	}
+	if len(ret) == 0 && len(portals) > 0 {
+		return nil, fmt.Errorf("None of backend %s's portals %v are in "+
+			"reportedPortals.", b.Name, portals)
+	}
+	return ret, nil
+}
+
+// setIscsiPortals records every portal through which a new block volume in
+// pool can be reached, so that hosts can configure multipathing.  The
+// volume's target portal is listed first, unless the backend's portal config
+// excludes it.  Failing to enumerate the portals leaves just the target
+// portal.
+func (b *StorageBackend) setIscsiPortals(
+	volConfig *VolumeConfig, pool *StoragePool,
+) {
+	if pool.GetProtocol() != config.Block || pool.IsNVMe() {
+		return
+	}
+	portals, err := b.GetIscsiPortals()
+	if err != nil {
+		log.WithFields(log.Fields{
+			"backend": b.Name,
+			"volume":  volConfig.Name,
+			"error":   err,
+		}).Warn("Unable to list the backend's iSCSI portals.")
+		return
+	}
+	if len(portals) == 0 {
+		return
+	}
+	accessInfo := &volConfig.AccessInfo
+	ordered := make([]string, 0, len(portals))
+	for _, portal := range portals {
+		if portalHost(portal) == portalHost(accessInfo.IscsiTargetPortal) {
+			ordered = append([]string{portal}, ordered...)
+		} else {
+			ordered = append(ordered, portal)
+		}
+	}
+	accessInfo.IscsiTargetPortal = ordered[0]
+	accessInfo.IscsiPortals = ordered
+}
@@ -0,0 +1,216 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/netapp/netappdvp/utils"
+
+	"github.com/netapp/trident/errors"
+)
+
+type MirrorState string
+
+const (
+	// MirrorInitializing is the state of a mirror whose baseline transfer
+	// hasn't finished.
+	MirrorInitializing MirrorState = "initializing"
+	// MirrorMirrored is the state of a mirror that is being updated from
+	// its source.
+	MirrorMirrored MirrorState = "mirrored"
+	// MirrorBroken is the state of a mirror that is no longer updated, and
+	// whose destination is writable.
+	MirrorBroken MirrorState = "broken"
+)
+
+// MirrorStatus reports a mirror relationship as seen from its destination.
+type MirrorStatus struct {
+	State   MirrorState `json:"state"`
+	Healthy bool        `json:"healthy"`
+	// LastTransfer is when the destination was last updated from its
+	// source.
+	LastTransfer time.Time `json:"lastTransfer"`
+	// Message explains why the relationship is unhealthy, if it is.
+	Message string `json:"message,omitempty"`
+}
+
+// ReplicationDriver is implemented by drivers that can mirror volumes from
+// another storage system, e.g., with SnapMirror.  Relationships are managed
+// from their destinations.  Methods given a volume that isn't a mirror
+// return a NotFound error from the errors package.
+type ReplicationDriver interface {
+	// MirrorEndpoint returns the name by which a peer's driver refers to
+	// one of this driver's volumes as a mirror source, e.g., "svm:volume".
+	MirrorEndpoint(volumeName string) (string, error)
+	// CreateMirror creates volumeName as a mirror of sourceEndpoint and
+	// starts its baseline transfer.
+	CreateMirror(
+		volumeName string, sizeBytes uint64, opts map[string]string,
+		sourceEndpoint string,
+	) error
+	GetMirrorStatus(volumeName string) (*MirrorStatus, error)
+	// UpdateMirror transfers the source's latest changes to the
+	// destination, returning once the transfer is complete.
+	UpdateMirror(volumeName string) error
+	// BreakMirror stops updating the destination and makes it writable.
+	// Breaking a broken mirror succeeds.
+	BreakMirror(volumeName string) error
+}
+
+// VolumeReplication records a volume's mirror on a peer backend, and the
+// relationship's status when it was last checked.
+type VolumeReplication struct {
+	PeerBackend      string       `json:"peerBackend"`
+	PeerPool         string       `json:"peerPool"`
+	PeerInternalName string       `json:"peerInternalName"`
+	CreatedAt        time.Time    `json:"createdAt"`
+	Status           MirrorStatus `json:"status"`
+	LastChecked      time.Time    `json:"lastChecked"`
+	// LagSeconds is how far the mirror was behind its source when it was
+	// last checked.
+	LagSeconds int64 `json:"lagSeconds"`
+}
+
+// SetStatus records a relationship's status, as observed at now.
+func (r *VolumeReplication) SetStatus(status *MirrorStatus, now time.Time) {
+	r.Status = *status
+	r.LastChecked = now
+	r.LagSeconds = 0
+	if !status.LastTransfer.IsZero() && now.After(status.LastTransfer) {
+		r.LagSeconds = int64(now.Sub(status.LastTransfer) / time.Second)
+	}
+}
+
+// SupportsReplication returns true if the backend can mirror volumes to
+// and from peers that also support it.
+func (b *StorageBackend) SupportsReplication() bool {
+	_, ok := b.Driver.(ReplicationDriver)
+	return ok
+}
+
+func (b *StorageBackend) getReplicationDriver() (ReplicationDriver, error) {
+	replicationDriver, ok := b.Driver.(ReplicationDriver)
+	if !ok {
+		return nil, fmt.Errorf("Backend %s does not support replication.",
+			b.Name)
+	}
+	return replicationDriver, nil
+}
+
+// CreateMirror creates a mirror of source, which is on a peer backend, in
+// this backend's pool.
+func (b *StorageBackend) CreateMirror(
+	source *Volume, pool *StoragePool,
+) (*VolumeReplication, error) {
+	replicationDriver, err := b.getReplicationDriver()
+	if err != nil {
+		return nil, err
+	}
+	sourceDriver, err := source.Backend.getReplicationDriver()
+	if err != nil {
+		return nil, err
+	}
+	sourceEndpoint, err := sourceDriver.MirrorEndpoint(
+		source.Config.InternalName)
+	if err != nil {
+		return nil, err
+	}
+	size, err := utils.ConvertSizeToBytes(source.Config.Size)
+	if err != nil {
+		return nil, fmt.Errorf("Could not convert volume size %s: %v",
+			source.Config.Size, err)
+	}
+	sizeBytes, err := strconv.ParseUint(size, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%v is an invalid volume size: %v",
+			source.Config.Size, err)
+	}
+	// The destination is named as the volume would be on this backend.
+	destConfig := *source.Config
+	destConfig.InternalName = b.Driver.GetInternalVolumeName(
+		source.Config.Name)
+	opts, err := b.Driver.GetVolumeOpts(&destConfig, pool.physical(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = replicationDriver.CreateMirror(destConfig.InternalName,
+		sizeBytes, opts, sourceEndpoint); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	replication := &VolumeReplication{
+		PeerBackend:      b.Name,
+		PeerPool:         pool.Name,
+		PeerInternalName: destConfig.InternalName,
+		CreatedAt:        now,
+	}
+	replication.SetStatus(&MirrorStatus{State: MirrorInitializing}, now)
+	return replication, nil
+}
+
+// GetMirrorStatus returns the status of the relationship of one of this
+// backend's mirrors.
+func (b *StorageBackend) GetMirrorStatus(
+	internalName string,
+) (*MirrorStatus, error) {
+	replicationDriver, err := b.getReplicationDriver()
+	if err != nil {
+		return nil, err
+	}
+	return replicationDriver.GetMirrorStatus(internalName)
+}
+
+// UpdateMirror brings one of this backend's mirrors up to date with its
+// source.
+func (b *StorageBackend) UpdateMirror(internalName string) error {
+	replicationDriver, err := b.getReplicationDriver()
+	if err != nil {
+		return err
+	}
+	return replicationDriver.UpdateMirror(internalName)
+}
+
+// RemoveMirror breaks one of this backend's mirrors and destroys it.
+func (b *StorageBackend) RemoveMirror(internalName string) error {
+	replicationDriver, err := b.getReplicationDriver()
+	if err != nil {
+		return err
+	}
+	// A mirror that is already gone may have been removed before.
+	err = replicationDriver.BreakMirror(internalName)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	err = b.Driver.Destroy(internalName)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// PromoteMirror breaks vol's mirror, on this backend, and returns the
+// config of the volume that the mirror becomes, with its access information.
+// vol is left as it was; the caller moves it to the mirror.
+func (b *StorageBackend) PromoteMirror(
+	vol *Volume, pool *StoragePool,
+) (*VolumeConfig, error) {
+	replicationDriver, err := b.getReplicationDriver()
+	if err != nil {
+		return nil, err
+	}
+	internalName := vol.Replication.PeerInternalName
+	if err = replicationDriver.BreakMirror(internalName); err != nil {
+		return nil, err
+	}
+	promoted := *vol.Config
+	promoted.InternalName = internalName
+	promoted.AccessInfo = VolumeAccessInfo{}
+	if err = b.createFollowup(&promoted, pool); err != nil {
+		return nil, err
+	}
+	b.setIscsiPortals(&promoted, pool)
+	return &promoted, nil
+}
@@ -0,0 +1,182 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/netapp/trident/credentials"
+)
+
+const (
+	// RotationGenerate rotates to passwords Trident generates.
+	RotationGenerate = "generate"
+	// RotationVault rotates to the password held in a Vault secret, under
+	// the key "password".
+	RotationVault = "vault"
+
+	// MinRotationInterval is the shortest interval between rotations.
+	MinRotationInterval = time.Hour
+
+	rotationPasswordLength = 24
+	rotationVaultKey       = "password"
+)
+
+// RotationConfig schedules the rotation of the password a backend's driver
+// uses to log in to its storage system.  It is read from the backend
+// config's credentialRotation setting, and stored, encrypted if a key is
+// set, with the backend along with the state of its rotations.
+type RotationConfig struct {
+	// Interval is the time between rotations, e.g., "720h".
+	Interval string `json:"interval,omitempty"`
+	// Source is where new passwords come from:  RotationGenerate, the
+	// default, or RotationVault.
+	Source string `json:"source,omitempty"`
+	// VaultPath is the Vault secret holding the new password, for
+	// RotationVault.
+	VaultPath string `json:"vaultPath,omitempty"`
+	// LastRotated is when the password was last rotated or, if it hasn't
+	// been, when rotation was set up.  It is set by Trident.
+	LastRotated time.Time `json:"lastRotated,omitempty"`
+	// PendingPassword is the new password while a rotation is in progress.
+	// It is kept if the rotation can neither be finished nor undone, so
+	// that it isn't lost.
+	PendingPassword string `json:"pendingPassword,omitempty"`
+}
+
+// CredentialDriver is implemented by drivers that can change the password
+// of the storage system account they log in as.
+type CredentialDriver interface {
+	// GetPassword returns the password the driver logs in with.
+	GetPassword() string
+	// ChangePassword changes the account's password on the storage system
+	// and logs in with it from then on.
+	ChangePassword(password string) error
+	// CheckCredentials logs in to the storage system with the driver's
+	// current credentials.
+	CheckCredentials() error
+}
+
+// ParseRotationConfig reads any credential rotation settings in a backend
+// config.
+func ParseRotationConfig(configJSON string) (*RotationConfig, error) {
+	var backendConfig struct {
+		Rotation *RotationConfig `json:"credentialRotation"`
+	}
+	if err := json.Unmarshal([]byte(configJSON), &backendConfig); err != nil {
+		return nil, fmt.Errorf("Unable to parse credential rotation "+
+			"settings:  %v", err)
+	}
+	rotation := backendConfig.Rotation
+	if rotation == nil {
+		return &RotationConfig{}, nil
+	}
+	if err := rotation.Validate(); err != nil {
+		return nil, err
+	}
+	return rotation, nil
+}
+
+func (r *RotationConfig) Validate() error {
+	if !r.IsSet() {
+		if r.Source != "" || r.VaultPath != "" {
+			return fmt.Errorf("credentialRotation must set an interval.")
+		}
+		return nil
+	}
+	interval, err := time.ParseDuration(r.Interval)
+	if err != nil {
+		return fmt.Errorf("Invalid credentialRotation interval %q:  %v",
+			r.Interval, err)
+	}
+	if interval < MinRotationInterval {
+		return fmt.Errorf("credentialRotation interval must be at least %v.",
+			MinRotationInterval)
+	}
+	switch r.Source {
+	case "", RotationGenerate:
+		if r.VaultPath != "" {
+			return fmt.Errorf("credentialRotation vaultPath only applies " +
+				"to the vault source.")
+		}
+	case RotationVault:
+		if r.VaultPath == "" {
+			return fmt.Errorf("credentialRotation from Vault must set " +
+				"vaultPath.")
+		}
+	default:
+		return fmt.Errorf("Invalid credentialRotation source %q; must be %s "+
+			"or %s.", r.Source, RotationGenerate, RotationVault)
+	}
+	return nil
+}
+
+// IsSet returns true if credential rotation is enabled.
+func (r *RotationConfig) IsSet() bool {
+	return r.Interval != ""
+}
+
+// Due returns true if the password is due to be rotated at t.
+func (r *RotationConfig) Due(t time.Time) bool {
+	// The interval was validated when the config was parsed.
+	interval, err := time.ParseDuration(r.Interval)
+	if err != nil {
+		return false
+	}
+	return !t.Before(r.LastRotated.Add(interval))
+}
+
+// NewPassword generates or requests the password to rotate to.
+func (r *RotationConfig) NewPassword() (string, error) {
+	if r.Source != RotationVault {
+		password, err := generateSecret(rotationPasswordLength)
+		if err != nil {
+			return "", fmt.Errorf("Unable to generate password:  %v", err)
+		}
+		return password, nil
+	}
+	values, err := credentials.Lookup(credentials.Vault, r.VaultPath)
+	if err != nil {
+		return "", fmt.Errorf("Unable to read password from Vault secret "+
+			"%s:  %v", r.VaultPath, err)
+	}
+	password := values[rotationVaultKey]
+	if password == "" {
+		return "", fmt.Errorf("Vault secret %s has no %s.", r.VaultPath,
+			rotationVaultKey)
+	}
+	return password, nil
+}
+
+// Redacted returns a copy of the settings with any pending password
+// redacted, for reporting through the API.
+func (r *RotationConfig) Redacted() *RotationConfig {
+	return credentials.Sanitize(r).(*RotationConfig)
+}
+
+// Inherit keeps the time of other's last rotation, e.g., that of the
+// backend being updated, so that updating a backend doesn't restart its
+// rotation interval.  Any pending password is dropped, as the update
+// supplies the password the backend logs in with.
+func (r *RotationConfig) Inherit(other *RotationConfig) {
+	if other.IsSet() && r.LastRotated.IsZero() {
+		r.LastRotated = other.LastRotated
+	}
+}
+
+// CheckRotation returns an error if the backend's password can't be
+// rotated:  if its driver can't change it, or if it's read from a secret,
+// which should be rotated instead.
+func (b *StorageBackend) CheckRotation() error {
+	if _, ok := b.Driver.(CredentialDriver); !ok {
+		return fmt.Errorf("The %s driver doesn't support credential "+
+			"rotation.", b.GetDriverName())
+	}
+	if b.Credentials != nil {
+		return fmt.Errorf("Credentials read from a secret can't be rotated " +
+			"by Trident; rotate the secret instead.")
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package storage
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+)
+
+// SessionPool bounds the number of concurrent operations on a backend's
+// storage system.  Each driver operation holds an API session on the
+// storage system while it runs, so a high provisioning rate, or operations
+// abandoned after timing out that are still running, could otherwise
+// exhaust the sessions it allows.  A nil pool is unbounded.
+type SessionPool struct {
+	tokens chan struct{}
+}
+
+// NewSessionPool returns a pool that allows size concurrent operations, or
+// nil, an unbounded pool, if size is zero.
+func NewSessionPool(size int) *SessionPool {
+	if size <= 0 {
+		return nil
+	}
+	return &SessionPool{tokens: make(chan struct{}, size)}
+}
+
+// Acquire waits until the pool has a free session and takes it, or returns
+// an error if ctx is done first.  Since a wait without a context couldn't be
+// ended, a nil ctx is an error, even for an unbounded pool.
+func (p *SessionPool) Acquire(ctx context.Context) error {
+	if ctx == nil {
+		return fmt.Errorf("A session can't be acquired without a context.")
+	}
+	if p == nil {
+		return ctx.Err()
+	}
+	select {
+	case p.tokens <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("Timed out waiting for a session:  %v", ctx.Err())
+	}
+}
+
+// Release returns a session taken by Acquire.
+func (p *SessionPool) Release() {
+	if p != nil {
+		<-p.tokens
+	}
+}
+
+// Size returns the number of sessions the pool allows, or zero if it is
+// unbounded.
+func (p *SessionPool) Size() int {
+	if p == nil {
+		return 0
+	}
+	return cap(p.tokens)
+}
+
+// InUse returns the number of sessions taken.
+func (p *SessionPool) InUse() int {
+	if p == nil {
+		return 0
+	}
+	return len(p.tokens)
+}
+
+// lockDriver takes a session and then waits for the backend's other driver
+// operations to finish, or returns an error if ctx is done first.  The
+// drivers aren't safe for concurrent use, but an operation the orchestrator
+// abandons after timing out keeps running without the orchestrator's lock,
+// so later operations, including undoing the abandoned one, must wait for
+// it here.
+func (b *StorageBackend) lockDriver(ctx context.Context) error {
+	if err := b.Sessions.Acquire(ctx); err != nil {
+		return err
+	}
+	b.driverOnce.Do(func() {
+		b.driverLock = make(chan struct{}, 1)
+	})
+	select {
+	case b.driverLock <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		b.Sessions.Release()
+		return fmt.Errorf("Timed out waiting for backend %s's other "+
+			"operations:  %v", b.Name, ctx.Err())
+	}
+}
+
+// unlockDriver ends an operation started with lockDriver.
+func (b *StorageBackend) unlockDriver() {
+	<-b.driverLock
+	b.Sessions.Release()
+}
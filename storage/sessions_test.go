@@ -0,0 +1,107 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestSessionPool(t *testing.T) {
+	ctx := context.Background()
+	var unbounded *SessionPool
+	if err := unbounded.Acquire(ctx); err != nil {
+		t.Error("Unable to acquire an unbounded session:  ", err)
+	}
+	unbounded.Release()
+	if NewSessionPool(0) != nil || unbounded.Size() != 0 {
+		t.Error("Expected an unbounded pool for a size of zero.")
+	}
+
+	pool := NewSessionPool(2)
+	pool.Acquire(ctx)
+	pool.Acquire(ctx)
+	acquired := make(chan error)
+	go func() {
+		acquired <- pool.Acquire(ctx)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("Acquired more sessions than the pool allows.")
+	case <-time.After(50 * time.Millisecond):
+	}
+	pool.Release()
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatal("Unable to acquire a released session:  ", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Released session wasn't reused.")
+	}
+	if pool.Size() != 2 || pool.InUse() != 2 {
+		t.Errorf("Expected 2 of 2 sessions in use; got %d of %d.",
+			pool.InUse(), pool.Size())
+	}
+
+	// Waits end with their context.
+	timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := pool.Acquire(timeoutCtx); err == nil {
+		t.Error("Acquired a session after the context was done.")
+	}
+	if pool.InUse() != 2 {
+		t.Errorf("Expected 2 sessions in use; got %d.", pool.InUse())
+	}
+	if err := pool.Acquire(nil); err == nil {
+		t.Error("Acquired a session without a context.")
+	}
+	if err := unbounded.Acquire(nil); err == nil {
+		t.Error("Acquired an unbounded session without a context.")
+	}
+}
+
+func TestLockDriver(t *testing.T) {
+	ctx := context.Background()
+	b := &StorageBackend{Name: "backend", Sessions: NewSessionPool(3)}
+	if err := b.lockDriver(ctx); err != nil {
+		t.Fatal("Unable to lock the driver:  ", err)
+	}
+
+	// A second operation waits for the first, holding its session.
+	locked := make(chan error)
+	go func() {
+		locked <- b.lockDriver(ctx)
+	}()
+	select {
+	case <-locked:
+		t.Fatal("Locked the driver twice.")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// A third gives up when its context is done, releasing its session.
+	timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := b.lockDriver(timeoutCtx); err == nil {
+		t.Fatal("Locked the driver after the context was done.")
+	}
+	if b.Sessions.InUse() != 2 {
+		t.Errorf("Expected 2 sessions in use; got %d.", b.Sessions.InUse())
+	}
+
+	b.unlockDriver()
+	select {
+	case err := <-locked:
+		if err != nil {
+			t.Fatal("Unable to lock the driver:  ", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Unlocked driver wasn't locked by the waiting operation.")
+	}
+	b.unlockDriver()
+	if b.Sessions.InUse() != 0 {
+		t.Errorf("Expected no sessions in use; got %d.", b.Sessions.InUse())
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package sidecar
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/netapp/trident/errors"
+)
+
+// sidecarHost is the host in the URLs of sidecar requests.  Requests are
+// always sent over the sidecar's Unix socket, so it's only informative.
+const sidecarHost = "sidecar"
+
+// maxIdleConns is how many connections to a sidecar are kept open between
+// requests, so that concurrent operations reuse them rather than opening a
+// connection, and perhaps a storage system session, for each request.
+const maxIdleConns = 16
+
+// errorResponse is the body of a failed sidecar request.  NotFound is set
+// if the request named a volume that doesn't exist.
+type errorResponse struct {
+	Error    string `json:"error"`
+	NotFound bool   `json:"notFound,omitempty"`
+}
+
+// client calls a sidecar's operations:  each is an HTTP POST to
+// /v1/<operation> over the sidecar's Unix socket, with JSON request and
+// response bodies.
+type client struct {
+	socket string
+	http   *http.Client
+}
+
+func newClient(socket string, timeout time.Duration) *client {
+	return &client{
+		socket: socket,
+		http: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Dial: func(network, addr string) (net.Conn, error) {
+					return net.DialTimeout("unix", socket, timeout)
+				},
+				MaxIdleConnsPerHost: maxIdleConns,
+			},
+		},
+	}
+}
+
+// call runs a sidecar operation, decoding its result into response unless
+// response is nil.
+func (c *client) call(
+	operation string, request, response interface{},
+) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("Unable to marshal %s request:  %v", operation,
+			err)
+	}
+	log.WithFields(log.Fields{
+		"socket":    c.socket,
+		"operation": operation,
+	}).Debug("Calling sidecar.")
+
+	url := fmt.Sprintf("http://%s/v1/%s", sidecarHost, operation)
+	resp, err := c.http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("Unable to reach sidecar at %s:  %v", c.socket,
+			err)
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("Unable to read %s response:  %v", operation, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		errResp := &errorResponse{}
+		if json.Unmarshal(respBody, errResp) != nil || errResp.Error == "" {
+			errResp.Error = resp.Status
+		}
+		if errResp.NotFound {
+			return errors.Errorf(errors.NotFound, "Sidecar %s failed:  %s",
+				operation, errResp.Error)
+		}
+		return fmt.Errorf("Sidecar %s failed:  %s", operation, errResp.Error)
+	}
+	if response == nil {
+		return nil
+	}
+	if err = json.Unmarshal(respBody, response); err != nil {
+		return fmt.Errorf("Unable to parse %s response:  %v", operation, err)
+	}
+	return nil
+}
@@ -0,0 +1,496 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+// Package sidecar provides the external storage driver, which proxies
+// volume operations to a sidecar process supplied by a storage vendor, so
+// that arrays without a built-in driver can be used with Trident.  It
+// registers itself with the storage factory.
+package sidecar
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	dvp "github.com/netapp/netappdvp/storage_drivers"
+
+	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/credentials"
+	"github.com/netapp/trident/storage"
+	"github.com/netapp/trident/storage/factory"
+	sa "github.com/netapp/trident/storage_attribute"
+)
+
+const (
+	ExternalStorageDriverName = "external"
+	// PoolOpt is the volume option naming the pool to create a volume in.
+	PoolOpt = "pool"
+	// defaultTimeout bounds each sidecar operation, unless the backend
+	// config sets a timeout.
+	defaultTimeout = 2 * time.Minute
+)
+
+func init() {
+	if err := factory.RegisterDriver(ExternalStorageDriverName,
+		&driverFactory{}); err != nil {
+		log.Fatalf("Unable to register the %s driver:  %v",
+			ExternalStorageDriverName, err)
+	}
+}
+
+type driverFactory struct{}
+
+func (f *driverFactory) NewDriver() storage.StorageDriver {
+	return &ExternalStorageDriver{}
+}
+
+func (f *driverFactory) Validate(driver storage.StorageDriver) error {
+	return driver.Validate()
+}
+
+// ExternalStorageDriverConfig configures the external driver.  Username,
+// Password, and Settings are passed to the sidecar when the driver is
+// initialized; Settings holds whatever else the sidecar needs, e.g., the
+// array's address.
+type ExternalStorageDriverConfig struct {
+	dvp.CommonStorageDriverConfig
+	// Socket is the path of the Unix socket on which the sidecar listens.
+	Socket string `json:"socket"`
+	// Timeout bounds each sidecar operation, in seconds.
+	Timeout  int               `json:"timeout,omitempty"`
+	Username string            `json:"username,omitempty"`
+	Password string            `json:"password,omitempty"`
+	Settings map[string]string `json:"settings,omitempty"`
+}
+
+// ExternalStorageDriver proxies volume operations to a sidecar.
+type ExternalStorageDriver struct {
+	Config ExternalStorageDriverConfig
+	// arrayName, protocol, version, and pools are reported by the sidecar.
+	arrayName string
+	protocol  config.Protocol
+	version   string
+	pools     []sidecarPool
+	client    *client
+}
+
+type activateRequest struct {
+	Username string            `json:"username,omitempty"`
+	Password string            `json:"password,omitempty"`
+	Settings map[string]string `json:"settings,omitempty"`
+}
+
+type activateResponse struct {
+	// Name identifies the array; the backend is named after it.
+	Name     string          `json:"name"`
+	Protocol config.Protocol `json:"protocol"`
+	// Version, if set, is the sidecar's version.
+	Version string `json:"version,omitempty"`
+}
+
+// sidecarPool is a storage pool reported by a sidecar.  Its attributes are
+// storage attribute offers in the form used for storage class attributes,
+// e.g., "ssd" or "1000-5000"; Protocol, if set, overrides the array's.
+type sidecarPool struct {
+	Name       string            `json:"name"`
+	Protocol   config.Protocol   `json:"protocol,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+type getPoolsResponse struct {
+	Pools []sidecarPool `json:"pools"`
+}
+
+type volumeRequest struct {
+	Name string `json:"name"`
+}
+
+type createRequest struct {
+	Name      string            `json:"name"`
+	SizeBytes uint64            `json:"sizeBytes"`
+	Opts      map[string]string `json:"opts,omitempty"`
+}
+
+type cloneRequest struct {
+	Name     string `json:"name"`
+	Source   string `json:"source"`
+	Snapshot string `json:"snapshot,omitempty"`
+}
+
+type attachRequest struct {
+	Name       string            `json:"name"`
+	Mountpoint string            `json:"mountpoint"`
+	Opts       map[string]string `json:"opts,omitempty"`
+}
+
+type listRequest struct {
+	Prefix string `json:"prefix"`
+}
+
+type listResponse struct {
+	Volumes []string `json:"volumes"`
+}
+
+type groupSnapshotRequest struct {
+	Names    []string `json:"names"`
+	Snapshot string   `json:"snapshot"`
+}
+
+type usedBytesResponse struct {
+	UsedBytes uint64 `json:"usedBytes"`
+}
+
+type mirrorEndpointResponse struct {
+	Endpoint string `json:"endpoint"`
+}
+
+type createMirrorRequest struct {
+	Name      string            `json:"name"`
+	SizeBytes uint64            `json:"sizeBytes"`
+	Opts      map[string]string `json:"opts,omitempty"`
+	Source    string            `json:"source"`
+}
+
+type snapshotListResponse struct {
+	Snapshots []dvp.CommonSnapshot `json:"snapshots"`
+}
+
+func (d *ExternalStorageDriver) Name() string {
+	return ExternalStorageDriverName
+}
+
+// Initialize connects to the sidecar, passing it the array's settings, and
+// reads the array's storage pools.
+func (d *ExternalStorageDriver) Initialize(configJSON string) error {
+	if err := json.Unmarshal([]byte(configJSON), &d.Config); err != nil {
+		return fmt.Errorf("Unable to initialize external driver:  %v", err)
+	}
+	if d.Config.Socket == "" {
+		return fmt.Errorf("The external driver requires the sidecar's " +
+			"socket.")
+	}
+	if d.Config.Timeout < 0 {
+		return fmt.Errorf("Sidecar timeout must not be negative.")
+	}
+	timeout := defaultTimeout
+	if d.Config.Timeout > 0 {
+		timeout = time.Duration(d.Config.Timeout) * time.Second
+	}
+	d.client = newClient(d.Config.Socket, timeout)
+
+	activated := &activateResponse{}
+	if err := d.client.call("Activate", &activateRequest{
+		Username: d.Config.Username,
+		Password: d.Config.Password,
+		Settings: d.Config.Settings,
+	}, activated); err != nil {
+		return err
+	}
+	if activated.Name == "" {
+		return fmt.Errorf("Sidecar at %s didn't name its array.",
+			d.Config.Socket)
+	}
+	d.arrayName = activated.Name
+	d.protocol = activated.Protocol
+	d.version = activated.Version
+
+	pools := &getPoolsResponse{}
+	if err := d.client.call("GetPools", struct{}{}, pools); err != nil {
+		return err
+	}
+	d.pools = pools.Pools
+	log.WithFields(log.Fields{
+		"socket":   d.Config.Socket,
+		"array":    d.arrayName,
+		"protocol": d.protocol,
+		"version":  d.version,
+		"pools":    len(d.pools),
+	}).Debug("Initialized external driver.")
+	return nil
+}
+
+// Validate checks what the sidecar reported:  the array must provide file
+// or block volumes, and have at least one pool.
+func (d *ExternalStorageDriver) Validate() error {
+	if d.protocol != config.File && d.protocol != config.Block {
+		return fmt.Errorf("Sidecar reported unsupported protocol %q; must "+
+			"be %s or %s.", d.protocol, config.File, config.Block)
+	}
+	if len(d.pools) == 0 {
+		return fmt.Errorf("Sidecar reported no storage pools.")
+	}
+	names := make(map[string]bool, len(d.pools))
+	for _, pool := range d.pools {
+		if pool.Name == "" {
+			return fmt.Errorf("Sidecar reported a storage pool without a " +
+				"name.")
+		}
+		if names[pool.Name] {
+			return fmt.Errorf("Sidecar reported storage pool %s twice.",
+				pool.Name)
+		}
+		names[pool.Name] = true
+		if pool.Protocol != config.ProtocolAny &&
+			pool.Protocol != config.File && pool.Protocol != config.Block {
+			return fmt.Errorf("Sidecar reported unsupported protocol %q "+
+				"for storage pool %s.", pool.Protocol, pool.Name)
+		}
+		for attr, value := range pool.Attributes {
+			if _, err := sa.CreateAttributeOfferFromTypedValue(attr,
+				value); err != nil {
+				return fmt.Errorf("Invalid attribute %s of storage pool "+
+					"%s:  %v", attr, pool.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (d *ExternalStorageDriver) Create(
+	name string, sizeBytes uint64, opts map[string]string,
+) error {
+	return d.client.call("Create", &createRequest{
+		Name:      name,
+		SizeBytes: sizeBytes,
+		Opts:      opts,
+	}, nil)
+}
+
+func (d *ExternalStorageDriver) CreateClone(
+	name, source, snapshot, newSnapshotPrefix string,
+) error {
+	return d.client.call("CreateClone", &cloneRequest{
+		Name:     name,
+		Source:   source,
+		Snapshot: snapshot,
+	}, nil)
+}
+
+// Destroy deletes a volume.  The sidecar reports volumes that don't exist,
+// so a NotFound error is returned for them.
+func (d *ExternalStorageDriver) Destroy(name string) error {
+	return d.client.call("Destroy", &volumeRequest{Name: name}, nil)
+}
+
+func (d *ExternalStorageDriver) Attach(
+	name, mountpoint string, opts map[string]string,
+) error {
+	return d.client.call("Attach", &attachRequest{
+		Name:       name,
+		Mountpoint: mountpoint,
+		Opts:       opts,
+	}, nil)
+}
+
+func (d *ExternalStorageDriver) Detach(name, mountpoint string) error {
+	return d.client.call("Detach", &attachRequest{
+		Name:       name,
+		Mountpoint: mountpoint,
+	}, nil)
+}
+
+func (d *ExternalStorageDriver) DefaultStoragePrefix() string {
+	return config.OrchestratorName
+}
+
+func (d *ExternalStorageDriver) DefaultSnapshotPrefix() string {
+	return ""
+}
+
+func (d *ExternalStorageDriver) SnapshotList(
+	name string,
+) ([]dvp.CommonSnapshot, error) {
+	snapshots := &snapshotListResponse{}
+	if err := d.client.call("SnapshotList", &volumeRequest{Name: name},
+		snapshots); err != nil {
+		return nil, err
+	}
+	return snapshots.Snapshots, nil
+}
+
+// CreateGroupSnapshot has the sidecar snapshot the volumes consistently,
+// e.g., with the array's consistency groups.  Sidecars that can't fail the
+// operation.
+func (d *ExternalStorageDriver) CreateGroupSnapshot(
+	volumeNames []string, snapshotName string,
+) error {
+	return d.client.call("CreateGroupSnapshot", &groupSnapshotRequest{
+		Names:    volumeNames,
+		Snapshot: snapshotName,
+	}, nil)
+}
+
+// GetUsedBytes asks the sidecar for the space a volume consumes on the
+// array.
+func (d *ExternalStorageDriver) GetUsedBytes(
+	volumeName string,
+) (uint64, error) {
+	usage := &usedBytesResponse{}
+	if err := d.client.call("GetUsedBytes",
+		&volumeRequest{Name: volumeName}, usage); err != nil {
+		return 0, err
+	}
+	return usage.UsedBytes, nil
+}
+
+// MirrorEndpoint asks the sidecar for the name by which its peers mirror a
+// volume.  Replication requires sidecars on both sides to implement the
+// mirror operations.
+func (d *ExternalStorageDriver) MirrorEndpoint(
+	volumeName string,
+) (string, error) {
+	endpoint := &mirrorEndpointResponse{}
+	if err := d.client.call("MirrorEndpoint",
+		&volumeRequest{Name: volumeName}, endpoint); err != nil {
+		return "", err
+	}
+	return endpoint.Endpoint, nil
+}
+
+func (d *ExternalStorageDriver) CreateMirror(
+	volumeName string, sizeBytes uint64, opts map[string]string,
+	sourceEndpoint string,
+) error {
+	return d.client.call("CreateMirror", &createMirrorRequest{
+		Name:      volumeName,
+		SizeBytes: sizeBytes,
+		Opts:      opts,
+		Source:    sourceEndpoint,
+	}, nil)
+}
+
+func (d *ExternalStorageDriver) GetMirrorStatus(
+	volumeName string,
+) (*storage.MirrorStatus, error) {
+	status := &storage.MirrorStatus{}
+	if err := d.client.call("GetMirrorStatus",
+		&volumeRequest{Name: volumeName}, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+func (d *ExternalStorageDriver) UpdateMirror(volumeName string) error {
+	return d.client.call("UpdateMirror", &volumeRequest{Name: volumeName},
+		nil)
+}
+
+func (d *ExternalStorageDriver) BreakMirror(volumeName string) error {
+	return d.client.call("BreakMirror", &volumeRequest{Name: volumeName},
+		nil)
+}
+
+func (d *ExternalStorageDriver) List(prefix string) ([]string, error) {
+	volumes := &listResponse{}
+	if err := d.client.call("List", &listRequest{Prefix: prefix},
+		volumes); err != nil {
+		return nil, err
+	}
+	return volumes.Volumes, nil
+}
+
+func (d *ExternalStorageDriver) Get(name string) error {
+	return d.client.call("Get", &volumeRequest{Name: name}, nil)
+}
+
+// GetStorageBackendSpecs adds the pools reported by the sidecar.
+func (d *ExternalStorageDriver) GetStorageBackendSpecs(
+	backend *storage.StorageBackend,
+) error {
+	backend.Name = "external_" + d.arrayName
+	for _, pool := range d.pools {
+		vc := storage.NewStoragePool(backend, pool.Name)
+		vc.Protocol = pool.Protocol
+		for attr, value := range pool.Attributes {
+			offer, err := sa.CreateAttributeOfferFromTypedValue(attr, value)
+			if err != nil {
+				return err
+			}
+			vc.Attributes[attr] = offer
+		}
+		vc.Attributes[sa.BackendType] = sa.NewStringOffer(d.Name())
+		backend.AddStoragePool(vc)
+	}
+	return nil
+}
+
+// GetVolumeOpts passes the storage pool, the storage class's requests, and
+// the volume's settings to the sidecar, which must honor them.
+func (d *ExternalStorageDriver) GetVolumeOpts(
+	volConfig *storage.VolumeConfig,
+	pool *storage.StoragePool,
+	requests map[string]sa.Request,
+) (map[string]string, error) {
+	opts := make(map[string]string)
+	for name, request := range requests {
+		opts[name] = fmt.Sprintf("%v", request.Value())
+	}
+	for name, value := range map[string]string{
+		"fsType":          volConfig.FSType,
+		"snapshotPolicy":  volConfig.SnapshotPolicy,
+		"exportPolicy":    volConfig.ExportPolicy,
+		"unixPermissions": volConfig.UnixPermissions,
+	} {
+		if value != "" {
+			opts[name] = value
+		}
+	}
+	if volConfig.HasQoS() {
+		opts["minIOPS"] = strconv.Itoa(volConfig.MinIOPS)
+		opts["maxIOPS"] = strconv.Itoa(volConfig.MaxIOPS)
+	}
+	if volConfig.Encryption {
+		opts["encryption"] = "true"
+	}
+	opts[PoolOpt] = pool.Name
+	return opts, nil
+}
+
+func (d *ExternalStorageDriver) GetInternalVolumeName(name string) string {
+	return storage.GetCommonInternalVolumeName(
+		&d.Config.CommonStorageDriverConfig, name)
+}
+
+func (d *ExternalStorageDriver) CreatePrepare(
+	volConfig *storage.VolumeConfig,
+) bool {
+	volConfig.InternalName = d.GetInternalVolumeName(volConfig.Name)
+	return true
+}
+
+// CreateFollowup asks the sidecar how hosts reach the volume.
+func (d *ExternalStorageDriver) CreateFollowup(
+	volConfig *storage.VolumeConfig,
+) error {
+	return d.client.call("GetAccessInfo",
+		&volumeRequest{Name: volConfig.InternalName}, &volConfig.AccessInfo)
+}
+
+func (d *ExternalStorageDriver) GetProtocol() config.Protocol {
+	return d.protocol
+}
+
+func (d *ExternalStorageDriver) GetDriverName() string {
+	return d.Config.StorageDriverName
+}
+
+// DriverVersion returns the version the sidecar reported, if any.
+func (d *ExternalStorageDriver) DriverVersion() string {
+	if d.version == "" {
+		return "unknown"
+	}
+	return d.version
+}
+
+func (d *ExternalStorageDriver) StoreConfig(
+	b *storage.PersistentStorageBackendConfig,
+) {
+	storage.SanitizeCommonStorageDriverConfig(
+		&d.Config.CommonStorageDriverConfig)
+	b.DriverConfig = &d.Config
+}
+
+func (d *ExternalStorageDriver) GetExternalConfig() interface{} {
+	return credentials.Sanitize(&d.Config)
+}
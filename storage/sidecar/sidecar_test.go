@@ -0,0 +1,201 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package sidecar
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/netapp/trident/config"
+	"github.com/netapp/trident/errors"
+	"github.com/netapp/trident/storage"
+	"github.com/netapp/trident/storage/factory"
+	sa "github.com/netapp/trident/storage_attribute"
+)
+
+// fakeSidecar serves the sidecar operations on a Unix socket, keeping its
+// volumes in memory.
+type fakeSidecar struct {
+	server  *httptest.Server
+	dir     string
+	socket  string
+	volumes map[string]createRequest
+}
+
+func newFakeSidecar(t *testing.T) *fakeSidecar {
+	dir, err := ioutil.TempDir("", "sidecar")
+	if err != nil {
+		t.Fatal("Unable to create socket directory:  ", err)
+	}
+	s := &fakeSidecar{
+		dir:     dir,
+		socket:  filepath.Join(dir, "sidecar.sock"),
+		volumes: make(map[string]createRequest),
+	}
+	listener, err := net.Listen("unix", s.socket)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal("Unable to listen on socket:  ", err)
+	}
+	s.server = httptest.NewUnstartedServer(http.HandlerFunc(s.serve))
+	s.server.Listener.Close()
+	s.server.Listener = listener
+	s.server.Start()
+	return s
+}
+
+func (s *fakeSidecar) close() {
+	s.server.Close()
+	os.RemoveAll(s.dir)
+}
+
+func (s *fakeSidecar) serve(w http.ResponseWriter, r *http.Request) {
+	var response interface{} = struct{}{}
+	var err *errorResponse
+	switch strings.TrimPrefix(r.URL.Path, "/v1/") {
+	case "Activate":
+		request := &activateRequest{}
+		json.NewDecoder(r.Body).Decode(request)
+		if request.Password != "secret" {
+			err = &errorResponse{Error: "Bad credentials."}
+			break
+		}
+		response = &activateResponse{Name: "array1", Protocol: config.Block}
+	case "GetPools":
+		response = &getPoolsResponse{Pools: []sidecarPool{
+			{
+				Name:       "gold",
+				Attributes: map[string]string{sa.Media: sa.SSD},
+			},
+			{
+				Name:     "files",
+				Protocol: config.File,
+				Attributes: map[string]string{
+					sa.Media: sa.HDD,
+					sa.IOPS:  "100-500",
+				},
+			},
+		}}
+	case "Create":
+		request := &createRequest{}
+		json.NewDecoder(r.Body).Decode(request)
+		s.volumes[request.Name] = *request
+	case "Destroy":
+		request := &volumeRequest{}
+		json.NewDecoder(r.Body).Decode(request)
+		if _, ok := s.volumes[request.Name]; !ok {
+			err = &errorResponse{Error: "No such volume.", NotFound: true}
+			break
+		}
+		delete(s.volumes, request.Name)
+	case "GetAccessInfo":
+		info := &storage.VolumeAccessInfo{}
+		info.IscsiTargetPortal = "10.0.0.1:3260"
+		info.IscsiTargetIQN = "iqn.2016-01.com.example:array1"
+		response = info
+	default:
+		err = &errorResponse{Error: "Unsupported operation."}
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(err)
+		return
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s *fakeSidecar) configJSON(password string) string {
+	return `{"version": 1, "storageDriverName": "external", "socket": "` +
+		s.socket + `", "password": "` + password + `"}`
+}
+
+func TestExternalBackend(t *testing.T) {
+	sidecar := newFakeSidecar(t)
+	defer sidecar.close()
+
+	backend, err := factory.NewStorageBackendForConfig(
+		sidecar.configJSON("secret"))
+	if err != nil {
+		t.Fatal("Unable to create external backend:  ", err)
+	}
+	if backend.Name != "external_array1" {
+		t.Errorf("Wrong backend name %s.", backend.Name)
+	}
+	gold, ok := backend.Storage["gold"]
+	if !ok {
+		t.Fatal("Pool gold not found.")
+	}
+	if gold.GetProtocol() != config.Block {
+		t.Errorf("Pool gold provides %s volumes.", gold.GetProtocol())
+	}
+	files, ok := backend.Storage["files"]
+	if !ok {
+		t.Fatal("Pool files not found.")
+	}
+	if files.GetProtocol() != config.File {
+		t.Errorf("Pool files provides %s volumes.", files.GetProtocol())
+	}
+	if !files.Attributes[sa.IOPS].Matches(sa.NewIntRequest(300)) {
+		t.Error("Pool files doesn't offer its IOPS.")
+	}
+
+	volConfig := &storage.VolumeConfig{
+		Name:     "vol1",
+		Size:     "1073741824",
+		Protocol: config.Block,
+		FSType:   "ext4",
+	}
+	vol, err := backend.AddVolume(context.Background(), volConfig, gold,
+		map[string]sa.Request{sa.Media: sa.NewStringRequest(sa.SSD)})
+	if err != nil {
+		t.Fatal("Unable to create volume:  ", err)
+	}
+	created, ok := sidecar.volumes[vol.Config.InternalName]
+	if !ok {
+		t.Fatal("Volume not created by sidecar.")
+	}
+	if created.Opts[PoolOpt] != "gold" || created.Opts["fsType"] != "ext4" ||
+		created.Opts[sa.Media] != sa.SSD {
+		t.Errorf("Wrong volume options:  %v", created.Opts)
+	}
+	if vol.Config.AccessInfo.IscsiTargetPortal != "10.0.0.1:3260" {
+		t.Errorf("Wrong target portal %s.",
+			vol.Config.AccessInfo.IscsiTargetPortal)
+	}
+
+	if err = backend.Driver.Destroy(vol.Config.InternalName); err != nil {
+		t.Error("Unable to destroy volume:  ", err)
+	}
+	err = backend.Driver.Destroy(vol.Config.InternalName)
+	if !errors.IsNotFound(err) {
+		t.Error("Expected NotFound error for missing volume, got ", err)
+	}
+
+	external := backend.ConstructExternal()
+	externalJSON, err := json.Marshal(external)
+	if err != nil {
+		t.Fatal("Unable to marshal backend:  ", err)
+	}
+	if strings.Contains(string(externalJSON), "secret") {
+		t.Error("Password reported:  ", string(externalJSON))
+	}
+}
+
+func TestExternalBackendFailure(t *testing.T) {
+	sidecar := newFakeSidecar(t)
+	defer sidecar.close()
+
+	_, err := factory.NewStorageBackendForConfig(sidecar.configJSON("wrong"))
+	if err == nil || !strings.Contains(err.Error(), "Bad credentials.") {
+		t.Error("Expected sidecar error, got ", err)
+	}
+}
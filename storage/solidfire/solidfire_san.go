@@ -3,7 +3,9 @@
 package solidfire
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/url"
 	"strings"
 
 	log "github.com/Sirupsen/logrus"
@@ -70,6 +72,36 @@ func (d *SolidfireSANStorageDriver) GetStorageBackendSpecs(
 	return nil
 }
 
+// modifyAccountRequest sets the CHAP secrets of a SolidFire account.
+type modifyAccountRequest struct {
+	AccountID       int64  `json:"accountID"`
+	InitiatorSecret string `json:"initiatorSecret,omitempty"`
+	TargetSecret    string `json:"targetSecret,omitempty"`
+}
+
+// SetChap sets the CHAP secrets of the tenant account that owns the
+// backend's volumes.  SolidFire uses the account name as both CHAP
+// usernames, so they're replaced with the tenant name.
+func (d *SolidfireSANStorageDriver) SetChap(chap *storage.ChapConfig) error {
+	chap.ChapUsername = d.Config.TenantName
+	chap.ChapTargetUsername = d.Config.TenantName
+	req := &modifyAccountRequest{
+		AccountID:       d.TenantID,
+		InitiatorSecret: chap.ChapInitiatorSecret,
+		TargetSecret:    chap.ChapTargetInitiatorSecret,
+	}
+	if _, err := d.Client.Request("ModifyAccount", req,
+		sfapi.NewReqID()); err != nil {
+		return fmt.Errorf("Could not set CHAP secrets for SolidFire "+
+			"account %s: %v", d.Config.TenantName, err)
+	}
+	log.WithFields(log.Fields{
+		"tenant":    d.Config.TenantName,
+		"accountID": d.TenantID,
+	}).Debug("Set SolidFire account CHAP secrets.")
+	return nil
+}
+
 func (d *SolidfireSANStorageDriver) GetInternalVolumeName(name string) string {
 	internalName := storage.GetCommonInternalVolumeName(
 		&d.Config.CommonStorageDriverConfig, name)
@@ -118,6 +150,122 @@ func (d *SolidfireSANStorageDriver) mapSolidfireLun(volConfig *storage.VolumeCon
 	return nil
 }
 
+// modifyVolumeAccessRequest sets the access of a SolidFire volume:
+// "readOnly" or "readWrite".
+type modifyVolumeAccessRequest struct {
+	VolumeID int64  `json:"volumeID"`
+	Access   string `json:"access"`
+}
+
+// SetVolumeReadOnly write-protects a volume, or makes it writable again.
+func (d *SolidfireSANStorageDriver) SetVolumeReadOnly(
+	volConfig *storage.VolumeConfig, readOnly bool,
+) error {
+	name := volConfig.InternalName
+	v, err := d.GetVolume(name)
+	if err != nil {
+		return fmt.Errorf("Could not find SolidFire volume %s: %v", name, err)
+	}
+	req := &modifyVolumeAccessRequest{VolumeID: v.VolumeID, Access: "readWrite"}
+	if readOnly {
+		req.Access = "readOnly"
+	}
+	if _, err = d.Client.Request("ModifyVolume", req,
+		sfapi.NewReqID()); err != nil {
+		return fmt.Errorf("Could not set the access of SolidFire volume "+
+			"%s to %s: %v", name, req.Access, err)
+	}
+	log.WithFields(log.Fields{
+		"volume":          volConfig.Name,
+		"volume_internal": name,
+		"access":          req.Access,
+	}).Debug("Set SolidFire volume access.")
+	return nil
+}
+
+// clusterAdmin is a SolidFire cluster admin, as listed by
+// ListClusterAdmins.
+type clusterAdmin struct {
+	ClusterAdminID int64  `json:"clusterAdminID"`
+	Username       string `json:"username"`
+}
+
+// modifyClusterAdminRequest changes the password of a cluster admin.
+type modifyClusterAdminRequest struct {
+	ClusterAdminID int64  `json:"clusterAdminID"`
+	Password       string `json:"password"`
+}
+
+// GetPassword returns the password in the driver's endpoint.
+func (d *SolidfireSANStorageDriver) GetPassword() string {
+	endpoint, err := url.Parse(d.Config.EndPoint)
+	if err != nil || endpoint.User == nil {
+		return ""
+	}
+	password, _ := endpoint.User.Password()
+	return password
+}
+
+// ChangePassword changes the password of the cluster admin named in the
+// driver's endpoint, and switches the endpoint to it.
+func (d *SolidfireSANStorageDriver) ChangePassword(password string) error {
+	endpoint, err := url.Parse(d.Config.EndPoint)
+	if err != nil || endpoint.User == nil {
+		return fmt.Errorf("SolidFire endpoint has no credentials")
+	}
+	username := endpoint.User.Username()
+	body, err := d.Client.Request("ListClusterAdmins", struct{}{},
+		sfapi.NewReqID())
+	if err != nil {
+		return fmt.Errorf("Could not list SolidFire cluster admins: %v", err)
+	}
+	var response struct {
+		Result struct {
+			ClusterAdmins []clusterAdmin `json:"clusterAdmins"`
+		} `json:"result"`
+	}
+	if err = json.Unmarshal(body, &response); err != nil {
+		return fmt.Errorf("Could not parse SolidFire cluster admins: %v", err)
+	}
+	req := &modifyClusterAdminRequest{Password: password}
+	for _, admin := range response.Result.ClusterAdmins {
+		if admin.Username == username {
+			req.ClusterAdminID = admin.ClusterAdminID
+			break
+		}
+	}
+	if req.ClusterAdminID == 0 {
+		return fmt.Errorf("SolidFire cluster admin %s not found", username)
+	}
+	if _, err = d.Client.Request("ModifyClusterAdmin", req,
+		sfapi.NewReqID()); err != nil {
+		return fmt.Errorf("Could not change the password of SolidFire "+
+			"cluster admin %s: %v", username, err)
+	}
+	endpoint.User = url.UserPassword(username, password)
+	d.Config.EndPoint = endpoint.String()
+	d.Client.Endpoint = d.Config.EndPoint
+	log.WithFields(log.Fields{
+		"username": username,
+	}).Debug("Changed SolidFire cluster admin password.")
+	return nil
+}
+
+// CheckCredentials logs in to the cluster with the driver's endpoint.
+func (d *SolidfireSANStorageDriver) CheckCredentials() error {
+	if _, err := d.Client.Request("GetClusterInfo", struct{}{},
+		sfapi.NewReqID()); err != nil {
+		return fmt.Errorf("Could not log in to SolidFire cluster: %v", err)
+	}
+	return nil
+}
+
+// GetIscsiPortals returns the cluster's SVIP, which redirects each login to
+// the node hosting the volume.
+func (d *SolidfireSANStorageDriver) GetIscsiPortals() ([]string, error) {
+	return []string{d.Config.SVIP}, nil
+}
+
 func (d *SolidfireSANStorageDriver) GetVolumeOpts(
 	volConfig *storage.VolumeConfig,
 	pool *storage.StoragePool,
@@ -125,10 +273,65 @@ func (d *SolidfireSANStorageDriver) GetVolumeOpts(
 ) (map[string]string, error) {
 	opts := make(map[string]string)
 	opts["type"] = pool.Name
+	if volConfig.HasQoS() {
+		opts["qos"] = d.getQoSOpt(volConfig, pool.Name)
+	}
 
 	return opts, nil
 }
 
+// getQoSOpt returns the nDVP "qos" option (min,max,burst) for a volume,
+// filling in any limits the volume doesn't set from its volume type.
+func (d *SolidfireSANStorageDriver) getQoSOpt(
+	volConfig *storage.VolumeConfig, volType string,
+) string {
+	qos := sfapi.QoS{
+		MinIOPS: sfDefaultMinIOPS,
+		MaxIOPS: sfDefaultMaxIOPS,
+	}
+	if d.Client != nil && d.Client.VolumeTypes != nil {
+		for _, t := range *d.Client.VolumeTypes {
+			if t.Type == volType {
+				qos = t.QOS
+				break
+			}
+		}
+	}
+	if volConfig.MinIOPS != 0 {
+		qos.MinIOPS = int64(volConfig.MinIOPS)
+	}
+	if volConfig.MaxIOPS != 0 {
+		qos.MaxIOPS = int64(volConfig.MaxIOPS)
+	}
+	if volConfig.BurstIOPS != 0 {
+		qos.BurstIOPS = int64(volConfig.BurstIOPS)
+	}
+	if qos.BurstIOPS < qos.MaxIOPS {
+		qos.BurstIOPS = qos.MaxIOPS
+	}
+	return fmt.Sprintf("%d,%d,%d", qos.MinIOPS, qos.MaxIOPS, qos.BurstIOPS)
+}
+
+// Destroy deletes a volume, failing with a NotFound error if it doesn't
+// exist.
+func (d *SolidfireSANStorageDriver) Destroy(name string) error {
+	return storage.DestroyVolume(&d.SolidfireSANStorageDriver, name)
+}
+
+// solidfireMaxVolumeSize is the size of the largest volume a SolidFire
+// cluster can create, 16 TiB.
+const solidfireMaxVolumeSize = 16 * 1024 * 1024 * 1024 * 1024
+
+// GetCapabilities reports that SolidFire volumes can be cloned and have QoS.
+// Their snapshots are taken by the cluster's schedules, not on demand.
+func (d *SolidfireSANStorageDriver) GetCapabilities() storage.Capabilities {
+	return storage.Capabilities{
+		SupportsClones: true,
+		SupportsQoS:    true,
+		MaxVolumeSize:  solidfireMaxVolumeSize,
+	}
+}
+
 func (d *SolidfireSANStorageDriver) GetProtocol() config.Protocol {
 	return config.Block
 }
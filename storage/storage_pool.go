@@ -4,7 +4,9 @@ package storage
 
 import (
 	"sort"
+	"strconv"
 
+	"github.com/netapp/trident/config"
 	sa "github.com/netapp/trident/storage_attribute"
 )
 
@@ -16,6 +18,15 @@ type StoragePool struct {
 	Volumes        map[string]*Volume
 	Backend        *StorageBackend
 	Attributes     map[string]sa.Offer
+	// Labels are set by the backend config's pool settings.
+	Labels map[string]string
+	// For virtual pools, PhysicalPool is the pool that volumes are created
+	// in, and VirtualPool is the pool's definition.
+	PhysicalPool *StoragePool
+	VirtualPool  *VirtualPool
+	// Protocol is set by drivers whose backends serve more than one
+	// protocol, e.g., NFS and iSCSI from one SVM; see GetProtocol.
+	Protocol config.Protocol
 }
 
 func NewStoragePool(backend *StorageBackend, name string) *StoragePool {
@@ -28,6 +39,18 @@ func NewStoragePool(backend *StorageBackend, name string) *StoragePool {
 	}
 }
 
+// GetProtocol returns the protocol of the pool's volumes:  the pool's own
+// protocol, if its driver set one, or else its backend's.
+func (vc *StoragePool) GetProtocol() config.Protocol {
+	if vc.Protocol != config.ProtocolAny {
+		return vc.Protocol
+	}
+	if vc.PhysicalPool != nil {
+		return vc.PhysicalPool.GetProtocol()
+	}
+	return vc.Backend.GetProtocol()
+}
+
 func (vc *StoragePool) AddVolume(vol *Volume, bootstrap bool) {
 	vc.Volumes[vol.Config.Name] = vol
 }
@@ -40,6 +63,28 @@ func (vc *StoragePool) DeleteVolume(vol *Volume) bool {
 	return false
 }
 
+// CanHonorQoS returns true if the pool can provide the requested IOPS
+// limits.  Unset (zero) limits are ignored; pools that don't offer IOPS, or
+// whose drivers don't support QoS, can't honor any limit.
+func (vc *StoragePool) CanHonorQoS(minIOPS, maxIOPS int) bool {
+	if minIOPS == 0 && maxIOPS == 0 {
+		return true
+	}
+	offer, ok := vc.Attributes[sa.IOPS]
+	if !ok {
+		return false
+	}
+	if vc.Backend != nil && !vc.Backend.Capabilities().SupportsQoS {
+		return false
+	}
+	for _, iops := range []int{minIOPS, maxIOPS} {
+		if iops != 0 && !offer.Matches(sa.NewIntRequest(iops)) {
+			return false
+		}
+	}
+	return true
+}
+
 func (vc *StoragePool) AddStorageClass(class string) {
 	// Note that this function should get called once per storage class
 	// affecting the volume; thus, we don't need to check for duplicates.
@@ -61,20 +106,35 @@ func (vc *StoragePool) RemoveStorageClass(class string) bool {
 
 type StoragePoolExternal struct {
 	Name           string              `json:"name"`
+	Protocol       config.Protocol     `json:"protocol"`
 	StorageClasses []string            `json:"storageClasses"`
 	Attributes     map[string]sa.Offer `json:"storageAttributes"`
-	Volumes        []string            `json:"volumes"`
+	// ResolvedAttributes are the pool's attributes, after any virtual pool
+	// overrides, written as in virtual pool configs, e.g., "100-5000" for
+	// an IOPS range.
+	ResolvedAttributes map[string]string `json:"resolvedAttributes"`
+	Labels             map[string]string `json:"labels,omitempty"`
+	PhysicalPool       string            `json:"physicalPool,omitempty"`
+	Volumes            []string          `json:"volumes"`
 }
 
 func (vc *StoragePool) ConstructExternal() *StoragePoolExternal {
 	external := &StoragePoolExternal{
 		Name:           vc.Name,
+		Protocol:       vc.GetProtocol(),
 		StorageClasses: vc.StorageClasses,
 		Attributes:     make(map[string]sa.Offer),
-		Volumes:        make([]string, 0, len(vc.Volumes)),
+		ResolvedAttributes: make(map[string]string,
+			len(vc.Attributes)),
+		Labels:  vc.Labels,
+		Volumes: make([]string, 0, len(vc.Volumes)),
+	}
+	if vc.PhysicalPool != nil {
+		external.PhysicalPool = vc.PhysicalPool.Name
 	}
 	for k, v := range vc.Attributes {
 		external.Attributes[k] = v
+		external.ResolvedAttributes[k] = sa.OfferToTypedValue(v)
 	}
 	for name, _ := range vc.Volumes {
 		external.Volumes = append(external.Volumes, name)
@@ -85,3 +145,46 @@ func (vc *StoragePool) ConstructExternal() *StoragePoolExternal {
 	sort.Strings(external.Volumes)
 	return external
 }
+
+// SupportsEncryption returns true if the pool can create encrypted volumes.
+func (vc *StoragePool) SupportsEncryption() bool {
+	offer, ok := vc.Attributes[sa.Encryption]
+	return ok && offer.Matches(sa.NewBoolRequest(true))
+}
+
+// SupportsSpaceEfficiency returns true if the pool can create volumes with
+// the provisioning type, deduplication, and compression volConfig sets.
+func (vc *StoragePool) SupportsSpaceEfficiency(volConfig *VolumeConfig) bool {
+	if volConfig.ProvisioningType != "" {
+		offer, ok := vc.Attributes[sa.ProvisioningType]
+		if !ok || !offer.Matches(
+			sa.NewStringRequest(volConfig.ProvisioningType)) {
+			return false
+		}
+	}
+	for name, value := range map[string]string{
+		sa.Deduplication: volConfig.Deduplication,
+		sa.Compression:   volConfig.Compression,
+	} {
+		if value == "" {
+			continue
+		}
+		// VolumeConfig.Validate has checked the value.
+		enable, _ := strconv.ParseBool(value)
+		offer, ok := vc.Attributes[name]
+		if !ok || !offer.Matches(sa.NewBoolRequest(enable)) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchesLabels returns true if the pool has every label in selector.
+func (vc *StoragePool) MatchesLabels(selector map[string]string) bool {
+	for k, v := range selector {
+		if label, ok := vc.Labels[k]; !ok || label != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,145 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package storage
+
+import (
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+
+	sa "github.com/netapp/trident/storage_attribute"
+)
+
+// VirtualPool is a named service level defined in a backend config.  Each
+// virtual pool is backed by one of the backend's physical pools but is
+// matched against storage classes as a distinct pool, so one physical
+// backend can offer several service levels.
+type VirtualPool struct {
+	// Pool is the physical pool that volumes are created in.  It may be
+	// omitted if the backend has only one pool.
+	Pool string `json:"pool,omitempty"`
+	// Attributes override the physical pool's storage attributes.  Values
+	// are given as strings:  "true" for booleans, "min-max" or a single
+	// value for integers, and a comma-separated list for strings.
+	Attributes map[string]string `json:"attributes,omitempty"`
+	// Labels are added to the physical pool's labels.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Defaults are applied to volumes created in the pool.
+	Defaults VirtualPoolDefaults `json:"defaults,omitempty"`
+}
+
+// VirtualPoolDefaults are volume options applied to volumes in a virtual
+// pool that don't set them.
+type VirtualPoolDefaults struct {
+	MinIOPS          int    `json:"minIOPS,omitempty"`
+	MaxIOPS          int    `json:"maxIOPS,omitempty"`
+	BurstIOPS        int    `json:"burstIOPS,omitempty"`
+	SnapshotPolicy   string `json:"snapshotPolicy,omitempty"`
+	SnapshotDir      string `json:"snapshotDirectory,omitempty"`
+	SnapshotReserve  string `json:"snapshotReserve,omitempty"`
+	SnapshotSchedule string `json:"snapshotSchedule,omitempty"`
+}
+
+// applyVirtualPools replaces the backend's physical pools with virtual
+// pools.  The physical pools must already be labeled.
+func (b *StorageBackend) applyVirtualPools(
+	virtualPools map[string]*VirtualPool,
+) error {
+	physicalPools := b.Storage
+	b.Storage = make(map[string]*StoragePool, len(virtualPools))
+	for name, vp := range virtualPools {
+		if err := ValidateQoS(vp.Defaults.MinIOPS, vp.Defaults.MaxIOPS,
+			vp.Defaults.BurstIOPS); err != nil {
+			return fmt.Errorf("Invalid defaults for virtual pool %s:  %v",
+				name, err)
+		}
+		if err := ValidateSnapshotReserve(
+			vp.Defaults.SnapshotReserve); err != nil {
+			return fmt.Errorf("Invalid defaults for virtual pool %s:  %v",
+				name, err)
+		}
+		physicalName := vp.Pool
+		if physicalName == "" {
+			if len(physicalPools) != 1 {
+				return fmt.Errorf("Virtual pool %s must name one of the "+
+					"storage pools of backend %s.", name, b.Name)
+			}
+			for onlyName := range physicalPools {
+				physicalName = onlyName
+			}
+		}
+		physical, ok := physicalPools[physicalName]
+		if !ok {
+			return fmt.Errorf("Virtual pool %s uses storage pool %s, which "+
+				"backend %s doesn't have or excludes.", name, physicalName,
+				b.Name)
+		}
+
+		pool := NewStoragePool(b, name)
+		pool.PhysicalPool = physical
+		pool.VirtualPool = vp
+		for k, v := range physical.Attributes {
+			pool.Attributes[k] = v
+		}
+		for k, v := range vp.Attributes {
+			offer, err := sa.CreateAttributeOfferFromTypedValue(k, v)
+			if err != nil {
+				return fmt.Errorf("Invalid attribute for virtual pool %s:  "+
+					"%v", name, err)
+			}
+			pool.Attributes[k] = offer
+		}
+		pool.Labels = make(map[string]string)
+		for k, v := range physical.Labels {
+			pool.Labels[k] = v
+		}
+		for k, v := range vp.Labels {
+			pool.Labels[k] = v
+		}
+		b.AddStoragePool(pool)
+		log.WithFields(log.Fields{
+			"backend":      b.Name,
+			"virtualPool":  name,
+			"physicalPool": physicalName,
+		}).Debug("Added virtual pool.")
+	}
+	return nil
+}
+
+// applyVirtualPoolDefaults returns a copy of volConfig with the pool's
+// defaults filled in.  Physical pools have no defaults.
+func (vc *StoragePool) applyVirtualPoolDefaults(
+	volConfig *VolumeConfig,
+) *VolumeConfig {
+	if vc.VirtualPool == nil {
+		return volConfig
+	}
+	defaults := vc.VirtualPool.Defaults
+	ret := *volConfig
+	if !ret.HasQoS() {
+		ret.MinIOPS = defaults.MinIOPS
+		ret.MaxIOPS = defaults.MaxIOPS
+		ret.BurstIOPS = defaults.BurstIOPS
+	}
+	if ret.SnapshotPolicy == "" {
+		ret.SnapshotPolicy = defaults.SnapshotPolicy
+	}
+	if ret.SnapshotDir == "" {
+		ret.SnapshotDir = defaults.SnapshotDir
+	}
+	if ret.SnapshotReserve == "" {
+		ret.SnapshotReserve = defaults.SnapshotReserve
+	}
+	if ret.SnapshotSchedule == "" {
+		ret.SnapshotSchedule = defaults.SnapshotSchedule
+	}
+	return &ret
+}
+
+// physical returns the pool that the driver creates volumes in.
+func (vc *StoragePool) physical() *StoragePool {
+	if vc.PhysicalPool != nil {
+		return vc.PhysicalPool
+	}
+	return vc
+}
@@ -4,29 +4,92 @@ package storage
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/netapp/trident/config"
+	sa "github.com/netapp/trident/storage_attribute"
 )
 
 type VolumeConfig struct {
-	Version         string            `json:"version"`
-	Name            string            `json:"name"`
-	InternalName    string            `json:"internalName"`
-	Size            string            `json:"size"`
-	Protocol        config.Protocol   `json:"protocol"`
-	SnapshotPolicy  string            `json:"snapshotPolicy,omitempty"`
-	ExportPolicy    string            `json:"exportPolicy,omitempty"`
-	SnapshotDir     string            `json:"snapshotDirectory,omitempty"`
-	UnixPermissions string            `json:"unixPermissions,omitempty"`
-	StorageClass    string            `json:"storageClass,omitempty"`
-	AccessMode      config.AccessMode `json:"accessMode,omitempty"`
-	AccessInfo      VolumeAccessInfo  `json:"accessInformation"`
+	Version          string            `json:"version"`
+	Name             string            `json:"name"`
+	InternalName     string            `json:"internalName"`
+	Size             string            `json:"size"`
+	Protocol         config.Protocol   `json:"protocol"`
+	SnapshotPolicy   string            `json:"snapshotPolicy,omitempty"`
+	ExportPolicy     string            `json:"exportPolicy,omitempty"`
+	SnapshotDir      string            `json:"snapshotDirectory,omitempty"`
+	SnapshotReserve  string            `json:"snapshotReserve,omitempty"`
+	UnixPermissions  string            `json:"unixPermissions,omitempty"`
+	StorageClass     string            `json:"storageClass,omitempty"`
+	AccessMode       config.AccessMode `json:"accessMode,omitempty"`
+	MinIOPS          int               `json:"minIOPS,omitempty"`
+	MaxIOPS          int               `json:"maxIOPS,omitempty"`
+	BurstIOPS        int               `json:"burstIOPS,omitempty"`
+	Encryption       bool              `json:"encryption,omitempty"`
+	ProvisioningType string            `json:"provisioningType,omitempty"`
+	Deduplication    string            `json:"deduplication,omitempty"`
+	Compression      string            `json:"compression,omitempty"`
+	SnapshotSchedule string            `json:"snapshotSchedule,omitempty"`
+	SourceVolume     string            `json:"sourceVolume,omitempty"`
+	SourceSnapshot   string            `json:"sourceSnapshot,omitempty"`
+	Access           *VolumeAccess     `json:"access,omitempty"`
+	FSType           string            `json:"fsType,omitempty"`
+	MkfsOptions      string            `json:"mkfsOptions,omitempty"`
+	MountOptions     string            `json:"mountOptions,omitempty"`
+	Formatted        bool              `json:"formatted,omitempty"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+	// ReclaimPolicy is Retain if deleting the volume should leave it on
+	// its backend; see config.ReclaimRetain.
+	ReclaimPolicy config.ReclaimPolicy `json:"reclaimPolicy,omitempty"`
+	// RequestID, if set by the client, identifies the request that created
+	// the volume, so that a retried request returns the same volume.
+	RequestID string `json:"requestID,omitempty"`
+	// Group names the volume group the volume was created in, if any.
+	Group string `json:"group,omitempty"`
+	// Namespace is the namespace of the claim that requested the volume, if
+	// any; volume name templates may use it.
+	Namespace string `json:"namespace,omitempty"`
+	// AntiAffinity names volumes whose backends the volume must not be
+	// placed on.
+	AntiAffinity []string `json:"antiAffinity,omitempty"`
+	// SpreadGroup, if set, keeps the volume off the backends of the other
+	// volumes in the same namespace with the same spread group.
+	SpreadGroup string `json:"spreadGroup,omitempty"`
+	// BackendTier is the tier, in its storage class's backend preference,
+	// of the backend the volume was placed on, or 0 if the class has none;
+	// see storage_class.StorageClass.BackendTier.
+	BackendTier int `json:"backendTier,omitempty"`
+	// ReadOnly volumes are configured read-only on their backends:  NFS
+	// exports allow no writes, and LUNs are write-protected.  Volumes with
+	// the ReadOnlyMany access mode are always read-only.
+	ReadOnly bool `json:"readOnly,omitempty"`
+	// UntruncatedInternalName is set if InternalName was truncated to fit
+	// the storage system, to the name before truncation.
+	UntruncatedInternalName string `json:"untruncatedInternalName,omitempty"`
+	// CreatedAt is when Trident created the volume.  It is zero for
+	// volumes created by earlier versions, or imported.
+	CreatedAt time.Time `json:"createdAt"`
+	// TTL, if set, is how long after its creation the volume expires, e.g.,
+	// 24h.  It sets ExpiresAt when the volume is created.
+	TTL string `json:"ttl,omitempty"`
+	// ExpiresAt, if set, is when the volume expires and is deleted, once
+	// the expiry grace period ends; see config.VolumeExpiryConfig.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	// ExpiredAt is set by Trident when it finds that the volume has
+	// expired.
+	ExpiredAt  *time.Time       `json:"expiredAt,omitempty"`
+	AccessInfo VolumeAccessInfo `json:"accessInformation"`
 }
 
 type VolumeAccessInfo struct {
 	IscsiAccessInfo
 	NfsAccessInfo
+	SmbAccessInfo
+	NvmeAccessInfo
 }
 
 type IscsiAccessInfo struct {
@@ -36,6 +99,9 @@ type IscsiAccessInfo struct {
 	IscsiInterface    string `json:"iscsiInterface,omitempty"`
 	IscsiIgroup       string `json:"iscsiIgroup,omitempty"`
 	IscsiVAG          int64  `json:"iscsiVag,omitempty"`
+	// IscsiPortals lists every portal through which the volume can be
+	// reached, starting with IscsiTargetPortal, for multipathing.
+	IscsiPortals []string `json:"iscsiPortals,omitempty"`
 }
 
 type NfsAccessInfo struct {
@@ -43,23 +109,297 @@ type NfsAccessInfo struct {
 	NfsPath     string `json:"nfsPath,omitempty"`
 }
 
+type NvmeAccessInfo struct {
+	NvmeSubsystemNQN  string `json:"nvmeSubsystemNqn,omitempty"`
+	NvmeNamespaceUUID string `json:"nvmeNamespaceUuid,omitempty"`
+	// NvmeTransport is one of the NVMeTransport constants.
+	NvmeTransport       string   `json:"nvmeTransport,omitempty"`
+	NvmeTargetAddresses []string `json:"nvmeTargetAddresses,omitempty"`
+}
+
+type SmbAccessInfo struct {
+	SmbServer string `json:"smbServer,omitempty"`
+	SmbShare  string `json:"smbShare,omitempty"`
+}
+
+// VolumeAccess lists the hosts allowed to access a volume:  NFS client match
+// specifications (addresses, subnets, or host names) for file volumes, and
+// initiator names for block volumes.
+type VolumeAccess struct {
+	NfsClients      []string `json:"nfsClients,omitempty"`
+	IscsiInitiators []string `json:"iscsiInitiators,omitempty"`
+}
+
+func (a *VolumeAccess) Validate() error {
+	for _, client := range a.NfsClients {
+		if strings.TrimSpace(client) == "" {
+			return fmt.Errorf("NFS clients must not be empty.")
+		}
+	}
+	for _, initiator := range a.IscsiInitiators {
+		if !strings.HasPrefix(initiator, "iqn.") &&
+			!strings.HasPrefix(initiator, "eui.") &&
+			!strings.HasPrefix(initiator, "naa.") {
+			return fmt.Errorf("%s is not a valid iSCSI initiator name.",
+				initiator)
+		}
+	}
+	return nil
+}
+
+// IsEmpty returns true if the access list names no hosts.
+func (a *VolumeAccess) IsEmpty() bool {
+	return len(a.NfsClients) == 0 && len(a.IscsiInitiators) == 0
+}
+
+// IsReadOnly returns true if the volume is, or must be, read-only on its
+// backend.
+func (c *VolumeConfig) IsReadOnly() bool {
+	return c.ReadOnly || c.AccessMode == config.ReadOnlyMany
+}
+
+// ValidateReadOnly checks that a read-only volume's access mode allows no
+// writes.
+func (c *VolumeConfig) ValidateReadOnly() error {
+	if c.ReadOnly && c.AccessMode != config.ModeAny &&
+		c.AccessMode != config.ReadOnlyMany {
+		return fmt.Errorf("Read-only volumes can't have the %s access "+
+			"mode; use %s.", c.AccessMode, config.ReadOnlyMany)
+	}
+	return nil
+}
+
 func (c *VolumeConfig) Validate() error {
-	if c.Name == "" || c.Size == "" {
+	// Volumes created from snapshots take their size from the source.
+	if c.Name == "" || (c.Size == "" && !c.IsClone()) {
 		return fmt.Errorf("The following fields for \"Volume\" are mandatory: name and size")
 	}
+	if (c.SourceVolume == "") != (c.SourceSnapshot == "") {
+		return fmt.Errorf("sourceVolume and sourceSnapshot must be " +
+			"specified together.")
+	}
 	if !config.IsValidProtocol(c.Protocol) {
 		return fmt.Errorf("%v is an usupported protocol! Acceptable values:  "+
 			"%s", c.Protocol,
 			strings.Join([]string(config.GetValidProtocolNames()), ", "),
 		)
 	}
+	if c.Access != nil {
+		if err := c.Access.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := ValidateMetadata(c.Metadata); err != nil {
+		return err
+	}
+	if err := ValidateMountOptions(c.MountOptions); err != nil {
+		return err
+	}
+	if err := ValidateSnapshotReserve(c.SnapshotReserve); err != nil {
+		return err
+	}
+	if err := c.validateSpaceEfficiency(); err != nil {
+		return err
+	}
+	if !config.IsValidReclaimPolicy(c.ReclaimPolicy) {
+		return fmt.Errorf("%v is an unsupported reclaim policy!  Acceptable "+
+			"values:  %s, %s", c.ReclaimPolicy, config.ReclaimDelete,
+			config.ReclaimRetain)
+	}
+	if c.FSType != "" && !config.IsValidFSType(c.FSType) {
+		return fmt.Errorf("%v is an unsupported filesystem type! "+
+			"Acceptable values:  %s", c.FSType,
+			strings.Join(config.GetValidFSTypes(), ", "))
+	}
+	if c.Protocol == config.File && (c.FSType != "" || c.MkfsOptions != "") {
+		return fmt.Errorf("fsType and mkfsOptions only apply to block " +
+			"volumes.")
+	}
+	if err := ValidateExpiry(c.TTL, c.ExpiresAt); err != nil {
+		return err
+	}
+	if err := c.ValidateReadOnly(); err != nil {
+		return err
+	}
+	return ValidateQoS(c.MinIOPS, c.MaxIOPS, c.BurstIOPS)
+}
+
+// SetCreationTime records that the volume was created at now, setting its
+// expiry from its TTL, if it has one.
+func (c *VolumeConfig) SetCreationTime(now time.Time) {
+	c.CreatedAt = now
+	c.ExpiredAt = nil
+	if c.TTL == "" {
+		return
+	}
+	// Validate has checked the TTL.
+	ttl, _ := time.ParseDuration(c.TTL)
+	expiresAt := now.Add(ttl)
+	c.ExpiresAt = &expiresAt
+}
+
+// IsExpired returns true if the volume has an expiry no later than now.
+func (c *VolumeConfig) IsExpired(now time.Time) bool {
+	return c.ExpiresAt != nil && !now.Before(*c.ExpiresAt)
+}
+
+// ValidateExpiry checks that a volume's TTL, if set, is a positive
+// duration, and that it doesn't also have an expiry time.
+func ValidateExpiry(ttl string, expiresAt *time.Time) error {
+	if ttl == "" {
+		return nil
+	}
+	if expiresAt != nil {
+		return fmt.Errorf("ttl and expiresAt must not be specified together.")
+	}
+	duration, err := time.ParseDuration(ttl)
+	if err != nil {
+		return fmt.Errorf("Invalid ttl:  %v", err)
+	}
+	if duration <= 0 {
+		return fmt.Errorf("ttl must be positive.")
+	}
+	return nil
+}
+
+// ValidateMetadata checks that volume metadata has no empty keys.
+func ValidateMetadata(metadata map[string]string) error {
+	for k := range metadata {
+		if strings.TrimSpace(k) == "" {
+			return fmt.Errorf("Volume metadata keys must not be empty.")
+		}
+	}
+	return nil
+}
+
+// ValidateMountOptions checks that comma-separated mount options have no
+// empty options or whitespace.
+func ValidateMountOptions(options string) error {
+	if options == "" {
+		return nil
+	}
+	for _, option := range strings.Split(options, ",") {
+		if option == "" || strings.IndexFunc(option, unicode.IsSpace) >= 0 {
+			return fmt.Errorf("Invalid mount options %q:  options must be "+
+				"separated by single commas, without spaces.", options)
+		}
+	}
+	return nil
+}
+
+// MaxSnapshotReserve is the largest percentage of a volume that may be
+// reserved for its snapshots.
+const MaxSnapshotReserve = 90
+
+// ValidateSnapshotReserve checks that a snapshot reserve, if set, is a
+// percentage no greater than MaxSnapshotReserve.
+func ValidateSnapshotReserve(reserve string) error {
+	if reserve == "" {
+		return nil
+	}
+	percent, err := strconv.Atoi(reserve)
+	if err != nil || percent < 0 || percent > MaxSnapshotReserve {
+		return fmt.Errorf("Invalid snapshotReserve %q:  must be a percentage "+
+			"from 0 to %d.", reserve, MaxSnapshotReserve)
+	}
+	return nil
+}
+
+// validateSpaceEfficiency checks that the provisioning type, if set, is
+// thin or thick, and that deduplication and compression, if set, are true or
+// false.
+func (c *VolumeConfig) validateSpaceEfficiency() error {
+	switch c.ProvisioningType {
+	case "", sa.Thin, sa.Thick:
+	default:
+		return fmt.Errorf("Invalid provisioningType %q; must be %s or %s.",
+			c.ProvisioningType, sa.Thin, sa.Thick)
+	}
+	for name, value := range map[string]string{
+		sa.Deduplication: c.Deduplication,
+		sa.Compression:   c.Compression,
+	} {
+		if value == "" {
+			continue
+		}
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("Invalid %s %q; must be true or false.", name,
+				value)
+		}
+	}
+	return nil
+}
+
+// MatchesMetadata returns true if the volume has every key and value in
+// selector.
+func (c *VolumeConfig) MatchesMetadata(selector map[string]string) bool {
+	for k, v := range selector {
+		if value, ok := c.Metadata[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// IsClone returns true if the volume is to be created from a snapshot.
+func (c *VolumeConfig) IsClone() bool {
+	return c.SourceSnapshot != ""
+}
+
+// SizeBytes returns the volume's size in bytes.
+func (c *VolumeConfig) SizeBytes() (uint64, error) {
+	return sizeInBytes(c.Size)
+}
+
+// HasQoS returns true if any IOPS limit is set for the volume.
+func (c *VolumeConfig) HasQoS() bool {
+	return c.MinIOPS != 0 || c.MaxIOPS != 0 || c.BurstIOPS != 0
+}
+
+// ValidateQoS checks that the IOPS limits that are set (non-zero) are
+// consistent with each other, i.e., min <= max <= burst.
+func ValidateQoS(minIOPS, maxIOPS, burstIOPS int) error {
+	if minIOPS < 0 || maxIOPS < 0 || burstIOPS < 0 {
+		return fmt.Errorf("IOPS limits must not be negative.")
+	}
+	if minIOPS != 0 && maxIOPS != 0 && minIOPS > maxIOPS {
+		return fmt.Errorf("minIOPS (%d) must not exceed maxIOPS (%d).",
+			minIOPS, maxIOPS)
+	}
+	if maxIOPS != 0 && burstIOPS != 0 && maxIOPS > burstIOPS {
+		return fmt.Errorf("maxIOPS (%d) must not exceed burstIOPS (%d).",
+			maxIOPS, burstIOPS)
+	}
 	return nil
 }
 
 type Volume struct {
-	Config  *VolumeConfig
-	Backend *StorageBackend
-	Pool    *StoragePool
+	Config      *VolumeConfig
+	Backend     *StorageBackend
+	Pool        *StoragePool
+	Attachments []VolumeAttachment
+	// Deletion is set while the volume is terminating, i.e., deleted but
+	// left on its backend until the deletion grace period ends.
+	Deletion *VolumeDeletion
+	// Replication is set while the volume is mirrored to a peer backend.
+	Replication *VolumeReplication
+}
+
+// VolumeDeletion records the deletion of a terminating volume.
+type VolumeDeletion struct {
+	DeletedAt time.Time `json:"deletedAt"`
+	// ReapAt is when the volume is to be deleted from its backend.
+	ReapAt time.Time `json:"reapAt"`
+	// InternalName is the volume's internal name before it was renamed on
+	// its backend for deletion; undeleting the volume restores it.
+	InternalName string `json:"internalName"`
+}
+
+// VolumeAttachment records that a volume is published to a node.
+type VolumeAttachment struct {
+	Node       string    `json:"node"`
+	ReadOnly   bool      `json:"readOnly,omitempty"`
+	AttachedAt time.Time `json:"attachedAt"`
 }
 
 func NewVolume(conf *VolumeConfig, backend *StorageBackend, pool *StoragePool) *Volume {
@@ -70,16 +410,91 @@ func NewVolume(conf *VolumeConfig, backend *StorageBackend, pool *StoragePool) *
 	}
 }
 
+// GetAttachment returns the volume's attachment to node, or nil if it isn't
+// attached there.
+func (v *Volume) GetAttachment(node string) *VolumeAttachment {
+	for i := range v.Attachments {
+		if v.Attachments[i].Node == node {
+			return &v.Attachments[i]
+		}
+	}
+	return nil
+}
+
+// IsTerminating returns true if the volume has been deleted but is still
+// within its deletion grace period.
+func (v *Volume) IsTerminating() bool {
+	return v.Deletion != nil
+}
+
+// AttachedNodes returns the names of the nodes the volume is attached to.
+func (v *Volume) AttachedNodes() []string {
+	nodes := make([]string, 0, len(v.Attachments))
+	for _, a := range v.Attachments {
+		nodes = append(nodes, a.Node)
+	}
+	return nodes
+}
+
 type VolumeExternal struct {
-	Config  *VolumeConfig
-	Backend string `json:"backend"`
-	Pool    string `json:"pool"`
+	Config *VolumeConfig
+	// Name and InternalName are the volume's names in Trident and on its
+	// storage system, as in Config.
+	Name         string `json:"name"`
+	InternalName string `json:"internalName"`
+	// SnapshotReserve and SnapshotDir are the volume's snapshot options,
+	// as in Config, once its class's and pool's defaults are applied.
+	SnapshotReserve string             `json:"snapshotReserve,omitempty"`
+	SnapshotDir     string             `json:"snapshotDirectory,omitempty"`
+	Backend         string             `json:"backend"`
+	Pool            string             `json:"pool"`
+	Encrypted       bool               `json:"encrypted"`
+	Attachments     []VolumeAttachment `json:"attachments,omitempty"`
+	Deletion        *VolumeDeletion    `json:"deletion,omitempty"`
+	Replication     *VolumeReplication `json:"replication,omitempty"`
+	// Orphaned is set for volumes whose backend or pool no longer exists.
+	// It is never persisted.
+	Orphaned bool `json:"orphaned,omitempty"`
+	// Chap holds the backend's CHAP settings, with the secrets redacted,
+	// for volumes on backends that use CHAP.
+	Chap *ChapConfig `json:"chap,omitempty"`
+	// BackendTier is the tier of the volume's backend, as in Config.
+	BackendTier int `json:"backendTier,omitempty"`
+	// AccessInfo is how hosts reach the volume, from Config.AccessInfo.
+	AccessInfo *AccessDetails `json:"accessInfo,omitempty"`
+	// ReadOnly is set if the volume is read-only on its backend, as in
+	// Config.
+	ReadOnly bool `json:"readOnly,omitempty"`
 }
 
 func (v *Volume) ConstructExternal() *VolumeExternal {
-	return &VolumeExternal{
-		Config:  v.Config,
-		Backend: v.Backend.Name,
-		Pool:    v.Pool.Name,
+	external := &VolumeExternal{
+		Config:          v.Config,
+		Name:            v.Config.Name,
+		InternalName:    v.Config.InternalName,
+		SnapshotReserve: v.Config.SnapshotReserve,
+		SnapshotDir:     v.Config.SnapshotDir,
+		BackendTier:     v.Config.BackendTier,
+		ReadOnly:        v.Config.ReadOnly,
+		Backend:         v.Backend.Name,
+		Pool:            v.Pool.Name,
+		Encrypted:       v.Config.Encryption,
+	}
+	if v.Deletion != nil {
+		deletion := *v.Deletion
+		external.Deletion = &deletion
+	}
+	if v.Replication != nil {
+		replication := *v.Replication
+		external.Replication = &replication
+	}
+	if len(v.Attachments) > 0 {
+		external.Attachments = make([]VolumeAttachment, len(v.Attachments))
+		copy(external.Attachments, v.Attachments)
+	}
+	if v.Backend.Chap.IsSet() {
+		external.Chap = v.Backend.Chap.Redacted()
 	}
+	external.AccessInfo = v.Config.AccessInfo.Details()
+	return external
 }
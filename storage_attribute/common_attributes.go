@@ -7,7 +7,13 @@ const (
 	IOPS = "IOPS"
 
 	// Constants for boolean storage category attributes
-	Snapshots = "snapshots"
+	Snapshots     = "snapshots"
+	Encryption    = "encryption"
+	Deduplication = "deduplication"
+	Compression   = "compression"
+	// NVMe pools serve their volumes as NVMe namespaces; see
+	// storage.NVMeDriver.
+	NVMe = "nvme"
 
 	// Constants for string list attributes
 	ProvisioningType = "provisioningType"
@@ -25,17 +31,10 @@ const (
 	SSD    = "ssd"
 	Hybrid = "hybrid"
 
+	// Values for provisioningType
+	Thin  = "thin"
+	Thick = "thick"
+
 	BackendStoragePools = "requiredStorage"
+	Selector            = "selector"
 )
-
-var attrTypes = map[string]StorageAttributeType{
-	IOPS:             intType,
-	Snapshots:        boolType,
-	ProvisioningType: stringType,
-	BackendType:      stringType,
-	Media:            stringType,
-	RecoveryTest:     boolType,
-	UniqueOptions:    stringType,
-	TestingAttribute: boolType,
-	NonexistentBool:  boolType,
-}
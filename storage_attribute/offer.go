@@ -5,6 +5,8 @@ package storage_attribute
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 )
 
 func UnmarshalOfferMap(mapJSON json.RawMessage) (
@@ -21,9 +23,10 @@ func UnmarshalOfferMap(mapJSON json.RawMessage) (
 		var (
 			final Offer
 		)
-		baseType, ok := attrTypes[name]
+		baseType, ok := attributeType(name)
 		if !ok {
-			return nil, fmt.Errorf("Unknown storage attribute:  %s", name)
+			return nil, unknownAttributeError(
+				"Unknown storage attribute:  %s", name)
 		}
 		switch {
 		case baseType == boolType:
@@ -45,3 +48,84 @@ func UnmarshalOfferMap(mapJSON json.RawMessage) (
 	}
 	return ret, nil
 }
+
+// CreateAttributeOfferFromTypedValue creates an offer from a string:  "true"
+// or "false" for booleans, a single value or "min-max" for integers, and a
+// comma-separated list for strings.
+func CreateAttributeOfferFromTypedValue(name, val string) (Offer, error) {
+	valType, ok := attributeType(name)
+	if !ok {
+		return nil, unknownAttributeError(
+			"Unrecognized storage attribute:  %s", name)
+	}
+	switch valType {
+	case boolType:
+		v, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, fmt.Errorf("Storage attribute value (%s)"+
+				" doesn't match the specified type (%s)!", val, valType)
+		}
+		return NewBoolOffer(v), nil
+	case intType:
+		bounds := strings.SplitN(val, "-", 2)
+		min, err := strconv.ParseInt(bounds[0], 10, 0)
+		if err != nil {
+			return nil, fmt.Errorf("Storage attribute value (%s)"+
+				" doesn't match the specified type (%s)!", val, valType)
+		}
+		max := min
+		if len(bounds) == 2 {
+			if max, err = strconv.ParseInt(bounds[1], 10, 0); err != nil {
+				return nil, fmt.Errorf("Storage attribute value (%s)"+
+					" doesn't match the specified type (%s)!", val, valType)
+			}
+		}
+		if min > max {
+			return nil, fmt.Errorf("Storage attribute range (%s) is empty!",
+				val)
+		}
+		return NewIntOffer(int(min), int(max)), nil
+	case stringType:
+		return NewStringOffer(strings.Split(val, ",")...), nil
+	default:
+		return nil, fmt.Errorf("Unrecognized type for a storage attribute "+
+			"offer: %s", valType)
+	}
+}
+
+// OfferToTypedValue formats an offer in the form accepted by
+// CreateAttributeOfferFromTypedValue.
+func OfferToTypedValue(offer Offer) string {
+	switch o := offer.(type) {
+	case *boolOffer:
+		return strconv.FormatBool(o.Offer)
+	case *intOffer:
+		if o.Min == o.Max {
+			return strconv.Itoa(o.Min)
+		}
+		return fmt.Sprintf("%d-%d", o.Min, o.Max)
+	case *stringOffer:
+		return strings.Join(o.Offers, ",")
+	default:
+		return fmt.Sprintf("%v", offer)
+	}
+}
+
+// RequestForOffer returns the request that selects the pools making offer:
+// true for a boolean offer of true, and the value of a string offer with a
+// single value.  Other offers don't set pools apart, since a false boolean
+// request matches any boolean offer and a string offer with several values
+// leaves the choice to the storage class, so it returns false for them.
+func RequestForOffer(offer Offer) (Request, bool) {
+	switch o := offer.(type) {
+	case *boolOffer:
+		if o.Offer {
+			return NewBoolRequest(true), true
+		}
+	case *stringOffer:
+		if len(o.Offers) == 1 {
+			return NewStringRequest(o.Offers[0]), true
+		}
+	}
+	return nil, false
+}
@@ -44,9 +44,10 @@ func MarshalRequestMap(requestMap map[string]Request) ([]byte, error) {
 
 func CreateAttributeRequestFromTypedValue(name, val string) (Request, error) {
 	var req Request
-	valType, ok := attrTypes[name]
+	valType, ok := attributeType(name)
 	if !ok {
-		return nil, fmt.Errorf("Unrecognized storage attribute:  %s", name)
+		return nil, unknownAttributeError(
+			"Unrecognized storage attribute:  %s", name)
 	}
 	switch valType {
 	case boolType:
@@ -89,3 +90,18 @@ func CreateBackendStoragePoolsMapFromEncodedString(
 	}
 	return backendPoolsMap, nil
 }
+
+// CreateSelectorFromEncodedString parses a pool label selector of the form
+// "key1=value1,key2=value2".
+func CreateSelectorFromEncodedString(arg string) (map[string]string, error) {
+	selector := make(map[string]string)
+	for _, label := range strings.Split(arg, ",") {
+		vals := strings.SplitN(label, "=", 2)
+		if len(vals) != 2 || vals[0] == "" {
+			return nil, fmt.Errorf("The encoded selector string does not " +
+				"have the right format!")
+		}
+		selector[vals[0]] = vals[1]
+	}
+	return selector, nil
+}
@@ -0,0 +1,200 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package storage_attribute
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Schema describes a storage attribute:  its type, the values storage
+// classes may request, and the drivers whose pools offer it.
+type Schema struct {
+	Name string               `json:"name"`
+	Type StorageAttributeType `json:"type"`
+	// Values, if set, are the only values a string attribute may take.
+	Values []string `json:"values,omitempty"`
+	// Drivers, if set, are the only built-in drivers whose pools can
+	// provide the attribute; otherwise, every driver's pools can.  External
+	// drivers may offer any attribute.
+	Drivers []string `json:"drivers,omitempty"`
+	// Testing attributes are only offered by the fake driver.
+	Testing bool `json:"testing,omitempty"`
+}
+
+var ontapDrivers = []string{"ontap-nas", "ontap-san"}
+
+var schemas = map[string]*Schema{
+	IOPS:             {Type: intType, Drivers: []string{"solidfire-san"}},
+	Snapshots:        {Type: boolType},
+	Encryption:       {Type: boolType, Drivers: ontapDrivers},
+	Deduplication:    {Type: boolType, Drivers: ontapDrivers},
+	Compression:      {Type: boolType, Drivers: ontapDrivers},
+	NVMe:             {Type: boolType},
+	ProvisioningType: {Type: stringType, Values: []string{Thick, Thin}},
+	BackendType:      {Type: stringType},
+	Media:            {Type: stringType, Values: []string{HDD, Hybrid, SSD}},
+	RecoveryTest:     {Type: boolType, Testing: true},
+	UniqueOptions:    {Type: stringType, Testing: true},
+	TestingAttribute: {Type: boolType, Testing: true},
+	NonexistentBool:  {Type: boolType, Testing: true},
+}
+
+func init() {
+	for name, schema := range schemas {
+		schema.Name = name
+	}
+}
+
+// GetSchema returns the schema of the named attribute, or nil if there is
+// no such attribute.
+func GetSchema(name string) *Schema {
+	return schemas[name]
+}
+
+// ListSchemas returns the schemas of the attributes storage classes may
+// request, by name, leaving out the attributes only used in testing.
+func ListSchemas() []*Schema {
+	names := make([]string, 0, len(schemas))
+	for name, schema := range schemas {
+		if !schema.Testing {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	ret := make([]*Schema, 0, len(names))
+	for _, name := range names {
+		ret = append(ret, schemas[name])
+	}
+	return ret
+}
+
+// attributeType returns the type of the named attribute.
+func attributeType(name string) (StorageAttributeType, bool) {
+	schema, ok := schemas[name]
+	if !ok {
+		return "", false
+	}
+	return schema.Type, true
+}
+
+// unknownAttributeError reports an unknown attribute, suggesting the
+// attribute that was probably meant.
+func unknownAttributeError(format, name string) error {
+	names := make([]string, 0, len(schemas))
+	for known := range schemas {
+		names = append(names, known)
+	}
+	if suggestion := closest(name, names); suggestion != "" {
+		return fmt.Errorf(format+" (did you mean %s?)", name, suggestion)
+	}
+	return fmt.Errorf(format, name)
+}
+
+// ValidateRequests checks a storage class's attribute requests against
+// their schemas, returning the problems found, in attribute order:
+// attributes that don't exist, requests of the wrong type, IOPS that
+// aren't positive, and values an attribute doesn't take.
+func ValidateRequests(requests map[string]Request) []string {
+	names := make([]string, 0, len(requests))
+	for name := range requests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	problems := make([]string, 0)
+	for _, name := range names {
+		if problem := validateRequest(name, requests[name]); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+	return problems
+}
+
+func validateRequest(name string, request Request) string {
+	schema, ok := schemas[name]
+	if !ok {
+		return unknownAttributeError("Unknown storage attribute %s",
+			name).Error()
+	}
+	if request.GetType() != schema.Type {
+		return fmt.Sprintf("Storage attribute %s is a %s, not a %s.", name,
+			schema.Type, request.GetType())
+	}
+	switch schema.Type {
+	case intType:
+		if v, _ := request.Value().(int); v <= 0 {
+			return fmt.Sprintf("Storage attribute %s must be positive; "+
+				"got %d.", name, v)
+		}
+	case stringType:
+		if len(schema.Values) == 0 {
+			break
+		}
+		v, _ := request.Value().(string)
+		for _, allowed := range schema.Values {
+			if v == allowed {
+				return ""
+			}
+		}
+		problem := fmt.Sprintf("Storage attribute %s doesn't take the value "+
+			"%q; it must be one of %s.", name, v,
+			strings.Join(schema.Values, ", "))
+		if suggestion := closest(v, schema.Values); suggestion != "" {
+			problem += fmt.Sprintf("  Did you mean %s?", suggestion)
+		}
+		return problem
+	}
+	return ""
+}
+
+// closest returns the candidate nearest s, ignoring case, if it is within
+// two edits of it, or "" if none is.  Ties go to the first candidate in
+// lexical order.
+func closest(s string, candidates []string) string {
+	best, bestDistance := "", 3
+	for _, candidate := range candidates {
+		d := editDistance(strings.ToLower(s), strings.ToLower(candidate))
+		if d < bestDistance || (d == bestDistance && best != "" &&
+			candidate < best) {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the number of insertions, deletions, substitutions,
+// and transpositions of adjacent characters that turn a into b.
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = minInt(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = minInt(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+func minInt(values ...int) int {
+	ret := values[0]
+	for _, v := range values[1:] {
+		if v < ret {
+			ret = v
+		}
+	}
+	return ret
+}
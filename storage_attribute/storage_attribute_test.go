@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"log"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -97,3 +98,110 @@ func TestUnmarshalRequest(t *testing.T) {
 			targetRequestMap)
 	}
 }
+
+func TestCreateAttributeOfferFromTypedValue(t *testing.T) {
+	for _, test := range []struct {
+		name, val string
+		expected  Offer
+	}{
+		{Snapshots, "true", NewBoolOffer(true)},
+		{IOPS, "100", NewIntOffer(100, 100)},
+		{IOPS, "100-5000", NewIntOffer(100, 5000)},
+		{Media, "ssd,hybrid", NewStringOffer("ssd", "hybrid")},
+	} {
+		offer, err := CreateAttributeOfferFromTypedValue(test.name, test.val)
+		if err != nil {
+			t.Errorf("Unable to create offer %s=%s:  %v", test.name,
+				test.val, err)
+			continue
+		}
+		if !reflect.DeepEqual(offer, test.expected) {
+			t.Errorf("%s=%s:  expected %v; got %v.", test.name, test.val,
+				test.expected, offer)
+		}
+		if val := OfferToTypedValue(offer); val != test.val {
+			t.Errorf("%s=%s:  formatted as %s.", test.name, test.val, val)
+		}
+	}
+	for _, test := range []struct{ name, val string }{
+		{"unknown", "true"},
+		{Snapshots, "maybe"},
+		{IOPS, "high"},
+		{IOPS, "5000-100"},
+	} {
+		if _, err := CreateAttributeOfferFromTypedValue(test.name,
+			test.val); err == nil {
+			t.Errorf("Expected an error creating offer %s=%s.", test.name,
+				test.val)
+		}
+	}
+}
+
+func TestValidateRequests(t *testing.T) {
+	for i, test := range []struct {
+		requests map[string]Request
+		expected []string
+	}{
+		{
+			requests: map[string]Request{
+				Media:            NewStringRequest(SSD),
+				ProvisioningType: NewStringRequest(Thin),
+				IOPS:             NewIntRequest(1000),
+				Snapshots:        NewBoolRequest(true),
+				BackendType:      NewStringRequest("ontap-nas"),
+			},
+			expected: []string{},
+		},
+		{
+			requests: map[string]Request{
+				Media: NewStringRequest("hybird"),
+			},
+			expected: []string{"Storage attribute media doesn't take the " +
+				"value \"hybird\"; it must be one of hdd, hybrid, ssd.  Did " +
+				"you mean hybrid?"},
+		},
+		{
+			requests: map[string]Request{
+				"meda":    NewStringRequest(SSD),
+				IOPS:      NewIntRequest(0),
+				Snapshots: NewStringRequest("true"),
+			},
+			expected: []string{
+				"Storage attribute IOPS must be positive; got 0.",
+				"Unknown storage attribute meda (did you mean media?)",
+				"Storage attribute snapshots is a bool, not a string.",
+			},
+		},
+	} {
+		if problems := ValidateRequests(test.requests); !reflect.DeepEqual(
+			problems, test.expected) {
+			t.Errorf("Test case %d:  expected %v; got %v.", i,
+				test.expected, problems)
+		}
+	}
+}
+
+func TestUnknownAttributeSuggestion(t *testing.T) {
+	_, err := CreateAttributeRequestFromTypedValue("snapshot", "true")
+	if err == nil || !strings.Contains(err.Error(), "did you mean snapshots?") {
+		t.Errorf("Expected a suggestion for a misspelled attribute; got %v.",
+			err)
+	}
+	_, err = CreateAttributeRequestFromTypedValue("throughput", "true")
+	if err == nil || strings.Contains(err.Error(), "did you mean") {
+		t.Errorf("Expected an error without a suggestion; got %v.", err)
+	}
+}
+
+func TestListSchemas(t *testing.T) {
+	schemas := ListSchemas()
+	names := make([]string, 0, len(schemas))
+	for _, schema := range schemas {
+		names = append(names, schema.Name)
+	}
+	expected := []string{IOPS, BackendType, Compression, Deduplication,
+		Encryption, Media, NVMe, ProvisioningType, Snapshots}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected schemas %v; got %v.", expected, names)
+	}
+}
@@ -5,15 +5,26 @@ package storage_class
 import (
 	"encoding/json"
 
+	"github.com/netapp/trident/config"
 	"github.com/netapp/trident/storage_attribute"
 )
 
 func (c *Config) UnmarshalJSON(data []byte) error {
 	var tmp struct {
-		Version             string              `json:"version"`
-		Name                string              `json:"name"`
-		Attributes          json.RawMessage     `json:"attributes,omitempty"`
-		BackendStoragePools map[string][]string `json:"requiredStorage,omitempty"`
+		Version             string               `json:"version"`
+		Name                string               `json:"name"`
+		Attributes          json.RawMessage      `json:"attributes,omitempty"`
+		BackendStoragePools map[string][]string  `json:"requiredStorage,omitempty"`
+		MinIOPS             int                  `json:"minIOPS,omitempty"`
+		MaxIOPS             int                  `json:"maxIOPS,omitempty"`
+		BurstIOPS           int                  `json:"burstIOPS,omitempty"`
+		Selector            map[string]string    `json:"selector,omitempty"`
+		ReclaimPolicy       config.ReclaimPolicy `json:"reclaimPolicy,omitempty"`
+		RequireCHAP         bool                 `json:"requireCHAP,omitempty"`
+		MountOptions        string               `json:"mountOptions,omitempty"`
+		SnapshotReserve     string               `json:"snapshotReserve,omitempty"`
+		SnapshotDir         string               `json:"snapshotDirectory,omitempty"`
+		BackendPreference   []string             `json:"backendPreference,omitempty"`
 	}
 	err := json.Unmarshal(data, &tmp)
 	if err != nil {
@@ -23,19 +34,49 @@ func (c *Config) UnmarshalJSON(data []byte) error {
 	c.Name = tmp.Name
 	c.Attributes, err = storage_attribute.UnmarshalRequestMap(tmp.Attributes)
 	c.BackendStoragePools = tmp.BackendStoragePools
+	c.MinIOPS = tmp.MinIOPS
+	c.MaxIOPS = tmp.MaxIOPS
+	c.BurstIOPS = tmp.BurstIOPS
+	c.Selector = tmp.Selector
+	c.ReclaimPolicy = tmp.ReclaimPolicy
+	c.RequireCHAP = tmp.RequireCHAP
+	c.MountOptions = tmp.MountOptions
+	c.SnapshotReserve = tmp.SnapshotReserve
+	c.SnapshotDir = tmp.SnapshotDir
+	c.BackendPreference = tmp.BackendPreference
 	return err
 }
 
 func (c *Config) MarshalJSON() ([]byte, error) {
 	var tmp struct {
-		Version             string              `json:"version"`
-		Name                string              `json:"name"`
-		Attributes          json.RawMessage     `json:"attributes,omitempty"`
-		BackendStoragePools map[string][]string `json:"requiredStorage,omitempty"`
+		Version             string               `json:"version"`
+		Name                string               `json:"name"`
+		Attributes          json.RawMessage      `json:"attributes,omitempty"`
+		BackendStoragePools map[string][]string  `json:"requiredStorage,omitempty"`
+		MinIOPS             int                  `json:"minIOPS,omitempty"`
+		MaxIOPS             int                  `json:"maxIOPS,omitempty"`
+		BurstIOPS           int                  `json:"burstIOPS,omitempty"`
+		Selector            map[string]string    `json:"selector,omitempty"`
+		ReclaimPolicy       config.ReclaimPolicy `json:"reclaimPolicy,omitempty"`
+		RequireCHAP         bool                 `json:"requireCHAP,omitempty"`
+		MountOptions        string               `json:"mountOptions,omitempty"`
+		SnapshotReserve     string               `json:"snapshotReserve,omitempty"`
+		SnapshotDir         string               `json:"snapshotDirectory,omitempty"`
+		BackendPreference   []string             `json:"backendPreference,omitempty"`
 	}
 	tmp.Version = c.Version
 	tmp.Name = c.Name
 	tmp.BackendStoragePools = c.BackendStoragePools
+	tmp.MinIOPS = c.MinIOPS
+	tmp.MaxIOPS = c.MaxIOPS
+	tmp.BurstIOPS = c.BurstIOPS
+	tmp.Selector = c.Selector
+	tmp.ReclaimPolicy = c.ReclaimPolicy
+	tmp.RequireCHAP = c.RequireCHAP
+	tmp.MountOptions = c.MountOptions
+	tmp.SnapshotReserve = c.SnapshotReserve
+	tmp.SnapshotDir = c.SnapshotDir
+	tmp.BackendPreference = c.BackendPreference
 	attrs, err := storage_attribute.MarshalRequestMap(c.Attributes)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,144 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package storage_class
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/netapp/trident/storage"
+	sa "github.com/netapp/trident/storage_attribute"
+)
+
+// Recommendation is a suggested storage class for a backend's pools.
+type Recommendation struct {
+	Config *Config `json:"config"`
+	// StoragePools are the backend's pools that the class would match.
+	StoragePools []string `json:"storagePools"`
+}
+
+// Recommend suggests a storage class for each distinct set of capabilities
+// among a backend's pools.  Each class requests the attributes that set its
+// pools apart; see storage_attribute.RequestForOffer.  Pools that offer no
+// such attributes are named in the class instead.  The recommendations are
+// sorted by name.
+func Recommend(backend *storage.StorageBackend) []*Recommendation {
+	poolNames := make([]string, 0, len(backend.Storage))
+	for name := range backend.Storage {
+		poolNames = append(poolNames, name)
+	}
+	sort.Strings(poolNames)
+
+	groups := make(map[string]map[string]sa.Request)
+	ungrouped := make([]string, 0)
+	keys := make([]string, 0)
+	for _, poolName := range poolNames {
+		attributes := make(map[string]sa.Request)
+		for name, offer := range backend.Storage[poolName].Attributes {
+			if request, ok := sa.RequestForOffer(offer); ok {
+				attributes[name] = request
+			}
+		}
+		if len(attributes) == 0 {
+			ungrouped = append(ungrouped, poolName)
+			continue
+		}
+		key := attributesKey(attributes)
+		if _, ok := groups[key]; !ok {
+			groups[key] = attributes
+			keys = append(keys, key)
+		}
+	}
+
+	ret := make([]*Recommendation, 0, len(keys)+1)
+	used := make(map[string]bool)
+	for _, key := range keys {
+		attributes := groups[key]
+		sc := New(&Config{
+			Name:       recommendedName(backend.Name, attributes, used),
+			Attributes: attributes,
+		})
+		ret = append(ret, &Recommendation{
+			Config:       sc.config,
+			StoragePools: matchingPools(sc, backend, poolNames),
+		})
+	}
+	if len(ungrouped) > 0 {
+		ret = append(ret, &Recommendation{
+			Config: New(&Config{
+				Name: recommendedName(backend.Name, nil, used),
+				BackendStoragePools: map[string][]string{
+					backend.Name: ungrouped,
+				},
+			}).config,
+			StoragePools: ungrouped,
+		})
+	}
+	sort.Sort(byRecommendationName(ret))
+	return ret
+}
+
+func matchingPools(
+	sc *StorageClass, backend *storage.StorageBackend, poolNames []string,
+) []string {
+	ret := make([]string, 0)
+	for _, poolName := range poolNames {
+		if sc.Matches(backend.Storage[poolName]) {
+			ret = append(ret, poolName)
+		}
+	}
+	return ret
+}
+
+// attributesKey returns a string that identifies a set of attribute
+// requests.
+func attributesKey(attributes map[string]sa.Request) string {
+	parts := make([]string, 0, len(attributes))
+	for name, request := range attributes {
+		parts = append(parts, fmt.Sprintf("%s=%s", name, request))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// recommendedName names a recommended class after its backend and the
+// values it requests, e.g., "backend-ssd-snapshots", adding a suffix if the
+// name is already used.  The backend type is left out, since every pool of
+// a backend shares it.
+func recommendedName(
+	backendName string, attributes map[string]sa.Request,
+	used map[string]bool,
+) string {
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		if name != sa.BackendType {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	parts := []string{backendName}
+	for _, name := range names {
+		switch value := attributes[name].Value().(type) {
+		case bool:
+			parts = append(parts, strings.ToLower(name))
+		default:
+			parts = append(parts, strings.ToLower(fmt.Sprintf("%v", value)))
+		}
+	}
+	base := strings.Join(parts, "-")
+	name := base
+	for i := 2; used[name]; i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	used[name] = true
+	return name
+}
+
+type byRecommendationName []*Recommendation
+
+func (a byRecommendationName) Len() int      { return len(a) }
+func (a byRecommendationName) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a byRecommendationName) Less(i, j int) bool {
+	return a[i].Config.Name < a[j].Config.Name
+}
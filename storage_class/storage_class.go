@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strconv"
+	"strings"
 
 	log "github.com/Sirupsen/logrus"
 
@@ -34,21 +36,78 @@ func NewForConfig(configJSON string) (*StorageClass, error) {
 }
 
 func NewFromPersistent(persistent *StorageClassPersistent) *StorageClass {
-	return New(persistent.Config)
+	sc := New(persistent.Config)
+	sc.pendingDelete = persistent.PendingDelete
+	return sc
 }
 
 func (s *StorageClass) Matches(vc *storage.StoragePool) bool {
+	return s.Mismatch(vc) == ""
+}
+
+// Mismatch explains why a storage pool doesn't satisfy the storage class,
+// or returns "" if it does.  Every attribute that the pool fails to offer
+// is listed.
+func (s *StorageClass) Mismatch(vc *storage.StoragePool) string {
+	if !vc.CanHonorQoS(s.config.MinIOPS, s.config.MaxIOPS) {
+		log.WithFields(log.Fields{
+			"storageClass": s.GetName(),
+			"pool":         vc.Name,
+			"minIOPS":      s.config.MinIOPS,
+			"maxIOPS":      s.config.MaxIOPS,
+		}).Debug("Storage pool cannot honor storage class QoS.")
+		return fmt.Sprintf("Storage pool cannot provide the storage "+
+			"class's IOPS (min %d, max %d).", s.config.MinIOPS,
+			s.config.MaxIOPS)
+	}
+	if s.config.RequireCHAP && !vc.Backend.Chap.IsSet() {
+		return "Backend doesn't use CHAP."
+	}
+	if request, ok := s.config.Attributes[storage_attribute.Snapshots]; ok &&
+		request.Value() == true &&
+		!vc.Backend.Capabilities().SupportsSnapshots {
+		return "Backend's driver can't create snapshots on demand."
+	}
 	if len(s.config.BackendStoragePools) > 0 {
 		if vcList, ok := s.config.BackendStoragePools[vc.Backend.Name]; ok {
 			for _, vcName := range vcList {
 				if vcName == vc.Name {
-					return true
+					return ""
 				}
 			}
 		}
 	}
-	matches := len(s.config.Attributes) > 0
-	for name, request := range s.config.Attributes {
+	// Hosts reach NVMe namespaces differently from other block volumes, so
+	// NVMe pools only satisfy classes that ask for NVMe.
+	if vc.IsNVMe() {
+		if _, ok := s.config.Attributes[storage_attribute.NVMe]; !ok {
+			return "Storage pool serves NVMe namespaces, which the " +
+				"storage class doesn't request."
+		}
+	}
+	if !vc.MatchesLabels(s.config.Selector) {
+		log.WithFields(log.Fields{
+			"storageClass": s.GetName(),
+			"pool":         vc.Name,
+			"selector":     s.config.Selector,
+			"labels":       vc.Labels,
+		}).Debug("Storage pool labels failed to match storage class.")
+		return fmt.Sprintf("Storage pool labels %v don't match the storage "+
+			"class's selector %v.", vc.Labels, s.config.Selector)
+	}
+	if len(s.config.Attributes) == 0 && !s.HasQoS() &&
+		len(s.config.Selector) == 0 && !s.config.RequireCHAP {
+		return "Storage class requests no attributes, labels, IOPS, or " +
+			"CHAP, and doesn't require the storage pool."
+	}
+	names := make([]string, 0, len(s.config.Attributes))
+	for name := range s.config.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	mismatches := make([]string, 0)
+	for _, name := range names {
+		request := s.config.Attributes[name]
 		if vc.Attributes == nil {
 			log.WithFields(log.Fields{
 				"storageClass": s.GetName(),
@@ -56,18 +115,30 @@ func (s *StorageClass) Matches(vc *storage.StoragePool) bool {
 				"attribute":    name,
 			}).Panic("Storage pool attributes are nil")
 		}
-		if offer, ok := vc.Attributes[name]; !ok || !offer.Matches(request) {
-			log.WithFields(log.Fields{
-				"storageClass": s.GetName(),
-				"pool":         vc.Name,
-				"attribute":    name,
-				"found":        ok}).Debug("Attribute for storage " +
-				"pool failed to match storage class.")
-			matches = false
-			break
+		offer, ok := vc.Attributes[name]
+		if ok && offer.Matches(request) {
+			continue
+		}
+		log.WithFields(log.Fields{
+			"storageClass": s.GetName(),
+			"pool":         vc.Name,
+			"attribute":    name,
+			"found":        ok}).Debug("Attribute for storage " +
+			"pool failed to match storage class.")
+		if !ok {
+			mismatches = append(mismatches, fmt.Sprintf(
+				"%s=%s requested, but not offered", name, request))
+		} else {
+			mismatches = append(mismatches, fmt.Sprintf(
+				"%s=%s requested, but %s offered", name, request,
+				storage_attribute.OfferToTypedValue(offer)))
 		}
 	}
-	return matches
+	if len(mismatches) > 0 {
+		return fmt.Sprintf("Storage pool doesn't match the storage class's "+
+			"attributes:  %s.", strings.Join(mismatches, "; "))
+	}
+	return ""
 }
 
 // CheckAndAddBackend iterates through each of the storage pools
@@ -98,6 +169,16 @@ func (s *StorageClass) RemovePoolsForBackend(backend *storage.StorageBackend) {
 	s.pools = newVCList
 }
 
+// ContainsPool returns true if vc satisfies the storage class.
+func (s *StorageClass) ContainsPool(vc *storage.StoragePool) bool {
+	for _, pool := range s.pools {
+		if pool == vc {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *StorageClass) GetVolumes() []*storage.Volume {
 	ret := make([]*storage.Volume, 0)
 	for _, vc := range s.pools {
@@ -117,6 +198,115 @@ func (s *StorageClass) GetAttributes() map[string]storage_attribute.Request {
 	return s.config.Attributes
 }
 
+// HasQoS returns true if the storage class sets any IOPS limit.
+func (s *StorageClass) HasQoS() bool {
+	return s.config.MinIOPS != 0 || s.config.MaxIOPS != 0 ||
+		s.config.BurstIOPS != 0
+}
+
+// ApplyQoSDefaults sets any IOPS limits left unset in volConfig to the
+// storage class's values.
+func (s *StorageClass) ApplyQoSDefaults(volConfig *storage.VolumeConfig) {
+	if volConfig.MinIOPS == 0 {
+		volConfig.MinIOPS = s.config.MinIOPS
+	}
+	if volConfig.MaxIOPS == 0 {
+		volConfig.MaxIOPS = s.config.MaxIOPS
+	}
+	if volConfig.BurstIOPS == 0 {
+		volConfig.BurstIOPS = s.config.BurstIOPS
+	}
+}
+
+// ApplyReclaimPolicy sets volConfig's reclaim policy to the storage class's
+// if it is unset.
+func (s *StorageClass) ApplyReclaimPolicy(volConfig *storage.VolumeConfig) {
+	if volConfig.ReclaimPolicy == config.ReclaimAny {
+		volConfig.ReclaimPolicy = s.config.ReclaimPolicy
+	}
+}
+
+// ApplyMountOptions sets volConfig's mount options to the storage class's
+// if it has none.
+func (s *StorageClass) ApplyMountOptions(volConfig *storage.VolumeConfig) {
+	if volConfig.MountOptions == "" {
+		volConfig.MountOptions = s.config.MountOptions
+	}
+}
+
+// ApplySnapshotDefaults sets volConfig's snapshot reserve and snapshot
+// directory options to the storage class's if they are unset.
+func (s *StorageClass) ApplySnapshotDefaults(volConfig *storage.VolumeConfig) {
+	if volConfig.SnapshotReserve == "" {
+		volConfig.SnapshotReserve = s.config.SnapshotReserve
+	}
+	if volConfig.SnapshotDir == "" {
+		volConfig.SnapshotDir = s.config.SnapshotDir
+	}
+}
+
+// ApplySpaceEfficiency sets volConfig's provisioning type, deduplication,
+// and compression to the values the storage class requests, for those it
+// leaves unset.
+func (s *StorageClass) ApplySpaceEfficiency(volConfig *storage.VolumeConfig) {
+	if req, ok := s.config.Attributes[storage_attribute.ProvisioningType]; ok &&
+		volConfig.ProvisioningType == "" {
+		if provisioningType, ok := req.Value().(string); ok {
+			volConfig.ProvisioningType = provisioningType
+		}
+	}
+	if req, ok := s.config.Attributes[storage_attribute.Deduplication]; ok &&
+		volConfig.Deduplication == "" {
+		if enable, ok := req.Value().(bool); ok {
+			volConfig.Deduplication = strconv.FormatBool(enable)
+		}
+	}
+	if req, ok := s.config.Attributes[storage_attribute.Compression]; ok &&
+		volConfig.Compression == "" {
+		if enable, ok := req.Value().(bool); ok {
+			volConfig.Compression = strconv.FormatBool(enable)
+		}
+	}
+}
+
+// BackendTier returns the tier of a backend in the storage class's
+// backend preference:  1 for the first backend listed, and one more than the
+// number listed for a backend that isn't.  It returns 0 if the class has no
+// preference.
+func (s *StorageClass) BackendTier(backendName string) int {
+	if len(s.config.BackendPreference) == 0 {
+		return 0
+	}
+	for i, name := range s.config.BackendPreference {
+		if name == backendName {
+			return i + 1
+		}
+	}
+	return len(s.config.BackendPreference) + 1
+}
+
+// RequiresEncryption returns true if the storage class requests encrypted
+// volumes.
+func (s *StorageClass) RequiresEncryption() bool {
+	req, ok := s.config.Attributes[storage_attribute.Encryption]
+	if !ok {
+		return false
+	}
+	encrypt, ok := req.Value().(bool)
+	return ok && encrypt
+}
+
+// IsPendingDelete returns whether the class will be deleted once its last
+// volume is.
+func (s *StorageClass) IsPendingDelete() bool {
+	return s.pendingDelete
+}
+
+// SetPendingDelete marks the class to be deleted once its last volume is.
+func (s *StorageClass) SetPendingDelete(pending bool) {
+	s.pendingDelete = pending
+}
+
 func (s *StorageClass) GetName() string {
 	return s.config.Name
 }
@@ -129,7 +319,7 @@ func (s *StorageClass) GetStoragePoolsForProtocol(p config.Protocol) []*storage.
 	ret := make([]*storage.StoragePool, 0, len(s.pools))
 	// TODO:  Change this to work with indices of backends?
 	for _, vc := range s.pools {
-		if p == config.ProtocolAny || vc.Backend.GetProtocol() == p {
+		if p == config.ProtocolAny || vc.GetProtocol() == p {
 			ret = append(ret, vc)
 		}
 	}
@@ -138,8 +328,9 @@ func (s *StorageClass) GetStoragePoolsForProtocol(p config.Protocol) []*storage.
 
 func (s *StorageClass) ConstructExternal() *StorageClassExternal {
 	ret := &StorageClassExternal{
-		Config:       s.config,
-		StoragePools: make(map[string][]string),
+		Config:        s.config,
+		StoragePools:  make(map[string][]string),
+		PendingDelete: s.pendingDelete,
 	}
 	for _, vc := range s.pools {
 		backendName := vc.Backend.Name
@@ -176,7 +367,10 @@ func (s *StorageClassExternal) GetName() string {
 }
 
 func (s *StorageClass) ConstructPersistent() *StorageClassPersistent {
-	ret := &StorageClassPersistent{Config: s.config}
+	ret := &StorageClassPersistent{
+		Config:        s.config,
+		PendingDelete: s.pendingDelete,
+	}
 	for _, list := range ret.Config.BackendStoragePools {
 		sort.Strings(list)
 	}
@@ -3,6 +3,7 @@
 package storage_class
 
 import (
+	"encoding/json"
 	"strings"
 	"testing"
 
@@ -112,6 +113,31 @@ func TestAttributeMatches(t *testing.T) {
 			expectedPools: []string{tu.FastSmall, tu.FastThinOnly,
 				tu.FastUniqueAttr, tu.MediumOverlap},
 		},
+		{
+			// Tests that QoS limits exclude pools that can't provide them
+			name: "QoS",
+			sc: New(&Config{
+				Name: "qos",
+				Attributes: map[string]sa.Request{
+					sa.Snapshots:        sa.NewBoolRequest(true),
+					sa.ProvisioningType: sa.NewStringRequest("thin"),
+				},
+				MinIOPS: 800,
+				MaxIOPS: 1000,
+			}),
+			expectedPools: []string{tu.MediumOverlap},
+		},
+		{
+			// Tests that QoS limits alone are enough to match pools
+			name: "QoS only",
+			sc: New(&Config{
+				Name:    "qos-only",
+				MinIOPS: 2000,
+				MaxIOPS: 5000,
+			}),
+			expectedPools: []string{tu.FastSmall, tu.FastThinOnly,
+				tu.FastUniqueAttr},
+		},
 		// BEGIN Failure tests
 		{
 			// Tests non-existent bool attribute
@@ -304,3 +330,431 @@ func TestSpecificBackends(t *testing.T) {
 		}
 	}
 }
+
+func TestRequiresEncryption(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		attrs    map[string]sa.Request
+		expected bool
+	}{
+		{"Unset", map[string]sa.Request{}, false},
+		{"False", map[string]sa.Request{
+			sa.Encryption: sa.NewBoolRequest(false)}, false},
+		{"True", map[string]sa.Request{
+			sa.Encryption: sa.NewBoolRequest(true)}, true},
+	} {
+		sc := New(&Config{Name: "encryption", Attributes: test.attrs})
+		if sc.RequiresEncryption() != test.expected {
+			t.Errorf("%s:  expected RequiresEncryption to return %t.",
+				test.name, test.expected)
+		}
+	}
+}
+
+func TestPoolLabels(t *testing.T) {
+	mockPools := tu.GetFakePools()
+	configJSON, err := fake.NewFakeStorageDriverConfigJSON("labeled",
+		config.File, map[string]*fake.FakeStoragePool{
+			tu.FastSmall:     mockPools[tu.FastSmall],
+			tu.FastThinOnly:  mockPools[tu.FastThinOnly],
+			tu.SlowSnapshots: mockPools[tu.SlowSnapshots],
+		})
+	if err != nil {
+		t.Fatal("Unable to generate config JSON:  ", err)
+	}
+	var configMap map[string]interface{}
+	if err = json.Unmarshal([]byte(configJSON), &configMap); err != nil {
+		t.Fatal("Unable to unmarshal config JSON:  ", err)
+	}
+	configMap["excludePools"] = []string{tu.SlowSnapshots}
+	configMap["labels"] = map[string]string{"tier": "gold"}
+	configMap["poolLabels"] = map[string]map[string]string{
+		tu.FastThinOnly: {"tier": "silver", "app": "db"},
+	}
+	labeledJSON, err := json.Marshal(configMap)
+	if err != nil {
+		t.Fatal("Unable to marshal config JSON:  ", err)
+	}
+	backend, err := factory.NewStorageBackendForConfig(string(labeledJSON))
+	if err != nil {
+		t.Fatal("Unable to construct backend:  ", err)
+	}
+	if _, ok := backend.Storage[tu.SlowSnapshots]; ok {
+		t.Errorf("Excluded pool %s was not removed.", tu.SlowSnapshots)
+	}
+
+	configMap["includePools"] = []string{"missing"}
+	unknownJSON, _ := json.Marshal(configMap)
+	if _, err = factory.NewStorageBackendForConfig(string(unknownJSON)); err == nil {
+		t.Error("Backend including an unknown pool was created.")
+	}
+
+	for _, test := range []struct {
+		name     string
+		selector map[string]string
+		expected []string
+	}{
+		{"Shared label", map[string]string{"tier": "gold"},
+			[]string{tu.FastSmall}},
+		{"Pool label", map[string]string{"tier": "silver", "app": "db"},
+			[]string{tu.FastThinOnly}},
+		{"No match", map[string]string{"app": "web"}, []string{}},
+	} {
+		sc := New(&Config{Name: "labels", Selector: test.selector})
+		sc.CheckAndAddBackend(backend)
+		matched := sc.GetStoragePoolsForProtocol(config.File)
+		if len(matched) != len(test.expected) {
+			t.Errorf("%s:  expected %d matching pools; got %d.", test.name,
+				len(test.expected), len(matched))
+			continue
+		}
+		for i, pool := range matched {
+			if pool.Name != test.expected[i] {
+				t.Errorf("%s:  unexpected match %s.", test.name, pool.Name)
+			}
+		}
+	}
+}
+
+func TestApplyReclaimPolicy(t *testing.T) {
+	sc := New(&Config{Name: "retain", ReclaimPolicy: config.ReclaimRetain})
+	volConfig := &storage.VolumeConfig{Name: "vol"}
+	sc.ApplyReclaimPolicy(volConfig)
+	if volConfig.ReclaimPolicy != config.ReclaimRetain {
+		t.Errorf("Expected the class's reclaim policy; got %q.",
+			volConfig.ReclaimPolicy)
+	}
+	volConfig.ReclaimPolicy = config.ReclaimDelete
+	sc.ApplyReclaimPolicy(volConfig)
+	if volConfig.ReclaimPolicy != config.ReclaimDelete {
+		t.Errorf("Expected the volume's reclaim policy to be kept; got %q.",
+			volConfig.ReclaimPolicy)
+	}
+
+	scJSON, err := json.Marshal(sc.ConstructExternal().Config)
+	if err != nil {
+		t.Fatal("Unable to marshal storage class:  ", err)
+	}
+	parsed, err := NewForConfig(string(scJSON))
+	if err != nil {
+		t.Fatal("Unable to parse storage class:  ", err)
+	}
+	if parsed.config.ReclaimPolicy != config.ReclaimRetain {
+		t.Errorf("Reclaim policy lost in JSON:  %s", scJSON)
+	}
+}
+
+func TestApplyMountOptions(t *testing.T) {
+	const options = "nfsvers=4.1,hard"
+	sc := New(&Config{Name: "mount", MountOptions: options})
+	volConfig := &storage.VolumeConfig{Name: "vol"}
+	sc.ApplyMountOptions(volConfig)
+	if volConfig.MountOptions != options {
+		t.Errorf("Expected the class's mount options; got %q.",
+			volConfig.MountOptions)
+	}
+	volConfig.MountOptions = "nfsvers=3"
+	sc.ApplyMountOptions(volConfig)
+	if volConfig.MountOptions != "nfsvers=3" {
+		t.Errorf("Expected the volume's mount options to be kept; got %q.",
+			volConfig.MountOptions)
+	}
+
+	scJSON, err := json.Marshal(sc.ConstructExternal().Config)
+	if err != nil {
+		t.Fatal("Unable to marshal storage class:  ", err)
+	}
+	parsed, err := NewForConfig(string(scJSON))
+	if err != nil {
+		t.Fatal("Unable to parse storage class:  ", err)
+	}
+	if parsed.config.MountOptions != options {
+		t.Errorf("Mount options lost in JSON:  %s", scJSON)
+	}
+}
+
+func TestApplySnapshotDefaults(t *testing.T) {
+	sc := New(&Config{
+		Name:            "snapshots",
+		SnapshotReserve: "20",
+		SnapshotDir:     "true",
+	})
+	volConfig := &storage.VolumeConfig{Name: "vol", SnapshotReserve: "0"}
+	sc.ApplySnapshotDefaults(volConfig)
+	if volConfig.SnapshotReserve != "0" {
+		t.Errorf("Expected the volume's snapshot reserve to be kept; got %q.",
+			volConfig.SnapshotReserve)
+	}
+	if volConfig.SnapshotDir != "true" {
+		t.Errorf("Expected the class's snapshot directory; got %q.",
+			volConfig.SnapshotDir)
+	}
+
+	scJSON, err := json.Marshal(sc.ConstructExternal().Config)
+	if err != nil {
+		t.Fatal("Unable to marshal storage class:  ", err)
+	}
+	parsed, err := NewForConfig(string(scJSON))
+	if err != nil {
+		t.Fatal("Unable to parse storage class:  ", err)
+	}
+	if parsed.config.SnapshotReserve != "20" ||
+		parsed.config.SnapshotDir != "true" {
+		t.Errorf("Snapshot defaults lost in JSON:  %s", scJSON)
+	}
+}
+
+func TestApplySpaceEfficiency(t *testing.T) {
+	sc := New(&Config{
+		Name: "efficient",
+		Attributes: map[string]sa.Request{
+			sa.ProvisioningType: sa.NewStringRequest(sa.Thin),
+			sa.Deduplication:    sa.NewBoolRequest(true),
+			sa.Compression:      sa.NewBoolRequest(false),
+		},
+	})
+	volConfig := &storage.VolumeConfig{Name: "vol", Compression: "true"}
+	sc.ApplySpaceEfficiency(volConfig)
+	if volConfig.ProvisioningType != sa.Thin ||
+		volConfig.Deduplication != "true" {
+		t.Errorf("Expected the class's settings; got %s, %s.",
+			volConfig.ProvisioningType, volConfig.Deduplication)
+	}
+	if volConfig.Compression != "true" {
+		t.Errorf("Expected the volume's compression to be kept; got %s.",
+			volConfig.Compression)
+	}
+
+	pool := storage.NewStoragePool(nil, "pool")
+	pool.Attributes[sa.ProvisioningType] = sa.NewStringOffer(sa.Thin)
+	pool.Attributes[sa.Deduplication] = sa.NewBoolOffer(true)
+	if pool.SupportsSpaceEfficiency(volConfig) {
+		t.Error("Pool without compression supports a compressed volume.")
+	}
+	pool.Attributes[sa.Compression] = sa.NewBoolOffer(true)
+	if !pool.SupportsSpaceEfficiency(volConfig) {
+		t.Error("Pool doesn't support the volume's space efficiency.")
+	}
+}
+
+func TestBackendTier(t *testing.T) {
+	sc := New(&Config{
+		Name:              "tiered",
+		BackendPreference: []string{"primary", "secondary"},
+	})
+	for backend, expected := range map[string]int{
+		"primary":   1,
+		"secondary": 2,
+		"other":     3,
+	} {
+		if tier := sc.BackendTier(backend); tier != expected {
+			t.Errorf("Expected tier %d for backend %s; got %d.", expected,
+				backend, tier)
+		}
+	}
+	if tier := New(&Config{Name: "flat"}).BackendTier("primary"); tier != 0 {
+		t.Errorf("Expected no tier without a preference; got %d.", tier)
+	}
+
+	scJSON, err := json.Marshal(sc.ConstructExternal().Config)
+	if err != nil {
+		t.Fatal("Unable to marshal storage class:  ", err)
+	}
+	parsed, err := NewForConfig(string(scJSON))
+	if err != nil {
+		t.Fatal("Unable to parse storage class:  ", err)
+	}
+	if parsed.BackendTier("secondary") != 2 {
+		t.Errorf("Backend preference lost in JSON:  %s", scJSON)
+	}
+}
+
+func TestRecommend(t *testing.T) {
+	mockPools := tu.GetFakePools()
+	configJSON, err := fake.NewFakeStorageDriverConfigJSON("mock",
+		config.File, map[string]*fake.FakeStoragePool{
+			tu.SlowNoSnapshots: mockPools[tu.SlowNoSnapshots],
+			tu.SlowSnapshots:   mockPools[tu.SlowSnapshots],
+			tu.FastSmall:       mockPools[tu.FastSmall],
+			tu.FastThinOnly:    mockPools[tu.FastThinOnly],
+		})
+	if err != nil {
+		t.Fatal("Unable to generate config JSON:  ", err)
+	}
+	backend, err := factory.NewStorageBackendForConfig(configJSON)
+	if err != nil {
+		t.Fatal("Unable to construct backend:  ", err)
+	}
+
+	expected := []struct {
+		name  string
+		pools []string
+	}{
+		{"mock", []string{tu.FastSmall, tu.FastThinOnly, tu.SlowNoSnapshots,
+			tu.SlowSnapshots}},
+		{"mock-snapshots", []string{tu.FastSmall, tu.FastThinOnly,
+			tu.SlowSnapshots}},
+		{"mock-thin-snapshots", []string{tu.FastThinOnly}},
+	}
+	recommendations := Recommend(backend)
+	if len(recommendations) != len(expected) {
+		t.Fatalf("Expected %d recommendations; got %d.", len(expected),
+			len(recommendations))
+	}
+	for i, r := range recommendations {
+		if r.Config.Name != expected[i].name {
+			t.Errorf("Expected recommendation %s; got %s.", expected[i].name,
+				r.Config.Name)
+			continue
+		}
+		if strings.Join(r.StoragePools, ",") !=
+			strings.Join(expected[i].pools, ",") {
+			t.Errorf("%s:  expected pools %v; got %v.", r.Config.Name,
+				expected[i].pools, r.StoragePools)
+		}
+		// The recommendation must survive the round trip through the API.
+		scJSON, err := json.Marshal(r.Config)
+		if err != nil {
+			t.Fatal("Unable to marshal storage class:  ", err)
+		}
+		sc, err := NewForConfig(string(scJSON))
+		if err != nil {
+			t.Fatal("Unable to parse storage class:  ", err)
+		}
+		if added := sc.CheckAndAddBackend(backend); added !=
+			len(expected[i].pools) {
+			t.Errorf("%s:  expected %d pools to match; got %d.",
+				r.Config.Name, len(expected[i].pools), added)
+		}
+	}
+
+	// Pools without distinguishing attributes are named explicitly.
+	bare := &storage.StorageBackend{
+		Name:    "bare",
+		Storage: make(map[string]*storage.StoragePool),
+	}
+	bare.AddStoragePool(storage.NewStoragePool(bare, "pool1"))
+	recommendations = Recommend(bare)
+	if len(recommendations) != 1 {
+		t.Fatalf("Expected one recommendation; got %d.",
+			len(recommendations))
+	}
+	pools := recommendations[0].Config.BackendStoragePools["bare"]
+	if len(pools) != 1 || pools[0] != "pool1" {
+		t.Errorf("Expected the class to require pool1; got %v.", pools)
+	}
+}
+
+func TestMismatch(t *testing.T) {
+	mockPools := tu.GetFakePools()
+	configJSON, err := fake.NewFakeStorageDriverConfigJSON("mismatch",
+		config.File, map[string]*fake.FakeStoragePool{
+			tu.SlowNoSnapshots: mockPools[tu.SlowNoSnapshots],
+		})
+	if err != nil {
+		t.Fatal("Unable to generate config JSON:  ", err)
+	}
+	backend, err := factory.NewStorageBackendForConfig(configJSON)
+	if err != nil {
+		t.Fatal("Unable to construct backend:  ", err)
+	}
+	pool := backend.Storage[tu.SlowNoSnapshots]
+	for _, test := range []struct {
+		name     string
+		sc       *StorageClass
+		expected []string
+	}{
+		{"Match", New(&Config{
+			Name: "match",
+			Attributes: map[string]sa.Request{
+				sa.ProvisioningType: sa.NewStringRequest("thin"),
+			},
+		}), nil},
+		{"Attributes", New(&Config{
+			Name: "attributes",
+			Attributes: map[string]sa.Request{
+				sa.Snapshots: sa.NewBoolRequest(true),
+				sa.Media:     sa.NewStringRequest("ssd"),
+			},
+		}), []string{"media=ssd requested, but not offered",
+			"snapshots=true requested, but false offered"}},
+		{"QoS", New(&Config{Name: "qos", MinIOPS: 500}),
+			[]string{"IOPS"}},
+		{"CHAP", New(&Config{Name: "chap", RequireCHAP: true}),
+			[]string{"doesn't use CHAP"}},
+		{"Empty", New(&Config{Name: "empty"}),
+			[]string{"requests no attributes"}},
+	} {
+		mismatch := test.sc.Mismatch(pool)
+		if (mismatch == "") != (len(test.expected) == 0) {
+			t.Errorf("%s:  unexpected mismatch %q.", test.name, mismatch)
+		}
+		for _, expected := range test.expected {
+			if !strings.Contains(mismatch, expected) {
+				t.Errorf("%s:  expected %q in %q.", test.name, expected,
+					mismatch)
+			}
+		}
+		if test.sc.Matches(pool) != (mismatch == "") {
+			t.Errorf("%s:  Matches disagrees with Mismatch.", test.name)
+		}
+	}
+}
+
+// limitedDriver wraps a driver, hiding its optional interfaces, and reports
+// fixed capabilities.
+type limitedDriver struct {
+	storage.StorageDriver
+	capabilities storage.Capabilities
+}
+
+func (d *limitedDriver) GetCapabilities() storage.Capabilities {
+	return d.capabilities
+}
+
+func TestCapabilities(t *testing.T) {
+	mockPools := tu.GetFakePools()
+	configJSON, err := fake.NewFakeStorageDriverConfigJSON("capable",
+		config.File, map[string]*fake.FakeStoragePool{
+			tu.SlowSnapshots: mockPools[tu.SlowSnapshots],
+		})
+	if err != nil {
+		t.Fatal("Unable to generate config JSON:  ", err)
+	}
+	backend, err := factory.NewStorageBackendForConfig(configJSON)
+	if err != nil {
+		t.Fatal("Unable to construct backend:  ", err)
+	}
+	if !backend.ConstructExternal().Capabilities.SupportsSnapshots {
+		t.Error("Expected the fake driver to support snapshots.")
+	}
+	snapshots := map[string]sa.Request{sa.Snapshots: sa.NewBoolRequest(true)}
+	sc := New(&Config{Name: "snapshots", Attributes: snapshots})
+	if added := sc.CheckAndAddBackend(backend); added != 1 {
+		t.Errorf("Expected the snapshot pool to match; %d matched.", added)
+	}
+
+	backend.Driver = &limitedDriver{
+		StorageDriver: backend.Driver,
+		capabilities: storage.Capabilities{
+			SupportsClones: true,
+			MaxVolumeSize:  1024 * 1024 * 1024,
+		},
+	}
+	capabilities := backend.ConstructExternal().Capabilities
+	if capabilities.SupportsSnapshots || !capabilities.SupportsClones {
+		t.Errorf("Unexpected capabilities %+v.", capabilities)
+	}
+	sc = New(&Config{Name: "snapshots", Attributes: snapshots})
+	if added := sc.CheckAndAddBackend(backend); added != 0 {
+		t.Errorf("Backend without snapshots matched %d pools.", added)
+	}
+	if err = backend.CheckLimits(
+		&storage.VolumeConfig{Size: "1073741824"}); err != nil {
+		t.Error("Volume within the maximum size was rejected:  ", err)
+	}
+	if err = backend.CheckLimits(
+		&storage.VolumeConfig{Size: "2147483648"}); err == nil {
+		t.Error("Volume over the maximum size was accepted.")
+	}
+}
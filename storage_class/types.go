@@ -3,6 +3,7 @@
 package storage_class
 
 import (
+	"github.com/netapp/trident/config"
 	"github.com/netapp/trident/storage"
 	"github.com/netapp/trident/storage_attribute"
 )
@@ -10,6 +11,9 @@ import (
 type StorageClass struct {
 	config *Config
 	pools  []*storage.StoragePool
+	// pendingDelete is set when the class was deleted while volumes still
+	// used it; it's deleted once the last of them is.
+	pendingDelete bool
 }
 
 type Config struct {
@@ -19,11 +23,40 @@ type Config struct {
 	Name                string                               `json:"name"`
 	Attributes          map[string]storage_attribute.Request `json:"attributes,omitempty"`
 	BackendStoragePools map[string][]string                  `json:"requiredStorage,omitempty"`
+	// Default IOPS limits for volumes in this class.  Only pools that can
+	// honor them match the class.
+	MinIOPS   int `json:"minIOPS,omitempty"`
+	MaxIOPS   int `json:"maxIOPS,omitempty"`
+	BurstIOPS int `json:"burstIOPS,omitempty"`
+	// Selector restricts the class to pools with all of these labels.
+	Selector map[string]string `json:"selector,omitempty"`
+	// ReclaimPolicy is the default reclaim policy for volumes in this
+	// class.
+	ReclaimPolicy config.ReclaimPolicy `json:"reclaimPolicy,omitempty"`
+	// RequireCHAP restricts the class to backends that use CHAP.
+	RequireCHAP bool `json:"requireCHAP,omitempty"`
+	// MountOptions are the default mount options for volumes in this
+	// class.
+	MountOptions string `json:"mountOptions,omitempty"`
+	// SnapshotReserve and SnapshotDir are the default snapshot options for
+	// volumes in this class.
+	SnapshotReserve string `json:"snapshotReserve,omitempty"`
+	SnapshotDir     string `json:"snapshotDirectory,omitempty"`
+	// BackendPreference orders the backends that volumes in this class are
+	// placed on:  each is tried in turn, and backends it doesn't list are
+	// tried last.
+	BackendPreference []string `json:"backendPreference,omitempty"`
 }
 
 type StorageClassExternal struct {
 	Config       *Config
 	StoragePools map[string][]string `json:"storage"` // Backend -> list of StoragePools
+	// NonconformingVolumes are the class's volumes whose pools no longer
+	// match it, e.g., after the class was updated.
+	NonconformingVolumes []string `json:"nonconformingVolumes,omitempty"`
+	// PendingDelete is set when the class will be deleted once its last
+	// volume is.
+	PendingDelete bool `json:"pendingDelete,omitempty"`
 }
 
 // StorageClassPersistent contains the minimal information needed to persist
@@ -31,5 +64,6 @@ type StorageClassExternal struct {
 // struct; it also avoids overloading the semantics of Config and is
 // consistent with StorageBackendExternal.
 type StorageClassPersistent struct {
-	Config *Config `json:"config"`
+	Config        *Config `json:"config"`
+	PendingDelete bool    `json:"pendingDelete,omitempty"`
 }
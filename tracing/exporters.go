@@ -0,0 +1,153 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	/* Exporters */
+	ExporterLog    = "log"
+	ExporterZipkin = "zipkin"
+
+	// zipkinQueueSize is the number of finished spans held for the Zipkin
+	// exporter; spans are dropped while it is full.
+	zipkinQueueSize = 1000
+	// zipkinBatchSize is the most spans posted at once.
+	zipkinBatchSize = 100
+)
+
+type logExporter struct{}
+
+// NewLogExporter returns an Exporter that logs each span at debug level,
+// for when no tracing system is available.
+func NewLogExporter() Exporter {
+	return &logExporter{}
+}
+
+func (e *logExporter) Export(span *Span) {
+	fields := log.Fields{
+		"traceID":  span.TraceID,
+		"spanID":   span.ID,
+		"parentID": span.ParentID,
+		"duration": span.Duration,
+	}
+	for k, v := range span.Tags {
+		fields["tag."+k] = v
+	}
+	log.WithFields(fields).Debugf("Finished span %s.", span.Name)
+}
+
+// zipkinSpan is a span in Zipkin's v2 JSON format, which OpenTracing and
+// OpenCensus collectors, such as Zipkin and Jaeger, accept.
+type zipkinSpan struct {
+	TraceID       string            `json:"traceId"`
+	ID            string            `json:"id"`
+	ParentID      string            `json:"parentId,omitempty"`
+	Name          string            `json:"name"`
+	Timestamp     int64             `json:"timestamp"`
+	Duration      int64             `json:"duration"`
+	LocalEndpoint zipkinEndpoint    `json:"localEndpoint"`
+	Tags          map[string]string `json:"tags,omitempty"`
+}
+
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+}
+
+type zipkinExporter struct {
+	url         string
+	serviceName string
+	client      *http.Client
+	spans       chan *zipkinSpan
+}
+
+// NewZipkinExporter returns an Exporter that posts spans in batches, once
+// per interval, to a Zipkin-compatible collector's spans endpoint, e.g.,
+// http://zipkin:9411/api/v2/spans.
+func NewZipkinExporter(
+	url, serviceName string, interval, timeout time.Duration,
+) Exporter {
+	e := &zipkinExporter{
+		url:         url,
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: timeout},
+		spans:       make(chan *zipkinSpan, zipkinQueueSize),
+	}
+	go func() {
+		for range time.Tick(interval) {
+			e.flush()
+		}
+	}()
+	return e
+}
+
+func (e *zipkinExporter) Export(span *Span) {
+	zs := &zipkinSpan{
+		TraceID:       span.TraceID,
+		ID:            span.ID,
+		ParentID:      span.ParentID,
+		Name:          span.Name,
+		Timestamp:     span.Start.UnixNano() / int64(time.Microsecond),
+		Duration:      int64(span.Duration / time.Microsecond),
+		LocalEndpoint: zipkinEndpoint{e.serviceName},
+		Tags:          span.Tags,
+	}
+	select {
+	case e.spans <- zs:
+	default:
+		log.WithFields(log.Fields{
+			"span": span.Name,
+		}).Debug("Tracing queue full; dropped span.")
+	}
+}
+
+// flush posts the queued spans.
+func (e *zipkinExporter) flush() {
+	for {
+		batch := make([]*zipkinSpan, 0, zipkinBatchSize)
+	collect:
+		for len(batch) < zipkinBatchSize {
+			select {
+			case zs := <-e.spans:
+				batch = append(batch, zs)
+			default:
+				break collect
+			}
+		}
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.post(batch); err != nil {
+			log.WithFields(log.Fields{
+				"url":   e.url,
+				"spans": len(batch),
+			}).Warnf("Unable to export spans:  %v", err)
+			return
+		}
+	}
+}
+
+func (e *zipkinExporter) post(batch []*zipkinSpan) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Post(e.url, "application/json",
+		bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Collector returned %s.", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+// Package tracing records spans for orchestrator operations, persistent
+// store requests, and driver calls, so that the time a request takes can be
+// attributed to etcd, a storage system's API, or Trident itself.  Spans are
+// passed to an Exporter; none are recorded unless one is set.
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	/* Components, recorded in each span's component tag */
+	Core            = "core"
+	PersistentStore = "persistent_store"
+	Driver          = "driver"
+
+	// ComponentTag names the layer that recorded a span.
+	ComponentTag = "component"
+	// ErrorTag holds the error a span's operation returned, if any.
+	ErrorTag = "error"
+)
+
+// Span is a timed operation within a trace.  Spans started from a context
+// holding another span are its children and share its trace.
+type Span struct {
+	TraceID  string            `json:"traceId"`
+	ID       string            `json:"id"`
+	ParentID string            `json:"parentId,omitempty"`
+	Name     string            `json:"name"`
+	Start    time.Time         `json:"start"`
+	Duration time.Duration     `json:"duration"`
+	Tags     map[string]string `json:"tags,omitempty"`
+
+	exporter Exporter
+	mutex    sync.Mutex
+	finished bool
+}
+
+// Exporter sends finished spans to a tracing system.  Export must not
+// block.
+type Exporter interface {
+	Export(span *Span)
+}
+
+var (
+	exporterMutex sync.RWMutex
+	exporter      Exporter
+)
+
+// SetExporter sets the exporter finished spans are sent to.  A nil
+// exporter disables tracing.
+func SetExporter(e Exporter) {
+	exporterMutex.Lock()
+	defer exporterMutex.Unlock()
+	exporter = e
+}
+
+func getExporter() Exporter {
+	exporterMutex.RLock()
+	defer exporterMutex.RUnlock()
+	return exporter
+}
+
+type spanKey struct{}
+
+// parent is the span a context holds, or the remote span of a trace
+// started by a client.
+type parent struct {
+	traceID string
+	spanID  string
+}
+
+// ContextWithRemoteParent returns a context whose spans join a trace
+// started outside Trident, e.g., by a REST client that sent B3 headers.
+func ContextWithRemoteParent(
+	ctx context.Context, traceID, spanID string,
+) context.Context {
+	if traceID == "" || spanID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, &parent{traceID, spanID})
+}
+
+// Detach returns a background context holding any span ctx holds, for work
+// that outlives ctx's cancellation but belongs to its trace.
+func Detach(ctx context.Context) context.Context {
+	if p := parentFromContext(ctx); p != nil {
+		return context.WithValue(context.Background(), spanKey{}, p)
+	}
+	return context.Background()
+}
+
+func parentFromContext(ctx context.Context) *parent {
+	p, _ := ctx.Value(spanKey{}).(*parent)
+	return p
+}
+
+// StartSpan starts a span, named for an operation, that is a child of any
+// span ctx holds or else starts a trace.  The returned context holds the
+// new span.  If tracing is disabled, ctx and a nil span, on which all
+// methods are no-ops, are returned.
+func StartSpan(
+	ctx context.Context, component, name string, tags map[string]string,
+) (context.Context, *Span) {
+	return startSpan(ctx, component, name, tags, false)
+}
+
+// StartChildSpan starts a span as StartSpan does, but only if ctx holds a
+// span, so that operations outside a traced request, e.g., at bootstrap,
+// aren't recorded as traces of their own.
+func StartChildSpan(
+	ctx context.Context, component, name string, tags map[string]string,
+) (context.Context, *Span) {
+	return startSpan(ctx, component, name, tags, true)
+}
+
+func startSpan(
+	ctx context.Context, component, name string, tags map[string]string,
+	childOnly bool,
+) (context.Context, *Span) {
+	e := getExporter()
+	if e == nil {
+		return ctx, nil
+	}
+	p := parentFromContext(ctx)
+	if p == nil && childOnly {
+		return ctx, nil
+	}
+	span := &Span{
+		ID:       newID(),
+		Name:     name,
+		Start:    time.Now(),
+		Tags:     make(map[string]string, len(tags)+1),
+		exporter: e,
+	}
+	if p != nil {
+		span.TraceID = p.traceID
+		span.ParentID = p.spanID
+	} else {
+		span.TraceID = newID()
+	}
+	for k, v := range tags {
+		span.Tags[k] = v
+	}
+	span.Tags[ComponentTag] = component
+	return context.WithValue(ctx, spanKey{}, &parent{span.TraceID, span.ID}),
+		span
+}
+
+// SetTag records a value with the span, e.g., the name of the backend an
+// operation chose.
+func (s *Span) SetTag(key, value string) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.finished {
+		s.Tags[key] = value
+	}
+}
+
+// Finish ends the span, recording err, if any, and exports it.  Only the
+// first call has any effect.
+func (s *Span) Finish(err error) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	if s.finished {
+		s.mutex.Unlock()
+		return
+	}
+	s.finished = true
+	s.Duration = time.Since(s.Start)
+	if err != nil {
+		s.Tags[ErrorTag] = err.Error()
+	}
+	s.mutex.Unlock()
+	s.exporter.Export(s)
+}
+
+// newID returns a random 64-bit ID, hex-encoded, as Zipkin expects.
+func newID() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,125 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package tracing
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+type recorder struct {
+	mutex sync.Mutex
+	spans []*Span
+}
+
+func (r *recorder) Export(span *Span) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+func TestSpans(t *testing.T) {
+	defer SetExporter(nil)
+
+	// Nothing is recorded while tracing is disabled.
+	SetExporter(nil)
+	ctx, span := StartSpan(context.Background(), Core, "disabled", nil)
+	if span != nil || ctx != context.Background() {
+		t.Error("Span started with tracing disabled.")
+	}
+	span.SetTag("key", "value")
+	span.Finish(nil)
+
+	r := &recorder{}
+	SetExporter(r)
+	if _, span = StartChildSpan(context.Background(), Driver, "orphan",
+		nil); span != nil {
+		t.Error("Child span started without a parent.")
+	}
+	ctx, root := StartSpan(context.Background(), Core, "root",
+		map[string]string{"volume": "vol1"})
+	_, child := StartChildSpan(ctx, PersistentStore, "child", nil)
+	child.Finish(fmt.Errorf("failed"))
+	_, detached := StartChildSpan(Detach(ctx), Driver, "detached", nil)
+	detached.Finish(nil)
+	root.SetTag("backend", "backend1")
+	root.Finish(nil)
+	root.Finish(nil)
+
+	if len(r.spans) != 3 {
+		t.Fatalf("Expected 3 spans; got %d.", len(r.spans))
+	}
+	if root.ParentID != "" || root.TraceID == "" ||
+		root.Tags["volume"] != "vol1" || root.Tags["backend"] != "backend1" ||
+		root.Tags[ComponentTag] != Core {
+		t.Errorf("Unexpected root span:  %+v", root)
+	}
+	for _, s := range []*Span{child, detached} {
+		if s.TraceID != root.TraceID || s.ParentID != root.ID {
+			t.Errorf("Span %s isn't a child of the root span.", s.Name)
+		}
+	}
+	if child.Tags[ErrorTag] != "failed" {
+		t.Errorf("Error not recorded; got tags %v.", child.Tags)
+	}
+
+	// Spans join traces started by clients.
+	_, remote := StartSpan(ContextWithRemoteParent(context.Background(),
+		"0123456789abcdef", "fedcba9876543210"), Core, "remote", nil)
+	if remote.TraceID != "0123456789abcdef" ||
+		remote.ParentID != "fedcba9876543210" {
+		t.Errorf("Span didn't join the remote trace:  %+v", remote)
+	}
+}
+
+func TestZipkinExporter(t *testing.T) {
+	var (
+		mutex sync.Mutex
+		spans []*zipkinSpan
+	)
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			batch := make([]*zipkinSpan, 0)
+			if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			mutex.Lock()
+			spans = append(spans, batch...)
+			mutex.Unlock()
+			w.WriteHeader(http.StatusAccepted)
+		}))
+	defer server.Close()
+
+	e := NewZipkinExporter(server.URL, "trident", time.Hour,
+		time.Second).(*zipkinExporter)
+	for i := 0; i < zipkinBatchSize+1; i++ {
+		e.Export(&Span{
+			TraceID:  "0123456789abcdef",
+			ID:       fmt.Sprintf("%016x", i),
+			Name:     "AddVolume",
+			Start:    time.Now(),
+			Duration: time.Millisecond,
+			Tags:     map[string]string{ComponentTag: Core},
+		})
+	}
+	e.flush()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(spans) != zipkinBatchSize+1 {
+		t.Fatalf("Expected %d spans; got %d.", zipkinBatchSize+1,
+			len(spans))
+	}
+	if spans[0].LocalEndpoint.ServiceName != "trident" ||
+		spans[0].Duration != 1000 || spans[0].Tags[ComponentTag] != Core {
+		t.Errorf("Unexpected Zipkin span:  %+v", spans[0])
+	}
+}
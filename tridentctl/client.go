@@ -0,0 +1,180 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+var (
+	// apiURL and apiClient are set from the command line flags by
+	// configureClient before any command runs.
+	apiURL    string
+	apiClient *http.Client
+)
+
+// apiResponse captures the error field common to every v1 API response.
+type apiResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// serverURL returns the REST API's base URL.  The server address may
+// include an http:// or https:// scheme; without one, HTTPS is used only
+// if --tls is set.
+func serverURL() (string, error) {
+	scheme, address := "http", server
+	if i := strings.Index(server, "://"); i >= 0 {
+		scheme, address = server[:i], server[i+len("://"):]
+		switch scheme {
+		case "https":
+		case "http":
+			if useTLS {
+				return "", fmt.Errorf("--tls can't be used with the "+
+					"http:// server address %s.", server)
+			}
+		default:
+			return "", fmt.Errorf("Unsupported scheme %s in server "+
+				"address %s; must be http or https.", scheme, server)
+		}
+	}
+	address = strings.TrimSuffix(address, "/")
+	if address == "" {
+		return "", fmt.Errorf("No server address given.")
+	}
+	if useTLS {
+		scheme = "https"
+	}
+	if scheme == "http" &&
+		(caCertFile != "" || certFile != "" || keyFile != "") {
+		return "", fmt.Errorf("--ca-cert, --cert, and --key require --tls " +
+			"or an https:// server address.")
+	}
+	return scheme + "://" + address, nil
+}
+
+// newHTTPClient returns a client for the REST API at baseURL.  HTTPS
+// clients verify the server against --ca-cert, or the system's CAs if it
+// isn't set, and present the --cert and --key pair if one is given.
+func newHTTPClient(baseURL string) (*http.Client, error) {
+	client := &http.Client{Timeout: timeout}
+	if !strings.HasPrefix(baseURL, "https://") {
+		return client, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caCertFile != "" {
+		caPEM, err := ioutil.ReadFile(caCertFile)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read CA certificate:  %v", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("No valid certificates found in %s.",
+				caCertFile)
+		}
+	}
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("--cert and --key must be given together.")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to load client certificate:  %v",
+				err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	client.Transport = &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: tlsConfig,
+	}
+	return client, nil
+}
+
+// configureClient sets apiURL and apiClient from the command line flags.
+func configureClient() error {
+	baseURL, err := serverURL()
+	if err != nil {
+		return err
+	}
+	client, err := newHTTPClient(baseURL)
+	if err != nil {
+		return err
+	}
+	apiURL, apiClient = baseURL, client
+	return nil
+}
+
+// doRequest sends a request to the REST API and returns the response body.
+// Non-2xx responses are returned as errors using the API's error message.
+func doRequest(method, url string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequest(method, apiURL+url, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := apiClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr apiResponse
+		if err := json.Unmarshal(respBody, &apiErr); err == nil &&
+			apiErr.Error != "" {
+			return nil, fmt.Errorf("%s", apiErr.Error)
+		}
+		return nil, fmt.Errorf("%s %s returned %s", method, url, resp.Status)
+	}
+	return respBody, nil
+}
+
+// getObject fetches a single object and returns the value of field from the
+// response, e.g., the "volume" field of GET /volume/{name}.
+func getObject(url, field string) (json.RawMessage, error) {
+	body, err := doRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	var response map[string]json.RawMessage
+	if err = json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+	object, ok := response[field]
+	if !ok {
+		return nil, fmt.Errorf("Response from %s has no %s.", url, field)
+	}
+	return object, nil
+}
+
+// listNames returns the object names from a list call, e.g., the "volumes"
+// field of GET /volume.
+func listNames(url, field string) ([]string, error) {
+	body, err := doRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	var response map[string]json.RawMessage
+	if err = json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0)
+	if raw, ok := response[field]; ok && raw != nil {
+		if err = json.Unmarshal(raw, &names); err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,181 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parseFlags resets the client flags to their defaults and parses args.
+func parseFlags(t *testing.T, args ...string) error {
+	server, useTLS, caCertFile, certFile, keyFile = defaultServer, false, "",
+		"", ""
+	if err := rootCmd.PersistentFlags().Parse(args); err != nil {
+		t.Fatalf("Unable to parse %v:  %v", args, err)
+	}
+	return configureClient()
+}
+
+func TestServerURL(t *testing.T) {
+	defer parseFlags(t)
+	for _, test := range []struct {
+		args     []string
+		expected string
+	}{
+		{nil, "http://" + defaultServer},
+		{[]string{"--server", "trident:8000"}, "http://trident:8000"},
+		{[]string{"-s", "http://trident:8000/"}, "http://trident:8000"},
+		{[]string{"-s", "trident:8443", "--tls"}, "https://trident:8443"},
+		{[]string{"-s", "https://trident:8443"}, "https://trident:8443"},
+		{[]string{"-s", "https://trident:8443", "--tls=true"},
+			"https://trident:8443"},
+	} {
+		if err := parseFlags(t, test.args...); err != nil {
+			t.Errorf("Unexpected error for %v:  %v", test.args, err)
+		} else if apiURL != test.expected {
+			t.Errorf("Expected %s for %v; got %s.", test.expected, test.args,
+				apiURL)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"-s", "ftp://trident:8000"},
+		{"-s", "http://trident:8000", "--tls"},
+		{"-s", "https://"},
+		{"-s", ""},
+		{"--ca-cert", "ca.pem"},
+		{"--cert", "cert.pem", "--key", "key.pem"},
+		{"--tls", "--cert", "cert.pem"},
+		{"--tls", "--key", "key.pem"},
+		{"--tls", "--ca-cert", "missing.pem"},
+	} {
+		if err := parseFlags(t, args...); err == nil {
+			t.Errorf("Expected %v to be rejected.", args)
+		}
+	}
+}
+
+// writeClientCert writes a new self-signed client certificate and its key
+// to dir, returning the certificate.
+func writeClientCert(t *testing.T, dir string) *x509.Certificate {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal("Unable to generate key:  ", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "tridentctl"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage: x509.KeyUsageCertSign |
+			x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template,
+		&key.PublicKey, key)
+	if err != nil {
+		t.Fatal("Unable to create certificate:  ", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal("Unable to parse certificate:  ", err)
+	}
+	writePEM(t, filepath.Join(dir, "cert.pem"), "CERTIFICATE", der)
+	writePEM(t, filepath.Join(dir, "key.pem"), "RSA PRIVATE KEY",
+		x509.MarshalPKCS1PrivateKey(key))
+	return cert
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{
+		Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatalf("Unable to write %s:  %v", path, err)
+	}
+}
+
+func TestDoRequestTLS(t *testing.T) {
+	defer parseFlags(t)
+	dir, err := ioutil.TempDir("", "tridentctl")
+	if err != nil {
+		t.Fatal("Unable to create directory:  ", err)
+	}
+	defer os.RemoveAll(dir)
+	clientCA := x509.NewCertPool()
+	clientCA.AddCert(writeClientCert(t, dir))
+
+	requests := make(chan *http.Request, 1)
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			requests <- r
+			w.Write([]byte(`{"name":"vol1"}`))
+		}))
+	ts.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCA,
+	}
+	ts.StartTLS()
+	defer ts.Close()
+	caFile := filepath.Join(dir, "ca.pem")
+	writePEM(t, caFile, "CERTIFICATE", ts.TLS.Certificates[0].Certificate[0])
+	address := strings.TrimPrefix(ts.URL, "https://")
+
+	certArgs := []string{"--cert", filepath.Join(dir, "cert.pem"),
+		"--key", filepath.Join(dir, "key.pem")}
+	for _, test := range []struct {
+		name string
+		args []string
+		ok   bool
+	}{
+		{"PlainHTTP", []string{"-s", address}, false},
+		{"UnknownCA", append([]string{"-s", address, "--tls"},
+			certArgs...), false},
+		{"NoClientCert", []string{"-s", address, "--tls",
+			"--ca-cert", caFile}, false},
+		{"TLSFlag", append([]string{"-s", address, "--tls",
+			"--ca-cert", caFile}, certArgs...), true},
+		{"HTTPSScheme", append([]string{"-s", ts.URL,
+			"--ca-cert", caFile}, certArgs...), true},
+	} {
+		if err = parseFlags(t, test.args...); err != nil {
+			t.Errorf("%s:  unable to configure client:  %v", test.name, err)
+			continue
+		}
+		body, err := doRequest("POST", "/trident/v1/volume",
+			strings.NewReader(`{"name":"vol1"}`))
+		if !test.ok {
+			if err == nil {
+				t.Errorf("%s:  expected the request to fail.", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s:  request failed:  %v", test.name, err)
+			continue
+		}
+		if string(body) != `{"name":"vol1"}` {
+			t.Errorf("%s:  unexpected response %s.", test.name, body)
+		}
+		request := <-requests
+		if request.Method != "POST" ||
+			request.URL.Path != "/trident/v1/volume" ||
+			request.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("%s:  unexpected request %+v.", test.name, request)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/netapp/trident/config"
+)
+
+const tridentContainerName = "trident-main"
+
+var (
+	createFile    string
+	deleteForce   bool
+	getMetadata   string
+	logsNamespace string
+	logsFollow    bool
+	bundleFile    string
+)
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the client and server versions",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		serverVersion := "unknown"
+		body, err := doRequest("GET", config.VersionURL, nil)
+		if err == nil {
+			var response struct {
+				Version string `json:"version"`
+			}
+			if err = json.Unmarshal(body, &response); err == nil {
+				serverVersion = response.Version
+			}
+		}
+		versions := map[string]string{
+			"client": config.OrchestratorVersion,
+			"server": serverVersion,
+		}
+		if outputFormat == outputTable {
+			fmt.Printf("Client version:  %s\nServer version:  %s\n",
+				versions["client"], versions["server"])
+		} else if writeErr := writeValue(os.Stdout, versions); writeErr != nil {
+			return writeErr
+		}
+		return err
+	},
+}
+
+var getCmd = &cobra.Command{
+	Use:   "get (backend|volume|storageclass|hook|snapshotpolicy) [NAME...]",
+	Short: "Display one or more resources",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := lookupResource(args[0])
+		if err != nil {
+			return err
+		}
+		names := args[1:]
+		if getMetadata != "" && (r.kind != "volume" || len(names) > 0) {
+			return fmt.Errorf("--metadata only applies to listing volumes.")
+		}
+		if len(names) == 0 {
+			listURL := r.url
+			if getMetadata != "" {
+				listURL += "?metadata=" + url.QueryEscape(getMetadata)
+			}
+			if names, err = listNames(listURL, r.listField); err != nil {
+				return err
+			}
+		}
+		objects := make([]json.RawMessage, 0, len(names))
+		for _, name := range names {
+			object, err := getObject(r.url+"/"+name, r.getField)
+			if err != nil {
+				return err
+			}
+			objects = append(objects, object)
+		}
+		return writeObjects(os.Stdout, r, objects)
+	},
+}
+
+var createCmd = &cobra.Command{
+	Use:   "create (backend|volume|storageclass|hook|snapshotpolicy) -f FILE",
+	Short: "Create a resource from a JSON file",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := lookupResource(args[0])
+		if err != nil {
+			return err
+		}
+		if createFile == "" {
+			return fmt.Errorf("A configuration file must be specified with -f.")
+		}
+		var body []byte
+		if createFile == "-" {
+			body, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			body, err = ioutil.ReadFile(createFile)
+		}
+		if err != nil {
+			return err
+		}
+		respBody, err := doRequest("POST", r.url, bytes.NewBuffer(body))
+		if err != nil {
+			return err
+		}
+		var response map[string]interface{}
+		if err = json.Unmarshal(respBody, &response); err != nil {
+			return err
+		}
+		if outputFormat != outputTable {
+			return writeValue(os.Stdout, response)
+		}
+		fmt.Printf("Created %s.\n", r.kind)
+		return nil
+	},
+}
+
+var deleteCmd = &cobra.Command{
+	Use:   "delete (backend|volume|storageclass|hook|snapshotpolicy) NAME...",
+	Short: "Delete one or more resources",
+	Long: "Delete one or more resources.  Backends are taken offline and " +
+		"removed once they no longer have volumes.  Attached volumes are " +
+		"only deleted with --force.",
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := lookupResource(args[0])
+		if err != nil {
+			return err
+		}
+		query := ""
+		if deleteForce {
+			query = "?force=true"
+		}
+		failed := make([]string, 0)
+		for _, name := range args[1:] {
+			if _, err := doRequest("DELETE", r.url+"/"+name+query,
+				nil); err != nil {
+				fmt.Fprintf(os.Stderr, "Error deleting %s %s:  %v\n", r.kind,
+					name, err)
+				failed = append(failed, name)
+				continue
+			}
+			fmt.Printf("Deleted %s %s.\n", r.kind, name)
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("Failed to delete %s(s) %s.", r.kind,
+				strings.Join(failed, ", "))
+		}
+		return nil
+	},
+}
+
+// logsCmd shells out to kubectl, since Trident logs to its container's
+// standard output when running in Kubernetes.
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Print the logs of the " + config.OrchestratorName + " pod",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		nsArgs := []string{}
+		if logsNamespace != "" {
+			nsArgs = []string{"--namespace", logsNamespace}
+		}
+		getPod := exec.Command("kubectl", append(nsArgs, "get", "pod",
+			"-l", "app=trident.netapp.io",
+			"-o", "jsonpath={.items[0].metadata.name}")...)
+		podName, err := getPod.Output()
+		if err != nil {
+			return fmt.Errorf("Unable to find the %s pod:  %v",
+				config.OrchestratorName, err)
+		}
+		if len(podName) == 0 {
+			return fmt.Errorf("No %s pod found.", config.OrchestratorName)
+		}
+		logsArgs := append(nsArgs, "logs", string(podName),
+			"-c", tridentContainerName)
+		if logsFollow {
+			logsArgs = append(logsArgs, "-f")
+		}
+		logs := exec.Command("kubectl", logsArgs...)
+		logs.Stdout = os.Stdout
+		logs.Stderr = os.Stderr
+		return logs.Run()
+	},
+}
+
+var supportBundleCmd = &cobra.Command{
+	Use:   "supportbundle",
+	Short: "Download a support bundle of the server's state",
+	Long: "Download a gzipped tar archive of the server's state, with " +
+		"secrets redacted, to attach to support cases.",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		body, err := doRequest("GET", config.SupportBundleURL, nil)
+		if err != nil {
+			return err
+		}
+		if err = ioutil.WriteFile(bundleFile, body, 0600); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote support bundle to %s.\n", bundleFile)
+		return nil
+	},
+}
+
+func init() {
+	createCmd.Flags().StringVarP(&createFile, "filename", "f", "",
+		"JSON file describing the resource, or - for standard input")
+	getCmd.Flags().StringVar(&getMetadata, "metadata", "",
+		"Only list volumes with this metadata, e.g., owner=alice,team=db")
+	deleteCmd.Flags().BoolVar(&deleteForce, "force", false,
+		"Delete volumes even if they are attached to nodes")
+	logsCmd.Flags().StringVarP(&logsNamespace, "namespace", "n", "",
+		"Namespace of the "+config.OrchestratorName+" pod")
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false,
+		"Stream the logs")
+	supportBundleCmd.Flags().StringVarP(&bundleFile, "filename", "f",
+		config.OrchestratorName+"-support.tar.gz",
+		"File to write the support bundle to")
+}
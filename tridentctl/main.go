@@ -0,0 +1,81 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+// tridentctl is a command-line client for Trident's REST API.
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/netapp/trident/config"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+
+	defaultServer = "127.0.0.1:8000"
+	serverEnvVar  = "TRIDENT_SERVER"
+)
+
+var (
+	server       string
+	useTLS       bool
+	caCertFile   string
+	certFile     string
+	keyFile      string
+	outputFormat string
+	timeout      time.Duration
+
+	rootCmd = &cobra.Command{
+		Use:          "tridentctl",
+		Short:        "A CLI tool for managing " + config.OrchestratorName,
+		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			switch outputFormat {
+			case outputTable, outputJSON, outputYAML:
+			default:
+				return fmt.Errorf("Unsupported output format %s; must be "+
+					"one of %s, %s, or %s.", outputFormat, outputTable,
+					outputJSON, outputYAML)
+			}
+			return configureClient()
+		},
+	}
+)
+
+func init() {
+	defaultAddr := os.Getenv(serverEnvVar)
+	if defaultAddr == "" {
+		defaultAddr = defaultServer
+	}
+	rootCmd.PersistentFlags().StringVarP(&server, "server", "s", defaultAddr,
+		"Address and port of the "+config.OrchestratorName+" REST API (or set "+
+			serverEnvVar+"), optionally prefixed with http:// or https://")
+	rootCmd.PersistentFlags().BoolVar(&useTLS, "tls", false,
+		"Connect to the REST API over HTTPS")
+	rootCmd.PersistentFlags().StringVar(&caCertFile, "ca-cert", "",
+		"CA bundle used to verify the server's certificate (default: the "+
+			"system's CAs)")
+	rootCmd.PersistentFlags().StringVar(&certFile, "cert", "",
+		"Client certificate presented to the server; requires --key")
+	rootCmd.PersistentFlags().StringVar(&keyFile, "key", "",
+		"Private key for --cert")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o",
+		outputTable, "Output format: table, json, or yaml")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout",
+		30*time.Second, "Timeout for REST requests")
+
+	rootCmd.AddCommand(versionCmd, getCmd, createCmd, deleteCmd, logsCmd,
+		supportBundleCmd)
+}
+
+func main() {
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
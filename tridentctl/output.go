@@ -0,0 +1,57 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ghodss/yaml"
+)
+
+// writeObjects prints objects in the selected output format.  JSON and YAML
+// output is the API representation; table output uses the resource's
+// columns.
+func writeObjects(w io.Writer, r *resource, objects []json.RawMessage) error {
+	switch outputFormat {
+	case outputJSON, outputYAML:
+		var data interface{} = objects
+		if len(objects) == 1 {
+			data = objects[0]
+		}
+		return writeValue(w, data)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(r.header, "\t"))
+	for _, object := range objects {
+		row, err := r.row(object)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// writeValue prints an arbitrary value as JSON or YAML.  Table output falls
+// back to indented JSON.
+func writeValue(w io.Writer, value interface{}) error {
+	jsonBytes, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	if outputFormat == outputYAML {
+		yamlBytes, err := yaml.JSONToYAML(jsonBytes)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(yamlBytes)
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(jsonBytes))
+	return err
+}
@@ -0,0 +1,177 @@
+// Copyright 2016 NetApp, Inc. All Rights Reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/netapp/trident/config"
+)
+
+// resource describes how tridentctl reads and displays one kind of object
+// exposed by the REST API.
+type resource struct {
+	kind      string
+	aliases   []string
+	url       string
+	listField string
+	getField  string
+	header    []string
+	row       func(json.RawMessage) ([]string, error)
+}
+
+var resources = []*resource{
+	{
+		kind:      "backend",
+		aliases:   []string{"backend", "backends"},
+		url:       config.BackendURL,
+		listField: "backends",
+		getField:  "backend",
+		header:    []string{"NAME", "ONLINE", "POOLS", "VOLUMES"},
+		row: func(raw json.RawMessage) ([]string, error) {
+			var b struct {
+				Name    string                     `json:"name"`
+				Online  bool                       `json:"online"`
+				Storage map[string]json.RawMessage `json:"storage"`
+				Volumes []string                   `json:"volumes"`
+			}
+			if err := json.Unmarshal(raw, &b); err != nil {
+				return nil, err
+			}
+			return []string{b.Name, strconv.FormatBool(b.Online),
+				strconv.Itoa(len(b.Storage)), strconv.Itoa(len(b.Volumes))}, nil
+		},
+	},
+	{
+		kind:      "volume",
+		aliases:   []string{"volume", "volumes", "vol"},
+		url:       config.VolumeURL,
+		listField: "volumes",
+		getField:  "volume",
+		header: []string{"NAME", "SIZE", "PROTOCOL", "STORAGE CLASS",
+			"BACKEND", "POOL", "ATTACHED TO"},
+		row: func(raw json.RawMessage) ([]string, error) {
+			var v struct {
+				Config struct {
+					Name         string `json:"name"`
+					Size         string `json:"size"`
+					Protocol     string `json:"protocol"`
+					StorageClass string `json:"storageClass"`
+				}
+				Backend     string `json:"backend"`
+				Pool        string `json:"pool"`
+				Attachments []struct {
+					Node string `json:"node"`
+				} `json:"attachments"`
+				Orphaned bool `json:"orphaned"`
+			}
+			if err := json.Unmarshal(raw, &v); err != nil {
+				return nil, err
+			}
+			nodes := make([]string, 0, len(v.Attachments))
+			for _, a := range v.Attachments {
+				nodes = append(nodes, a.Node)
+			}
+			backend := v.Backend
+			if v.Orphaned {
+				backend += " (orphaned)"
+			}
+			return []string{v.Config.Name, v.Config.Size, v.Config.Protocol,
+				v.Config.StorageClass, backend, v.Pool,
+				strings.Join(nodes, ",")}, nil
+		},
+	},
+	{
+		kind:      "storageclass",
+		aliases:   []string{"storageclass", "storageclasses", "sc"},
+		url:       config.StorageClassURL,
+		listField: "storageClasses",
+		getField:  "storageClass",
+		header:    []string{"NAME", "ATTRIBUTES", "BACKENDS"},
+		row: func(raw json.RawMessage) ([]string, error) {
+			var sc struct {
+				Config struct {
+					Name       string                     `json:"name"`
+					Attributes map[string]json.RawMessage `json:"attributes"`
+				}
+				StoragePools map[string][]string `json:"storage"`
+			}
+			if err := json.Unmarshal(raw, &sc); err != nil {
+				return nil, err
+			}
+			attrs := make([]string, 0, len(sc.Config.Attributes))
+			for k, v := range sc.Config.Attributes {
+				attrs = append(attrs, k+"="+strings.Trim(string(v), `"`))
+			}
+			sort.Strings(attrs)
+			backends := make([]string, 0, len(sc.StoragePools))
+			for b := range sc.StoragePools {
+				backends = append(backends, b)
+			}
+			sort.Strings(backends)
+			return []string{sc.Config.Name, strings.Join(attrs, ","),
+				strings.Join(backends, ",")}, nil
+		},
+	},
+	{
+		kind:      "hook",
+		aliases:   []string{"hook", "hooks"},
+		url:       config.HookURL,
+		listField: "hooks",
+		getField:  "hook",
+		header:    []string{"NAME", "PHASE", "URL", "FAILURE POLICY"},
+		row: func(raw json.RawMessage) ([]string, error) {
+			var h struct {
+				Name          string `json:"name"`
+				Phase         string `json:"phase"`
+				URL           string `json:"url"`
+				FailurePolicy string `json:"failurePolicy"`
+			}
+			if err := json.Unmarshal(raw, &h); err != nil {
+				return nil, err
+			}
+			return []string{h.Name, h.Phase, h.URL, h.FailurePolicy}, nil
+		},
+	},
+	{
+		kind:      "snapshotpolicy",
+		aliases:   []string{"snapshotpolicy", "snapshotpolicies", "sp"},
+		url:       config.SnapshotPolicyURL,
+		listField: "snapshotPolicies",
+		getField:  "snapshotPolicy",
+		header:    []string{"NAME", "SCHEDULE", "RETENTION"},
+		row: func(raw json.RawMessage) ([]string, error) {
+			var p struct {
+				Name      string `json:"name"`
+				Schedule  string `json:"schedule"`
+				Retention int    `json:"retention"`
+			}
+			if err := json.Unmarshal(raw, &p); err != nil {
+				return nil, err
+			}
+			return []string{p.Name, p.Schedule, strconv.Itoa(p.Retention)},
+				nil
+		},
+	},
+}
+
+func lookupResource(name string) (*resource, error) {
+	name = strings.ToLower(name)
+	for _, r := range resources {
+		for _, alias := range r.aliases {
+			if alias == name {
+				return r, nil
+			}
+		}
+	}
+	kinds := make([]string, 0, len(resources))
+	for _, r := range resources {
+		kinds = append(kinds, r.kind)
+	}
+	return nil, fmt.Errorf("Unknown resource type %s; must be one of %s.",
+		name, strings.Join(kinds, ", "))
+}